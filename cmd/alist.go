@@ -10,7 +10,9 @@ import (
 	"github.com/alist-org/alist/v3/internal/bootstrap"
 	"github.com/alist-org/alist/v3/internal/bootstrap/data"
 	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
 	"github.com/alist-org/alist/v3/server"
+	"github.com/alist-org/alist/v3/server/middlewares"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
@@ -22,6 +24,8 @@ func init() {
 	flag.BoolVar(&args.Password, "password", false, "print current password")
 	flag.BoolVar(&args.NoPrefix, "no-prefix", false, "disable env prefix")
 	flag.BoolVar(&args.Dev, "dev", false, "start with dev mode")
+	flag.BoolVar(&args.EncryptAddition, "encrypt-addition", false, "encrypt the additions of storages in database and exit")
+	flag.BoolVar(&args.ReadOnly, "read-only", false, "start in read-only mode, all writes are rejected")
 	flag.Parse()
 }
 
@@ -34,16 +38,52 @@ func Init() {
 	bootstrap.InitConfig()
 	bootstrap.Log()
 	bootstrap.InitDB()
+	if args.EncryptAddition {
+		encryptAdditions()
+	}
+	if flag.Arg(0) == "apply" {
+		runApply(flag.Args()[1:])
+	}
+	bootstrap.LoadPlugins()
+	bootstrap.LoadStorages()
 	data.InitData()
 	bootstrap.InitAria2()
+	bootstrap.InitLeaderElection()
+	bootstrap.InitReauthReminder()
+	bootstrap.InitUsageCollector()
+	bootstrap.InitChangePruner()
+	bootstrap.InitAccessCounter()
+	bootstrap.InitDigest()
+	bootstrap.InitDemo()
+}
+
+// encryptAdditions encrypt the existing additions, the new ones are encrypted only if it's enabled in config
+func encryptAdditions() {
+	if !conf.Conf.EncryptAddition {
+		log.Fatalf("set encrypt_addition to true in config first")
+	}
+	n, err := db.EncryptStorageAdditions()
+	if err != nil {
+		log.Fatalf("failed encrypt additions: %+v", err)
+	}
+	m, err := db.EncryptStorageTemplateAdditions()
+	if err != nil {
+		log.Fatalf("failed encrypt additions of templates: %+v", err)
+	}
+	log.Infof("encrypted the additions of %d storages and %d storage templates", n, m)
+	os.Exit(0)
 }
+
 func main() {
 	Init()
 	if !args.Debug && !args.Dev {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New()
-	r.Use(gin.LoggerWithWriter(log.StandardLogger().Out), gin.RecoveryWithWriter(log.StandardLogger().Out))
+	r.Use(middlewares.RequestID, middlewares.ClientIP, gin.LoggerWithConfig(gin.LoggerConfig{
+		Formatter: middlewares.LogFormatter,
+		Output:    log.StandardLogger().Out,
+	}), gin.RecoveryWithWriter(log.StandardLogger().Out))
 	server.Init(r)
 	base := fmt.Sprintf("%s:%d", conf.Conf.Address, conf.Conf.Port)
 	log.Infof("start server @ %s", base)
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/bootstrap"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// runApply `alist apply -f state.yaml [-plan]` print the plan to converge the instance to the declared state,
+// apply it and exit. it's for the stopped instance, the running one should be applied by the api
+func runApply(arguments []string) {
+	set := flag.NewFlagSet("apply", flag.ExitOnError)
+	file := set.String("f", "", "the state file, yaml or json")
+	planOnly := set.Bool("plan", false, "only print the plan")
+	_ = set.Parse(arguments)
+	if *file == "" {
+		log.Fatalf("the state file is required: alist apply -f state.yaml")
+	}
+	state, err := loadState(*file)
+	if err != nil {
+		log.Fatalf("failed load state: %+v", err)
+	}
+	bootstrap.LoadPlugins()
+	bootstrap.LoadStorages()
+	changes, err := operations.PlanState(*state)
+	if err != nil {
+		log.Fatalf("invalid state: %+v", err)
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes, the instance matches the state.")
+		os.Exit(0)
+	}
+	fmt.Println("Plan:")
+	printChanges(changes)
+	if *planOnly {
+		os.Exit(0)
+	}
+	changes, err = operations.ApplyState(context.Background(), *state)
+	if err != nil {
+		log.Fatalf("failed apply: %+v", err)
+	}
+	fmt.Println("Applied:")
+	printChanges(changes)
+	for _, c := range changes {
+		if c.Error != "" {
+			os.Exit(1)
+		}
+	}
+	os.Exit(0)
+}
+
+// loadState the yaml is converted to json first, so the fields are named by their json tags
+func loadState(file string) (*operations.State, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	data, err = utils.Json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var state operations.State
+	if err := utils.Json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func printChanges(changes []operations.Change) {
+	signs := map[string]string{operations.ApplyCreate: "+", operations.ApplyUpdate: "~", operations.ApplyDelete: "-"}
+	for _, c := range changes {
+		line := fmt.Sprintf("  %s %s %s", signs[c.Action], c.Section, c.Key)
+		if len(c.Fields) > 0 {
+			line += " (" + strings.Join(c.Fields, ", ") + ")"
+		}
+		if c.Password != "" {
+			line += " password: " + c.Password
+		}
+		if c.Error != "" {
+			line += " failed: " + c.Error
+		}
+		fmt.Println(line)
+	}
+}
@@ -7,4 +7,8 @@ var (
 	Password bool
 	NoPrefix bool
 	Dev      bool
+	// encrypt the plaintext additions of storages and exit
+	EncryptAddition bool
+	// reject all writes regardless of the setting
+	ReadOnly bool
 )
@@ -0,0 +1,189 @@
+package alias
+
+import (
+	"context"
+	stdpath "path"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+type Alias struct {
+	model.Storage
+	Addition
+}
+
+func (d *Alias) Config() driver.Config {
+	return config
+}
+
+func (d *Alias) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if d.Path == "" {
+		return errors.New("path is required")
+	}
+	d.Path = utils.StandardizePath(d.Path)
+	return operations.CheckAliasLoop(d.MountPath, d.Path)
+}
+
+func (d *Alias) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *Alias) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *Alias) GetAliasPath() string {
+	return d.Path
+}
+
+// resolve get the storage and actual path of the path in the alias
+func (d *Alias) resolve(ctx context.Context, path string) (context.Context, driver.Driver, string, error) {
+	ctx, err := operations.EnterAlias(ctx, d.MountPath)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, stdpath.Join(d.Path, path))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return ctx, storage, actualPath, nil
+}
+
+// wrap the obj of the target storage, the id is the path in the alias
+func wrap(path string, obj model.Obj) model.Obj {
+	return &model.Object{
+		ID:       path,
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		IsFolder: obj.IsDir(),
+	}
+}
+
+func (d *Alias) Get(ctx context.Context, path string) (model.Obj, error) {
+	ctx, storage, actualPath, err := d.resolve(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := operations.Get(ctx, storage, actualPath)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(path, obj), nil
+}
+
+func (d *Alias) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	ctx, storage, actualPath, err := d.resolve(ctx, dir.GetID())
+	if err != nil {
+		return nil, err
+	}
+	objs, err := operations.List(ctx, storage, actualPath)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		res = append(res, wrap(stdpath.Join(dir.GetID(), obj.GetName()), obj))
+	}
+	return res, nil
+}
+
+func (d *Alias) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	ctx, storage, actualPath, err := d.resolve(ctx, file.GetID())
+	if err != nil {
+		return nil, err
+	}
+	link, _, err := operations.Link(ctx, storage, actualPath, args)
+	return link, err
+}
+
+func (d *Alias) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, stdpath.Join(parentDir.GetID(), dirName))
+	if err != nil {
+		return err
+	}
+	return operations.MakeDir(ctx, storage, actualPath)
+}
+
+// resolvePair resolve the paths, which must be in the same target storage
+func (d *Alias) resolvePair(ctx context.Context, src, dst string) (context.Context, driver.Driver, string, string, error) {
+	ctx, storage, srcPath, err := d.resolve(ctx, src)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	_, dstStorage, dstPath, err := d.resolve(ctx, dst)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	if storage.GetStorage().MountPath != dstStorage.GetStorage().MountPath {
+		return nil, nil, "", "", errors.WithMessage(errs.NotSupport, "the paths are in different storages")
+	}
+	return ctx, storage, srcPath, dstPath, nil
+}
+
+func (d *Alias) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ctx, storage, srcPath, dstPath, err := d.resolvePair(ctx, srcObj.GetID(), dstDir.GetID())
+	if err != nil {
+		return err
+	}
+	return operations.Move(ctx, storage, srcPath, dstPath)
+}
+
+func (d *Alias) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, srcObj.GetID())
+	if err != nil {
+		return err
+	}
+	return operations.Rename(ctx, storage, actualPath, newName)
+}
+
+func (d *Alias) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ctx, storage, srcPath, dstPath, err := d.resolvePair(ctx, srcObj.GetID(), dstDir.GetID())
+	if err != nil {
+		return err
+	}
+	return operations.Copy(ctx, storage, srcPath, dstPath)
+}
+
+func (d *Alias) Remove(ctx context.Context, obj model.Obj) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, obj.GetID())
+	if err != nil {
+		return err
+	}
+	return operations.Remove(ctx, storage, actualPath)
+}
+
+// stream closed by the caller of the alias, not the target
+type stream struct {
+	model.FileStreamer
+}
+
+func (s stream) Close() error {
+	return nil
+}
+
+func (d *Alias) Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, dstDir.GetID())
+	if err != nil {
+		return err
+	}
+	return operations.Put(ctx, storage, actualPath, stream{file}, up)
+}
+
+func (d *Alias) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*Alias)(nil)
+var _ driver.Getter = (*Alias)(nil)
+var _ driver.Alias = (*Alias)(nil)
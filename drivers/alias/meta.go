@@ -0,0 +1,25 @@
+package alias
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	Path string `json:"path" required:"true" help:"the virtual path to mount, such as /movies"`
+}
+
+var config = driver.Config{
+	Name:      "Alias",
+	LocalSort: true,
+	// the target storage caches already
+	NoCache: true,
+}
+
+func New() driver.Driver {
+	return &Alias{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
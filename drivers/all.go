@@ -1,6 +1,20 @@
 package drivers
 
 import (
+	_ "github.com/alist-org/alist/v3/drivers/alias"
+	_ "github.com/alist-org/alist/v3/drivers/b2"
+	_ "github.com/alist-org/alist/v3/drivers/baidu_netdisk"
+	_ "github.com/alist-org/alist/v3/drivers/chunker"
+	_ "github.com/alist-org/alist/v3/drivers/crypt"
+	_ "github.com/alist-org/alist/v3/drivers/dedup"
+	_ "github.com/alist-org/alist/v3/drivers/ftp"
+	_ "github.com/alist-org/alist/v3/drivers/google_drive"
 	_ "github.com/alist-org/alist/v3/drivers/local"
+	_ "github.com/alist-org/alist/v3/drivers/onedrive"
+	_ "github.com/alist-org/alist/v3/drivers/s3"
+	_ "github.com/alist-org/alist/v3/drivers/sftp"
+	_ "github.com/alist-org/alist/v3/drivers/share"
+	_ "github.com/alist-org/alist/v3/drivers/smb"
 	_ "github.com/alist-org/alist/v3/drivers/virtual"
+	_ "github.com/alist-org/alist/v3/drivers/webdav"
 )
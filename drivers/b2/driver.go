@@ -0,0 +1,170 @@
+package b2
+
+import (
+	"context"
+	"io"
+	"net/http"
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// B2 mount a bucket of backblaze b2 by the native api, the folders are the prefixes of the file names
+type B2 struct {
+	model.Storage
+	Addition
+	// client for the api calls, uploadClient for the uploads without timeout
+	client       *http.Client
+	uploadClient *http.Client
+
+	mu       sync.Mutex
+	auth     authorizeResp
+	bucketID string
+	public   bool
+}
+
+func (d *B2) Config() driver.Config {
+	return config
+}
+
+func (d *B2) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if d.PartSize < 5 {
+		d.PartSize = 5
+	}
+	d.client = net.APIClient(d.ClientNetwork())
+	d.uploadClient = net.Client(d.ClientNetwork())
+	if err := d.authorize(ctx); err != nil {
+		return err
+	}
+	if err := d.resolveBucket(ctx); err != nil {
+		return err
+	}
+	if _, err := d.list(ctx, d.RootFolder); err != nil {
+		if errs.IsObjectNotFound(err) {
+			return errors.Errorf("root folder %s not exists", d.RootFolder)
+		}
+		return err
+	}
+	return nil
+}
+
+// resolveBucket get the id of the bucket, the key may be restricted to the bucket
+func (d *B2) resolveBucket(ctx context.Context) error {
+	auth := d.getAuth()
+	if auth.Allowed.BucketID != "" && auth.Allowed.BucketName != d.Bucket {
+		return errors.Errorf("the key is restricted to the bucket %s", auth.Allowed.BucketName)
+	}
+	var res listBucketsResp
+	body := map[string]interface{}{"accountId": auth.AccountID, "bucketName": d.Bucket}
+	if auth.Allowed.BucketID != "" {
+		body["bucketId"] = auth.Allowed.BucketID
+	}
+	if err := d.call(ctx, "b2_list_buckets", body, &res); err != nil {
+		return errors.WithMessage(err, "failed list buckets")
+	}
+	for _, b := range res.Buckets {
+		if b.BucketName == d.Bucket {
+			d.bucketID = b.BucketID
+			d.public = b.BucketType == "allPublic"
+			return nil
+		}
+	}
+	return errors.Errorf("bucket %s not exists", d.Bucket)
+}
+
+func (d *B2) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *B2) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *B2) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	return d.list(ctx, dir.GetID())
+}
+
+func (d *B2) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	u, err := d.downloadUrl(ctx, getName(file.GetID(), false))
+	if err != nil {
+		return nil, err
+	}
+	link := &model.Link{URL: u}
+	if !d.public {
+		// expire the link before the authorization, so it's not cached while invalid
+		exp := time.Duration(d.SignURLExpire)*time.Hour - time.Minute
+		if exp <= 0 {
+			exp = time.Minute
+		}
+		link.Expiration = &exp
+	}
+	return link, nil
+}
+
+// MakeDir upload an empty placeholder, as b2 has no real folders
+func (d *B2) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	name := getName(stdpath.Join(parentDir.GetID(), dirName), true) + folderPlaceholder
+	return d.uploadFile(ctx, name, "application/x-bz-empty", nil)
+}
+
+// Move is a copy then a remove, b2 can't rename the files
+func (d *B2) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	if err := d.copyPath(ctx, srcObj.GetID(), stdpath.Join(dstDir.GetID(), srcObj.GetName()), srcObj.IsDir()); err != nil {
+		return err
+	}
+	return d.removePath(ctx, srcObj.GetID(), srcObj.IsDir())
+}
+
+func (d *B2) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	dstPath := stdpath.Join(stdpath.Dir(srcObj.GetID()), newName)
+	if err := d.copyPath(ctx, srcObj.GetID(), dstPath, srcObj.IsDir()); err != nil {
+		return err
+	}
+	return d.removePath(ctx, srcObj.GetID(), srcObj.IsDir())
+}
+
+func (d *B2) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.copyPath(ctx, srcObj.GetID(), stdpath.Join(dstDir.GetID(), srcObj.GetName()), srcObj.IsDir())
+}
+
+func (d *B2) Remove(ctx context.Context, obj model.Obj) error {
+	return d.removePath(ctx, obj.GetID(), obj.IsDir())
+}
+
+func (d *B2) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	name := getName(stdpath.Join(dstDir.GetID(), stream.GetName()), false)
+	contentType := stream.GetMimetype()
+	if contentType == "" {
+		contentType = "b2/x-auto"
+	}
+	partSize := int64(d.PartSize) * 1024 * 1024
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+	if stream.GetSize() > partSize {
+		return d.uploadLargeFile(ctx, name, contentType, stream, partSize, up)
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return errors.Wrap(err, "failed read stream")
+	}
+	return d.uploadFile(ctx, name, contentType, data)
+}
+
+func (d *B2) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*B2)(nil)
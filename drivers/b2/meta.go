@@ -0,0 +1,30 @@
+package b2
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	Bucket         string `json:"bucket" required:"true"`
+	KeyID          string `json:"key_id" required:"true" help:"the id of the application key"`
+	ApplicationKey string `json:"application_key" required:"true"`
+	// the downloads of private buckets are authorized by a token in the url
+	SignURLExpire int `json:"sign_url_expire" type:"number" default:"4" help:"hours of the download authorization of the links"`
+	PartSize      int `json:"part_size" type:"number" default:"100" help:"MB, the larger files are uploaded in parts, at least 5"`
+}
+
+var config = driver.Config{
+	Name:        "BackblazeB2",
+	LocalSort:   true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &B2{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
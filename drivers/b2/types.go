@@ -0,0 +1,48 @@
+package b2
+
+type authorizeResp struct {
+	AccountID          string `json:"accountId"`
+	AuthorizationToken string `json:"authorizationToken"`
+	ApiUrl             string `json:"apiUrl"`
+	DownloadUrl        string `json:"downloadUrl"`
+	Allowed            struct {
+		BucketID   string `json:"bucketId"`
+		BucketName string `json:"bucketName"`
+	} `json:"allowed"`
+}
+
+type bucket struct {
+	BucketID   string `json:"bucketId"`
+	BucketName string `json:"bucketName"`
+	BucketType string `json:"bucketType"`
+}
+
+type listBucketsResp struct {
+	Buckets []bucket `json:"buckets"`
+}
+
+type file struct {
+	FileID          string `json:"fileId"`
+	FileName        string `json:"fileName"`
+	ContentLength   int64  `json:"contentLength"`
+	UploadTimestamp int64  `json:"uploadTimestamp"` // milliseconds
+	// upload, folder, hide or start
+	Action string `json:"action"`
+}
+
+type listFilesResp struct {
+	Files        []file `json:"files"`
+	NextFileName string `json:"nextFileName"`
+	NextFileID   string `json:"nextFileId"`
+}
+
+type uploadUrlResp struct {
+	UploadUrl          string `json:"uploadUrl"`
+	AuthorizationToken string `json:"authorizationToken"`
+}
+
+type apiError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
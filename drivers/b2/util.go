@@ -0,0 +1,409 @@
+package b2
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	authorizeUrl = "https://api.backblazeb2.com/b2api/v2/b2_authorize_account"
+	// the placeholder making an empty folder visible, same as the web ui of b2
+	folderPlaceholder = ".bzEmpty"
+	// the min size of the parts except the last one
+	minPartSize = 5 * 1024 * 1024
+)
+
+// getName return the file name in the bucket, the names of folders end with a slash
+func getName(path string, dir bool) string {
+	name := strings.TrimPrefix(path, "/")
+	if dir && name != "" {
+		name += "/"
+	}
+	return name
+}
+
+// escapeName percent-encode the file name for the urls and headers, the slashes are kept
+func escapeName(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// authorize get the api url and the token of the account, the token is valid for 24 hours
+func (d *B2) authorize(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, authorizeUrl, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.SetBasicAuth(d.KeyID, d.ApplicationKey)
+	res, err := d.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed authorize account")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.WithMessage(decodeError(res), "failed authorize account")
+	}
+	var auth authorizeResp
+	if err := utils.Json.NewDecoder(res.Body).Decode(&auth); err != nil {
+		return errors.Wrap(err, "failed decode authorization")
+	}
+	d.mu.Lock()
+	d.auth = auth
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *B2) getAuth() authorizeResp {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.auth
+}
+
+func decodeError(res *http.Response) error {
+	var apiErr apiError
+	if err := utils.Json.NewDecoder(res.Body).Decode(&apiErr); err != nil || apiErr.Message == "" {
+		return errors.WithMessage(errs.NewStatusError(res), "unexpected status")
+	}
+	return errors.WithMessagef(errs.NewStatusError(res), "%s: %s", apiErr.Code, apiErr.Message)
+}
+
+// call the api with the json body, authorize again if the token is expired
+func (d *B2) call(ctx context.Context, name string, body, res interface{}) error {
+	data, err := utils.Json.Marshal(body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for retried := false; ; retried = true {
+		auth := d.getAuth()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.ApiUrl+"/b2api/v2/"+name, bytes.NewReader(data))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		req.Header.Set("Authorization", auth.AuthorizationToken)
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return errors.Wrapf(err, "failed call %s", name)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && !retried {
+			_ = resp.Body.Close()
+			if err := d.authorize(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return errors.WithStack(errs.ObjectNotFound)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return errors.WithMessagef(decodeError(resp), "failed call %s", name)
+		}
+		if res == nil {
+			return nil
+		}
+		return errors.Wrapf(utils.Json.NewDecoder(resp.Body).Decode(res), "failed decode response of %s", name)
+	}
+}
+
+// listFiles list the latest versions under the prefix, the sub folders are returned as folders unless recursive
+func (d *B2) listFiles(ctx context.Context, prefix string, recursive bool, handle func(files []file) error) error {
+	body := map[string]interface{}{
+		"bucketId":     d.bucketID,
+		"prefix":       prefix,
+		"maxFileCount": 1000,
+	}
+	if !recursive {
+		body["delimiter"] = "/"
+	}
+	for {
+		var res listFilesResp
+		if err := d.call(ctx, "b2_list_file_names", body, &res); err != nil {
+			return err
+		}
+		if err := handle(res.Files); err != nil {
+			return err
+		}
+		if res.NextFileName == "" {
+			return nil
+		}
+		body["startFileName"] = res.NextFileName
+	}
+}
+
+// listVersions list all versions of the file name, the older versions would be visible if only the latest is deleted
+func (d *B2) listVersions(ctx context.Context, name string) ([]file, error) {
+	body := map[string]interface{}{
+		"bucketId":      d.bucketID,
+		"prefix":        name,
+		"startFileName": name,
+		"maxFileCount":  1000,
+	}
+	var versions []file
+	for {
+		var res listFilesResp
+		if err := d.call(ctx, "b2_list_file_versions", body, &res); err != nil {
+			return nil, err
+		}
+		for _, f := range res.Files {
+			if f.FileName == name {
+				versions = append(versions, f)
+			}
+		}
+		if res.NextFileName != name {
+			return versions, nil
+		}
+		body["startFileId"] = res.NextFileID
+	}
+}
+
+func (d *B2) list(ctx context.Context, path string) ([]model.Obj, error) {
+	prefix := getName(path, true)
+	var objs []model.Obj
+	exists := prefix == ""
+	err := d.listFiles(ctx, prefix, false, func(files []file) error {
+		for _, f := range files {
+			name := stdpath.Base(strings.TrimSuffix(f.FileName, "/"))
+			switch {
+			case f.Action == "folder":
+				objs = append(objs, &model.Object{ID: stdpath.Join(path, name), Name: name, IsFolder: true})
+			case f.FileName == prefix+folderPlaceholder:
+				exists = true
+			case f.Action == "upload":
+				objs = append(objs, &model.Object{
+					ID:       stdpath.Join(path, name),
+					Name:     name,
+					Size:     f.ContentLength,
+					Modified: time.UnixMilli(f.UploadTimestamp),
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 && !exists {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	return objs, nil
+}
+
+// getFile return the latest version of the file name
+func (d *B2) getFile(ctx context.Context, name string) (*file, error) {
+	var res listFilesResp
+	body := map[string]interface{}{
+		"bucketId":      d.bucketID,
+		"prefix":        name,
+		"startFileName": name,
+		"maxFileCount":  1,
+	}
+	if err := d.call(ctx, "b2_list_file_names", body, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Files) == 0 || res.Files[0].FileName != name {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	return &res.Files[0], nil
+}
+
+// removeName delete all versions of the file name
+func (d *B2) removeName(ctx context.Context, name string) error {
+	versions, err := d.listVersions(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		body := map[string]interface{}{"fileName": v.FileName, "fileId": v.FileID}
+		if err := d.call(ctx, "b2_delete_file_version", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removePath remove the file at the path, all files under it if it's a folder
+func (d *B2) removePath(ctx context.Context, path string, dir bool) error {
+	if !dir {
+		return d.removeName(ctx, getName(path, false))
+	}
+	var names []string
+	err := d.listFiles(ctx, getName(path, true), true, func(files []file) error {
+		for _, f := range files {
+			names = append(names, f.FileName)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		if err := d.removeName(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPath copy the file at the path on the server side, all files under it if it's a folder
+func (d *B2) copyPath(ctx context.Context, srcPath, dstPath string, dir bool) error {
+	copyFile := func(fileID, dstName string) error {
+		body := map[string]interface{}{"sourceFileId": fileID, "fileName": dstName}
+		return d.call(ctx, "b2_copy_file", body, nil)
+	}
+	if !dir {
+		f, err := d.getFile(ctx, getName(srcPath, false))
+		if err != nil {
+			return err
+		}
+		return copyFile(f.FileID, getName(dstPath, false))
+	}
+	srcPrefix, dstPrefix := getName(srcPath, true), getName(dstPath, true)
+	return d.listFiles(ctx, srcPrefix, true, func(files []file) error {
+		for _, f := range files {
+			if f.Action != "upload" {
+				continue
+			}
+			if err := copyFile(f.FileID, dstPrefix+strings.TrimPrefix(f.FileName, srcPrefix)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// uploadFile upload the data in one request, the url and token are got for each upload
+func (d *B2) uploadFile(ctx context.Context, name, contentType string, data []byte) error {
+	var upload uploadUrlResp
+	if err := d.call(ctx, "b2_get_upload_url", map[string]interface{}{"bucketId": d.bucketID}, &upload); err != nil {
+		return err
+	}
+	sum := sha1.Sum(data)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upload.UploadUrl, bytes.NewReader(data))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Authorization", upload.AuthorizationToken)
+	req.Header.Set("X-Bz-File-Name", escapeName(name))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Bz-Content-Sha1", hex.EncodeToString(sum[:]))
+	res, err := d.uploadClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed upload %s", name)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.WithMessagef(decodeError(res), "failed upload %s", name)
+	}
+	return nil
+}
+
+// uploadLargeFile upload the stream in parts, the large file is canceled if any part fails
+func (d *B2) uploadLargeFile(ctx context.Context, name, contentType string, stream model.FileStreamer, partSize int64, up driver.UpdateProgress) error {
+	var started file
+	body := map[string]interface{}{"bucketId": d.bucketID, "fileName": name, "contentType": contentType}
+	if err := d.call(ctx, "b2_start_large_file", body, &started); err != nil {
+		return errors.WithMessage(err, "failed start large file")
+	}
+	sha1s, err := d.uploadParts(ctx, started.FileID, stream, partSize, up)
+	if err != nil {
+		// cancel with a new context, the one of the task may be canceled
+		_ = d.call(context.Background(), "b2_cancel_large_file", map[string]interface{}{"fileId": started.FileID}, nil)
+		return err
+	}
+	body = map[string]interface{}{"fileId": started.FileID, "partSha1Array": sha1s}
+	return errors.WithMessage(d.call(ctx, "b2_finish_large_file", body, nil), "failed finish large file")
+}
+
+func (d *B2) uploadParts(ctx context.Context, fileID string, stream model.FileStreamer, partSize int64, up driver.UpdateProgress) ([]string, error) {
+	var upload uploadUrlResp
+	if err := d.call(ctx, "b2_get_upload_part_url", map[string]interface{}{"fileId": fileID}, &upload); err != nil {
+		return nil, err
+	}
+	size := stream.GetSize()
+	buf := make([]byte, partSize)
+	var sha1s []string
+	var uploaded int64
+	for partNumber := 1; uploaded < size; partNumber++ {
+		if utils.IsCanceled(ctx) {
+			return nil, ctx.Err()
+		}
+		n := partSize
+		if size-uploaded < n {
+			n = size - uploaded
+		}
+		if _, err := io.ReadFull(stream, buf[:n]); err != nil {
+			return nil, errors.Wrapf(err, "failed read part %d", partNumber)
+		}
+		sum := sha1.Sum(buf[:n])
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, upload.UploadUrl, bytes.NewReader(buf[:n]))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		req.Header.Set("Authorization", upload.AuthorizationToken)
+		req.Header.Set("X-Bz-Part-Number", strconv.Itoa(partNumber))
+		req.Header.Set("X-Bz-Content-Sha1", hex.EncodeToString(sum[:]))
+		res, err := d.uploadClient.Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed upload part %d", partNumber)
+		}
+		if res.StatusCode != http.StatusOK {
+			err = decodeError(res)
+			_ = res.Body.Close()
+			return nil, errors.WithMessagef(err, "failed upload part %d", partNumber)
+		}
+		_ = res.Body.Close()
+		sha1s = append(sha1s, hex.EncodeToString(sum[:]))
+		uploaded += n
+		if up != nil {
+			up(int(uploaded * 100 / size))
+		}
+	}
+	return sha1s, nil
+}
+
+// downloadUrl return the url of the file name, authorized by the token for private buckets
+func (d *B2) downloadUrl(ctx context.Context, name string) (string, error) {
+	auth := d.getAuth()
+	u := fmt.Sprintf("%s/file/%s/%s", auth.DownloadUrl, url.PathEscape(d.Bucket), escapeName(name))
+	if d.public {
+		return u, nil
+	}
+	expire := time.Duration(d.SignURLExpire) * time.Hour
+	if expire <= 0 {
+		expire = 4 * time.Hour
+	}
+	var res struct {
+		AuthorizationToken string `json:"authorizationToken"`
+	}
+	body := map[string]interface{}{
+		"bucketId":               d.bucketID,
+		"fileNamePrefix":         name,
+		"validDurationInSeconds": int64(expire.Seconds()),
+	}
+	if err := d.call(ctx, "b2_get_download_authorization", body, &res); err != nil {
+		return "", errors.WithMessage(err, "failed get download authorization")
+	}
+	return u + "?Authorization=" + url.QueryEscape(res.AuthorizationToken), nil
+}
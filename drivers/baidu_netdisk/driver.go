@@ -0,0 +1,143 @@
+package baidu_netdisk
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	stdpath "path"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// BaiduNetdisk mount the netdisk by the api of the baidu open platform
+type BaiduNetdisk struct {
+	model.Storage
+	Addition
+	// client for the metadata calls, uploadClient for the uploads without timeout
+	client       *http.Client
+	uploadClient *http.Client
+	// decided by the vip type of the account
+	blockSize int64
+	tokens    *operations.TokenManager
+}
+
+func (d *BaiduNetdisk) Config() driver.Config {
+	return config
+}
+
+func (d *BaiduNetdisk) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if d.DownloadAPI == "" {
+		d.DownloadAPI = "official"
+	}
+	if d.DownloadAPI != "official" && d.DownloadAPI != "crack" {
+		return errors.Errorf("unsupported download api: %s", d.DownloadAPI)
+	}
+	d.client = net.APIClient(d.ClientNetwork())
+	d.uploadClient = net.Client(d.ClientNetwork())
+	d.tokens = operations.NewTokenManager(d, &d.RefreshToken, d.refreshToken)
+	var info uinfoResp
+	if err := d.request(ctx, http.MethodGet, api+"/nas", url.Values{"method": {"uinfo"}}, nil, &info); err != nil {
+		return errors.WithMessage(err, "failed get user info")
+	}
+	var ok bool
+	if d.blockSize, ok = blockSizes[info.VipType]; !ok {
+		d.blockSize = blockSizes[0]
+	}
+	return nil
+}
+
+func (d *BaiduNetdisk) Drop(ctx context.Context) error {
+	if d.tokens != nil {
+		d.tokens.Close()
+	}
+	return nil
+}
+
+func (d *BaiduNetdisk) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *BaiduNetdisk) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	files, err := d.getFiles(ctx, dir.GetID())
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]model.Obj, 0, len(files))
+	for _, f := range files {
+		objs = append(objs, f.toObj())
+	}
+	return objs, nil
+}
+
+func (d *BaiduNetdisk) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	obj, ok := file.(*object)
+	if !ok {
+		return nil, errors.Errorf("unexpected obj of %s", file.GetName())
+	}
+	if d.DownloadAPI == "crack" {
+		return d.linkCrack(ctx, obj)
+	}
+	return d.linkOfficial(ctx, obj)
+}
+
+func (d *BaiduNetdisk) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	return d.create(ctx, stdpath.Join(parentDir.GetID(), dirName), 0, true, "", nil)
+}
+
+func (d *BaiduNetdisk) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.manage(ctx, "move", []map[string]string{{
+		"path":    srcObj.GetID(),
+		"dest":    dstDir.GetID(),
+		"newname": srcObj.GetName(),
+	}})
+}
+
+func (d *BaiduNetdisk) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.manage(ctx, "rename", []map[string]string{{
+		"path":    srcObj.GetID(),
+		"newname": newName,
+	}})
+}
+
+func (d *BaiduNetdisk) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.manage(ctx, "copy", []map[string]string{{
+		"path":    srcObj.GetID(),
+		"dest":    dstDir.GetID(),
+		"newname": srcObj.GetName(),
+	}})
+}
+
+func (d *BaiduNetdisk) Remove(ctx context.Context, obj model.Obj) error {
+	// deleted into the recycle bin of the netdisk
+	return d.manage(ctx, "delete", []string{obj.GetID()})
+}
+
+func (d *BaiduNetdisk) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	return d.upload(ctx, stdpath.Join(dstDir.GetID(), stream.GetName()), stream, up)
+}
+
+func (d *BaiduNetdisk) About(ctx context.Context) (*model.StorageUsage, error) {
+	var res quotaResp
+	query := url.Values{"checkfree": {"1"}, "checkexpire": {"1"}}
+	if err := d.request(ctx, http.MethodGet, "https://pan.baidu.com/api/quota", query, nil, &res); err != nil {
+		return nil, err
+	}
+	return &model.StorageUsage{Total: res.Total, Used: res.Used, Free: res.Free}, nil
+}
+
+func (d *BaiduNetdisk) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*BaiduNetdisk)(nil)
@@ -0,0 +1,34 @@
+package baidu_netdisk
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	ClientID     string `json:"client_id" required:"true" help:"the api key of the app of the baidu open platform"`
+	ClientSecret string `json:"client_secret" required:"true" help:"the secret key of the app"`
+	RefreshToken string `json:"refresh_token" required:"true"`
+	// the official links are limited in speed for the non-vip accounts
+	DownloadAPI   string `json:"download_api" type:"select" values:"official,crack" default:"official"`
+	CustomCrackUA string `json:"custom_crack_ua" default:"netdisk" help:"the user agent to download the links of the crack api"`
+	// the server checks the md5 of the whole file and its first 256 KB,
+	// the file is created without uploading if the same content exists
+	RapidUpload bool `json:"rapid_upload" default:"true" help:"skip uploading the content that already exists in baidu netdisk"`
+}
+
+var config = driver.Config{
+	Name:        "BaiduNetdisk",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &BaiduNetdisk{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
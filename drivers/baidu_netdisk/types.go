@@ -0,0 +1,101 @@
+package baidu_netdisk
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+type file struct {
+	FsID           int64  `json:"fs_id"`
+	Path           string `json:"path"`
+	ServerFilename string `json:"server_filename"`
+	Size           int64  `json:"size"`
+	Isdir          int    `json:"isdir"`
+	ServerMtime    int64  `json:"server_mtime"`
+}
+
+// object keep the fs_id, which is needed to get the link of the file
+type object struct {
+	model.Object
+	FsID int64
+}
+
+func (f file) toObj() model.Obj {
+	return &object{
+		Object: model.Object{
+			ID:       f.Path,
+			Name:     f.ServerFilename,
+			Size:     f.Size,
+			Modified: time.Unix(f.ServerMtime, 0),
+			IsFolder: f.Isdir == 1,
+		},
+		FsID: f.FsID,
+	}
+}
+
+// errnoResp is embedded in all responses of the apis
+type errnoResp struct {
+	Errno  int    `json:"errno"`
+	Errmsg string `json:"errmsg"`
+}
+
+func (r errnoResp) errno() int {
+	return r.Errno
+}
+
+type listResp struct {
+	errnoResp
+	List []file `json:"list"`
+}
+
+type metasResp struct {
+	errnoResp
+	List []struct {
+		FsID  int64  `json:"fs_id"`
+		Dlink string `json:"dlink"`
+	} `json:"list"`
+}
+
+// crackMetasResp the response of the api of the old clients
+type crackMetasResp struct {
+	errnoResp
+	Info []struct {
+		Dlink string `json:"dlink"`
+	} `json:"info"`
+}
+
+type uinfoResp struct {
+	errnoResp
+	VipType int `json:"vip_type"`
+}
+
+type quotaResp struct {
+	errnoResp
+	Total int64 `json:"total"`
+	Used  int64 `json:"used"`
+	Free  int64 `json:"free"`
+}
+
+type precreateResp struct {
+	errnoResp
+	// 2 if the file is rapid uploaded
+	ReturnType int    `json:"return_type"`
+	UploadID   string `json:"uploadid"`
+	// the index of the blocks that need to be uploaded
+	BlockList []int `json:"block_list"`
+}
+
+type uploadBlockResp struct {
+	MD5       string `json:"md5"`
+	ErrorCode int    `json:"error_code"`
+	ErrorMsg  string `json:"error_msg"`
+}
+
+type tokenResp struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
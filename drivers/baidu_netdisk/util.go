@@ -0,0 +1,374 @@
+package baidu_netdisk
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	api       = "https://pan.baidu.com/rest/2.0/xpan"
+	uploadApi = "https://d.pcs.baidu.com/rest/2.0/pcs/superfile2"
+	tokenApi  = "https://openapi.baidu.com/oauth/2.0/token"
+	// the user agent required by the official links
+	officialUA = "pan.baidu.com"
+	// the md5 of the first 256 KB is checked by the rapid upload
+	sliceSize = 256 * 1024
+)
+
+// blockSizes the size of the upload blocks by the vip type,
+// the number of blocks of a file is limited, so the larger blocks are allowed for vip
+var blockSizes = map[int]int64{
+	0: 4 * 1024 * 1024,
+	1: 16 * 1024 * 1024,
+	2: 32 * 1024 * 1024,
+}
+
+// errnos of the token is invalid or expired
+var tokenErrnos = map[int]bool{-6: true, 111: true, 110: true}
+
+// refreshToken exchange the refresh token for the access token,
+// the refresh token can be used only once, so the new one is returned
+func (d *BaiduNetdisk) refreshToken(ctx context.Context, refreshToken string) (*operations.OAuthToken, error) {
+	query := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {d.ClientID},
+		"client_secret": {d.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenApi+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed refresh token")
+	}
+	defer res.Body.Close()
+	var token tokenResp
+	if err := utils.Json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, errors.Wrapf(err, "failed decode token: %s", res.Status)
+	}
+	if token.Error == "invalid_grant" || token.Error == "expired_token" {
+		return nil, errors.Wrap(errs.RefreshTokenInvalid, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.Errorf("failed refresh token: %s %s", token.Error, token.ErrorDescription)
+	}
+	return &operations.OAuthToken{
+		AccessToken:  token.AccessToken,
+		Expiry:       time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+		RefreshToken: token.RefreshToken,
+	}, nil
+}
+
+// request call the api with the access token in the query and the form as the body,
+// the errno of the response is converted to errors
+func (d *BaiduNetdisk) request(ctx context.Context, method, u string, query, form url.Values, res interface{ errno() int }) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	for retried := false; ; retried = true {
+		token, err := d.tokens.Token(ctx)
+		if err != nil {
+			return err
+		}
+		query.Set("access_token", token)
+		var body io.Reader
+		if form != nil {
+			body = strings.NewReader(form.Encode())
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u+"?"+query.Encode(), body)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("User-Agent", officialUA)
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return errors.Wrap(err, "failed request baidu netdisk")
+		}
+		err = utils.Json.NewDecoder(resp.Body).Decode(res)
+		_ = resp.Body.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed decode baidu netdisk response: %s", resp.Status)
+		}
+		errno := res.errno()
+		switch {
+		case errno == 0:
+			return nil
+		// the token may be revoked before it's expired
+		case tokenErrnos[errno] && !retried:
+			d.tokens.Invalidate(token)
+			continue
+		case errno == -9 || errno == 31066:
+			return errors.WithStack(errs.ObjectNotFound)
+		case errno == -10:
+			return errors.Wrap(errs.QuotaExceeded, "failed request baidu netdisk: the space is full")
+		}
+		return errors.Errorf("failed request baidu netdisk: errno %d", errno)
+	}
+}
+
+func (d *BaiduNetdisk) getFiles(ctx context.Context, dir string) ([]file, error) {
+	query := url.Values{
+		"method": {"list"},
+		"dir":    {dir},
+		"web":    {"web"},
+		"limit":  {"1000"},
+	}
+	var files []file
+	for start := 0; ; start += 1000 {
+		query.Set("start", strconv.Itoa(start))
+		var res listResp
+		if err := d.request(ctx, http.MethodGet, api+"/file", query, nil, &res); err != nil {
+			return nil, err
+		}
+		files = append(files, res.List...)
+		if len(res.List) < 1000 {
+			return files, nil
+		}
+	}
+}
+
+// manage move, rename, copy or delete the files, filelist is the json of the params
+func (d *BaiduNetdisk) manage(ctx context.Context, opera string, filelist interface{}) error {
+	data, err := utils.Json.Marshal(filelist)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	query := url.Values{"method": {"filemanager"}, "opera": {opera}}
+	form := url.Values{"async": {"0"}, "filelist": {string(data)}, "ondup": {"fail"}}
+	var res errnoResp
+	return d.request(ctx, http.MethodPost, api+"/file", query, form, &res)
+}
+
+// create the dir or the file of the uploaded blocks, the existing file is overwritten
+func (d *BaiduNetdisk) create(ctx context.Context, path string, size int64, isDir bool, uploadID string, blockList []string) error {
+	form := url.Values{
+		"path":  {path},
+		"size":  {strconv.FormatInt(size, 10)},
+		"isdir": {"0"},
+		"rtype": {"3"},
+	}
+	if isDir {
+		form.Set("isdir", "1")
+	} else {
+		data, err := utils.Json.Marshal(blockList)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		form.Set("uploadid", uploadID)
+		form.Set("block_list", string(data))
+	}
+	var res errnoResp
+	return d.request(ctx, http.MethodPost, api+"/file", url.Values{"method": {"create"}}, form, &res)
+}
+
+func (d *BaiduNetdisk) linkOfficial(ctx context.Context, file *object) (*model.Link, error) {
+	query := url.Values{
+		"method": {"filemetas"},
+		"fsids":  {fmt.Sprintf("[%d]", file.FsID)},
+		"dlink":  {"1"},
+	}
+	var res metasResp
+	if err := d.request(ctx, http.MethodGet, api+"/multimedia", query, nil, &res); err != nil {
+		return nil, err
+	}
+	if len(res.List) == 0 {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	token, err := d.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Link{
+		URL:    res.List[0].Dlink + "&access_token=" + url.QueryEscape(token),
+		Header: http.Header{"User-Agent": {officialUA}},
+	}, nil
+}
+
+// linkCrack get the link by the api of the old clients, which isn't limited in speed
+// as long as it's downloaded with the user agent of the clients
+func (d *BaiduNetdisk) linkCrack(ctx context.Context, file *object) (*model.Link, error) {
+	data, err := utils.Json.Marshal([]string{file.GetID()})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	query := url.Values{
+		"target": {string(data)},
+		"dlink":  {"1"},
+		"web":    {"5"},
+		"origin": {"dlna"},
+	}
+	var res crackMetasResp
+	if err := d.request(ctx, http.MethodGet, "https://pan.baidu.com/api/filemetas", query, nil, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Info) == 0 {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	return &model.Link{
+		URL:    res.Info[0].Dlink,
+		Header: http.Header{"User-Agent": {d.CustomCrackUA}},
+	}, nil
+}
+
+// hashBlocks return the md5 of the blocks, the whole file and its first slice,
+// which are needed to create the upload
+func hashBlocks(f *os.File, size, blockSize int64) (blocks []string, contentMD5, sliceMD5 string, err error) {
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, "", "", errors.WithStack(err)
+	}
+	whole, slice := md5.New(), md5.New()
+	buf := make([]byte, blockSize)
+	for offset := int64(0); offset < size || offset == 0; offset += blockSize {
+		n := blockSize
+		if size-offset < n {
+			n = size - offset
+		}
+		if _, err = io.ReadFull(f, buf[:n]); err != nil {
+			return nil, "", "", errors.Wrapf(err, "failed read block at %d", offset)
+		}
+		block := md5.Sum(buf[:n])
+		blocks = append(blocks, hex.EncodeToString(block[:]))
+		whole.Write(buf[:n])
+		if offset == 0 {
+			s := n
+			if s > sliceSize {
+				s = sliceSize
+			}
+			slice.Write(buf[:s])
+		}
+		if size == 0 {
+			break
+		}
+	}
+	return blocks, hex.EncodeToString(whole.Sum(nil)), hex.EncodeToString(slice.Sum(nil)), nil
+}
+
+// upload cache the stream in a temp file to hash its blocks, only the blocks
+// the server doesn't have are uploaded if the rapid upload is enabled
+func (d *BaiduNetdisk) upload(ctx context.Context, path string, stream model.FileStreamer, up driver.UpdateProgress) error {
+	f, err := utils.CreateTempFile(stream)
+	if err != nil {
+		return errors.Wrap(err, "failed cache the stream")
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	size := stream.GetSize()
+	blocks, contentMD5, sliceMD5, err := hashBlocks(f, size, d.blockSize)
+	if err != nil {
+		return err
+	}
+	blockList, err := utils.Json.Marshal(blocks)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	form := url.Values{
+		"path":       {path},
+		"size":       {strconv.FormatInt(size, 10)},
+		"isdir":      {"0"},
+		"autoinit":   {"1"},
+		"rtype":      {"3"},
+		"block_list": {string(blockList)},
+	}
+	if d.RapidUpload {
+		form.Set("content-md5", contentMD5)
+		form.Set("slice-md5", sliceMD5)
+	}
+	var pre precreateResp
+	if err := d.request(ctx, http.MethodPost, api+"/file", url.Values{"method": {"precreate"}}, form, &pre); err != nil {
+		return errors.WithMessage(err, "failed precreate")
+	}
+	if pre.ReturnType == 2 {
+		return nil
+	}
+	buf := make([]byte, d.blockSize)
+	for i, seq := range pre.BlockList {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		offset := int64(seq) * d.blockSize
+		n := d.blockSize
+		if size-offset < n {
+			n = size - offset
+		}
+		if _, err := f.ReadAt(buf[:n], offset); err != nil && err != io.EOF {
+			return errors.Wrapf(err, "failed read block %d", seq)
+		}
+		if err := d.uploadBlock(ctx, path, pre.UploadID, seq, buf[:n]); err != nil {
+			return err
+		}
+		if up != nil {
+			up((i + 1) * 100 / len(pre.BlockList))
+		}
+	}
+	return errors.WithMessage(d.create(ctx, path, size, false, pre.UploadID, blocks), "failed create the uploaded file")
+}
+
+func (d *BaiduNetdisk) uploadBlock(ctx context.Context, path, uploadID string, seq int, data []byte) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "file")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	token, err := d.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"method":       {"upload"},
+		"access_token": {token},
+		"type":         {"tmpfile"},
+		"path":         {path},
+		"uploadid":     {uploadID},
+		"partseq":      {strconv.Itoa(seq)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadApi+"?"+query.Encode(), &body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	res, err := d.uploadClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed upload block %d", seq)
+	}
+	defer res.Body.Close()
+	var resp uploadBlockResp
+	if err := utils.Json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return errors.Wrapf(err, "failed decode the response of block %d: %s", seq, res.Status)
+	}
+	if resp.ErrorCode != 0 || resp.MD5 == "" {
+		return errors.Errorf("failed upload block %d: %d %s", seq, resp.ErrorCode, resp.ErrorMsg)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	stdpath "path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the same naming as rclone chunker with the default name format and simplejson metadata,
+// the chunks are numbered from 1 and the small object of the name keeps the metadata
+var chunkRe = regexp.MustCompile(`^(.+)\.rclone_chunk\.(\d{3,})(_[0-9a-z]{4,9})?$`)
+
+func chunkName(name string, i int) string {
+	return fmt.Sprintf("%s.rclone_chunk.%03d", name, i)
+}
+
+const (
+	// the newest metadata version of rclone, the 2nd adds the transaction id
+	metaVersion = 2
+	// the objects larger are not metadata, the same limit as rclone
+	maxMetaSize = 1023
+)
+
+// chunkMeta the metadata object of a composite file
+type chunkMeta struct {
+	Ver     int    `json:"ver"`
+	Size    int64  `json:"size"`
+	NChunks int    `json:"nchunks"`
+	MD5     string `json:"md5,omitempty"`
+	SHA1    string `json:"sha1,omitempty"`
+	Txn     string `json:"txn,omitempty"`
+}
+
+// rawChunkMeta is the metadata as it's stored, the missing fields are nil
+type rawChunkMeta struct {
+	Ver     *int   `json:"ver"`
+	Size    *int64 `json:"size"`
+	NChunks *int   `json:"nchunks"`
+	MD5     string `json:"md5,omitempty"`
+	SHA1    string `json:"sha1,omitempty"`
+	Txn     string `json:"txn,omitempty"`
+}
+
+// parseMeta parse and validate the metadata the same way as rclone
+func parseMeta(data []byte) (*chunkMeta, error) {
+	if len(data) > maxMetaSize {
+		return nil, errors.New("metadata is too big")
+	}
+	var raw rawChunkMeta
+	if err := utils.Json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "invalid metadata")
+	}
+	if raw.Ver == nil || raw.Size == nil || raw.NChunks == nil {
+		return nil, errors.New("invalid metadata: some fields are missing")
+	}
+	if *raw.Ver < 1 {
+		return nil, errors.Errorf("invalid metadata version %d", *raw.Ver)
+	}
+	if *raw.Ver > metaVersion {
+		return nil, errors.Errorf("metadata version %d is not supported", *raw.Ver)
+	}
+	if *raw.Size < 0 || *raw.NChunks < 1 {
+		return nil, errors.New("invalid metadata: bad size or number of chunks")
+	}
+	// the chunks of the transactions of rclone without renaming are named by the id
+	if raw.Txn != "" {
+		return nil, errors.New("the chunks named by the transaction are not supported")
+	}
+	return &chunkMeta{Ver: *raw.Ver, Size: *raw.Size, NChunks: *raw.NChunks, MD5: raw.MD5, SHA1: raw.SHA1}, nil
+}
+
+// checkMeta read the metadata of the composite file and reject it if it doesn't match the chunks
+func checkMeta(ctx context.Context, storage driver.Driver, path string, o *Object) error {
+	rc, err := operations.OpenRange(ctx, storage, path, 0, -1)
+	if err != nil {
+		return errors.WithMessage(err, "failed open metadata")
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxMetaSize+1))
+	if err != nil {
+		return errors.Wrap(err, "failed read metadata")
+	}
+	meta, err := parseMeta(data)
+	if err != nil {
+		return err
+	}
+	if meta.NChunks != len(o.chunks) {
+		return errors.Errorf("metadata has %d chunks, but %d are found", meta.NChunks, len(o.chunks))
+	}
+	if meta.Size != o.Size {
+		return errors.Errorf("metadata doesn't match file size: %d, %d", meta.Size, o.Size)
+	}
+	return nil
+}
+
+type chunk struct {
+	name string
+	size int64
+}
+
+// Object of the chunker, a composite file has chunks
+type Object struct {
+	model.Object
+	// hasMeta is false if the metadata object of the composite file is missing
+	hasMeta bool
+	chunks  []chunk
+}
+
+// parts the names of the objects of the file in the target storage
+func (o *Object) parts() []string {
+	var names []string
+	if o.chunks == nil || o.hasMeta {
+		names = append(names, o.Name)
+	}
+	for _, c := range o.chunks {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// group the objs of the target dir into the files of the chunker, the id is the path in the chunker
+func group(dir string, objs []model.Obj) ([]model.Obj, []string) {
+	type composite struct {
+		chunks map[int]chunk
+		last   model.Obj
+	}
+	composites := make(map[string]*composite)
+	var plain []model.Obj
+	for _, obj := range objs {
+		m := chunkRe.FindStringSubmatch(obj.GetName())
+		if obj.IsDir() || m == nil {
+			plain = append(plain, obj)
+			continue
+		}
+		// the temporary chunks of an unfinished upload of rclone
+		if m[3] != "" {
+			continue
+		}
+		i, _ := strconv.Atoi(m[2])
+		c, ok := composites[m[1]]
+		if !ok {
+			c = &composite{chunks: make(map[int]chunk)}
+			composites[m[1]] = c
+		}
+		c.chunks[i] = chunk{name: obj.GetName(), size: obj.GetSize()}
+		if c.last == nil || obj.ModTime().After(c.last.ModTime()) {
+			c.last = obj
+		}
+	}
+	var (
+		res     []model.Obj
+		invalid []string
+	)
+	toObject := func(name string, c *composite, meta model.Obj) {
+		o := &Object{Object: model.Object{ID: stdpath.Join(dir, name), Name: name, Modified: c.last.ModTime()}}
+		for i := 1; i <= len(c.chunks); i++ {
+			ch, ok := c.chunks[i]
+			if !ok {
+				invalid = append(invalid, name)
+				return
+			}
+			o.chunks = append(o.chunks, ch)
+			o.Size += ch.size
+		}
+		if meta != nil {
+			// a data file with the name of the chunks, rclone refuses them too
+			if meta.GetSize() > maxMetaSize {
+				invalid = append(invalid, name)
+				return
+			}
+			o.hasMeta, o.Modified = true, meta.ModTime()
+		}
+		res = append(res, o)
+	}
+	for _, obj := range plain {
+		name := obj.GetName()
+		if c, ok := composites[name]; ok && !obj.IsDir() {
+			toObject(name, c, obj)
+			delete(composites, name)
+			continue
+		}
+		res = append(res, &Object{Object: model.Object{
+			ID:       stdpath.Join(dir, name),
+			Name:     name,
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			IsFolder: obj.IsDir(),
+		}})
+	}
+	// the metadata object is missing, e.g. the chunks are made by rclone without metadata
+	names := make([]string, 0, len(composites))
+	for name := range composites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		toObject(name, composites[name], nil)
+	}
+	return res, invalid
+}
+
+type rangePart struct {
+	name   string
+	offset int64
+	length int64
+}
+
+// chunkReader read the parts of the chunks one by one, a chunk is opened when it's reached
+type chunkReader struct {
+	ctx     context.Context
+	storage driver.Driver
+	dir     string
+	parts   []rangePart
+	cur     io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			part := r.parts[0]
+			rc, err := operations.OpenRange(r.ctx, r.storage, stdpath.Join(r.dir, part.name), part.offset, part.length)
+			if err != nil {
+				return 0, errors.WithMessagef(err, "failed open chunk %s", part.name)
+			}
+			r.cur, r.parts = rc, r.parts[1:]
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			_ = r.cur.Close()
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	return r.cur.Close()
+}
@@ -0,0 +1,358 @@
+package chunker
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Chunker split the files larger than the chunk size into chunks stored in another storage,
+// for the storages limiting the size of the files, compatible with rclone chunker
+type Chunker struct {
+	model.Storage
+	Addition
+}
+
+func (d *Chunker) Config() driver.Config {
+	return config
+}
+
+func (d *Chunker) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if d.Path == "" {
+		return errors.New("path is required")
+	}
+	if d.ChunkSize <= 0 {
+		return errors.New("chunk size must be positive")
+	}
+	d.Path = utils.StandardizePath(d.Path)
+	return operations.CheckAliasLoop(d.MountPath, d.Path)
+}
+
+func (d *Chunker) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *Chunker) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *Chunker) GetAliasPath() string {
+	return d.Path
+}
+
+func (d *Chunker) chunkSize() int64 {
+	return int64(d.ChunkSize) << 20
+}
+
+// resolve get the storage and actual path of the path in the chunker
+func (d *Chunker) resolve(ctx context.Context, path string) (context.Context, driver.Driver, string, error) {
+	ctx, err := operations.EnterAlias(ctx, d.MountPath)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, stdpath.Join(d.Path, path))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return ctx, storage, actualPath, nil
+}
+
+// resolvePair resolve the paths, which must be in the same target storage
+func (d *Chunker) resolvePair(ctx context.Context, src, dst string) (context.Context, driver.Driver, string, string, error) {
+	ctx, storage, srcPath, err := d.resolve(ctx, src)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	_, dstStorage, dstPath, err := d.resolve(ctx, dst)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	if storage.GetStorage().MountPath != dstStorage.GetStorage().MountPath {
+		return nil, nil, "", "", errors.WithMessage(errs.NotSupport, "the paths are in different storages")
+	}
+	return ctx, storage, srcPath, dstPath, nil
+}
+
+// toObject get the obj of the chunker, the objs from the caches of alist are got again
+func (d *Chunker) toObject(ctx context.Context, obj model.Obj) (*Object, error) {
+	if o, ok := obj.(*Object); ok {
+		return o, nil
+	}
+	got, err := d.Get(ctx, obj.GetID())
+	if err != nil {
+		return nil, err
+	}
+	return got.(*Object), nil
+}
+
+func (d *Chunker) Get(ctx context.Context, path string) (model.Obj, error) {
+	path = utils.StandardizePath(path)
+	if path == "/" {
+		return &Object{Object: model.Object{ID: "/", Name: "root", Modified: d.Modified, IsFolder: true}}, nil
+	}
+	// the chunks are next to the file, so the parent is listed
+	objs, err := d.List(ctx, &model.Object{ID: stdpath.Dir(path), IsFolder: true})
+	if err != nil {
+		return nil, err
+	}
+	name := stdpath.Base(path)
+	for _, obj := range objs {
+		if obj.GetName() == name {
+			return obj, nil
+		}
+	}
+	return nil, errors.WithStack(errs.ObjectNotFound)
+}
+
+func (d *Chunker) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	ctx, storage, actualPath, err := d.resolve(ctx, dir.GetID())
+	if err != nil {
+		return nil, err
+	}
+	objs, err := operations.List(ctx, storage, actualPath)
+	if err != nil {
+		return nil, err
+	}
+	res, invalid := group(dir.GetID(), objs)
+	for _, name := range invalid {
+		// rclone refuses them too
+		log.Warnf("skip %s in [%s]: some chunks are missing", name, d.MountPath)
+	}
+	return res, nil
+}
+
+// Link join the parts of the chunks covering the range
+func (d *Chunker) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	o, err := d.toObject(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	ctx, storage, actualPath, err := d.resolve(ctx, o.GetID())
+	if err != nil {
+		return nil, err
+	}
+	if o.chunks == nil {
+		link, _, err := operations.Link(ctx, storage, actualPath, args)
+		return link, err
+	}
+	if o.hasMeta {
+		if err := checkMeta(ctx, storage, actualPath, o); err != nil {
+			return nil, err
+		}
+	}
+	size := o.GetSize()
+	if size == 0 {
+		return &model.Link{Data: io.NopCloser(strings.NewReader(""))}, nil
+	}
+	start, end, ranged := utils.ParseRange(args.Header.Get("Range"), size)
+	if !ranged {
+		start, end = 0, size-1
+	}
+	r := &chunkReader{ctx: ctx, storage: storage, dir: stdpath.Dir(actualPath)}
+	var offset int64
+	for _, c := range o.chunks {
+		from, to := start, end
+		if from < offset {
+			from = offset
+		}
+		if to > offset+c.size-1 {
+			to = offset + c.size - 1
+		}
+		if from <= to {
+			r.parts = append(r.parts, rangePart{name: c.name, offset: from - offset, length: to - from + 1})
+		}
+		offset += c.size
+	}
+	link := &model.Link{Data: r}
+	if ranged {
+		link.Status = http.StatusPartialContent
+		link.Header = http.Header{
+			"Content-Range":  []string{fmt.Sprintf("bytes %d-%d/%d", start, end, size)},
+			"Content-Length": []string{strconv.FormatInt(end-start+1, 10)},
+			"Accept-Ranges":  []string{"bytes"},
+		}
+	}
+	return link, nil
+}
+
+func (d *Chunker) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, stdpath.Join(parentDir.GetID(), dirName))
+	if err != nil {
+		return err
+	}
+	return operations.MakeDir(ctx, storage, actualPath)
+}
+
+func (d *Chunker) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	o, err := d.toObject(ctx, srcObj)
+	if err != nil {
+		return err
+	}
+	ctx, storage, srcPath, dstPath, err := d.resolvePair(ctx, o.GetID(), dstDir.GetID())
+	if err != nil {
+		return err
+	}
+	for _, name := range o.parts() {
+		if err := operations.Move(ctx, storage, stdpath.Join(stdpath.Dir(srcPath), name), dstPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Chunker) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	o, err := d.toObject(ctx, srcObj)
+	if err != nil {
+		return err
+	}
+	ctx, storage, actualPath, err := d.resolve(ctx, o.GetID())
+	if err != nil {
+		return err
+	}
+	if o.chunks == nil || o.hasMeta {
+		if err := operations.Rename(ctx, storage, actualPath, newName); err != nil {
+			return err
+		}
+	}
+	for i, c := range o.chunks {
+		if err := operations.Rename(ctx, storage, stdpath.Join(stdpath.Dir(actualPath), c.name), chunkName(newName, i+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Chunker) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	o, err := d.toObject(ctx, srcObj)
+	if err != nil {
+		return err
+	}
+	ctx, storage, srcPath, dstPath, err := d.resolvePair(ctx, o.GetID(), dstDir.GetID())
+	if err != nil {
+		return err
+	}
+	for _, name := range o.parts() {
+		if err := operations.Copy(ctx, storage, stdpath.Join(stdpath.Dir(srcPath), name), dstPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Chunker) Remove(ctx context.Context, obj model.Obj) error {
+	o, err := d.toObject(ctx, obj)
+	if err != nil {
+		return err
+	}
+	ctx, storage, actualPath, err := d.resolve(ctx, o.GetID())
+	if err != nil {
+		return err
+	}
+	for _, name := range o.parts() {
+		if err := operations.Remove(ctx, storage, stdpath.Join(stdpath.Dir(actualPath), name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put store the file as it is if it's not larger than the chunk size, otherwise upload
+// the chunks in order and then the metadata, the chunks left by the old file are removed
+func (d *Chunker) Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) error {
+	var old []chunk
+	if o, err := d.Get(ctx, stdpath.Join(dstDir.GetID(), file.GetName())); err == nil && !o.IsDir() {
+		old = o.(*Object).chunks
+	}
+	ctx, storage, actualPath, err := d.resolve(ctx, dstDir.GetID())
+	if err != nil {
+		return err
+	}
+	size, chunkSize := file.GetSize(), d.chunkSize()
+	var nChunks int
+	if size <= chunkSize {
+		// the file is closed by the caller of the chunker, not the target
+		err = operations.Put(ctx, storage, actualPath, &model.FileStream{
+			Obj:        model.Object{Name: file.GetName(), Size: size, Modified: file.ModTime()},
+			ReadCloser: io.NopCloser(file),
+			Mimetype:   file.GetMimetype(),
+		}, up)
+	} else {
+		nChunks, err = d.putChunks(ctx, storage, actualPath, file, up)
+	}
+	if err != nil {
+		return err
+	}
+	for i, c := range old {
+		if i < nChunks {
+			continue
+		}
+		// the chunks left would be taken as a part of the new file
+		if err := operations.Remove(ctx, storage, stdpath.Join(actualPath, c.name)); err != nil {
+			return errors.WithMessagef(err, "failed remove the old chunk %s", c.name)
+		}
+	}
+	return nil
+}
+
+func (d *Chunker) putChunks(ctx context.Context, storage driver.Driver, dir string, file model.FileStreamer, up driver.UpdateProgress) (int, error) {
+	size, chunkSize := file.GetSize(), d.chunkSize()
+	h := md5.New()
+	r := io.TeeReader(file, h)
+	n := int((size + chunkSize - 1) / chunkSize)
+	for i := 0; i < n; i++ {
+		offset := int64(i) * chunkSize
+		length := chunkSize
+		if size-offset < length {
+			length = size - offset
+		}
+		err := operations.Put(ctx, storage, dir, &model.FileStream{
+			Obj:        model.Object{Name: chunkName(file.GetName(), i+1), Size: length, Modified: file.ModTime()},
+			ReadCloser: io.NopCloser(io.LimitReader(r, length)),
+			Mimetype:   "application/octet-stream",
+		}, func(p int) {
+			if up != nil {
+				up(int((offset + length*int64(p)/100) * 100 / size))
+			}
+		})
+		if err != nil {
+			return 0, errors.WithMessagef(err, "failed upload chunk %d", i+1)
+		}
+	}
+	meta, err := utils.Json.Marshal(chunkMeta{Ver: 1, Size: size, NChunks: n, MD5: hex.EncodeToString(h.Sum(nil))})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	err = operations.Put(ctx, storage, dir, &model.FileStream{
+		Obj:        model.Object{Name: file.GetName(), Size: int64(len(meta)), Modified: file.ModTime()},
+		ReadCloser: io.NopCloser(strings.NewReader(string(meta))),
+		Mimetype:   "application/json",
+	}, nil)
+	return n, errors.WithMessage(err, "failed upload metadata")
+}
+
+func (d *Chunker) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*Chunker)(nil)
+var _ driver.Getter = (*Chunker)(nil)
+var _ driver.Alias = (*Chunker)(nil)
@@ -0,0 +1,100 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/drivers/drivertest"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+func TestParseMeta(t *testing.T) {
+	for _, c := range []struct {
+		data  string
+		valid bool
+	}{
+		{`{"ver":1,"size":3,"nchunks":1}`, true},
+		{`{"ver":2,"size":3,"nchunks":2,"md5":"x"}`, true},
+		{`{"ver":1,"size":3}`, false},
+		{`{"ver":0,"size":3,"nchunks":1}`, false},
+		{`{"ver":3,"size":3,"nchunks":1}`, false},
+		{`{"ver":1,"size":-1,"nchunks":1}`, false},
+		{`{"ver":1,"size":3,"nchunks":0}`, false},
+		{`{"ver":2,"size":3,"nchunks":1,"txn":"abcd"}`, false},
+		{`not json`, false},
+		{`{"ver":1,"size":3,"nchunks":1,"md5":"` + strings.Repeat("0", maxMetaSize) + `"}`, false},
+	} {
+		if _, err := parseMeta([]byte(c.data)); (err == nil) != c.valid {
+			t.Errorf("expected the validity of %.40s is %v, got %+v", c.data, c.valid, err)
+		}
+	}
+}
+
+func TestChunker(t *testing.T) {
+	dir, s := drivertest.Mount(t, "/chunker", "Chunker", map[string]interface{}{"chunk_size": 1})
+	content := []byte(strings.Repeat("0123456789", 250000))
+	put := func(name string, content []byte) {
+		stream := &model.FileStream{
+			Obj:        model.Object{Name: name, Size: int64(len(content))},
+			ReadCloser: io.NopCloser(bytes.NewReader(content)),
+		}
+		if err := operations.Put(context.Background(), s, "/", stream, nil); err != nil {
+			t.Fatalf("failed put: %+v", err)
+		}
+	}
+	put("movie.mkv", content)
+	// 3 chunks of 1MB at most and the metadata
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 4 {
+		t.Fatalf("expected the file is split, got %+v %+v", entries, err)
+	}
+	objs, err := operations.List(context.Background(), s, "/")
+	if err != nil || len(objs) != 1 || objs[0].GetName() != "movie.mkv" || objs[0].GetSize() != int64(len(content)) {
+		t.Fatalf("unexpected list: %+v %+v", objs, err)
+	}
+	for _, c := range []struct {
+		rangeHeader string
+		start, end  int
+	}{
+		{"", 0, len(content) - 1},
+		{"bytes=1048570-1048589", 1048570, 1048589},
+		{"bytes=-5", len(content) - 5, len(content) - 1},
+	} {
+		link, _, err := operations.Link(context.Background(), s, "/movie.mkv", model.LinkArgs{Header: http.Header{"Range": []string{c.rangeHeader}}})
+		if err != nil {
+			t.Fatalf("failed link: %+v", err)
+		}
+		data, err := io.ReadAll(link.Data)
+		_ = link.Data.Close()
+		if err != nil || !bytes.Equal(data, content[c.start:c.end+1]) {
+			t.Errorf("unexpected content of range %q: %d bytes %+v", c.rangeHeader, len(data), err)
+		}
+	}
+	// a chunk not counted in the metadata, the file is refused like rclone does
+	extra := filepath.Join(dir, "movie.mkv.rclone_chunk.004")
+	if err := os.WriteFile(extra, []byte("0"), 0600); err != nil {
+		t.Fatalf("failed write chunk: %+v", err)
+	}
+	drivertest.ClearCache(t, "/chunker")
+	if _, _, err := operations.Link(context.Background(), s, "/movie.mkv", model.LinkArgs{Header: http.Header{}}); err == nil {
+		t.Errorf("expected the chunks not matching the metadata are refused")
+	}
+	_ = os.Remove(extra)
+	drivertest.ClearCache(t, "/chunker")
+	if err := operations.Rename(context.Background(), s, "/movie.mkv", "film.mkv"); err != nil {
+		t.Fatalf("failed rename: %+v", err)
+	}
+	// overwritten by a small file, the old chunks are removed
+	put("film.mkv", content[:100])
+	entries, err = os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || entries[0].Name() != "film.mkv" {
+		t.Fatalf("expected only the small file is left, got %+v %+v", entries, err)
+	}
+}
@@ -0,0 +1,28 @@
+package chunker
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	Path      string `json:"path" required:"true" help:"the virtual path to store the chunks, such as /webdav/chunks"`
+	ChunkSize int    `json:"chunk_size" type:"number" default:"2048" help:"MB, the larger files are split into chunks of the size"`
+}
+
+var config = driver.Config{
+	Name:      "Chunker",
+	LocalSort: true,
+	// the chunks are joined by alist
+	OnlyProxy: true,
+	// the target storage caches already
+	NoCache: true,
+}
+
+func New() driver.Driver {
+	return &Chunker{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
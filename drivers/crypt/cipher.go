@@ -0,0 +1,274 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// the format of rclone crypt, so the remotes encrypted by rclone can be read and the other way round
+const (
+	fileMagic       = "RCLONE\x00\x00"
+	fileNonceSize   = 24
+	fileHeaderSize  = len(fileMagic) + fileNonceSize
+	blockHeaderSize = secretbox.Overhead
+	blockDataSize   = 64 * 1024
+	blockSize       = blockHeaderSize + blockDataSize
+	nameBlockSize   = aes.BlockSize
+	// the longest name rclone encrypts
+	maxNameSize = 2048
+)
+
+// the salt of rclone if the password2 is empty
+var defaultSalt = []byte{0xA8, 0x0D, 0xF4, 0x3A, 0x8F, 0xBD, 0x03, 0x08, 0xA7, 0xCA, 0xB8, 0x3E, 0x58, 0x1F, 0x86, 0xB1}
+
+var nameEncoding = base32.HexEncoding.WithPadding(base32.NoPadding)
+
+var errCorrupted = errors.New("the encrypted data is corrupted")
+
+type cipherKeys struct {
+	dataKey   [32]byte
+	nameKey   [32]byte
+	nameTweak [nameBlockSize]byte
+	block     cipher.Block
+}
+
+// newCipherKeys derive the keys from the passwords like rclone
+func newCipherKeys(password, salt string) (*cipherKeys, error) {
+	c := &cipherKeys{}
+	keySize := len(c.dataKey) + len(c.nameKey) + len(c.nameTweak)
+	saltBytes := defaultSalt
+	if salt != "" {
+		saltBytes = []byte(salt)
+	}
+	key := make([]byte, keySize)
+	if password != "" {
+		var err error
+		if key, err = scrypt.Key([]byte(password), saltBytes, 16384, 8, 1, keySize); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	copy(c.dataKey[:], key)
+	copy(c.nameKey[:], key[len(c.dataKey):])
+	copy(c.nameTweak[:], key[len(c.dataKey)+len(c.nameKey):])
+	block, err := aes.NewCipher(c.nameKey[:])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c.block = block
+	return c, nil
+}
+
+// encryptName encrypt a segment of the path, padded by pkcs7 and encoded by base32hex in lowercase
+func (c *cipherKeys) encryptName(name string) string {
+	if name == "" {
+		return ""
+	}
+	pad := nameBlockSize - len(name)%nameBlockSize
+	padded := append([]byte(name), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	return strings.ToLower(nameEncoding.EncodeToString(emeTransform(c.block, c.nameTweak[:], padded, true)))
+}
+
+func (c *cipherKeys) decryptName(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	data, err := nameEncoding.DecodeString(strings.ToUpper(name))
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid encrypted name %s", name)
+	}
+	if len(data) == 0 || len(data)%nameBlockSize != 0 || len(data) > maxNameSize {
+		return "", errors.Errorf("invalid length of encrypted name %s", name)
+	}
+	plain := emeTransform(c.block, c.nameTweak[:], data, false)
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > nameBlockSize || pad > len(plain) {
+		return "", errors.Errorf("invalid padding of encrypted name %s", name)
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return "", errors.Errorf("invalid padding of encrypted name %s", name)
+		}
+	}
+	return string(plain[:len(plain)-pad]), nil
+}
+
+// encryptedSize the size of the file encrypted from the size
+func encryptedSize(size int64) int64 {
+	blocks, residue := size/blockDataSize, size%blockDataSize
+	encrypted := int64(fileHeaderSize) + blocks*blockSize
+	if residue != 0 {
+		encrypted += int64(blockHeaderSize) + residue
+	}
+	return encrypted
+}
+
+// decryptedSize the size of the file decrypted from the size
+func decryptedSize(size int64) (int64, error) {
+	size -= int64(fileHeaderSize)
+	if size < 0 {
+		return 0, errCorrupted
+	}
+	blocks, residue := size/blockSize, size%blockSize
+	decrypted := blocks * blockDataSize
+	if residue != 0 {
+		residue -= int64(blockHeaderSize)
+		if residue <= 0 {
+			return 0, errCorrupted
+		}
+		decrypted += residue
+	}
+	return decrypted, nil
+}
+
+type nonce [fileNonceSize]byte
+
+// carry add 1 to the nonce from the byte i, in little endian
+func (n *nonce) carry(i int) {
+	for ; i < len(n); i++ {
+		n[i]++
+		if n[i] != 0 {
+			break
+		}
+	}
+}
+
+func (n *nonce) increment() {
+	n.carry(0)
+}
+
+// add the x to the lowest 8 bytes of the nonce, in little endian
+func (n *nonce) add(x uint64) {
+	var carry uint16
+	for i := 0; i < 8; i++ {
+		carry += uint16(n[i]) + uint16(byte(x))
+		x >>= 8
+		n[i] = byte(carry)
+		carry >>= 8
+	}
+	if carry != 0 {
+		n.carry(8)
+	}
+}
+
+// encrypter encrypt the plaintext block by block
+type encrypter struct {
+	r      io.Reader
+	key    *[32]byte
+	nonce  nonce
+	in     []byte
+	sealed []byte
+	out    []byte
+	err    error
+}
+
+func (c *cipherKeys) newEncrypter(r io.Reader) (*encrypter, error) {
+	e := &encrypter{r: r, key: &c.dataKey, in: make([]byte, blockDataSize)}
+	if _, err := io.ReadFull(rand.Reader, e.nonce[:]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	e.out = append(append(e.out, fileMagic...), e.nonce[:]...)
+	return e, nil
+}
+
+func (e *encrypter) Read(p []byte) (int, error) {
+	if len(e.out) == 0 {
+		if e.err != nil {
+			return 0, e.err
+		}
+		n, err := io.ReadFull(e.r, e.in)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			e.err = io.EOF
+		} else if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, e.err
+		}
+		e.sealed = secretbox.Seal(e.sealed[:0], e.in[:n], (*[24]byte)(&e.nonce), e.key)
+		e.out = e.sealed
+		e.nonce.increment()
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+// decrypter decrypt the blocks from the block of the nonce, skip the bytes of the first block
+type decrypter struct {
+	rc    io.ReadCloser
+	key   *[32]byte
+	nonce nonce
+	skip  int
+	in    []byte
+	out   []byte
+	buf   []byte
+	err   error
+}
+
+func (c *cipherKeys) newDecrypter(rc io.ReadCloser, n nonce, skip int) *decrypter {
+	return &decrypter{rc: rc, key: &c.dataKey, nonce: n, skip: skip, in: make([]byte, blockSize), buf: make([]byte, 0, blockDataSize)}
+}
+
+func (d *decrypter) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		n, err := io.ReadFull(d.rc, d.in)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			d.err = io.EOF
+		} else if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, d.err
+		}
+		if n <= blockHeaderSize {
+			d.err = errCorrupted
+			return 0, d.err
+		}
+		out, ok := secretbox.Open(d.buf[:0], d.in[:n], (*[24]byte)(&d.nonce), d.key)
+		if !ok {
+			d.err = errors.WithMessage(errCorrupted, "failed authenticate the block, the password may be wrong")
+			return 0, d.err
+		}
+		d.nonce.increment()
+		if d.skip > 0 {
+			if d.skip > len(out) {
+				d.skip = len(out)
+			}
+			out, d.skip = out[d.skip:], 0
+		}
+		d.out = out
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *decrypter) Close() error {
+	return d.rc.Close()
+}
+
+// readHeader read the nonce of the file from the header
+func readHeader(r io.Reader) (nonce, error) {
+	var n nonce
+	header := make([]byte, fileHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return n, errors.WithMessage(errCorrupted, "failed read the header")
+	}
+	if string(header[:len(fileMagic)]) != fileMagic {
+		return n, errors.WithMessage(errCorrupted, "not encrypted by rclone crypt")
+	}
+	copy(n[:], header[len(fileMagic):])
+	return n, nil
+}
@@ -0,0 +1,343 @@
+package crypt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Crypt encrypt the contents and the names of the files stored in another storage,
+// compatible with rclone crypt
+type Crypt struct {
+	model.Storage
+	Addition
+	cipher *cipherKeys
+}
+
+func (d *Crypt) Config() driver.Config {
+	return config
+}
+
+func (d *Crypt) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if d.Path == "" {
+		return errors.New("path is required")
+	}
+	if d.Password == "" {
+		return errors.New("password is required")
+	}
+	if d.FilenameEncryption != "standard" && d.FilenameEncryption != "off" {
+		return errors.Errorf("unsupported filename encryption: %s", d.FilenameEncryption)
+	}
+	d.Path = utils.StandardizePath(d.Path)
+	if d.cipher, err = newCipherKeys(d.Password, d.Password2); err != nil {
+		return err
+	}
+	return operations.CheckAliasLoop(d.MountPath, d.Path)
+}
+
+func (d *Crypt) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *Crypt) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *Crypt) GetAliasPath() string {
+	return d.Path
+}
+
+func (d *Crypt) encryptFileName(name string) string {
+	if d.FilenameEncryption == "off" {
+		return name + ".bin"
+	}
+	return d.cipher.encryptName(name)
+}
+
+func (d *Crypt) decryptFileName(name string) (string, error) {
+	if d.FilenameEncryption == "off" {
+		if !strings.HasSuffix(name, ".bin") {
+			return "", errors.Errorf("%s is not an encrypted file", name)
+		}
+		return strings.TrimSuffix(name, ".bin"), nil
+	}
+	return d.cipher.decryptName(name)
+}
+
+func (d *Crypt) encryptDirName(name string) string {
+	if d.FilenameEncryption == "off" || !d.DirectoryNameEncryption {
+		return name
+	}
+	return d.cipher.encryptName(name)
+}
+
+func (d *Crypt) decryptDirName(name string) (string, error) {
+	if d.FilenameEncryption == "off" || !d.DirectoryNameEncryption {
+		return name, nil
+	}
+	return d.cipher.decryptName(name)
+}
+
+// encryptPath encrypt the path of a dir, or a file if isFile, every segment is encrypted alone
+func (d *Crypt) encryptPath(path string, isFile bool) string {
+	segments := strings.Split(strings.Trim(utils.StandardizePath(path), "/"), "/")
+	for i, segment := range segments {
+		if isFile && i == len(segments)-1 {
+			segments[i] = d.encryptFileName(segment)
+		} else {
+			segments[i] = d.encryptDirName(segment)
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// resolve get the storage and actual path of the encrypted path
+func (d *Crypt) resolve(ctx context.Context, encrypted string) (context.Context, driver.Driver, string, error) {
+	ctx, err := operations.EnterAlias(ctx, d.MountPath)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, stdpath.Join(d.Path, encrypted))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return ctx, storage, actualPath, nil
+}
+
+// resolvePair resolve the paths, which must be in the same target storage
+func (d *Crypt) resolvePair(ctx context.Context, src, dst string) (context.Context, driver.Driver, string, string, error) {
+	ctx, storage, srcPath, err := d.resolve(ctx, src)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	_, dstStorage, dstPath, err := d.resolve(ctx, dst)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	if storage.GetStorage().MountPath != dstStorage.GetStorage().MountPath {
+		return nil, nil, "", "", errors.WithMessage(errs.NotSupport, "the paths are in different storages")
+	}
+	return ctx, storage, srcPath, dstPath, nil
+}
+
+// decrypt the obj of the target storage, the id is the path in the crypt
+func (d *Crypt) decrypt(dir string, obj model.Obj) (model.Obj, error) {
+	if obj.IsDir() {
+		name, err := d.decryptDirName(obj.GetName())
+		if err != nil {
+			return nil, err
+		}
+		return &model.Object{ID: stdpath.Join(dir, name), Name: name, Modified: obj.ModTime(), IsFolder: true}, nil
+	}
+	name, err := d.decryptFileName(obj.GetName())
+	if err != nil {
+		return nil, err
+	}
+	size, err := decryptedSize(obj.GetSize())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid size of %s", name)
+	}
+	return &model.Object{ID: stdpath.Join(dir, name), Name: name, Size: size, Modified: obj.ModTime()}, nil
+}
+
+func (d *Crypt) Get(ctx context.Context, path string) (model.Obj, error) {
+	path = utils.StandardizePath(path)
+	if path == "/" {
+		return &model.Object{ID: "/", Name: "root", Modified: d.Modified, IsFolder: true}, nil
+	}
+	// the file and the dir of the same name are encrypted differently unless the names are encrypted the same way
+	candidates := []string{d.encryptPath(path, true)}
+	if dirPath := d.encryptPath(path, false); dirPath != candidates[0] {
+		candidates = append(candidates, dirPath)
+	}
+	var lastErr error
+	for _, encrypted := range candidates {
+		resolveCtx, storage, actualPath, err := d.resolve(ctx, encrypted)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := operations.Get(resolveCtx, storage, actualPath)
+		if err == nil {
+			return d.decrypt(stdpath.Dir(path), obj)
+		}
+		if !errs.IsObjectNotFound(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (d *Crypt) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	ctx, storage, actualPath, err := d.resolve(ctx, d.encryptPath(dir.GetID(), false))
+	if err != nil {
+		return nil, err
+	}
+	objs, err := operations.List(ctx, storage, actualPath)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		decrypted, err := d.decrypt(dir.GetID(), obj)
+		if err != nil {
+			// such as the files not encrypted, rclone skips them too
+			log.Debugf("skip %s in [%s]: %+v", obj.GetName(), d.MountPath, err)
+			continue
+		}
+		res = append(res, decrypted)
+	}
+	return res, nil
+}
+
+// Link decrypt the blocks covering the range, the nonce of the first block is the nonce
+// in the header added by the index of the block
+func (d *Crypt) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	ctx, storage, actualPath, err := d.resolve(ctx, d.encryptPath(file.GetID(), true))
+	if err != nil {
+		return nil, err
+	}
+	size := file.GetSize()
+	if size == 0 {
+		return &model.Link{Data: io.NopCloser(strings.NewReader(""))}, nil
+	}
+	start, end, ranged := utils.ParseRange(args.Header.Get("Range"), size)
+	if !ranged {
+		start, end = 0, size-1
+	}
+	firstBlock, lastBlock := start/blockDataSize, end/blockDataSize
+	offset := int64(fileHeaderSize) + firstBlock*blockSize
+	length := (lastBlock - firstBlock + 1) * blockSize
+	var (
+		rc io.ReadCloser
+		n  nonce
+	)
+	if firstBlock == 0 {
+		// the header is read with the blocks
+		offset, length = 0, length+int64(fileHeaderSize)
+	} else {
+		header, err := operations.OpenRange(ctx, storage, actualPath, 0, int64(fileHeaderSize))
+		if err != nil {
+			return nil, err
+		}
+		n, err = readHeader(header)
+		_ = header.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rc, err = operations.OpenRange(ctx, storage, actualPath, offset, length); err != nil {
+		return nil, err
+	}
+	if firstBlock == 0 {
+		if n, err = readHeader(rc); err != nil {
+			_ = rc.Close()
+			return nil, err
+		}
+	}
+	n.add(uint64(firstBlock))
+	dec := d.cipher.newDecrypter(rc, n, int(start-firstBlock*blockDataSize))
+	link := &model.Link{Data: utils.ReadCloser{Reader: io.LimitReader(dec, end-start+1), Closer: dec}}
+	if ranged {
+		link.Status = http.StatusPartialContent
+		link.Header = http.Header{
+			"Content-Range":  []string{fmt.Sprintf("bytes %d-%d/%d", start, end, size)},
+			"Content-Length": []string{strconv.FormatInt(end-start+1, 10)},
+			"Accept-Ranges":  []string{"bytes"},
+		}
+	}
+	return link, nil
+}
+
+func (d *Crypt) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, d.encryptPath(stdpath.Join(parentDir.GetID(), dirName), false))
+	if err != nil {
+		return err
+	}
+	return operations.MakeDir(ctx, storage, actualPath)
+}
+
+func (d *Crypt) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ctx, storage, srcPath, dstPath, err := d.resolvePair(ctx, d.encryptPath(srcObj.GetID(), !srcObj.IsDir()), d.encryptPath(dstDir.GetID(), false))
+	if err != nil {
+		return err
+	}
+	return operations.Move(ctx, storage, srcPath, dstPath)
+}
+
+func (d *Crypt) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, d.encryptPath(srcObj.GetID(), !srcObj.IsDir()))
+	if err != nil {
+		return err
+	}
+	if srcObj.IsDir() {
+		newName = d.encryptDirName(newName)
+	} else {
+		newName = d.encryptFileName(newName)
+	}
+	return operations.Rename(ctx, storage, actualPath, newName)
+}
+
+func (d *Crypt) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ctx, storage, srcPath, dstPath, err := d.resolvePair(ctx, d.encryptPath(srcObj.GetID(), !srcObj.IsDir()), d.encryptPath(dstDir.GetID(), false))
+	if err != nil {
+		return err
+	}
+	return operations.Copy(ctx, storage, srcPath, dstPath)
+}
+
+func (d *Crypt) Remove(ctx context.Context, obj model.Obj) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, d.encryptPath(obj.GetID(), !obj.IsDir()))
+	if err != nil {
+		return err
+	}
+	return operations.Remove(ctx, storage, actualPath)
+}
+
+// Put encrypt the stream while it's uploaded to the target storage, the size is known beforehand
+func (d *Crypt) Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) error {
+	ctx, storage, actualPath, err := d.resolve(ctx, d.encryptPath(dstDir.GetID(), false))
+	if err != nil {
+		return err
+	}
+	e, err := d.cipher.newEncrypter(file)
+	if err != nil {
+		return err
+	}
+	// the file is closed by the caller of the crypt, not the target
+	encrypted := &model.FileStream{
+		Obj: model.Object{
+			Name:     d.encryptFileName(file.GetName()),
+			Size:     encryptedSize(file.GetSize()),
+			Modified: file.ModTime(),
+		},
+		ReadCloser: io.NopCloser(e),
+		Mimetype:   "application/octet-stream",
+	}
+	return operations.Put(ctx, storage, actualPath, encrypted, up)
+}
+
+func (d *Crypt) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*Crypt)(nil)
+var _ driver.Getter = (*Crypt)(nil)
+var _ driver.Alias = (*Crypt)(nil)
@@ -0,0 +1,95 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/drivers/drivertest"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+func TestEncryptName(t *testing.T) {
+	// the known answers of rclone, the keys are zero without the password
+	c, err := newCipherKeys("", "")
+	if err != nil {
+		t.Fatalf("failed create cipher: %+v", err)
+	}
+	for _, kat := range []struct {
+		name, encrypted string
+	}{
+		{"", ""},
+		{"1", "p0e52nreeaj0a5ea7s64m4j72s"},
+		{"12", "l42g6771hnv3an9cgc8cr2n1ng"},
+		{"123", "qgm4avr35m5loi1th53ato71v0"},
+		{"1234", "8ivr2e9plj3c3esisjpdisikos"},
+	} {
+		if got := c.encryptName(kat.name); got != kat.encrypted {
+			t.Errorf("expected %q is encrypted to %q, got %q", kat.name, kat.encrypted, got)
+		}
+		if got, err := c.decryptName(kat.encrypted); err != nil || got != kat.name {
+			t.Errorf("expected %q is decrypted to %q, got %q %+v", kat.encrypted, kat.name, got, err)
+		}
+	}
+}
+
+func TestCrypt(t *testing.T) {
+	dir, s := drivertest.Mount(t, "/crypt", "Crypt", map[string]interface{}{
+		"password":                  "potato",
+		"filename_encryption":       "standard",
+		"directory_name_encryption": true,
+	})
+	content := []byte(strings.Repeat("0123456789", 20000))
+	if err := operations.MakeDir(context.Background(), s, "/movies"); err != nil {
+		t.Fatalf("failed mkdir: %+v", err)
+	}
+	stream := &model.FileStream{
+		Obj:        model.Object{Name: "movie.mkv", Size: int64(len(content))},
+		ReadCloser: io.NopCloser(bytes.NewReader(content)),
+	}
+	if err := operations.Put(context.Background(), s, "/movies", stream, nil); err != nil {
+		t.Fatalf("failed put: %+v", err)
+	}
+	// the names and the content are encrypted in the target
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || entries[0].Name() == "movies" {
+		t.Fatalf("expected the dir name is encrypted, got %+v %+v", entries, err)
+	}
+	files, _ := os.ReadDir(filepath.Join(dir, entries[0].Name()))
+	if len(files) != 1 || files[0].Name() == "movie.mkv" {
+		t.Fatalf("expected the file name is encrypted, got %+v", files)
+	}
+	encrypted, _ := os.ReadFile(filepath.Join(dir, entries[0].Name(), files[0].Name()))
+	if !strings.HasPrefix(string(encrypted), "RCLONE\x00\x00") || bytes.Contains(encrypted, content[:100]) {
+		t.Fatalf("expected the content is encrypted")
+	}
+	objs, err := operations.List(context.Background(), s, "/movies")
+	if err != nil || len(objs) != 1 || objs[0].GetName() != "movie.mkv" || objs[0].GetSize() != int64(len(content)) {
+		t.Fatalf("unexpected list: %+v %+v", objs, err)
+	}
+	for _, c := range []struct {
+		rangeHeader string
+		start, end  int
+	}{
+		{"", 0, len(content) - 1},
+		{"bytes=10-19", 10, 19},
+		{"bytes=131000-131199", 131000, 131199},
+		{"bytes=-5", len(content) - 5, len(content) - 1},
+	} {
+		link, _, err := operations.Link(context.Background(), s, "/movies/movie.mkv", model.LinkArgs{Header: http.Header{"Range": []string{c.rangeHeader}}})
+		if err != nil {
+			t.Fatalf("failed link: %+v", err)
+		}
+		data, err := io.ReadAll(link.Data)
+		_ = link.Data.Close()
+		if err != nil || !bytes.Equal(data, content[c.start:c.end+1]) {
+			t.Errorf("unexpected content of range %q: %d bytes %+v", c.rangeHeader, len(data), err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package crypt
+
+import "crypto/cipher"
+
+// the EME (ECB-Mix-ECB) wide block mode rclone encrypts the names with,
+// so the same name is always encrypted to the same one and a change of one byte changes all
+
+func multByTwo(out, in []byte) {
+	var tmp [16]byte
+	tmp[0] = 2 * in[0]
+	if in[15] >= 128 {
+		tmp[0] ^= 135
+	}
+	for j := 1; j < 16; j++ {
+		tmp[j] = 2 * in[j]
+		if in[j-1] >= 128 {
+			tmp[j]++
+		}
+	}
+	copy(out, tmp[:])
+}
+
+func xorBlocks(out, in1, in2 []byte) {
+	for i := range in1 {
+		out[i] = in1[i] ^ in2[i]
+	}
+}
+
+// emeTransform encrypt or decrypt the data, its length must be a multiple of 16, at most 2048
+func emeTransform(bc cipher.Block, tweak, data []byte, encrypt bool) []byte {
+	transform := bc.Decrypt
+	if encrypt {
+		transform = bc.Encrypt
+	}
+	m := len(data) / 16
+	// L_i = 2^(i+1) * AES(K; 0)
+	lTable := make([][]byte, m)
+	l := make([]byte, 16)
+	bc.Encrypt(l, make([]byte, 16))
+	for i := 0; i < m; i++ {
+		multByTwo(l, l)
+		lTable[i] = append([]byte(nil), l...)
+	}
+	c := make([]byte, len(data))
+	pp := make([]byte, 16)
+	for j := 0; j < m; j++ {
+		xorBlocks(pp, data[j*16:(j+1)*16], lTable[j])
+		transform(c[j*16:(j+1)*16], pp)
+	}
+	mp := make([]byte, 16)
+	xorBlocks(mp, c[0:16], tweak)
+	for j := 1; j < m; j++ {
+		xorBlocks(mp, mp, c[j*16:(j+1)*16])
+	}
+	mc := make([]byte, 16)
+	transform(mc, mp)
+	mm := make([]byte, 16)
+	xorBlocks(mm, mp, mc)
+	for j := 1; j < m; j++ {
+		multByTwo(mm, mm)
+		xorBlocks(c[j*16:(j+1)*16], c[j*16:(j+1)*16], mm)
+	}
+	ccc := make([]byte, 16)
+	xorBlocks(ccc, mc, tweak)
+	for j := 1; j < m; j++ {
+		xorBlocks(ccc, ccc, c[j*16:(j+1)*16])
+	}
+	copy(c[0:16], ccc)
+	for j := 0; j < m; j++ {
+		transform(c[j*16:(j+1)*16], c[j*16:(j+1)*16])
+		xorBlocks(c[j*16:(j+1)*16], c[j*16:(j+1)*16], lTable[j])
+	}
+	return c
+}
@@ -0,0 +1,32 @@
+package crypt
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	Path      string `json:"path" required:"true" help:"the virtual path to store the encrypted files, such as /s3/crypt"`
+	Password  string `json:"password" required:"true" help:"the plain password, not the obscured one in rclone.conf"`
+	Password2 string `json:"password2" help:"the salt, empty to use the default one of rclone"`
+	// the same options as rclone crypt
+	FilenameEncryption      string `json:"filename_encryption" type:"select" values:"standard,off" default:"standard" help:"off only appends .bin to the names"`
+	DirectoryNameEncryption bool   `json:"directory_name_encryption" default:"true"`
+}
+
+var config = driver.Config{
+	Name:      "Crypt",
+	LocalSort: true,
+	// the content is decrypted by alist
+	OnlyProxy: true,
+	// the target storage caches already
+	NoCache: true,
+}
+
+func New() driver.Driver {
+	return &Crypt{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
@@ -0,0 +1,197 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	stdpath "path"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Dedup store the files by the hash of their content in another storage,
+// so the same content is stored only once, and removed when no file refers to it
+type Dedup struct {
+	model.Storage
+	Addition
+	locks hashLocks
+}
+
+func (d *Dedup) Config() driver.Config {
+	return config
+}
+
+func (d *Dedup) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if d.Path == "" {
+		return errors.New("path is required")
+	}
+	d.Path = utils.StandardizePath(d.Path)
+	return operations.CheckAliasLoop(d.MountPath, d.Path)
+}
+
+func (d *Dedup) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *Dedup) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+// GetAliasPath the blobs are in the path, so it can't be in the mount
+func (d *Dedup) GetAliasPath() string {
+	return d.Path
+}
+
+func (d *Dedup) Get(ctx context.Context, path string) (model.Obj, error) {
+	path = utils.StandardizePath(path)
+	if path == "/" {
+		return &model.Object{ID: "/", Name: "root", Modified: d.Modified, IsFolder: true}, nil
+	}
+	e, err := db.GetDedupEntry(d.ID, path)
+	if err != nil {
+		return nil, err
+	}
+	return toObj(*e), nil
+}
+
+func (d *Dedup) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	entries, err := db.GetDedupEntries(d.ID, dir.GetID())
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]model.Obj, 0, len(entries))
+	for _, e := range entries {
+		objs = append(objs, toObj(e))
+	}
+	return objs, nil
+}
+
+func (d *Dedup) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	e, err := db.GetDedupEntry(d.ID, file.GetID())
+	if err != nil {
+		return nil, err
+	}
+	ctx, storage, actualPath, err := d.blobs(ctx, e.Hash)
+	if err != nil {
+		return nil, err
+	}
+	link, _, err := operations.Link(ctx, storage, actualPath, args)
+	return link, err
+}
+
+func (d *Dedup) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	return db.CreateDedupDir(&model.DedupEntry{
+		StorageID: d.ID,
+		Path:      stdpath.Join(parentDir.GetID(), dirName),
+		Dir:       parentDir.GetID(),
+		Name:      dirName,
+		IsDir:     true,
+		Modified:  time.Now(),
+	})
+}
+
+func (d *Dedup) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return db.MoveDedupEntries(d.ID, srcObj.GetID(), stdpath.Join(dstDir.GetID(), srcObj.GetName()))
+}
+
+func (d *Dedup) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return db.MoveDedupEntries(d.ID, srcObj.GetID(), stdpath.Join(stdpath.Dir(srcObj.GetID()), newName))
+}
+
+// Copy only the entries are copied, they refer to the same blobs
+func (d *Dedup) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return db.CopyDedupEntries(d.ID, srcObj.GetID(), stdpath.Join(dstDir.GetID(), srcObj.GetName()))
+}
+
+func (d *Dedup) Remove(ctx context.Context, obj model.Obj) error {
+	hashes, err := db.RemoveDedupEntries(d.ID, obj.GetID())
+	if err != nil {
+		return err
+	}
+	d.release(ctx, hashes...)
+	return nil
+}
+
+// Put hash the stream into a temp file, which is uploaded as the blob if the content isn't stored yet
+func (d *Dedup) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	f, err := ioutil.TempFile(conf.Conf.TempDir, "file-*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), stream)
+	if err != nil {
+		return errors.Wrap(err, "failed read stream")
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	unlock := d.lock(hash)
+	_, err = db.GetDedupBlob(d.ID, hash)
+	if errs.IsObjectNotFound(err) {
+		err = d.putBlob(ctx, hash, f, size, up)
+	}
+	var old string
+	if err == nil {
+		old, err = db.PutDedupFile(&model.DedupEntry{
+			StorageID: d.ID,
+			Path:      stdpath.Join(dstDir.GetID(), stream.GetName()),
+			Dir:       dstDir.GetID(),
+			Name:      stream.GetName(),
+			Hash:      hash,
+			Size:      size,
+			Modified:  time.Now(),
+		})
+	}
+	unlock()
+	if err != nil {
+		return err
+	}
+	if old != "" {
+		d.release(ctx, old)
+	}
+	return nil
+}
+
+func (d *Dedup) putBlob(ctx context.Context, hash string, f *os.File, size int64, up driver.UpdateProgress) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return errors.WithStack(err)
+	}
+	ctx, storage, actualPath, err := d.blobs(ctx, hash)
+	if err != nil {
+		return err
+	}
+	// the temp file is closed and removed by the put
+	blob := &model.FileStream{
+		Obj:        model.Object{Name: hash, Size: size, Modified: time.Now()},
+		ReadCloser: f,
+		Mimetype:   "application/octet-stream",
+	}
+	return errors.WithMessage(operations.Put(ctx, storage, stdpath.Dir(actualPath), blob, up), "failed put blob")
+}
+
+func (d *Dedup) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*Dedup)(nil)
+var _ driver.Getter = (*Dedup)(nil)
+var _ driver.Alias = (*Dedup)(nil)
@@ -0,0 +1,57 @@
+package dedup
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/drivers/drivertest"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+func TestDedup(t *testing.T) {
+	blobs, s := drivertest.Mount(t, "/dedup", "Dedup", map[string]interface{}{})
+	conf.Conf.TempDir = t.TempDir()
+	countBlobs := func() int {
+		var n int
+		_ = filepath.Walk(blobs, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				n++
+			}
+			return nil
+		})
+		return n
+	}
+	for _, dir := range []string{"/a", "/b"} {
+		stream := &model.FileStream{
+			Obj:        model.Object{Name: "same.txt", Size: 4},
+			ReadCloser: io.NopCloser(strings.NewReader("same")),
+		}
+		if err := operations.Put(context.Background(), s, dir, stream, nil); err != nil {
+			t.Fatalf("failed put: %+v", err)
+		}
+	}
+	if n := countBlobs(); n != 1 {
+		t.Fatalf("expected the same content is stored once, got %d blobs", n)
+	}
+	if err := operations.Remove(context.Background(), s, "/a/same.txt"); err != nil {
+		t.Fatalf("failed remove: %+v", err)
+	}
+	if n := countBlobs(); n != 1 {
+		t.Errorf("expected the blob is kept while referred, got %d blobs", n)
+	}
+	if err := operations.Remove(context.Background(), s, "/b"); err != nil {
+		t.Fatalf("failed remove: %+v", err)
+	}
+	if n := countBlobs(); n != 0 {
+		t.Errorf("expected the unreferenced blob is removed, got %d blobs", n)
+	}
+	if n := len(s.(*Dedup).locks.locks); n != 0 {
+		t.Errorf("expected the locks are deleted once released, got %d", n)
+	}
+}
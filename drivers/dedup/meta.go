@@ -0,0 +1,37 @@
+package dedup
+
+import (
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+	log "github.com/sirupsen/logrus"
+)
+
+type Addition struct {
+	// the blobs are named by their sha256, the tree of the mount is kept in the database
+	Path string `json:"path" required:"true" help:"the virtual path to store the contents, such as /local/blobs"`
+}
+
+var config = driver.Config{
+	Name:      "Dedup",
+	LocalSort: true,
+	// the tree is read from the database
+	NoCache: true,
+}
+
+func New() driver.Driver {
+	return &Dedup{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+	// the blobs are left in the storage of them, only the references are forgotten
+	operations.RegisterStorageHook(func(event operations.StorageEvent) {
+		if event.Type != operations.EventStorageDeleted || event.Storage.Driver != config.Name {
+			return
+		}
+		if err := db.DeleteDedupByStorage(event.Storage.ID); err != nil {
+			log.Warnf("failed delete the tree of storage [%s]: %+v", event.Storage.MountPath, err)
+		}
+	})
+}
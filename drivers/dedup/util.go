@@ -0,0 +1,105 @@
+package dedup
+
+import (
+	"context"
+	stdpath "path"
+	"sync"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	log "github.com/sirupsen/logrus"
+)
+
+func toObj(e model.DedupEntry) model.Obj {
+	return &model.Object{
+		ID:       e.Path,
+		Name:     e.Name,
+		Size:     e.Size,
+		Modified: e.Modified,
+		IsFolder: e.IsDir,
+	}
+}
+
+// blobs resolve the storage of the blobs and the actual path of the blob
+func (d *Dedup) blobs(ctx context.Context, hash string) (context.Context, driver.Driver, string, error) {
+	ctx, err := operations.EnterAlias(ctx, d.MountPath)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	// spread the blobs into dirs by the first byte, so the dirs don't get too large
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, stdpath.Join(d.Path, hash[:2], hash))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return ctx, storage, actualPath, nil
+}
+
+// hashLocks the locks of the blobs, an entry is kept only while it's held or waited for
+type hashLocks struct {
+	mu    sync.Mutex
+	locks map[string]*hashLock
+}
+
+type hashLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock the blob of the hash, so it isn't removed while a new file refers to it
+func (d *Dedup) lock(hash string) func() {
+	l := &d.locks
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*hashLock{}
+	}
+	hl, ok := l.locks[hash]
+	if !ok {
+		hl = &hashLock{}
+		l.locks[hash] = hl
+	}
+	hl.refs++
+	l.mu.Unlock()
+	hl.Lock()
+	return func() {
+		hl.Unlock()
+		l.mu.Lock()
+		hl.refs--
+		if hl.refs == 0 {
+			delete(l.locks, hash)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// release remove the blobs no file refers to anymore
+func (d *Dedup) release(ctx context.Context, hashes ...string) {
+	released := make(map[string]bool)
+	for _, hash := range hashes {
+		if released[hash] {
+			continue
+		}
+		released[hash] = true
+		d.releaseOne(ctx, hash)
+	}
+}
+
+func (d *Dedup) releaseOne(ctx context.Context, hash string) {
+	defer d.lock(hash)()
+	deleted, err := db.DeleteUnusedDedupBlob(d.ID, hash)
+	if err != nil {
+		log.Warnf("failed delete blob %s of [%s]: %+v", hash, d.MountPath, err)
+		return
+	}
+	if !deleted {
+		return
+	}
+	ctx, storage, actualPath, err := d.blobs(ctx, hash)
+	if err == nil {
+		err = operations.Remove(ctx, storage, actualPath)
+	}
+	if err != nil {
+		log.Warnf("failed remove blob %s of [%s]: %+v", hash, d.MountPath, err)
+	}
+}
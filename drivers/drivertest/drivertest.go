@@ -0,0 +1,70 @@
+// Package drivertest mount the drivers wrapping another storage on a local folder for their tests
+package drivertest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var initOnce sync.Once
+
+func initDB() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig()
+	db.Init(dB)
+}
+
+// Create create the storage and return it
+func Create(t *testing.T, storage model.Storage) driver.Driver {
+	initOnce.Do(initDB)
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage %s: %+v", storage.MountPath, err)
+	}
+	s, err := operations.GetStorageByVirtualPath(storage.MountPath)
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	return s
+}
+
+// Mount create a local storage of a temp folder, and the storage of the driver at the mount path
+// storing into it by the path of the addition. the temp folder and the storage are returned
+func Mount(t *testing.T, mountPath, driverName string, addition map[string]interface{}) (string, driver.Driver) {
+	dir := t.TempDir()
+	src := mountPath + "_src"
+	local, err := utils.Json.MarshalToString(map[string]interface{}{"root_folder": dir})
+	if err != nil {
+		t.Fatalf("failed marshal addition: %+v", err)
+	}
+	addition["path"] = src
+	wrapped, err := utils.Json.MarshalToString(addition)
+	if err != nil {
+		t.Fatalf("failed marshal addition: %+v", err)
+	}
+	Create(t, model.Storage{Driver: "Local", MountPath: src, Addition: local})
+	return dir, Create(t, model.Storage{Driver: driverName, MountPath: mountPath, Addition: wrapped})
+}
+
+// ClearCache clear the listing cache of the local storage under the mount path,
+// after its folder is changed directly
+func ClearCache(t *testing.T, mountPath string) {
+	s, err := operations.GetStorageByVirtualPath(mountPath + "_src")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	operations.ClearCache(s, "/")
+}
@@ -0,0 +1,112 @@
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/errors"
+)
+
+const (
+	TLSNone     = "none"
+	TLSExplicit = "explicit"
+	TLSImplicit = "implicit"
+)
+
+const timeout = 30 * time.Second
+
+// dial connect and login, a connection runs one command at a time. the data connections
+// are made through the dialer of the storage as well, so they go through the same proxy
+func (d *FTP) dial() (*ftp.ServerConn, error) {
+	host, _, err := net.SplitHostPort(d.Address)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	control := true
+	dialFunc := func(network, address string) (net.Conn, error) {
+		isControl := control
+		control = false
+		if !isControl {
+			// the address in the passive reply may be a private one behind nat,
+			// so the data connections are made to the host of the control one
+			_, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			address = net.JoinHostPort(host, port)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		conn, err := d.dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		// the control connection of explicit tls is upgraded by the client after AUTH TLS
+		if d.TLS == TLSImplicit || (d.TLS == TLSExplicit && !isControl) {
+			return tls.Client(conn, d.tlsConfig), nil
+		}
+		return conn, nil
+	}
+	opts := []ftp.DialOption{ftp.DialWithDialFunc(dialFunc), ftp.DialWithShutTimeout(timeout)}
+	switch d.TLS {
+	case TLSImplicit:
+		opts = append(opts, ftp.DialWithTLS(d.tlsConfig))
+	case TLSExplicit:
+		opts = append(opts, ftp.DialWithExplicitTLS(d.tlsConfig))
+	}
+	if d.enc != nil {
+		opts = append(opts, ftp.DialWithDisabledUTF8(true))
+	}
+	c, err := ftp.Dial(d.Address, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed connect %s", d.Address)
+	}
+	if err := c.Login(d.encode(d.Username), d.Password); err != nil {
+		_ = c.Quit()
+		return nil, errors.Wrap(err, "failed login")
+	}
+	return c, nil
+}
+
+// encode the path to the encoding of the server
+func (d *FTP) encode(path string) string {
+	if d.enc == nil {
+		return path
+	}
+	s, err := d.enc.NewEncoder().String(path)
+	if err != nil {
+		return path
+	}
+	return s
+}
+
+func (d *FTP) decode(name string) string {
+	if d.enc == nil {
+		return name
+	}
+	s, err := d.enc.NewDecoder().String(name)
+	if err != nil {
+		return name
+	}
+	return s
+}
+
+// list the entries of the dir, the names are decoded
+func (d *FTP) list(c *ftp.ServerConn, path string) ([]*ftp.Entry, error) {
+	entries, err := c.List(d.encode(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed list %s", path)
+	}
+	res := entries[:0]
+	for _, e := range entries {
+		if e.Name == "." || e.Name == ".." {
+			continue
+		}
+		e.Name = d.decode(e.Name)
+		res = append(res, e)
+	}
+	return res, nil
+}
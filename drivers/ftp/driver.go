@@ -0,0 +1,265 @@
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"sync"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	inet "github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+type FTP struct {
+	model.Storage
+	Addition
+	dialer    inet.Dialer
+	tlsConfig *tls.Config
+	enc       encoding.Encoding // nil means utf-8
+	// conn is shared by the metadata commands, the transfers use their own connections
+	mu   sync.Mutex
+	conn *ftp.ServerConn
+}
+
+func (d *FTP) Config() driver.Config {
+	return config
+}
+
+func (d *FTP) Capabilities() driver.Capabilities {
+	caps := driver.AllCapabilities()
+	// ftp has no server side copy, the copies are downloaded and uploaded again
+	caps.Copy, caps.CopyDir = false, false
+	return caps
+}
+
+func (d *FTP) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if d.TLS == "" {
+		d.TLS = TLSNone
+	}
+	if d.TLS != TLSNone && d.TLS != TLSExplicit && d.TLS != TLSImplicit {
+		return errors.Errorf("unsupported tls mode: %s", d.TLS)
+	}
+	if _, _, err := net.SplitHostPort(d.Address); err != nil {
+		port := "21"
+		if d.TLS == TLSImplicit {
+			port = "990"
+		}
+		d.Address = net.JoinHostPort(d.Address, port)
+	}
+	host, _, _ := net.SplitHostPort(d.Address)
+	d.tlsConfig = &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: d.InsecureSkipVerify,
+		// the data connections resume the session of the control one, required by some servers
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+	if d.dialer, err = inet.NewDialer(d.Network); err != nil {
+		return err
+	}
+	d.enc = nil
+	if d.Encoding != "" {
+		d.enc, err = htmlindex.Get(d.Encoding)
+		if err != nil {
+			return errors.Wrapf(err, "unsupported encoding %s", d.Encoding)
+		}
+	}
+	_ = d.Drop(ctx)
+	return d.withConn(func(c *ftp.ServerConn) error {
+		_, err := d.list(c, d.RootFolder)
+		return err
+	})
+}
+
+// withConn run f with the shared connection, it's dropped if it's broken after f fails,
+// so the next call reconnects
+func (d *FTP) withConn(f func(c *ftp.ServerConn) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn == nil {
+		c, err := d.dial()
+		if err != nil {
+			return err
+		}
+		d.conn = c
+	}
+	err := f(d.conn)
+	if err != nil && d.conn.NoOp() != nil {
+		_ = d.conn.Quit()
+		d.conn = nil
+	}
+	return err
+}
+
+func (d *FTP) Drop(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn != nil {
+		_ = d.conn.Quit()
+		d.conn = nil
+	}
+	return nil
+}
+
+func (d *FTP) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *FTP) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	var entries []*ftp.Entry
+	err := d.withConn(func(c *ftp.ServerConn) error {
+		var err error
+		entries, err = d.list(c, dir.GetID())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]model.Obj, 0, len(entries))
+	for _, e := range entries {
+		objs = append(objs, &model.Object{
+			ID:       stdpath.Join(dir.GetID(), e.Name),
+			Name:     e.Name,
+			Size:     int64(e.Size),
+			Modified: e.Time,
+			IsFolder: e.Type == ftp.EntryTypeFolder,
+		})
+	}
+	return objs, nil
+}
+
+// transferReader close the connection of the transfer after the data
+type transferReader struct {
+	io.Reader
+	data io.Closer
+	c    *ftp.ServerConn
+}
+
+func (r *transferReader) Close() error {
+	err := r.data.Close()
+	_ = r.c.Quit()
+	return err
+}
+
+func (d *FTP) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	c, err := d.dial()
+	if err != nil {
+		return nil, err
+	}
+	start, end, ranged := utils.ParseRange(args.Header.Get("Range"), file.GetSize())
+	data, err := c.RetrFrom(d.encode(file.GetID()), uint64(start))
+	if err != nil {
+		_ = c.Quit()
+		return nil, errors.Wrapf(err, "failed retrieve %s", file.GetID())
+	}
+	link := &model.Link{Data: &transferReader{Reader: data, data: data, c: c}}
+	if ranged {
+		link.Data = &transferReader{Reader: io.LimitReader(data, end-start+1), data: data, c: c}
+		link.Status = http.StatusPartialContent
+		link.Header = http.Header{
+			"Content-Range":  []string{fmt.Sprintf("bytes %d-%d/%d", start, end, file.GetSize())},
+			"Content-Length": []string{strconv.FormatInt(end-start+1, 10)},
+			"Accept-Ranges":  []string{"bytes"},
+		}
+	}
+	return link, nil
+}
+
+func (d *FTP) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	return d.withConn(func(c *ftp.ServerConn) error {
+		return c.MakeDir(d.encode(stdpath.Join(parentDir.GetID(), dirName)))
+	})
+}
+
+func (d *FTP) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.withConn(func(c *ftp.ServerConn) error {
+		return c.Rename(d.encode(srcObj.GetID()), d.encode(stdpath.Join(dstDir.GetID(), srcObj.GetName())))
+	})
+}
+
+func (d *FTP) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.withConn(func(c *ftp.ServerConn) error {
+		return c.Rename(d.encode(srcObj.GetID()), d.encode(stdpath.Join(stdpath.Dir(srcObj.GetID()), newName)))
+	})
+}
+
+func (d *FTP) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	// ftp has no server side copy
+	return errs.NotSupport
+}
+
+func (d *FTP) Remove(ctx context.Context, obj model.Obj) error {
+	return d.withConn(func(c *ftp.ServerConn) error {
+		return d.removeAll(ctx, c, obj.GetID(), obj.IsDir())
+	})
+}
+
+// removeAll remove the file or the folder with all its children
+func (d *FTP) removeAll(ctx context.Context, c *ftp.ServerConn, path string, dir bool) error {
+	if !dir {
+		return c.Delete(d.encode(path))
+	}
+	entries, err := d.list(c, path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		if err := d.removeAll(ctx, c, stdpath.Join(path, e.Name), e.Type == ftp.EntryTypeFolder); err != nil {
+			return err
+		}
+	}
+	return c.RemoveDir(d.encode(path))
+}
+
+func (d *FTP) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	c, err := d.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Quit()
+	path := stdpath.Join(dstDir.GetID(), stream.GetName())
+	err = c.Stor(d.encode(path), &ctxReader{ctx: ctx, r: stream})
+	if err != nil && utils.IsCanceled(ctx) {
+		_ = c.Delete(d.encode(path))
+	}
+	return errors.Wrapf(err, "failed store %s", path)
+}
+
+// ctxReader stop reading when the context is canceled
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+func (d *FTP) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*FTP)(nil)
+var _ driver.Capable = (*FTP)(nil)
@@ -0,0 +1,32 @@
+package ftp
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	Address  string `json:"address" required:"true" help:"host:port, the port is 21 or 990 for implicit tls if omitted"`
+	Username string `json:"username" default:"anonymous"`
+	Password string `json:"password"`
+	TLS      string `json:"tls" type:"select" values:"none,explicit,implicit" default:"none"`
+	// the self-signed certificates are common on the legacy servers
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	Encoding           string `json:"encoding" help:"the encoding of the names on the server, such as gbk or shift_jis, empty means utf-8"`
+}
+
+var config = driver.Config{
+	Name:        "FTP",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &FTP{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
@@ -0,0 +1,140 @@
+package google_drive
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	tokenUrl = "https://oauth2.googleapis.com/token"
+	scope    = "https://www.googleapis.com/auth/drive"
+)
+
+// parseServiceAccounts accept a json key or an array of them
+func parseServiceAccounts(keys string) ([]serviceAccount, error) {
+	keys = strings.TrimSpace(keys)
+	if keys == "" {
+		return nil, nil
+	}
+	var accounts []serviceAccount
+	if strings.HasPrefix(keys, "[") {
+		if err := utils.Json.UnmarshalFromString(keys, &accounts); err != nil {
+			return nil, errors.Wrap(err, "failed unmarshal service accounts")
+		}
+	} else {
+		var account serviceAccount
+		if err := utils.Json.UnmarshalFromString(keys, &account); err != nil {
+			return nil, errors.Wrap(err, "failed unmarshal service account")
+		}
+		accounts = append(accounts, account)
+	}
+	for i, account := range accounts {
+		if account.ClientEmail == "" || account.PrivateKey == "" {
+			return nil, errors.Errorf("the service account %d has no client_email or private_key", i)
+		}
+		if account.TokenUri == "" {
+			accounts[i].TokenUri = tokenUrl
+		}
+	}
+	return accounts, nil
+}
+
+// refreshToken request the access token of the service account in use,
+// or exchange the refresh token for it if no service account is set
+func (d *GoogleDrive) refreshToken(ctx context.Context, refreshToken string) (*operations.OAuthToken, error) {
+	var form url.Values
+	endpoint := tokenUrl
+	d.mu.Lock()
+	accounts, current := d.accounts, d.current
+	d.mu.Unlock()
+	if len(accounts) > 0 {
+		account := accounts[current]
+		assertion, err := signAssertion(account)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = account.TokenUri
+		form = url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+	} else {
+		form = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {d.ClientID},
+			"client_secret": {d.ClientSecret},
+			"refresh_token": {refreshToken},
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed request access token")
+	}
+	defer res.Body.Close()
+	var token tokenResp
+	if err := utils.Json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, errors.Wrapf(err, "failed decode access token: %s", res.Status)
+	}
+	if token.Error == "invalid_grant" && len(accounts) == 0 {
+		return nil, errors.Wrap(errs.RefreshTokenInvalid, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.Errorf("failed get access token: %s %s", token.Error, token.ErrorDescription)
+	}
+	oauthToken := &operations.OAuthToken{
+		AccessToken: token.AccessToken,
+		Expiry:      time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}
+	if token.RefreshTokenExpiresIn > 0 {
+		oauthToken.RefreshExpiry = time.Now().Add(time.Duration(token.RefreshTokenExpiresIn) * time.Second)
+	}
+	return oauthToken, nil
+}
+
+// signAssertion sign the jwt exchanged for the access token of the service account
+func signAssertion(account serviceAccount) (string, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed parse private key of %s", account.ClientEmail)
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   account.ClientEmail,
+		"scope": scope,
+		"aud":   account.TokenUri,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	assertion, err := token.SignedString(key)
+	return assertion, errors.Wrap(err, "failed sign assertion")
+}
+
+// rotate switch to the next service account if the failed token is still in use,
+// return false if there is no other account
+func (d *GoogleDrive) rotate(token string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.accounts) < 2 {
+		return false
+	}
+	// rotated by another request already
+	if !d.tokens.Invalidate(token) {
+		return true
+	}
+	d.current = (d.current + 1) % len(d.accounts)
+	return true
+}
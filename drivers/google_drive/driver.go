@@ -0,0 +1,187 @@
+package google_drive
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// GoogleDrive mount my drive or a shared drive, authorized by a refresh token or a pool of service accounts
+type GoogleDrive struct {
+	model.Storage
+	Addition
+	accounts []serviceAccount
+	// client for the metadata calls, uploadClient for the transfers without timeout
+	client       *http.Client
+	uploadClient *http.Client
+	tokens       *operations.TokenManager
+
+	mu      sync.Mutex
+	current int // index of the service account in use
+}
+
+func (d *GoogleDrive) Config() driver.Config {
+	return config
+}
+
+func (d *GoogleDrive) Capabilities() driver.Capabilities {
+	caps := driver.AllCapabilities()
+	// the api can't copy folders
+	caps.CopyDir = false
+	return caps
+}
+
+func (d *GoogleDrive) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	d.accounts, err = parseServiceAccounts(d.ServiceAccounts)
+	if err != nil {
+		return err
+	}
+	if len(d.accounts) == 0 && d.RefreshToken == "" {
+		return errors.New("either refresh token or service accounts is required")
+	}
+	// the root of a shared drive is the drive itself
+	if d.DriveID != "" && (d.RootFolder == "" || d.RootFolder == "root") {
+		d.RootFolder = d.DriveID
+	}
+	d.client = net.APIClient(d.ClientNetwork())
+	d.uploadClient = net.Client(d.ClientNetwork())
+	d.mu.Lock()
+	d.current = 0
+	d.mu.Unlock()
+	d.tokens = operations.NewTokenManager(d, &d.RefreshToken, d.refreshToken)
+	_, err = d.getFile(ctx, d.RootFolder)
+	if errs.IsObjectNotFound(err) {
+		return errors.Errorf("root folder %s not exists", d.RootFolder)
+	}
+	return err
+}
+
+func (d *GoogleDrive) Drop(ctx context.Context) error {
+	if d.tokens != nil {
+		d.tokens.Close()
+	}
+	return nil
+}
+
+func (d *GoogleDrive) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *GoogleDrive) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	files, err := d.getFiles(ctx, dir.GetID())
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]model.Obj, 0, len(files))
+	for _, f := range files {
+		objs = append(objs, f.toObj())
+	}
+	return objs, nil
+}
+
+// Link download the file by the driver instead of redirecting, so the service account is rotated
+// if its download quota is exceeded, and the access token is never exposed
+func (d *GoogleDrive) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	u := api + "/files/" + file.GetID() + "?alt=media&supportsAllDrives=true"
+	res, err := d.do(ctx, d.uploadClient, func(token string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if r := args.Header.Get("Range"); r != "" {
+			req.Header.Set("Range", r)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed download %s", file.GetName())
+	}
+	header := http.Header{}
+	for _, k := range []string{"Content-Range", "Content-Length", "Accept-Ranges", "Content-Type"} {
+		if v := res.Header.Get(k); v != "" {
+			header.Set(k, v)
+		}
+	}
+	return &model.Link{Data: res.Body, Status: res.StatusCode, Header: header}, nil
+}
+
+func (d *GoogleDrive) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	body := map[string]interface{}{
+		"name":     dirName,
+		"mimeType": folderMimeType,
+		"parents":  []string{parentDir.GetID()},
+	}
+	return d.request(ctx, http.MethodPost, "/files", nil, body, nil)
+}
+
+func (d *GoogleDrive) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	f, err := d.getFile(ctx, srcObj.GetID())
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"addParents":    {dstDir.GetID()},
+		"removeParents": {strings.Join(f.Parents, ",")},
+	}
+	return d.request(ctx, http.MethodPatch, "/files/"+srcObj.GetID(), query, map[string]interface{}{}, nil)
+}
+
+func (d *GoogleDrive) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	body := map[string]interface{}{"name": newName}
+	return d.request(ctx, http.MethodPatch, "/files/"+srcObj.GetID(), nil, body, nil)
+}
+
+func (d *GoogleDrive) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	// the api can't copy folders
+	if srcObj.IsDir() {
+		return errs.NotSupport
+	}
+	body := map[string]interface{}{
+		"name":    srcObj.GetName(),
+		"parents": []string{dstDir.GetID()},
+	}
+	return d.request(ctx, http.MethodPost, "/files/"+srcObj.GetID()+"/copy", nil, body, nil)
+}
+
+func (d *GoogleDrive) Remove(ctx context.Context, obj model.Obj) error {
+	// move to the trash, so it can be restored in google drive
+	body := map[string]interface{}{"trashed": true}
+	return d.request(ctx, http.MethodPatch, "/files/"+obj.GetID(), nil, body, nil)
+}
+
+func (d *GoogleDrive) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	return d.upload(ctx, dstDir.GetID(), stream, up)
+}
+
+func (d *GoogleDrive) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+// CredentialExpiration the refresh token of the apps in testing expires in 7 days,
+// the service accounts never expire
+func (d *GoogleDrive) CredentialExpiration() time.Time {
+	if d.tokens == nil {
+		return time.Time{}
+	}
+	return d.tokens.CredentialExpiration()
+}
+
+var _ driver.Driver = (*GoogleDrive)(nil)
+var _ driver.Capable = (*GoogleDrive)(nil)
+var _ driver.CredentialExpirer = (*GoogleDrive)(nil)
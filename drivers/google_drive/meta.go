@@ -0,0 +1,32 @@
+package google_drive
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderId
+	// the folders of a shared drive are listed in the drive instead of my drive
+	DriveID      string `json:"drive_id" help:"the id of the shared drive, empty for my drive"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token" help:"used if no service account is set"`
+	// rotated to the next one when the current one exceeds its quota
+	ServiceAccounts string `json:"service_accounts" type:"text" help:"the json keys of the service accounts, one object or an array of them"`
+	ChunkSize       int    `json:"chunk_size" type:"number" default:"8" help:"MB of each request of the resumable uploads"`
+}
+
+var config = driver.Config{
+	Name:        "GoogleDrive",
+	OnlyProxy:   true,
+	DefaultRoot: "root",
+}
+
+func New() driver.Driver {
+	return &GoogleDrive{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
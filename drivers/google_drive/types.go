@@ -0,0 +1,85 @@
+package google_drive
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+const folderMimeType = "application/vnd.google-apps.folder"
+
+type file struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	MimeType     string    `json:"mimeType"`
+	Size         int64     `json:"size,string"`
+	ModifiedTime time.Time `json:"modifiedTime"`
+	Parents      []string  `json:"parents"`
+}
+
+func (f file) toObj() model.Obj {
+	return &model.Object{
+		ID:       f.ID,
+		Name:     f.Name,
+		Size:     f.Size,
+		Modified: f.ModifiedTime,
+		IsFolder: f.MimeType == folderMimeType,
+	}
+}
+
+type fileList struct {
+	Files         []file `json:"files"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+type apiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// quotaExceeded report whether the account should be rotated for the error,
+// the quotas are reset in hours, unlike the rate limits
+func (e apiError) quotaExceeded() bool {
+	for _, err := range e.Error.Errors {
+		switch err.Reason {
+		case "storageQuotaExceeded", "downloadQuotaExceeded", "dailyLimitExceeded":
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimited report whether the request should be retried later
+func (e apiError) rateLimited(status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	for _, err := range e.Error.Errors {
+		switch err.Reason {
+		case "userRateLimitExceeded", "rateLimitExceeded":
+			return true
+		}
+	}
+	return false
+}
+
+type tokenResp struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	// only set if the refresh token is time limited, such as the apps in testing
+	RefreshTokenExpiresIn int64  `json:"refresh_token_expires_in"`
+	Error                 string `json:"error"`
+	ErrorDescription      string `json:"error_description"`
+}
+
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenUri    string `json:"token_uri"`
+}
@@ -0,0 +1,306 @@
+package google_drive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	api       = "https://www.googleapis.com/drive/v3"
+	uploadApi = "https://www.googleapis.com/upload/drive/v3"
+	// the size of the chunks of resumable uploads must be a multiple of 256 KB
+	chunkUnit = 256 * 1024
+	// the rate limited requests are retried with exponential backoff
+	rateLimitRetries = 5
+)
+
+// the backoff of the first retry of the rate limited requests
+var rateLimitBackoff = time.Second
+
+// do send the request built with the access token, the token is refreshed on 401, the rate limited
+// requests are retried later, and the service account is rotated on the quota errors until all of them are tried
+func (d *GoogleDrive) do(ctx context.Context, client *http.Client, build func(token string) (*http.Request, error)) (*http.Response, error) {
+	var refreshed bool
+	var rotated, limited int
+	for {
+		token, err := d.tokens.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req, err := build(token)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed request google drive")
+		}
+		if res.StatusCode < 400 {
+			return res, nil
+		}
+		var apiErr apiError
+		_ = utils.Json.NewDecoder(res.Body).Decode(&apiErr)
+		_ = res.Body.Close()
+		switch {
+		case res.StatusCode == http.StatusUnauthorized && !refreshed:
+			refreshed = true
+			d.tokens.Invalidate(token)
+			continue
+		case apiErr.rateLimited(res.StatusCode) && limited < rateLimitRetries:
+			timer := time.NewTimer(rateLimitBackoff << limited)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, errors.WithStack(ctx.Err())
+			case <-timer.C:
+			}
+			limited++
+			continue
+		case res.StatusCode == http.StatusForbidden && apiErr.quotaExceeded() &&
+			rotated < len(d.accounts)-1 && d.rotate(token):
+			rotated++
+			continue
+		case res.StatusCode == http.StatusNotFound:
+			return nil, errors.WithStack(errs.ObjectNotFound)
+		case res.StatusCode == http.StatusForbidden && apiErr.quotaExceeded():
+			// all service accounts are tried
+			return nil, errors.Wrapf(errs.QuotaExceeded, "failed request google drive: %s", apiErr.Error.Message)
+		}
+		if apiErr.Error.Message != "" {
+			return nil, errors.WithMessagef(errs.NewStatusError(res), "failed request google drive: %s", apiErr.Error.Message)
+		}
+		return nil, errors.WithMessage(errs.NewStatusError(res), "failed request google drive")
+	}
+}
+
+// request call the api with the json body and decode the json response into res
+func (d *GoogleDrive) request(ctx context.Context, method, path string, query url.Values, body, res interface{}) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("supportsAllDrives", "true")
+	u := api + path + "?" + query.Encode()
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = utils.Json.Marshal(body)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	resp, err := d.do(ctx, d.client, func(token string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if res == nil {
+		return nil
+	}
+	return errors.Wrap(utils.Json.NewDecoder(resp.Body).Decode(res), "failed decode google drive response")
+}
+
+func (d *GoogleDrive) getFile(ctx context.Context, id string) (*file, error) {
+	var f file
+	query := url.Values{"fields": {"id,name,mimeType,size,modifiedTime,parents"}}
+	if err := d.request(ctx, http.MethodGet, "/files/"+id, query, nil, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (d *GoogleDrive) getFiles(ctx context.Context, parentId string) ([]file, error) {
+	query := url.Values{
+		"q":        {fmt.Sprintf("'%s' in parents and trashed = false", parentId)},
+		"fields":   {"nextPageToken,files(id,name,mimeType,size,modifiedTime)"},
+		"pageSize": {"1000"},
+	}
+	if d.DriveID != "" {
+		query.Set("corpora", "drive")
+		query.Set("driveId", d.DriveID)
+		query.Set("includeItemsFromAllDrives", "true")
+	}
+	var files []file
+	for {
+		var res fileList
+		if err := d.request(ctx, http.MethodGet, "/files", query, nil, &res); err != nil {
+			return nil, err
+		}
+		files = append(files, res.Files...)
+		if res.NextPageToken == "" {
+			return files, nil
+		}
+		query.Set("pageToken", res.NextPageToken)
+	}
+}
+
+// upload the stream with a resumable upload session, the chunks failed are resumed from the offset the server received
+func (d *GoogleDrive) upload(ctx context.Context, parentId string, stream model.FileStreamer, up driver.UpdateProgress) error {
+	size := stream.GetSize()
+	mimetype := stream.GetMimetype()
+	if mimetype == "" {
+		mimetype = "application/octet-stream"
+	}
+	data, err := utils.Json.Marshal(map[string]interface{}{
+		"name":    stream.GetName(),
+		"parents": []string{parentId},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	u := uploadApi + "/files?uploadType=resumable&supportsAllDrives=true"
+	res, err := d.do(ctx, d.client, func(token string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+		req.Header.Set("X-Upload-Content-Type", mimetype)
+		req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+		return req, nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed create upload session")
+	}
+	_ = res.Body.Close()
+	session := res.Header.Get("Location")
+	if session == "" {
+		return errors.New("no upload session returned")
+	}
+	chunkSize := int64(d.ChunkSize) * 1024 * 1024
+	if chunkSize < chunkUnit {
+		chunkSize = 8 * 1024 * 1024
+	}
+	if size == 0 {
+		return d.putChunk(ctx, session, nil, 0, 0)
+	}
+	buf := make([]byte, chunkSize)
+	for offset := int64(0); offset < size; {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		n := chunkSize
+		if size-offset < n {
+			n = size - offset
+		}
+		if _, err := io.ReadFull(stream, buf[:n]); err != nil {
+			return errors.Wrapf(err, "failed read chunk at %d", offset)
+		}
+		if err := d.uploadChunk(ctx, session, buf[:n], offset, size); err != nil {
+			return err
+		}
+		offset += n
+		if up != nil {
+			up(int(offset * 100 / size))
+		}
+	}
+	return nil
+}
+
+// uploadChunk put the chunk at the offset, retry the rest of it a few times if failed
+func (d *GoogleDrive) uploadChunk(ctx context.Context, session string, chunk []byte, offset, size int64) error {
+	var err error
+	sent := int64(0)
+	for retry := 0; retry < 3; retry++ {
+		if retry > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(retry) * time.Second):
+			}
+			// ask the server how many bytes it has received
+			var received int64
+			received, err = d.uploadStatus(ctx, session, size)
+			if err != nil {
+				continue
+			}
+			// the bytes before the chunk are gone, or more than sent are claimed
+			if received < offset || received > offset+int64(len(chunk)) {
+				return errors.Errorf("the session received %d bytes, out of the chunk at %d-%d", received, offset, offset+int64(len(chunk)))
+			}
+			sent = received - offset
+			if sent == int64(len(chunk)) {
+				return nil
+			}
+		}
+		err = d.putChunk(ctx, session, chunk[sent:], offset+sent, size)
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.WithMessagef(err, "failed upload chunk at %d", offset)
+}
+
+func (d *GoogleDrive) putChunk(ctx context.Context, session string, chunk []byte, offset, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, bytes.NewReader(chunk))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(chunk) == 0 {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	} else {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, size))
+	}
+	res, err := d.uploadClient.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_ = res.Body.Close()
+	// 308 means the server is waiting for the rest
+	if res.StatusCode == http.StatusOK || res.StatusCode == http.StatusCreated || res.StatusCode == http.StatusPermanentRedirect {
+		return nil
+	}
+	return errors.WithMessage(errs.NewStatusError(res), "unexpected status")
+}
+
+// uploadStatus return the number of bytes received by the session
+func (d *GoogleDrive) uploadStatus(ctx context.Context, session string, size int64) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, nil)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	res, err := d.uploadClient.Do(req)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	_ = res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return size, nil
+	case http.StatusPermanentRedirect:
+		// such as bytes=0-1048575, no header if nothing is received
+		r := res.Header.Get("Range")
+		if r == "" {
+			return 0, nil
+		}
+		end, err := strconv.ParseInt(r[strings.LastIndex(r, "-")+1:], 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid range %s", r)
+		}
+		return end + 1, nil
+	}
+	return 0, errors.WithMessage(errs.NewStatusError(res), "unexpected status")
+}
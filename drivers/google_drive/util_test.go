@@ -0,0 +1,97 @@
+package google_drive
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/pkg/errors"
+)
+
+func TestDo(t *testing.T) {
+	rateLimitBackoff = time.Millisecond
+	d := &GoogleDrive{}
+	refreshToken := "refresh"
+	d.tokens = operations.NewTokenManager(d, &refreshToken, func(ctx context.Context, refreshToken string) (*operations.OAuthToken, error) {
+		return &operations.OAuthToken{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}, nil
+	})
+	defer d.tokens.Close()
+	const (
+		rateLimited   = `{"error":{"errors":[{"reason":"userRateLimitExceeded"}],"message":"rate limited"}}`
+		quotaExceeded = `{"error":{"errors":[{"reason":"downloadQuotaExceeded"}],"message":"quota exceeded"}}`
+	)
+	for _, c := range []struct {
+		name     string
+		failures int // the responses failed before succeeding
+		body     string
+		ok       bool
+		quota    bool
+	}{
+		{name: "rate limited for a while", failures: 2, body: rateLimited, ok: true},
+		{name: "rate limited", failures: rateLimitRetries + 1, body: rateLimited},
+		{name: "quota exceeded", failures: 1, body: quotaExceeded, quota: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) <= int32(c.failures) {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(c.body))
+				}
+			}))
+			defer server.Close()
+			res, err := d.do(context.Background(), http.DefaultClient, func(token string) (*http.Request, error) {
+				return http.NewRequest(http.MethodGet, server.URL, nil)
+			})
+			if res != nil {
+				_ = res.Body.Close()
+			}
+			if (err == nil) != c.ok || errors.Is(errors.Cause(err), errs.QuotaExceeded) != c.quota {
+				t.Errorf("unexpected error: %+v", err)
+			}
+		})
+	}
+}
+
+func TestUploadChunk(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		received string // the range the session reports after the failed put
+		ok       bool
+	}{
+		{name: "resume the rest", received: "bytes=0-5", ok: true},
+		{name: "lost the bytes before the chunk", received: "bytes=0-1", ok: false},
+		{name: "claim more than sent", received: "bytes=0-11", ok: false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var puts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.ContentLength == 0 {
+					w.Header().Set("Range", c.received)
+					w.WriteHeader(http.StatusPermanentRedirect)
+					return
+				}
+				// the first put of the chunk fails
+				if atomic.AddInt32(&puts, 1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if r.Header.Get("Content-Range") != "bytes 6-7/10" {
+					t.Errorf("expected the rest of the chunk, got %s", r.Header.Get("Content-Range"))
+				}
+				w.WriteHeader(http.StatusPermanentRedirect)
+			}))
+			defer server.Close()
+			d := &GoogleDrive{uploadClient: http.DefaultClient}
+			err := d.uploadChunk(context.Background(), server.URL, []byte("abcd"), 4, 10)
+			if (err == nil) != c.ok {
+				t.Errorf("unexpected error: %+v", err)
+			}
+		})
+	}
+}
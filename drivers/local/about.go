@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd
+
+package local
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func (d *Local) About(ctx context.Context) (*model.StorageUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(d.RootFolder, &st); err != nil {
+		return nil, errors.Wrap(err, "failed statfs")
+	}
+	bsize := int64(st.Bsize)
+	total := int64(st.Blocks) * bsize
+	free := int64(st.Bavail) * bsize
+	return &model.StorageUsage{
+		Total: total,
+		Used:  total - int64(st.Bfree)*bsize,
+		Free:  free,
+	}, nil
+}
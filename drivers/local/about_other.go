@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd)
+
+package local
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func (d *Local) About(ctx context.Context) (*model.StorageUsage, error) {
+	return nil, errors.WithStack(errs.NotSupport)
+}
@@ -3,6 +3,7 @@ package local
 import (
 	"context"
 	"github.com/alist-org/alist/v3/internal/errs"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -18,6 +19,12 @@ import (
 type Local struct {
 	model.Storage
 	Addition
+	// parsed from the addition, 0 perm and -1 id mean unchanged
+	filePerm os.FileMode
+	dirPerm  os.FileMode
+	uid, gid int
+	// the root folder with symlinks resolved, used to restrict the paths
+	realRoot string
 }
 
 func (d *Local) Config() driver.Config {
@@ -30,9 +37,11 @@ func (d *Local) Init(ctx context.Context, storage model.Storage) error {
 	if err != nil {
 		return errors.Wrap(err, "error while unmarshal addition")
 	}
+	if err := d.parseOptions(); err != nil {
+		return err
+	}
 	if !utils.Exists(d.RootFolder) {
 		err = errors.Errorf("root folder %s not exists", d.RootFolder)
-		d.SetStatus(err.Error())
 	} else {
 		if !filepath.IsAbs(d.RootFolder) {
 			d.RootFolder, err = filepath.Abs(d.RootFolder)
@@ -40,7 +49,10 @@ func (d *Local) Init(ctx context.Context, storage model.Storage) error {
 				return errors.Wrap(err, "error while get abs path")
 			}
 		}
-		d.SetStatus("OK")
+		d.realRoot, err = filepath.EvalSymlinks(d.RootFolder)
+		if err != nil {
+			return errors.Wrap(err, "error while resolve root folder")
+		}
 	}
 	operations.MustSaveDriverStorage(d)
 	return err
@@ -56,15 +68,24 @@ func (d *Local) GetAddition() driver.Additional {
 
 func (d *Local) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
 	fullPath := dir.GetID()
+	if err := d.checkPath(fullPath); err != nil {
+		return nil, err
+	}
 	rawFiles, err := ioutil.ReadDir(fullPath)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error while read dir %s", fullPath)
 	}
 	var files []model.Obj
 	for _, f := range rawFiles {
-		if strings.HasPrefix(f.Name(), ".") {
+		if !d.ShowHidden && strings.HasPrefix(f.Name(), ".") {
 			continue
 		}
+		// the links are listed as they are by default, as the old versions did
+		if f.Mode()&os.ModeSymlink != 0 && (d.Symlinks == "follow" || d.Symlinks == "hide") {
+			if f, err = d.followSymlink(filepath.Join(fullPath, f.Name())); err != nil {
+				continue
+			}
+		}
 		file := model.Object{
 			Name:     f.Name(),
 			Modified: f.ModTime(),
@@ -77,8 +98,21 @@ func (d *Local) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
 }
 
 func (d *Local) Get(ctx context.Context, path string) (model.Obj, error) {
-	f, err := os.Stat(path)
+	if err := d.checkPath(path); err != nil {
+		return nil, err
+	}
+	f, err := os.Lstat(path)
+	if err == nil && f.Mode()&os.ModeSymlink != 0 {
+		if d.Symlinks == "follow" || d.Symlinks == "hide" {
+			f, err = d.followSymlink(path)
+		} else {
+			f, err = os.Stat(path)
+		}
+	}
 	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.WithStack(errs.ObjectNotFound)
+		}
 		return nil, errors.Wrapf(err, "error while stat %s", path)
 	}
 	file := model.Object{
@@ -93,6 +127,9 @@ func (d *Local) Get(ctx context.Context, path string) (model.Obj, error) {
 
 func (d *Local) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
 	fullPath := file.GetID()
+	if err := d.checkPath(fullPath); err != nil {
+		return nil, err
+	}
 	link := model.Link{
 		FilePath: &fullPath,
 	}
@@ -101,16 +138,22 @@ func (d *Local) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (
 
 func (d *Local) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
 	fullPath := filepath.Join(parentDir.GetID(), dirName)
-	err := os.MkdirAll(fullPath, 0700)
+	if err := d.checkPath(fullPath); err != nil {
+		return err
+	}
+	err := os.MkdirAll(fullPath, d.dirPerm)
 	if err != nil {
 		return errors.Wrapf(err, "error while make dir %s", fullPath)
 	}
-	return nil
+	return d.setMeta(fullPath, d.dirPerm)
 }
 
 func (d *Local) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
 	srcPath := srcObj.GetID()
 	dstPath := filepath.Join(dstDir.GetID(), srcObj.GetName())
+	if err := d.checkPaths(srcPath, dstPath); err != nil {
+		return err
+	}
 	err := os.Rename(srcPath, dstPath)
 	if err != nil {
 		return errors.Wrapf(err, "error while move %s to %s", srcPath, dstPath)
@@ -121,6 +164,9 @@ func (d *Local) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
 func (d *Local) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
 	srcPath := srcObj.GetID()
 	dstPath := filepath.Join(filepath.Dir(srcPath), newName)
+	if err := d.checkPaths(srcPath, dstPath); err != nil {
+		return err
+	}
 	err := os.Rename(srcPath, dstPath)
 	if err != nil {
 		return errors.Wrapf(err, "error while rename %s to %s", srcPath, dstPath)
@@ -131,6 +177,9 @@ func (d *Local) Rename(ctx context.Context, srcObj model.Obj, newName string) er
 func (d *Local) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
 	srcPath := srcObj.GetID()
 	dstPath := filepath.Join(dstDir.GetID(), srcObj.GetName())
+	if err := d.checkPaths(srcPath, dstPath); err != nil {
+		return err
+	}
 	var err error
 	if srcObj.IsDir() {
 		err = copyDir(srcPath, dstPath)
@@ -144,6 +193,9 @@ func (d *Local) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
 }
 
 func (d *Local) Remove(ctx context.Context, obj model.Obj) error {
+	if err := d.checkPath(obj.GetID()); err != nil {
+		return err
+	}
 	var err error
 	if obj.IsDir() {
 		err = os.RemoveAll(obj.GetID())
@@ -158,6 +210,9 @@ func (d *Local) Remove(ctx context.Context, obj model.Obj) error {
 
 func (d *Local) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
 	fullPath := filepath.Join(dstDir.GetID(), stream.GetName())
+	if err := d.checkPath(fullPath); err != nil {
+		return err
+	}
 	out, err := os.Create(fullPath)
 	if err != nil {
 		return errors.Wrapf(err, "error while create file %s", fullPath)
@@ -172,6 +227,43 @@ func (d *Local) Put(ctx context.Context, dstDir model.Obj, stream model.FileStre
 	if err != nil {
 		return errors.Wrapf(err, "error while copy file %s", fullPath)
 	}
+	return d.setMeta(fullPath, d.filePerm)
+}
+
+func (d *Local) Append(ctx context.Context, file model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	fullPath := file.GetID()
+	if err := d.checkPath(fullPath); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return errors.Wrapf(err, "error while open file %s", fullPath)
+	}
+	defer out.Close()
+	err = utils.CopyWithCtx(ctx, out, stream)
+	if err != nil {
+		return errors.Wrapf(err, "error while append file %s", fullPath)
+	}
+	return nil
+}
+
+func (d *Local) Patch(ctx context.Context, file model.Obj, offset int64, stream model.FileStreamer, up driver.UpdateProgress) error {
+	fullPath := file.GetID()
+	if err := d.checkPath(fullPath); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(fullPath, os.O_WRONLY, 0)
+	if err != nil {
+		return errors.Wrapf(err, "error while open file %s", fullPath)
+	}
+	defer out.Close()
+	if _, err := out.Seek(offset, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "error while seek file %s", fullPath)
+	}
+	err = utils.CopyWithCtx(ctx, out, stream)
+	if err != nil {
+		return errors.Wrapf(err, "error while patch file %s", fullPath)
+	}
 	return nil
 }
 
@@ -180,3 +272,5 @@ func (d *Local) Other(ctx context.Context, data interface{}) (interface{}, error
 }
 
 var _ driver.Driver = (*Local)(nil)
+var _ driver.Append = (*Local)(nil)
+var _ driver.Patch = (*Local)(nil)
@@ -7,6 +7,14 @@ import (
 
 type Addition struct {
 	driver.RootFolderPath
+	Symlinks   string `json:"symlinks,omitempty" type:"select" values:"keep,follow,hide" default:"keep" help:"list the symlinks as they are, show them as their targets, or hide them"`
+	ShowHidden bool   `json:"show_hidden,omitempty" help:"show the files starting with a dot"`
+	// the options are omitted if empty, so the addition of the old storages is unchanged.
+	// the permissions are set explicitly, so the umask doesn't apply
+	FilePerm     string `json:"file_perm,omitempty" help:"octal permission of the created files, such as 0644, empty to use the default"`
+	DirPerm      string `json:"dir_perm,omitempty" default:"0700" help:"octal permission of the created folders"`
+	Owner        string `json:"owner,omitempty" help:"uid:gid of the created files and folders, not supported on windows"`
+	RestrictRoot bool   `json:"restrict_root,omitempty" default:"true" help:"reject the paths resolved outside the root folder by symlinks"`
 }
 
 var config = driver.Config{
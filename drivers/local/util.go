@@ -6,8 +6,144 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/pkg/errors"
 )
 
+// parseOptions parse the permissions and the owner of the created files and folders
+func (d *Local) parseOptions() error {
+	d.filePerm, d.dirPerm = 0, 0700
+	if d.FilePerm != "" {
+		perm, err := strconv.ParseUint(d.FilePerm, 8, 32)
+		if err != nil {
+			return errors.Wrapf(err, "invalid file permission %s", d.FilePerm)
+		}
+		d.filePerm = os.FileMode(perm)
+	}
+	if d.DirPerm != "" {
+		perm, err := strconv.ParseUint(d.DirPerm, 8, 32)
+		if err != nil {
+			return errors.Wrapf(err, "invalid folder permission %s", d.DirPerm)
+		}
+		d.dirPerm = os.FileMode(perm)
+	}
+	d.uid, d.gid = -1, -1
+	if d.Owner == "" {
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return errors.New("owner is not supported on windows")
+	}
+	uid, gid, _ := strings.Cut(d.Owner, ":")
+	var err error
+	if d.uid, err = strconv.Atoi(uid); err != nil {
+		return errors.Wrapf(err, "invalid owner %s", d.Owner)
+	}
+	if gid != "" {
+		if d.gid, err = strconv.Atoi(gid); err != nil {
+			return errors.Wrapf(err, "invalid owner %s", d.Owner)
+		}
+	}
+	return nil
+}
+
+// setMeta set the permission and the owner of the created file or folder
+func (d *Local) setMeta(fullPath string, perm os.FileMode) error {
+	if perm != 0 {
+		if err := os.Chmod(fullPath, perm); err != nil {
+			return errors.Wrapf(err, "error while chmod %s", fullPath)
+		}
+	}
+	if d.uid >= 0 || d.gid >= 0 {
+		if err := os.Chown(fullPath, d.uid, d.gid); err != nil {
+			return errors.Wrapf(err, "error while chown %s", fullPath)
+		}
+	}
+	return nil
+}
+
+// checkPath reject the path resolved outside the root folder by symlinks if restricted,
+// the path not exists yet is checked by its nearest existing parent
+func (d *Local) checkPath(fullPath string) error {
+	if !d.RestrictRoot || d.realRoot == "" {
+		return nil
+	}
+	for p := fullPath; ; {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			if !isWithin(d.realRoot, real) {
+				return errors.Wrapf(errs.PermissionDenied, "%s is outside the root folder", fullPath)
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return errors.Wrapf(err, "error while resolve %s", p)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return nil
+		}
+		p = parent
+	}
+}
+
+func (d *Local) checkPaths(paths ...string) error {
+	for _, p := range paths {
+		if err := d.checkPath(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// followSymlink return the info of the target named as the link, os.ErrNotExist if the symlinks
+// are hidden, the target is not allowed, or it's a folder visited on the way to the link,
+// which would make the walks of the folders endless
+func (d *Local) followSymlink(fullPath string) (os.FileInfo, error) {
+	if d.Symlinks == "hide" || d.checkPath(fullPath) != nil {
+		return nil, os.ErrNotExist
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		target, err := filepath.EvalSymlinks(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		parent, err := filepath.EvalSymlinks(filepath.Dir(fullPath))
+		if err != nil {
+			return nil, err
+		}
+		// the real paths visited on the way are the ancestors of the real parent
+		if isWithin(target, parent) {
+			return nil, os.ErrNotExist
+		}
+	}
+	return namedInfo{FileInfo: info, name: filepath.Base(fullPath)}, nil
+}
+
+// namedInfo is the info of the symlink target with the name of the link
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (n namedInfo) Name() string {
+	return n.name
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // copyFile File copies a single file from src to dst
 func copyFile(src, dst string) error {
 	var err error
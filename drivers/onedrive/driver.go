@@ -0,0 +1,174 @@
+package onedrive
+
+import (
+	"context"
+	"net/http"
+	stdpath "path"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// OneDrive mount a drive by the microsoft graph api, such as the personal or business onedrive,
+// or the document library of a sharepoint site
+type OneDrive struct {
+	model.Storage
+	Addition
+	host host
+	// client for the metadata calls, uploadClient for the uploads without timeout
+	client       *http.Client
+	uploadClient *http.Client
+	tokens       *operations.TokenManager
+}
+
+func (d *OneDrive) Config() driver.Config {
+	return config
+}
+
+func (d *OneDrive) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if d.Region == "" {
+		d.Region = "global"
+	}
+	var ok bool
+	d.host, ok = hosts[d.Region]
+	if !ok {
+		return errors.Errorf("unsupported region: %s", d.Region)
+	}
+	d.client = net.APIClient(d.ClientNetwork())
+	d.uploadClient = net.Client(d.ClientNetwork())
+	d.tokens = operations.NewTokenManager(d, &d.RefreshToken, d.refreshToken)
+	root, err := d.getItem(ctx, d.RootFolder)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			return errors.Errorf("root folder %s not exists", d.RootFolder)
+		}
+		return err
+	}
+	if root.Folder == nil {
+		return errors.Errorf("root folder %s is not a folder", d.RootFolder)
+	}
+	return nil
+}
+
+func (d *OneDrive) Drop(ctx context.Context) error {
+	if d.tokens != nil {
+		d.tokens.Close()
+	}
+	return nil
+}
+
+func (d *OneDrive) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *OneDrive) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	items, err := d.getChildren(ctx, dir.GetID())
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]model.Obj, 0, len(items))
+	for _, i := range items {
+		objs = append(objs, i.toObj(stdpath.Join(dir.GetID(), i.Name)))
+	}
+	return objs, nil
+}
+
+func (d *OneDrive) Get(ctx context.Context, path string) (model.Obj, error) {
+	i, err := d.getItem(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return i.toObj(path), nil
+}
+
+func (d *OneDrive) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	i, err := d.getItem(ctx, file.GetID())
+	if err != nil {
+		return nil, err
+	}
+	if i.DownloadUrl == "" {
+		return nil, errors.Errorf("no download url of %s", file.GetID())
+	}
+	// the download url is pre-authenticated and valid for about an hour
+	exp := 30 * time.Minute
+	return &model.Link{URL: i.DownloadUrl, Expiration: &exp}, nil
+}
+
+func (d *OneDrive) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	body := map[string]interface{}{
+		"name":                              dirName,
+		"folder":                            map[string]interface{}{},
+		"@microsoft.graph.conflictBehavior": "fail",
+	}
+	return d.request(ctx, http.MethodPost, d.itemUrl(parentDir.GetID())+"/children", body, nil)
+}
+
+// parentReference return the reference of the folder used by move and copy
+func (d *OneDrive) parentReference(ctx context.Context, dir string) (map[string]interface{}, error) {
+	i, err := d.getItem(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"driveId": i.ParentReference.DriveID, "id": i.ID}, nil
+}
+
+func (d *OneDrive) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ref, err := d.parentReference(ctx, dstDir.GetID())
+	if err != nil {
+		return err
+	}
+	body := map[string]interface{}{"parentReference": ref}
+	return d.request(ctx, http.MethodPatch, d.itemUrl(srcObj.GetID()), body, nil)
+}
+
+func (d *OneDrive) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	body := map[string]interface{}{"name": newName}
+	return d.request(ctx, http.MethodPatch, d.itemUrl(srcObj.GetID()), body, nil)
+}
+
+// Copy is done by microsoft in the background, it returns once the copy is accepted
+func (d *OneDrive) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ref, err := d.parentReference(ctx, dstDir.GetID())
+	if err != nil {
+		return err
+	}
+	body := map[string]interface{}{"parentReference": ref, "name": srcObj.GetName()}
+	return d.request(ctx, http.MethodPost, d.itemUrl(srcObj.GetID())+"/copy", body, nil)
+}
+
+func (d *OneDrive) Remove(ctx context.Context, obj model.Obj) error {
+	return d.request(ctx, http.MethodDelete, d.itemUrl(obj.GetID()), nil, nil)
+}
+
+func (d *OneDrive) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	return d.upload(ctx, dstDir.GetID(), stream, up)
+}
+
+func (d *OneDrive) About(ctx context.Context) (*model.StorageUsage, error) {
+	var res drive
+	if err := d.request(ctx, http.MethodGet, d.driveUrl(), nil, &res); err != nil {
+		return nil, err
+	}
+	return &model.StorageUsage{
+		Total: res.Quota.Total,
+		Used:  res.Quota.Used,
+		Free:  res.Quota.Remaining,
+	}, nil
+}
+
+func (d *OneDrive) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*OneDrive)(nil)
@@ -0,0 +1,33 @@
+package onedrive
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	Region       string `json:"region" type:"select" values:"global,cn,us,de" default:"global"`
+	ClientID     string `json:"client_id" required:"true"`
+	ClientSecret string `json:"client_secret" required:"true"`
+	RedirectUri  string `json:"redirect_uri" required:"true" default:"https://alist.nn.ci/tool/onedrive/callback"`
+	RefreshToken string `json:"refresh_token" required:"true"`
+	// the drive of the account is used if both are empty
+	SiteID    string `json:"site_id" help:"the id of the sharepoint site, its default document library is mounted"`
+	DriveID   string `json:"drive_id" help:"the id of the drive, takes precedence over the site"`
+	ChunkSize int    `json:"chunk_size" type:"number" default:"10" help:"MB of each request of the upload sessions"`
+}
+
+var config = driver.Config{
+	Name:        "OneDrive",
+	LocalSort:   true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &OneDrive{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
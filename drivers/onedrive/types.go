@@ -0,0 +1,74 @@
+package onedrive
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+type host struct {
+	oauth string
+	api   string
+}
+
+// the endpoints of the national clouds
+var hosts = map[string]host{
+	"global": {oauth: "https://login.microsoftonline.com", api: "https://graph.microsoft.com"},
+	"cn":     {oauth: "https://login.chinacloudapi.cn", api: "https://microsoftgraph.chinacloudapi.cn"},
+	"us":     {oauth: "https://login.microsoftonline.us", api: "https://graph.microsoft.us"},
+	"de":     {oauth: "https://login.microsoftonline.de", api: "https://graph.microsoft.de"},
+}
+
+type item struct {
+	ID                   string    `json:"id"`
+	Name                 string    `json:"name"`
+	Size                 int64     `json:"size"`
+	LastModifiedDateTime time.Time `json:"lastModifiedDateTime"`
+	Folder               *struct{} `json:"folder"`
+	DownloadUrl          string    `json:"@microsoft.graph.downloadUrl"`
+	ParentReference      struct {
+		DriveID string `json:"driveId"`
+	} `json:"parentReference"`
+}
+
+func (i item) toObj(path string) model.Obj {
+	return &model.Object{
+		ID:       path,
+		Name:     i.Name,
+		Size:     i.Size,
+		Modified: i.LastModifiedDateTime,
+		IsFolder: i.Folder != nil,
+	}
+}
+
+type children struct {
+	Value    []item `json:"value"`
+	NextLink string `json:"@odata.nextLink"`
+}
+
+type drive struct {
+	Quota struct {
+		Total     int64 `json:"total"`
+		Used      int64 `json:"used"`
+		Remaining int64 `json:"remaining"`
+	} `json:"quota"`
+}
+
+type uploadSession struct {
+	UploadUrl string `json:"uploadUrl"`
+}
+
+type apiError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type tokenResp struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
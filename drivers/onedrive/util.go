@@ -0,0 +1,259 @@
+package onedrive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// the files not larger than it are uploaded in one request
+	simpleUploadLimit = 4 * 1024 * 1024
+	// the size of the chunks of upload sessions must be a multiple of 320 KiB
+	chunkUnit = 320 * 1024
+)
+
+// driveUrl return the api url of the mounted drive
+func (d *OneDrive) driveUrl() string {
+	api := d.host.api + "/v1.0"
+	switch {
+	case d.DriveID != "":
+		return api + "/drives/" + d.DriveID
+	case d.SiteID != "":
+		return api + "/sites/" + d.SiteID + "/drive"
+	default:
+		return api + "/me/drive"
+	}
+}
+
+// itemUrl return the api url of the item at the path of the drive
+func (d *OneDrive) itemUrl(path string) string {
+	u := d.driveUrl() + "/root"
+	if path = strings.Trim(path, "/"); path != "" {
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		u += ":/" + strings.Join(segments, "/") + ":"
+	}
+	return u
+}
+
+// refreshToken exchange the refresh token for the access token,
+// the refresh token is rotated by microsoft, so the new one is returned
+func (d *OneDrive) refreshToken(ctx context.Context, refreshToken string) (*operations.OAuthToken, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {d.ClientID},
+		"client_secret": {d.ClientSecret},
+		"redirect_uri":  {d.RedirectUri},
+		"refresh_token": {refreshToken},
+	}
+	u := d.host.oauth + "/common/oauth2/v2.0/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed refresh token")
+	}
+	defer res.Body.Close()
+	var token tokenResp
+	if err := utils.Json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, errors.Wrapf(err, "failed decode token: %s", res.Status)
+	}
+	if token.Error == "invalid_grant" {
+		return nil, errors.Wrap(errs.RefreshTokenInvalid, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.Errorf("failed refresh token: %s %s", token.Error, token.ErrorDescription)
+	}
+	return &operations.OAuthToken{
+		AccessToken:  token.AccessToken,
+		Expiry:       time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+		RefreshToken: token.RefreshToken,
+	}, nil
+}
+
+// request call the graph api with the json body and decode the json response into res
+func (d *OneDrive) request(ctx context.Context, method, u string, body, res interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = utils.Json.Marshal(body)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	resp, err := d.do(ctx, method, u, bytes.NewReader(data), int64(len(data)), body != nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if res == nil {
+		return nil
+	}
+	return errors.Wrap(utils.Json.NewDecoder(resp.Body).Decode(res), "failed decode onedrive response")
+}
+
+// do send the request with the access token, the error responses are converted to errors
+func (d *OneDrive) do(ctx context.Context, method, u string, body io.ReadSeeker, size int64, isJson bool) (*http.Response, error) {
+	for retried := false; ; retried = true {
+		token, err := d.tokens.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, io.NopCloser(body))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		req.ContentLength = size
+		req.Header.Set("Authorization", "Bearer "+token)
+		if isJson {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		res, err := d.client.Do(req)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed request onedrive")
+		}
+		if res.StatusCode < 400 {
+			return res, nil
+		}
+		var apiErr apiError
+		_ = utils.Json.NewDecoder(res.Body).Decode(&apiErr)
+		_ = res.Body.Close()
+		// the token may be revoked before it's expired
+		if res.StatusCode == http.StatusUnauthorized && !retried {
+			d.tokens.Invalidate(token)
+			continue
+		}
+		if res.StatusCode == http.StatusNotFound {
+			return nil, errors.WithStack(errs.ObjectNotFound)
+		}
+		if res.StatusCode == http.StatusInsufficientStorage || apiErr.Error.Code == "quotaLimitReached" {
+			return nil, errors.Wrapf(errs.QuotaExceeded, "failed request onedrive: %s", apiErr.Error.Message)
+		}
+		if apiErr.Error.Message != "" {
+			return nil, errors.WithMessagef(errs.NewStatusError(res), "failed request onedrive: %s", apiErr.Error.Message)
+		}
+		return nil, errors.WithMessage(errs.NewStatusError(res), "failed request onedrive")
+	}
+}
+
+func (d *OneDrive) getItem(ctx context.Context, path string) (*item, error) {
+	var i item
+	if err := d.request(ctx, http.MethodGet, d.itemUrl(path), nil, &i); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (d *OneDrive) getChildren(ctx context.Context, path string) ([]item, error) {
+	var items []item
+	u := d.itemUrl(path) + "/children?$top=1000"
+	for u != "" {
+		var res children
+		if err := d.request(ctx, http.MethodGet, u, nil, &res); err != nil {
+			return nil, err
+		}
+		items = append(items, res.Value...)
+		u = res.NextLink
+	}
+	return items, nil
+}
+
+// upload the small file in one request, the larger ones with an upload session
+func (d *OneDrive) upload(ctx context.Context, dir string, stream model.FileStreamer, up driver.UpdateProgress) error {
+	path := stdpath.Join(dir, stream.GetName())
+	size := stream.GetSize()
+	if size <= simpleUploadLimit {
+		data, err := io.ReadAll(io.LimitReader(stream, size))
+		if err != nil {
+			return errors.Wrap(err, "failed read stream")
+		}
+		res, err := d.do(ctx, http.MethodPut, d.itemUrl(path)+"/content", bytes.NewReader(data), size, false)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+		return nil
+	}
+	var session uploadSession
+	body := map[string]interface{}{
+		"item": map[string]interface{}{"@microsoft.graph.conflictBehavior": "replace"},
+	}
+	if err := d.request(ctx, http.MethodPost, d.itemUrl(path)+"/createUploadSession", body, &session); err != nil {
+		return errors.WithMessage(err, "failed create upload session")
+	}
+	err := d.uploadChunks(ctx, session.UploadUrl, stream, up)
+	if err != nil {
+		// cancel the session with a new context, the one of the task may be canceled
+		req, reqErr := http.NewRequestWithContext(context.Background(), http.MethodDelete, session.UploadUrl, nil)
+		if reqErr == nil {
+			if res, reqErr := d.uploadClient.Do(req); reqErr == nil {
+				_ = res.Body.Close()
+			}
+		}
+	}
+	return err
+}
+
+// uploadChunks put the stream to the session chunk by chunk, the url of the session needs no token
+func (d *OneDrive) uploadChunks(ctx context.Context, uploadUrl string, stream model.FileStreamer, up driver.UpdateProgress) error {
+	size := stream.GetSize()
+	chunkSize := int64(d.ChunkSize) * 1024 * 1024
+	if chunkSize <= 0 {
+		chunkSize = 10 * 1024 * 1024
+	}
+	chunkSize = chunkSize / chunkUnit * chunkUnit
+	buf := make([]byte, chunkSize)
+	for offset := int64(0); offset < size; {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		n := chunkSize
+		if size-offset < n {
+			n = size - offset
+		}
+		if _, err := io.ReadFull(stream, buf[:n]); err != nil {
+			return errors.Wrapf(err, "failed read chunk at %d", offset)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadUrl, bytes.NewReader(buf[:n]))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+n-1, size))
+		res, err := d.uploadClient.Do(req)
+		if err != nil {
+			return errors.Wrapf(err, "failed upload chunk at %d", offset)
+		}
+		_ = res.Body.Close()
+		// 202 for the chunks accepted, 200 or 201 for the last one
+		if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
+			return errors.WithMessagef(errs.NewStatusError(res), "failed upload chunk at %d", offset)
+		}
+		offset += n
+		if up != nil {
+			up(int(offset * 100 / size))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the limit of parts in a multipart upload
+const maxParts = 10000
+
+// S3 mount a bucket of s3 or a compatible object store, such as minio and wasabi
+type S3 struct {
+	model.Storage
+	Addition
+	endpoint *url.URL
+	signer   *signer // nil for the public bucket
+	// client for the metadata calls, uploadClient for the uploads without timeout
+	client       *http.Client
+	uploadClient *http.Client
+}
+
+func (d *S3) Config() driver.Config {
+	return config
+}
+
+func (d *S3) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	d.endpoint, err = url.Parse(strings.TrimSuffix(strings.TrimSpace(d.Endpoint), "/"))
+	if err != nil || d.endpoint.Host == "" {
+		return errors.Errorf("invalid endpoint: %s", d.Endpoint)
+	}
+	if d.Bucket == "" {
+		return errors.New("bucket is required")
+	}
+	if d.Region == "" {
+		d.Region = "us-east-1"
+	}
+	d.signer = nil
+	if d.AccessKeyID != "" {
+		d.signer = &signer{accessKeyID: d.AccessKeyID, secretAccessKey: d.SecretAccessKey, region: d.Region}
+	}
+	d.client = net.APIClient(d.ClientNetwork())
+	d.uploadClient = net.Client(d.ClientNetwork())
+	// make sure the bucket is accessible
+	_, err = d.list(ctx, utils.StandardizePath(d.RootFolder))
+	if errs.IsObjectNotFound(err) {
+		return errors.Errorf("root folder %s not exists", d.RootFolder)
+	}
+	return err
+}
+
+func (d *S3) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *S3) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *S3) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	return d.list(ctx, utils.StandardizePath(dir.GetID()))
+}
+
+func (d *S3) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	u := d.objectURL(getKey(utils.StandardizePath(file.GetID()), false), nil)
+	if d.signer == nil {
+		return &model.Link{URL: u.String()}, nil
+	}
+	expires := time.Duration(d.SignURLExpire) * time.Hour
+	if expires <= 0 {
+		expires = 4 * time.Hour
+	}
+	return &model.Link{URL: d.signer.presign(http.MethodGet, u, time.Now().UTC(), expires)}, nil
+}
+
+func (d *S3) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	// the empty placeholder makes the folder visible
+	key := getKey(stdpath.Join(utils.StandardizePath(parentDir.GetID()), dirName), true)
+	return d.do(ctx, http.MethodPut, key, nil, []byte{}, nil, nil)
+}
+
+func (d *S3) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	srcPath := utils.StandardizePath(srcObj.GetID())
+	dstPath := stdpath.Join(utils.StandardizePath(dstDir.GetID()), srcObj.GetName())
+	if err := d.copyPath(ctx, srcPath, dstPath, srcObj.IsDir()); err != nil {
+		return err
+	}
+	return d.removePath(ctx, srcPath, srcObj.IsDir())
+}
+
+func (d *S3) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	srcPath := utils.StandardizePath(srcObj.GetID())
+	dstPath := stdpath.Join(stdpath.Dir(srcPath), newName)
+	if err := d.copyPath(ctx, srcPath, dstPath, srcObj.IsDir()); err != nil {
+		return err
+	}
+	return d.removePath(ctx, srcPath, srcObj.IsDir())
+}
+
+func (d *S3) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	srcPath := utils.StandardizePath(srcObj.GetID())
+	dstPath := stdpath.Join(utils.StandardizePath(dstDir.GetID()), srcObj.GetName())
+	return d.copyPath(ctx, srcPath, dstPath, srcObj.IsDir())
+}
+
+func (d *S3) Remove(ctx context.Context, obj model.Obj) error {
+	return d.removePath(ctx, utils.StandardizePath(obj.GetID()), obj.IsDir())
+}
+
+func (d *S3) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	key := getKey(stdpath.Join(utils.StandardizePath(dstDir.GetID()), stream.GetName()), false)
+	partSize := int64(d.PartSize) * 1024 * 1024
+	if partSize <= 0 {
+		partSize = 16 * 1024 * 1024
+	}
+	header := http.Header{}
+	if mimetype := stream.GetMimetype(); mimetype != "" {
+		header.Set("Content-Type", mimetype)
+	}
+	if stream.GetSize() <= partSize {
+		res, err := d.request(ctx, d.uploadClient, http.MethodPut, key, nil, io.LimitReader(stream, stream.GetSize()), stream.GetSize(), header)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+		return nil
+	}
+	return d.multipartUpload(ctx, key, stream, partSize, header, up)
+}
+
+// multipartUpload upload the stream in parts, the upload is aborted if any part fails
+func (d *S3) multipartUpload(ctx context.Context, key string, stream model.FileStreamer, partSize int64, header http.Header, up driver.UpdateProgress) error {
+	size := stream.GetSize()
+	if (size+partSize-1)/partSize > maxParts {
+		partSize = (size + maxParts - 1) / maxParts
+	}
+	var initiated initiateMultipartUploadResult
+	if err := d.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, []byte{}, header, &initiated); err != nil {
+		return errors.WithMessage(err, "failed create multipart upload")
+	}
+	uploadId := url.Values{"uploadId": {initiated.UploadId}}
+	err := d.uploadParts(ctx, key, initiated.UploadId, stream, partSize, up)
+	if err != nil {
+		// abort with a new context, the one of the task may be canceled
+		if abortErr := d.do(context.Background(), http.MethodDelete, key, uploadId, nil, nil, nil); abortErr != nil {
+			log.Warnf("failed abort multipart upload of %s: %+v", key, abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (d *S3) uploadParts(ctx context.Context, key, uploadId string, stream model.FileStreamer, partSize int64, up driver.UpdateProgress) error {
+	size := stream.GetSize()
+	buf := make([]byte, partSize)
+	var complete completeMultipartUpload
+	var uploaded int64
+	for partNumber := 1; uploaded < size; partNumber++ {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		n := partSize
+		if size-uploaded < n {
+			n = size - uploaded
+		}
+		_, err := io.ReadFull(stream, buf[:n])
+		if err != nil {
+			return errors.Wrapf(err, "failed read part %d", partNumber)
+		}
+		query := url.Values{}
+		query.Set("partNumber", fmt.Sprint(partNumber))
+		query.Set("uploadId", uploadId)
+		res, err := d.request(ctx, d.uploadClient, http.MethodPut, key, query, bytes.NewReader(buf[:n]), n, nil)
+		if err != nil {
+			return errors.WithMessagef(err, "failed upload part %d", partNumber)
+		}
+		_ = res.Body.Close()
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: partNumber, ETag: res.Header.Get("ETag")})
+		uploaded += n
+		if up != nil {
+			up(int(uploaded * 100 / size))
+		}
+	}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var res struct {
+		Key string `xml:"Key"`
+	}
+	err = d.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadId}}, body, nil, &res)
+	return errors.WithMessage(err, "failed complete multipart upload")
+}
+
+func (d *S3) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*S3)(nil)
@@ -0,0 +1,32 @@
+package s3
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	Bucket          string `json:"bucket" required:"true"`
+	Endpoint        string `json:"endpoint" required:"true" format:"url" help:"such as https://s3.us-east-1.amazonaws.com or http://127.0.0.1:9000"`
+	Region          string `json:"region" default:"us-east-1"`
+	AccessKeyID     string `json:"access_key_id" help:"empty for the public bucket"`
+	SecretAccessKey string `json:"secret_access_key"`
+	ForcePathStyle  bool   `json:"force_path_style" help:"use https://endpoint/bucket instead of https://bucket.endpoint, required by most minio setups"`
+	SignURLExpire   int    `json:"sign_url_expire" type:"number" default:"4" help:"hours of the presigned download urls"`
+	PartSize        int    `json:"part_size" type:"number" default:"16" help:"MB, the larger files are uploaded in parts"`
+}
+
+var config = driver.Config{
+	Name:        "S3",
+	LocalSort:   true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &S3{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
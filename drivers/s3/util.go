@@ -0,0 +1,252 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// getKey return the object key of the path, the keys of folders end with a slash
+func getKey(path string, dir bool) string {
+	key := strings.TrimPrefix(path, "/")
+	if dir && key != "" {
+		key += "/"
+	}
+	return key
+}
+
+// objectURL build the url of the key in the bucket with the addressing style configured
+func (d *S3) objectURL(key string, query url.Values) *url.URL {
+	u := *d.endpoint
+	path := "/" + key
+	if d.ForcePathStyle {
+		path = "/" + d.Bucket + path
+	} else {
+		u.Host = d.Bucket + "." + u.Host
+	}
+	u.Path = path
+	u.RawPath = escape(path, true)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// request send the signed request, the caller should close the body of the response
+func (d *S3) request(ctx context.Context, client *http.Client, method, key string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if body != nil {
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if d.signer != nil {
+		d.signer.sign(req, time.Now().UTC())
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed %s %s", method, key)
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	var e errorResponse
+	_ = xml.NewDecoder(res.Body).Decode(&e)
+	if e.Code == "NoSuchKey" || method == http.MethodHead && res.StatusCode == http.StatusNotFound {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	if e.Code == "" {
+		return nil, errors.WithMessagef(errs.NewStatusError(res), "failed %s %s", method, key)
+	}
+	return nil, errors.WithMessagef(errs.NewStatusError(res), "failed %s %s: %s %s", method, key, e.Code, e.Message)
+}
+
+// do send the request and decode the xml response into out if not nil
+func (d *S3) do(ctx context.Context, method, key string, query url.Values, body []byte, header http.Header, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	res, err := d.request(ctx, d.client, method, key, query, reader, int64(len(body)), header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if out == nil {
+		return nil
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// some calls respond 200 with an error
+	var e struct {
+		XMLName xml.Name
+		errorResponse
+	}
+	if xml.Unmarshal(data, &e) == nil && e.XMLName.Local == "Error" {
+		return errors.Errorf("failed %s %s: %s %s", method, key, e.Code, e.Message)
+	}
+	return errors.Wrap(xml.Unmarshal(data, out), "failed decode response")
+}
+
+// listObjects list the objs under the prefix, or all objs in the sub folders if recursive
+func (d *S3) listObjects(ctx context.Context, prefix string, recursive bool, handle func(res *listBucketResult) error) error {
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if !recursive {
+			query.Set("delimiter", "/")
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		var res listBucketResult
+		if err := d.do(ctx, http.MethodGet, "", query, nil, nil, &res); err != nil {
+			return err
+		}
+		if err := handle(&res); err != nil {
+			return err
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+func (d *S3) list(ctx context.Context, path string) ([]model.Obj, error) {
+	prefix := getKey(path, true)
+	var objs []model.Obj
+	// there is no folder in s3, but the empty one may have a placeholder
+	exists := prefix == ""
+	err := d.listObjects(ctx, prefix, false, func(res *listBucketResult) error {
+		for _, p := range res.CommonPrefixes {
+			name := stdpath.Base(strings.TrimSuffix(p.Prefix, "/"))
+			objs = append(objs, &model.Object{
+				ID:       stdpath.Join(path, name),
+				Name:     name,
+				IsFolder: true,
+			})
+		}
+		for _, c := range res.Contents {
+			if c.Key == prefix {
+				exists = true
+				continue
+			}
+			name := stdpath.Base(c.Key)
+			objs = append(objs, &model.Object{
+				ID:       stdpath.Join(path, name),
+				Name:     name,
+				Size:     c.Size,
+				Modified: c.LastModified,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 && !exists {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	return objs, nil
+}
+
+func (d *S3) copyObject(ctx context.Context, srcKey, dstKey string) error {
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", escape("/"+d.Bucket+"/"+srcKey, true))
+	var res struct {
+		ETag string `xml:"ETag"`
+	}
+	return d.do(ctx, http.MethodPut, dstKey, nil, nil, header, &res)
+}
+
+// copyPath copy the obj at the path, all objs under it if it's a folder
+func (d *S3) copyPath(ctx context.Context, srcPath, dstPath string, dir bool) error {
+	if !dir {
+		return d.copyObject(ctx, getKey(srcPath, false), getKey(dstPath, false))
+	}
+	srcPrefix, dstPrefix := getKey(srcPath, true), getKey(dstPath, true)
+	return d.listObjects(ctx, srcPrefix, true, func(res *listBucketResult) error {
+		for _, c := range res.Contents {
+			if err := d.copyObject(ctx, c.Key, dstPrefix+strings.TrimPrefix(c.Key, srcPrefix)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// removePath remove the obj at the path, all objs under it if it's a folder
+func (d *S3) removePath(ctx context.Context, path string, dir bool) error {
+	if !dir {
+		return d.do(ctx, http.MethodDelete, getKey(path, false), nil, nil, nil, nil)
+	}
+	var keys []string
+	err := d.listObjects(ctx, getKey(path, true), true, func(res *listBucketResult) error {
+		for _, c := range res.Contents {
+			keys = append(keys, c.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := d.do(ctx, http.MethodDelete, key, nil, nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package sftp
+
+import (
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// client is a connection to the server, used by one request at a time
+type client struct {
+	*sftp.Client
+	conn   *ssh.Client
+	closed chan struct{}
+}
+
+func newClient(conn *ssh.Client) (*client, error) {
+	sc, err := sftp.NewClient(conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed start sftp")
+	}
+	c := &client{Client: sc, conn: conn, closed: make(chan struct{})}
+	// the connection may be lost while idle
+	go func() {
+		_ = sc.Wait()
+		close(c.closed)
+	}()
+	return c, nil
+}
+
+func (c *client) Close() error {
+	_ = c.Client.Close()
+	return c.conn.Close()
+}
+
+func (c *client) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,392 @@
+package sftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	stdpath "path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	inet "github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+type SFTP struct {
+	model.Storage
+	Addition
+	mu sync.Mutex
+	// the connected clients not in use, a client serves one request at a time
+	idle []*client
+	// limit the clients in use and idle
+	sem     chan struct{}
+	dropped bool
+}
+
+func (d *SFTP) Config() driver.Config {
+	return config
+}
+
+func (d *SFTP) Capabilities() driver.Capabilities {
+	caps := driver.AllCapabilities()
+	// sftp has no server side copy, the copies are downloaded and uploaded again
+	caps.Copy, caps.CopyDir = false, false
+	return caps
+}
+
+func (d *SFTP) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if _, _, err := net.SplitHostPort(d.Address); err != nil {
+		d.Address = net.JoinHostPort(d.Address, "22")
+	}
+	if d.MaxConnections <= 0 {
+		d.MaxConnections = 1
+	}
+	d.sem = make(chan struct{}, d.MaxConnections)
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.putClient(c)
+	info, err := c.Stat(d.RootFolder)
+	if err != nil {
+		return errors.WithMessagef(err, "failed stat root folder %s", d.RootFolder)
+	}
+	if !info.IsDir() {
+		return errors.Errorf("root folder %s is not a folder", d.RootFolder)
+	}
+	return nil
+}
+
+// getClient take an idle client from the pool or connect a new one if the max connections
+// is not reached, the client must be put back after use
+func (d *SFTP) getClient(ctx context.Context) (*client, error) {
+	select {
+	case d.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	d.mu.Lock()
+	for len(d.idle) > 0 {
+		c := d.idle[len(d.idle)-1]
+		d.idle = d.idle[:len(d.idle)-1]
+		// the connection may be lost while idle
+		if !c.isClosed() {
+			d.mu.Unlock()
+			return c, nil
+		}
+	}
+	d.mu.Unlock()
+	c, err := d.connect()
+	if err != nil {
+		<-d.sem
+		return nil, err
+	}
+	return c, nil
+}
+
+// putClient put the client back to the pool, the broken one is dropped
+func (d *SFTP) putClient(c *client) {
+	d.mu.Lock()
+	if c.isClosed() || d.dropped {
+		_ = c.Close()
+	} else {
+		d.idle = append(d.idle, c)
+	}
+	d.mu.Unlock()
+	<-d.sem
+}
+
+func (d *SFTP) connect() (*client, error) {
+	var auth []ssh.AuthMethod
+	if d.PrivateKey != "" {
+		var signer ssh.Signer
+		var err error
+		if d.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(d.PrivateKey), []byte(d.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(d.PrivateKey))
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed parse private key")
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	} else {
+		auth = append(auth, ssh.Password(d.Password))
+	}
+	dialer, err := inet.NewDialer(d.Network)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	raw, err := dialer.DialContext(ctx, "tcp", d.Address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed connect %s", d.Address)
+	}
+	// the handshake is limited by the timeout as well
+	_ = raw.SetDeadline(time.Now().Add(30 * time.Second))
+	sshConn, chans, reqs, err := ssh.NewClientConn(raw, d.Address, &ssh.ClientConfig{
+		User:            d.Username,
+		Auth:            auth,
+		HostKeyCallback: d.checkHostKey,
+	})
+	if err != nil {
+		_ = raw.Close()
+		return nil, errors.Wrapf(err, "failed connect %s", d.Address)
+	}
+	_ = raw.SetDeadline(time.Time{})
+	conn := ssh.NewClient(sshConn, chans, reqs)
+	c, err := newClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkHostKey compare the host key with the approved one, the unknown host key is never
+// trusted, the admin has to approve it by setting its fingerprint
+func (d *SFTP) checkHostKey(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+	if d.HostKey == "" {
+		return errors.Errorf("the host key of %s is %s, set it as the host key to approve it", hostname, fingerprint)
+	}
+	if d.HostKey != fingerprint {
+		return errors.Errorf("host key mismatch, expected %s, got %s", d.HostKey, fingerprint)
+	}
+	return nil
+}
+
+func (d *SFTP) Drop(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dropped = true
+	for _, c := range d.idle {
+		_ = c.Close()
+	}
+	d.idle = nil
+	return nil
+}
+
+func (d *SFTP) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *SFTP) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer d.putClient(c)
+	infos, err := c.ReadDir(dir.GetID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed read dir %s", dir.GetID())
+	}
+	objs := make([]model.Obj, 0, len(infos))
+	for _, info := range infos {
+		objs = append(objs, &model.Object{
+			ID:       stdpath.Join(dir.GetID(), info.Name()),
+			Name:     info.Name(),
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+			IsFolder: info.IsDir(),
+		})
+	}
+	return objs, nil
+}
+
+func (d *SFTP) Get(ctx context.Context, path string) (model.Obj, error) {
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer d.putClient(c)
+	// the path is relative to the storage, the ids are the paths on the server
+	id := stdpath.Join(d.RootFolder, path)
+	info, err := c.Stat(id)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errors.WithStack(errs.ObjectNotFound)
+		}
+		return nil, errors.WithMessagef(err, "failed stat %s", path)
+	}
+	return &model.Object{
+		ID:       id,
+		Name:     stdpath.Base(path),
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+		IsFolder: info.IsDir(),
+	}, nil
+}
+
+type rangeReader struct {
+	io.Reader
+	io.Closer
+}
+
+// pooledFile put the client back to the pool once the file is closed
+type pooledFile struct {
+	*sftp.File
+	put  func()
+	once sync.Once
+}
+
+func (f *pooledFile) Close() error {
+	err := f.File.Close()
+	f.once.Do(f.put)
+	return err
+}
+
+// progressWriter report the progress of the upload by the bytes written
+type progressWriter struct {
+	io.Writer
+	written int64
+	size    int64
+	up      driver.UpdateProgress
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.written += int64(n)
+	if w.up != nil && w.size > 0 {
+		w.up(int(w.written * 100 / w.size))
+	}
+	return n, err
+}
+
+func (d *SFTP) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.Open(file.GetID())
+	if err != nil {
+		d.putClient(c)
+		return nil, errors.WithMessagef(err, "failed open %s", file.GetID())
+	}
+	// the client is used by the reader until it's closed
+	rc := &pooledFile{File: f, put: func() { d.putClient(c) }}
+	link := &model.Link{Data: rc}
+	start, end, ok := utils.ParseRange(args.Header.Get("Range"), file.GetSize())
+	if ok {
+		if _, err := f.Seek(start, io.SeekStart); err != nil {
+			_ = rc.Close()
+			return nil, errors.Wrapf(err, "failed seek %s", file.GetID())
+		}
+		link.Data = rangeReader{Reader: io.LimitReader(rc, end-start+1), Closer: rc}
+		link.Status = http.StatusPartialContent
+		link.Header = http.Header{
+			"Content-Range":  []string{fmt.Sprintf("bytes %d-%d/%d", start, end, file.GetSize())},
+			"Content-Length": []string{strconv.FormatInt(end-start+1, 10)},
+			"Accept-Ranges":  []string{"bytes"},
+		}
+	}
+	return link, nil
+}
+
+func (d *SFTP) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.putClient(c)
+	return c.Mkdir(stdpath.Join(parentDir.GetID(), dirName))
+}
+
+func (d *SFTP) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.putClient(c)
+	return c.Rename(srcObj.GetID(), stdpath.Join(dstDir.GetID(), srcObj.GetName()))
+}
+
+func (d *SFTP) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.putClient(c)
+	return c.Rename(srcObj.GetID(), stdpath.Join(stdpath.Dir(srcObj.GetID()), newName))
+}
+
+func (d *SFTP) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	// sftp has no server side copy
+	return errs.NotSupport
+}
+
+func (d *SFTP) Remove(ctx context.Context, obj model.Obj) error {
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.putClient(c)
+	return d.remove(ctx, c, obj.GetID(), obj.IsDir())
+}
+
+// remove the file or the folder with all its children
+func (d *SFTP) remove(ctx context.Context, c *client, path string, dir bool) error {
+	if !dir {
+		return c.Remove(path)
+	}
+	infos, err := c.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		if err := d.remove(ctx, c, stdpath.Join(path, info.Name()), info.IsDir()); err != nil {
+			return err
+		}
+	}
+	return c.RemoveDirectory(path)
+}
+
+func (d *SFTP) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	c, err := d.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.putClient(c)
+	path := stdpath.Join(dstDir.GetID(), stream.GetName())
+	f, err := c.Create(path)
+	if err != nil {
+		return errors.WithMessagef(err, "failed create %s", path)
+	}
+	err = utils.CopyWithCtx(ctx, &progressWriter{Writer: f, size: stream.GetSize(), up: up}, stream)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			_ = c.Remove(path)
+		}
+		return errors.WithMessagef(err, "failed upload %s", path)
+	}
+	return nil
+}
+
+func (d *SFTP) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*SFTP)(nil)
+var _ driver.Capable = (*SFTP)(nil)
@@ -0,0 +1,129 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alist-org/alist/v3/drivers/drivertest"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// serve the sftp subsystem on a local port, only the password "pwd" is accepted
+func serve(t *testing.T) (string, string) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generate key: %+v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("failed create signer: %+v", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "pwd" {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listen: %+v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn, config)
+		}
+	}()
+	return l.Addr().String(), ssh.FingerprintSHA256(signer.PublicKey())
+}
+
+func handle(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		ch, chReqs, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range chReqs {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(ok, nil)
+				if ok {
+					server, err := sftp.NewServer(ch)
+					if err == nil {
+						_ = server.Serve()
+					}
+					_ = ch.Close()
+				}
+			}
+		}()
+	}
+}
+
+func TestSFTP(t *testing.T) {
+	address, hostKey := serve(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("failed write file: %+v", err)
+	}
+	addition, err := utils.Json.MarshalToString(map[string]interface{}{
+		"root_folder": dir,
+		"address":     address,
+		"username":    "user",
+		"password":    "pwd",
+		"host_key":    hostKey,
+	})
+	if err != nil {
+		t.Fatalf("failed marshal addition: %+v", err)
+	}
+	s := drivertest.Create(t, model.Storage{Driver: "SFTP", MountPath: "/sftp", Addition: addition})
+	defer operations.DeleteStorageById(context.Background(), s.GetStorage().ID)
+
+	objs, err := operations.List(context.Background(), s, "/")
+	if err != nil || len(objs) != 1 || objs[0].GetName() != "a.txt" || objs[0].GetSize() != 10 {
+		t.Fatalf("unexpected objs: %+v %+v", objs, err)
+	}
+	link, obj, err := operations.Link(context.Background(), s, "/a.txt", model.LinkArgs{Header: http.Header{"Range": []string{"bytes=2-5"}}})
+	if err != nil {
+		t.Fatalf("failed link: %+v", err)
+	}
+	data, err := io.ReadAll(link.Data)
+	_ = link.Data.Close()
+	if err != nil || string(data) != "2345" || link.Status != http.StatusPartialContent {
+		t.Errorf("unexpected range of %s: %q %+v", obj.GetName(), data, err)
+	}
+	content := bytes.Repeat([]byte("x"), 100*1024)
+	err = operations.Put(context.Background(), s, "/", &model.FileStream{
+		Obj:        &model.Object{Name: "b.txt", Size: int64(len(content))},
+		ReadCloser: io.NopCloser(bytes.NewReader(content)),
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed put: %+v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "b.txt")); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("unexpected uploaded file: %d bytes %+v", len(got), err)
+	}
+}
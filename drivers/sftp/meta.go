@@ -0,0 +1,33 @@
+package sftp
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	Address    string `json:"address" required:"true" help:"host:port, the port is 22 if omitted"`
+	Username   string `json:"username" required:"true"`
+	Password   string `json:"password"`
+	PrivateKey string `json:"private_key" type:"text" help:"the private key in pem format, used instead of the password if set"`
+	Passphrase string `json:"passphrase" help:"the passphrase of the private key"`
+	HostKey    string `json:"host_key" help:"the SHA256 fingerprint of the host key, the connection is refused with the fingerprint of the server if it's empty"`
+	// a client serves one request at a time, the link being read holds one
+	MaxConnections int `json:"max_connections" type:"number" default:"4" help:"the max sftp connections to the server"`
+}
+
+var config = driver.Config{
+	Name:        "SFTP",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &SFTP{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
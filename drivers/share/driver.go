@@ -0,0 +1,126 @@
+package share
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Share mount a public bucket or the shared link of others read-only,
+// no credential of the account is required
+type Share struct {
+	model.Storage
+	Addition
+	client *http.Client
+	source source
+}
+
+// source is the provider of the shared files, the paths are relative to the share
+type source interface {
+	list(ctx context.Context, path string) ([]model.Obj, error)
+	link(ctx context.Context, path string) (*model.Link, error)
+}
+
+func (d *Share) Config() driver.Config {
+	return config
+}
+
+func (d *Share) Capabilities() driver.Capabilities {
+	// the shares are read-only
+	return driver.Capabilities{Range: true}
+}
+
+func (d *Share) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	d.URL = strings.TrimSpace(d.URL)
+	if d.URL == "" {
+		return errors.New("url is required")
+	}
+	// nobody can write to the share of others
+	d.Storage.ReadOnly = true
+	d.client = net.APIClient(d.ClientNetwork())
+	switch d.Type {
+	case TypeS3:
+		source := &s3Source{client: d.client, url: strings.TrimSuffix(d.URL, "/")}
+		for _, mirror := range strings.Split(d.Mirrors, "\n") {
+			if mirror = strings.TrimSuffix(strings.TrimSpace(mirror), "/"); mirror != "" {
+				source.mirrors = append(source.mirrors, mirror)
+			}
+		}
+		d.source = source
+	case TypeOneDrive:
+		d.source = newOneDriveSource(d.client, d.URL)
+	case TypeGoogleDrive:
+		if d.source, err = newGoogleDriveSource(d.client, d.URL, d.APIKey); err != nil {
+			return err
+		}
+		// the links carry the api key in the header, they can't be redirected to
+		d.Storage.WebProxy = true
+		if d.Storage.Webdav302() {
+			d.Storage.WebdavPolicy = "native_proxy"
+		}
+	default:
+		return errors.Errorf("unsupported share type: %s", d.Type)
+	}
+	// make sure the share is accessible
+	_, err = d.source.list(ctx, utils.StandardizePath(d.RootFolder))
+	return err
+}
+
+func (d *Share) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *Share) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *Share) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	return d.source.list(ctx, utils.StandardizePath(dir.GetID()))
+}
+
+func (d *Share) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	return d.source.link(ctx, utils.StandardizePath(file.GetID()))
+}
+
+func (d *Share) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	return errs.StorageReadOnly
+}
+
+func (d *Share) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return errs.StorageReadOnly
+}
+
+func (d *Share) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return errs.StorageReadOnly
+}
+
+func (d *Share) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return errs.StorageReadOnly
+}
+
+func (d *Share) Remove(ctx context.Context, obj model.Obj) error {
+	return errs.StorageReadOnly
+}
+
+func (d *Share) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	return errs.StorageReadOnly
+}
+
+func (d *Share) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*Share)(nil)
+var _ driver.Capable = (*Share)(nil)
@@ -0,0 +1,162 @@
+package share
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/drivers/drivertest"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+func TestS3Mirrors(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+	// the bucket stalls after the first bytes of the file
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/" && r.URL.Query().Get("list-type") == "2":
+			fmt.Fprintf(w, `<ListBucketResult><Contents><Key>movie.mkv</Key><Size>%d</Size></Contents></ListBucketResult>`, len(content))
+		case r.URL.Path == "/movie.mkv" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/movie.mkv":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(content[:100])
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer bucket.Close()
+	// the mirror answers slower, so the bucket is tried first
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/movie.mkv" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodHead {
+			time.Sleep(200 * time.Millisecond)
+		}
+		http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(content))
+	}))
+	defer mirror.Close()
+
+	addition, err := utils.Json.MarshalToString(map[string]interface{}{
+		"root_folder": "/",
+		"type":        TypeS3,
+		"url":         bucket.URL,
+		"mirrors":     "\n" + mirror.URL + "/\n",
+	})
+	if err != nil {
+		t.Fatalf("failed marshal addition: %+v", err)
+	}
+	s := drivertest.Create(t, model.Storage{
+		Driver:       "Share",
+		MountPath:    "/share",
+		Addition:     addition,
+		Accelerate:   true,
+		StallTimeout: 1,
+	})
+	link, _, err := operations.Link(context.Background(), s, "/movie.mkv", model.LinkArgs{})
+	if err != nil {
+		t.Fatalf("failed link: %+v", err)
+	}
+	if link.URL != bucket.URL+"/movie.mkv" {
+		t.Fatalf("expected the bucket is the fastest, got %s", link.URL)
+	}
+	if len(link.Mirrors) != 1 || link.Mirrors[0] != mirror.URL+"/movie.mkv" {
+		t.Fatalf("expected the mirror, got %v", link.Mirrors)
+	}
+	res, err := http.Get(link.URL)
+	if err != nil {
+		t.Fatalf("failed get: %+v", err)
+	}
+	r := operations.NewMirrorReader(context.Background(), http.DefaultClient, link, res)
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed read: %+v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("expected the rest is read from the mirror, got %d bytes", len(got))
+	}
+}
+
+func TestGoogleDrive(t *testing.T) {
+	files := map[string]string{
+		"root": `{"files":[{"id":"sub","name":"sub","mimeType":"application/vnd.google-apps.folder"},{"id":"doc","name":"doc","mimeType":"application/vnd.google-apps.document"}]}`,
+		"sub":  `{"files":[{"id":"b","name":"b.txt","mimeType":"text/plain","size":"5","modifiedTime":"2024-01-02T03:04:05Z"}]}`,
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Goog-Api-Key") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.URL.Path == "/files":
+			var parent string
+			if _, err := fmt.Sscanf(r.URL.Query().Get("q"), "'%s", &parent); err != nil {
+				t.Errorf("failed parse query: %+v", err)
+			}
+			body, ok := files[strings.TrimSuffix(parent, "'")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(body))
+		case r.URL.Path == "/files/b" && r.URL.Query().Get("alt") == "media":
+			_, _ = w.Write([]byte("hello"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+	defer func(api string) { googleDriveApi = api }(googleDriveApi)
+	googleDriveApi = api.URL
+	addition, err := utils.Json.MarshalToString(map[string]interface{}{
+		"root_folder": "/",
+		"type":        TypeGoogleDrive,
+		"url":         "https://drive.google.com/drive/folders/root?usp=sharing",
+		"api_key":     "key",
+	})
+	if err != nil {
+		t.Fatalf("failed marshal addition: %+v", err)
+	}
+	s := drivertest.Create(t, model.Storage{Driver: "Share", MountPath: "/gdrive", Addition: addition})
+	if !s.GetStorage().WebProxy {
+		t.Errorf("expected the downloads are proxied to keep the api key")
+	}
+	objs, err := operations.List(context.Background(), s, "/")
+	if err != nil {
+		t.Fatalf("failed list: %+v", err)
+	}
+	if len(objs) != 1 || objs[0].GetName() != "sub" || !objs[0].IsDir() {
+		t.Fatalf("expected only the sub folder without the google doc, got %+v", objs)
+	}
+	// the ids of the folders are looked up by listing the parents
+	link, _, err := operations.Link(context.Background(), s, "/sub/b.txt", model.LinkArgs{})
+	if err != nil {
+		t.Fatalf("failed link: %+v", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, link.URL, nil)
+	if err != nil {
+		t.Fatalf("failed new request: %+v", err)
+	}
+	req.Header = link.Header
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed get: %+v", err)
+	}
+	defer res.Body.Close()
+	if got, _ := io.ReadAll(res.Body); string(got) != "hello" {
+		t.Errorf("expected the content of the file, got %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package share
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+var googleDriveApi = "https://www.googleapis.com/drive/v3"
+
+const googleDriveFolder = "application/vnd.google-apps.folder"
+
+// the folder id in https://drive.google.com/drive/folders/<id> or https://drive.google.com/open?id=<id>
+var googleDriveFolderId = regexp.MustCompile(`(?:/folders/|[?&]id=)([\w-]+)`)
+
+// googleDriveSource access a folder shared to anyone with the link by the drive api,
+// the requests are made with the api key of the admin instead of an account
+type googleDriveSource struct {
+	client *http.Client
+	apiKey string
+	// the file ids by the paths in the share, filled by the listings
+	ids generic_sync.MapOf[string, string]
+}
+
+type googleDriveFile struct {
+	Id           string    `json:"id"`
+	Name         string    `json:"name"`
+	MimeType     string    `json:"mimeType"`
+	Size         int64     `json:"size,string"`
+	ModifiedTime time.Time `json:"modifiedTime"`
+}
+
+type googleDriveFiles struct {
+	Files         []googleDriveFile `json:"files"`
+	NextPageToken string            `json:"nextPageToken"`
+}
+
+func newGoogleDriveSource(client *http.Client, shareUrl, apiKey string) (*googleDriveSource, error) {
+	m := googleDriveFolderId.FindStringSubmatch(shareUrl)
+	if m == nil {
+		return nil, errors.Errorf("no folder id in the google drive link: %s", shareUrl)
+	}
+	if apiKey == "" {
+		return nil, errors.New("the api key is required for google drive")
+	}
+	s := &googleDriveSource{client: client, apiKey: apiKey}
+	s.ids.Store("/", m[1])
+	return s, nil
+}
+
+// fileId return the id of the file at the path, the parents are listed if it's unknown
+func (s *googleDriveSource) fileId(ctx context.Context, path string) (string, error) {
+	if id, ok := s.ids.Load(path); ok {
+		return id, nil
+	}
+	if _, err := s.list(ctx, stdpath.Dir(path)); err != nil {
+		return "", err
+	}
+	if id, ok := s.ids.Load(path); ok {
+		return id, nil
+	}
+	return "", errors.WithStack(errs.ObjectNotFound)
+}
+
+func (s *googleDriveSource) list(ctx context.Context, path string) ([]model.Obj, error) {
+	id, err := s.fileId(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("q", "'"+id+"' in parents and trashed = false")
+	query.Set("fields", "nextPageToken,files(id,name,mimeType,size,modifiedTime)")
+	query.Set("pageSize", "1000")
+	query.Set("supportsAllDrives", "true")
+	query.Set("includeItemsFromAllDrives", "true")
+	var objs []model.Obj
+	for {
+		var res googleDriveFiles
+		if err := s.get(ctx, googleDriveApi+"/files?"+query.Encode(), &res); err != nil {
+			return nil, err
+		}
+		for _, file := range res.Files {
+			isFolder := file.MimeType == googleDriveFolder
+			// the google docs have no content to download, only the exports
+			if !isFolder && strings.HasPrefix(file.MimeType, "application/vnd.google-apps.") {
+				continue
+			}
+			p := strings.TrimSuffix(path, "/") + "/" + file.Name
+			s.ids.Store(p, file.Id)
+			objs = append(objs, &model.Object{
+				ID:       p,
+				Name:     file.Name,
+				Size:     file.Size,
+				Modified: file.ModifiedTime,
+				IsFolder: isFolder,
+			})
+		}
+		if res.NextPageToken == "" {
+			return objs, nil
+		}
+		query.Set("pageToken", res.NextPageToken)
+	}
+}
+
+func (s *googleDriveSource) link(ctx context.Context, path string) (*model.Link, error) {
+	id, err := s.fileId(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	// the key is sent in the header, so the downloads are proxied to keep it from the users
+	return &model.Link{
+		URL:    googleDriveApi + "/files/" + url.PathEscape(id) + "?alt=media&supportsAllDrives=true",
+		Header: http.Header{"X-Goog-Api-Key": []string{s.apiKey}},
+	}, nil
+}
+
+func (s *googleDriveSource) get(ctx context.Context, u string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("X-Goog-Api-Key", s.apiKey)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed request google drive")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.WithStack(errs.ObjectNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed request google drive: %s", resp.Status)
+	}
+	return errors.Wrap(utils.Json.NewDecoder(resp.Body).Decode(res), "failed decode google drive response")
+}
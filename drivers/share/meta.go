@@ -0,0 +1,37 @@
+package share
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+const (
+	TypeS3          = "s3"
+	TypeOneDrive    = "onedrive"
+	TypeGoogleDrive = "google_drive"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	Type string `json:"type" type:"select" values:"s3,onedrive,google_drive" default:"s3" required:"true"`
+	URL  string `json:"url" required:"true" format:"url" help:"the public bucket url, such as https://bucket.s3.amazonaws.com, or the shared folder link"`
+	// google drive has no anonymous api, the requests are made with the key of a cloud project
+	APIKey string `json:"api_key" help:"google drive only, the api key of a google cloud project with the drive api enabled"`
+	// the replicas of a bucket or the cdn in front of it serve the same keys
+	Mirrors string `json:"mirrors" type:"text" help:"s3 only, the other urls of the bucket such as the cdn or the replicas, one per line, switched to if faster or the transfer stalls with acceleration"`
+}
+
+var config = driver.Config{
+	Name:        "Share",
+	LocalSort:   true,
+	NoUpload:    true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &Share{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
@@ -0,0 +1,111 @@
+package share
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const oneDriveApi = "https://api.onedrive.com/v1.0"
+
+// oneDriveSource access an anonymous shared folder link by the shares api
+type oneDriveSource struct {
+	client *http.Client
+	// the encoded sharing url, see https://learn.microsoft.com/onedrive/developer/rest-api/api/shares_get
+	token string
+}
+
+type oneDriveItem struct {
+	Name                 string    `json:"name"`
+	Size                 int64     `json:"size"`
+	LastModifiedDateTime time.Time `json:"lastModifiedDateTime"`
+	Folder               *struct{} `json:"folder"`
+	DownloadUrl          string    `json:"@content.downloadUrl"`
+}
+
+type oneDriveChildren struct {
+	Value    []oneDriveItem `json:"value"`
+	NextLink string         `json:"@odata.nextLink"`
+}
+
+func newOneDriveSource(client *http.Client, shareUrl string) *oneDriveSource {
+	return &oneDriveSource{
+		client: client,
+		token:  "u!" + base64.RawURLEncoding.EncodeToString([]byte(shareUrl)),
+	}
+}
+
+// itemUrl return the api url of the item at the path in the share
+func (s *oneDriveSource) itemUrl(path string) string {
+	u := oneDriveApi + "/shares/" + s.token + "/root"
+	if path = strings.Trim(path, "/"); path != "" {
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		u += ":/" + strings.Join(segments, "/") + ":"
+	}
+	return u
+}
+
+func (s *oneDriveSource) list(ctx context.Context, path string) ([]model.Obj, error) {
+	var objs []model.Obj
+	u := s.itemUrl(path) + "/children?$top=1000"
+	for u != "" {
+		var res oneDriveChildren
+		if err := s.get(ctx, u, &res); err != nil {
+			return nil, err
+		}
+		for _, item := range res.Value {
+			objs = append(objs, &model.Object{
+				ID:       strings.TrimSuffix(path, "/") + "/" + item.Name,
+				Name:     item.Name,
+				Size:     item.Size,
+				Modified: item.LastModifiedDateTime,
+				IsFolder: item.Folder != nil,
+			})
+		}
+		u = res.NextLink
+	}
+	return objs, nil
+}
+
+func (s *oneDriveSource) link(ctx context.Context, path string) (*model.Link, error) {
+	var item oneDriveItem
+	if err := s.get(ctx, s.itemUrl(path), &item); err != nil {
+		return nil, err
+	}
+	if item.DownloadUrl == "" {
+		return nil, errors.New("the share doesn't allow downloading")
+	}
+	// the download url is valid for about an hour
+	exp := 30 * time.Minute
+	return &model.Link{URL: item.DownloadUrl, Expiration: &exp}, nil
+}
+
+func (s *oneDriveSource) get(ctx context.Context, u string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed request onedrive")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.WithStack(errs.ObjectNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed request onedrive: %s", resp.Status)
+	}
+	return errors.Wrap(utils.Json.NewDecoder(resp.Body).Decode(res), "failed decode onedrive response")
+}
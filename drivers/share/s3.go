@@ -0,0 +1,120 @@
+package share
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// s3Source list a public bucket with ListObjectsV2, both the path-style url
+// (https://endpoint/bucket) and the virtual-host one (https://bucket.endpoint) work.
+// the files are served by the mirrors too, but only listed from the url
+type s3Source struct {
+	client  *http.Client
+	url     string
+	mirrors []string
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+func (s *s3Source) list(ctx context.Context, path string) ([]model.Obj, error) {
+	prefix := strings.TrimPrefix(path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	var objs []model.Obj
+	// there is no folder in s3, but the empty one may have a placeholder
+	exists := prefix == ""
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("delimiter", "/")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		var res listBucketResult
+		if err := s.get(ctx, s.url+"/?"+query.Encode(), &res); err != nil {
+			return nil, err
+		}
+		for _, p := range res.CommonPrefixes {
+			name := stdpath.Base(strings.TrimSuffix(p.Prefix, "/"))
+			objs = append(objs, &model.Object{
+				ID:       stdpath.Join(path, name),
+				Name:     name,
+				IsFolder: true,
+			})
+		}
+		for _, c := range res.Contents {
+			// the placeholder of the folder itself
+			if c.Key == prefix {
+				exists = true
+				continue
+			}
+			name := stdpath.Base(c.Key)
+			objs = append(objs, &model.Object{
+				ID:       stdpath.Join(path, name),
+				Name:     name,
+				Size:     c.Size,
+				Modified: c.LastModified,
+			})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+		token = res.NextContinuationToken
+	}
+	if len(objs) == 0 && !exists {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	return objs, nil
+}
+
+func (s *s3Source) link(ctx context.Context, path string) (*model.Link, error) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	key := "/" + strings.Join(segments, "/")
+	link := &model.Link{URL: s.url + key}
+	for _, mirror := range s.mirrors {
+		link.Mirrors = append(link.Mirrors, mirror+key)
+	}
+	return link, nil
+}
+
+func (s *s3Source) get(ctx context.Context, u string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed list bucket")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed list bucket: %s", resp.Status)
+	}
+	return errors.Wrap(xml.NewDecoder(resp.Body).Decode(res), "failed decode bucket list")
+}
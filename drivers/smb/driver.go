@@ -0,0 +1,327 @@
+package smb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	stdpath "path"
+	"strconv"
+	"sync"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/hirochachacha/go-smb2"
+	"github.com/pkg/errors"
+)
+
+type SMB struct {
+	model.Storage
+	Addition
+	mu      sync.Mutex
+	session *session
+	share   *smb2.Share
+}
+
+func (d *SMB) Config() driver.Config {
+	return config
+}
+
+func (d *SMB) unmarshalAddition(addition string) error {
+	err := utils.Json.UnmarshalFromString(addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	if _, _, err := net.SplitHostPort(d.Address); err != nil {
+		d.Address = net.JoinHostPort(d.Address, "445")
+	}
+	return nil
+}
+
+func (d *SMB) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	if err := d.unmarshalAddition(d.Storage.Addition); err != nil {
+		return err
+	}
+	_ = d.Drop(ctx)
+	return d.withShare(func(s *smb2.Share) error {
+		info, err := s.Stat(toSharePath(d.RootFolder))
+		if err != nil {
+			return errors.WithMessagef(err, "failed stat root folder %s", d.RootFolder)
+		}
+		if !info.IsDir() {
+			return errors.Errorf("root folder %s is not a folder", d.RootFolder)
+		}
+		return nil
+	})
+}
+
+// Enumerate list the shares of the server, so the admin can pick one in the form
+func (d *SMB) Enumerate(ctx context.Context, storage model.Storage, field string) ([]string, error) {
+	if field != "share" {
+		return nil, errs.NotSupport
+	}
+	if err := d.unmarshalAddition(storage.Addition); err != nil {
+		return nil, err
+	}
+	s, err := dial(ctx, d.Addition, d.Network)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed connect %s", d.Address)
+	}
+	defer s.logoff()
+	return listShares(s)
+}
+
+// withShare run f with the mounted share, reconnect if the connection is broken
+func (d *SMB) withShare(f func(s *smb2.Share) error) error {
+	d.mu.Lock()
+	if d.share == nil {
+		s, err := dial(context.Background(), d.Addition, d.Network)
+		if err != nil {
+			d.mu.Unlock()
+			return errors.Wrapf(err, "failed connect %s", d.Address)
+		}
+		share, err := s.Mount(d.Share)
+		if err != nil {
+			s.logoff()
+			d.mu.Unlock()
+			return errors.Wrapf(err, "failed mount share %s", d.Share)
+		}
+		d.session, d.share = s, share
+	}
+	share := d.share
+	d.mu.Unlock()
+	err := f(share)
+	if err != nil && isConnErr(err) {
+		d.mu.Lock()
+		if d.share == share {
+			d.close()
+		}
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// close the share and the session, the caller should hold the lock
+func (d *SMB) close() {
+	if d.share != nil {
+		_ = d.share.Umount()
+		d.share = nil
+	}
+	if d.session != nil {
+		d.session.logoff()
+		d.session = nil
+	}
+}
+
+func (d *SMB) Drop(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.close()
+	return nil
+}
+
+func (d *SMB) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *SMB) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	var infos []os.FileInfo
+	err := d.withShare(func(s *smb2.Share) error {
+		var err error
+		infos, err = s.WithContext(ctx).ReadDir(toSharePath(dir.GetID()))
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed read dir %s", dir.GetID())
+	}
+	objs := make([]model.Obj, 0, len(infos))
+	for _, info := range infos {
+		objs = append(objs, fileToObj(dir.GetID(), info))
+	}
+	return objs, nil
+}
+
+func fileToObj(dir string, info os.FileInfo) model.Obj {
+	return &model.Object{
+		ID:       stdpath.Join(dir, info.Name()),
+		Name:     info.Name(),
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+		IsFolder: info.IsDir(),
+	}
+}
+
+func (d *SMB) Get(ctx context.Context, path string) (model.Obj, error) {
+	var info os.FileInfo
+	err := d.withShare(func(s *smb2.Share) error {
+		var err error
+		info, err = s.WithContext(ctx).Stat(toSharePath(path))
+		return err
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.WithStack(errs.ObjectNotFound)
+		}
+		return nil, errors.WithMessagef(err, "failed stat %s", path)
+	}
+	return fileToObj(stdpath.Dir(path), info), nil
+}
+
+type rangeReader struct {
+	io.Reader
+	io.Closer
+}
+
+func (d *SMB) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	var f *smb2.File
+	err := d.withShare(func(s *smb2.Share) error {
+		var err error
+		f, err = s.Open(toSharePath(file.GetID()))
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed open %s", file.GetID())
+	}
+	link := &model.Link{Data: f}
+	start, end, ok := utils.ParseRange(args.Header.Get("Range"), file.GetSize())
+	if ok {
+		if _, err := f.Seek(start, io.SeekStart); err != nil {
+			_ = f.Close()
+			return nil, errors.WithMessagef(err, "failed seek %s", file.GetID())
+		}
+		link.Data = rangeReader{Reader: io.LimitReader(f, end-start+1), Closer: f}
+		link.Status = http.StatusPartialContent
+		link.Header = http.Header{
+			"Content-Range":  []string{fmt.Sprintf("bytes %d-%d/%d", start, end, file.GetSize())},
+			"Content-Length": []string{strconv.FormatInt(end-start+1, 10)},
+			"Accept-Ranges":  []string{"bytes"},
+		}
+	}
+	return link, nil
+}
+
+func (d *SMB) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	return d.withShare(func(s *smb2.Share) error {
+		return s.WithContext(ctx).Mkdir(toSharePath(stdpath.Join(parentDir.GetID(), dirName)), 0755)
+	})
+}
+
+func (d *SMB) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.withShare(func(s *smb2.Share) error {
+		return s.WithContext(ctx).Rename(toSharePath(srcObj.GetID()), toSharePath(stdpath.Join(dstDir.GetID(), srcObj.GetName())))
+	})
+}
+
+func (d *SMB) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.withShare(func(s *smb2.Share) error {
+		return s.WithContext(ctx).Rename(toSharePath(srcObj.GetID()), toSharePath(stdpath.Join(stdpath.Dir(srcObj.GetID()), newName)))
+	})
+}
+
+func (d *SMB) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.withShare(func(s *smb2.Share) error {
+		return copyAll(ctx, s.WithContext(ctx), srcObj.GetID(), stdpath.Join(dstDir.GetID(), srcObj.GetName()), srcObj.IsDir())
+	})
+}
+
+// copyAll copy the file or the folder with all its children,
+// the content of the files is copied on the server side
+func copyAll(ctx context.Context, s *smb2.Share, src, dst string, dir bool) error {
+	if !dir {
+		srcFile, err := s.Open(toSharePath(src))
+		if err != nil {
+			return err
+		}
+		defer srcFile.Close()
+		dstFile, err := s.Create(toSharePath(dst))
+		if err != nil {
+			return err
+		}
+		_, err = dstFile.ReadFrom(srcFile)
+		if closeErr := dstFile.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+	if err := s.Mkdir(toSharePath(dst), 0755); err != nil {
+		return err
+	}
+	infos, err := s.ReadDir(toSharePath(src))
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		err := copyAll(ctx, s, stdpath.Join(src, info.Name()), stdpath.Join(dst, info.Name()), info.IsDir())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *SMB) Remove(ctx context.Context, obj model.Obj) error {
+	return d.withShare(func(s *smb2.Share) error {
+		s = s.WithContext(ctx)
+		if obj.IsDir() {
+			return s.RemoveAll(toSharePath(obj.GetID()))
+		}
+		return s.Remove(toSharePath(obj.GetID()))
+	})
+}
+
+func (d *SMB) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	path := stdpath.Join(dstDir.GetID(), stream.GetName())
+	err := d.withShare(func(s *smb2.Share) error {
+		f, err := s.Create(toSharePath(path))
+		if err != nil {
+			return err
+		}
+		err = utils.CopyWithCtx(ctx, f, stream)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if errors.Is(err, context.Canceled) {
+			_ = s.Remove(toSharePath(path))
+		}
+		return err
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "failed upload %s", path)
+	}
+	return nil
+}
+
+func (d *SMB) About(ctx context.Context) (*model.StorageUsage, error) {
+	var info smb2.FileFsInfo
+	err := d.withShare(func(s *smb2.Share) error {
+		var err error
+		info, err = s.WithContext(ctx).Statfs(toSharePath(d.RootFolder))
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed statfs")
+	}
+	// the size of the blocks is the bytes per sector multiplied by the sectors per allocation unit
+	bsize := int64(info.BlockSize() * info.FragmentSize())
+	total := int64(info.TotalBlockCount()) * bsize
+	return &model.StorageUsage{
+		Total: total,
+		Used:  total - int64(info.FreeBlockCount())*bsize,
+		Free:  int64(info.AvailableBlockCount()) * bsize,
+	}, nil
+}
+
+func (d *SMB) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*SMB)(nil)
+var _ driver.Enumerator = (*SMB)(nil)
@@ -0,0 +1,30 @@
+package smb
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	Address  string `json:"address" required:"true" help:"host:port, the port is 445 if omitted"`
+	Username string `json:"username" default:"guest"`
+	Password string `json:"password"`
+	Domain   string `json:"domain" help:"the domain or workgroup of the account, empty for the local accounts"`
+	Share    string `json:"share" required:"true" enumerable:"true" help:"the name of the share, can be listed after the address and the account are filled"`
+}
+
+var config = driver.Config{
+	Name:        "SMB",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &SMB{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
@@ -0,0 +1,78 @@
+package smb
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	inet "github.com/alist-org/alist/v3/internal/net"
+	"github.com/hirochachacha/go-smb2"
+	"github.com/pkg/errors"
+)
+
+const timeout = 30 * time.Second
+
+// session is the authenticated connection to the server
+type session struct {
+	conn net.Conn
+	*smb2.Session
+}
+
+func (s *session) logoff() {
+	_ = s.Logoff()
+	_ = s.conn.Close()
+}
+
+func dial(ctx context.Context, addition Addition, network model.Network) (*session, error) {
+	dialer, err := inet.NewDialer(network)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", addition.Address)
+	if err != nil {
+		return nil, err
+	}
+	d := &smb2.Dialer{
+		Initiator: &smb2.NTLMInitiator{
+			User:     addition.Username,
+			Password: addition.Password,
+			Domain:   addition.Domain,
+		},
+	}
+	s, err := d.DialContext(ctx, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &session{conn: conn, Session: s}, nil
+}
+
+// listShares return the names of the disk shares, the hidden ones such as IPC$ are excluded
+func listShares(s *session) ([]string, error) {
+	names, err := s.ListSharenames()
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]string, 0, len(names))
+	for _, name := range names {
+		if !strings.HasSuffix(name, "$") {
+			shares = append(shares, name)
+		}
+	}
+	return shares, nil
+}
+
+// toSharePath convert the path to the one relative to the share root
+func toSharePath(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
+
+// isConnErr report whether the connection is broken by the error, so it should be dropped
+func isConnErr(err error) bool {
+	var transportErr *smb2.TransportError
+	return errors.As(err, &transportErr)
+}
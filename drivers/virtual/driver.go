@@ -21,6 +21,13 @@ func (d *Virtual) Config() driver.Config {
 	return config
 }
 
+func (d *Virtual) Capabilities() driver.Capabilities {
+	caps := driver.AllCapabilities()
+	// the random data can't be seeked
+	caps.Range = false
+	return caps
+}
+
 func (d *Virtual) Init(ctx context.Context, storage model.Storage) error {
 	d.Storage = storage
 	err := utils.Json.UnmarshalFromString(storage.Addition, &d.Addition)
@@ -98,3 +105,4 @@ func (d *Virtual) Other(ctx context.Context, data interface{}) (interface{}, err
 }
 
 var _ driver.Driver = (*Virtual)(nil)
+var _ driver.Capable = (*Virtual)(nil)
@@ -0,0 +1,134 @@
+package webdav
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// authTransport authorize the requests with basic auth, or digest auth once the server asks for it
+type authTransport struct {
+	base     http.RoundTripper
+	username string
+	password string
+
+	mu        sync.Mutex
+	challenge map[string]string // the digest challenge, nil for basic auth
+	nc        int
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.username == "" && t.password == "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	t.authorize(req)
+	res, err := t.base.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	challenge := parseChallenge(res.Header.Get("WWW-Authenticate"))
+	if challenge == nil {
+		return res, nil
+	}
+	t.mu.Lock()
+	t.challenge, t.nc = challenge, 0
+	t.mu.Unlock()
+	// the body can't be sent again, the caller should retry
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return res, nil
+		}
+		req.Body = body
+	}
+	_ = res.Body.Close()
+	t.authorize(req)
+	return t.base.RoundTrip(req)
+}
+
+func (t *authTransport) authorize(req *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.challenge == nil {
+		req.SetBasicAuth(t.username, t.password)
+		return
+	}
+	t.nc++
+	req.Header.Set("Authorization", digest(t.challenge, t.username, t.password, req.Method, req.URL.RequestURI(), t.nc))
+}
+
+// parseChallenge parse the digest challenge like `Digest realm="x", nonce="y", qop="auth"`,
+// nil if it's not digest
+func parseChallenge(header string) map[string]string {
+	scheme, params, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Digest") {
+		return nil
+	}
+	challenge := map[string]string{}
+	for _, param := range splitParams(params) {
+		k, v, _ := strings.Cut(param, "=")
+		challenge[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return challenge
+}
+
+// splitParams split by the commas not quoted
+func splitParams(s string) []string {
+	var params []string
+	quoted, start := false, 0
+	for i, c := range s {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				params = append(params, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(params, s[start:])
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// digest compute the authorization of rfc 2617 with md5
+func digest(challenge map[string]string, username, password, method, uri string, nc int) string {
+	ha1 := md5Hex(username + ":" + challenge["realm"] + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	cnonce := hex.EncodeToString(b)
+	ncStr := fmt.Sprintf("%08x", nc)
+	var response, qop string
+	for _, q := range strings.Split(challenge["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	if qop != "" {
+		response = md5Hex(strings.Join([]string{ha1, challenge["nonce"], ncStr, cnonce, qop, ha2}, ":"))
+	} else {
+		response = md5Hex(ha1 + ":" + challenge["nonce"] + ":" + ha2)
+	}
+	auth := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		username, challenge["realm"], challenge["nonce"], uri, response)
+	if opaque, ok := challenge["opaque"]; ok {
+		auth += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+	if qop != "" {
+		auth += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, ncStr, cnonce)
+	}
+	return auth + ", algorithm=MD5"
+}
@@ -0,0 +1,165 @@
+package webdav
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+type WebDAV struct {
+	model.Storage
+	Addition
+	address *url.URL
+	// client for the metadata calls, transferClient for the downloads and uploads without timeout
+	client         *http.Client
+	transferClient *http.Client
+}
+
+func (d *WebDAV) Config() driver.Config {
+	return config
+}
+
+func (d *WebDAV) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	err := utils.Json.UnmarshalFromString(d.Storage.Addition, &d.Addition)
+	if err != nil {
+		return errors.Wrap(err, "error while unmarshal addition")
+	}
+	d.address, err = url.Parse(d.Address)
+	if err != nil || d.address.Host == "" {
+		return errors.Errorf("invalid address %s", d.Address)
+	}
+	transport, err := net.NewTransport(d.Network)
+	if err != nil {
+		return err
+	}
+	if d.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	auth := net.WithRequestID(&authTransport{base: transport, username: d.Username, password: d.Password})
+	d.client = &http.Client{Transport: auth, Timeout: time.Minute}
+	d.transferClient = &http.Client{Transport: auth}
+	// it also picks up the digest challenge, so the uploads are not rejected
+	obj, _, err := d.propfind(ctx, d.RootFolder, "0")
+	if err != nil {
+		return errors.WithMessagef(err, "failed get root folder %s", d.RootFolder)
+	}
+	if !obj.IsDir() {
+		return errors.Errorf("root folder %s is not a folder", d.RootFolder)
+	}
+	return nil
+}
+
+func (d *WebDAV) Drop(ctx context.Context) error {
+	if d.client != nil {
+		d.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (d *WebDAV) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *WebDAV) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	_, objs, err := d.propfind(ctx, dir.GetID(), "1")
+	return objs, err
+}
+
+func (d *WebDAV) Get(ctx context.Context, path string) (model.Obj, error) {
+	obj, _, err := d.propfind(ctx, path, "0")
+	return obj, err
+}
+
+func (d *WebDAV) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	header := http.Header{}
+	if r := args.Header.Get("Range"); r != "" {
+		header.Set("Range", r)
+	}
+	res, err := d.request(ctx, d.transferClient, http.MethodGet, file.GetID(), false, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	link := &model.Link{Data: res.Body, Status: res.StatusCode, Header: http.Header{}}
+	for _, k := range []string{"Content-Range", "Content-Length", "Accept-Ranges"} {
+		if v := res.Header.Get(k); v != "" {
+			link.Header.Set(k, v)
+		}
+	}
+	return link, nil
+}
+
+func (d *WebDAV) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	return d.do(ctx, "MKCOL", stdpath.Join(parentDir.GetID(), dirName), true, nil)
+}
+
+// move or copy the obj to the path on the server
+func (d *WebDAV) transfer(ctx context.Context, method string, srcObj model.Obj, dstPath string) error {
+	header := http.Header{
+		"Destination": []string{d.fileURL(dstPath, srcObj.IsDir())},
+		"Overwrite":   []string{"F"},
+	}
+	if method == "COPY" {
+		header.Set("Depth", "infinity")
+	}
+	return d.do(ctx, method, srcObj.GetID(), srcObj.IsDir(), header)
+}
+
+func (d *WebDAV) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.transfer(ctx, "MOVE", srcObj, stdpath.Join(dstDir.GetID(), srcObj.GetName()))
+}
+
+func (d *WebDAV) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.transfer(ctx, "MOVE", srcObj, stdpath.Join(stdpath.Dir(srcObj.GetID()), newName))
+}
+
+func (d *WebDAV) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.transfer(ctx, "COPY", srcObj, stdpath.Join(dstDir.GetID(), srcObj.GetName()))
+}
+
+func (d *WebDAV) Remove(ctx context.Context, obj model.Obj) error {
+	return d.do(ctx, http.MethodDelete, obj.GetID(), obj.IsDir(), nil)
+}
+
+func (d *WebDAV) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	path := stdpath.Join(dstDir.GetID(), stream.GetName())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, d.fileURL(path, false), io.NopCloser(stream))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if d.ChunkedUpload {
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+	} else if req.ContentLength = stream.GetSize(); req.ContentLength == 0 {
+		req.Body = http.NoBody
+	}
+	if mimetype := stream.GetMimetype(); mimetype != "" {
+		req.Header.Set("Content-Type", mimetype)
+	}
+	res, err := d.transferClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed upload %s", path)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= 300 {
+		return errors.WithMessagef(errs.NewStatusError(res), "failed upload %s", path)
+	}
+	return nil
+}
+
+func (d *WebDAV) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*WebDAV)(nil)
@@ -0,0 +1,31 @@
+package webdav
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+type Addition struct {
+	driver.RootFolderPath
+	Address  string `json:"address" required:"true" format:"url" help:"such as https://cloud.example.com/remote.php/dav/files/user"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// the size of the uploads is not sent, for the servers buffering the whole body otherwise
+	ChunkedUpload      bool `json:"chunked_upload" help:"upload with chunked transfer encoding"`
+	InsecureSkipVerify bool `json:"insecure_skip_verify" help:"trust the self-signed certificates"`
+}
+
+var config = driver.Config{
+	Name:        "WebDAV",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	DefaultRoot: "/",
+}
+
+func New() driver.Driver {
+	return &WebDAV{}
+}
+
+func init() {
+	operations.RegisterDriver(config, New)
+}
@@ -0,0 +1,149 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop>
+<d:displayname/><d:resourcetype/><d:getcontentlength/><d:getlastmodified/>
+</d:prop></d:propfind>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				DisplayName   string `xml:"displayname"`
+				ContentLength int64  `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// fileURL return the url of the path under the address, the folders end with a slash
+func (d *WebDAV) fileURL(path string, dir bool) string {
+	u := *d.address
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	if dir && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	return u.String()
+}
+
+// request send the request to the path, the caller should close the body of the response
+func (d *WebDAV) request(ctx context.Context, client *http.Client, method, path string, dir bool, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.fileURL(path, dir), body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed %s %s", method, path)
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+	_ = res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	return nil, errors.WithMessagef(errs.NewStatusError(res), "failed %s %s", method, path)
+}
+
+// do send the request without response body
+func (d *WebDAV) do(ctx context.Context, method, path string, dir bool, header http.Header) error {
+	res, err := d.request(ctx, d.client, method, path, dir, nil, header)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// propfind return the obj of the path, and its children if depth is 1
+func (d *WebDAV) propfind(ctx context.Context, path string, depth string) (model.Obj, []model.Obj, error) {
+	res, err := d.request(ctx, d.client, "PROPFIND", path, false, strings.NewReader(propfindBody), http.Header{
+		"Depth":        []string{depth},
+		"Content-Type": []string{"application/xml; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	var ms multistatus
+	if err := xml.NewDecoder(res.Body).Decode(&ms); err != nil {
+		return nil, nil, errors.Wrap(err, "failed decode propfind response")
+	}
+	if len(ms.Responses) == 0 {
+		return nil, nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	var (
+		self     *model.Object
+		children []model.Obj
+		objs     []*model.Object
+	)
+	selfPath := strings.TrimSuffix(d.fileURLPath(path), "/")
+	for _, r := range ms.Responses {
+		u, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		hrefPath := strings.TrimSuffix(u.Path, "/")
+		obj := &model.Object{
+			ID:   stdpath.Join(path, stdpath.Base(hrefPath)),
+			Name: stdpath.Base(hrefPath),
+		}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200") {
+				continue
+			}
+			prop := ps.Prop
+			obj.IsFolder = prop.ResourceType.Collection != nil
+			obj.Size = prop.ContentLength
+			if t, err := http.ParseTime(prop.LastModified); err == nil {
+				obj.Modified = t
+			}
+		}
+		if self == nil && hrefPath == selfPath {
+			self = obj
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	// some servers respond the href of the requested one differently, it comes first
+	if self == nil {
+		if len(objs) == 0 {
+			return nil, nil, errors.WithStack(errs.ObjectNotFound)
+		}
+		self, objs = objs[0], objs[1:]
+	}
+	self.ID, self.Name = path, stdpath.Base(path)
+	for _, obj := range objs {
+		children = append(children, obj)
+	}
+	return self, children, nil
+}
+
+// fileURLPath return the unescaped path of the url of the path, to match the hrefs
+func (d *WebDAV) fileURLPath(path string) string {
+	return strings.TrimSuffix(d.address.Path, "/") + path
+}
@@ -0,0 +1,61 @@
+// Package activity record the events of users into their activity streams,
+// and push them to the users subscribing
+package activity
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// the activities are dropped for the slow subscribers, they can list them later
+const subscriberBuffer = 16
+
+var (
+	mu sync.RWMutex
+	// user id => the channels of the subscribers
+	subscribers = make(map[uint]map[chan model.Activity]struct{})
+)
+
+// Record save the activity of the user and push it to the subscribers,
+// the guest (id 0) is ignored
+func Record(userId uint, typ, title, path string) {
+	if userId == 0 {
+		return
+	}
+	a := model.Activity{UserID: userId, Type: typ, Title: title, Path: path, Time: time.Now()}
+	if err := db.CreateActivity(&a); err != nil {
+		log.Errorf("failed record activity [%s] of user %d: %+v", title, userId, err)
+		return
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for ch := range subscribers[userId] {
+		select {
+		case ch <- a:
+		default:
+		}
+	}
+}
+
+// Subscribe receive the new activities of the user, call the returned func to unsubscribe
+func Subscribe(userId uint) (<-chan model.Activity, func()) {
+	ch := make(chan model.Activity, subscriberBuffer)
+	mu.Lock()
+	if subscribers[userId] == nil {
+		subscribers[userId] = make(map[chan model.Activity]struct{})
+	}
+	subscribers[userId][ch] = struct{}{}
+	mu.Unlock()
+	return ch, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(subscribers[userId], ch)
+		if len(subscribers[userId]) == 0 {
+			delete(subscribers, userId)
+		}
+	}
+}
@@ -14,7 +14,7 @@ import (
 
 func AddURI(ctx context.Context, uri string, dstDirPath string) error {
 	// check storage
-	storage, dstDirActualPath, err := operations.GetStorageAndActualPath(dstDirPath)
+	storage, dstDirActualPath, err := operations.GetStorageAndActualPath(ctx, dstDirPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
 	}
@@ -114,7 +114,7 @@ var TransferTaskManager = task.NewTaskManager(3, func(k *uint64) {
 
 func (m *Monitor) Complete() error {
 	// check dstDir again
-	storage, dstDirActualPath, err := operations.GetStorageAndActualPath(m.dstDirPath)
+	storage, dstDirActualPath, err := operations.GetStorageAndActualPath(m.tsk.Ctx, m.dstDirPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
 	}
@@ -0,0 +1,18 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/fs"
+)
+
+// InitAccessCounter save the access counts every minute
+func InitAccessCounter() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			fs.FlushAccessCounts()
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/leader"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	changeRetention = 30 * 24 * time.Hour
+	accessRetention = 90 * 24 * time.Hour
+)
+
+// InitChangePruner remove the changes, share logs and activities older than 30 days,
+// and the access counts of the paths not accessed for 90 days every day
+func InitChangePruner() {
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			if !leader.IsLeader() {
+				<-ticker.C
+				continue
+			}
+			n, err := db.DeleteChangesBefore(time.Now().Add(-changeRetention))
+			if err != nil {
+				log.Errorf("failed prune changes: %+v", err)
+			} else if n > 0 {
+				log.Infof("pruned %d changes", n)
+			}
+			if _, err := db.DeleteShareLogsBefore(time.Now().Add(-changeRetention)); err != nil {
+				log.Errorf("failed prune share logs: %+v", err)
+			}
+			if _, err := db.DeleteActivitiesBefore(time.Now().Add(-changeRetention)); err != nil {
+				log.Errorf("failed prune activities: %+v", err)
+			}
+			if _, err := db.DeleteAccessCountsBefore(time.Now().Add(-accessRetention)); err != nil {
+				log.Errorf("failed prune access counts: %+v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -5,10 +5,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/alist-org/alist/v3/cmd/args"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/caarlos0/env/v6"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -44,10 +47,17 @@ func InitConfig() {
 		if err != nil {
 			log.Fatalf("update config struct error: %s", err.Error())
 		}
+		// the included ones are not written back to the main file
+		if err := includeConfigs(filepath.Dir(args.Config), conf2.Conf.Include); err != nil {
+			log.Fatalf("include config error: %+v", err)
+		}
 	}
 	if !conf2.Conf.Force {
 		confFromEnv()
 	}
+	if err := loadSecrets(conf2.Conf); err != nil {
+		log.Fatalf("load secrets error: %+v", err)
+	}
 	// convert abs path
 	var absPath string
 	var err error
@@ -69,6 +79,46 @@ func InitConfig() {
 	log.Debugf("config: %+v", conf2.Conf)
 }
 
+// includeConfigs merge the included files into the config, the precedence from low to high is:
+// the defaults, the main file, the included files in order, and the env unless force is set.
+// a directory includes all json files in it sorted by name, so is a glob
+func includeConfigs(base string, includes []string) error {
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(base, include)
+		}
+		var files []string
+		if stat, err := os.Stat(include); err == nil && stat.IsDir() {
+			files, err = filepath.Glob(filepath.Join(include, "*.json"))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		} else {
+			files, err = filepath.Glob(include)
+			if err != nil {
+				return errors.Wrapf(err, "invalid include %s", include)
+			}
+			if len(files) == 0 && !strings.ContainsAny(include, "*?[") {
+				return errors.Errorf("included file %s not exists", include)
+			}
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return errors.Wrapf(err, "failed read %s", file)
+			}
+			if err := utils.Json.Unmarshal(data, conf2.Conf); err != nil {
+				return errors.Wrapf(err, "failed load %s", file)
+			}
+			log.Infof("included config file: %s", file)
+		}
+	}
+	// the includes in the included files are ignored
+	conf2.Conf.Include = includes
+	return nil
+}
+
 func confFromEnv() {
 	prefix := "ALIST_"
 	if args.NoPrefix {
@@ -89,12 +89,25 @@ func initialSettings() {
 		{Key: conf.GlobalReadme, Value: "This is global readme", Type: conf.TypeText, Group: model.GLOBAL},
 		{Key: conf.CustomizeHead, Type: conf.TypeText, Group: model.GLOBAL, Flag: model.PRIVATE},
 		{Key: conf.CustomizeBody, Type: conf.TypeText, Group: model.GLOBAL, Flag: model.PRIVATE},
+		{Key: conf.ReadOnly, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL},
 		{Key: conf.LinkExpiration, Value: "0", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE},
+		{Key: conf.FeedPaths, Value: "", Type: conf.TypeText, Group: model.GLOBAL, Flag: model.PRIVATE},
+		{Key: conf.ShareRateLimit, Value: "60", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE},
+		{Key: conf.AppendMaxSize, Value: "16", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: "MB, the max size of the files appended or patched by rewriting on the storages can't write in place"},
+		{Key: conf.PatchEmulation, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: "patch the files by rewriting on the storages can't write in place"},
 		// aria2 settings
 		{Key: conf.Aria2Uri, Value: "http://localhost:6800/jsonrpc", Type: conf.TypeString, Group: model.ARIA2, Flag: model.PRIVATE},
 		{Key: conf.Aria2Secret, Value: "", Type: conf.TypeString, Group: model.ARIA2, Flag: model.PRIVATE},
+		// notify settings
+		{Key: conf.SmtpHost, Value: "", Type: conf.TypeString, Group: model.NOTIFY, Flag: model.PRIVATE},
+		{Key: conf.SmtpPort, Value: "465", Type: conf.TypeNumber, Group: model.NOTIFY, Flag: model.PRIVATE},
+		{Key: conf.SmtpUsername, Value: "", Type: conf.TypeString, Group: model.NOTIFY, Flag: model.PRIVATE},
+		{Key: conf.SmtpPassword, Value: "", Type: conf.TypeString, Group: model.NOTIFY, Flag: model.PRIVATE},
+		{Key: conf.SmtpFrom, Value: "", Type: conf.TypeString, Group: model.NOTIFY, Flag: model.PRIVATE},
+		{Key: conf.NotifyWebhook, Value: "", Type: conf.TypeString, Group: model.NOTIFY, Flag: model.PRIVATE, Help: "POST a json with subject and body to the url"},
 		// single settings
 		{Key: conf.Token, Value: token, Type: conf.TypeString, Group: model.SINGLE, Flag: model.PRIVATE},
+		{Key: conf.DigestSentAt, Value: "", Type: conf.TypeString, Group: model.SINGLE, Flag: model.PRIVATE},
 	}
 	if args.Dev {
 		initialSettingItems = append(initialSettingItems, model.SettingItem{Key: "test_deprecated", Value: "test_value", Type: conf.TypeString, Flag: model.DEPRECATED})
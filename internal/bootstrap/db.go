@@ -5,6 +5,7 @@ import (
 	"github.com/alist-org/alist/v3/cmd/args"
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/pkg/utils"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -35,7 +36,7 @@ func InitDB() {
 	}
 	var dB *gorm.DB
 	var err error
-	if args.Dev {
+	if args.Dev || conf.Conf.Demo.Enable {
 		dB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), gormConfig)
 	} else {
 		database := conf.Conf.Database
@@ -67,4 +68,19 @@ func InitDB() {
 		log.Fatalf("failed to connect database:%s", err.Error())
 	}
 	db.Init(dB)
+	if conf.Conf.Demo.Enable && !args.Dev {
+		seedDemo()
+	}
+}
+
+// seedDemo copy the rows of the sqlite database file into memory, so the demo starts with them
+func seedDemo() {
+	database := conf.Conf.Database
+	if database.Type != "sqlite3" || !utils.Exists(database.DBFile) {
+		log.Infof("demo mode starts with an empty database")
+		return
+	}
+	if err := db.Restore(database.DBFile); err != nil {
+		log.Fatalf("failed seed demo database from %s: %+v", database.DBFile, err)
+	}
 }
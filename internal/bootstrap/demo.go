@@ -0,0 +1,53 @@
+package bootstrap
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/leader"
+	"github.com/alist-org/alist/v3/internal/operations"
+	log "github.com/sirupsen/logrus"
+)
+
+// InitDemo snapshot the database after the initialization and restore it periodically in demo mode
+func InitDemo() {
+	if !conf.Conf.Demo.Enable {
+		return
+	}
+	interval := time.Duration(conf.Conf.Demo.ResetInterval) * time.Minute
+	if interval <= 0 {
+		log.Fatalf("the reset interval of demo mode should be positive")
+	}
+	snapshot := filepath.Join(conf.Conf.TempDir, "demo.db")
+	if err := db.Snapshot(snapshot); err != nil {
+		log.Fatalf("failed snapshot demo database: %+v", err)
+	}
+	log.Infof("demo mode is enabled, reset every %s, all storages are read-only", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			resetDemo(snapshot)
+		}
+	}()
+}
+
+// resetDemo restore the database on the leader only, as it's shared by the replicas,
+// the others just reload the storages from it
+func resetDemo(snapshot string) {
+	if leader.IsLeader() {
+		if err := db.Restore(snapshot); err != nil {
+			log.Errorf("failed reset demo database: %+v", err)
+			return
+		}
+	}
+	res, err := operations.ReloadStorages(context.Background())
+	if err != nil {
+		log.Errorf("failed reload storages after demo reset: %+v", err)
+		return
+	}
+	log.Infof("demo is reset, %d storages changed, %d failed", len(res.Loaded)+len(res.Reloaded)+len(res.Dropped), len(res.Errors))
+}
@@ -0,0 +1,21 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/digest"
+	"github.com/alist-org/alist/v3/internal/leader"
+)
+
+// InitDigest check every hour whether to send the digest to admin
+func InitDigest() {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if leader.IsLeader() {
+				digest.Run(now)
+			}
+		}
+	}()
+}
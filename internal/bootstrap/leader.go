@@ -0,0 +1,14 @@
+package bootstrap
+
+import (
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/leader"
+	log "github.com/sirupsen/logrus"
+)
+
+// InitLeaderElection elect the replica running the scheduled jobs, before they are started
+func InitLeaderElection() {
+	if err := leader.Init(conf.Conf.LeaderElection); err != nil {
+		log.Fatalf("failed init leader election: %+v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package bootstrap
+
+import (
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/plugin"
+)
+
+// LoadPlugins register the drivers of the plugins, before the storages are loaded
+func LoadPlugins() {
+	if conf.Conf.PluginDir == "" {
+		return
+	}
+	plugin.Load(conf.Conf.PluginDir)
+}
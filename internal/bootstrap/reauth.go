@@ -0,0 +1,23 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/leader"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+// InitReauthReminder check the credentials of storages every hour,
+// and remind the admin to re-auth the ones will expire in a day
+func InitReauthReminder() {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if leader.IsLeader() {
+				operations.RemindExpiringStorages(24 * time.Hour)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,99 @@
+package bootstrap
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// loadSecrets resolve the secrets not kept in the config or env,
+// the files override the config and env, the vault overrides all
+func loadSecrets(c *conf.Config) error {
+	for _, secret := range []struct {
+		file  string
+		value *string
+	}{
+		{c.JwtSecretFile, &c.JwtSecret},
+		{c.EncryptKeyFile, &c.EncryptKey},
+		{c.Database.PasswordFile, &c.Database.Password},
+		{c.Vault.TokenFile, &c.Vault.Token},
+	} {
+		if secret.file == "" {
+			continue
+		}
+		value, err := readSecretFile(secret.file)
+		if err != nil {
+			return err
+		}
+		*secret.value = value
+	}
+	if c.Vault.Address == "" {
+		return nil
+	}
+	secrets, err := fetchVault(c.Vault)
+	if err != nil {
+		return errors.WithMessage(err, "failed fetch secrets from vault")
+	}
+	for key, value := range map[string]*string{
+		"jwt_secret":  &c.JwtSecret,
+		"encrypt_key": &c.EncryptKey,
+		"db_password": &c.Database.Password,
+	} {
+		if v, ok := secrets[key]; ok {
+			*value = v
+			log.Infof("loaded %s from vault", key)
+		}
+	}
+	return nil
+}
+
+// readSecretFile read the secret without the trailing newline
+func readSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed read secret file %s", path)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// fetchVault read the secret at the path, both kv v1 and v2 are supported
+func fetchVault(vault conf.Vault) (map[string]string, error) {
+	u := strings.TrimSuffix(vault.Address, "/") + "/v1/" + strings.TrimPrefix(vault.Path, "/")
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("X-Vault-Token", vault.Token)
+	res, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("vault responded with status %d", res.StatusCode)
+	}
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := utils.Json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(err, "failed decode vault response")
+	}
+	data := body.Data
+	// kv v2 nests the secret in data.data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	secrets := make(map[string]string, len(data))
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			secrets[k] = s
+		}
+	}
+	return secrets, nil
+}
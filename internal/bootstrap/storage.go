@@ -0,0 +1,29 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/operations"
+	log "github.com/sirupsen/logrus"
+)
+
+func LoadStorages() {
+	storages, err := db.GetAllStorages()
+	if err != nil {
+		log.Fatalf("failed get storages: %+v", err)
+	}
+	results := operations.LoadStorages(context.Background(), storages, conf.Conf.InitConcurrency)
+	failed := 0
+	for _, res := range results {
+		if res.Err != nil {
+			failed++
+			log.Errorf("failed load storage [%s]: %+v", res.Storage.MountPath, res.Err)
+		} else {
+			log.Infof("success load storage: [%s], driver: [%s]", res.Storage.MountPath, res.Storage.Driver)
+		}
+	}
+	log.Infof("loaded %d storages, %d failed", len(results)-failed, failed)
+	operations.ResumeFailbackProbes()
+}
@@ -0,0 +1,23 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/leader"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+// InitUsageCollector collect the usages of storages every half an hour, on the leader only
+func InitUsageCollector() {
+	go func() {
+		ticker := time.NewTicker(30 * time.Minute)
+		defer ticker.Stop()
+		for {
+			if leader.IsLeader() {
+				operations.CollectUsages(context.Background())
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -5,15 +5,17 @@ import (
 )
 
 type Database struct {
-	Type        string `json:"type" env:"DB_TYPE"`
-	Host        string `json:"host" env:"DB_HOST"`
-	Port        int    `json:"port" env:"DB_PORT"`
-	User        string `json:"user" env:"DB_USER"`
-	Password    string `json:"password" env:"DB_PASS"`
-	Name        string `json:"name" env:"DB_NAME"`
-	DBFile      string `json:"db_file" env:"DB_FILE"`
-	TablePrefix string `json:"table_prefix" env:"DB_TABLE_PREFIX"`
-	SSLMode     string `json:"ssl_mode" env:"DB_SSL_MODE"`
+	Type     string `json:"type" env:"DB_TYPE"`
+	Host     string `json:"host" env:"DB_HOST"`
+	Port     int    `json:"port" env:"DB_PORT"`
+	User     string `json:"user" env:"DB_USER"`
+	Password string `json:"password" env:"DB_PASS"`
+	// read the password from the file instead, such as a docker or k8s secret
+	PasswordFile string `json:"password_file" env:"DB_PASS_FILE"`
+	Name         string `json:"name" env:"DB_NAME"`
+	DBFile       string `json:"db_file" env:"DB_FILE"`
+	TablePrefix  string `json:"table_prefix" env:"DB_TABLE_PREFIX"`
+	SSLMode      string `json:"ssl_mode" env:"DB_SSL_MODE"`
 }
 
 type Scheme struct {
@@ -30,33 +32,120 @@ type LogConfig struct {
 	RotationCount uint   `json:"rotation_count" env:"LOG_COUNT"`
 }
 
+type Net struct {
+	DNS                   []string `json:"dns" env:"DNS"`                                         // host:port, tls://host:port or https://.../dns-query, empty means system
+	DialTimeout           int      `json:"dial_timeout" env:"DIAL_TIMEOUT"`                       // seconds
+	TLSHandshakeTimeout   int      `json:"tls_handshake_timeout" env:"TLS_HANDSHAKE_TIMEOUT"`     // seconds
+	FallbackDelay         int      `json:"fallback_delay" env:"FALLBACK_DELAY"`                   // milliseconds before falling back to ipv4, negative disables happy eyeballs
+	ResponseHeaderTimeout int      `json:"response_header_timeout" env:"RESPONSE_HEADER_TIMEOUT"` // seconds, 0 means no limit
+	// the limits below can be overridden by the network of storages
+	RequestTimeout int   `json:"request_timeout" env:"REQUEST_TIMEOUT"` // seconds of the whole metadata call, 0 means no limit
+	MaxBodySize    int64 `json:"max_body_size" env:"MAX_BODY_SIZE"`     // bytes of the metadata response, 0 means no limit
+}
+
+// Budget limit the resources used by subsystems, 0 means no limit
+type Budget struct {
+	ProxyStreams int   `json:"proxy_streams" env:"BUDGET_PROXY_STREAMS"`
+	Tasks        int   `json:"tasks" env:"BUDGET_TASKS"`           // pending and running upload and copy tasks
+	TempDisk     int64 `json:"temp_disk" env:"BUDGET_TEMP_DISK"`   // MB
+	Thumbnails   int   `json:"thumbnails" env:"BUDGET_THUMBNAILS"` // the cover images served at the same time
+}
+
+// Vault fetch the secrets from the kv engine of hashicorp vault at startup,
+// the keys jwt_secret, encrypt_key and db_password of the secret override the config
+type Vault struct {
+	Address   string `json:"address" env:"VAULT_ADDR"` // empty to disable
+	Token     string `json:"token" env:"VAULT_TOKEN"`
+	TokenFile string `json:"token_file" env:"VAULT_TOKEN_FILE"` // such as the one written by the vault agent
+	Path      string `json:"path" env:"VAULT_PATH"`             // the path of the secret, such as secret/data/alist for kv v2
+}
+
+// LeaderElection run the scheduled jobs on one of the replicas in kubernetes, elected by a Lease
+// with the service account of the pod, which should be allowed to get, create and update leases
+type LeaderElection struct {
+	Enable        bool   `json:"enable" env:"LEADER_ELECTION"`
+	Namespace     string `json:"namespace" env:"LEADER_ELECTION_NAMESPACE"`           // empty means the namespace of the pod
+	LeaseName     string `json:"lease_name" env:"LEADER_ELECTION_LEASE_NAME"`         // shared by the replicas
+	Identity      string `json:"identity" env:"LEADER_ELECTION_IDENTITY"`             // empty means the hostname, which is the name of the pod
+	LeaseDuration int    `json:"lease_duration" env:"LEADER_ELECTION_LEASE_DURATION"` // seconds before another replica takes over
+}
+
+// Demo run a public demo, the database is kept in memory and reset to the state at startup periodically,
+// the database file is only read once. the files of the storages can't be reset, so all storages are read-only
+type Demo struct {
+	Enable        bool `json:"enable" env:"DEMO"`
+	ResetInterval int  `json:"reset_interval" env:"DEMO_RESET_INTERVAL"` // minutes
+}
+
 type Config struct {
-	Force           bool      `json:"force"`
-	Address         string    `json:"address" env:"ADDR"`
-	Port            int       `json:"port" env:"PORT"`
-	JwtSecret       string    `json:"jwt_secret" env:"JWT_SECRET"`
-	CaCheExpiration int       `json:"cache_expiration" env:"CACHE_EXPIRATION"`
-	Assets          string    `json:"assets" env:"ASSETS"`
-	Database        Database  `json:"database"`
-	Scheme          Scheme    `json:"scheme"`
-	TempDir         string    `json:"temp_dir" env:"TEMP_DIR"`
-	Log             LogConfig `json:"log"`
+	Force                   bool      `json:"force"`
+	Address                 string    `json:"address" env:"ADDR"`
+	Port                    int       `json:"port" env:"PORT"`
+	JwtSecret               string    `json:"jwt_secret" env:"JWT_SECRET"`
+	EncryptKey              string    `json:"encrypt_key" env:"ENCRYPT_KEY"`
+	JwtSecretFile           string    `json:"jwt_secret_file" env:"JWT_SECRET_FILE"`   // read the jwt secret from the file instead
+	EncryptKeyFile          string    `json:"encrypt_key_file" env:"ENCRYPT_KEY_FILE"` // read the encrypt key from the file instead
+	EncryptAddition         bool      `json:"encrypt_addition" env:"ENCRYPT_ADDITION"` // encrypt the addition of storages in database with the encrypt key
+	CaCheExpiration         int       `json:"cache_expiration" env:"CACHE_EXPIRATION"`
+	NotFoundCacheExpiration int       `json:"not_found_cache_expiration" env:"NOT_FOUND_CACHE_EXPIRATION"` // seconds, 0 to disable
+	Assets                  string    `json:"assets" env:"ASSETS"`
+	Database                Database  `json:"database"`
+	Scheme                  Scheme    `json:"scheme"`
+	TempDir                 string    `json:"temp_dir" env:"TEMP_DIR"`
+	PluginDir               string    `json:"plugin_dir" env:"PLUGIN_DIR"` // the executables of the driver plugins, disabled if empty
+	Log                     LogConfig `json:"log"`
+	LazyInit                bool      `json:"lazy_init" env:"LAZY_INIT"`
+	InitConcurrency         int       `json:"init_concurrency" env:"INIT_CONCURRENCY"`
+	ReadAhead               int       `json:"read_ahead" env:"READ_AHEAD"`           // MB buffered for proxied media, 0 to disable
+	DropTimeout             int       `json:"drop_timeout" env:"DROP_TIMEOUT"`       // seconds to wait for the in-flight calls before dropping a storage
+	AccessSampling          int       `json:"access_sampling" env:"ACCESS_SAMPLING"` // count 1 of every N listings and downloads, 0 to disable
+	Net                     Net       `json:"net"`
+	TrustedProxies          []string  `json:"trusted_proxies"` // ips or cidrs whose X-Request-ID is accepted
+	Budget                  Budget    `json:"budget"`
+	Vault                   Vault     `json:"vault"`
+	Demo                    Demo      `json:"demo"`
+	// the replicas sharing the database elect one to run the scheduled jobs
+	LeaderElection LeaderElection `json:"leader_election"`
+	// files, directories or globs relative to this file, merged in order after it,
+	// the objects are merged by key, the other values are replaced, such as ["conf.d/*.json"]
+	Include []string `json:"include"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		Address:   "0.0.0.0",
-		Port:      5244,
-		JwtSecret: random.String(16),
-		Assets:    "https://npm.elemecdn.com/alist-web@$version/dist",
-		TempDir:   "data/temp",
+		Address:    "0.0.0.0",
+		Port:       5244,
+		JwtSecret:  random.String(16),
+		EncryptKey: random.String(32),
+		Assets:     "https://npm.elemecdn.com/alist-web@$version/dist",
+		TempDir:    "data/temp",
 		Database: Database{
 			Type:        "sqlite3",
 			Port:        0,
 			TablePrefix: "x_",
 			DBFile:      "data/data.db",
 		},
-		CaCheExpiration: 30,
+		CaCheExpiration:         30,
+		NotFoundCacheExpiration: 10,
+		InitConcurrency:         4,
+		ReadAhead:               4,
+		DropTimeout:             30,
+		AccessSampling:          1,
+		Demo: Demo{
+			ResetInterval: 60,
+		},
+		LeaderElection: LeaderElection{
+			LeaseName:     "alist-leader",
+			LeaseDuration: 15,
+		},
+		Net: Net{
+			DialTimeout:           30,
+			TLSHandshakeTimeout:   10,
+			FallbackDelay:         300,
+			ResponseHeaderTimeout: 60,
+			RequestTimeout:        120,
+			MaxBodySize:           32 * 1024 * 1024,
+		},
 		Log: LogConfig{
 			Enable:        true,
 			Path:          "log/%Y-%m-%d-%H:%M.log",
@@ -31,9 +31,23 @@ const (
 	CustomizeHead  = "customize_head"
 	CustomizeBody  = "customize_body"
 	LinkExpiration = "link_expiration"
+	FeedPaths      = "feed_paths"
+	ShareRateLimit = "share_rate_limit"
+	ReadOnly       = "read_only"
+	AppendMaxSize  = "append_max_size"
+	PatchEmulation = "patch_emulation"
 
 	Aria2Uri    = "aria2_uri"
 	Aria2Secret = "aria2_secret"
 
+	SmtpHost      = "smtp_host"
+	SmtpPort      = "smtp_port"
+	SmtpUsername  = "smtp_username"
+	SmtpPassword  = "smtp_password"
+	SmtpFrom      = "smtp_from"
+	NotifyWebhook = "notify_webhook"
+
 	Token = "token"
+	// the time the last digest was sent to admin
+	DigestSentAt = "digest_sent_at"
 )
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// AddAccessCounts add the counts to the saved ones in a transaction
+func AddAccessCounts(counts []model.AccessCount) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for _, count := range counts {
+			res := tx.Model(&model.AccessCount{}).Where("path = ?", count.Path).Updates(map[string]interface{}{
+				"lists":            gorm.Expr("lists + ?", count.Lists),
+				"downloads":        gorm.Expr("downloads + ?", count.Downloads),
+				"last_access":      count.LastAccess,
+				"period_downloads": gorm.Expr("period_downloads + ?", count.Downloads),
+			})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				count.PeriodDownloads = count.Downloads
+				if err := tx.Create(&count).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}))
+}
+
+// GetTopAccessCounts get the most accessed paths under the path, orderBy is lists or downloads
+func GetTopAccessCounts(path, orderBy string, limit int) ([]model.AccessCount, error) {
+	if orderBy != "lists" {
+		orderBy = "downloads"
+	}
+	path = utils.StandardizePath(path)
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var counts []model.AccessCount
+	err := db.Where("path LIKE ? AND "+orderBy+" > 0", prefix+"%").
+		Order(orderBy + " desc").Limit(limit).Find(&counts).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed get access counts")
+	}
+	// LIKE treats % and _ in path as wildcards, so check the prefix again
+	res := counts[:0]
+	for _, count := range counts {
+		if strings.HasPrefix(count.Path, prefix) {
+			res = append(res, count)
+		}
+	}
+	return res, nil
+}
+
+// GetTopPeriodDownloads get the most downloaded paths since the last digest
+func GetTopPeriodDownloads(limit int) ([]model.AccessCount, error) {
+	var counts []model.AccessCount
+	if err := db.Where("period_downloads > 0").Order("period_downloads desc").Limit(limit).Find(&counts).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get access counts")
+	}
+	return counts, nil
+}
+
+// ResetPeriodDownloads start counting the downloads of the next digest
+func ResetPeriodDownloads() error {
+	return errors.WithStack(db.Model(&model.AccessCount{}).Where("period_downloads > 0").Update("period_downloads", 0).Error)
+}
+
+// DeleteAccessCountsBefore remove the counts of the paths not accessed since t,
+// the ones with downloads not sent in a digest yet are kept
+func DeleteAccessCountsBefore(t time.Time) (int64, error) {
+	res := db.Where("last_access < ? AND period_downloads = 0", t).Delete(&model.AccessCount{})
+	return res.RowsAffected, errors.WithStack(res.Error)
+}
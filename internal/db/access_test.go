@@ -0,0 +1,41 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestDeleteAccessCountsBefore(t *testing.T) {
+	now := time.Now()
+	counts := []model.AccessCount{
+		{Path: "/prune/old", Lists: 1, LastAccess: now.AddDate(0, 0, -100)},
+		{Path: "/prune/new", Lists: 1, LastAccess: now},
+		{Path: "/prune/digest", Downloads: 1, LastAccess: now.AddDate(0, 0, -100)},
+	}
+	if err := AddAccessCounts(counts); err != nil {
+		t.Fatalf("failed add access counts: %+v", err)
+	}
+	n, err := DeleteAccessCountsBefore(now.AddDate(0, 0, -90))
+	if err != nil {
+		t.Fatalf("failed delete access counts: %+v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 count is deleted, got %d", n)
+	}
+	got, err := GetTopAccessCounts("/prune", "lists", 10)
+	if err != nil {
+		t.Fatalf("failed get access counts: %+v", err)
+	}
+	if len(got) != 1 || got[0].Path != "/prune/new" {
+		t.Errorf("expected only the recent path is left, got %+v", got)
+	}
+	// the downloads of the period are kept until the digest is sent
+	if err := ResetPeriodDownloads(); err != nil {
+		t.Fatalf("failed reset period downloads: %+v", err)
+	}
+	if n, err := DeleteAccessCountsBefore(now.AddDate(0, 0, -90)); err != nil || n != 1 {
+		t.Errorf("expected the count after the digest is deleted, got %d %+v", n, err)
+	}
+}
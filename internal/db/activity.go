@@ -0,0 +1,50 @@
+package db
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func CreateActivity(a *model.Activity) error {
+	return errors.WithStack(db.Create(a).Error)
+}
+
+// GetActivities get the activities of the user from the newest, return the unread count as well
+func GetActivities(userId uint, unreadOnly bool, pageIndex, pageSize int) ([]model.Activity, int64, int64, error) {
+	// a new session, so the conditions added below don't leak into each other
+	activityDB := db.Model(&model.Activity{}).Where("user_id = ?", userId).Session(&gorm.Session{})
+	var unread int64
+	if err := activityDB.Where("is_read = ?", false).Count(&unread).Error; err != nil {
+		return nil, 0, 0, errors.Wrapf(err, "failed get unread activities count")
+	}
+	if unreadOnly {
+		activityDB = activityDB.Where("is_read = ?", false)
+	}
+	var count int64
+	if err := activityDB.Count(&count).Error; err != nil {
+		return nil, 0, 0, errors.Wrapf(err, "failed get activities count")
+	}
+	var activities []model.Activity
+	if err := activityDB.Order("id desc").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&activities).Error; err != nil {
+		return nil, 0, 0, errors.Wrapf(err, "failed find activities")
+	}
+	return activities, count, unread, nil
+}
+
+// MarkActivitiesRead mark the activities of the user as read, all of them if ids is empty
+func MarkActivitiesRead(userId uint, ids []uint) error {
+	activityDB := db.Model(&model.Activity{}).Where("user_id = ? AND is_read = ?", userId, false)
+	if len(ids) > 0 {
+		activityDB = activityDB.Where("id IN ?", ids)
+	}
+	return errors.WithStack(activityDB.Update("is_read", true).Error)
+}
+
+// DeleteActivitiesBefore remove the old activities, return the count of deleted
+func DeleteActivitiesBefore(t time.Time) (int64, error) {
+	res := db.Where("time < ?", t).Delete(&model.Activity{})
+	return res.RowsAffected, errors.WithStack(res.Error)
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestActivities(t *testing.T) {
+	for _, title := range []string{"a", "b", "c"} {
+		if err := CreateActivity(&model.Activity{UserID: 100, Type: model.ActivityUploadFinished, Title: title, Time: time.Now()}); err != nil {
+			t.Fatalf("failed create activity: %+v", err)
+		}
+	}
+	activities, total, unread, err := GetActivities(100, false, 1, 10)
+	if err != nil {
+		t.Fatalf("failed get activities: %+v", err)
+	}
+	if total != 3 || unread != 3 || activities[0].Title != "c" {
+		t.Fatalf("expected 3 unread activities from the newest, got %d %d %+v", total, unread, activities)
+	}
+	if err := MarkActivitiesRead(100, []uint{activities[0].ID}); err != nil {
+		t.Fatalf("failed mark read: %+v", err)
+	}
+	activities, total, unread, err = GetActivities(100, true, 1, 10)
+	if err != nil {
+		t.Fatalf("failed get activities: %+v", err)
+	}
+	if total != 2 || unread != 2 || len(activities) != 2 {
+		t.Errorf("expected 2 unread activities, got %d %d %d", total, unread, len(activities))
+	}
+	if err := MarkActivitiesRead(100, nil); err != nil {
+		t.Fatalf("failed mark read: %+v", err)
+	}
+	if _, _, unread, _ = GetActivities(100, false, 1, 10); unread != 0 {
+		t.Errorf("expected all activities are read, got %d unread", unread)
+	}
+}
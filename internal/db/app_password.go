@@ -0,0 +1,63 @@
+package db
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// CreateAppPassword save the app password with the password hashed
+func CreateAppPassword(p *model.AppPassword, password string) error {
+	p.Hash = utils.HashPassword(password)
+	return errors.WithStack(db.Create(p).Error)
+}
+
+// the interval the last seen of a device is updated in, if it's not seen from another address
+const appPasswordSeenInterval = time.Minute
+
+// GetAppPasswordsOfUser the devices of the user, the recently seen first
+func GetAppPasswordsOfUser(userID uint) ([]model.AppPassword, error) {
+	var passwords []model.AppPassword
+	if err := db.Where("user_id = ?", userID).Order("last_used desc, id desc").Find(&passwords).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get app passwords")
+	}
+	return passwords, nil
+}
+
+// DeleteAppPassword delete the app password of the user, so one can't revoke the others'
+func DeleteAppPassword(userID, id uint) error {
+	res := db.Where("user_id = ?", userID).Delete(&model.AppPassword{}, id)
+	if res.Error != nil {
+		return errors.WithStack(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errors.WithStack(gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// MatchAppPassword find the app password of the user for the protocol, nil if the password doesn't match.
+// the device is recorded as seen, the revoked ones never match since it's looked up every time
+func MatchAppPassword(userID uint, protocol, password, ip, userAgent string) (*model.AppPassword, error) {
+	if password == "" {
+		return nil, nil
+	}
+	var p model.AppPassword
+	err := db.Where("user_id = ? AND protocol = ? AND hash = ?", userID, protocol, utils.HashPassword(password)).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed get app password")
+	}
+	now := time.Now()
+	if p.LastUsed != nil && now.Sub(*p.LastUsed) < appPasswordSeenInterval && p.LastIP == ip && p.LastUserAgent == userAgent {
+		return &p, nil
+	}
+	p.LastUsed, p.LastIP, p.LastUserAgent = &now, ip, userAgent
+	err = db.Model(&p).Select("last_used", "last_ip", "last_user_agent").Updates(&p).Error
+	return &p, errors.WithStack(err)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestAppPasswords(t *testing.T) {
+	p := model.AppPassword{UserID: 100, Name: "phone", Protocol: model.ProtocolWebdav, Created: time.Now()}
+	if err := CreateAppPassword(&p, "secret"); err != nil {
+		t.Fatalf("failed create app password: %+v", err)
+	}
+	if p.Hash == "" || p.Hash == "secret" {
+		t.Fatalf("expected the password is hashed, got %s", p.Hash)
+	}
+	for _, c := range []struct {
+		userID   uint
+		protocol string
+		password string
+		match    bool
+	}{
+		{100, model.ProtocolWebdav, "secret", true},
+		{100, model.ProtocolWebdav, "wrong", false},
+		{100, model.ProtocolWeb, "secret", false},
+		{101, model.ProtocolWebdav, "secret", false},
+	} {
+		if p, err := MatchAppPassword(c.userID, c.protocol, c.password, "10.0.0.1", "davfs2"); err != nil || (p != nil) != c.match {
+			t.Errorf("expected %+v matches %v, got %+v %+v", c, c.match, p, err)
+		}
+	}
+	if err := DeleteAppPassword(101, p.ID); err == nil {
+		t.Errorf("expected the app password of others can't be deleted")
+	}
+	// seen from another address
+	if _, err := MatchAppPassword(100, model.ProtocolWebdav, "secret", "10.0.0.2", "davfs2"); err != nil {
+		t.Fatalf("failed match app password: %+v", err)
+	}
+	passwords, err := GetAppPasswordsOfUser(100)
+	if err != nil || len(passwords) != 1 || passwords[0].LastUsed == nil || passwords[0].LastIP != "10.0.0.2" || passwords[0].LastUserAgent != "davfs2" {
+		t.Fatalf("unexpected app passwords: %+v %+v", passwords, err)
+	}
+	if err := DeleteAppPassword(100, p.ID); err != nil {
+		t.Fatalf("failed delete app password: %+v", err)
+	}
+	if p, _ := MatchAppPassword(100, model.ProtocolWebdav, "secret", "10.0.0.2", "davfs2"); p != nil {
+		t.Errorf("expected the deleted app password doesn't match")
+	}
+}
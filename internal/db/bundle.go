@@ -0,0 +1,81 @@
+package db
+
+import (
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// BundleShare a share of the bundle, created by the user of the username,
+// who is either a user of the bundle or an existing one
+type BundleShare struct {
+	model.Share
+	Username string
+}
+
+// CreateBundle insert the storage with its metas, users and shares in one transaction
+func CreateBundle(storage *model.Storage, metas []model.Meta, users []model.User, shares []BundleShare) error {
+	encrypted, err := encryptStorage(*storage)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&encrypted).Error; err != nil {
+			return errors.Wrap(err, "failed create storage")
+		}
+		storage.ID = encrypted.ID
+		for i := range metas {
+			if err := tx.Create(&metas[i]).Error; err != nil {
+				return errors.Wrapf(err, "failed create meta [%s]", metas[i].Path)
+			}
+		}
+		for i := range users {
+			if err := tx.Create(&users[i]).Error; err != nil {
+				return errors.Wrapf(err, "failed create user [%s]", users[i].Username)
+			}
+		}
+		for i := range shares {
+			var user model.User
+			if err := tx.Where("username = ?", shares[i].Username).First(&user).Error; err != nil {
+				return errors.Wrapf(err, "failed get user [%s] of share", shares[i].Username)
+			}
+			shares[i].UserID = user.ID
+			if err := tx.Create(&shares[i].Share).Error; err != nil {
+				return errors.Wrapf(err, "failed create share [%s]", shares[i].Path)
+			}
+		}
+		return nil
+	}))
+}
+
+// DeleteBundle delete the records created by CreateBundle in one transaction
+func DeleteBundle(storage *model.Storage, metas []model.Meta, users []model.User, shares []BundleShare) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.Storage{}, storage.ID).Error; err != nil {
+			return err
+		}
+		for _, meta := range metas {
+			if err := tx.Delete(&model.Meta{}, meta.ID).Error; err != nil {
+				return err
+			}
+		}
+		for _, user := range users {
+			if err := tx.Delete(&model.User{}, user.ID).Error; err != nil {
+				return err
+			}
+		}
+		for _, share := range shares {
+			if err := tx.Delete(&model.Share{}, share.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	for _, meta := range metas {
+		metaCache.Del(meta.Path)
+	}
+	for _, user := range users {
+		userCache.Del(user.Username)
+	}
+	return errors.WithStack(err)
+}
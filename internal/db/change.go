@@ -0,0 +1,85 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func CreateChange(change *model.Change) error {
+	return errors.WithStack(db.Create(change).Error)
+}
+
+// GetChanges get the latest changes under the path, action is optional
+func GetChanges(path, action string, limit int) ([]model.Change, error) {
+	path = utils.StandardizePath(path)
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	changeDB := db.Where("path LIKE ?", prefix+"%")
+	if action != "" {
+		changeDB = changeDB.Where("action = ?", action)
+	}
+	var changes []model.Change
+	if err := changeDB.Order("id desc").Limit(limit).Find(&changes).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get changes")
+	}
+	// LIKE treats % and _ in path as wildcards, so check the prefix again
+	res := changes[:0]
+	for _, change := range changes {
+		if strings.HasPrefix(change.Path, prefix) {
+			res = append(res, change)
+		}
+	}
+	return res, nil
+}
+
+// DeleteChangesBefore remove the old changes, return the count of deleted
+func DeleteChangesBefore(t time.Time) (int64, error) {
+	res := db.Where("time < ?", t).Delete(&model.Change{})
+	return res.RowsAffected, errors.WithStack(res.Error)
+}
+
+// GetLatestChangeID get the id of the latest change, 0 if there is none
+func GetLatestChangeID() (uint, error) {
+	var id uint
+	if err := db.Model(&model.Change{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error; err != nil {
+		return 0, errors.Wrapf(err, "failed get latest change")
+	}
+	return id, nil
+}
+
+// GetFirstChangeID get the id of the oldest change kept, 0 if there is none
+func GetFirstChangeID() (uint, error) {
+	var id uint
+	if err := db.Model(&model.Change{}).Select("COALESCE(MIN(id), 0)").Scan(&id).Error; err != nil {
+		return 0, errors.Wrapf(err, "failed get first change")
+	}
+	return id, nil
+}
+
+// GetChangesAfter get at most limit changes under the path in (after, until] in order,
+// and the id reached, which is until if there is no more
+func GetChangesAfter(path string, after, until uint, limit int) ([]model.Change, uint, error) {
+	path = utils.StandardizePath(path)
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var changes []model.Change
+	err := db.Where("id > ? AND id <= ? AND path LIKE ?", after, until, prefix+"%").
+		Order("id").Limit(limit).Find(&changes).Error
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get changes")
+	}
+	reached := until
+	if len(changes) == limit {
+		reached = changes[len(changes)-1].ID
+	}
+	// LIKE treats % and _ in path as wildcards, so check the prefix again
+	res := changes[:0]
+	for _, change := range changes {
+		if strings.HasPrefix(change.Path, prefix) {
+			res = append(res, change)
+		}
+	}
+	return res, reached, nil
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestGetChangesAfter(t *testing.T) {
+	cursor, err := GetLatestChangeID()
+	if err != nil {
+		t.Fatalf("failed get latest change: %+v", err)
+	}
+	for _, path := range []string{"/sync/a", "/other/b", "/sync/c", "/sync_x/d", "/sync/e"} {
+		if err := CreateChange(&model.Change{Path: path, Action: model.ChangeCreate, Time: time.Now()}); err != nil {
+			t.Fatalf("failed create change: %+v", err)
+		}
+	}
+	latest, err := GetLatestChangeID()
+	if err != nil || latest != cursor+5 {
+		t.Fatalf("unexpected latest change: %d %+v", latest, err)
+	}
+	changes, reached, err := GetChangesAfter("/sync", cursor, latest, 2)
+	if err != nil || len(changes) != 2 || changes[0].Path != "/sync/a" || changes[1].Path != "/sync/c" || reached != cursor+3 {
+		t.Fatalf("unexpected changes: %+v %d %+v", changes, reached, err)
+	}
+	changes, reached, err = GetChangesAfter("/sync", reached, latest, 2)
+	if err != nil || len(changes) != 1 || changes[0].Path != "/sync/e" || reached != latest {
+		t.Fatalf("unexpected changes: %+v %d %+v", changes, reached, err)
+	}
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func SaveCoverImage(cover *model.CoverImage) error {
+	return errors.WithStack(db.Save(cover).Error)
+}
+
+func GetCoverImage(metaID uint) (*model.CoverImage, error) {
+	var cover model.CoverImage
+	if err := db.Where("meta_id = ?", metaID).First(&cover).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get meta cover")
+	}
+	return &cover, nil
+}
+
+func DeleteCoverImage(metaID uint) error {
+	return errors.WithStack(db.Where("meta_id = ?", metaID).Delete(&model.CoverImage{}).Error)
+}
+
+// GetCoverImageTimes get the modified time of the uploaded covers of the metas, without the data
+func GetCoverImageTimes(metaIDs []uint) (map[uint]time.Time, error) {
+	res := make(map[uint]time.Time)
+	if len(metaIDs) == 0 {
+		return res, nil
+	}
+	var covers []model.CoverImage
+	if err := db.Select("meta_id", "modified").Where("meta_id IN ?", metaIDs).Find(&covers).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get meta covers")
+	}
+	for _, cover := range covers {
+		res[cover.MetaID] = cover.Modified
+	}
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the data of credential is encrypted in database,
+// so always work on a copy to keep the caller's plaintext
+
+func encryptCredential(c model.Credential) (model.Credential, error) {
+	data, err := utils.EncryptString(conf.Conf.EncryptKey, c.Data)
+	if err != nil {
+		return c, errors.Wrap(err, "failed encrypt credential")
+	}
+	c.Data = data
+	return c, nil
+}
+
+func decryptCredential(c *model.Credential) error {
+	data, err := utils.DecryptString(conf.Conf.EncryptKey, c.Data)
+	if err != nil {
+		return errors.Wrapf(err, "failed decrypt credential [%s]", c.Name)
+	}
+	c.Data = data
+	return nil
+}
+
+func CreateCredential(c *model.Credential) error {
+	encrypted, err := encryptCredential(*c)
+	if err != nil {
+		return err
+	}
+	if err := db.Create(&encrypted).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	c.ID = encrypted.ID
+	return nil
+}
+
+func UpdateCredential(c *model.Credential) error {
+	encrypted, err := encryptCredential(*c)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(db.Save(&encrypted).Error)
+}
+
+func GetCredentialById(id uint) (*model.Credential, error) {
+	var c model.Credential
+	if err := db.First(&c, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get credential")
+	}
+	if err := decryptCredential(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCredentials the data of credentials is not returned
+func GetCredentials(pageIndex, pageSize int) ([]model.Credential, int64, error) {
+	credentialDB := db.Model(&model.Credential{})
+	var count int64
+	if err := credentialDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get credentials count")
+	}
+	var credentials []model.Credential
+	if err := credentialDB.Omit("data").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&credentials).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find credentials")
+	}
+	return credentials, count, nil
+}
+
+func DeleteCredentialById(id uint) error {
+	return errors.WithStack(db.Delete(&model.Credential{}, id).Error)
+}
@@ -10,7 +10,7 @@ var db gorm.DB
 
 func Init(d *gorm.DB) {
 	db = *d
-	err := db.AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem))
+	err := db.AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem), new(model.Credential), new(model.StorageTemplate), new(model.StorageUsage), new(model.ObjHash), new(model.DedupEntry), new(model.DedupBlob), new(model.Activity), new(model.Change), new(model.AccessCount), new(model.Share), new(model.ShareLog), new(model.Hold), new(model.HoldLog), new(model.AppPassword), new(model.Progress), new(model.CoverImage))
 	if err != nil {
 		log.Fatalf("failed migrate database: %s", err.Error())
 	}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	stdpath "path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetDedupEntry(storageId uint, path string) (*model.DedupEntry, error) {
+	var e model.DedupEntry
+	if err := db.Where("storage_id = ? AND path = ?", storageId, path).First(&e).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.WithStack(errs.ObjectNotFound)
+		}
+		return nil, errors.Wrapf(err, "failed get dedup entry [%s]", path)
+	}
+	return &e, nil
+}
+
+func GetDedupEntries(storageId uint, dir string) ([]model.DedupEntry, error) {
+	var entries []model.DedupEntry
+	if err := db.Where("storage_id = ? AND dir = ?", storageId, dir).Find(&entries).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get dedup entries of [%s]", dir)
+	}
+	return entries, nil
+}
+
+// getDedupTree return the entry of the path and all the entries under it
+func getDedupTree(tx *gorm.DB, storageId uint, path string) ([]model.DedupEntry, error) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var entries []model.DedupEntry
+	if err := tx.Where("storage_id = ? AND (path = ? OR path LIKE ?)", storageId, path, prefix+"%").
+		Find(&entries).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// LIKE treats % and _ in path as wildcards, so check the prefix again
+	res := entries[:0]
+	for _, e := range entries {
+		if e.Path == path || strings.HasPrefix(e.Path, prefix) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func refDedupBlob(tx *gorm.DB, storageId uint, hash string, delta int) error {
+	return errors.WithStack(tx.Model(&model.DedupBlob{}).Where("storage_id = ? AND hash = ?", storageId, hash).
+		Update("ref_count", gorm.Expr("ref_count + ?", delta)).Error)
+}
+
+// CreateDedupDir create the entry of the dir if not exists
+func CreateDedupDir(e *model.DedupEntry) error {
+	return errors.WithStack(db.Where("storage_id = ? AND path = ?", e.StorageID, e.Path).FirstOrCreate(e).Error)
+}
+
+// PutDedupFile create the entry of the file referring to its blob, the blob is created if not exists.
+// return the hash of the old content if the file is overwritten, the blob of it may be unreferenced
+func PutDedupFile(e *model.DedupEntry) (string, error) {
+	var old string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var exist model.DedupEntry
+		err := tx.Where("storage_id = ? AND path = ?", e.StorageID, e.Path).First(&exist).Error
+		switch {
+		case err == nil && exist.IsDir:
+			return errors.WithStack(errs.NotFile)
+		case err == nil:
+			if err := tx.Delete(&exist).Error; err != nil {
+				return errors.WithStack(err)
+			}
+			if err := refDedupBlob(tx, e.StorageID, exist.Hash, -1); err != nil {
+				return err
+			}
+			old = exist.Hash
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return errors.WithStack(err)
+		}
+		blob := model.DedupBlob{StorageID: e.StorageID, Hash: e.Hash, Size: e.Size}
+		if err := tx.Where("storage_id = ? AND hash = ?", e.StorageID, e.Hash).FirstOrCreate(&blob).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		if err := refDedupBlob(tx, e.StorageID, e.Hash, 1); err != nil {
+			return err
+		}
+		return errors.WithStack(tx.Create(e).Error)
+	})
+	if old == e.Hash {
+		old = ""
+	}
+	return old, err
+}
+
+// RemoveDedupEntries remove the entry of the path and all the entries under it,
+// return the hashes of the removed files, the blobs of them may be unreferenced
+func RemoveDedupEntries(storageId uint, path string) ([]string, error) {
+	var hashes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		entries, err := getDedupTree(tx, storageId, path)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := tx.Delete(&e).Error; err != nil {
+				return errors.WithStack(err)
+			}
+			if e.IsDir {
+				continue
+			}
+			if err := refDedupBlob(tx, storageId, e.Hash, -1); err != nil {
+				return err
+			}
+			hashes = append(hashes, e.Hash)
+		}
+		return nil
+	})
+	return hashes, err
+}
+
+// MoveDedupEntries move the entry of the path and all the entries under it to the dst path
+func MoveDedupEntries(storageId uint, srcPath, dstPath string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("storage_id = ? AND path = ?", storageId, dstPath).First(&model.DedupEntry{}).Error; err == nil {
+			return errors.Errorf("%s already exists", dstPath)
+		}
+		entries, err := getDedupTree(tx, storageId, srcPath)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			e.Path = dstPath + strings.TrimPrefix(e.Path, srcPath)
+			e.Dir, e.Name = stdpath.Split(e.Path)
+			e.Dir = stdpath.Clean(e.Dir)
+			if err := tx.Save(&e).Error; err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	})
+}
+
+// CopyDedupEntries copy the entry of the path and all the entries under it to the dst path,
+// the copied files refer to the same blobs
+func CopyDedupEntries(storageId uint, srcPath, dstPath string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("storage_id = ? AND path = ?", storageId, dstPath).First(&model.DedupEntry{}).Error; err == nil {
+			return errors.Errorf("%s already exists", dstPath)
+		}
+		entries, err := getDedupTree(tx, storageId, srcPath)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			e.ID = 0
+			e.Path = dstPath + strings.TrimPrefix(e.Path, srcPath)
+			e.Dir, e.Name = stdpath.Split(e.Path)
+			e.Dir = stdpath.Clean(e.Dir)
+			if err := tx.Create(&e).Error; err != nil {
+				return errors.WithStack(err)
+			}
+			if !e.IsDir {
+				if err := refDedupBlob(tx, storageId, e.Hash, 1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func GetDedupBlob(storageId uint, hash string) (*model.DedupBlob, error) {
+	var blob model.DedupBlob
+	if err := db.Where("storage_id = ? AND hash = ?", storageId, hash).First(&blob).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.WithStack(errs.ObjectNotFound)
+		}
+		return nil, errors.WithStack(err)
+	}
+	return &blob, nil
+}
+
+// DeleteUnusedDedupBlob delete the blob if no entry refers to it, return whether it's deleted
+func DeleteUnusedDedupBlob(storageId uint, hash string) (bool, error) {
+	res := db.Where("storage_id = ? AND hash = ? AND ref_count <= 0", storageId, hash).Delete(&model.DedupBlob{})
+	return res.RowsAffected > 0, errors.WithStack(res.Error)
+}
+
+// DeleteDedupByStorage delete all the entries and blobs of the storage
+func DeleteDedupByStorage(storageId uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("storage_id = ?", storageId).Delete(&model.DedupEntry{}).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(tx.Where("storage_id = ?", storageId).Delete(&model.DedupBlob{}).Error)
+	})
+}
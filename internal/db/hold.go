@@ -0,0 +1,51 @@
+package db
+
+import (
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateHold(h *model.Hold) error {
+	return errors.WithStack(db.Create(h).Error)
+}
+
+func GetHoldById(id uint) (*model.Hold, error) {
+	var h model.Hold
+	if err := db.First(&h, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get hold")
+	}
+	return &h, nil
+}
+
+func GetHolds() ([]model.Hold, error) {
+	var holds []model.Hold
+	if err := db.Order("path").Find(&holds).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find holds")
+	}
+	return holds, nil
+}
+
+func DeleteHoldById(id uint) error {
+	return errors.WithStack(db.Delete(&model.Hold{}, id).Error)
+}
+
+func CreateHoldLog(l *model.HoldLog) error {
+	return errors.WithStack(db.Create(l).Error)
+}
+
+// GetHoldLogs get the latest logs, of all holds if path is empty
+func GetHoldLogs(path string, pageIndex, pageSize int) ([]model.HoldLog, int64, error) {
+	logDB := db.Model(&model.HoldLog{})
+	if path != "" {
+		logDB = logDB.Where("path = ?", path)
+	}
+	var count int64
+	if err := logDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get hold logs count")
+	}
+	var logs []model.HoldLog
+	if err := logDB.Order("id desc").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find hold logs")
+	}
+	return logs, count, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 	stdpath "path"
+	"strings"
 	"time"
 )
 
@@ -88,5 +89,76 @@ func DeleteMetaById(id uint) error {
 		return err
 	}
 	metaCache.Del(old.Path)
+	if err := DeleteCoverImage(id); err != nil {
+		return err
+	}
 	return errors.WithStack(db.Delete(&model.Meta{}, id).Error)
 }
+
+// GetSubMetas get all metas under the path, not including the path itself
+func GetSubMetas(path string) ([]model.Meta, error) {
+	path = utils.StandardizePath(path)
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var metas []model.Meta
+	if err := db.Where("path LIKE ?", prefix+"%").Find(&metas).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get sub metas")
+	}
+	// LIKE treats % and _ in path as wildcards, so check the prefix again
+	res := metas[:0]
+	for _, meta := range metas {
+		if strings.HasPrefix(meta.Path, prefix) {
+			res = append(res, meta)
+		}
+	}
+	return res, nil
+}
+
+// GetChildMetas get the metas of the direct children of the path
+func GetChildMetas(path string) ([]model.Meta, error) {
+	prefix := escapeLike(strings.TrimSuffix(utils.StandardizePath(path), "/") + "/")
+	var metas []model.Meta
+	if err := db.Where("path LIKE ? ESCAPE ? AND path NOT LIKE ? ESCAPE ?", prefix+"%", likeEscape, prefix+"%/%", likeEscape).
+		Find(&metas).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get child metas")
+	}
+	return metas, nil
+}
+
+// PropagateMeta copy the attrs of src to all sub metas of src.Path in one transaction,
+// return the affected metas, nothing is saved if dryRun
+func PropagateMeta(src model.Meta, attrs []string, dryRun bool) ([]model.Meta, error) {
+	metas, err := GetSubMetas(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range metas {
+		for _, attr := range attrs {
+			if !metas[i].CopyAttr(src, attr) {
+				return nil, errors.Errorf("unknown meta attr: %s", attr)
+			}
+		}
+	}
+	if dryRun || len(metas) == 0 {
+		return metas, nil
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for i := range metas {
+			if err := tx.Save(&metas[i]).Error; err != nil {
+				return errors.Wrapf(err, "failed update meta [%s]", metas[i].Path)
+			}
+		}
+		return nil
+	})
+	for _, meta := range metas {
+		metaCache.Del(meta.Path)
+	}
+	return metas, errors.WithStack(err)
+}
+
+func GetAllMetas() ([]model.Meta, error) {
+	var metas []model.Meta
+	if err := db.Order("path").Find(&metas).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get metas")
+	}
+	return metas, nil
+}
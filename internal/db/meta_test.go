@@ -57,3 +57,105 @@ func TestGetNearestMeta2(t *testing.T) {
 		t.Errorf("unexpected meta: %+v", meta)
 	}
 }
+
+func TestPropagateMeta(t *testing.T) {
+	src := model.Meta{Path: "/a", Password: "pwd", PSub: true}
+	metas, err := PropagateMeta(src, []string{model.MetaPassword}, true)
+	if err != nil {
+		t.Fatalf("failed to preview propagate: %+v", err)
+	}
+	if len(metas) != 2 {
+		t.Errorf("unexpected affected metas: %+v", metas)
+	}
+	if meta, _ := GetMetaByPath("/a/b"); meta.Password != "" {
+		t.Errorf("dry run should not update meta: %+v", meta)
+	}
+	if _, err := PropagateMeta(src, []string{model.MetaPassword}, false); err != nil {
+		t.Fatalf("failed to propagate meta: %+v", err)
+	}
+	if meta, _ := GetMetaByPath("/a/b/c"); meta.Password != "pwd" || !meta.PSub {
+		t.Errorf("meta not propagated: %+v", meta)
+	}
+}
+
+func TestRewritePath(t *testing.T) {
+	if err := CreateMeta(&model.Meta{Path: "/ab"}); err != nil {
+		t.Fatalf("failed to create meta: %+v", err)
+	}
+	share := model.Share{Token: "rewrite", Path: "/a/b"}
+	if err := CreateShare(&share); err != nil {
+		t.Fatalf("failed to create share: %+v", err)
+	}
+	hold := model.Hold{Path: "/a/b/c"}
+	if err := CreateHold(&hold); err != nil {
+		t.Fatalf("failed to create hold: %+v", err)
+	}
+	change := model.Change{Path: "/a/b/d.txt", Action: model.ChangeCreate}
+	if err := CreateChange(&change); err != nil {
+		t.Fatalf("failed to create change: %+v", err)
+	}
+	// the count of the new path is merged into
+	if err := AddAccessCounts([]model.AccessCount{{Path: "/a/b", Downloads: 2}, {Path: "/x/b", Downloads: 1}}); err != nil {
+		t.Fatalf("failed to add access counts: %+v", err)
+	}
+	res, err := RewritePath("/a", "/x", true)
+	if err != nil {
+		t.Fatalf("failed to preview rewrite: %+v", err)
+	}
+	if len(res) != 7 {
+		t.Errorf("unexpected rewrite report: %+v", res)
+	}
+	if _, err := RewritePath("/a", "/x", false); err != nil {
+		t.Fatalf("failed to rewrite path: %+v", err)
+	}
+	if _, err := GetMetaByPath("/x/b/c"); err != nil {
+		t.Errorf("meta not rewritten: %+v", err)
+	}
+	if _, err := GetMetaByPath("/ab"); err != nil {
+		t.Errorf("unrelated meta should be kept: %+v", err)
+	}
+	if s, err := GetShareById(share.ID); err != nil || s.Path != "/x/b" {
+		t.Errorf("share not rewritten: %+v %+v", s, err)
+	}
+	if h, err := GetHoldById(hold.ID); err != nil || h.Path != "/x/b/c" {
+		t.Errorf("hold not rewritten: %+v %+v", h, err)
+	}
+	if changes, err := GetChanges("/x/b", "", 10); err != nil || len(changes) != 1 || changes[0].Path != "/x/b/d.txt" {
+		t.Errorf("change not rewritten: %+v %+v", changes, err)
+	}
+	counts, err := GetTopAccessCounts("/", "downloads", 10)
+	if err != nil || len(counts) != 1 || counts[0].Path != "/x/b" || counts[0].Downloads != 3 {
+		t.Errorf("access count not rewritten: %+v %+v", counts, err)
+	}
+}
+
+func TestCoverImage(t *testing.T) {
+	metas := []model.Meta{
+		{Path: "/gallery"},
+		{Path: "/gallery/2020", Cover: "cover.jpg"},
+		{Path: "/gallery/2021", Description: "the trip"},
+		{Path: "/gallery/2021/spring", Cover: "a.jpg"},
+	}
+	for i := range metas {
+		if err := CreateMeta(&metas[i]); err != nil {
+			t.Fatalf("failed to create meta: %+v", err)
+		}
+	}
+	children, err := GetChildMetas("/gallery")
+	if err != nil || len(children) != 2 {
+		t.Fatalf("expected 2 child metas, got %+v %+v", children, err)
+	}
+	if err := SaveCoverImage(&model.CoverImage{MetaID: metas[2].ID, Mimetype: "image/png", Data: []byte("png")}); err != nil {
+		t.Fatalf("failed save meta cover: %+v", err)
+	}
+	times, err := GetCoverImageTimes([]uint{metas[1].ID, metas[2].ID})
+	if _, ok := times[metas[2].ID]; err != nil || len(times) != 1 || !ok {
+		t.Fatalf("unexpected cover times: %+v %+v", times, err)
+	}
+	if err := DeleteMetaById(metas[2].ID); err != nil {
+		t.Fatalf("failed delete meta: %+v", err)
+	}
+	if _, err := GetCoverImage(metas[2].ID); err == nil {
+		t.Errorf("expected the cover is deleted with the meta")
+	}
+}
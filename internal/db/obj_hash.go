@@ -0,0 +1,64 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+// SaveObjHash insert or update the hashes of the file
+func SaveObjHash(h *model.ObjHash) error {
+	return errors.WithStack(db.Clauses(clause.OnConflict{UpdateAll: true}).Create(h).Error)
+}
+
+func GetObjHash(storageId uint, path string) (*model.ObjHash, error) {
+	var h model.ObjHash
+	if err := db.Where("storage_id = ? AND path = ?", storageId, path).First(&h).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get hash of [%s]", path)
+	}
+	return &h, nil
+}
+
+// DeleteObjHashes remove the hashes of the path and everything under it
+func DeleteObjHashes(storageId uint, path string) error {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var hashes []model.ObjHash
+	if err := db.Where("storage_id = ? AND (path = ? OR path LIKE ?)", storageId, path, prefix+"%").
+		Find(&hashes).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	for _, h := range hashes {
+		// LIKE treats % and _ in path as wildcards, so check the prefix again
+		if h.Path != path && !strings.HasPrefix(h.Path, prefix) {
+			continue
+		}
+		if err := db.Where("storage_id = ? AND path = ?", storageId, h.Path).
+			Delete(&model.ObjHash{}).Error; err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func DeleteObjHashesByStorage(storageId uint) error {
+	return errors.WithStack(db.Where("storage_id = ?", storageId).Delete(&model.ObjHash{}).Error)
+}
+
+// GetObjHashesInDir get the saved hashes of the files directly in the dir
+func GetObjHashesInDir(storageId uint, dir string) ([]model.ObjHash, error) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var hashes []model.ObjHash
+	if err := db.Where("storage_id = ? AND path LIKE ?", storageId, prefix+"%").Find(&hashes).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get hashes in [%s]", dir)
+	}
+	res := hashes[:0]
+	for _, h := range hashes {
+		// LIKE treats % and _ in path as wildcards, so check the prefix again
+		if name := strings.TrimPrefix(h.Path, prefix); strings.HasPrefix(h.Path, prefix) && !strings.Contains(name, "/") {
+			res = append(res, h)
+		}
+	}
+	return res, nil
+}
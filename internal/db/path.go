@@ -0,0 +1,189 @@
+package db
+
+import (
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	RecordMeta        = "meta"
+	RecordUser        = "user"
+	RecordShare       = "share"
+	RecordAccessCount = "access_count"
+	RecordHold        = "hold"
+	RecordChange      = "change"
+)
+
+// PathRewrite a record which references a renamed path
+type PathRewrite struct {
+	Type    string `json:"type"`
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+}
+
+// findUnder find the records whose column is the path or under it
+func findUnder(dest interface{}, column, path string) error {
+	return db.Where(column+" = ?", path).Or(column+" LIKE ?", path+"/%").Find(dest).Error
+}
+
+// RewritePath rewrite the paths of metas, users' base path, shares, access counts, holds and changes
+// under oldPath to newPath in one transaction, return the affected records, nothing is saved if dryRun
+func RewritePath(oldPath, newPath string, dryRun bool) ([]PathRewrite, error) {
+	oldPath, newPath = utils.StandardizePath(oldPath), utils.StandardizePath(newPath)
+	if oldPath == newPath || oldPath == "/" {
+		return nil, nil
+	}
+	var metas []model.Meta
+	if err := findUnder(&metas, "path", oldPath); err != nil {
+		return nil, errors.Wrapf(err, "failed get metas")
+	}
+	var users []model.User
+	if err := findUnder(&users, "base_path", oldPath); err != nil {
+		return nil, errors.Wrapf(err, "failed get users")
+	}
+	var shares []model.Share
+	if err := findUnder(&shares, "path", oldPath); err != nil {
+		return nil, errors.Wrapf(err, "failed get shares")
+	}
+	var counts []model.AccessCount
+	if err := findUnder(&counts, "path", oldPath); err != nil {
+		return nil, errors.Wrapf(err, "failed get access counts")
+	}
+	var holds []model.Hold
+	if err := findUnder(&holds, "path", oldPath); err != nil {
+		return nil, errors.Wrapf(err, "failed get holds")
+	}
+	var changes []model.Change
+	if err := findUnder(&changes, "path", oldPath); err != nil {
+		return nil, errors.Wrapf(err, "failed get changes")
+	}
+	var res []PathRewrite
+	var rewriteMetas []model.Meta
+	var rewriteUsers []model.User
+	var rewriteShares []model.Share
+	var rewriteCounts []model.AccessCount
+	var rewriteHolds []model.Hold
+	var rewriteChanges []model.Change
+	for _, meta := range metas {
+		if !utils.IsSubPath(oldPath, meta.Path) {
+			continue
+		}
+		p := utils.ReplacePathPrefix(meta.Path, oldPath, newPath)
+		res = append(res, PathRewrite{Type: RecordMeta, ID: meta.ID, Name: meta.Path, OldPath: meta.Path, NewPath: p})
+		meta.Path = p
+		rewriteMetas = append(rewriteMetas, meta)
+	}
+	for _, user := range users {
+		if !utils.IsSubPath(oldPath, user.BasePath) {
+			continue
+		}
+		p := utils.ReplacePathPrefix(user.BasePath, oldPath, newPath)
+		res = append(res, PathRewrite{Type: RecordUser, ID: user.ID, Name: user.Username, OldPath: user.BasePath, NewPath: p})
+		user.BasePath = p
+		rewriteUsers = append(rewriteUsers, user)
+	}
+	for _, share := range shares {
+		if !utils.IsSubPath(oldPath, share.Path) {
+			continue
+		}
+		p := utils.ReplacePathPrefix(share.Path, oldPath, newPath)
+		res = append(res, PathRewrite{Type: RecordShare, ID: share.ID, Name: share.Token, OldPath: share.Path, NewPath: p})
+		share.Path = p
+		rewriteShares = append(rewriteShares, share)
+	}
+	for _, count := range counts {
+		if !utils.IsSubPath(oldPath, count.Path) {
+			continue
+		}
+		p := utils.ReplacePathPrefix(count.Path, oldPath, newPath)
+		res = append(res, PathRewrite{Type: RecordAccessCount, Name: count.Path, OldPath: count.Path, NewPath: p})
+		rewriteCounts = append(rewriteCounts, count)
+	}
+	for _, hold := range holds {
+		if !utils.IsSubPath(oldPath, hold.Path) {
+			continue
+		}
+		p := utils.ReplacePathPrefix(hold.Path, oldPath, newPath)
+		res = append(res, PathRewrite{Type: RecordHold, ID: hold.ID, Name: hold.Path, OldPath: hold.Path, NewPath: p})
+		hold.Path = p
+		rewriteHolds = append(rewriteHolds, hold)
+	}
+	for _, change := range changes {
+		if !utils.IsSubPath(oldPath, change.Path) {
+			continue
+		}
+		p := utils.ReplacePathPrefix(change.Path, oldPath, newPath)
+		res = append(res, PathRewrite{Type: RecordChange, ID: change.ID, Name: change.Path, OldPath: change.Path, NewPath: p})
+		change.Path = p
+		rewriteChanges = append(rewriteChanges, change)
+	}
+	if dryRun || len(res) == 0 {
+		return res, nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range rewriteMetas {
+			if err := tx.Model(&rewriteMetas[i]).Update("path", rewriteMetas[i].Path).Error; err != nil {
+				return errors.Wrapf(err, "failed update meta [%d]", rewriteMetas[i].ID)
+			}
+		}
+		for i := range rewriteUsers {
+			if err := tx.Model(&rewriteUsers[i]).Update("base_path", rewriteUsers[i].BasePath).Error; err != nil {
+				return errors.Wrapf(err, "failed update user [%s]", rewriteUsers[i].Username)
+			}
+		}
+		for i := range rewriteShares {
+			if err := tx.Model(&rewriteShares[i]).Update("path", rewriteShares[i].Path).Error; err != nil {
+				return errors.Wrapf(err, "failed update share [%d]", rewriteShares[i].ID)
+			}
+		}
+		for _, count := range rewriteCounts {
+			if err := rewriteAccessCount(tx, count, utils.ReplacePathPrefix(count.Path, oldPath, newPath)); err != nil {
+				return errors.Wrapf(err, "failed update access count [%s]", count.Path)
+			}
+		}
+		for i := range rewriteHolds {
+			if err := tx.Model(&rewriteHolds[i]).Update("path", rewriteHolds[i].Path).Error; err != nil {
+				return errors.Wrapf(err, "failed update hold [%d]", rewriteHolds[i].ID)
+			}
+		}
+		for i := range rewriteChanges {
+			if err := tx.Model(&rewriteChanges[i]).Update("path", rewriteChanges[i].Path).Error; err != nil {
+				return errors.Wrapf(err, "failed update change [%d]", rewriteChanges[i].ID)
+			}
+		}
+		return nil
+	})
+	for _, r := range res {
+		if r.Type == RecordMeta {
+			metaCache.Del(r.OldPath)
+			metaCache.Del(r.NewPath)
+		}
+	}
+	for _, user := range rewriteUsers {
+		userCache.Del(user.Username)
+	}
+	guest, admin = nil, nil
+	return res, errors.WithStack(err)
+}
+
+// rewriteAccessCount move the count to the new path, as the path is the primary key,
+// it's added to the count already at the new path
+func rewriteAccessCount(tx *gorm.DB, count model.AccessCount, newPath string) error {
+	if err := tx.Delete(&model.AccessCount{}, "path = ?", count.Path).Error; err != nil {
+		return err
+	}
+	res := tx.Model(&model.AccessCount{}).Where("path = ?", newPath).Updates(map[string]interface{}{
+		"lists":            gorm.Expr("lists + ?", count.Lists),
+		"downloads":        gorm.Expr("downloads + ?", count.Downloads),
+		"period_downloads": gorm.Expr("period_downloads + ?", count.PeriodDownloads),
+	})
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+	count.Path = newPath
+	return tx.Create(&count).Error
+}
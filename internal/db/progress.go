@@ -0,0 +1,53 @@
+package db
+
+import (
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// SaveProgress save the progress unless a newer one is saved by another device,
+// the progress kept is returned
+func SaveProgress(p *model.Progress) (*model.Progress, error) {
+	var old model.Progress
+	err := db.Where("user_id = ? AND path = ?", p.UserID, p.Path).First(&old).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrapf(err, "failed get progress")
+	}
+	if err == nil {
+		if old.Updated.After(p.Updated) {
+			return &old, nil
+		}
+		p.ID = old.ID
+	}
+	if err := db.Save(p).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return p, nil
+}
+
+func GetProgress(userId uint, path string) (*model.Progress, error) {
+	var p model.Progress
+	if err := db.Where("user_id = ? AND path = ?", userId, path).First(&p).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get progress")
+	}
+	return &p, nil
+}
+
+// GetProgresses get the progresses of the user, the latest first, to continue watching
+func GetProgresses(userId uint, pageIndex, pageSize int) ([]model.Progress, int64, error) {
+	progressDB := db.Model(&model.Progress{}).Where("user_id = ?", userId)
+	var count int64
+	if err := progressDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get progresses count")
+	}
+	var progresses []model.Progress
+	if err := progressDB.Order("updated desc").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&progresses).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find progresses")
+	}
+	return progresses, count, nil
+}
+
+func DeleteProgress(userId uint, path string) error {
+	return errors.WithStack(db.Where("user_id = ? AND path = ?", userId, path).Delete(&model.Progress{}).Error)
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestProgress(t *testing.T) {
+	now := time.Now()
+	if _, err := SaveProgress(&model.Progress{UserID: 100, Path: "/movie.mkv", Position: 60, Device: "tv", Updated: now}); err != nil {
+		t.Fatalf("failed save progress: %+v", err)
+	}
+	// synced late by an offline device
+	p, err := SaveProgress(&model.Progress{UserID: 100, Path: "/movie.mkv", Position: 10, Device: "phone", Updated: now.Add(-time.Minute)})
+	if err != nil || p.Position != 60 || p.Device != "tv" {
+		t.Fatalf("expected the newer progress is kept, got %+v %+v", p, err)
+	}
+	if _, err := SaveProgress(&model.Progress{UserID: 100, Path: "/movie.mkv", Position: 90, Device: "phone", Updated: now.Add(time.Minute)}); err != nil {
+		t.Fatalf("failed save progress: %+v", err)
+	}
+	if _, err := SaveProgress(&model.Progress{UserID: 101, Path: "/movie.mkv", Position: 5, Updated: now}); err != nil {
+		t.Fatalf("failed save progress: %+v", err)
+	}
+	progresses, total, err := GetProgresses(100, 1, 10)
+	if err != nil || total != 1 || progresses[0].Position != 90 || progresses[0].Device != "phone" {
+		t.Fatalf("unexpected progresses: %+v %d %+v", progresses, total, err)
+	}
+	if err := DeleteProgress(100, "/movie.mkv"); err != nil {
+		t.Fatalf("failed delete progress: %+v", err)
+	}
+	if _, err := GetProgress(100, "/movie.mkv"); err == nil {
+		t.Errorf("expected the progress is deleted")
+	}
+	if p, err := GetProgress(101, "/movie.mkv"); err != nil || p.Position != 5 {
+		t.Errorf("expected the progress of others is kept, got %+v %+v", p, err)
+	}
+}
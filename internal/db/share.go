@@ -0,0 +1,76 @@
+package db
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateShare(s *model.Share) error {
+	return errors.WithStack(db.Create(s).Error)
+}
+
+func GetShareByToken(token string) (*model.Share, error) {
+	var s model.Share
+	if err := db.Where("token = ?", token).First(&s).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get share")
+	}
+	return &s, nil
+}
+
+func GetShareById(id uint) (*model.Share, error) {
+	var s model.Share
+	if err := db.First(&s, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get share")
+	}
+	return &s, nil
+}
+
+// GetShares get the shares created by the user, all users if userID is 0
+func GetShares(userID uint, pageIndex, pageSize int) ([]model.Share, int64, error) {
+	shareDB := db.Model(&model.Share{})
+	if userID != 0 {
+		shareDB = shareDB.Where("user_id = ?", userID)
+	}
+	var count int64
+	if err := shareDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get shares count")
+	}
+	var shares []model.Share
+	if err := shareDB.Order("id desc").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&shares).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find shares")
+	}
+	return shares, count, nil
+}
+
+func DeleteShareById(id uint) error {
+	return errors.WithStack(db.Delete(&model.Share{}, id).Error)
+}
+
+func CreateShareLog(l *model.ShareLog) error {
+	return errors.WithStack(db.Create(l).Error)
+}
+
+// GetShareLogs get the latest logs, of all shares if token is empty
+func GetShareLogs(token string, pageIndex, pageSize int) ([]model.ShareLog, int64, error) {
+	logDB := db.Model(&model.ShareLog{})
+	if token != "" {
+		logDB = logDB.Where("token = ?", token)
+	}
+	var count int64
+	if err := logDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get share logs count")
+	}
+	var logs []model.ShareLog
+	if err := logDB.Order("id desc").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find share logs")
+	}
+	return logs, count, nil
+}
+
+// DeleteShareLogsBefore remove the old logs, return the count of deleted
+func DeleteShareLogsBefore(t time.Time) (int64, error) {
+	res := db.Where("time < ?", t).Delete(&model.ShareLog{})
+	return res.RowsAffected, errors.WithStack(res.Error)
+}
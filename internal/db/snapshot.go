@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Snapshot write a copy of the sqlite database to the file
+func Snapshot(file string) error {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Exec("VACUUM INTO ?", file).Error)
+}
+
+// Restore replace the rows of the sqlite database with the ones in the file,
+// the tables or the columns missing in the file are left empty
+func Restore(file string) error {
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// the attached database is only visible to the connection attaching it
+	err = db.Connection(func(tx *gorm.DB) error {
+		if err := tx.Exec("ATTACH DATABASE ? AS snapshot", file).Error; err != nil {
+			return err
+		}
+		defer tx.Exec("DETACH DATABASE snapshot")
+		return tx.Transaction(func(tx *gorm.DB) error {
+			for _, table := range tables {
+				if err := restoreTable(tx, table); err != nil {
+					return errors.WithMessagef(err, "failed restore table %s", table)
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	clearCaches()
+	return nil
+}
+
+func restoreTable(tx *gorm.DB, table string) error {
+	var columns []string
+	err := tx.Raw("SELECT name FROM pragma_table_info(?, 'snapshot')", table).Scan(&columns).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec(fmt.Sprintf("DELETE FROM main.`%s`", table)).Error; err != nil {
+		return err
+	}
+	// not exists in the snapshot
+	if len(columns) == 0 {
+		return nil
+	}
+	cols := "`" + strings.Join(columns, "`,`") + "`"
+	return tx.Exec(fmt.Sprintf("INSERT INTO main.`%s` (%s) SELECT %s FROM snapshot.`%s`", table, cols, cols, table)).Error
+}
+
+// clearCaches forget the rows cached in memory after they are replaced
+func clearCaches() {
+	settingsMap = nil
+	publicSettingsMap = nil
+	admin = nil
+	guest = nil
+	userCache.Clear()
+	metaCache.Clear()
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	if err := CreateMeta(&model.Meta{Path: "/snapshot/kept"}); err != nil {
+		t.Fatalf("failed create meta: %+v", err)
+	}
+	file := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := Snapshot(file); err != nil {
+		t.Fatalf("failed snapshot: %+v", err)
+	}
+	if err := CreateMeta(&model.Meta{Path: "/snapshot/added"}); err != nil {
+		t.Fatalf("failed create meta: %+v", err)
+	}
+	if _, err := GetMetaByPath("/snapshot/added"); err != nil {
+		t.Fatalf("failed get meta: %+v", err)
+	}
+	if err := Restore(file); err != nil {
+		t.Fatalf("failed restore: %+v", err)
+	}
+	if _, err := GetMetaByPath("/snapshot/kept"); err != nil {
+		t.Errorf("expected meta in snapshot is kept: %+v", err)
+	}
+	if _, err := GetMetaByPath("/snapshot/added"); err == nil {
+		t.Errorf("expected meta added after snapshot is removed")
+	}
+}
@@ -1,8 +1,14 @@
 package db
 
 import (
+	"sort"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 // why don't need `cache` for storage?
@@ -10,14 +16,140 @@ import (
 // the most of the read operation is from `operations.storagesMap`
 // just for persistence in database
 
+// the prefix of the encrypted addition, so the plaintext rows can still be read
+const encryptedAdditionPrefix = "enc:"
+
+// encryptAddition encrypt the addition if it's enabled, the encrypted one is returned as it is
+func encryptAddition(addition string) (string, error) {
+	if !conf.Conf.EncryptAddition || strings.HasPrefix(addition, encryptedAdditionPrefix) {
+		return addition, nil
+	}
+	encrypted, err := utils.EncryptString(conf.Conf.EncryptKey, addition)
+	if err != nil {
+		return addition, err
+	}
+	return encryptedAdditionPrefix + encrypted, nil
+}
+
+// decryptAddition decrypt the encrypted addition, whether the encryption is enabled or not
+func decryptAddition(addition string) (string, error) {
+	if !strings.HasPrefix(addition, encryptedAdditionPrefix) {
+		return addition, nil
+	}
+	return utils.DecryptString(conf.Conf.EncryptKey, strings.TrimPrefix(addition, encryptedAdditionPrefix))
+}
+
+// encryptStorage return a copy with the addition encrypted if it's enabled
+func encryptStorage(storage model.Storage) (model.Storage, error) {
+	addition, err := encryptAddition(storage.Addition)
+	if err != nil {
+		return storage, errors.Wrapf(err, "failed encrypt addition of storage [%s]", storage.MountPath)
+	}
+	storage.Addition = addition
+	return storage, nil
+}
+
+// decryptStorages decrypt the encrypted additions in place, whether the encryption is enabled or not
+func decryptStorages(storages ...*model.Storage) error {
+	for _, storage := range storages {
+		addition, err := decryptAddition(storage.Addition)
+		if err != nil {
+			return errors.Wrapf(err, "failed decrypt addition of storage [%s]", storage.MountPath)
+		}
+		storage.Addition = addition
+	}
+	return nil
+}
+
+func decryptStorageSlice(storages []model.Storage) error {
+	for i := range storages {
+		if err := decryptStorages(&storages[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CreateStorage just insert storage to database
 func CreateStorage(storage *model.Storage) error {
-	return errors.WithStack(db.Create(storage).Error)
+	encrypted, err := encryptStorage(*storage)
+	if err != nil {
+		return err
+	}
+	if err := db.Create(&encrypted).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	storage.ID = encrypted.ID
+	return nil
 }
 
 // UpdateStorage just update storage in database
 func UpdateStorage(storage *model.Storage) error {
-	return errors.WithStack(db.Save(storage).Error)
+	encrypted, err := encryptStorage(*storage)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(db.Save(&encrypted).Error)
+}
+
+// UpdateStorages save the storages in one transaction, none is saved if any failed
+func UpdateStorages(storages []model.Storage) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for _, storage := range storages {
+			encrypted, err := encryptStorage(storage)
+			if err != nil {
+				return err
+			}
+			if err := tx.Save(&encrypted).Error; err != nil {
+				return errors.Wrapf(err, "failed update storage [%s]", storage.MountPath)
+			}
+		}
+		return nil
+	}))
+}
+
+// EncryptStorageAdditions encrypt the additions of all storages saved in plaintext,
+// return the count of encrypted
+func EncryptStorageAdditions() (int, error) {
+	var storages []model.Storage
+	if err := db.Find(&storages).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	n := 0
+	for _, storage := range storages {
+		if strings.HasPrefix(storage.Addition, encryptedAdditionPrefix) {
+			continue
+		}
+		encrypted, err := encryptStorage(storage)
+		if err != nil {
+			return n, err
+		}
+		if err := db.Model(&model.Storage{}).Where("id = ?", storage.ID).Update("addition", encrypted.Addition).Error; err != nil {
+			return n, errors.Wrapf(err, "failed save addition of storage [%s]", storage.MountPath)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// UpdateStorageStatus only update the status, init attempts and last error of the storage,
+// so that the addition saved by the driver will not be overwritten
+func UpdateStorageStatus(id uint, status string, attempts int, lastError string) error {
+	return errors.WithStack(db.Model(&model.Storage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        status,
+		"init_attempts": attempts,
+		"last_error":    lastError,
+	}).Error)
+}
+
+// UpdateStorageDependsOn only update the mount paths the storage depends on
+func UpdateStorageDependsOn(id uint, dependsOn string) error {
+	return errors.WithStack(db.Model(&model.Storage{}).Where("id = ?", id).Update("depends_on", dependsOn).Error)
+}
+
+// UpdateStorageOnBackup only update whether the backup credential of the storage is in use
+func UpdateStorageOnBackup(id uint, onBackup bool) error {
+	return errors.WithStack(db.Model(&model.Storage{}).Where("id = ?", id).Update("on_backup", onBackup).Error)
 }
 
 // DeleteStorageById just delete storage from database by id
@@ -25,9 +157,27 @@ func DeleteStorageById(id uint) error {
 	return errors.WithStack(db.Delete(&model.Storage{}, id).Error)
 }
 
-// GetStorages Get all storages from database order by index
-func GetStorages(pageIndex, pageSize int) ([]model.Storage, int64, error) {
+type StorageFilter struct {
+	Tag    string `json:"tag" form:"tag"`
+	Driver string `json:"driver" form:"driver"`
+	Status string `json:"status" form:"status"`
+}
+
+// GetStorages Get the storages matching the filter from database order by index
+func GetStorages(pageIndex, pageSize int, filter StorageFilter) ([]model.Storage, int64, error) {
 	storageDB := db.Model(&model.Storage{})
+	if filter.Tag != "" {
+		// the tags are normalized when saved, so the tag is the whole, first, last or a middle one
+		tag := escapeLike(filter.Tag)
+		storageDB = storageDB.Where("tags = ? OR tags LIKE ? ESCAPE ? OR tags LIKE ? ESCAPE ? OR tags LIKE ? ESCAPE ?",
+			filter.Tag, tag+",%", likeEscape, "%,"+tag, likeEscape, "%,"+tag+",%", likeEscape)
+	}
+	if filter.Driver != "" {
+		storageDB = storageDB.Where("driver = ?", filter.Driver)
+	}
+	if filter.Status != "" {
+		storageDB = storageDB.Where("status = ?", filter.Status)
+	}
 	var count int64
 	if err := storageDB.Count(&count).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get storages count")
@@ -36,6 +186,9 @@ func GetStorages(pageIndex, pageSize int) ([]model.Storage, int64, error) {
 	if err := storageDB.Order(columnName("index")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&storages).Error; err != nil {
 		return nil, 0, errors.WithStack(err)
 	}
+	if err := decryptStorageSlice(storages); err != nil {
+		return nil, 0, err
+	}
 	return storages, count, nil
 }
 
@@ -46,5 +199,109 @@ func GetStorageById(id uint) (*model.Storage, error) {
 	if err := db.First(&storage).Error; err != nil {
 		return nil, errors.WithStack(err)
 	}
+	if err := decryptStorages(&storage); err != nil {
+		return nil, err
+	}
+	return &storage, nil
+}
+
+// GetAllStorages Get all storages from database order by index, used to load storages at startup
+func GetAllStorages() ([]model.Storage, error) {
+	var storages []model.Storage
+	if err := db.Order(columnName("index")).Find(&storages).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := decryptStorageSlice(storages); err != nil {
+		return nil, err
+	}
+	return storages, nil
+}
+
+// GetStoragesByCredentialId get storages using the credential, as the primary or backup one
+func GetStoragesByCredentialId(id uint) ([]model.Storage, error) {
+	var storages []model.Storage
+	if err := db.Where("credential_id = ? OR backup_credential_id = ?", id, id).Find(&storages).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := decryptStorageSlice(storages); err != nil {
+		return nil, err
+	}
+	return storages, nil
+}
+
+// GetStorageByMountPath Get Storage by mount path, used to check conflicts
+func GetStorageByMountPath(mountPath string) (*model.Storage, error) {
+	var storage model.Storage
+	if err := db.Where("mount_path = ?", mountPath).First(&storage).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := decryptStorages(&storage); err != nil {
+		return nil, err
+	}
 	return &storage, nil
 }
+
+// ReorderStorages set the index of storages to their position in ids in one transaction
+func ReorderStorages(ids []uint) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			res := tx.Model(&model.Storage{}).Where("id = ?", id).Update("index", i)
+			if res.Error != nil {
+				return errors.Wrapf(res.Error, "failed update index of storage [%d]", id)
+			}
+			if res.RowsAffected == 0 {
+				return errors.Errorf("storage [%d] not found", id)
+			}
+		}
+		return nil
+	}))
+}
+
+const (
+	GroupByTag    = "tag"
+	GroupByDriver = "driver"
+	GroupByStatus = "status"
+)
+
+type StorageGroup struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// GetStorageGroups count the storages grouped by tag, driver or status,
+// the storages without tag are counted in the group with empty name
+func GetStorageGroups(by string) ([]StorageGroup, error) {
+	var groups []StorageGroup
+	switch by {
+	case GroupByDriver, GroupByStatus:
+		err := db.Model(&model.Storage{}).Select(by + " as name, count(*) as count").
+			Group(by).Order("name").Scan(&groups).Error
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case GroupByTag:
+		var storages []model.Storage
+		if err := db.Select("tags").Find(&storages).Error; err != nil {
+			return nil, errors.WithStack(err)
+		}
+		counts := make(map[string]int64)
+		for _, storage := range storages {
+			tags := storage.GetTags()
+			if len(tags) == 0 {
+				tags = []string{""}
+			}
+			for _, tag := range tags {
+				counts[tag]++
+			}
+		}
+		for name, count := range counts {
+			groups = append(groups, StorageGroup{Name: name, Count: count})
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			return groups[i].Name < groups[j].Name
+		})
+	default:
+		return nil, errors.Errorf("unsupported group by: %s", by)
+	}
+	return groups, nil
+}
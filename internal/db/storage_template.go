@@ -0,0 +1,105 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// the addition of a template is encrypted like the one of a storage, it may have the secrets too
+
+func encryptStorageTemplate(t model.StorageTemplate) (model.StorageTemplate, error) {
+	addition, err := encryptAddition(t.Addition)
+	if err != nil {
+		return t, errors.Wrapf(err, "failed encrypt addition of storage template [%s]", t.Name)
+	}
+	t.Addition = addition
+	return t, nil
+}
+
+func decryptStorageTemplate(t *model.StorageTemplate) error {
+	addition, err := decryptAddition(t.Addition)
+	if err != nil {
+		return errors.Wrapf(err, "failed decrypt addition of storage template [%s]", t.Name)
+	}
+	t.Addition = addition
+	return nil
+}
+
+func CreateStorageTemplate(t *model.StorageTemplate) error {
+	encrypted, err := encryptStorageTemplate(*t)
+	if err != nil {
+		return err
+	}
+	if err := db.Create(&encrypted).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	t.ID = encrypted.ID
+	return nil
+}
+
+func UpdateStorageTemplate(t *model.StorageTemplate) error {
+	encrypted, err := encryptStorageTemplate(*t)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(db.Save(&encrypted).Error)
+}
+
+func GetStorageTemplateById(id uint) (*model.StorageTemplate, error) {
+	var t model.StorageTemplate
+	if err := db.First(&t, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get storage template")
+	}
+	if err := decryptStorageTemplate(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func GetStorageTemplates(pageIndex, pageSize int) ([]model.StorageTemplate, int64, error) {
+	templateDB := db.Model(&model.StorageTemplate{})
+	var count int64
+	if err := templateDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get storage templates count")
+	}
+	var templates []model.StorageTemplate
+	if err := templateDB.Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&templates).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find storage templates")
+	}
+	for i := range templates {
+		if err := decryptStorageTemplate(&templates[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return templates, count, nil
+}
+
+func DeleteStorageTemplateById(id uint) error {
+	return errors.WithStack(db.Delete(&model.StorageTemplate{}, id).Error)
+}
+
+// EncryptStorageTemplateAdditions encrypt the additions of all storage templates saved in plaintext,
+// return the count of encrypted
+func EncryptStorageTemplateAdditions() (int, error) {
+	var templates []model.StorageTemplate
+	if err := db.Find(&templates).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+	n := 0
+	for _, t := range templates {
+		if strings.HasPrefix(t.Addition, encryptedAdditionPrefix) {
+			continue
+		}
+		encrypted, err := encryptStorageTemplate(t)
+		if err != nil {
+			return n, err
+		}
+		if err := db.Model(&model.StorageTemplate{}).Where("id = ?", t.ID).Update("addition", encrypted.Addition).Error; err != nil {
+			return n, errors.Wrapf(err, "failed save addition of storage template [%s]", t.Name)
+		}
+		n++
+	}
+	return n, nil
+}
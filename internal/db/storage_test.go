@@ -0,0 +1,121 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestGetStoragesByTag(t *testing.T) {
+	conf.Conf = conf.DefaultConfig()
+	storages := []model.Storage{
+		{MountPath: "/tag/a", Driver: "Local", Tags: "media"},
+		{MountPath: "/tag/b", Driver: "Local", Tags: "backup,media,cold"},
+		{MountPath: "/tag/c", Driver: "Virtual", Tags: "multimedia"},
+	}
+	for i := range storages {
+		if err := CreateStorage(&storages[i]); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	res, total, err := GetStorages(1, 10, StorageFilter{Tag: "media"})
+	if err != nil {
+		t.Fatalf("failed get storages: %+v", err)
+	}
+	if total != 2 || len(res) != 2 {
+		t.Errorf("expected 2 storages tagged media, got %d", total)
+	}
+	res, _, err = GetStorages(1, 10, StorageFilter{Tag: "media", Driver: "Virtual"})
+	if err != nil {
+		t.Fatalf("failed get storages: %+v", err)
+	}
+	if len(res) != 0 {
+		t.Errorf("expected no storage, got %+v", res)
+	}
+	// the wildcards of LIKE in the tag are matched literally
+	res, _, err = GetStorages(1, 10, StorageFilter{Tag: "med_a"})
+	if err != nil {
+		t.Fatalf("failed get storages: %+v", err)
+	}
+	if len(res) != 0 {
+		t.Errorf("expected no storage tagged med_a, got %+v", res)
+	}
+	groups, err := GetStorageGroups(GroupByTag)
+	if err != nil {
+		t.Fatalf("failed get groups: %+v", err)
+	}
+	for _, group := range groups {
+		if group.Name == "media" && group.Count != 2 {
+			t.Errorf("expected 2 in group media, got %d", group.Count)
+		}
+	}
+	if _, err := GetStorageGroups(GroupByDriver); err != nil {
+		t.Errorf("failed group by driver: %+v", err)
+	}
+}
+
+func TestEncryptAddition(t *testing.T) {
+	conf.Conf = conf.DefaultConfig()
+	plain := model.Storage{MountPath: "/encrypt/plain", Driver: "Local", Addition: `{"token":"a"}`}
+	if err := CreateStorage(&plain); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	conf.Conf.EncryptAddition = true
+	defer func() {
+		conf.Conf.EncryptAddition = false
+	}()
+	storage := model.Storage{MountPath: "/encrypt/new", Driver: "Local", Addition: `{"token":"b"}`}
+	if err := CreateStorage(&storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	if storage.Addition != `{"token":"b"}` {
+		t.Errorf("the addition of the caller should be kept, got %s", storage.Addition)
+	}
+	if _, err := EncryptStorageAdditions(); err != nil {
+		t.Fatalf("failed encrypt additions: %+v", err)
+	}
+	var raw model.Storage
+	if err := db.First(&raw, plain.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw.Addition, encryptedAdditionPrefix) {
+		t.Errorf("expected encrypted addition in database, got %s", raw.Addition)
+	}
+	for _, s := range []model.Storage{plain, storage} {
+		got, err := GetStorageById(s.ID)
+		if err != nil {
+			t.Fatalf("failed get storage: %+v", err)
+		}
+		if got.Addition != s.Addition {
+			t.Errorf("expected %s, got %s", s.Addition, got.Addition)
+		}
+	}
+}
+
+func TestEncryptTemplateAddition(t *testing.T) {
+	conf.Conf = conf.DefaultConfig()
+	conf.Conf.EncryptAddition = true
+	defer func() {
+		conf.Conf.EncryptAddition = false
+	}()
+	template := model.StorageTemplate{Name: "encrypt", Driver: "Local", Addition: `{"token":"c"}`}
+	if err := CreateStorageTemplate(&template); err != nil {
+		t.Fatalf("failed create storage template: %+v", err)
+	}
+	var raw model.StorageTemplate
+	if err := db.First(&raw, template.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw.Addition, encryptedAdditionPrefix) {
+		t.Errorf("expected encrypted addition in database, got %s", raw.Addition)
+	}
+	got, err := GetStorageTemplateById(template.ID)
+	if err != nil {
+		t.Fatalf("failed get storage template: %+v", err)
+	}
+	if got.Addition != template.Addition {
+		t.Errorf("expected %s, got %s", template.Addition, got.Addition)
+	}
+}
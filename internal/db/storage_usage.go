@@ -0,0 +1,24 @@
+package db
+
+import (
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+// SaveStorageUsage insert or update the usage of the storage
+func SaveStorageUsage(u *model.StorageUsage) error {
+	return errors.WithStack(db.Clauses(clause.OnConflict{UpdateAll: true}).Create(u).Error)
+}
+
+func GetStorageUsages() ([]model.StorageUsage, error) {
+	var usages []model.StorageUsage
+	if err := db.Find(&usages).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get storage usages")
+	}
+	return usages, nil
+}
+
+func DeleteStorageUsage(storageId uint) error {
+	return errors.WithStack(db.Delete(&model.StorageUsage{}, storageId).Error)
+}
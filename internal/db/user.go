@@ -6,6 +6,7 @@ import (
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/pkg/singleflight"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -106,5 +107,30 @@ func DeleteUserById(id uint) error {
 		return errors.WithStack(errs.DeleteAdminOrGuest)
 	}
 	userCache.Del(old.Username)
-	return errors.WithStack(db.Delete(&model.User{}, id).Error)
+	// the rows of the user are deleted together, or a reused id would inherit them
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{&model.AppPassword{}, &model.Share{}, &model.Progress{}, &model.Activity{}} {
+			if err := tx.Where("user_id = ?", id).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.User{}, id).Error
+	}))
+}
+
+// GetUsersCreatedBetween get the users created in [start, end), used to find the new ones
+func GetUsersCreatedBetween(start, end time.Time) ([]model.User, error) {
+	var users []model.User
+	if err := db.Where("created_at >= ? AND created_at < ?", start, end).Order("id").Find(&users).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get users")
+	}
+	return users, nil
+}
+
+func GetAllUsers() ([]model.User, error) {
+	var users []model.User
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get users")
+	}
+	return users, nil
 }
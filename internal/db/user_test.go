@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+func TestDeleteUserById(t *testing.T) {
+	user := model.User{Username: "deleted"}
+	if err := CreateUser(&user); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	p := model.AppPassword{UserID: user.ID, Name: "phone", Protocol: model.ProtocolWebdav, Created: time.Now()}
+	if err := CreateAppPassword(&p, "secret"); err != nil {
+		t.Fatalf("failed create app password: %+v", err)
+	}
+	if err := CreateShare(&model.Share{Token: "deleted", UserID: user.ID, Path: "/a"}); err != nil {
+		t.Fatalf("failed create share: %+v", err)
+	}
+	if err := DeleteUserById(user.ID); err != nil {
+		t.Fatalf("failed delete user: %+v", err)
+	}
+	if passwords, err := GetAppPasswordsOfUser(user.ID); err != nil || len(passwords) != 0 {
+		t.Errorf("expected the app passwords are deleted, got %+v %+v", passwords, err)
+	}
+	var count int64
+	if err := db.Model(&model.Share{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("expected the shares are deleted, got %d %+v", count, err)
+	}
+}
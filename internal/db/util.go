@@ -2,6 +2,8 @@ package db
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/alist-org/alist/v3/internal/conf"
 )
 
@@ -11,3 +13,13 @@ func columnName(name string) string {
 	}
 	return fmt.Sprintf("`%s`", name)
 }
+
+// likeEscaper escape the wildcards of LIKE, the patterns are matched with ESCAPE ?
+// bound to likeEscape, as a literal backslash isn't portable across the databases
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+const likeEscape = `\`
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
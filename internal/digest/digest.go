@@ -0,0 +1,200 @@
+// Package digest compile the weekly or monthly report for admins
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/aria2"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/notify"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/pkg/task"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the hour of the day to send the digests
+const sendHour = 8
+
+// the digest of admin is sent by one goroutine, but the state is saved in the database,
+// so the lock only keeps Run from overlapping itself
+var mu sync.Mutex
+
+// due tell whether it's the time to send the digest
+func due(digest string, now time.Time) bool {
+	if now.Hour() != sendHour {
+		return false
+	}
+	switch digest {
+	case model.DigestWeekly:
+		return now.Weekday() == time.Monday
+	case model.DigestMonthly:
+		return now.Day() == 1
+	}
+	return false
+}
+
+// lastSent the time the last digest was sent, zero if never
+func lastSent() time.Time {
+	t, err := time.Parse(time.RFC3339, setting.GetByKey(conf.DigestSentAt))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// PeriodStart the start of the period of the digest ending at now,
+// which is the time the last one was sent, or a whole period before if never sent
+func PeriodStart(digest string, now time.Time) time.Time {
+	if sent := lastSent(); !sent.IsZero() && sent.Before(now) {
+		return sent
+	}
+	if digest == model.DigestMonthly {
+		return now.AddDate(0, -1, 0)
+	}
+	return now.AddDate(0, 0, -7)
+}
+
+// Run send the digest due at now to admin and start the next period, it should be called every hour
+func Run(now time.Time) {
+	admin, err := db.GetAdmin()
+	if err != nil {
+		log.Errorf("failed get admin: %+v", err)
+		return
+	}
+	if !due(admin.Digest, now) {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// sent in this hour already, e.g. before restarting
+	if !lastSent().Before(now.Truncate(time.Hour)) {
+		return
+	}
+	if err := Send(admin, PeriodStart(admin.Digest, now), now); err != nil {
+		log.Errorf("failed send digest to [%s]: %+v", admin.Username, err)
+		return
+	}
+	if err := db.ResetPeriodDownloads(); err != nil {
+		log.Errorf("failed reset the downloads of the period: %+v", err)
+	}
+	err = db.SaveSettingItem(model.SettingItem{Key: conf.DigestSentAt, Value: now.Format(time.RFC3339),
+		Type: conf.TypeString, Group: model.SINGLE, Flag: model.PRIVATE})
+	if err != nil {
+		log.Errorf("failed save the time of the digest: %+v", err)
+	}
+}
+
+// Send the digest of the period [since, until) to the user, the state of the digests is not changed
+func Send(user *model.User, since, until time.Time) error {
+	body, err := Build(since, until)
+	if err != nil {
+		return err
+	}
+	var to []string
+	if user.Email != "" {
+		to = append(to, user.Email)
+	}
+	return notify.Send(notify.Message{
+		Subject: fmt.Sprintf("[%s] digest since %s", setting.GetByKey(conf.SiteTitle), since.Format("2006-01-02")),
+		Body:    body,
+		To:      to,
+	})
+}
+
+// Build the report of storage health, traffic, failed tasks, new users and expiring tokens
+// of the period [since, until)
+func Build(since, until time.Time) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Report from %s to %s\n", since.Format(time.RFC1123), until.Format(time.RFC1123))
+
+	storages, err := db.GetAllStorages()
+	if err != nil {
+		return "", errors.WithMessage(err, "failed get storages")
+	}
+	sb.WriteString("\n== Storage health ==\n")
+	unhealthy := 0
+	for _, s := range storages {
+		if s.Status != model.StorageWork && s.Status != model.StoragePending {
+			unhealthy++
+			fmt.Fprintf(&sb, "- %s: %s %s\n", s.MountPath, s.Status, s.LastError)
+		}
+	}
+	fmt.Fprintf(&sb, "%d storages, %d not working\n", len(storages), unhealthy)
+	if summary, err := operations.GetUsageSummary(); err == nil && summary.Total > 0 {
+		fmt.Fprintf(&sb, "space used %.1f%% (%d of %d bytes)\n", float64(summary.Used)*100/float64(summary.Total), summary.Used, summary.Total)
+	}
+
+	sb.WriteString("\n== Traffic ==\n")
+	// counted since the last digest sent, the accesses not saved yet are in the next one
+	counts, err := db.GetTopPeriodDownloads(10)
+	if err != nil {
+		return "", err
+	}
+	for _, count := range counts {
+		fmt.Fprintf(&sb, "- %s: %d downloads\n", count.Path, count.PeriodDownloads)
+	}
+	if len(counts) == 0 {
+		sb.WriteString("no downloads\n")
+	}
+
+	sb.WriteString("\n== Failed tasks ==\n")
+	failed := 0
+	inPeriod := func(t time.Time) bool {
+		return !t.Before(since) && t.Before(until)
+	}
+	writeFailed := func(kind string, name string, err string) {
+		failed++
+		fmt.Fprintf(&sb, "- [%s] %s: %s\n", kind, name, err)
+	}
+	for _, t := range aria2.DownTaskManager.GetByStates(task.ERRORED) {
+		if inPeriod(t.GetEndTime()) {
+			writeFailed("download", t.Name, t.GetErrMsg())
+		}
+	}
+	for kind, tm := range map[string]*task.Manager[uint64]{
+		"transfer": aria2.TransferTaskManager,
+		"upload":   fs.UploadTaskManager,
+		"copy":     fs.CopyTaskManager,
+		"checksum": fs.ChecksumTaskManager,
+		"migrate":  fs.MigrateTaskManager,
+	} {
+		for _, t := range tm.GetByStates(task.ERRORED) {
+			if inPeriod(t.GetEndTime()) {
+				writeFailed(kind, t.Name, t.GetErrMsg())
+			}
+		}
+	}
+	if failed == 0 {
+		sb.WriteString("no failed task\n")
+	}
+
+	sb.WriteString("\n== New users ==\n")
+	users, err := db.GetUsersCreatedBetween(since, until)
+	if err != nil {
+		return "", err
+	}
+	for _, u := range users {
+		fmt.Fprintf(&sb, "- %s\n", u.Username)
+	}
+	if len(users) == 0 {
+		sb.WriteString("no new user\n")
+	}
+
+	sb.WriteString("\n== Expiring credentials ==\n")
+	expiring := operations.GetExpiringStorages(7 * 24 * time.Hour)
+	for _, s := range expiring {
+		fmt.Fprintf(&sb, "- %s: expire at %s\n", s.Storage.MountPath, s.ExpireAt.Format(time.RFC1123))
+	}
+	if len(expiring) == 0 {
+		sb.WriteString("no credential expires in 7 days\n")
+	}
+	return sb.String(), nil
+}
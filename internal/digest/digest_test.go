@@ -0,0 +1,102 @@
+package digest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig()
+	db.Init(dB)
+}
+
+func TestDigest(t *testing.T) {
+	var bodies []string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Body string `json:"body"`
+		}
+		if err := utils.Json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed decode message: %+v", err)
+		}
+		bodies = append(bodies, msg.Body)
+	}))
+	defer webhook.Close()
+	if err := db.SaveSettingItem(model.SettingItem{Key: conf.NotifyWebhook, Value: webhook.URL}); err != nil {
+		t.Fatalf("failed save setting: %+v", err)
+	}
+	// the time to send the next weekly digest
+	now := time.Now().Truncate(time.Hour)
+	for now.Weekday() != time.Monday || now.Hour() != sendHour {
+		now = now.Add(time.Hour)
+	}
+	users := []model.User{
+		{Username: "admin", Role: model.ADMIN, Digest: model.DigestWeekly, CreatedAt: now.AddDate(0, 0, -30)},
+		{Username: "old_user", CreatedAt: now.AddDate(0, 0, -8)},
+		{Username: "new_user", CreatedAt: now.AddDate(0, 0, -1)},
+	}
+	for i := range users {
+		if err := db.CreateUser(&users[i]); err != nil {
+			t.Fatalf("failed create user: %+v", err)
+		}
+	}
+	if err := db.AddAccessCounts([]model.AccessCount{{Path: "/a", Downloads: 3, LastAccess: now}}); err != nil {
+		t.Fatalf("failed add access counts: %+v", err)
+	}
+	admin, err := db.GetAdmin()
+	if err != nil {
+		t.Fatalf("failed get admin: %+v", err)
+	}
+
+	// sent manually, the period goes on
+	if err := Send(admin, PeriodStart(admin.Digest, now), now); err != nil {
+		t.Fatalf("failed send digest: %+v", err)
+	}
+	if !lastSent().IsZero() {
+		t.Errorf("expected the digest sent manually isn't recorded")
+	}
+	Run(now.Add(-time.Hour))
+	Run(now)
+	Run(now.Add(time.Minute))
+	if len(bodies) != 2 {
+		t.Fatalf("expected the manual one and the scheduled one, got %d", len(bodies))
+	}
+	for _, body := range bodies {
+		if !strings.Contains(body, "new_user") || strings.Contains(body, "old_user") {
+			t.Errorf("expected only the users created in the period:\n%s", body)
+		}
+		if !strings.Contains(body, "- /a: 3 downloads") {
+			t.Errorf("expected the downloads of the period:\n%s", body)
+		}
+	}
+	if sent := lastSent(); !sent.Equal(now) {
+		t.Errorf("expected the digest sent at %s, got %s", now, sent)
+	}
+
+	// the next period starts from the last digest
+	next := now.AddDate(0, 0, 7)
+	if start := PeriodStart(admin.Digest, next); !start.Equal(now) {
+		t.Errorf("expected the period starts at %s, got %s", now, start)
+	}
+	body, err := Build(PeriodStart(admin.Digest, next), next)
+	if err != nil {
+		t.Fatalf("failed build digest: %+v", err)
+	}
+	if !strings.Contains(body, "no downloads") || !strings.Contains(body, "no new user") {
+		t.Errorf("expected nothing in the next period:\n%s", body)
+	}
+}
@@ -0,0 +1,39 @@
+package driver
+
+// Capabilities the operations supported by a driver, the fs layer falls back or
+// returns errs.NotSupport for the ones not supported instead of calling the driver
+type Capabilities struct {
+	MakeDir bool `json:"make_dir"`
+	Move    bool `json:"move"`
+	Rename  bool `json:"rename"`
+	// Copy the server side copy
+	Copy bool `json:"copy"`
+	// CopyDir the server side copy of folders, not only the files
+	CopyDir bool `json:"copy_dir"`
+	Remove  bool `json:"remove"`
+	Put     bool `json:"put"`
+	Append  bool `json:"append"`
+	Patch   bool `json:"patch"`
+	// Range the link of the driver honors the range requests
+	Range bool `json:"range"`
+}
+
+// AllCapabilities the drivers not implementing Capable are supposed to support,
+// Append and Patch are told by the interfaces
+func AllCapabilities() Capabilities {
+	return Capabilities{
+		MakeDir: true,
+		Move:    true,
+		Rename:  true,
+		Copy:    true,
+		CopyDir: true,
+		Remove:  true,
+		Put:     true,
+		Range:   true,
+	}
+}
+
+// Capable is implemented by drivers which don't support all the operations
+type Capable interface {
+	Capabilities() Capabilities
+}
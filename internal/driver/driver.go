@@ -2,6 +2,7 @@ package driver
 
 import (
 	"context"
+	"time"
 
 	"github.com/alist-org/alist/v3/internal/model"
 )
@@ -21,6 +22,9 @@ type Meta interface {
 	Drop(ctx context.Context) error
 	// GetStorage just get raw storage
 	GetStorage() model.Storage
+	// SetStorage update raw storage in memory without init,
+	// only used for the fields that the driver doesn't care, such as Index
+	SetStorage(model.Storage)
 	GetAddition() Additional
 }
 
@@ -41,6 +45,43 @@ type Getter interface {
 	Get(ctx context.Context, path string) (model.Obj, error)
 }
 
+// CredentialExpirer is implemented by drivers whose cookie/token will expire
+// and can't be refreshed automatically, so the admin should re-auth before then
+type CredentialExpirer interface {
+	// CredentialExpiration return the zero time if unknown
+	CredentialExpiration() time.Time
+}
+
+// Alias is implemented by drivers mounting another virtual path
+type Alias interface {
+	// GetAliasPath return the virtual path mounted, which is the `path` of the addition
+	GetAliasPath() string
+}
+
+// About is implemented by drivers which can report the space of the storage
+type About interface {
+	About(ctx context.Context) (*model.StorageUsage, error)
+}
+
+// Append is implemented by drivers which can append bytes to the end of an existing file,
+// the others are emulated by reading the file and putting it again
+type Append interface {
+	Append(ctx context.Context, file model.Obj, stream model.FileStreamer, up UpdateProgress) error
+}
+
+// Patch is implemented by drivers which can overwrite a range of an existing file in place,
+// the file is extended if the range is beyond the end
+type Patch interface {
+	Patch(ctx context.Context, file model.Obj, offset int64, stream model.FileStreamer, up UpdateProgress) error
+}
+
+// Enumerator is implemented by drivers which can list the values of an addition field
+// before the storage is created, such as the shares of a server
+type Enumerator interface {
+	// Enumerate the values of the field, the storage is not initialized
+	Enumerate(ctx context.Context, storage model.Storage, field string) ([]string, error)
+}
+
 type Writer interface {
 	// MakeDir make a folder named `dirName` in `parentDir`
 	MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error
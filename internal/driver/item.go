@@ -2,6 +2,12 @@ package driver
 
 type Additional interface{}
 
+// DynamicAddition is implemented by the additions whose fields are only known at runtime,
+// such as the ones of the plugins, the items are not reflected from the struct
+type DynamicAddition interface {
+	Items() []Item
+}
+
 type Select string
 
 type Item struct {
@@ -11,8 +17,14 @@ type Item struct {
 	Values   string `json:"values"`
 	Required bool   `json:"required"`
 	Help     string `json:"help"`
+	// the values can be listed by the driver with the other fields filled
+	Enumerable bool `json:"enumerable"`
+	// the format of the value validated before init, such as url
+	Format string `json:"format,omitempty"`
 }
 
+const FormatURL = "url"
+
 type Items struct {
 	Common     []Item `json:"common"`
 	Additional []Item `json:"additional"`
@@ -0,0 +1,24 @@
+package errs
+
+import (
+	"errors"
+
+	pkgerr "github.com/pkg/errors"
+)
+
+// the errors of the account rather than the request, drivers wrap them
+// so the storage can switch to its backup credential
+var (
+	QuotaExceeded    = errors.New("quota of the account exceeded")
+	AccountSuspended = errors.New("the account is suspended")
+)
+
+// IsAccountUnavailable judge whether the account can't be used until the quota is reset or it's restored
+func IsAccountUnavailable(err error) bool {
+	cause := pkgerr.Cause(err)
+	return errors.Is(cause, QuotaExceeded) || errors.Is(cause, AccountSuspended)
+}
+
+// RefreshTokenInvalid drivers wrap it when the provider rejects the refresh token,
+// the storage can't work until it's authorized again
+var RefreshTokenInvalid = errors.New("the refresh token is expired or revoked")
@@ -3,5 +3,10 @@ package errs
 import "errors"
 
 var (
-	PermissionDenied = errors.New("permission denied")
+	PermissionDenied  = errors.New("permission denied")
+	OperationDisabled = errors.New("this operation is disabled for the storage")
+	StorageReadOnly   = errors.New("the storage is read-only")
+	InstanceReadOnly  = errors.New("the site is in read-only mode")
+	NoSpaceLeft       = errors.New("no space left for the upload")
+	PathOnHold        = errors.New("the path is on hold")
 )
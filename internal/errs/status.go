@@ -0,0 +1,31 @@
+package errs
+
+import (
+	"net/http"
+
+	pkgerr "github.com/pkg/errors"
+)
+
+// StatusError is the unexpected status of a response from the provider,
+// the drivers wrap it so the callers can tell the failure by the code
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
+func NewStatusError(res *http.Response) error {
+	return &StatusError{Code: res.StatusCode, Status: res.Status}
+}
+
+// StatusCode return the status of the response the err is caused by, 0 if none
+func StatusCode(err error) int {
+	var statusErr *StatusError
+	if pkgerr.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	return 0
+}
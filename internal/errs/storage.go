@@ -0,0 +1,23 @@
+package errs
+
+import (
+	"errors"
+
+	pkgerr "github.com/pkg/errors"
+)
+
+var (
+	InvalidMountPath  = errors.New("invalid mount path")
+	MountPathConflict = errors.New("mount path is used by another storage")
+	MountPathIsFile   = errors.New("mount path is a file in another storage")
+	AliasLoop         = errors.New("aliases form a loop")
+	DependencyLoop    = errors.New("storage dependencies form a loop")
+)
+
+// IsMountPathError judge whether the error is caused by a bad mount path given by the user,
+// including the ones depended on
+func IsMountPathError(err error) bool {
+	cause := pkgerr.Cause(err)
+	return errors.Is(cause, InvalidMountPath) || errors.Is(cause, MountPathConflict) || errors.Is(cause, MountPathIsFile) ||
+		errors.Is(cause, DependencyLoop)
+}
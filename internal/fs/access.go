@@ -0,0 +1,62 @@
+package fs
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AccessList     = "list"
+	AccessDownload = "download"
+)
+
+var (
+	accessCounts   = map[string]*model.AccessCount{}
+	accessCountsMu sync.Mutex
+)
+
+// CountAccess count a listing or downloading of the path in memory,
+// only 1 of every N accesses is counted as N, and they are saved by FlushAccessCounts
+func CountAccess(path, kind string) {
+	n := conf.Conf.AccessSampling
+	if n <= 0 || (n > 1 && rand.Intn(n) != 0) {
+		return
+	}
+	accessCountsMu.Lock()
+	defer accessCountsMu.Unlock()
+	count, ok := accessCounts[path]
+	if !ok {
+		count = &model.AccessCount{Path: path}
+		accessCounts[path] = count
+	}
+	switch kind {
+	case AccessList:
+		count.Lists += int64(n)
+	case AccessDownload:
+		count.Downloads += int64(n)
+	}
+	count.LastAccess = time.Now()
+}
+
+// FlushAccessCounts save the counts in memory to the database in a batch
+func FlushAccessCounts() {
+	accessCountsMu.Lock()
+	counts := make([]model.AccessCount, 0, len(accessCounts))
+	for _, count := range accessCounts {
+		counts = append(counts, *count)
+	}
+	accessCounts = map[string]*model.AccessCount{}
+	accessCountsMu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	if err := db.AddAccessCounts(counts); err != nil {
+		log.Errorf("failed save %d access counts: %+v", len(counts), err)
+	}
+}
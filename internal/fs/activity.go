@@ -0,0 +1,27 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alist-org/alist/v3/internal/activity"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// userID return the id of the user requesting to record the activities, 0 for the guest
+// and the jobs without a user, whose activities are not recorded
+func userID(ctx context.Context) uint {
+	if user, ok := ctx.Value("user").(*model.User); ok && !user.IsGuest() {
+		return user.ID
+	}
+	return 0
+}
+
+// recordTaskFailed record the failed task into the activities of the user, the canceled one is not
+func recordTaskFailed(ctx context.Context, userId uint, name, path string, err error) {
+	if err == nil || utils.IsCanceled(ctx) {
+		return
+	}
+	activity.Record(userId, model.ActivityTaskFailed, fmt.Sprintf("%s failed: %s", name, err), path)
+}
@@ -0,0 +1,155 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// pathLocks serialize the writes to the same file, the emulated ones read the old content
+// and put it back, the concurrent ones would lose the writes of each other
+type pathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+type pathLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock the path, the returned func unlocks it
+func (l *pathLocks) lock(path string) func() {
+	l.mu.Lock()
+	pl, ok := l.locks[path]
+	if !ok {
+		pl = &pathLock{}
+		l.locks[path] = pl
+	}
+	pl.refs++
+	l.mu.Unlock()
+	pl.Lock()
+	return func() {
+		pl.Unlock()
+		l.mu.Lock()
+		pl.refs--
+		if pl.refs == 0 {
+			delete(l.locks, path)
+		}
+		l.mu.Unlock()
+	}
+}
+
+var writeLocks = pathLocks{locks: map[string]*pathLock{}}
+
+// appendFile append the stream to the end of the file, the storages can't append
+// are emulated by putting the old content followed by the new one, so the size is limited
+func appendFile(ctx context.Context, path string, stream model.FileStreamer) error {
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, path)
+	if err != nil {
+		_ = stream.Close()
+		return errors.WithMessage(err, "failed get storage")
+	}
+	defer writeLocks.lock(path)()
+	if _, ok := storage.(driver.Append); ok {
+		err = operations.Append(ctx, storage, actualPath, stream, nil)
+	} else {
+		var merged model.FileStreamer
+		merged, err = rewriteStream(ctx, storage, actualPath, -1, stream)
+		if err != nil {
+			_ = stream.Close()
+			return err
+		}
+		err = operations.Put(ctx, storage, stdpath.Dir(actualPath), merged, nil)
+	}
+	if err != nil {
+		return err
+	}
+	recordWrite(ctx, storage, actualPath, path)
+	return nil
+}
+
+// recordWrite record the change of the file written in place, with the size it has now
+func recordWrite(ctx context.Context, storage driver.Driver, actualPath, path string) {
+	var size int64
+	if obj, err := operations.Get(ctx, storage, actualPath); err == nil {
+		size = obj.GetSize()
+	}
+	recordChange(path, model.ChangeModify, false, size)
+}
+
+// rewriteStream read the old content of the file into memory, and return the stream of it with the new one
+// written at offset, -1 means the end. the old content must be read before the file is overwritten by the put
+func rewriteStream(ctx context.Context, storage driver.Driver, actualPath string, offset int64, stream model.FileStreamer) (model.FileStreamer, error) {
+	if storage.Config().NoUpload {
+		return nil, errors.WithStack(errs.UploadNotSupported)
+	}
+	if err := operations.CheckOperation(storage, model.OpPut); err != nil {
+		return nil, err
+	}
+	file, err := operations.Get(ctx, storage, actualPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get file")
+	}
+	if file.IsDir() {
+		return nil, errors.WithStack(errs.NotFile)
+	}
+	if offset < 0 {
+		offset = file.GetSize()
+	}
+	if offset > file.GetSize() {
+		return nil, errors.Errorf("offset %d is beyond the end of the file", offset)
+	}
+	size := file.GetSize()
+	if end := offset + stream.GetSize(); end > size {
+		size = end
+	}
+	maxSize := int64(setting.GetIntSetting(conf.AppendMaxSize, 16)) * 1024 * 1024
+	if size > maxSize {
+		return nil, errors.Errorf("the storage can't write in place, and the file would be larger than %d MB to rewrite", maxSize/1024/1024)
+	}
+	link, _, err := operations.Link(ctx, storage, actualPath, model.LinkArgs{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get link")
+	}
+	old, err := getFileStreamFromLink(file, link)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get stream")
+	}
+	data, err := io.ReadAll(io.LimitReader(old, maxSize+1))
+	_ = old.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed read file")
+	}
+	if int64(len(data)) < offset {
+		return nil, errors.Errorf("the file is shorter than the offset %d", offset)
+	}
+	var tail []byte
+	if end := offset + stream.GetSize(); end < int64(len(data)) {
+		tail = data[end:]
+	}
+	return &model.FileStream{
+		Obj: model.Object{
+			Name:     file.GetName(),
+			Size:     offset + stream.GetSize() + int64(len(tail)),
+			Modified: time.Now(),
+		},
+		ReadCloser: utils.ReadCloser{
+			Reader: io.MultiReader(bytes.NewReader(data[:offset]), io.LimitReader(stream, stream.GetSize()), bytes.NewReader(tail)),
+			Closer: stream,
+		},
+		Mimetype: old.GetMimetype(),
+	}, nil
+}
@@ -0,0 +1,36 @@
+package fs
+
+import (
+	"context"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	log "github.com/sirupsen/logrus"
+)
+
+// recordChange save the change of the virtual path, failures are only logged
+// because the operation itself has succeeded
+func recordChange(path, action string, isDir bool, size int64) {
+	err := db.CreateChange(&model.Change{
+		Path:   path,
+		Action: action,
+		IsDir:  isDir,
+		Size:   size,
+		Time:   time.Now(),
+	})
+	if err != nil {
+		log.Errorf("failed record change of %s: %+v", path, err)
+	}
+}
+
+// putAction get the action of the change of the file to put at the actual path,
+// it should be called before putting, as the file overwritten is modified, not created
+func putAction(ctx context.Context, storage driver.Driver, actualPath string) string {
+	if _, err := operations.Get(ctx, storage, actualPath); err == nil {
+		return model.ChangeModify
+	}
+	return model.ChangeCreate
+}
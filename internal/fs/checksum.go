@@ -0,0 +1,300 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	stdpath "path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	"github.com/alist-org/alist/v3/pkg/task"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+var ChecksumTaskManager = task.NewTaskManager(1, func(tid *uint64) {
+	atomic.AddUint64(tid, 1)
+})
+
+// ChecksumReports the per file results of verify tasks, keyed by task id
+var ChecksumReports generic_sync.MapOf[uint64, []ChecksumResult]
+
+type ChecksumResult struct {
+	Path     string `json:"path"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+}
+
+type checksumFormat struct {
+	manifest string
+	// algo of the hashes saved by operations, empty if they can't be used
+	algo    string
+	newHash func() hash.Hash
+	// format a line of the manifest
+	format func(sum, path string) string
+	// parse a line of the manifest, return false if the line should be ignored
+	parse func(line string) (sum, path string, ok bool)
+}
+
+func parseSumLine(line string) (string, string, bool) {
+	// <sum>  <path> or <sum> *<path> in binary mode
+	i := strings.Index(line, " ")
+	if i <= 0 || len(line) < i+2 {
+		return "", "", false
+	}
+	return line[:i], strings.TrimPrefix(line[i+1:], "*"), true
+}
+
+var checksumFormats = map[string]checksumFormat{
+	"sha256": {
+		manifest: "SHA256SUMS",
+		algo:     model.HashSHA256,
+		newHash:  sha256.New,
+		format: func(sum, path string) string {
+			return fmt.Sprintf("%s  %s\n", sum, path)
+		},
+		parse: parseSumLine,
+	},
+	"md5": {
+		manifest: "MD5SUMS",
+		algo:     model.HashMD5,
+		newHash:  md5.New,
+		format: func(sum, path string) string {
+			return fmt.Sprintf("%s  %s\n", sum, path)
+		},
+		parse: parseSumLine,
+	},
+	"sfv": {
+		manifest: "checksum.sfv",
+		newHash: func() hash.Hash {
+			return crc32.NewIEEE()
+		},
+		format: func(sum, path string) string {
+			return fmt.Sprintf("%s %s\n", path, strings.ToUpper(sum))
+		},
+		parse: func(line string) (string, string, bool) {
+			if strings.HasPrefix(line, ";") {
+				return "", "", false
+			}
+			i := strings.LastIndex(line, " ")
+			if i <= 0 {
+				return "", "", false
+			}
+			return line[i+1:], line[:i], true
+		},
+	},
+}
+
+// GenerateChecksum add a task to write the checksum manifest of all files under the dir into the dir
+func GenerateChecksum(dirPath, algo string) (uint64, error) {
+	format, ok := checksumFormats[algo]
+	if !ok {
+		return 0, errors.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+	storage, dirActualPath, err := operations.GetStorageAndActualPath(context.Background(), dirPath)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed get storage")
+	}
+	if err := operations.CheckOperation(storage, model.OpPut); err != nil {
+		return 0, err
+	}
+	tid := ChecksumTaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
+		Name: fmt.Sprintf("generate %s of [%s](%s)", format.manifest, storage.GetStorage().MountPath, dirActualPath),
+		Func: func(t *task.Task[uint64]) error {
+			return generateChecksum(t, storage, dirActualPath, format)
+		},
+	}))
+	return tid, nil
+}
+
+func generateChecksum(t *task.Task[uint64], storage driver.Driver, dirPath string, format checksumFormat) error {
+	t.SetStatus("listing files")
+	files, err := listFilesRecursive(t.Ctx, storage, dirPath, "")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for i, file := range files {
+		if utils.IsCanceled(t.Ctx) {
+			return nil
+		}
+		// don't include the old manifest itself
+		if file == format.manifest {
+			continue
+		}
+		t.SetStatus("hashing " + file)
+		sum, err := hashFile(t.Ctx, storage, stdpath.Join(dirPath, file), format)
+		if err != nil {
+			return errors.WithMessagef(err, "failed hash [%s]", file)
+		}
+		buf.WriteString(format.format(sum, file))
+		t.SetProgress((i + 1) * 100 / len(files))
+	}
+	t.SetStatus("writing " + format.manifest)
+	stream := &model.FileStream{
+		Obj: model.Object{
+			Name:     format.manifest,
+			Size:     int64(buf.Len()),
+			Modified: time.Now(),
+		},
+		ReadCloser: ioutil.NopCloser(&buf),
+		Mimetype:   "text/plain",
+	}
+	return operations.Put(t.Ctx, storage, dirPath, stream, nil)
+}
+
+// VerifyChecksum add a task to verify the files under the dir of the manifest,
+// the format is decided by the name of the manifest
+func VerifyChecksum(manifestPath string) (uint64, error) {
+	var format checksumFormat
+	var ok bool
+	for _, f := range checksumFormats {
+		if f.manifest == stdpath.Base(manifestPath) {
+			format, ok = f, true
+			break
+		}
+	}
+	if !ok {
+		return 0, errors.Errorf("unknown checksum manifest: %s", stdpath.Base(manifestPath))
+	}
+	storage, manifestActualPath, err := operations.GetStorageAndActualPath(context.Background(), manifestPath)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed get storage")
+	}
+	tid := ChecksumTaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
+		Name: fmt.Sprintf("verify [%s](%s)", storage.GetStorage().MountPath, manifestActualPath),
+		Func: func(t *task.Task[uint64]) error {
+			return verifyChecksum(t, storage, manifestActualPath, format)
+		},
+	}))
+	return tid, nil
+}
+
+func verifyChecksum(t *task.Task[uint64], storage driver.Driver, manifestPath string, format checksumFormat) error {
+	t.SetStatus("reading manifest")
+	rc, err := openFile(t.Ctx, storage, manifestPath)
+	if err != nil {
+		return err
+	}
+	type entry struct{ sum, path string }
+	var entries []entry
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if sum, path, ok := format.parse(line); ok {
+			entries = append(entries, entry{sum: strings.ToLower(sum), path: path})
+		}
+	}
+	_ = rc.Close()
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed read manifest")
+	}
+	dirPath := stdpath.Dir(manifestPath)
+	results := make([]ChecksumResult, 0, len(entries))
+	failed := 0
+	for i, e := range entries {
+		if utils.IsCanceled(t.Ctx) {
+			break
+		}
+		t.SetStatus("verifying " + e.path)
+		res := ChecksumResult{Path: e.path, Expected: e.sum}
+		var path string
+		path, err = manifestEntryPath(dirPath, e.path)
+		if err == nil {
+			res.Actual, err = hashFile(t.Ctx, storage, path, format)
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		res.Passed = err == nil && res.Actual == res.Expected
+		if !res.Passed {
+			failed++
+		}
+		results = append(results, res)
+		ChecksumReports.Store(t.ID, results)
+		t.SetProgress((i + 1) * 100 / len(entries))
+	}
+	t.SetStatus(fmt.Sprintf("%d passed, %d failed", len(results)-failed, failed))
+	if failed > 0 {
+		return errors.Errorf("%d files failed verification", failed)
+	}
+	return nil
+}
+
+// manifestEntryPath get the path of the file listed in the manifest, which must be relative
+// to the dir of the manifest and can't reach out of it
+func manifestEntryPath(dirPath, path string) (string, error) {
+	cleaned := stdpath.Clean(path)
+	if stdpath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errors.Errorf("the path is out of the dir of the manifest")
+	}
+	return stdpath.Join(dirPath, cleaned), nil
+}
+
+// listFilesRecursive return the paths of all files under the dir relative to the root
+func listFilesRecursive(ctx context.Context, storage driver.Driver, dirPath, rel string) ([]string, error) {
+	objs, err := operations.List(ctx, storage, stdpath.Join(dirPath, rel))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed list [%s]", rel)
+	}
+	var files []string
+	for _, obj := range objs {
+		p := stdpath.Join(rel, obj.GetName())
+		if !obj.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		sub, err := listFilesRecursive(ctx, storage, dirPath, p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+func openFile(ctx context.Context, storage driver.Driver, path string) (model.FileStreamer, error) {
+	link, file, err := operations.Link(ctx, storage, path, model.LinkArgs{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed get [%s] link", path)
+	}
+	stream, err := getFileStreamFromLink(file, link)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed get [%s] stream", path)
+	}
+	return stream, nil
+}
+
+// hashFile use the known hash of the file if any, otherwise download and hash it
+func hashFile(ctx context.Context, storage driver.Driver, path string, format checksumFormat) (string, error) {
+	if format.algo != "" {
+		if sum, err := operations.GetHash(ctx, storage, path, format.algo); err == nil && sum != "" {
+			return sum, nil
+		}
+	}
+	h := format.newHash()
+	rc, err := openFile(ctx, storage, path)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	if err := utils.CopyWithCtx(ctx, h, rc); err != nil {
+		return "", errors.Wrapf(err, "failed read [%s]", path)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/internal/supervisor"
 	"github.com/pkg/errors"
 )
 
@@ -22,29 +23,58 @@ var CopyTaskManager = task.NewTaskManager(3, func(tid *uint64) {
 // Copy if in the same storage, call move method
 // if not, add copy task
 func _copy(ctx context.Context, srcObjPath, dstDirPath string) (bool, error) {
-	srcStorage, srcObjActualPath, err := operations.GetStorageAndActualPath(srcObjPath)
+	srcStorage, srcObjActualPath, err := operations.GetStorageAndActualPath(ctx, srcObjPath)
 	if err != nil {
 		return false, errors.WithMessage(err, "failed get src storage")
 	}
-	dstStorage, dstDirActualPath, err := operations.GetStorageAndActualPath(dstDirPath)
+	dstStorage, dstDirActualPath, err := operations.GetStorageAndActualPath(ctx, dstDirPath)
 	if err != nil {
 		return false, errors.WithMessage(err, "failed get dst storage")
 	}
-	// copy if in the same storage, just call driver.Copy
+	// copy if in the same storage and the driver can, just call driver.Copy
 	if srcStorage.GetStorage() == dstStorage.GetStorage() {
-		return false, operations.Copy(ctx, srcStorage, srcObjActualPath, dstDirActualPath)
+		srcObj, err := operations.Get(ctx, srcStorage, srcObjActualPath)
+		if err != nil {
+			return false, errors.WithMessage(err, "failed get src object")
+		}
+		if !operations.CanCopy(srcStorage, srcObj) {
+			// download and upload again in a task like between two storages
+			return submitCopy(ctx, srcStorage, dstStorage, srcObjActualPath, dstDirActualPath)
+		}
+		// the folders copied are merged, so only the files can be overwritten
+		action := model.ChangeCreate
+		if !srcObj.IsDir() {
+			action = putAction(ctx, dstStorage, stdpath.Join(dstDirActualPath, srcObj.GetName()))
+		}
+		if err := operations.Copy(ctx, srcStorage, srcObjActualPath, dstDirActualPath); err != nil {
+			return false, err
+		}
+		recordChange(stdpath.Join(dstDirPath, srcObj.GetName()), action, srcObj.IsDir(), srcObj.GetSize())
+		return false, nil
 	}
-	// not in the same storage
-	CopyTaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
+	return submitCopy(ctx, srcStorage, dstStorage, srcObjActualPath, dstDirActualPath)
+}
+
+// submitCopy add the copy task, only the copy requested counts in the budget, not the sub tasks
+func submitCopy(ctx context.Context, srcStorage, dstStorage driver.Driver, srcObjActualPath, dstDirActualPath string) (bool, error) {
+	release, err := supervisor.Acquire(supervisor.Tasks, 1)
+	if err != nil {
+		return false, err
+	}
+	submitWithBudget(CopyTaskManager, &task.Task[uint64]{
 		Name: fmt.Sprintf("copy [%s](%s) to [%s](%s)", srcStorage.GetStorage().MountPath, srcObjActualPath, dstStorage.GetStorage().MountPath, dstDirActualPath),
 		Func: func(task *task.Task[uint64]) error {
-			return copyBetween2Storages(task, srcStorage, dstStorage, srcObjActualPath, dstDirActualPath)
+			return copyBetween2Storages(task, userID(ctx), srcStorage, dstStorage, srcObjActualPath, dstDirActualPath)
 		},
-	}))
+	}, release)
 	return true, nil
 }
 
-func copyBetween2Storages(t *task.Task[uint64], srcStorage, dstStorage driver.Driver, srcObjPath, dstDirPath string) error {
+// copyBetween2Storages the failures are recorded into the activities of the user of uid
+func copyBetween2Storages(t *task.Task[uint64], uid uint, srcStorage, dstStorage driver.Driver, srcObjPath, dstDirPath string) (err error) {
+	defer func() {
+		recordTaskFailed(t.Ctx, uid, "copy of "+srcObjPath, stdpath.Join(srcStorage.GetStorage().MountPath, srcObjPath), err)
+	}()
 	t.SetStatus("getting src object")
 	srcObj, err := operations.Get(t.Ctx, srcStorage, srcObjPath)
 	if err != nil {
@@ -65,7 +95,7 @@ func copyBetween2Storages(t *task.Task[uint64], srcStorage, dstStorage driver.Dr
 			CopyTaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
 				Name: fmt.Sprintf("copy [%s](%s) to [%s](%s)", srcStorage.GetStorage().MountPath, srcObjPath, dstStorage.GetStorage().MountPath, dstObjPath),
 				Func: func(t *task.Task[uint64]) error {
-					return copyBetween2Storages(t, srcStorage, dstStorage, srcObjPath, dstObjPath)
+					return copyBetween2Storages(t, uid, srcStorage, dstStorage, srcObjPath, dstObjPath)
 				},
 			}))
 		}
@@ -73,7 +103,9 @@ func copyBetween2Storages(t *task.Task[uint64], srcStorage, dstStorage driver.Dr
 		CopyTaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
 			Name: fmt.Sprintf("copy [%s](%s) to [%s](%s)", srcStorage.GetStorage().MountPath, srcObjPath, dstStorage.GetStorage().MountPath, dstDirPath),
 			Func: func(t *task.Task[uint64]) error {
-				return copyFileBetween2Storages(t, srcStorage, dstStorage, srcObjPath, dstDirPath)
+				err := copyFileBetween2Storages(t, srcStorage, dstStorage, srcObjPath, dstDirPath)
+				recordTaskFailed(t.Ctx, uid, "copy of "+srcObjPath, stdpath.Join(srcStorage.GetStorage().MountPath, srcObjPath), err)
+				return err
 			},
 		}))
 	}
@@ -93,5 +125,10 @@ func copyFileBetween2Storages(tsk *task.Task[uint64], srcStorage, dstStorage dri
 	if err != nil {
 		return errors.WithMessagef(err, "failed get [%s] stream", srcFilePath)
 	}
-	return operations.Put(tsk.Ctx, dstStorage, dstDirPath, stream, tsk.SetProgress)
+	action := putAction(tsk.Ctx, dstStorage, stdpath.Join(dstDirPath, srcFile.GetName()))
+	if err := operations.Put(tsk.Ctx, dstStorage, dstDirPath, stream, tsk.SetProgress); err != nil {
+		return err
+	}
+	recordChange(operations.VirtualPath(dstStorage, stdpath.Join(dstDirPath, srcFile.GetName())), action, false, srcFile.GetSize())
+	return nil
 }
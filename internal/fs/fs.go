@@ -5,6 +5,7 @@ import (
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -12,10 +13,10 @@ import (
 // So, the purpose of this package is to convert virtual path to actual path
 // then pass the actual path to the operations package
 
-func List(ctx context.Context, path string) ([]model.Obj, error) {
-	res, err := list(ctx, path)
+func List(ctx context.Context, path string, refresh ...bool) ([]model.Obj, error) {
+	res, err := list(ctx, path, refresh...)
 	if err != nil {
-		log.Errorf("failed list %s: %+v", path, err)
+		utils.Log(ctx).Errorf("failed list %s: %+v", path, err)
 		return nil, err
 	}
 	return res, nil
@@ -24,7 +25,7 @@ func List(ctx context.Context, path string) ([]model.Obj, error) {
 func Get(ctx context.Context, path string) (model.Obj, error) {
 	res, err := get(ctx, path)
 	if err != nil {
-		log.Errorf("failed get %s: %+v", path, err)
+		utils.Log(ctx).Errorf("failed get %s: %+v", path, err)
 		return nil, err
 	}
 	return res, nil
@@ -33,7 +34,7 @@ func Get(ctx context.Context, path string) (model.Obj, error) {
 func Link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
 	res, file, err := link(ctx, path, args)
 	if err != nil {
-		log.Errorf("failed link %s: %+v", path, err)
+		utils.Log(ctx).Errorf("failed link %s: %+v", path, err)
 		return nil, nil, err
 	}
 	return res, file, nil
@@ -42,7 +43,7 @@ func Link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, m
 func MakeDir(ctx context.Context, path string) error {
 	err := makeDir(ctx, path)
 	if err != nil {
-		log.Errorf("failed make dir %s: %+v", path, err)
+		utils.Log(ctx).Errorf("failed make dir %s: %+v", path, err)
 	}
 	return err
 }
@@ -50,7 +51,7 @@ func MakeDir(ctx context.Context, path string) error {
 func Move(ctx context.Context, srcPath, dstDirPath string) error {
 	err := move(ctx, srcPath, dstDirPath)
 	if err != nil {
-		log.Errorf("failed move %s to %s: %+v", srcPath, dstDirPath, err)
+		utils.Log(ctx).Errorf("failed move %s to %s: %+v", srcPath, dstDirPath, err)
 	}
 	return err
 }
@@ -58,7 +59,7 @@ func Move(ctx context.Context, srcPath, dstDirPath string) error {
 func Copy(ctx context.Context, srcObjPath, dstDirPath string) (bool, error) {
 	res, err := _copy(ctx, srcObjPath, dstDirPath)
 	if err != nil {
-		log.Errorf("failed copy %s to %s: %+v", srcObjPath, dstDirPath, err)
+		utils.Log(ctx).Errorf("failed copy %s to %s: %+v", srcObjPath, dstDirPath, err)
 	}
 	return res, err
 }
@@ -66,7 +67,7 @@ func Copy(ctx context.Context, srcObjPath, dstDirPath string) (bool, error) {
 func Rename(ctx context.Context, srcPath, dstName string) error {
 	err := rename(ctx, srcPath, dstName)
 	if err != nil {
-		log.Errorf("failed rename %s to %s: %+v", srcPath, dstName, err)
+		utils.Log(ctx).Errorf("failed rename %s to %s: %+v", srcPath, dstName, err)
 	}
 	return err
 }
@@ -74,7 +75,7 @@ func Rename(ctx context.Context, srcPath, dstName string) error {
 func Remove(ctx context.Context, path string) error {
 	err := remove(ctx, path)
 	if err != nil {
-		log.Errorf("failed remove %s: %+v", path, err)
+		utils.Log(ctx).Errorf("failed remove %s: %+v", path, err)
 	}
 	return err
 }
@@ -82,21 +83,38 @@ func Remove(ctx context.Context, path string) error {
 func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer) error {
 	err := putDirectly(ctx, dstDirPath, file)
 	if err != nil {
-		log.Errorf("failed put %s: %+v", dstDirPath, err)
+		utils.Log(ctx).Errorf("failed put %s: %+v", dstDirPath, err)
 	}
 	return err
 }
 
-func PutAsTask(dstDirPath string, file model.FileStreamer) error {
-	err := putAsTask(dstDirPath, file)
+// PutAsTask the user in ctx is notified when the task ends
+func PutAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer) error {
+	err := putAsTask(ctx, dstDirPath, file)
 	if err != nil {
 		log.Errorf("failed put %s: %+v", dstDirPath, err)
 	}
 	return err
 }
 
+func Append(ctx context.Context, path string, stream model.FileStreamer) error {
+	err := appendFile(ctx, path, stream)
+	if err != nil {
+		utils.Log(ctx).Errorf("failed append %s: %+v", path, err)
+	}
+	return err
+}
+
+func Patch(ctx context.Context, path string, offset int64, stream model.FileStreamer) error {
+	err := patchFile(ctx, path, offset, stream)
+	if err != nil {
+		utils.Log(ctx).Errorf("failed patch %s: %+v", path, err)
+	}
+	return err
+}
+
 func GetStorage(path string) (driver.Driver, error) {
-	storageDriver, _, err := operations.GetStorageAndActualPath(path)
+	storageDriver, _, err := operations.GetStorageAndActualPath(context.Background(), path)
 	if err != nil {
 		return nil, err
 	}
@@ -21,7 +21,7 @@ func get(ctx context.Context, path string) (model.Obj, error) {
 			}
 		}
 	}
-	storage, actualPath, err := operations.GetStorageAndActualPath(path)
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, path)
 	if err != nil {
 		// if there are no storage prefix with path, maybe root folder
 		if path == "/" {
@@ -0,0 +1,21 @@
+package fs
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+// KnownHashes return the hashes of the files in the dir known without downloading them
+func KnownHashes(dirPath string, objs []model.Obj) []map[string]string {
+	// the objs of a union come from several storages, only the reported hashes are known
+	if len(operations.GetUnionMembers(dirPath)) > 0 {
+		return operations.GetKnownHashes(nil, "", objs)
+	}
+	storage, actualPath, err := operations.GetStorageAndActualPath(context.Background(), dirPath)
+	if err != nil {
+		return make([]map[string]string, len(objs))
+	}
+	return operations.GetKnownHashes(storage, actualPath, objs)
+}
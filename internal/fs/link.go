@@ -8,7 +8,7 @@ import (
 )
 
 func link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
-	storage, actualPath, err := operations.GetStorageAndActualPath(path)
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, path)
 	if err != nil {
 		return nil, nil, errors.WithMessage(err, "failed get storage")
 	}
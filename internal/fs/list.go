@@ -6,16 +6,15 @@ import (
 	"github.com/alist-org/alist/v3/internal/operations"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 	"regexp"
 	"strings"
 )
 
 // List files
-func list(ctx context.Context, path string) ([]model.Obj, error) {
+func list(ctx context.Context, path string, refresh ...bool) ([]model.Obj, error) {
 	meta := ctx.Value("meta").(*model.Meta)
 	user := ctx.Value("user").(*model.User)
-	storage, actualPath, err := operations.GetStorageAndActualPath(path)
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, path)
 	virtualFiles := operations.GetStorageVirtualFilesByPath(path)
 	if err != nil {
 		if len(virtualFiles) != 0 {
@@ -23,19 +22,20 @@ func list(ctx context.Context, path string) ([]model.Obj, error) {
 		}
 		return nil, errors.WithMessage(err, "failed get storage")
 	}
-	objs, err := operations.List(ctx, storage, actualPath)
+	var objs []model.Obj
+	if members := operations.GetUnionMembers(path); len(members) > 0 {
+		objs, err = operations.ListUnion(ctx, members, path, refresh...)
+	} else {
+		objs, err = operations.List(ctx, storage, actualPath, refresh...)
+	}
 	if err != nil {
-		log.Errorf("%+v", err)
+		utils.Log(ctx).Errorf("%+v", err)
 		if len(virtualFiles) != 0 {
 			return virtualFiles, nil
 		}
 		return nil, errors.WithMessage(err, "failed get objs")
 	}
-	for _, storageFile := range virtualFiles {
-		if !containsByName(objs, storageFile) {
-			objs = append(objs, storageFile)
-		}
-	}
+	objs = operations.CombineVirtualFiles(storage, objs, virtualFiles)
 	if whetherHide(user, meta, path) {
 		objs = hide(objs, meta)
 	}
@@ -90,3 +90,11 @@ func hide(objs []model.Obj, meta *model.Meta) []model.Obj {
 	}
 	return res
 }
+
+// IsHidden check whether the obj named name in the dir is hidden to the user
+func IsHidden(user *model.User, meta *model.Meta, dirPath, name string) bool {
+	if !whetherHide(user, meta, dirPath) {
+		return false
+	}
+	return len(hide([]model.Obj{&model.Object{Name: name}}, meta)) == 0
+}
@@ -0,0 +1,107 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/task"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+var MigrateTaskManager = task.NewTaskManager(1, func(tid *uint64) {
+	atomic.AddUint64(tid, 1)
+})
+
+// Migrate add a task to copy everything under srcPath to dstPath, the files already
+// exist in dst with the same size are skipped, so run it again to resume a failed one
+func Migrate(srcPath, dstPath string) (uint64, error) {
+	srcStorage, srcActualPath, err := operations.GetStorageAndActualPath(context.Background(), srcPath)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed get src storage")
+	}
+	dstStorage, dstActualPath, err := operations.GetStorageAndActualPath(context.Background(), dstPath)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed get dst storage")
+	}
+	if srcStorage.GetStorage().MountPath == dstStorage.GetStorage().MountPath &&
+		utils.IsSubPath(srcActualPath, dstActualPath) {
+		return 0, errors.New("can't migrate a folder into itself")
+	}
+	if err := operations.CheckOperation(dstStorage, model.OpPut); err != nil {
+		return 0, err
+	}
+	tid := MigrateTaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
+		Name: fmt.Sprintf("migrate [%s](%s) to [%s](%s)", srcStorage.GetStorage().MountPath, srcActualPath, dstStorage.GetStorage().MountPath, dstActualPath),
+		Func: func(t *task.Task[uint64]) error {
+			return migrate(t, srcStorage, dstStorage, srcActualPath, dstActualPath)
+		},
+	}))
+	return tid, nil
+}
+
+type migrateEntry struct {
+	rel string
+	obj model.Obj
+}
+
+func migrate(t *task.Task[uint64], srcStorage, dstStorage driver.Driver, srcPath, dstPath string) error {
+	t.SetStatus("enumerating objects")
+	var entries []migrateEntry
+	prefix := strings.TrimSuffix(srcPath, "/") + "/"
+	err := operations.Walk(t.Ctx, srcStorage, srcPath, func(path string, obj model.Obj) error {
+		entries = append(entries, migrateEntry{rel: strings.TrimPrefix(path, prefix), obj: obj})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	copied, skipped := 0, 0
+	for i := range entries {
+		if utils.IsCanceled(t.Ctx) {
+			return nil
+		}
+		e := &entries[i]
+		dst := stdpath.Join(dstPath, e.rel)
+		if e.obj.IsDir() {
+			if err := operations.MakeDir(t.Ctx, dstStorage, dst); err != nil {
+				return errors.WithMessagef(err, "failed make dir [%s]", dst)
+			}
+		} else if obj, err := operations.Get(t.Ctx, dstStorage, dst); err == nil && obj.GetSize() == e.obj.GetSize() {
+			skipped++
+		} else {
+			t.SetStatus("copying " + e.rel)
+			stream, err := openFile(t.Ctx, srcStorage, stdpath.Join(srcPath, e.rel))
+			if err != nil {
+				return err
+			}
+			if err := operations.Put(t.Ctx, dstStorage, stdpath.Dir(dst), stream, nil); err != nil {
+				return errors.WithMessagef(err, "failed copy [%s]", e.rel)
+			}
+			copied++
+		}
+		t.SetProgress((i + 1) * 100 / len(entries))
+	}
+	t.SetStatus("verifying")
+	var failed []string
+	for _, e := range entries {
+		if e.obj.IsDir() {
+			continue
+		}
+		obj, err := operations.Get(t.Ctx, dstStorage, stdpath.Join(dstPath, e.rel))
+		if err != nil || obj.GetSize() != e.obj.GetSize() {
+			failed = append(failed, e.rel)
+		}
+	}
+	t.SetStatus(fmt.Sprintf("%d copied, %d skipped, %d failed verification", copied, skipped, len(failed)))
+	if len(failed) > 0 {
+		return errors.Errorf("failed verification: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package fs
+
+import (
+	"context"
+	stdpath "path"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/pkg/errors"
+)
+
+// patchFile write the stream into the file at offset, so only the changed blocks are uploaded.
+// the storages can't write in place are emulated by rewriting the file only if it's enabled,
+// as it costs a download and an upload of the whole file
+func patchFile(ctx context.Context, path string, offset int64, stream model.FileStreamer) error {
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, path)
+	if err != nil {
+		_ = stream.Close()
+		return errors.WithMessage(err, "failed get storage")
+	}
+	defer writeLocks.lock(path)()
+	if _, ok := storage.(driver.Patch); ok {
+		err = operations.Patch(ctx, storage, actualPath, offset, stream, nil)
+	} else {
+		if !setting.IsTrue(conf.PatchEmulation) {
+			_ = stream.Close()
+			return errors.New("the storage can't write in place, and the emulation is disabled")
+		}
+		var merged model.FileStreamer
+		merged, err = rewriteStream(ctx, storage, actualPath, offset, stream)
+		if err != nil {
+			_ = stream.Close()
+			return err
+		}
+		err = operations.Put(ctx, storage, stdpath.Dir(actualPath), merged, nil)
+	}
+	if err != nil {
+		return err
+	}
+	recordWrite(ctx, storage, actualPath, path)
+	return nil
+}
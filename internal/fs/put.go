@@ -3,12 +3,16 @@ package fs
 import (
 	"context"
 	"fmt"
+	"github.com/alist-org/alist/v3/internal/activity"
 	"github.com/alist-org/alist/v3/internal/errs"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/internal/supervisor"
 	"github.com/alist-org/alist/v3/pkg/task"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
+	stdpath "path"
+	"sync"
 	"sync/atomic"
 )
 
@@ -17,38 +21,116 @@ var UploadTaskManager = task.NewTaskManager[uint64](3, func(tid *uint64) {
 })
 
 // putAsTask add as a put task and return immediately
-func putAsTask(dstDirPath string, file model.FileStreamer) error {
-	storage, dstDirActualPath, err := operations.GetStorageAndActualPath(dstDirPath)
+func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer) error {
+	storage, dstDirActualPath, err := operations.GetPutStorageAndActualPath(ctx, dstDirPath, file.GetName(), file.GetSize())
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
 	if storage.Config().NoUpload {
 		return errors.WithStack(errs.UploadNotSupported)
 	}
+	if err := operations.CheckOperation(storage, model.OpPut); err != nil {
+		return err
+	}
+	release, err := supervisor.Acquire(supervisor.Tasks, 1)
 	if err != nil {
-		return errors.WithMessage(err, "failed get storage")
+		return err
 	}
 	if file.NeedStore() {
+		releaseDisk, err := supervisor.Acquire(supervisor.TempDisk, file.GetSize())
+		if err != nil {
+			release()
+			return err
+		}
 		tempFile, err := utils.CreateTempFile(file)
 		if err != nil {
+			release()
+			releaseDisk()
 			return errors.Wrapf(err, "failed to create temp file")
 		}
 		file.SetReadCloser(tempFile)
+		// the temp file is kept open until the task ends
+		releaseTask, releaseFile := release, supervisor.TrackFile(supervisor.Tasks)
+		release = func() {
+			releaseTask()
+			releaseDisk()
+			releaseFile()
+		}
 	}
-	UploadTaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
+	uid, dstPath := userID(ctx), stdpath.Join(dstDirPath, file.GetName())
+	submitWithBudget(UploadTaskManager, &task.Task[uint64]{
 		Name: fmt.Sprintf("upload %s to [%s](%s)", file.GetName(), storage.GetStorage().MountPath, dstDirActualPath),
 		Func: func(task *task.Task[uint64]) error {
-			return operations.Put(task.Ctx, storage, dstDirActualPath, file, nil)
+			action := putAction(task.Ctx, storage, stdpath.Join(dstDirActualPath, file.GetName()))
+			if err := operations.Put(task.Ctx, storage, dstDirActualPath, file, nil); err != nil {
+				recordTaskFailed(task.Ctx, uid, "upload of "+file.GetName(), dstPath, err)
+				return err
+			}
+			recordChange(dstPath, action, false, file.GetSize())
+			activity.Record(uid, model.ActivityUploadFinished, fmt.Sprintf("upload of %s finished", file.GetName()), dstPath)
+			return nil
 		},
-	}))
+	}, release)
 	return nil
 }
 
+// submitWithBudget submit the task, the resources are released when its func returns.
+// a pending task canceled never runs, so they are released on canceling if it hasn't started,
+// and it's skipped if a worker picks it up after that
+func submitWithBudget(tm *task.Manager[uint64], t *task.Task[uint64], release func()) {
+	t = task.WithCancelCtx(t)
+	f := t.Func
+	var (
+		mu       sync.Mutex
+		started  bool
+		skipped  bool
+		released sync.Once
+	)
+	ended := make(chan struct{})
+	t.Func = func(t *task.Task[uint64]) error {
+		mu.Lock()
+		if skipped {
+			mu.Unlock()
+			return t.Ctx.Err()
+		}
+		started = true
+		mu.Unlock()
+		defer supervisor.TrackGoroutine(supervisor.Tasks)()
+		defer released.Do(func() {
+			release()
+			close(ended)
+		})
+		return f(t)
+	}
+	go func() {
+		select {
+		case <-t.Ctx.Done():
+		case <-ended:
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !started {
+			skipped = true
+			released.Do(release)
+		}
+	}()
+	tm.Submit(t)
+}
+
 // putDirect put the file and return after finish
 func putDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer) error {
-	storage, dstDirActualPath, err := operations.GetStorageAndActualPath(dstDirPath)
+	storage, dstDirActualPath, err := operations.GetPutStorageAndActualPath(ctx, dstDirPath, file.GetName(), file.GetSize())
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
 	if storage.Config().NoUpload {
 		return errors.WithStack(errs.UploadNotSupported)
 	}
-	if err != nil {
-		return errors.WithMessage(err, "failed get storage")
+	action := putAction(ctx, storage, stdpath.Join(dstDirActualPath, file.GetName()))
+	if err := operations.Put(ctx, storage, dstDirActualPath, file, nil); err != nil {
+		return err
 	}
-	return operations.Put(ctx, storage, dstDirActualPath, file, nil)
+	recordChange(stdpath.Join(dstDirPath, file.GetName()), action, false, file.GetSize())
+	return nil
 }
@@ -0,0 +1,48 @@
+package fs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/pkg/task"
+)
+
+func TestSubmitWithBudget(t *testing.T) {
+	tm := task.NewTaskManager[uint64](1)
+	released := make(chan struct{}, 2)
+	release := func() { released <- struct{}{} }
+	start, finish := make(chan struct{}), make(chan struct{})
+	running := &task.Task[uint64]{Func: func(t *task.Task[uint64]) error {
+		close(start)
+		<-finish
+		return nil
+	}}
+	submitWithBudget(tm, running, release)
+	<-start
+	// pending as the only worker is taken
+	pending := &task.Task[uint64]{ID: 1, Func: func(t *task.Task[uint64]) error {
+		return nil
+	}}
+	submitWithBudget(tm, pending, release)
+
+	// the budget is kept until the func returns, even if it's canceled
+	running.Cancel()
+	select {
+	case <-released:
+		t.Fatalf("expected the budget is kept while the func is running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// the pending one never runs, so it's released on canceling
+	pending.Cancel()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the budget of the pending task is released")
+	}
+	close(finish)
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the budget is released after the func returns")
+	}
+}
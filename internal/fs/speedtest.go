@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	stdpath "path"
+	"sort"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils/random"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxSpeedTestSize   = 64 * 1024 * 1024
+	maxSpeedTestRounds = 50
+)
+
+type Latency struct {
+	P50 int64 `json:"p50"` // milliseconds
+	P90 int64 `json:"p90"`
+	P99 int64 `json:"p99"`
+}
+
+type SpeedTestResult struct {
+	Size          int64   `json:"size"`
+	UploadSpeed   float64 `json:"upload_speed"`   // bytes per second
+	DownloadSpeed float64 `json:"download_speed"` // bytes per second
+	Latency       Latency `json:"latency"`        // of listing the dir without cache
+}
+
+// SpeedTest upload and download a temporary file in the dir,
+// and measure the latency by listing the dir for rounds times
+func SpeedTest(ctx context.Context, dirPath string, size int64, rounds int) (*SpeedTestResult, error) {
+	if size <= 0 || size > maxSpeedTestSize {
+		return nil, errors.Errorf("size must be in (0, %d]", maxSpeedTestSize)
+	}
+	if rounds <= 0 || rounds > maxSpeedTestRounds {
+		return nil, errors.Errorf("rounds must be in (0, %d]", maxSpeedTestRounds)
+	}
+	storage, dirActualPath, err := operations.GetStorageAndActualPath(ctx, dirPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storage")
+	}
+	res := &SpeedTestResult{Size: size}
+	durations := make([]time.Duration, 0, rounds)
+	for i := 0; i < rounds; i++ {
+		start := time.Now()
+		if _, err := operations.List(ctx, storage, dirActualPath, true); err != nil {
+			return nil, errors.WithMessage(err, "failed list dir")
+		}
+		durations = append(durations, time.Since(start))
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentile := func(p int) int64 {
+		return durations[(len(durations)-1)*p/100].Milliseconds()
+	}
+	res.Latency = Latency{P50: percentile(50), P90: percentile(90), P99: percentile(99)}
+
+	data := make([]byte, size)
+	rand.Read(data)
+	name := ".alist_speedtest_" + random.String(8)
+	stream := &model.FileStream{
+		Obj: model.Object{
+			Name:     name,
+			Size:     size,
+			Modified: time.Now(),
+		},
+		ReadCloser: ioutil.NopCloser(bytes.NewReader(data)),
+		Mimetype:   "application/octet-stream",
+	}
+	start := time.Now()
+	if err := operations.Put(ctx, storage, dirActualPath, stream, nil); err != nil {
+		return nil, errors.WithMessage(err, "failed upload test file")
+	}
+	res.UploadSpeed = float64(size) / time.Since(start).Seconds()
+	filePath := stdpath.Join(dirActualPath, name)
+	defer func() {
+		if err := operations.Remove(context.Background(), storage, filePath); err != nil {
+			log.Warnf("failed remove speed test file %s: %+v", filePath, err)
+		}
+	}()
+
+	start = time.Now()
+	rc, err := openFile(ctx, storage, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	n, err := io.Copy(ioutil.Discard, rc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed download test file")
+	}
+	res.DownloadSpeed = float64(n) / time.Since(start).Seconds()
+	return res, nil
+}
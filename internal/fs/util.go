@@ -1,7 +1,11 @@
 package fs
 
 import (
+	"context"
+
+	"github.com/alist-org/alist/v3/internal/net"
 	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
 	"io"
 	"mime"
 	"net/http"
@@ -14,24 +18,13 @@ import (
 )
 
 func ClearCache(path string) {
-	storage, actualPath, err := operations.GetStorageAndActualPath(path)
+	storage, actualPath, err := operations.GetStorageAndActualPath(context.Background(), path)
 	if err != nil {
 		return
 	}
 	operations.ClearCache(storage, actualPath)
 }
 
-func containsByName(files []model.Obj, file model.Obj) bool {
-	for _, f := range files {
-		if f.GetName() == file.GetName() {
-			return true
-		}
-	}
-	return false
-}
-
-var httpClient = &http.Client{}
-
 func getFileStreamFromLink(file model.Obj, link *model.Link) (model.FileStreamer, error) {
 	var rc io.ReadCloser
 	mimetype := mime.TypeByExtension(stdpath.Ext(file.GetName()))
@@ -42,7 +35,8 @@ func getFileStreamFromLink(file model.Obj, link *model.Link) (model.FileStreamer
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to open file %s", *link.FilePath)
 		}
-		rc = f
+		// don't expose the *os.File, or it will be removed as a temp file after put
+		rc = utils.ReadCloser{Reader: f, Closer: f}
 	} else {
 		req, err := http.NewRequest(http.MethodGet, link.URL, nil)
 		if err != nil {
@@ -51,7 +45,8 @@ func getFileStreamFromLink(file model.Obj, link *model.Link) (model.FileStreamer
 		for h, val := range link.Header {
 			req.Header[h] = val
 		}
-		res, err := httpClient.Do(req)
+		client := net.Client(link.Network)
+		res, err := client.Do(req)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get response for %s", link.URL)
 		}
@@ -60,6 +55,12 @@ func getFileStreamFromLink(file model.Obj, link *model.Link) (model.FileStreamer
 			mimetype = mt
 		}
 		rc = res.Body
+		if res.StatusCode == http.StatusOK {
+			rc = operations.NewMirrorReader(context.Background(), client, link, res)
+		}
+	}
+	if link.Limiter != nil && link.Data == nil {
+		rc = utils.ReadCloser{Reader: utils.NewLimitedReader(context.Background(), rc, link.Limiter), Closer: rc}
 	}
 	// if can't get mimetype, use default application/octet-stream
 	if mimetype == "" {
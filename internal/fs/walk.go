@@ -0,0 +1,41 @@
+package fs
+
+import (
+	"context"
+	stdpath "path"
+	"path/filepath"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// Walk visit all objs under the dir path recursively, the ctx must contain the user.
+// If walkFn returns filepath.SkipDir for a dir, the dir will not be listed.
+func Walk(ctx context.Context, path string, walkFn func(path string, obj model.Obj) error) error {
+	meta, err := db.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return err
+	}
+	objs, err := List(context.WithValue(ctx, "meta", meta), path)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		objPath := stdpath.Join(path, obj.GetName())
+		err := walkFn(objPath, obj)
+		if err == filepath.SkipDir {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if obj.IsDir() {
+			if err := Walk(ctx, objPath, walkFn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -2,46 +2,109 @@ package fs
 
 import (
 	"context"
+	stdpath "path"
+
+	"github.com/alist-org/alist/v3/internal/db"
 	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/operations"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 func makeDir(ctx context.Context, path string) error {
-	storage, actualPath, err := operations.GetStorageAndActualPath(path)
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, path)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
 	}
-	return operations.MakeDir(ctx, storage, actualPath)
+	if err := operations.MakeDir(ctx, storage, actualPath); err != nil {
+		return err
+	}
+	recordChange(path, model.ChangeCreate, true, 0)
+	return nil
 }
 
 func move(ctx context.Context, srcPath, dstDirPath string) error {
-	srcStorage, srcActualPath, err := operations.GetStorageAndActualPath(srcPath)
+	srcStorage, srcActualPath, err := operations.GetStorageAndActualPath(ctx, srcPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed get src storage")
 	}
-	dstStorage, dstDirActualPath, err := operations.GetStorageAndActualPath(dstDirPath)
+	dstStorage, dstDirActualPath, err := operations.GetStorageAndActualPath(ctx, dstDirPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed get dst storage")
 	}
 	if srcStorage.GetStorage() != dstStorage.GetStorage() {
 		return errors.WithStack(errs.MoveBetweenTwoStorages)
 	}
-	return operations.Move(ctx, srcStorage, srcActualPath, dstDirActualPath)
+	srcObj, err := operations.Get(ctx, srcStorage, srcActualPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get src object")
+	}
+	if operations.GetCapabilities(srcStorage).Move || !operations.CanCopy(srcStorage, srcObj) {
+		if err := operations.Move(ctx, srcStorage, srcActualPath, dstDirActualPath); err != nil {
+			return err
+		}
+	} else {
+		// the driver can't move, but the obj can be copied and removed on the server side
+		if err := operations.Copy(ctx, srcStorage, srcActualPath, dstDirActualPath); err != nil {
+			return errors.WithMessage(err, "failed copy before removing")
+		}
+		if err := operations.Remove(ctx, srcStorage, srcActualPath); err != nil {
+			return errors.WithMessage(err, "failed remove after copying")
+		}
+	}
+	dstPath := stdpath.Join(dstDirPath, stdpath.Base(srcPath))
+	rewritePath(srcPath, dstPath)
+	recordMove(srcPath, dstPath, srcObj)
+	return nil
 }
 
 func rename(ctx context.Context, srcPath, dstName string) error {
-	storage, srcActualPath, err := operations.GetStorageAndActualPath(srcPath)
+	storage, srcActualPath, err := operations.GetStorageAndActualPath(ctx, srcPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
 	}
-	return operations.Rename(ctx, storage, srcActualPath, dstName)
+	srcObj, err := operations.Get(ctx, storage, srcActualPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get src object")
+	}
+	if err := operations.Rename(ctx, storage, srcActualPath, dstName); err != nil {
+		return err
+	}
+	dstPath := stdpath.Join(stdpath.Dir(srcPath), dstName)
+	rewritePath(srcPath, dstPath)
+	recordMove(srcPath, dstPath, srcObj)
+	return nil
+}
+
+func recordMove(srcPath, dstPath string, obj model.Obj) {
+	recordChange(srcPath, model.ChangeRemove, obj.IsDir(), obj.GetSize())
+	recordChange(dstPath, model.ChangeCreate, obj.IsDir(), obj.GetSize())
+}
+
+// rewritePath keep the records referencing the moved path valid
+func rewritePath(oldPath, newPath string) {
+	if _, err := db.RewritePath(oldPath, newPath, false); err != nil {
+		log.Errorf("failed rewrite records of path %s: %+v", oldPath, err)
+	}
 }
 
 func remove(ctx context.Context, path string) error {
-	storage, actualPath, err := operations.GetStorageAndActualPath(path)
+	storage, actualPath, err := operations.GetStorageAndActualPath(ctx, path)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
 	}
-	return operations.Remove(ctx, storage, actualPath)
+	obj, err := operations.Get(ctx, storage, actualPath)
+	if err != nil {
+		// if object not found, it's ok
+		if errs.IsObjectNotFound(err) {
+			return nil
+		}
+		return errors.WithMessage(err, "failed get object")
+	}
+	if err := operations.Remove(ctx, storage, actualPath); err != nil {
+		return err
+	}
+	recordChange(path, model.ChangeRemove, obj.IsDir(), obj.GetSize())
+	return nil
 }
@@ -0,0 +1,100 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the files mounted into the pod for the service account
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+
+// kubeClient call the api server with the in-cluster credentials
+type kubeClient struct {
+	base string
+	// re-read for every request, the projected tokens are rotated
+	tokenFile string
+	client    *http.Client
+}
+
+func newInClusterClient() (*kubeClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in kubernetes, KUBERNETES_SERVICE_HOST or KUBERNETES_SERVICE_PORT is not set")
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "ca.crt")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid ca of the service account")
+	}
+	return &kubeClient{
+		base:      "https://" + net.JoinHostPort(host, port),
+		tokenFile: serviceAccountDir + "token",
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// podNamespace the namespace of the service account
+func podNamespace() (string, error) {
+	ns, err := os.ReadFile(serviceAccountDir + "namespace")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return strings.TrimSpace(string(ns)), nil
+}
+
+// request return the status code, the body is decoded into res only if it's 2xx
+func (c *kubeClient) request(ctx context.Context, method, path string, body, res interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := utils.Json.Marshal(body)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.tokenFile != "" {
+		token, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, errors.Errorf("%s %s: %s %s", method, path, resp.Status, msg)
+	}
+	if res != nil {
+		if err := utils.Json.NewDecoder(resp.Body).Decode(res); err != nil {
+			return resp.StatusCode, errors.WithStack(err)
+		}
+	}
+	return resp.StatusCode, nil
+}
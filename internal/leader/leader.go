@@ -0,0 +1,194 @@
+// Package leader elect one of the replicas to run the scheduled jobs, such as pruning the records
+// and notifying the admin, so they run once however many replicas share the database
+package leader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// 1 if this replica is the leader or the election is disabled
+var leading int32 = 1
+
+// IsLeader tell whether the scheduled jobs should run on this replica
+func IsLeader() bool {
+	return atomic.LoadInt32(&leading) == 1
+}
+
+func setLeading(v bool) {
+	var n int32
+	if v {
+		n = 1
+	}
+	if atomic.SwapInt32(&leading, n) != n {
+		if v {
+			log.Infof("this replica becomes the leader")
+		} else {
+			log.Infof("this replica is not the leader any more")
+		}
+	}
+}
+
+// Init start the election if it's enabled, the replica is not the leader until it's elected
+func Init(c conf.LeaderElection) error {
+	if !c.Enable {
+		return nil
+	}
+	e, err := newElector(c)
+	if err != nil {
+		return err
+	}
+	setLeading(false)
+	go e.run()
+	return nil
+}
+
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// lease the fields of coordination.k8s.io/v1 Lease used
+type lease struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		HolderIdentity       string `json:"holderIdentity"`
+		LeaseDurationSeconds int    `json:"leaseDurationSeconds"`
+		AcquireTime          string `json:"acquireTime,omitempty"`
+		RenewTime            string `json:"renewTime,omitempty"`
+		LeaseTransitions     int    `json:"leaseTransitions"`
+	} `json:"spec"`
+}
+
+type elector struct {
+	client    *kubeClient
+	namespace string
+	name      string
+	identity  string
+	duration  time.Duration
+	// the last time the lease is renewed by this replica
+	renewed time.Time
+}
+
+func newElector(c conf.LeaderElection) (*elector, error) {
+	client, err := newInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+	e := &elector{
+		client:    client,
+		namespace: c.Namespace,
+		name:      c.LeaseName,
+		identity:  c.Identity,
+		duration:  time.Duration(c.LeaseDuration) * time.Second,
+	}
+	if e.namespace == "" {
+		if e.namespace, err = podNamespace(); err != nil {
+			return nil, errors.WithMessage(err, "failed get the namespace of the pod")
+		}
+	}
+	if e.identity == "" {
+		if e.identity, err = os.Hostname(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if e.name == "" || e.duration <= 0 {
+		return nil, errors.New("the lease name and a positive lease duration are required")
+	}
+	return e, nil
+}
+
+func (e *elector) path() string {
+	return fmt.Sprintf("/apis/coordination.k8s.io/v1/namespaces/%s/leases", e.namespace)
+}
+
+// run try to acquire or renew the lease 3 times in a lease duration
+func (e *elector) run() {
+	ticker := time.NewTicker(e.duration / 3)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), e.duration/3)
+		setLeading(e.tryAcquireOrRenew(ctx, time.Now()))
+		cancel()
+		<-ticker.C
+	}
+}
+
+// margin the leader gives up this long before its lease expires, and the others wait as long
+// after it, so the replicas whose clocks are skewed less than it never lead at the same time
+func (e *elector) margin() time.Duration {
+	return e.duration / 3
+}
+
+// tryAcquireOrRenew return whether this replica holds the lease. if the api server can't be reached,
+// the leader keeps leading until the margin before its lease expires
+func (e *elector) tryAcquireOrRenew(ctx context.Context, now time.Time) bool {
+	held, err := e.acquireOrRenew(ctx, now)
+	if err != nil {
+		log.Warnf("failed acquire or renew the lease %s/%s: %+v", e.namespace, e.name, err)
+		return !e.renewed.IsZero() && now.Before(e.renewed.Add(e.duration-e.margin()))
+	}
+	if held {
+		e.renewed = now
+	} else {
+		e.renewed = time.Time{}
+	}
+	return held
+}
+
+func (e *elector) acquireOrRenew(ctx context.Context, now time.Time) (bool, error) {
+	var l lease
+	status, err := e.client.request(ctx, http.MethodGet, e.path()+"/"+e.name, nil, &l)
+	if status == http.StatusNotFound {
+		l.APIVersion, l.Kind = "coordination.k8s.io/v1", "Lease"
+		l.Metadata.Name, l.Metadata.Namespace = e.name, e.namespace
+		e.hold(&l, now)
+		status, err = e.client.request(ctx, http.MethodPost, e.path(), l, nil)
+		if status == http.StatusConflict {
+			// created by another replica
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+	if l.Spec.HolderIdentity != e.identity && l.Spec.HolderIdentity != "" {
+		renewed, err := time.Parse(microTime, l.Spec.RenewTime)
+		duration := time.Duration(l.Spec.LeaseDurationSeconds) * time.Second
+		if err == nil && now.Before(renewed.Add(duration+e.margin())) {
+			return false, nil
+		}
+	}
+	e.hold(&l, now)
+	// the resource version makes the update fail if another replica updated it first
+	status, err = e.client.request(ctx, http.MethodPut, e.path()+"/"+e.name, l, nil)
+	if status == http.StatusConflict {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// hold set this replica as the holder of the lease
+func (e *elector) hold(l *lease, now time.Time) {
+	if l.Spec.HolderIdentity != e.identity {
+		if l.Spec.HolderIdentity != "" {
+			l.Spec.LeaseTransitions++
+		}
+		l.Spec.HolderIdentity = e.identity
+		l.Spec.AcquireTime = now.UTC().Format(microTime)
+	}
+	l.Spec.LeaseDurationSeconds = int(e.duration / time.Second)
+	l.Spec.RenewTime = now.UTC().Format(microTime)
+}
@@ -0,0 +1,87 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLeases keep one lease, the updates with a stale resource version conflict
+func fakeLeases() http.HandlerFunc {
+	var mu sync.Mutex
+	var stored *lease
+	version := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(stored)
+		case http.MethodPost, http.MethodPut:
+			var l lease
+			_ = json.NewDecoder(r.Body).Decode(&l)
+			if (r.Method == http.MethodPost) != (stored == nil) ||
+				(stored != nil && l.Metadata.ResourceVersion != stored.Metadata.ResourceVersion) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			version++
+			l.Metadata.ResourceVersion = strconv.Itoa(version)
+			stored = &l
+			_ = json.NewEncoder(w).Encode(stored)
+		}
+	}
+}
+
+func TestElection(t *testing.T) {
+	srv := httptest.NewServer(fakeLeases())
+	defer srv.Close()
+	newTestElector := func(identity string) *elector {
+		return &elector{
+			client:    &kubeClient{base: srv.URL, client: srv.Client()},
+			namespace: "default",
+			name:      "alist-leader",
+			identity:  identity,
+			duration:  15 * time.Second,
+		}
+	}
+	a, b := newTestElector("a"), newTestElector("b")
+	ctx := context.Background()
+	now := time.Now()
+	if !a.tryAcquireOrRenew(ctx, now) {
+		t.Fatalf("expected a acquires the lease")
+	}
+	if b.tryAcquireOrRenew(ctx, now.Add(5*time.Second)) {
+		t.Fatalf("expected b can't acquire the lease held by a")
+	}
+	if !a.tryAcquireOrRenew(ctx, now.Add(5*time.Second)) {
+		t.Fatalf("expected a renews the lease")
+	}
+	// a stops renewing, the lease expires at 20s but b waits for the clock skew
+	if b.tryAcquireOrRenew(ctx, now.Add(22*time.Second)) {
+		t.Fatalf("expected b can't acquire the lease within the margin")
+	}
+	if !b.tryAcquireOrRenew(ctx, now.Add(30*time.Second)) {
+		t.Fatalf("expected b takes over the expired lease")
+	}
+	if a.tryAcquireOrRenew(ctx, now.Add(31*time.Second)) {
+		t.Fatalf("expected a is not the leader any more")
+	}
+	// the api server can't be reached, b gives up the margin before its lease expires
+	b.client = &kubeClient{base: "http://127.0.0.1:1", client: srv.Client()}
+	if !b.tryAcquireOrRenew(ctx, now.Add(35*time.Second)) {
+		t.Fatalf("expected b keeps leading before the margin")
+	}
+	if b.tryAcquireOrRenew(ctx, now.Add(41*time.Second)) {
+		t.Fatalf("expected b gives up leading within the margin")
+	}
+}
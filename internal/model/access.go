@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// AccessCount the estimated count of listings and downloads of a virtual path
+type AccessCount struct {
+	Path       string    `json:"path" gorm:"primaryKey"`
+	Lists      int64     `json:"lists" gorm:"index"`
+	Downloads  int64     `json:"downloads" gorm:"index"`
+	LastAccess time.Time `json:"last_access" gorm:"index"`
+	// the downloads since the last digest
+	PeriodDownloads int64 `json:"period_downloads" gorm:"index"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+const (
+	ActivityUploadFinished  = "upload_finished"
+	ActivityShareDownloaded = "share_downloaded"
+	ActivityTaskFailed      = "task_failed"
+)
+
+// Activity is an event the user cares about, shown in the notification bell of the user
+type Activity struct {
+	ID     uint      `json:"id" gorm:"primaryKey"`
+	UserID uint      `json:"user_id" gorm:"index"`
+	Type   string    `json:"type"`
+	Title  string    `json:"title"`
+	Path   string    `json:"path"`                             // the virtual path involved, maybe empty
+	Read   bool      `json:"read" gorm:"column:is_read;index"` // read is reserved by mysql
+	Time   time.Time `json:"time"`
+}
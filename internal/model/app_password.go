@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// AppPassword let the clients of a protocol, such as the webdav clients, sign in without
+// the main password, it's registered for one device and can be revoked alone when the device is lost
+type AppPassword struct {
+	ID       uint       `json:"id" gorm:"primaryKey"`
+	UserID   uint       `json:"user_id" gorm:"index"`
+	Name     string     `json:"name"` // the device or the app using it
+	Protocol string     `json:"protocol"`
+	Hash     string     `json:"-"` // sha256 of the password, the password is shown once when created
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used"`
+	// the device seen the last time
+	LastIP        string `json:"last_ip"`
+	LastUserAgent string `json:"last_user_agent"`
+}
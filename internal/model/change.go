@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const (
+	ChangeCreate = "create"
+	ChangeRemove = "remove"
+	// ChangeModify the file existed is overwritten or written in place
+	ChangeModify = "modify"
+)
+
+// Change is a record of the objs created, modified or removed through alist,
+// a move or rename is recorded as a remove and a create
+type Change struct {
+	ID     uint      `json:"id" gorm:"primaryKey"`
+	Path   string    `json:"path" gorm:"index"` // virtual path
+	Action string    `json:"action"`
+	IsDir  bool      `json:"is_dir"`
+	Size   int64     `json:"size"`
+	Time   time.Time `json:"time" gorm:"index"`
+}
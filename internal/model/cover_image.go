@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// CoverImage the cover image uploaded for the folder of a meta, kept in the database
+// so that all the instances sharing it serve the same cover
+type CoverImage struct {
+	MetaID   uint      `json:"meta_id" gorm:"primaryKey"`
+	Mimetype string    `json:"mimetype"`
+	Data     []byte    `json:"-"`
+	Modified time.Time `json:"modified"`
+}
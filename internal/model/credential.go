@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Credential is shared by storages of the same account,
+// the Data will be merged into the addition of the storages using it
+type Credential struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	Name     string    `json:"name" gorm:"unique" binding:"required"`
+	Data     string    `json:"data" gorm:"type:text"` // json object, encrypted in database
+	Remark   string    `json:"remark"`
+	Modified time.Time `json:"modified"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// DedupEntry a file or dir in the mount of a dedup storage, the content of the file
+// is the blob of its hash, shared by all the files with the same content
+type DedupEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	StorageID uint      `json:"storage_id" gorm:"uniqueIndex:idx_dedup_entry_path;index:idx_dedup_entry_dir"`
+	Path      string    `json:"path" gorm:"uniqueIndex:idx_dedup_entry_path"`
+	Dir       string    `json:"dir" gorm:"index:idx_dedup_entry_dir"`
+	Name      string    `json:"name"`
+	IsDir     bool      `json:"is_dir"`
+	Hash      string    `json:"hash"` // sha256, empty for dirs
+	Size      int64     `json:"size"`
+	Modified  time.Time `json:"modified"`
+}
+
+// DedupBlob a content stored by a dedup storage, it's removed when no entry refers to it
+type DedupBlob struct {
+	StorageID uint   `json:"storage_id" gorm:"primaryKey;autoIncrement:false"`
+	Hash      string `json:"hash" gorm:"primaryKey"`
+	Size      int64  `json:"size"`
+	RefCount  int    `json:"ref_count"`
+}
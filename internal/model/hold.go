@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	HoldPlaced   = "placed"
+	HoldReleased = "released"
+	HoldBlocked  = "blocked"
+)
+
+// Hold keep the path and everything inside it from being removed, renamed, moved or overwritten,
+// until an admin releases it
+type Hold struct {
+	ID      uint      `json:"id" gorm:"primaryKey"`
+	Path    string    `json:"path" gorm:"unique"` // the held virtual path
+	Reason  string    `json:"reason"`
+	Creator string    `json:"creator"` // the username of who placed it
+	Created time.Time `json:"created"`
+}
+
+// HoldLog is the audit record of placing and releasing holds, and the operations blocked by them
+type HoldLog struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	Path     string    `json:"path" gorm:"index"` // the held path
+	Action   string    `json:"action"`
+	Target   string    `json:"target"`   // the path of the blocked operation
+	Username string    `json:"username"` // empty if not operated by a user, such as the tasks
+	Detail   string    `json:"detail"`   // the reason of placing or the blocked operation
+	Time     time.Time `json:"time" gorm:"index"`
+}
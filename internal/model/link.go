@@ -4,6 +4,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 type LinkArgs struct {
@@ -12,10 +14,14 @@ type LinkArgs struct {
 }
 
 type Link struct {
-	URL        string         `json:"url"`
-	Header     http.Header    `json:"header"` // needed header
-	Data       io.ReadCloser  // return file reader directly
-	Status     int            // status maybe 200 or 206, etc
-	FilePath   *string        // local file, return the filepath
-	Expiration *time.Duration // url expiration time
+	URL          string         `json:"url"`
+	Header       http.Header    `json:"header"` // needed header
+	Data         io.ReadCloser  // return file reader directly
+	Status       int            // status maybe 200 or 206, etc
+	FilePath     *string        // local file, return the filepath
+	Expiration   *time.Duration // url expiration time
+	Limiter      *rate.Limiter  // limit the speed of proxying, Data is already limited
+	Mirrors      []string       // other endpoints serving the same content as URL
+	StallTimeout time.Duration  // switch to the next mirror if no data received for so long, 0 means never
+	Network      Network        // the network of the storage to fetch the URL
 }
@@ -11,4 +11,39 @@ type Meta struct {
 	HSub     bool   `json:"h_sub"`
 	Readme   string `json:"readme"`
 	RSub     bool   `json:"r_sub"`
+	// Cover image of the folder, a path relative to the folder or an absolute one,
+	// an uploaded cover takes precedence
+	Cover       string `json:"cover"`
+	Description string `json:"description" gorm:"type:text"`
+}
+
+const (
+	MetaPassword = "password"
+	MetaWrite    = "write"
+	MetaHide     = "hide"
+	MetaReadme   = "readme"
+	// the cover and description have no sub flag, they only apply to the folder of the meta
+	MetaCover       = "cover"
+	MetaDescription = "description"
+)
+
+// CopyAttr copy the attribute and its sub flag from src, return false if attr is unknown
+func (m *Meta) CopyAttr(src Meta, attr string) bool {
+	switch attr {
+	case MetaPassword:
+		m.Password, m.PSub = src.Password, src.PSub
+	case MetaWrite:
+		m.Write, m.WSub = src.Write, src.WSub
+	case MetaHide:
+		m.Hide, m.HSub = src.Hide, src.HSub
+	case MetaReadme:
+		m.Readme, m.RSub = src.Readme, src.RSub
+	case MetaCover:
+		m.Cover = src.Cover
+	case MetaDescription:
+		m.Description = src.Description
+	default:
+		return false
+	}
+	return true
 }
@@ -32,6 +32,12 @@ type Thumbnail interface {
 	Thumbnail() string
 }
 
+// Hash is implemented by the objs that carry the hashes reported by the storage
+type Hash interface {
+	// GetHash return the lowercase hex of the algo, empty if the storage doesn't report it
+	GetHash(algo string) string
+}
+
 type SetID interface {
 	SetID(id string)
 }
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	HashMD5    = "md5"
+	HashSHA256 = "sha256"
+)
+
+// ObjHash the hashes of a file computed while it's uploaded through alist,
+// so the jobs that need them don't have to download the file again
+type ObjHash struct {
+	StorageID uint      `json:"storage_id" gorm:"primaryKey;autoIncrement:false"`
+	Path      string    `json:"path" gorm:"primaryKey"` // actual path in the storage
+	Size      int64     `json:"size"`
+	MD5       string    `json:"md5"`
+	SHA256    string    `json:"sha256"`
+	Updated   time.Time `json:"updated"`
+}
+
+// GetHash return the hex of the algo, empty if unknown
+func (h ObjHash) GetHash(algo string) string {
+	switch algo {
+	case HashMD5:
+		return h.MD5
+	case HashSHA256:
+		return h.SHA256
+	}
+	return ""
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Progress the playback position of a media file of the user, shared by the devices of the user,
+// the one updated the latest wins
+type Progress struct {
+	ID       uint      `json:"-" gorm:"primaryKey"`
+	UserID   uint      `json:"-" gorm:"uniqueIndex:idx_progress_user_path"`
+	Path     string    `json:"path" gorm:"uniqueIndex:idx_progress_user_path"`
+	Position float64   `json:"position"` // seconds
+	Duration float64   `json:"duration"` // seconds, 0 if unknown
+	Device   string    `json:"device"`   // the device updated it the last time
+	Updated  time.Time `json:"updated"`  // given by the device, so the late sync of an offline device doesn't win
+}
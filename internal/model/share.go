@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// Share give anyone having the token read access to the path,
+// with the permissions of the user who created it
+type Share struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Token  string `json:"token" gorm:"unique"`
+	Path   string `json:"path"` // the shared virtual path
+	UserID uint   `json:"user_id"`
+	// the hash of the password, empty means no password
+	PasswordHash string     `json:"-"`
+	Expires      *time.Time `json:"expires"` // nil means never expire
+	Created      time.Time  `json:"created"`
+}
+
+func (s Share) HasPassword() bool {
+	return s.PasswordHash != ""
+}
+
+func (s Share) CheckPassword(password string) bool {
+	return !s.HasPassword() || utils.CheckPassword(s.PasswordHash, password)
+}
+
+func (s Share) IsExpired() bool {
+	return s.Expires != nil && s.Expires.Before(time.Now())
+}
+
+// ShareLog is the audit record of resolving a share, used to track abuse
+type ShareLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Token     string    `json:"token" gorm:"index"`
+	Path      string    `json:"path"` // relative to the shared path
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Status    int       `json:"status"` // the http status responded
+	Time      time.Time `json:"time" gorm:"index"`
+}
@@ -1,20 +1,66 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
 
 type Storage struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`                        // unique key
-	MountPath string    `json:"mount_path" gorm:"unique" binding:"required"` // must be standardized
-	Index     int       `json:"index"`                                       // use to sort
-	Driver    string    `json:"driver"`                                      // driver used
-	Status    string    `json:"status"`
-	Addition  string    `json:"addition" gorm:"type:text"` // Additional information, defined in the corresponding driver
-	Remark    string    `json:"remark"`
-	Modified  time.Time `json:"modified"`
+	ID                 uint      `json:"id" gorm:"primaryKey"`                        // unique key
+	MountPath          string    `json:"mount_path" gorm:"unique" binding:"required"` // must be standardized
+	Index              int       `json:"index"`                                       // use to sort
+	Driver             string    `json:"driver"`                                      // driver used
+	Status             string    `json:"status"`
+	Addition           string    `json:"addition" gorm:"type:text"` // Additional information, defined in the corresponding driver
+	Remark             string    `json:"remark"`
+	Modified           time.Time `json:"modified"`
+	DisabledOps        string    `json:"disabled_ops"`                // comma separated operations that are not allowed
+	ReadOnly           bool      `json:"read_only"`                   // reject all write operations
+	UploadLimit        int64     `json:"upload_limit"`                // bytes per second, 0 means no limit
+	DownloadLimit      int64     `json:"download_limit"`              // bytes per second, 0 means no limit
+	CacheExpiration    int       `json:"cache_expiration"`            // minutes of the list cache, 0 means default, negative means no cache
+	InitAttempts       int       `json:"init_attempts"`               // the number of consecutive failed init attempts
+	LastError          string    `json:"last_error" gorm:"type:text"` // the error of last failed init
+	CredentialID       uint      `json:"credential_id"`               // shared credential merged into addition
+	BackupCredentialID uint      `json:"backup_credential_id"`        // switched to when the account of the primary one is unavailable
+	OnBackup           bool      `json:"on_backup"`                   // the backup credential is in use
+	BalancePolicy      string    `json:"balance_policy"`              // how to pick a member of the balance group
+	ShadowPolicy       string    `json:"shadow_policy"`               // how the listing combines with the storages mounted inside
+	Accelerate         bool      `json:"accelerate"`                  // probe the endpoints of links and use the fastest one
+	StallTimeout       int       `json:"stall_timeout"`               // seconds without data before switching endpoint, 0 means default
+	Tags               string    `json:"tags"`                        // comma separated, used to filter and group storages
+	DependsOn          string    `json:"depends_on"`                  // comma separated mount paths initialized before this one
+	UploadReserve      int64     `json:"upload_reserve"`              // bytes kept free, the uploads are routed to other members of the group then
+	RateLimit          float64   `json:"rate_limit"`                  // calls to the driver per second, 0 means no limit
+	RateBurst          int       `json:"rate_burst"`                  // calls allowed at once before limited, at least 1
+	MaxParallel        int       `json:"max_parallel"`                // calls to the driver running at once, 0 means no limit
+	DebugCapture       bool      `json:"debug_capture"`               // record the recent http calls of the driver, the secrets are redacted
+	RetryAttempts      int       `json:"retry_attempts"`              // extra attempts of the read calls failed transiently, 0 means no retry
+	RetryBackoff       int       `json:"retry_backoff"`               // milliseconds before the first retry, doubled every retry, 0 means default
+	RetryOn            string    `json:"retry_on"`                    // comma separated, the errors containing any of them are retried too
 	Sort
 	Proxy
+	Network
 }
 
+const (
+	StorageWork         = "work"
+	StorageInitFailed   = "init failed"
+	StoragePending      = "pending"       // lazy init, not accessed yet
+	StorageTokenExpired = "token expired" // the refresh token is rejected, needs to be authorized again
+)
+
+const (
+	OpMakeDir = "make_dir"
+	OpMove    = "move"
+	OpRename  = "rename"
+	OpCopy    = "copy"
+	OpRemove  = "remove"
+	OpPut     = "put"
+)
+
 type Sort struct {
 	OrderBy        string `json:"order_by"`
 	OrderDirection string `json:"order_direction"`
@@ -27,14 +73,94 @@ type Proxy struct {
 	DownProxyUrl string `json:"down_proxy_url"`
 }
 
+// Network is how the http clients of the storage connect to the provider
+type Network struct {
+	IPVersion     string `json:"ip_version"`     // force ipv4 or ipv6
+	BindAddress   string `json:"bind_address"`   // local ip or interface name to send requests from
+	OutboundProxy string `json:"outbound_proxy"` // socks5:// or http:// proxy to the provider
+	ProxyHosts    string `json:"proxy_hosts"`    // comma separated, only the hosts and their subdomains use the proxy, empty means all
+	// the limits of metadata calls, 0 means the global ones
+	RequestTimeout int   `json:"request_timeout"` // seconds
+	MaxBodySize    int64 `json:"max_body_size"`   // bytes
+	// the key the http calls are captured by, set to the mount path by ClientNetwork if the debug capture is on
+	Capture string `json:"-" gorm:"-"`
+}
+
 func (a *Storage) GetStorage() Storage {
 	return *a
 }
 
+func (a *Storage) SetStorage(storage Storage) {
+	*a = storage
+}
+
 func (a *Storage) SetStatus(status string) {
 	a.Status = status
 }
 
+// GetTags split the tags, the empty and duplicate ones are removed
+func (a Storage) GetTags() []string {
+	var tags []string
+	seen := make(map[string]struct{})
+	for _, tag := range strings.Split(a.Tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if _, ok := seen[tag]; ok || tag == "" {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// GetDependsOn split the mount paths depended on, the empty and duplicate ones are removed
+func (a Storage) GetDependsOn() []string {
+	var paths []string
+	seen := make(map[string]struct{})
+	for _, path := range strings.Split(a.DependsOn, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		path = utils.StandardizePath(path)
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// GetCredentialID return the credential in use, the backup one after switched
+// ClientNetwork return the network the http clients of the storage are built with,
+// the calls are captured by the mount path if the debug capture is on
+func (a Storage) ClientNetwork() Network {
+	network := a.Network
+	network.Capture = ""
+	if a.DebugCapture {
+		network.Capture = a.MountPath
+	}
+	return network
+}
+
+func (a Storage) GetCredentialID() uint {
+	if a.OnBackup && a.BackupCredentialID != 0 {
+		return a.BackupCredentialID
+	}
+	return a.CredentialID
+}
+
+// IsOpDisabled check whether the operation is disabled by admin
+func (a Storage) IsOpDisabled(op string) bool {
+	for _, v := range strings.Split(a.DisabledOps, ",") {
+		if strings.TrimSpace(v) == op {
+			return true
+		}
+	}
+	return false
+}
+
 func (p Proxy) Webdav302() bool {
 	return p.WebdavPolicy == "302_redirect"
 }
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// StorageTemplate a saved driver with partial addition,
+// used to create storages by only filling the differing fields
+type StorageTemplate struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	Name     string    `json:"name" gorm:"unique" binding:"required"`
+	Driver   string    `json:"driver" binding:"required"`
+	Addition string    `json:"addition" gorm:"type:text"` // partial json object
+	Remark   string    `json:"remark"`
+	Modified time.Time `json:"modified"`
+}
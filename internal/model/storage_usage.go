@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// StorageUsage the latest space of the storage reported by the driver
+type StorageUsage struct {
+	StorageID uint      `json:"storage_id" gorm:"primaryKey;autoIncrement:false"`
+	MountPath string    `json:"mount_path" gorm:"-"`
+	Total     int64     `json:"total"` // bytes, 0 if unknown
+	Used      int64     `json:"used"`
+	Free      int64     `json:"free"`
+	Updated   time.Time `json:"updated"`
+}
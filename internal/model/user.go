@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/alist-org/alist/v3/internal/errs"
 	"github.com/pkg/errors"
 )
@@ -29,8 +31,25 @@ type User struct {
 	//  8: webdav read
 	//  9: webdav write
 	Permission int32 `json:"permission"`
+	// the permissions denied when accessed by webdav, the same bits as the Permission,
+	// such as 7 to forbid removing by webdav even though the user can remove on the web
+	WebdavDenied int32  `json:"webdav_denied"`
+	Email        string `json:"email"`  // the address to receive notifications
+	Digest       string `json:"digest"` // weekly or monthly report for admin, empty to disable
+	// zero for the users created before it's recorded
+	CreatedAt time.Time `json:"created_at" gorm:"<-:create"`
 }
 
+const (
+	ProtocolWeb    = "web"
+	ProtocolWebdav = "webdav"
+)
+
+const (
+	DigestWeekly  = "weekly"
+	DigestMonthly = "monthly"
+)
+
 func (u User) IsGuest() bool {
 	return u.Role == GUEST
 }
@@ -49,6 +68,19 @@ func (u User) ValidatePassword(password string) error {
 	return nil
 }
 
+// ForProtocol the user with the permissions denied to the protocol removed
+func (u User) ForProtocol(protocol string) *User {
+	if protocol == ProtocolWebdav {
+		u.Permission &^= u.WebdavDenied
+	}
+	return &u
+}
+
+// IsDenied tell whether the permission bit is denied to the protocol
+func (u User) IsDenied(protocol string, bit int) bool {
+	return protocol == ProtocolWebdav && bit >= 0 && (u.WebdavDenied>>bit)&1 == 1
+}
+
 func (u User) CanSeeHides() bool {
 	return u.IsAdmin() || u.Permission&1 == 1
 }
@@ -0,0 +1,247 @@
+package net
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// the calls kept for each storage, the oldest ones are dropped
+	captureSize = 100
+	// the bytes of the bodies kept
+	captureBodySize = 4096
+)
+
+// Capture an http call of a driver, the secrets are redacted
+type Capture struct {
+	Time           time.Time   `json:"time"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"request_header"`
+	RequestBody    string      `json:"request_body"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"response_header"`
+	ResponseBody   string      `json:"response_body"`
+	Duration       int64       `json:"duration"` // milliseconds until the response header
+	Error          string      `json:"error,omitempty"`
+}
+
+type captureBuffer struct {
+	sync.Mutex
+	captures []Capture
+	next     int
+}
+
+func (b *captureBuffer) add(c Capture) {
+	b.Lock()
+	defer b.Unlock()
+	if len(b.captures) < captureSize {
+		b.captures = append(b.captures, c)
+		return
+	}
+	b.captures[b.next] = c
+	b.next = (b.next + 1) % captureSize
+}
+
+var (
+	captures   = map[string]*captureBuffer{}
+	capturesMu sync.Mutex
+)
+
+func getCaptureBuffer(key string) *captureBuffer {
+	capturesMu.Lock()
+	defer capturesMu.Unlock()
+	b, ok := captures[key]
+	if !ok {
+		b = &captureBuffer{}
+		captures[key] = b
+	}
+	return b
+}
+
+// GetCaptures the recent calls captured by the key, the latest first
+func GetCaptures(key string) []Capture {
+	capturesMu.Lock()
+	b, ok := captures[key]
+	capturesMu.Unlock()
+	if !ok {
+		return []Capture{}
+	}
+	b.Lock()
+	defer b.Unlock()
+	res := make([]Capture, 0, len(b.captures))
+	for i := len(b.captures) - 1; i >= 0; i-- {
+		res = append(res, b.captures[(b.next+i)%len(b.captures)])
+	}
+	return res
+}
+
+// ClearCaptures clear the calls captured by the key, the buffer is reset in place
+// as the transports of the storage keep adding to it
+func ClearCaptures(key string) {
+	capturesMu.Lock()
+	b, ok := captures[key]
+	capturesMu.Unlock()
+	if !ok {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.captures, b.next = nil, 0
+}
+
+// RemoveCaptures drop the buffer and the clients capturing by the key,
+// when the storage is deleted or its mount path is changed
+func RemoveCaptures(key string) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	for network := range clients {
+		if network.Capture == key {
+			delete(clients, network)
+		}
+	}
+	for network := range apiClients {
+		if network.Capture == key {
+			delete(apiClients, network)
+		}
+	}
+	capturesMu.Lock()
+	defer capturesMu.Unlock()
+	delete(captures, key)
+}
+
+var (
+	// the headers carrying urls, the query of them is signed often
+	urlHeaders = map[string]bool{"Location": true, "Content-Location": true, "Link": true, "Refresh": true}
+	secretName = regexp.MustCompile(`(?i)auth|token|cookie|secret|password|passwd|signature|credential|key`)
+	secretJson = regexp.MustCompile(`(?i)("[^"]*(?:token|secret|password|passwd|signature|credential|key)[^"]*"\s*:\s*)"[^"]*"`)
+	secretForm = regexp.MustCompile(`(?i)((?:^|&)[^=&]*(?:token|secret|password|passwd|sign|credential|key|auth)[^=&]*=)[^&]*`)
+	// the query of the urls in the bodies, such as the download urls signed by sign or auth_key
+	secretQuery = regexp.MustCompile(`(?i)([?&][^=&"'\s]*(?:token|secret|password|passwd|sign|credential|key|auth)[^=&"'\s]*=)[^&"'\s#]*`)
+	urlQuery    = regexp.MustCompile(`\?[^\s<>;,"'#]*`)
+	redactedVal = "[redacted]"
+)
+
+func redactHeader(h http.Header) http.Header {
+	res := make(http.Header, len(h))
+	for k, v := range h {
+		switch {
+		case secretName.MatchString(k):
+			res[k] = []string{redactedVal}
+		case urlHeaders[http.CanonicalHeaderKey(k)]:
+			res[k] = make([]string, len(v))
+			for i := range v {
+				res[k][i] = redactQuery(v[i])
+			}
+		default:
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// redactQuery redact the whole query of the urls in the value, the signature may be named anything
+func redactQuery(v string) string {
+	return urlQuery.ReplaceAllString(v, "?"+redactedVal)
+}
+
+func redactURL(u *url.URL) string {
+	r := *u
+	r.User = nil
+	if r.RawQuery != "" {
+		r.RawQuery = secretForm.ReplaceAllString(r.RawQuery, "${1}"+redactedVal)
+	}
+	return r.String()
+}
+
+func redactBody(body []byte, more bool) string {
+	s := string(body)
+	// the form values would run over the quotes of json
+	if trimmed := strings.TrimSpace(s); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		s = secretJson.ReplaceAllString(s, `${1}"`+redactedVal+`"`)
+	} else {
+		s = secretForm.ReplaceAllString(s, "${1}"+redactedVal)
+	}
+	s = secretQuery.ReplaceAllString(s, "${1}"+redactedVal)
+	if more {
+		s += "..."
+	}
+	return s
+}
+
+// captureTransport record the calls into the buffer
+type captureTransport struct {
+	base   http.RoundTripper
+	buffer *captureBuffer
+}
+
+func (t captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := Capture{
+		Time:          time.Now(),
+		Method:        req.Method,
+		URL:           redactURL(req.URL),
+		RequestHeader: redactHeader(req.Header),
+	}
+	// the body is read again from GetBody, the uploads without it are not read
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(io.LimitReader(body, captureBodySize+1))
+			_ = body.Close()
+			more := len(data) > captureBodySize
+			if more {
+				data = data[:captureBodySize]
+			}
+			c.RequestBody = redactBody(data, more)
+		}
+	}
+	res, err := t.base.RoundTrip(req)
+	c.Duration = time.Since(c.Time).Milliseconds()
+	if err != nil {
+		c.Error = err.Error()
+		t.buffer.add(c)
+		return res, err
+	}
+	c.Status = res.StatusCode
+	c.ResponseHeader = redactHeader(res.Header)
+	res.Body = &captureBody{ReadCloser: res.Body, capture: c, buffer: t.buffer}
+	return res, nil
+}
+
+// captureBody keep the head of the response body, the capture is added when it's closed
+type captureBody struct {
+	io.ReadCloser
+	capture Capture
+	buffer  *captureBuffer
+	head    bytes.Buffer
+	more    bool
+	once    sync.Once
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if remain := captureBodySize - b.head.Len(); remain > 0 {
+		if n > remain {
+			b.head.Write(p[:remain])
+			b.more = true
+		} else {
+			b.head.Write(p[:n])
+		}
+	} else if n > 0 {
+		b.more = true
+	}
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	b.once.Do(func() {
+		b.capture.ResponseBody = redactBody(b.head.Bytes(), b.more)
+		b.buffer.add(b.capture)
+	})
+	return b.ReadCloser.Close()
+}
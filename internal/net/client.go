@@ -0,0 +1,268 @@
+package net
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	IPAuto = "auto"
+	IPv4   = "ipv4"
+	IPv6   = "ipv6"
+)
+
+var (
+	clients    = map[model.Network]*http.Client{}
+	apiClients = map[model.Network]*http.Client{}
+	clientsMu  sync.Mutex
+)
+
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// Client return the shared http client of the network,
+// which resolves with the configured dns servers
+func Client(network model.Network) *http.Client {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	return client(network)
+}
+
+// APIClient return the shared http client of the network for metadata calls,
+// the whole call is limited by the request timeout and the response by the max body size,
+// so a misbehaving provider can't hold the goroutines or memory
+func APIClient(network model.Network) *http.Client {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if c, ok := apiClients[network]; ok {
+		return c
+	}
+	cfg := netConfig()
+	timeout, maxBodySize := cfg.RequestTimeout, cfg.MaxBodySize
+	if network.RequestTimeout > 0 {
+		timeout = network.RequestTimeout
+	}
+	if network.MaxBodySize > 0 {
+		maxBodySize = network.MaxBodySize
+	}
+	c := &http.Client{
+		Transport: limitTransport{base: client(network).Transport, max: maxBodySize},
+		Timeout:   time.Duration(timeout) * time.Second,
+	}
+	apiClients[network] = c
+	return c
+}
+
+func client(network model.Network) *http.Client {
+	if c, ok := clients[network]; ok {
+		return c
+	}
+	var c *http.Client
+	transport, err := NewTransport(network)
+	if err != nil {
+		// don't fall back to the default route silently
+		log.Errorf("invalid network %+v: %+v", network, err)
+		c = &http.Client{Transport: errTransport{err: err}}
+	} else if network.Capture != "" {
+		c = &http.Client{Transport: requestIDTransport{base: captureTransport{base: transport, buffer: getCaptureBuffer(network.Capture)}}}
+	} else {
+		c = &http.Client{Transport: requestIDTransport{base: transport}}
+	}
+	clients[network] = c
+	return c
+}
+
+func NewTransport(network model.Network) (*http.Transport, error) {
+	cfg := netConfig()
+	dialer, err := newDirectDialer(network)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSHandshakeTimeout = time.Duration(cfg.TLSHandshakeTimeout) * time.Second
+	transport.ResponseHeaderTimeout = time.Duration(cfg.ResponseHeaderTimeout) * time.Second
+	transport.DialContext = dialer.DialContext
+	if network.OutboundProxy != "" {
+		u, err := url.Parse(network.OutboundProxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid outbound proxy")
+		}
+		// socks5 is supported by the transport natively
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if !shouldProxy(network, req.URL.Hostname()) {
+				return nil, nil
+			}
+			return u, nil
+		}
+	}
+	return transport, nil
+}
+
+// bindIP return the ip itself, or an address of the interface matching the ip version
+func bindIP(address, ipVersion string) (net.IP, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip, nil
+	}
+	iface, err := net.InterfaceByName(address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid bind address %s", address)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed get addresses of interface %s", address)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (ipNet.IP.To4() != nil) == (ipVersion != IPv6) {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, errors.Errorf("no usable address on interface %s", address)
+}
+
+type limitTransport struct {
+	base http.RoundTripper
+	max  int64
+}
+
+func (t limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	if err != nil || t.max <= 0 {
+		return res, err
+	}
+	if res.ContentLength > t.max {
+		_ = res.Body.Close()
+		return nil, errors.Wrapf(ErrBodyTooLarge, "%d bytes from %s", res.ContentLength, req.URL.Host)
+	}
+	res.Body = &limitedBody{ReadCloser: res.Body, remain: t.max}
+	return res, nil
+}
+
+// limitedBody fail the read once more than the max bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	remain int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.remain+1 {
+		p = p[:b.remain+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remain -= int64(n)
+	if b.remain < 0 {
+		return n, errors.WithStack(ErrBodyTooLarge)
+	}
+	return n, err
+}
+
+// requestIDTransport set the request id of the context on the calls to the providers,
+// so a failed call can be found in their logs
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+// WithRequestID wrap the transport built by the driver itself to set the request id
+func WithRequestID(base http.RoundTripper) http.RoundTripper {
+	return requestIDTransport{base: base}
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := utils.RequestID(req.Context()); id != "" && req.Header.Get(utils.RequestIDHeader) == "" {
+		// the request must not be modified by the transport
+		req = req.Clone(req.Context())
+		req.Header.Set(utils.RequestIDHeader, id)
+	}
+	return t.base.RoundTrip(req)
+}
+
+type errTransport struct {
+	err error
+}
+
+func (t errTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+func netConfig() conf.Net {
+	if conf.Conf == nil {
+		return conf.DefaultConfig().Net
+	}
+	return conf.Conf.Net
+}
+
+func forceNetwork(network, ipVersion string) string {
+	if network != "tcp" {
+		return network
+	}
+	switch ipVersion {
+	case IPv4:
+		return "tcp4"
+	case IPv6:
+		return "tcp6"
+	}
+	return network
+}
+
+// newResolver return a resolver querying the servers in order,
+// the servers may be host:port, tls://host:port (DoT) or https://... (DoH)
+func newResolver(servers []string) *net.Resolver {
+	if len(servers) == 0 {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var (
+				conn net.Conn
+				err  error
+			)
+			for _, server := range servers {
+				conn, err = dialDNS(ctx, network, server)
+				if err == nil {
+					return conn, nil
+				}
+			}
+			return nil, err
+		},
+	}
+}
+
+func dialDNS(ctx context.Context, network, server string) (net.Conn, error) {
+	var d net.Dialer
+	switch {
+	case strings.HasPrefix(server, "https://"):
+		return newDohConn(ctx, server), nil
+	case strings.HasPrefix(server, "tls://"):
+		host := strings.TrimPrefix(server, "tls://")
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, "853")
+		}
+		conn, err := d.DialContext(ctx, "tcp", host)
+		if err != nil {
+			return nil, err
+		}
+		serverName, _, _ := net.SplitHostPort(host)
+		return tls.Client(conn, &tls.Config{ServerName: serverName}), nil
+	default:
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		return d.DialContext(ctx, network, server)
+	}
+}
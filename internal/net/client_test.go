@@ -0,0 +1,35 @@
+package net
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(utils.RequestIDHeader)
+	}))
+	defer server.Close()
+	ctx := context.WithValue(context.Background(), utils.RequestIDKey, "abc")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := APIClient(model.Network{}).Do(req)
+	if err != nil {
+		t.Fatalf("failed request: %+v", err)
+	}
+	_ = res.Body.Close()
+	if got != "abc" {
+		t.Errorf("expected the request id is sent to the provider, got %q", got)
+	}
+	if req.Header.Get(utils.RequestIDHeader) != "" {
+		t.Errorf("expected the request of the caller is not modified")
+	}
+}
@@ -0,0 +1,163 @@
+package net
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+// Dialer dial the connections of the storage to the provider
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+type dialerFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f dialerFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// Dial satisfy proxy.Dialer, so the socks5 dialer can forward through it
+func (f dialerFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+// NewDialer return the dialer of the network for the drivers speaking other protocols than http,
+// such as sftp, ftp and smb. the connections go through the outbound proxy as the http clients do
+func NewDialer(network model.Network) (Dialer, error) {
+	direct, err := newDirectDialer(network)
+	if err != nil {
+		return nil, err
+	}
+	if network.OutboundProxy == "" {
+		return direct, nil
+	}
+	u, err := url.Parse(network.OutboundProxy)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid outbound proxy")
+	}
+	var proxied Dialer
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		d, err := proxy.FromURL(u, direct)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid outbound proxy")
+		}
+		proxied = d.(proxy.ContextDialer)
+	case "http":
+		proxied = &connectDialer{proxy: u, forward: direct}
+	default:
+		return nil, errors.Errorf("unsupported outbound proxy scheme: %s", u.Scheme)
+	}
+	return dialerFunc(func(ctx context.Context, n, addr string) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		if !shouldProxy(network, host) {
+			return direct.DialContext(ctx, n, addr)
+		}
+		return proxied.DialContext(ctx, n, addr)
+	}), nil
+}
+
+// newDirectDialer dial with the dns servers, the bind address and the ip version of the network
+func newDirectDialer(network model.Network) (dialerFunc, error) {
+	cfg := netConfig()
+	dialer := &net.Dialer{
+		Timeout:       time.Duration(cfg.DialTimeout) * time.Second,
+		KeepAlive:     30 * time.Second,
+		FallbackDelay: time.Duration(cfg.FallbackDelay) * time.Millisecond,
+		Resolver:      newResolver(cfg.DNS),
+	}
+	if network.BindAddress != "" {
+		ip, err := bindIP(network.BindAddress, network.IPVersion)
+		if err != nil {
+			return nil, err
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	return func(ctx context.Context, n, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, forceNetwork(n, network.IPVersion), addr)
+	}, nil
+}
+
+// shouldProxy tell whether the connections to the host go through the outbound proxy,
+// all of them if the proxy hosts are not set, or the hosts matching any of them and their subdomains
+func shouldProxy(network model.Network, host string) bool {
+	if network.ProxyHosts == "" {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range strings.Split(network.ProxyHosts, ",") {
+		h = strings.ToLower(strings.Trim(strings.TrimSpace(h), "."))
+		if h != "" && (host == h || strings.HasSuffix(host, "."+h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// connectDialer tunnel the connections through the http proxy with CONNECT
+type connectDialer struct {
+	proxy   *url.URL
+	forward Dialer
+}
+
+func (d *connectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyAddr := d.proxy.Host
+	if d.proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(d.proxy.Hostname(), "80")
+	}
+	conn, err := d.forward.DialContext(ctx, network, proxyAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed connect the outbound proxy")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if u := d.proxy.User; u != nil {
+		password, _ := u.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + password))
+		req += "Proxy-Authorization: Basic " + auth + "\r\n"
+	}
+	if _, err := io.WriteString(conn, req+"\r\n"); err != nil {
+		_ = conn.Close()
+		return nil, errors.WithStack(err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrap(err, "failed read the response of the outbound proxy")
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, errors.Errorf("the outbound proxy refused to connect %s: %s", addr, res.Status)
+	}
+	if br.Buffered() > 0 {
+		// the server may speak first, such as the banner of ssh
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,87 @@
+package net
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var dohClient = &http.Client{Timeout: 10 * time.Second}
+
+// dohConn is a fake stream conn for the go resolver, every framed query
+// written to it is sent as a DNS over HTTPS request (RFC 8484)
+type dohConn struct {
+	ctx   context.Context
+	url   string
+	query bytes.Buffer
+	resp  bytes.Buffer
+}
+
+func newDohConn(ctx context.Context, url string) *dohConn {
+	return &dohConn{ctx: ctx, url: url}
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.query.Write(b)
+	q := c.query.Bytes()
+	if len(q) < 2 || len(q)-2 < int(binary.BigEndian.Uint16(q)) {
+		return len(b), nil
+	}
+	msg := q[2 : 2+int(binary.BigEndian.Uint16(q))]
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.url, bytes.NewReader(msg))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	res, err := dohClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("doh server responded with status %d", res.StatusCode)
+	}
+	answer, err := io.ReadAll(io.LimitReader(res.Body, 65535))
+	if err != nil {
+		return 0, err
+	}
+	_ = binary.Write(&c.resp, binary.BigEndian, uint16(len(answer)))
+	c.resp.Write(answer)
+	c.query.Reset()
+	return len(b), nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	return c.resp.Read(b)
+}
+
+func (c *dohConn) Close() error {
+	return nil
+}
+
+func (c *dohConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func (c *dohConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func (c *dohConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *dohConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *dohConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
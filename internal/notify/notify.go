@@ -0,0 +1,127 @@
+// Package notify send notifications to admins by email and webhook
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+type Message struct {
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	To      []string `json:"to"`
+}
+
+// Send the message by all configured channels,
+// the email is skipped if smtp is not configured or no recipient
+func Send(msg Message) error {
+	var errs []string
+	sent := false
+	if setting.GetByKey(conf.SmtpHost) != "" && len(msg.To) > 0 {
+		sent = true
+		if err := sendEmail(msg); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if setting.GetByKey(conf.NotifyWebhook) != "" {
+		sent = true
+		if err := sendWebhook(msg); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if !sent {
+		return errors.New("no notification channel is configured")
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func sendEmail(msg Message) error {
+	host := setting.GetByKey(conf.SmtpHost)
+	port := setting.GetIntSetting(conf.SmtpPort, 465)
+	username := setting.GetByKey(conf.SmtpUsername)
+	from := setting.GetByKey(conf.SmtpFrom)
+	if from == "" {
+		from = username
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	buf.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	addr := net.JoinHostPort(host, fmt.Sprint(port))
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, setting.GetByKey(conf.SmtpPassword), host)
+	}
+	// 465 is implicit tls, the others use starttls if supported
+	if port != 465 {
+		return errors.Wrap(smtp.SendMail(addr, auth, from, msg.To, buf.Bytes()), "failed send email")
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, &tls.Config{ServerName: host})
+	if err != nil {
+		return errors.Wrap(err, "failed connect smtp server")
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "failed connect smtp server")
+	}
+	defer c.Close()
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return errors.Wrap(err, "failed auth smtp")
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return errors.Wrap(err, "failed send email")
+	}
+	for _, to := range msg.To {
+		if err := c.Rcpt(to); err != nil {
+			return errors.Wrapf(err, "failed send email to %s", to)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "failed send email")
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return errors.Wrap(err, "failed send email")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "failed send email")
+	}
+	return c.Quit()
+}
+
+func sendWebhook(msg Message) error {
+	data, err := utils.Json.Marshal(msg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	res, err := webhookClient.Post(setting.GetByKey(conf.NotifyWebhook), "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "failed post webhook")
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errors.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
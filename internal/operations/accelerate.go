@@ -0,0 +1,211 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultStallTimeout = 10 * time.Second
+	probeTimeout        = 5 * time.Second
+	probeExpiration     = 5 * time.Minute
+)
+
+type endpointLatency struct {
+	latency time.Duration // -1 means unreachable
+	at      time.Time
+}
+
+// probed latencies keyed by host, so the links of the same endpoint share the result
+var endpointLatencies generic_sync.MapOf[string, endpointLatency]
+
+func probeEndpoint(ctx context.Context, u string, link *model.Link) time.Duration {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return -1
+	}
+	if l, ok := endpointLatencies.Load(parsed.Host); ok && time.Since(l.at) < probeExpiration {
+		return l.latency
+	}
+	latency := time.Duration(-1)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err == nil {
+		for h, val := range link.Header {
+			req.Header[h] = val
+		}
+		client := &http.Client{Transport: net.Client(link.Network).Transport, Timeout: probeTimeout}
+		start := time.Now()
+		res, err := client.Do(req)
+		if err == nil {
+			_ = res.Body.Close()
+			if res.StatusCode < 500 {
+				latency = time.Since(start)
+			}
+		}
+	}
+	endpointLatencies.Store(parsed.Host, endpointLatency{latency: latency, at: time.Now()})
+	return latency
+}
+
+// accelerateLink probe all endpoints of the link, then use the fastest one as URL
+// and keep the others as mirrors for switching over
+func accelerateLink(ctx context.Context, storage driver.Driver, link *model.Link) *model.Link {
+	// nothing to switch over to without mirrors, so no need to watch the transfer
+	if !storage.GetStorage().Accelerate || link.URL == "" || len(link.Mirrors) == 0 {
+		return link
+	}
+	accelerated := *link
+	accelerated.StallTimeout = time.Duration(storage.GetStorage().StallTimeout) * time.Second
+	if accelerated.StallTimeout <= 0 {
+		accelerated.StallTimeout = defaultStallTimeout
+	}
+	urls := append([]string{link.URL}, link.Mirrors...)
+	latencies := make([]time.Duration, len(urls))
+	var wg sync.WaitGroup
+	for i := range urls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			latencies[i] = probeEndpoint(ctx, urls[i], link)
+		}(i)
+	}
+	wg.Wait()
+	idx := make([]int, len(urls))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		li, lj := latencies[idx[i]], latencies[idx[j]]
+		if li < 0 || lj < 0 {
+			return lj < 0 && li >= 0
+		}
+		return li < lj
+	})
+	sorted := make([]string, len(urls))
+	for i, v := range idx {
+		sorted[i] = urls[v]
+	}
+	accelerated.URL = sorted[0]
+	accelerated.Mirrors = sorted[1:]
+	return &accelerated
+}
+
+type mirrorReader struct {
+	ctx     context.Context
+	client  *http.Client
+	link    *model.Link
+	urls    []string // mirrors not tried yet
+	body    io.ReadCloser
+	offset  int64 // absolute offset of the next byte
+	end     int64 // the last byte wanted, -1 means to the end
+	stalled int32
+}
+
+// NewMirrorReader read the body of a response from the link, and continue
+// from the mirrors of the link if it stalls. res must be a 200 or 206 response.
+func NewMirrorReader(ctx context.Context, client *http.Client, link *model.Link, res *http.Response) io.ReadCloser {
+	if link.StallTimeout <= 0 || len(link.Mirrors) == 0 {
+		return res.Body
+	}
+	r := &mirrorReader{
+		ctx:    ctx,
+		client: client,
+		link:   link,
+		urls:   link.Mirrors,
+		body:   res.Body,
+		end:    -1,
+	}
+	if res.StatusCode == http.StatusPartialContent {
+		var size string
+		if _, err := fmt.Sscanf(res.Header.Get("Content-Range"), "bytes %d-%d/%s", &r.offset, &r.end, &size); err != nil {
+			// can't resume from the others without knowing the range
+			return res.Body
+		}
+	}
+	return r
+}
+
+func (r *mirrorReader) Read(p []byte) (int, error) {
+	for {
+		body := r.body
+		timer := time.AfterFunc(r.link.StallTimeout, func() {
+			atomic.StoreInt32(&r.stalled, 1)
+			_ = body.Close()
+		})
+		n, err := body.Read(p)
+		timer.Stop()
+		r.offset += int64(n)
+		if atomic.SwapInt32(&r.stalled, 0) == 0 {
+			return n, err
+		}
+		log.Warnf("transfer of %s stalled at %d, switching endpoint", r.link.URL, r.offset)
+		if err := r.next(); err != nil {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// next open the next mirror from the current offset
+func (r *mirrorReader) next() error {
+	for len(r.urls) > 0 {
+		u := r.urls[0]
+		r.urls = r.urls[1:]
+		req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, u, nil)
+		if err != nil {
+			continue
+		}
+		for h, val := range r.link.Header {
+			req.Header[h] = val
+		}
+		if r.end >= 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.offset, r.end))
+		} else {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+		}
+		res, err := r.client.Do(req)
+		if err != nil {
+			log.Warnf("failed to switch to %s: %+v", u, err)
+			continue
+		}
+		if res.StatusCode != http.StatusPartialContent {
+			_ = res.Body.Close()
+			log.Warnf("failed to switch to %s: status %d", u, res.StatusCode)
+			continue
+		}
+		r.body = res.Body
+		return nil
+	}
+	return errors.New("transfer stalled and no more endpoint to switch to")
+}
+
+func (r *mirrorReader) Close() error {
+	return r.body.Close()
+}
+
+// networkLink set the network of the storage for fetching the link
+func networkLink(storage driver.Driver, link *model.Link) *model.Link {
+	network := storage.GetStorage().ClientNetwork()
+	if network == (model.Network{}) || link.URL == "" {
+		return link
+	}
+	l := *link
+	l.Network = network
+	return &l
+}
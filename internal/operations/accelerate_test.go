@@ -0,0 +1,62 @@
+package operations_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+func TestMirrorReader(t *testing.T) {
+	const content = "hello world"
+	release := make(chan struct{})
+	stalling := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content[:5]))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer stalling.Close()
+	defer close(release)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "bytes=5-" {
+			t.Errorf("expected the mirror is read from the stalled offset, got range %q", r.Header.Get("Range"))
+		}
+		w.Header().Set("Content-Range", "bytes 5-10/11")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte(content[5:]))
+	}))
+	defer mirror.Close()
+
+	link := &model.Link{URL: stalling.URL, StallTimeout: 200 * time.Millisecond}
+	res, err := http.Get(stalling.URL)
+	if err != nil {
+		t.Fatalf("failed get: %+v", err)
+	}
+	if r := operations.NewMirrorReader(context.Background(), http.DefaultClient, link, res); r != res.Body {
+		t.Errorf("expected the body is not watched without mirrors")
+	}
+	_ = res.Body.Close()
+
+	link.Mirrors = []string{mirror.URL}
+	res, err = http.Get(stalling.URL)
+	if err != nil {
+		t.Fatalf("failed get: %+v", err)
+	}
+	r := operations.NewMirrorReader(context.Background(), http.DefaultClient, link, res)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed read: %+v", err)
+	}
+	if string(data) != content {
+		t.Errorf("expected %q, got %q", content, data)
+	}
+}
@@ -0,0 +1,88 @@
+package operations
+
+import (
+	"context"
+	stdpath "path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the max count of aliases resolved for one path
+const maxAliasDepth = 8
+
+type aliasKey struct{}
+
+// EnterAlias record the alias storage resolving the path in ctx,
+// return errs.AliasLoop if it's entered already, which means the aliases form a loop
+func EnterAlias(ctx context.Context, mountPath string) (context.Context, error) {
+	entered, _ := ctx.Value(aliasKey{}).([]string)
+	if len(entered) >= maxAliasDepth || utils.SliceContains(entered, mountPath) {
+		return nil, errors.Wrapf(errs.AliasLoop, "%s -> %s", strings.Join(entered, " -> "), mountPath)
+	}
+	// copy to not share the backing array between branches
+	next := make([]string, len(entered), len(entered)+1)
+	copy(next, entered)
+	return context.WithValue(ctx, aliasKey{}, append(next, mountPath)), nil
+}
+
+// aliasChainKey the key of the calls coalesced under the aliases entered, a call looping back
+// through the aliases must not wait for the call of the same path it's made by, but fail to enter
+func aliasChainKey(ctx context.Context, key string) string {
+	entered, _ := ctx.Value(aliasKey{}).([]string)
+	if len(entered) == 0 {
+		return key
+	}
+	return key + "\x00" + strings.Join(entered, "\x00")
+}
+
+// getAliasPath get the virtual path mounted by the alias storage, the path of a lazy one
+// not initialized yet is read from the addition
+func getAliasPath(storageDriver driver.Driver) (string, bool) {
+	if d, ok := storageDriver.(*lazyDriver); ok {
+		if _, ok := d.Driver.(driver.Alias); !ok {
+			return "", false
+		}
+		var addition struct {
+			Path string `json:"path"`
+		}
+		if err := utils.Json.UnmarshalFromString(d.storage.Addition, &addition); err != nil {
+			return "", false
+		}
+		return utils.StandardizePath(addition.Path), true
+	}
+	alias, ok := storageDriver.(driver.Alias)
+	if !ok {
+		return "", false
+	}
+	return alias.GetAliasPath(), true
+}
+
+// CheckAliasLoop follow the aliases from the target path,
+// return errs.AliasLoop if it goes back to the alias at mountPath
+func CheckAliasLoop(mountPath, target string) error {
+	mountPath = utils.GetActualVirtualPath(utils.StandardizePath(mountPath))
+	path := utils.StandardizePath(target)
+	chain := []string{mountPath}
+	for i := 0; i < maxAliasDepth; i++ {
+		chain = append(chain, path)
+		if utils.IsSubPath(mountPath, path) {
+			return errors.Wrap(errs.AliasLoop, strings.Join(chain, " -> "))
+		}
+		storages := getStoragesByPath(path)
+		if len(storages) == 0 {
+			return nil
+		}
+		// the members of a balance group mount the same path, so only check the primary
+		aliasPath, ok := getAliasPath(storages[0])
+		if !ok {
+			return nil
+		}
+		virtualPath := utils.GetActualVirtualPath(storages[0].GetStorage().MountPath)
+		path = stdpath.Join(aliasPath, strings.TrimPrefix(path, virtualPath))
+	}
+	return errors.Wrapf(errs.AliasLoop, "too many aliases: %s", strings.Join(chain, " -> "))
+}
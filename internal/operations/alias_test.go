@@ -0,0 +1,39 @@
+package operations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/pkg/singleflight"
+	"github.com/pkg/errors"
+)
+
+func TestCoalesceAliasLoop(t *testing.T) {
+	var g singleflight.Group[int]
+	// the call of the path through the alias comes back to the same path
+	var call func(ctx context.Context) (int, error)
+	call = func(ctx context.Context) (int, error) {
+		return coalesce(ctx, &g, "/a/x", func(ctx context.Context) (int, error) {
+			ctx, err := EnterAlias(ctx, "/a")
+			if err != nil {
+				return 0, err
+			}
+			return call(ctx)
+		})
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := call(context.Background())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(errors.Cause(err), errs.AliasLoop) {
+			t.Errorf("expected alias loop, got %+v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the call looping back waits for itself")
+	}
+}
@@ -0,0 +1,355 @@
+package operations
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/pkg/utils/random"
+	"github.com/pkg/errors"
+)
+
+const (
+	ApplyCreate = "create"
+	ApplyUpdate = "update"
+	ApplyDelete = "delete"
+)
+
+const ConfigStorages = "storages"
+
+// State the declared state of the instance. the sections not declared (null) are left untouched,
+// the items not declared in a declared section are deleted, except the admin, the guest and the settings
+type State struct {
+	Storages []StorageState    `json:"storages"`
+	Users    []model.User      `json:"users"`
+	Metas    []model.Meta      `json:"metas"`
+	Settings map[string]string `json:"settings"`
+}
+
+// StorageState the addition is declared as an object, the fields not declared keep their values,
+// such as the tokens rotated by the driver
+type StorageState struct {
+	model.Storage
+	Addition map[string]interface{} `json:"addition"`
+}
+
+// Change a step of the plan to converge the instance to the state
+type Change struct {
+	Section string `json:"section"`
+	// Key the mount path, the username, the path of the meta or the key of the setting
+	Key    string `json:"key"`
+	Action string `json:"action"`
+	// Fields changed by the update, the values are not shown, they may be secrets
+	Fields []string `json:"fields,omitempty"`
+	Error  string   `json:"error,omitempty"`
+	// Password generated for the user created without password
+	Password string `json:"password,omitempty"`
+
+	apply func(ctx context.Context) error
+}
+
+// the fields maintained by alist, never declared
+var runtimeFields = map[string]bool{
+	"id": true, "status": true, "modified": true, "init_attempts": true, "last_error": true, "on_backup": true,
+}
+
+// diffFields the json fields which differ between the two values
+func diffFields(old, new interface{}) ([]string, error) {
+	var oldMap, newMap map[string]interface{}
+	for _, v := range []struct {
+		src interface{}
+		dst *map[string]interface{}
+	}{{old, &oldMap}, {new, &newMap}} {
+		bytes, err := utils.Json.Marshal(v.src)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := utils.Json.Unmarshal(bytes, v.dst); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	var fields []string
+	for k, v := range newMap {
+		if !runtimeFields[k] && !reflect.DeepEqual(oldMap[k], v) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// PlanState compute the changes to converge the instance to the state, nothing is changed.
+// an error is returned if the state is invalid, such as an unknown driver or setting
+func PlanState(state State) ([]Change, error) {
+	changes, err := planState(state)
+	for i := range changes {
+		// not created yet
+		changes[i].Password = ""
+	}
+	return changes, err
+}
+
+func planState(state State) ([]Change, error) {
+	var changes []Change
+	for _, plan := range []func(State) ([]Change, error){planSettings, planMetas, planUsers, planStorages} {
+		c, err := plan(state)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	return changes, nil
+}
+
+// ApplyState plan and apply the changes, the failure of one change doesn't stop the others
+func ApplyState(ctx context.Context, state State) ([]Change, error) {
+	changes, err := planState(state)
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		if err := changes[i].apply(ctx); err != nil {
+			changes[i].Error = err.Error()
+			changes[i].Password = ""
+		}
+	}
+	return changes, nil
+}
+
+func planSettings(state State) ([]Change, error) {
+	keys := make([]string, 0, len(state.Settings))
+	for key := range state.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var changes []Change
+	for _, key := range keys {
+		item, err := db.GetSettingItemByKey(key)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed get setting [%s]", key)
+		}
+		if item.Flag == model.READONLY || item.IsDeprecated() || instanceSettings[key] {
+			return nil, errors.Errorf("setting [%s] can't be declared", key)
+		}
+		if item.Value == state.Settings[key] {
+			continue
+		}
+		item.Value = state.Settings[key]
+		changes = append(changes, Change{Section: ConfigSettings, Key: key, Action: ApplyUpdate, Fields: []string{"value"},
+			apply: func(ctx context.Context) error {
+				return db.SaveSettingItem(*item)
+			}})
+	}
+	return changes, nil
+}
+
+func planMetas(state State) ([]Change, error) {
+	if state.Metas == nil {
+		return nil, nil
+	}
+	olds, err := db.GetAllMetas()
+	if err != nil {
+		return nil, err
+	}
+	oldMap := make(map[string]model.Meta, len(olds))
+	for _, old := range olds {
+		oldMap[old.Path] = old
+	}
+	var changes []Change
+	declared := make(map[string]bool, len(state.Metas))
+	for _, meta := range state.Metas {
+		meta := meta
+		meta.Path = utils.StandardizePath(meta.Path)
+		if declared[meta.Path] {
+			return nil, errors.Errorf("meta [%s] is declared twice", meta.Path)
+		}
+		declared[meta.Path] = true
+		old, ok := oldMap[meta.Path]
+		if !ok {
+			meta.ID = 0
+			changes = append(changes, Change{Section: ConfigMetas, Key: meta.Path, Action: ApplyCreate,
+				apply: func(ctx context.Context) error {
+					return db.CreateMeta(&meta)
+				}})
+			continue
+		}
+		meta.ID = old.ID
+		fields, err := diffFields(old, meta)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		changes = append(changes, Change{Section: ConfigMetas, Key: meta.Path, Action: ApplyUpdate, Fields: fields,
+			apply: func(ctx context.Context) error {
+				return db.UpdateMeta(&meta)
+			}})
+	}
+	for _, old := range olds {
+		if declared[old.Path] {
+			continue
+		}
+		id := old.ID
+		changes = append(changes, Change{Section: ConfigMetas, Key: old.Path, Action: ApplyDelete,
+			apply: func(ctx context.Context) error {
+				return db.DeleteMetaById(id)
+			}})
+	}
+	return changes, nil
+}
+
+// planUsers the users are matched by username, the roles can't be changed,
+// the passwords not declared are kept or generated for the new users
+func planUsers(state State) ([]Change, error) {
+	if state.Users == nil {
+		return nil, nil
+	}
+	olds, err := db.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+	oldMap := make(map[string]model.User, len(olds))
+	for _, old := range olds {
+		oldMap[old.Username] = old
+	}
+	var changes []Change
+	declared := make(map[string]bool, len(state.Users))
+	for _, user := range state.Users {
+		user := user
+		if user.Username == "" {
+			return nil, errors.New("a user is declared without username")
+		}
+		if declared[user.Username] {
+			return nil, errors.Errorf("user [%s] is declared twice", user.Username)
+		}
+		declared[user.Username] = true
+		old, ok := oldMap[user.Username]
+		if !ok {
+			if user.Role != model.GENERAL {
+				return nil, errors.Errorf("user [%s] can't be created as admin or guest", user.Username)
+			}
+			user.ID = 0
+			change := Change{Section: ConfigUsers, Key: user.Username, Action: ApplyCreate}
+			if user.Password == "" {
+				user.Password = random.String(16)
+				change.Password = user.Password
+			}
+			change.apply = func(ctx context.Context) error {
+				return db.CreateUser(&user)
+			}
+			changes = append(changes, change)
+			continue
+		}
+		user.ID, user.Role = old.ID, old.Role
+		if user.Password == "" {
+			user.Password = old.Password
+		}
+		fields, err := diffFields(old, user)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		changes = append(changes, Change{Section: ConfigUsers, Key: user.Username, Action: ApplyUpdate, Fields: fields,
+			apply: func(ctx context.Context) error {
+				return db.UpdateUser(&user)
+			}})
+	}
+	for _, old := range olds {
+		if declared[old.Username] || old.IsAdmin() || old.IsGuest() {
+			continue
+		}
+		id := old.ID
+		changes = append(changes, Change{Section: ConfigUsers, Key: old.Username, Action: ApplyDelete,
+			apply: func(ctx context.Context) error {
+				return db.DeleteUserById(id)
+			}})
+	}
+	return changes, nil
+}
+
+// planStorages the storages are matched by mount path, the drivers can't be changed
+func planStorages(state State) ([]Change, error) {
+	if state.Storages == nil {
+		return nil, nil
+	}
+	olds, err := db.GetAllStorages()
+	if err != nil {
+		return nil, err
+	}
+	oldMap := make(map[string]model.Storage, len(olds))
+	for _, old := range olds {
+		oldMap[old.MountPath] = old
+	}
+	var changes []Change
+	declared := make(map[string]bool, len(state.Storages))
+	for _, s := range state.Storages {
+		storage := s.Storage
+		storage.MountPath = utils.StandardizePath(storage.MountPath)
+		if declared[storage.MountPath] {
+			return nil, errors.Errorf("storage [%s] is declared twice", storage.MountPath)
+		}
+		declared[storage.MountPath] = true
+		if _, err := GetDriverNew(storage.Driver); err != nil {
+			return nil, errors.WithMessagef(err, "storage [%s]", storage.MountPath)
+		}
+		addition := map[string]interface{}{}
+		old, ok := oldMap[storage.MountPath]
+		if ok {
+			if old.Driver != storage.Driver {
+				return nil, errors.Errorf("the driver of storage [%s] can't be changed from %s to %s", storage.MountPath, old.Driver, storage.Driver)
+			}
+			if old.Addition != "" {
+				if err := utils.Json.UnmarshalFromString(old.Addition, &addition); err != nil {
+					return nil, errors.Wrapf(err, "failed unmarshal the addition of storage [%s]", storage.MountPath)
+				}
+			}
+			// compared with the keys sorted
+			if old.Addition, err = utils.Json.MarshalToString(addition); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		for k, v := range s.Addition {
+			addition[k] = v
+		}
+		if storage.Addition, err = utils.Json.MarshalToString(addition); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if !ok {
+			storage.ID = 0
+			changes = append(changes, Change{Section: ConfigStorages, Key: storage.MountPath, Action: ApplyCreate,
+				apply: func(ctx context.Context) error {
+					return CreateStorage(ctx, storage)
+				}})
+			continue
+		}
+		storage.ID = old.ID
+		fields, err := diffFields(old, storage)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		changes = append(changes, Change{Section: ConfigStorages, Key: storage.MountPath, Action: ApplyUpdate, Fields: fields,
+			apply: func(ctx context.Context) error {
+				return UpdateStorage(ctx, storage)
+			}})
+	}
+	for _, old := range olds {
+		if declared[old.MountPath] {
+			continue
+		}
+		id := old.ID
+		changes = append(changes, Change{Section: ConfigStorages, Key: old.MountPath, Action: ApplyDelete,
+			apply: func(ctx context.Context) error {
+				return DeleteStorageById(ctx, id)
+			}})
+	}
+	return changes, nil
+}
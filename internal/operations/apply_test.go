@@ -0,0 +1,53 @@
+package operations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+func TestApplyState(t *testing.T) {
+	root, _ := operations.CreateLocal(t, model.Storage{MountPath: "/apply/old"})
+	states, err := db.GetAllStorages()
+	if err != nil {
+		t.Fatalf("failed get storages: %+v", err)
+	}
+	// keep the other storages of the tests, only /apply/old is removed
+	state := operations.State{Storages: []operations.StorageState{}}
+	for _, s := range states {
+		if s.MountPath == "/apply/old" {
+			continue
+		}
+		var addition map[string]interface{}
+		_ = utils.Json.UnmarshalFromString(s.Addition, &addition)
+		state.Storages = append(state.Storages, operations.StorageState{Storage: s, Addition: addition})
+	}
+	state.Storages = append(state.Storages, operations.StorageState{
+		Storage:  model.Storage{Driver: "Local", MountPath: "/apply/new"},
+		Addition: map[string]interface{}{"root_folder": root},
+	})
+	changes, err := operations.PlanState(state)
+	if err != nil {
+		t.Fatalf("failed plan: %+v", err)
+	}
+	if len(changes) != 2 || changes[0].Action != operations.ApplyCreate || changes[1].Action != operations.ApplyDelete {
+		t.Fatalf("unexpected plan: %+v", changes)
+	}
+	if _, err := operations.ApplyState(context.Background(), state); err != nil {
+		t.Fatalf("failed apply: %+v", err)
+	}
+	if _, err := db.GetStorageByMountPath("/apply/old"); err == nil {
+		t.Errorf("expected the storage not declared is deleted")
+	}
+	changes, err = operations.PlanState(state)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("expected the instance converged, got %+v %+v", changes, err)
+	}
+	if s, err := db.GetStorageByMountPath("/apply/new"); err == nil {
+		_ = operations.DeleteStorageById(context.Background(), s.ID)
+	}
+}
@@ -0,0 +1,371 @@
+package operations
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	BalanceRoundRobin = "round_robin"
+	BalanceFastest    = "fastest"
+	BalanceSticky     = "sticky"
+	// the same client goes to the same member, the requests without a client stick to the path
+	BalanceStickyIP = "sticky_ip"
+	// read by round robin, and upload to the member with the most free space
+	BalanceMostFree = "most_free"
+)
+
+const (
+	// a member will be removed from the rotation after these consecutive failures
+	balanceFailThreshold = 3
+	// and after the cool-down, a single call is routed to it as a trial,
+	// it's re-admitted if the trial succeeds, or removed for another cool-down
+	balanceCoolDown = 5 * time.Minute
+	// another trial is made if the result of the last one isn't reported in time
+	balanceTrialTimeout = 30 * time.Second
+	// the latency sample older than this is expired, so the member will be probed again
+	latencyExpiration = time.Minute
+	// the weight of the new sample in the moving average of latency
+	latencyWeight = 0.3
+)
+
+type memberHealth struct {
+	sync.Mutex
+	failures      int
+	lastError     string
+	disabledUntil time.Time
+	trialUntil    time.Time     // the trial call is running until then
+	latency       time.Duration // moving average of read calls
+	latencyAt     time.Time
+}
+
+// removed tell whether the member is out of the rotation, including the one waiting for a trial
+func (h *memberHealth) removed() bool {
+	return h.failures >= balanceFailThreshold
+}
+
+// mount path => health of the storage
+var healthMap generic_sync.MapOf[string, *memberHealth]
+
+// isStorageError whether the error is caused by the storage itself,
+// e.g. auth expired or quota exceeded, not by the request
+func isStorageError(err error) bool {
+	if err == nil {
+		return false
+	}
+	cause := errors.Cause(err)
+	for _, e := range []error{errs.ObjectNotFound, errs.NotFolder, errs.NotFile, errs.NotSupport,
+		errs.NotImplement, errs.OperationDisabled, errs.StorageReadOnly, context.Canceled} {
+		if errors.Is(cause, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// reportResult record the result of driver call to the health of the storage,
+// the probe redoes the call to tell whether the account is available again
+func reportResult(storage driver.Driver, err error, probe ...probeCall) {
+	var p probeCall
+	if len(probe) > 0 {
+		p = probe[0]
+	}
+	checkFailover(storage, err, p)
+	mountPath := storage.GetStorage().MountPath
+	if err == nil {
+		if h, ok := healthMap.Load(mountPath); ok {
+			h.Lock()
+			if h.removed() {
+				log.Infof("storage [%s] succeeded, re-admit it to balance rotation", mountPath)
+			}
+			h.failures = 0
+			h.lastError = ""
+			h.disabledUntil = time.Time{}
+			h.trialUntil = time.Time{}
+			h.Unlock()
+		}
+		return
+	}
+	if !isStorageError(err) {
+		return
+	}
+	h, _ := healthMap.LoadOrStore(mountPath, &memberHealth{})
+	h.Lock()
+	defer h.Unlock()
+	h.failures++
+	h.lastError = err.Error()
+	h.trialUntil = time.Time{}
+	if h.removed() {
+		h.disabledUntil = time.Now().Add(balanceCoolDown)
+		log.Warnf("storage [%s] failed %d times, remove it from balance rotation until %s",
+			mountPath, h.failures, h.disabledUntil.Format(time.RFC3339))
+	}
+}
+
+// isHealthy tell whether the member isn't in cool-down, the one waiting for a trial is
+func isHealthy(mountPath string) bool {
+	h, ok := healthMap.Load(mountPath)
+	if !ok {
+		return true
+	}
+	h.Lock()
+	defer h.Unlock()
+	return time.Now().After(h.disabledUntil)
+}
+
+// admit tell whether the member is in the rotation, and whether the call is its trial
+func admit(mountPath string) (bool, bool) {
+	h, ok := healthMap.Load(mountPath)
+	if !ok {
+		return true, false
+	}
+	h.Lock()
+	defer h.Unlock()
+	if !h.removed() {
+		return true, false
+	}
+	now := time.Now()
+	if now.Before(h.disabledUntil) || now.Before(h.trialUntil) {
+		return false, false
+	}
+	h.trialUntil = now.Add(balanceTrialTimeout)
+	return true, true
+}
+
+// filterHealthy remove the members in cool-down, if all are unhealthy, return all of them.
+// a member whose cool-down is over is the only one returned for its trial
+func filterHealthy(storages []driver.Driver) []driver.Driver {
+	res := make([]driver.Driver, 0, len(storages))
+	for _, storage := range storages {
+		admitted, trial := admit(storage.GetStorage().MountPath)
+		if trial {
+			return []driver.Driver{storage}
+		}
+		if admitted {
+			res = append(res, storage)
+		}
+	}
+	if len(res) == 0 {
+		return storages
+	}
+	return res
+}
+
+// reportLatency record the duration of a succeeded read call to the storage
+func reportLatency(storage driver.Driver, d time.Duration) {
+	h, _ := healthMap.LoadOrStore(storage.GetStorage().MountPath, &memberHealth{})
+	h.Lock()
+	defer h.Unlock()
+	if h.latency == 0 || time.Since(h.latencyAt) > latencyExpiration {
+		h.latency = d
+	} else {
+		h.latency = time.Duration(latencyWeight*float64(d) + (1-latencyWeight)*float64(h.latency))
+	}
+	h.latencyAt = time.Now()
+}
+
+// getLatency return 0 if there is no recent sample
+func getLatency(mountPath string) time.Duration {
+	h, ok := healthMap.Load(mountPath)
+	if !ok {
+		return 0
+	}
+	h.Lock()
+	defer h.Unlock()
+	if time.Since(h.latencyAt) > latencyExpiration {
+		return 0
+	}
+	return h.latency
+}
+
+// pickFastest return the index of the member with the lowest recent latency,
+// the members without recent sample are picked first to probe them
+func pickFastest(storages []driver.Driver) int {
+	best, bestLatency := 0, time.Duration(-1)
+	for i, storage := range storages {
+		latency := getLatency(storage.GetStorage().MountPath)
+		if bestLatency < 0 || latency < bestLatency {
+			best, bestLatency = i, latency
+		}
+	}
+	return best
+}
+
+// pickSticky return the index of the member for the key, the path or client ip, by rendezvous hashing,
+// so the same key always goes to the same member, and only the keys of
+// a removed member are moved to the others
+func pickSticky(storages []driver.Driver, key string) int {
+	best, bestScore := 0, uint64(0)
+	for i, storage := range storages {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(storage.GetStorage().MountPath))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(key))
+		if score := h.Sum64(); i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// virtual path of balance group => mount path of the pinned member
+var pinnedMap generic_sync.MapOf[string, string]
+
+// pickPinned return the index of the pinned member, or -1 if not pinned or it's not in the storages
+func pickPinned(storages []driver.Driver) int {
+	virtualPath := utils.GetActualVirtualPath(storages[0].GetStorage().MountPath)
+	pinned, ok := pinnedMap.Load(virtualPath)
+	if !ok {
+		return -1
+	}
+	for i, storage := range storages {
+		if storage.GetStorage().MountPath == pinned {
+			return i
+		}
+	}
+	return -1
+}
+
+type BalanceMember struct {
+	MountPath     string        `json:"mount_path"`
+	Status        string        `json:"status"`
+	Healthy       bool          `json:"healthy"`
+	Failures      int           `json:"failures"`
+	LastError     string        `json:"last_error"`
+	DisabledUntil *time.Time    `json:"disabled_until"`
+	Latency       time.Duration `json:"latency"` // 0 if there is no recent sample
+}
+
+type BalanceGroup struct {
+	Path    string          `json:"path"`
+	Policy  string          `json:"policy"`
+	Index   int             `json:"index"` // the member picked by round robin last time, -1 if none
+	Pinned  string          `json:"pinned"`
+	Members []BalanceMember `json:"members"`
+}
+
+// GetBalanceGroups return the state of all groups with more than one member
+func GetBalanceGroups() []BalanceGroup {
+	groups := make(map[string][]driver.Driver)
+	storagesMap.Range(func(key string, value driver.Driver) bool {
+		virtualPath := utils.GetActualVirtualPath(value.GetStorage().MountPath)
+		groups[virtualPath] = append(groups[virtualPath], value)
+		return true
+	})
+	res := make([]BalanceGroup, 0)
+	for virtualPath, members := range groups {
+		if len(members) < 2 {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool {
+			return members[i].GetStorage().MountPath < members[j].GetStorage().MountPath
+		})
+		group := BalanceGroup{
+			Path:   virtualPath,
+			Policy: members[0].GetStorage().BalancePolicy,
+			Index:  -1,
+		}
+		if group.Policy == "" {
+			group.Policy = BalanceRoundRobin
+		}
+		if i, ok := balanceMap.Load(virtualPath); ok {
+			group.Index = i
+		}
+		group.Pinned, _ = pinnedMap.Load(virtualPath)
+		for _, member := range members {
+			group.Members = append(group.Members, getBalanceMember(member))
+		}
+		res = append(res, group)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res
+}
+
+func getBalanceMember(storage driver.Driver) BalanceMember {
+	mountPath := storage.GetStorage().MountPath
+	member := BalanceMember{
+		MountPath: mountPath,
+		Status:    storage.GetStorage().Status,
+		Healthy:   isHealthy(mountPath),
+		Latency:   getLatency(mountPath),
+	}
+	if h, ok := healthMap.Load(mountPath); ok {
+		h.Lock()
+		member.Failures = h.failures
+		member.LastError = h.lastError
+		if time.Now().Before(h.disabledUntil) {
+			disabledUntil := h.disabledUntil
+			member.DisabledUntil = &disabledUntil
+		}
+		h.Unlock()
+	}
+	return member
+}
+
+func getBalanceMembers(path string) []driver.Driver {
+	virtualPath := utils.GetActualVirtualPath(utils.StandardizePath(path))
+	var members []driver.Driver
+	storagesMap.Range(func(key string, value driver.Driver) bool {
+		if utils.GetActualVirtualPath(value.GetStorage().MountPath) == virtualPath {
+			members = append(members, value)
+		}
+		return true
+	})
+	return members
+}
+
+// ResetBalance clear the rotation index, the pinned member
+// and the health of the members of the group
+func ResetBalance(path string) error {
+	members := getBalanceMembers(path)
+	if len(members) == 0 {
+		return errors.Errorf("no balance group at %s", path)
+	}
+	virtualPath := utils.GetActualVirtualPath(members[0].GetStorage().MountPath)
+	balanceMap.Delete(virtualPath)
+	pinnedMap.Delete(virtualPath)
+	for _, member := range members {
+		healthMap.Delete(member.GetStorage().MountPath)
+	}
+	return nil
+}
+
+// PinBalance make the group always use the member, an empty mountPath unpins it
+func PinBalance(path, mountPath string) error {
+	members := getBalanceMembers(path)
+	if len(members) == 0 {
+		return errors.Errorf("no balance group at %s", path)
+	}
+	virtualPath := utils.GetActualVirtualPath(members[0].GetStorage().MountPath)
+	if mountPath == "" {
+		pinnedMap.Delete(virtualPath)
+		return nil
+	}
+	for _, member := range members {
+		if member.GetStorage().MountPath == mountPath {
+			pinnedMap.Store(virtualPath, mountPath)
+			return nil
+		}
+	}
+	return errors.Errorf("%s is not a member of balance group %s", mountPath, virtualPath)
+}
+
+// unpinMember unpin the group if the member is pinned, when it's deleted or its mount path is changed
+func unpinMember(mountPath string) {
+	virtualPath := utils.GetActualVirtualPath(mountPath)
+	if pinned, ok := pinnedMap.Load(virtualPath); ok && pinned == mountPath {
+		pinnedMap.Delete(virtualPath)
+	}
+}
@@ -0,0 +1,99 @@
+package operations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func mountPaths(storages []driver.Driver) []string {
+	var res []string
+	for _, storage := range storages {
+		res = append(res, storage.GetStorage().MountPath)
+	}
+	return res
+}
+
+func TestBalanceTrial(t *testing.T) {
+	a := &throttledDriver{storage: model.Storage{MountPath: "/health"}}
+	b := &throttledDriver{storage: model.Storage{MountPath: "/health.balance1"}}
+	members := []driver.Driver{a, b}
+	defer healthMap.Delete("/health.balance1")
+	expect := func(expected ...string) {
+		t.Helper()
+		got := mountPaths(filterHealthy(members))
+		if len(got) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Fatalf("expected %v, got %v", expected, got)
+			}
+		}
+	}
+	coolDown := func() {
+		h, _ := healthMap.Load("/health.balance1")
+		h.Lock()
+		h.disabledUntil = time.Now().Add(-time.Second)
+		h.Unlock()
+	}
+	for i := 0; i < balanceFailThreshold; i++ {
+		reportResult(b, errors.New("quota exceeded"))
+	}
+	expect("/health")
+	// the cool-down is over, a single call is made as the trial
+	coolDown()
+	expect("/health.balance1")
+	expect("/health")
+	// the trial failed, so it's removed for another cool-down
+	reportResult(b, errors.New("quota exceeded"))
+	expect("/health")
+	coolDown()
+	expect("/health.balance1")
+	reportResult(b, nil)
+	expect("/health", "/health.balance1")
+	// the failures before the success are forgotten
+	reportResult(b, errors.New("quota exceeded"))
+	expect("/health", "/health.balance1")
+}
+
+func TestStickyIPBalance(t *testing.T) {
+	for _, mountPath := range []string{"/sticky_ip", "/sticky_ip.balance1", "/sticky_ip.balance2"} {
+		createLocal(t, model.Storage{MountPath: mountPath, BalancePolicy: BalanceStickyIP})
+	}
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		ctx := context.WithValue(context.Background(), utils.ClientIPKey, ip)
+		first := GetBalancedStorage(ctx, "/sticky_ip/a.mp4").GetStorage().MountPath
+		for _, path := range []string{"/sticky_ip/b.mp4", "/sticky_ip/c.mp4", "/sticky_ip/d.mp4"} {
+			if cur := GetBalancedStorage(ctx, path).GetStorage().MountPath; cur != first {
+				t.Errorf("%s of %s: expected %s, got %s", path, ip, first, cur)
+			}
+		}
+	}
+}
+
+func TestPinUnhealthy(t *testing.T) {
+	createLocal(t, model.Storage{MountPath: "/pin_health"})
+	_, pinned := createLocal(t, model.Storage{MountPath: "/pin_health.balance1"})
+	if err := PinBalance("/pin_health", "/pin_health.balance1"); err != nil {
+		t.Fatalf("failed pin: %+v", err)
+	}
+	defer healthMap.Delete("/pin_health.balance1")
+	for i := 0; i < balanceFailThreshold; i++ {
+		reportResult(pinned, errors.New("quota exceeded"))
+	}
+	if cur := GetBalancedStorage(context.Background(), "/pin_health/a").GetStorage().MountPath; cur != "/pin_health" {
+		t.Errorf("expected the unhealthy pinned member is skipped, got %s", cur)
+	}
+	if err := DeleteStorageById(context.Background(), pinned.GetStorage().ID); err != nil {
+		t.Fatalf("failed delete storage: %+v", err)
+	}
+	if _, ok := pinnedMap.Load("/pin_health"); ok {
+		t.Errorf("expected the pin is cleared with the member")
+	}
+}
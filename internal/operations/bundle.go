@@ -0,0 +1,89 @@
+package operations
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Bundle is a storage with the metas, users and shares for it,
+// used to provision a space for a team or a customer.
+// the access control is given by the base path and permission of the users
+type Bundle struct {
+	Storage model.Storage `json:"storage" binding:"required"`
+	Metas   []model.Meta  `json:"metas"`
+	Users   []model.User  `json:"users"`
+	Shares  []BundleShare `json:"shares"`
+}
+
+// BundleShare a share to create with the bundle
+type BundleShare struct {
+	Path string `json:"path"` // the virtual path, not relative to the base path of the user
+	// the user of the bundle or an existing user creating the share
+	Username      string `json:"username"`
+	SharePassword string `json:"share_password"` // empty means no password
+	ExpireHours   int    `json:"expire_hours"`   // 0 means never expire
+}
+
+// CreateBundle create all records of the bundle in a transaction then init the storage,
+// if the init failed, all records will be deleted. the shares created are returned
+func CreateBundle(ctx context.Context, bundle Bundle) ([]model.Share, error) {
+	storage := bundle.Storage
+	storage.Modified = time.Now()
+	storage.MountPath = utils.StandardizePath(storage.MountPath)
+	driverNew, err := GetDriverNew(storage.Driver)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get driver new")
+	}
+	for i := range bundle.Metas {
+		bundle.Metas[i].Path = utils.StandardizePath(bundle.Metas[i].Path)
+	}
+	for _, user := range bundle.Users {
+		if user.IsAdmin() || user.IsGuest() {
+			return nil, errors.Errorf("admin or guest user can not be created: %s", user.Username)
+		}
+	}
+	shares := make([]db.BundleShare, len(bundle.Shares))
+	for i, s := range bundle.Shares {
+		share := model.Share{
+			Token:   strings.ReplaceAll(uuid.NewString(), "-", ""),
+			Path:    utils.StandardizePath(s.Path),
+			Created: time.Now(),
+		}
+		if s.SharePassword != "" {
+			share.PasswordHash = utils.HashPassword(s.SharePassword)
+		}
+		if s.ExpireHours > 0 {
+			expires := share.Created.Add(time.Duration(s.ExpireHours) * time.Hour)
+			share.Expires = &expires
+		}
+		shares[i] = db.BundleShare{Share: share, Username: s.Username}
+	}
+	if err := db.CreateBundle(&storage, bundle.Metas, bundle.Users, shares); err != nil {
+		return nil, errors.WithMessage(err, "failed create bundle in database")
+	}
+	storageDriver := driverNew()
+	initStorage, err := applyCredential(storage)
+	if err == nil {
+		err = storageDriver.Init(ctx, initStorage)
+	}
+	if err != nil {
+		if rbErr := db.DeleteBundle(&storage, bundle.Metas, bundle.Users, shares); rbErr != nil {
+			log.Errorf("failed rollback bundle of storage [%s]: %+v", storage.MountPath, rbErr)
+		}
+		return nil, errors.WithMessage(err, "failed init storage, bundle is rolled back")
+	}
+	storagesMap.Store(storage.MountPath, storageDriver)
+	res := make([]model.Share, len(shares))
+	for i := range shares {
+		res[i] = shares[i].Share
+	}
+	return res, nil
+}
@@ -0,0 +1,36 @@
+package operations
+
+import (
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// GetCapabilities return the operations supported by the driver of the storage
+func GetCapabilities(storage driver.Driver) driver.Capabilities {
+	caps := driver.AllCapabilities()
+	if c, ok := storage.(driver.Capable); ok {
+		caps = c.Capabilities()
+	}
+	if storage.Config().NoUpload {
+		caps.Put = false
+	}
+	_, caps.Append = storage.(driver.Append)
+	_, caps.Patch = storage.(driver.Patch)
+	return caps
+}
+
+// checkCapability return errs.NotSupport with the driver and the operation, rather than calling the driver
+func checkCapability(storage driver.Driver, supported bool, op string) error {
+	if supported {
+		return nil
+	}
+	return errors.WithMessagef(errs.NotSupport, "the %s driver can't %s", storage.Config().Name, op)
+}
+
+// CanCopy tell whether the driver can copy the obj on the server side
+func CanCopy(storage driver.Driver, obj model.Obj) bool {
+	caps := GetCapabilities(storage)
+	return caps.Copy && (caps.CopyDir || !obj.IsDir())
+}
@@ -0,0 +1,28 @@
+package operations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	_, local := operations.CreateLocal(t, model.Storage{MountPath: "/caps/local"})
+	if caps := operations.GetCapabilities(local); !caps.Move || !caps.CopyDir || !caps.Range || !caps.Append || !caps.Patch {
+		t.Errorf("expected the local storage supports all, got %+v", caps)
+	}
+	storage := model.Storage{Driver: "Virtual", MountPath: "/caps/virtual", Addition: `{"root_folder":"/","num_file":1,"num_folder":1,"max_file_size":1,"min_file_size":1}`}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	virtual, err := operations.GetStorageByVirtualPath("/caps/virtual")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	defer operations.DeleteStorageById(context.Background(), virtual.GetStorage().ID)
+	if caps := operations.GetCapabilities(virtual); caps.Range || caps.Append || !caps.Copy {
+		t.Errorf("expected the virtual storage can copy but not range or append, got %+v", caps)
+	}
+}
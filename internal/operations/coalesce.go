@@ -0,0 +1,41 @@
+package operations
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/pkg/singleflight"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// coalesce run fn only once for the concurrent calls with the same key.
+// fn runs with a context that won't be canceled when the caller starting it leaves,
+// so the others waiting for it still get the result
+func coalesce[T any](ctx context.Context, g *singleflight.Group[T], key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	return coalesceOrDiscard(ctx, g, key, fn, nil)
+}
+
+// coalesceOrDiscard is coalesce, and the result of fn started by the caller is passed to discard
+// if the caller has left before it's done, e.g. to close the resources only the caller can use
+func coalesceOrDiscard[T any](ctx context.Context, g *singleflight.Group[T], key string, fn func(ctx context.Context) (T, error), discard func(T)) (T, error) {
+	// only written by the fn started by the caller, read after the result is received
+	var ran bool
+	ch := g.DoChan(aliasChainKey(ctx, key), func() (T, error) {
+		ran = true
+		return fn(utils.WithoutCancel(ctx))
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		if discard != nil {
+			go func() {
+				res := <-ch
+				if ran && res.Err == nil {
+					discard(res.Val)
+				}
+			}()
+		}
+		var zero T
+		return zero, ctx.Err()
+	}
+}
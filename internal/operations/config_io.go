@@ -0,0 +1,306 @@
+package operations
+
+import (
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/pkg/utils/random"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ConflictMerge keep the local values which are not set by the imported ones
+const ConflictMerge = "merge"
+
+// the sections of the config, the storages are exported separately
+const (
+	ConfigSettings = "settings"
+	ConfigMetas    = "metas"
+	ConfigUsers    = "users"
+)
+
+// the settings belong to the instance, never exported or imported
+var instanceSettings = map[string]bool{
+	conf.Token: true,
+}
+
+// Config the settings, metas and users of the site, the roles and permissions are carried by the users
+type Config struct {
+	Settings []model.SettingItem `json:"settings,omitempty"`
+	Metas    []ConfigMeta        `json:"metas,omitempty"`
+	Users    []model.User        `json:"users,omitempty"`
+}
+
+// ConfigMeta the meta exported, the password is not exported but whether it's set
+type ConfigMeta struct {
+	model.Meta
+	PasswordSet bool `json:"password_set,omitempty"`
+}
+
+type ConfigExportOptions struct {
+	// Sections to export, all if empty
+	Sections []string `json:"sections" form:"sections"`
+	// WithPrivate export the private settings too, such as the keys of the services
+	WithPrivate bool `json:"with_private" form:"with_private"`
+}
+
+type ConfigImportOptions struct {
+	DryRun bool `json:"dry_run"`
+	// Sections to import, all in the config if empty
+	Sections []string `json:"sections"`
+	// Conflict what to do if the item already exists: skip, overwrite, merge or error
+	Conflict string `json:"conflict"`
+}
+
+type ConfigImportResult struct {
+	Section string `json:"section"`
+	// Key the key of the setting, the path of the meta or the username
+	Key    string `json:"key"`
+	Action string `json:"action"`
+	Error  string `json:"error"`
+	// Password generated for the user created without password, to be handed to the user
+	Password string `json:"password,omitempty"`
+}
+
+func hasSection(sections []string, section string) bool {
+	return len(sections) == 0 || utils.SliceContains(sections, section)
+}
+
+// ExportConfig export the selected sections, the admin and the readonly or deprecated settings are left out,
+// so are the private ones unless asked. the passwords are never exported, the users imported get new ones
+// generated, and the metas protected by password have to be given one to be created
+func ExportConfig(opts ConfigExportOptions) (*Config, error) {
+	var config Config
+	if hasSection(opts.Sections, ConfigSettings) {
+		settings, err := db.GetSettingItems()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed get settings")
+		}
+		for _, item := range settings {
+			if item.Flag == model.READONLY || item.IsDeprecated() || instanceSettings[item.Key] ||
+				(item.Flag == model.PRIVATE && !opts.WithPrivate) {
+				continue
+			}
+			config.Settings = append(config.Settings, item)
+		}
+	}
+	if hasSection(opts.Sections, ConfigMetas) {
+		metas, err := db.GetAllMetas()
+		if err != nil {
+			return nil, err
+		}
+		for _, meta := range metas {
+			meta.ID = 0
+			passwordSet := meta.Password != ""
+			meta.Password = ""
+			config.Metas = append(config.Metas, ConfigMeta{Meta: meta, PasswordSet: passwordSet})
+		}
+	}
+	if hasSection(opts.Sections, ConfigUsers) {
+		users, err := db.GetAllUsers()
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if user.IsAdmin() {
+				continue
+			}
+			user.ID = 0
+			user.Password = ""
+			config.Users = append(config.Users, user)
+		}
+	}
+	return &config, nil
+}
+
+// ImportConfig import the selected sections, the failure of one item doesn't affect the others
+func ImportConfig(config Config, opts ConfigImportOptions) []ConfigImportResult {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+	var results []ConfigImportResult
+	add := func(section, key, action string, err error) *ConfigImportResult {
+		res := ConfigImportResult{Section: section, Key: key, Action: action}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+		return &results[len(results)-1]
+	}
+	if hasSection(opts.Sections, ConfigSettings) {
+		for _, item := range config.Settings {
+			action, err := importSetting(item, opts)
+			add(ConfigSettings, item.Key, action, err)
+		}
+	}
+	if hasSection(opts.Sections, ConfigMetas) {
+		for _, meta := range config.Metas {
+			meta.Path = utils.StandardizePath(meta.Path)
+			action, err := importMeta(meta, opts)
+			add(ConfigMetas, meta.Path, action, err)
+		}
+	}
+	if hasSection(opts.Sections, ConfigUsers) {
+		for _, user := range config.Users {
+			password, action, err := importUser(user, opts)
+			add(ConfigUsers, user.Username, action, err).Password = password
+		}
+	}
+	return results
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(errors.Cause(err), gorm.ErrRecordNotFound)
+}
+
+// importSetting the settings are defined by the code, so the unknown ones are skipped,
+// merge only changes the value, keeping the local definition of the item
+func importSetting(item model.SettingItem, opts ConfigImportOptions) (string, error) {
+	if instanceSettings[item.Key] {
+		return ImportSkip, nil
+	}
+	old, err := db.GetSettingItemByKey(item.Key)
+	if err != nil {
+		if isNotFound(err) {
+			return ImportSkip, nil
+		}
+		return "", errors.WithMessage(err, "failed check existing setting")
+	}
+	if old.Flag == model.READONLY || old.IsDeprecated() || old.Value == item.Value {
+		return ImportSkip, nil
+	}
+	switch opts.Conflict {
+	case ConflictSkip:
+		return ImportSkip, nil
+	case ConflictMerge:
+		old.Value = item.Value
+		item = *old
+	case ConflictOverwrite:
+	default:
+		return "", errors.Errorf("setting [%s] already exists", item.Key)
+	}
+	if opts.DryRun {
+		return ImportOverwrite, nil
+	}
+	return ImportOverwrite, db.SaveSettingItem(item)
+}
+
+// importMeta merge only copies the attributes set in the imported meta. the meta protected by password
+// without the password is refused, unless it keeps the local password, it's never left unprotected
+func importMeta(imported ConfigMeta, opts ConfigImportOptions) (string, error) {
+	meta := imported.Meta
+	meta.ID = 0
+	old, err := db.GetMetaByPath(meta.Path)
+	if err != nil && !isNotFound(err) {
+		return "", errors.WithMessage(err, "failed check existing meta")
+	}
+	if imported.PasswordSet && meta.Password == "" && (old == nil || (old.Password == "" && opts.Conflict != ConflictSkip)) {
+		return "", errors.Errorf("meta [%s] is protected by password, set the password to import it", meta.Path)
+	}
+	if old == nil {
+		if opts.DryRun {
+			return ImportCreate, nil
+		}
+		return ImportCreate, db.CreateMeta(&meta)
+	}
+	switch opts.Conflict {
+	case ConflictSkip:
+		return ImportSkip, nil
+	case ConflictMerge:
+		merged := *old
+		if meta.Password != "" {
+			merged.CopyAttr(meta, model.MetaPassword)
+		}
+		if meta.Write {
+			merged.CopyAttr(meta, model.MetaWrite)
+		}
+		if meta.Hide != "" {
+			merged.CopyAttr(meta, model.MetaHide)
+		}
+		if meta.Readme != "" {
+			merged.CopyAttr(meta, model.MetaReadme)
+		}
+		if meta.Cover != "" {
+			merged.CopyAttr(meta, model.MetaCover)
+		}
+		if meta.Description != "" {
+			merged.CopyAttr(meta, model.MetaDescription)
+		}
+		meta = merged
+	case ConflictOverwrite:
+		meta.ID = old.ID
+		if meta.Password == "" {
+			// the password is never exported
+			meta.Password = old.Password
+		}
+	default:
+		return "", errors.Errorf("meta [%s] already exists", meta.Path)
+	}
+	if opts.DryRun {
+		return ImportOverwrite, nil
+	}
+	return ImportOverwrite, db.UpdateMeta(&meta)
+}
+
+// importUser the guest is matched by role, the others by username. the local passwords are kept
+// unless the imported ones are set, a random password is returned for the user created without it.
+// merge adds the imported permissions to the local ones
+func importUser(user model.User, opts ConfigImportOptions) (string, string, error) {
+	if user.IsAdmin() {
+		return "", "", errors.New("admin can't be imported")
+	}
+	user.ID = 0
+	var old *model.User
+	var err error
+	if user.IsGuest() {
+		old, err = db.GetGuest()
+	} else {
+		old, err = db.GetUserByName(user.Username)
+	}
+	if err != nil && !isNotFound(err) {
+		return "", "", errors.WithMessage(err, "failed check existing user")
+	}
+	if old == nil {
+		if user.IsGuest() {
+			return "", "", errors.New("guest is not found")
+		}
+		var password string
+		if user.Password == "" {
+			password = random.String(16)
+			user.Password = password
+		}
+		if opts.DryRun {
+			return "", ImportCreate, nil
+		}
+		return password, ImportCreate, db.CreateUser(&user)
+	}
+	if old.IsAdmin() {
+		return "", "", errors.Errorf("user [%s] is the admin", user.Username)
+	}
+	switch opts.Conflict {
+	case ConflictSkip:
+		return "", ImportSkip, nil
+	case ConflictMerge:
+		merged := *old
+		merged.Permission |= user.Permission
+		if merged.BasePath == "" || merged.BasePath == "/" {
+			merged.BasePath = user.BasePath
+		}
+		if merged.Email == "" {
+			merged.Email = user.Email
+		}
+		user = merged
+	case ConflictOverwrite:
+		user.ID, user.Username, user.Role = old.ID, old.Username, old.Role
+		if user.Password == "" {
+			user.Password = old.Password
+		}
+	default:
+		return "", "", errors.Errorf("user [%s] already exists", user.Username)
+	}
+	if opts.DryRun {
+		return "", ImportOverwrite, nil
+	}
+	return "", ImportOverwrite, db.UpdateUser(&user)
+}
@@ -0,0 +1,102 @@
+package operations_test
+
+import (
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+func TestImportConfig(t *testing.T) {
+	if err := db.CreateUser(&model.User{Username: "staging", Password: "secret", Permission: 1}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	if err := db.CreateMeta(&model.Meta{Path: "/config", Password: "local"}); err != nil {
+		t.Fatalf("failed create meta: %+v", err)
+	}
+	config, err := operations.ExportConfig(operations.ConfigExportOptions{Sections: []string{operations.ConfigUsers, operations.ConfigMetas}})
+	if err != nil {
+		t.Fatalf("failed export: %+v", err)
+	}
+	if len(config.Settings) != 0 || len(config.Users) == 0 || len(config.Metas) == 0 {
+		t.Fatalf("expected only the users and metas, got %+v", config)
+	}
+	for i := range config.Users {
+		if config.Users[i].Password != "" {
+			t.Errorf("expected the users without passwords, got %+v", config.Users[i])
+		}
+		if config.Users[i].Username == "staging" {
+			config.Users[i].Permission = 2
+		}
+	}
+	for i := range config.Metas {
+		if config.Metas[i].Password != "" {
+			t.Errorf("expected the metas without passwords, got %+v", config.Metas[i])
+		}
+		if config.Metas[i].Path == "/config" {
+			config.Metas[i].Readme = "hello"
+		}
+	}
+	config.Users = append(config.Users, model.User{Username: "promoted", Permission: 4})
+	results := operations.ImportConfig(*config, operations.ConfigImportOptions{Conflict: operations.ConflictMerge})
+	for _, res := range results {
+		if res.Error != "" {
+			t.Fatalf("failed import %s: %s", res.Key, res.Error)
+		}
+		if res.Key == "promoted" && (res.Action != operations.ImportCreate || res.Password == "") {
+			t.Errorf("expected the user is created with a random password, got %+v", res)
+		}
+	}
+	user, err := db.GetUserByName("staging")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	if user.Permission != 3 || user.Password != "secret" {
+		t.Errorf("expected the permissions merged and the password kept, got %+v", user)
+	}
+	meta, err := db.GetMetaByPath("/config")
+	if err != nil {
+		t.Fatalf("failed get meta: %+v", err)
+	}
+	if meta.Readme != "hello" || meta.Password != "local" {
+		t.Errorf("expected the readme merged and the password kept, got %+v", meta)
+	}
+	// the meta protected by password is never created without password
+	if err := db.DeleteMetaById(meta.ID); err != nil {
+		t.Fatalf("failed delete meta: %+v", err)
+	}
+	for _, m := range config.Metas {
+		if m.Path == "/config" {
+			if !m.PasswordSet {
+				t.Errorf("expected the password is marked set, got %+v", m)
+			}
+			results = operations.ImportConfig(operations.Config{Metas: []operations.ConfigMeta{m}}, operations.ConfigImportOptions{})
+			if len(results) != 1 || results[0].Error == "" {
+				t.Errorf("expected the meta without password is refused, got %+v", results)
+			}
+			m.Password = "promoted"
+			results = operations.ImportConfig(operations.Config{Metas: []operations.ConfigMeta{m}}, operations.ConfigImportOptions{})
+			if len(results) != 1 || results[0].Error != "" {
+				t.Errorf("expected the meta with password is imported, got %+v", results)
+			}
+		}
+	}
+	// the private settings are only exported if asked
+	if err := db.SaveSettingItem(model.SettingItem{Key: "config_secret", Value: "secret", Flag: model.PRIVATE}); err != nil {
+		t.Fatalf("failed save setting: %+v", err)
+	}
+	for _, withPrivate := range []bool{false, true} {
+		config, err := operations.ExportConfig(operations.ConfigExportOptions{Sections: []string{operations.ConfigSettings}, WithPrivate: withPrivate})
+		if err != nil {
+			t.Fatalf("failed export: %+v", err)
+		}
+		exported := false
+		for _, item := range config.Settings {
+			exported = exported || item.Key == "config_secret"
+		}
+		if exported != withPrivate {
+			t.Errorf("expected the private setting exported %v, got %v", withPrivate, exported)
+		}
+	}
+}
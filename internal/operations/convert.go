@@ -0,0 +1,215 @@
+package operations
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type ConvertResult struct {
+	Addition  string   `json:"addition"`
+	Mapped    []string `json:"mapped"`    // the fields kept from the old addition
+	Defaulted []string `json:"defaulted"` // the fields set to the default of the new driver
+	Dropped   []string `json:"dropped"`   // the old fields not in the new driver, or not compatible
+	Missing   []string `json:"missing"`   // the required fields without value
+}
+
+// MapAddition map the compatible fields of the addition to the items of the new driver,
+// a field is kept if the new driver has the same name and type, the others get the defaults
+func MapAddition(addition string, items []driver.Item) (*ConvertResult, error) {
+	old := make(map[string]interface{})
+	if addition != "" {
+		if err := utils.Json.UnmarshalFromString(addition, &old); err != nil {
+			return nil, errors.Wrap(err, "failed parse the old addition")
+		}
+	}
+	res := &ConvertResult{}
+	mapped := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		if v, ok := old[item.Name]; ok {
+			delete(old, item.Name)
+			if compatible(item, v) {
+				mapped[item.Name] = v
+				res.Mapped = append(res.Mapped, item.Name)
+				continue
+			}
+			res.Dropped = append(res.Dropped, item.Name)
+		}
+		if v, ok := defaultValue(item); ok {
+			mapped[item.Name] = v
+			res.Defaulted = append(res.Defaulted, item.Name)
+			continue
+		}
+		if item.Required {
+			res.Missing = append(res.Missing, item.Name)
+		}
+	}
+	for name := range old {
+		res.Dropped = append(res.Dropped, name)
+	}
+	var err error
+	res.Addition, err = utils.Json.MarshalToString(mapped)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+func isNumberType(t string) bool {
+	switch t {
+	case conf.TypeNumber, "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+		return true
+	}
+	return false
+}
+
+// compatible check whether the value fits the item
+func compatible(item driver.Item, v interface{}) bool {
+	switch {
+	case item.Type == conf.TypeBool:
+		_, ok := v.(bool)
+		return ok
+	case isNumberType(item.Type):
+		_, ok := v.(float64)
+		return ok
+	case item.Type == conf.TypeSelect:
+		s, ok := v.(string)
+		if !ok || item.Values == "" {
+			return ok
+		}
+		for _, value := range strings.Split(item.Values, ",") {
+			if strings.TrimSpace(value) == s {
+				return true
+			}
+		}
+		return false
+	default:
+		_, ok := v.(string)
+		return ok
+	}
+}
+
+// defaultValue return the typed default of the item, false if it has no default
+func defaultValue(item driver.Item) (interface{}, bool) {
+	if item.Default == "" {
+		return nil, false
+	}
+	switch {
+	case item.Type == conf.TypeBool:
+		b, err := strconv.ParseBool(item.Default)
+		return b, err == nil
+	case isNumberType(item.Type):
+		f, err := strconv.ParseFloat(item.Default, 64)
+		return f, err == nil
+	default:
+		return item.Default, true
+	}
+}
+
+// credentialFits tell whether the credential is used by another storage of the driver,
+// the fields of a credential are of the driver it's made for
+func credentialFits(credentialId, storageId uint, driverName string) bool {
+	storages, err := db.GetStoragesByCredentialId(credentialId)
+	if err != nil {
+		log.Warnf("failed get storages of credential [%d]: %+v", credentialId, err)
+		return false
+	}
+	for _, s := range storages {
+		if s.ID != storageId && s.Driver == driverName {
+			return true
+		}
+	}
+	return false
+}
+
+type ConvertReq struct {
+	ID     uint   `json:"id"`
+	Driver string `json:"driver"`
+	// the addition of the new driver, empty to map the old one
+	Addition string `json:"addition"`
+	// only return the mapped addition without converting
+	DryRun bool `json:"dry_run"`
+}
+
+// ConvertStorage change the driver of the storage, the mount path, meta and id are kept.
+// the new driver is initialized first, the old one is dropped and replaced only if it succeeds
+func ConvertStorage(ctx context.Context, req ConvertReq) (*ConvertResult, error) {
+	storage, err := db.GetStorageById(req.ID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storage")
+	}
+	if storage.Driver == req.Driver {
+		return nil, errors.Errorf("storage [%s] already uses driver %s", storage.MountPath, req.Driver)
+	}
+	driverNew, err := GetDriverNew(req.Driver)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get driver new")
+	}
+	var res *ConvertResult
+	if req.Addition != "" {
+		// check the fresh addition is json
+		var m map[string]interface{}
+		if err := utils.Json.UnmarshalFromString(req.Addition, &m); err != nil {
+			return nil, errors.Wrap(err, "invalid addition")
+		}
+		res = &ConvertResult{Addition: req.Addition}
+	} else {
+		res, err = MapAddition(storage.Addition, driverItemsMap[req.Driver].Additional)
+		if err != nil {
+			return nil, err
+		}
+	}
+	converted := *storage
+	converted.Driver = req.Driver
+	converted.Addition = res.Addition
+	if converted.CredentialID != 0 && !credentialFits(converted.CredentialID, converted.ID, req.Driver) {
+		converted.CredentialID = 0
+		res.Dropped = append(res.Dropped, "credential_id")
+	}
+	if converted.BackupCredentialID != 0 && !credentialFits(converted.BackupCredentialID, converted.ID, req.Driver) {
+		converted.BackupCredentialID, converted.OnBackup = 0, false
+		res.Dropped = append(res.Dropped, "backup_credential_id")
+	}
+	if req.DryRun {
+		return res, nil
+	}
+	if len(res.Missing) > 0 {
+		return res, errors.Errorf("missing required fields: %s", strings.Join(res.Missing, ", "))
+	}
+	converted.Modified = time.Now()
+	converted.Status, converted.InitAttempts, converted.LastError = "", 0, ""
+	applied, err := applyCredential(converted)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed apply credential")
+	}
+	// the old driver keeps serving while the new one is initialized
+	storageDriver := driverNew()
+	if err := storageDriver.Init(ctx, applied); err != nil {
+		_ = storageDriver.Drop(ctx)
+		return res, errors.WithMessage(err, "failed init storage")
+	}
+	if err := db.UpdateStorage(&converted); err != nil {
+		_ = storageDriver.Drop(ctx)
+		return nil, errors.WithMessage(err, "failed update storage in database")
+	}
+	cancelInitRetry(storage.ID)
+	if old, ok := storagesMap.Load(storage.MountPath); ok {
+		// the storage is converted already, the new driver replaces the old one anyway
+		if err := unloadStorage(ctx, old); err != nil {
+			log.Warnf("failed unload the old driver of storage [%s]: %+v", storage.MountPath, err)
+		}
+	}
+	storagesMap.Store(applied.MountPath, storageDriver)
+	emitStorageEvent(StorageEvent{Type: EventStorageUpdated, Storage: applied})
+	onInitSucceeded(storageDriver, applied)
+	return res, nil
+}
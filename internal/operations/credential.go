@@ -0,0 +1,106 @@
+package operations
+
+import (
+	"context"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// applyCredential merge the shared credential in use into the addition of the storage,
+// the fields of credential take precedence over the addition
+func applyCredential(storage model.Storage) (model.Storage, error) {
+	if storage.GetCredentialID() == 0 {
+		return storage, nil
+	}
+	credential, err := db.GetCredentialById(storage.GetCredentialID())
+	if err != nil {
+		return storage, errors.WithMessage(err, "failed get credential")
+	}
+	fields := make(map[string]interface{})
+	if err := utils.Json.UnmarshalFromString(credential.Data, &fields); err != nil {
+		return storage, errors.Wrapf(err, "failed unmarshal credential [%s]", credential.Name)
+	}
+	storage.Addition, err = utils.MergeJson(storage.Addition, fields)
+	if err != nil {
+		return storage, errors.Wrap(err, "failed merge credential into addition")
+	}
+	return storage, nil
+}
+
+// splitCredential write the credential fields of addition back to the credential,
+// e.g. the refreshed token, and return the addition without them
+func splitCredential(credentialId uint, addition string) (string, error) {
+	credential, err := db.GetCredentialById(credentialId)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed get credential")
+	}
+	fields := make(map[string]interface{})
+	if err := utils.Json.UnmarshalFromString(credential.Data, &fields); err != nil {
+		return "", errors.Wrapf(err, "failed unmarshal credential [%s]", credential.Name)
+	}
+	additionFields := make(map[string]interface{})
+	if err := utils.Json.UnmarshalFromString(addition, &additionFields); err != nil {
+		return "", errors.Wrap(err, "failed unmarshal addition")
+	}
+	changed := false
+	for k := range fields {
+		v, ok := additionFields[k]
+		if !ok {
+			continue
+		}
+		if !utils.JsonEqual(fields[k], v) {
+			fields[k] = v
+			changed = true
+		}
+		delete(additionFields, k)
+	}
+	if changed {
+		credential.Data, err = utils.Json.MarshalToString(fields)
+		if err != nil {
+			return "", errors.Wrap(err, "failed marshal credential")
+		}
+		credential.Modified = time.Now()
+		if err := db.UpdateCredential(credential); err != nil {
+			return "", errors.WithMessage(err, "failed update credential")
+		}
+	}
+	return utils.Json.MarshalToString(additionFields)
+}
+
+// UpdateCredential update the credential and reinitialize all storages using it
+func UpdateCredential(ctx context.Context, credential model.Credential) error {
+	credential.Modified = time.Now()
+	if err := db.UpdateCredential(&credential); err != nil {
+		return errors.WithMessage(err, "failed update credential in database")
+	}
+	storages, err := db.GetStoragesByCredentialId(credential.ID)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storages using the credential")
+	}
+	var failed []string
+	for _, storage := range storages {
+		if err := UpdateStorage(ctx, storage); err != nil {
+			failed = append(failed, storage.MountPath)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("credential updated, but failed reinitialize storages: %v", failed)
+	}
+	return nil
+}
+
+// DeleteCredentialById delete the credential if no storage is using it
+func DeleteCredentialById(id uint) error {
+	storages, err := db.GetStoragesByCredentialId(id)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storages using the credential")
+	}
+	if len(storages) > 0 {
+		return errors.Errorf("credential is used by %d storages", len(storages))
+	}
+	return db.DeleteCredentialById(id)
+}
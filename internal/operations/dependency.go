@@ -0,0 +1,166 @@
+package operations
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// dependencyLayers group the indexes of storages into layers, a storage is in a later layer
+// than all storages it depends on, the ones in a loop are in the last layer.
+// the dependencies not in the storages are ignored
+func dependencyLayers(storages []model.Storage) [][]int {
+	index := make(map[string]int, len(storages))
+	for i, storage := range storages {
+		index[storage.MountPath] = i
+	}
+	pending := make(map[int]struct{}, len(storages))
+	for i := range storages {
+		pending[i] = struct{}{}
+	}
+	var layers [][]int
+	for len(pending) > 0 {
+		var layer []int
+		for i := range storages {
+			if _, ok := pending[i]; !ok {
+				continue
+			}
+			ready := true
+			for _, dep := range storages[i].GetDependsOn() {
+				if j, ok := index[dep]; ok && j != i {
+					if _, ok := pending[j]; ok {
+						ready = false
+						break
+					}
+				}
+			}
+			if ready {
+				layer = append(layer, i)
+			}
+		}
+		if len(layer) == 0 {
+			// the rest form loops, load them anyway
+			for i := range storages {
+				if _, ok := pending[i]; ok {
+					log.Warnf("storage [%s] is in a dependency loop", storages[i].MountPath)
+					layer = append(layer, i)
+				}
+			}
+		}
+		for _, i := range layer {
+			delete(pending, i)
+		}
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+// checkDependencyLoop return errs.DependencyLoop if the storage depends on itself through others
+func checkDependencyLoop(storage model.Storage) error {
+	storages, err := db.GetAllStorages()
+	if err != nil {
+		return errors.WithMessage(err, "failed get storages")
+	}
+	deps := make(map[string][]string, len(storages)+1)
+	for _, s := range storages {
+		if s.ID != storage.ID {
+			deps[s.MountPath] = s.GetDependsOn()
+		}
+	}
+	deps[storage.MountPath] = storage.GetDependsOn()
+	visited := make(map[string]struct{})
+	var walk func(path string, chain []string) error
+	walk = func(path string, chain []string) error {
+		for _, dep := range deps[path] {
+			if dep == storage.MountPath {
+				return errors.Wrap(errs.DependencyLoop, strings.Join(append(chain, dep), " -> "))
+			}
+			if _, ok := visited[dep]; ok {
+				continue
+			}
+			visited[dep] = struct{}{}
+			if err := walk(dep, append(chain, dep)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(storage.MountPath, []string{storage.MountPath})
+}
+
+// getDependents return the loaded storages depending on the mount path directly or not,
+// the ones depending on others in the result come first, so they can be dropped in order
+func getDependents(mountPath string) []driver.Driver {
+	var res []driver.Driver
+	seen := map[string]struct{}{mountPath: {}}
+	var walk func(path string)
+	walk = func(path string) {
+		storagesMap.Range(func(key string, value driver.Driver) bool {
+			if _, ok := seen[key]; ok {
+				return true
+			}
+			for _, dep := range value.GetStorage().GetDependsOn() {
+				if dep == path {
+					seen[key] = struct{}{}
+					walk(key)
+					res = append(res, value)
+					break
+				}
+			}
+			return true
+		})
+	}
+	walk(mountPath)
+	return res
+}
+
+// dropDependents drop the storages depending on the deleted one, they are kept
+// so the admin can fix them, but marked as failed until reinitialized
+func dropDependents(ctx context.Context, mountPath string) {
+	for _, dependent := range getDependents(mountPath) {
+		storage := dependent.GetStorage()
+		if err := dropGracefully(ctx, dependent); err != nil {
+			log.Errorf("failed drop storage [%s] depending on [%s]: %+v", storage.MountPath, mountPath, err)
+		}
+		setStatus(dependent, storage, model.StorageInitFailed, storage.InitAttempts,
+			errors.Errorf("storage [%s] depended on is deleted", mountPath).Error())
+	}
+}
+
+// rewriteDependents point the storages depending on the renamed mount path to the new one,
+// both in the database and in the memory
+func rewriteDependents(oldPath, newPath string) error {
+	storages, err := db.GetAllStorages()
+	if err != nil {
+		return errors.WithMessage(err, "failed get storages")
+	}
+	for _, storage := range storages {
+		deps := storage.GetDependsOn()
+		changed := false
+		for i, dep := range deps {
+			if dep == oldPath {
+				deps[i], changed = newPath, true
+			}
+		}
+		if !changed {
+			continue
+		}
+		// it may already depend on the new one too
+		dependsOn := strings.Join(model.Storage{DependsOn: strings.Join(deps, ",")}.GetDependsOn(), ",")
+		if err := db.UpdateStorageDependsOn(storage.ID, dependsOn); err != nil {
+			return errors.WithMessagef(err, "failed update storage [%s]", storage.MountPath)
+		}
+		if storageDriver, ok := storagesMap.Load(storage.MountPath); ok {
+			updateStorageInMemory(storageDriver, func(cur *model.Storage) {
+				cur.DependsOn = dependsOn
+			})
+		}
+	}
+	return nil
+}
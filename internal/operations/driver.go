@@ -1,11 +1,13 @@
 package operations
 
 import (
-	"github.com/alist-org/alist/v3/internal/conf"
+	"context"
 	"reflect"
 	"strings"
 
+	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -41,11 +43,28 @@ func GetDriverItemsMap() map[string]driver.Items {
 	return driverItemsMap
 }
 
+// EnumerateDriverField list the values of the addition field with a driver not initialized
+func EnumerateDriverField(ctx context.Context, storage model.Storage, field string) ([]string, error) {
+	driverNew, err := GetDriverNew(storage.Driver)
+	if err != nil {
+		return nil, err
+	}
+	enumerator, ok := driverNew().(driver.Enumerator)
+	if !ok {
+		return nil, errors.Errorf("driver [%s] can't enumerate fields", storage.Driver)
+	}
+	return enumerator.Enumerate(ctx, storage, field)
+}
+
 func registerDriverItems(config driver.Config, addition driver.Additional) {
 	log.Debugf("addition of %s: %+v", config.Name, addition)
-	tAddition := reflect.TypeOf(addition)
 	mainItems := getMainItems(config)
-	additionalItems := getAdditionalItems(tAddition, config.DefaultRoot)
+	var additionalItems []driver.Item
+	if d, ok := addition.(driver.DynamicAddition); ok {
+		additionalItems = d.Items()
+	} else {
+		additionalItems = getAdditionalItems(reflect.TypeOf(addition), config.DefaultRoot)
+	}
 	driverItemsMap[config.Name] = driver.Items{
 		Common:     mainItems,
 		Additional: additionalItems,
@@ -68,7 +87,111 @@ func getMainItems(config driver.Config) []driver.Item {
 	}, {
 		Name: "down_proxy_url",
 		Type: conf.TypeText,
+	}, {
+		Name: "credential_id",
+		Type: conf.TypeNumber,
+		Help: "shared credential merged into the addition, 0 means not used",
+	}, {
+		Name: "disabled_ops",
+		Type: conf.TypeString,
+		Help: "comma separated, optional: make_dir,move,rename,copy,remove,put",
+	}, {
+		Name: "read_only",
+		Type: conf.TypeBool,
+		Help: "reject all write operations",
+	}, {
+		Name:    "balance_policy",
+		Type:    conf.TypeSelect,
+		Values:  "round_robin, fastest, sticky, sticky_ip, union_first_writable, union_most_free",
+		Default: "round_robin",
+		Help:    "only the policy of the first member in a balance group works, the union ones merge the members into one directory",
+	}, {
+		Name:    "shadow_policy",
+		Type:    conf.TypeSelect,
+		Values:  "merge, mount_wins, hide_parent",
+		Default: "merge",
+		Help:    "how the listing combines with the storages mounted inside",
+	}, {
+		Name: "upload_limit",
+		Type: conf.TypeNumber,
+		Help: "bytes per second, 0 means no limit",
+	}, {
+		Name: "download_limit",
+		Type: conf.TypeNumber,
+		Help: "bytes per second, only works for proxied downloads, 0 means no limit",
+	}, {
+		Name: "rate_limit",
+		Type: conf.TypeNumber,
+		Help: "calls to the driver per second, such as listing and getting links, 0 means no limit",
+	}, {
+		Name: "rate_burst",
+		Type: conf.TypeNumber,
+		Help: "calls allowed at once before the rate limit works, 0 means 1",
+	}, {
+		Name: "max_parallel",
+		Type: conf.TypeNumber,
+		Help: "calls to the driver running at once, the proxied downloads hold one till they end, 0 means no limit",
+	}, {
+		Name: "debug_capture",
+		Type: conf.TypeBool,
+		Help: "record the recent http calls of the driver to diagnose, the secrets are redacted",
+	}, {
+		Name: "retry_attempts",
+		Type: conf.TypeNumber,
+		Help: "retries of the listing, getting and linking failed with timeout, 429 or 5xx, 0 means no retry",
+	}, {
+		Name: "retry_backoff",
+		Type: conf.TypeNumber,
+		Help: "milliseconds before the first retry, doubled every retry, 0 means 500",
+	}, {
+		Name: "retry_on",
+		Type: conf.TypeString,
+		Help: "comma separated, the errors containing any of them are retried too, such as the error codes of the provider",
 	}}
+	if !config.NoCache {
+		items = append(items, driver.Item{
+			Name: "cache_expiration",
+			Type: conf.TypeNumber,
+			Help: "minutes of the list cache, 0 means default, negative means no cache",
+		})
+	}
+	if !config.OnlyLocal {
+		items = append(items, []driver.Item{{
+			Name: "accelerate",
+			Type: conf.TypeBool,
+			Help: "probe the endpoints of the driver and switch to another one when the transfer stalls",
+		}, {
+			Name: "stall_timeout",
+			Type: conf.TypeNumber,
+			Help: "seconds without data before switching endpoint, 0 means default",
+		}, {
+			Name:    "ip_version",
+			Type:    conf.TypeSelect,
+			Values:  "auto, ipv4, ipv6",
+			Default: "auto",
+			Help:    "force the ip version of the http connections",
+		}, {
+			Name: "bind_address",
+			Type: conf.TypeString,
+			Help: "local ip or interface name to send the requests from",
+		}, {
+			Name: "outbound_proxy",
+			Type: conf.TypeString,
+			Help: "socks5:// or http:// proxy for the requests to the provider",
+		}, {
+			Name: "proxy_hosts",
+			Type: conf.TypeString,
+			Help: "comma separated, only the requests to the hosts and their subdomains use the proxy, such as googleapis.com, empty means all",
+		}, {
+			Name: "request_timeout",
+			Type: conf.TypeNumber,
+			Help: "seconds of a whole metadata request to the provider, 0 means the global one",
+		}, {
+			Name: "max_body_size",
+			Type: conf.TypeNumber,
+			Help: "max bytes of a metadata response of the provider, 0 means the global one",
+		}}...)
+	}
 	if !config.OnlyProxy && !config.OnlyLocal {
 		items = append(items, []driver.Item{{
 			Name: "web_proxy",
@@ -123,12 +246,15 @@ func getAdditionalItems(t reflect.Type, defaultRoot string) []driver.Item {
 			continue
 		}
 		item := driver.Item{
-			Name:     tag.Get("json"),
+			Name:     strings.Split(tag.Get("json"), ",")[0],
 			Type:     strings.ToLower(field.Type.Name()),
 			Default:  tag.Get("default"),
 			Values:   tag.Get("values"),
 			Required: tag.Get("required") == "true",
 			Help:     tag.Get("help"),
+			// the driver should implement driver.Enumerator
+			Enumerable: tag.Get("enumerable") == "true",
+			Format:     tag.Get("format"),
 		}
 		if tag.Get("type") != "" {
 			item.Type = tag.Get("type")
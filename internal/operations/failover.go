@@ -0,0 +1,204 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/leader"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/notify"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the interval to probe whether the account of the primary credential is available again
+var failbackInterval = 30 * time.Minute
+
+// storage id => struct{}, the storages switching to the backup credential
+var switching generic_sync.MapOf[uint, struct{}]
+
+// storage id => struct{}, the storages probing the primary credential
+var probing generic_sync.MapOf[uint, struct{}]
+
+// probeCall redo the call failed with the primary credential on the probing instance, as some
+// quotas only limit some calls, e.g. the download quota, it's switched back only if the call works
+type probeCall func(ctx context.Context, storageDriver driver.Driver) error
+
+// storage id => the call failed with the primary credential
+var failedCalls generic_sync.MapOf[uint, probeCall]
+
+// the temporary instances probing the credentials, they never save the storage
+// in the database, or the storage in use would be overwritten
+var (
+	probesMu sync.Mutex
+	probes   = make(map[driver.Driver]struct{})
+)
+
+func isProbe(storageDriver driver.Driver) bool {
+	probesMu.Lock()
+	defer probesMu.Unlock()
+	_, ok := probes[storageDriver]
+	return ok
+}
+
+// checkFailover switch the storage to its backup credential in background
+// if the error shows the account of the primary one is unavailable
+func checkFailover(storageDriver driver.Driver, err error, probe probeCall) {
+	if err == nil || !errs.IsAccountUnavailable(err) {
+		return
+	}
+	storage := storageDriver.GetStorage()
+	if storage.BackupCredentialID == 0 || storage.OnBackup {
+		return
+	}
+	if _, loaded := switching.LoadOrStore(storage.ID, struct{}{}); loaded {
+		return
+	}
+	if probe != nil {
+		failedCalls.Store(storage.ID, probe)
+	}
+	go func() {
+		defer switching.Delete(storage.ID)
+		if err := switchCredential(context.Background(), storage.ID, true); err != nil {
+			log.Errorf("failed switch storage [%s] to backup credential: %+v", storage.MountPath, err)
+			return
+		}
+		log.Warnf("storage [%s] switched to backup credential: %s", storage.MountPath, err)
+		notifyStorage(storage, "switched to backup credential",
+			fmt.Sprintf("The account of the primary credential is unavailable: %s\nThe primary one will be probed every %s, and switched back once it's available.", err, failbackInterval))
+		probeFailback(storage.ID)
+	}()
+}
+
+// switchCredential reinitialize the storage with the backup credential or the primary one
+func switchCredential(ctx context.Context, id uint, backup bool) error {
+	storage, err := db.GetStorageById(id)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	storageDriver, err := GetStorageByVirtualPath(storage.MountPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage driver")
+	}
+	if storageDriver.GetStorage().OnBackup == backup {
+		return nil
+	}
+	if err := db.UpdateStorageOnBackup(id, backup); err != nil {
+		return errors.WithMessage(err, "failed update storage in database")
+	}
+	storage.OnBackup = backup
+	return reinitStorage(ctx, storageDriver, *storage)
+}
+
+// probeFailback probe the primary credential of the storage periodically in background,
+// and switch back once it's available, stop if the storage is switched back by others or deleted
+func probeFailback(id uint) {
+	if _, loaded := probing.LoadOrStore(id, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer probing.Delete(id)
+		ticker := time.NewTicker(failbackInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// the leader switches the storage in the database for all the replicas
+			if !leader.IsLeader() {
+				continue
+			}
+			storage, err := db.GetStorageById(id)
+			if err != nil || !storage.OnBackup || storage.BackupCredentialID == 0 {
+				failedCalls.Delete(id)
+				return
+			}
+			primary := *storage
+			primary.OnBackup = false
+			// unknown if the probing is resumed after restart, only the init and the space are probed
+			probe, _ := failedCalls.Load(id)
+			if err := probeCredential(context.Background(), primary, probe); err != nil {
+				log.Debugf("primary credential of storage [%s] is still unavailable: %+v", storage.MountPath, err)
+				continue
+			}
+			if err := switchCredential(context.Background(), id, false); err != nil {
+				log.Errorf("failed switch storage [%s] back to primary credential: %+v", storage.MountPath, err)
+				continue
+			}
+			failedCalls.Delete(id)
+			log.Infof("storage [%s] switched back to primary credential", storage.MountPath)
+			notifyStorage(*storage, "switched back to primary credential", "The account of the primary credential is available again.")
+			return
+		}
+	}()
+}
+
+// probeCredential init a temporary instance of the storage with the credential in use,
+// check the space left if the driver can report it, and redo the call failed if it's known
+func probeCredential(ctx context.Context, storage model.Storage, probe probeCall) error {
+	driverNew, err := GetDriverNew(storage.Driver)
+	if err != nil {
+		return errors.WithMessage(err, "failed get driver new")
+	}
+	storage, err = applyCredential(storage)
+	if err != nil {
+		return errors.WithMessage(err, "failed apply credential")
+	}
+	storageDriver := driverNew()
+	probesMu.Lock()
+	probes[storageDriver] = struct{}{}
+	probesMu.Unlock()
+	// dropped even if it fails to init, its token manager is never activated anyway
+	defer func() {
+		_ = storageDriver.Drop(ctx)
+		probesMu.Lock()
+		delete(probes, storageDriver)
+		probesMu.Unlock()
+	}()
+	if err := storageDriver.Init(ctx, storage); err != nil {
+		return errors.WithMessage(err, "failed init storage")
+	}
+	if a, ok := storageDriver.(driver.About); ok {
+		usage, err := a.About(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "failed get usage")
+		}
+		if usage.Total > 0 && usage.Free <= 0 {
+			return errors.WithStack(errs.QuotaExceeded)
+		}
+	}
+	if probe != nil {
+		return errors.WithMessage(probe(ctx, storageDriver), "failed redo the call")
+	}
+	return nil
+}
+
+// ResumeFailbackProbes probe the primary credentials of the storages on backup,
+// called after the storages are loaded
+func ResumeFailbackProbes() {
+	storagesMap.Range(func(key string, value driver.Driver) bool {
+		if storage := value.GetStorage(); storage.OnBackup && storage.BackupCredentialID != 0 {
+			probeFailback(storage.ID)
+		}
+		return true
+	})
+}
+
+// notifyStorage send the event of the storage to the admin
+func notifyStorage(storage model.Storage, subject, body string) {
+	var to []string
+	if admin, err := db.GetAdmin(); err == nil && admin.Email != "" {
+		to = append(to, admin.Email)
+	}
+	err := notify.Send(notify.Message{
+		Subject: fmt.Sprintf("storage [%s] %s", storage.MountPath, subject),
+		Body:    body,
+		To:      to,
+	})
+	if err != nil {
+		log.Warnf("failed notify the event of storage [%s]: %+v", storage.MountPath, err)
+	}
+}
@@ -0,0 +1,100 @@
+package operations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+type refreshingAddition struct {
+	Token string `json:"token"`
+}
+
+// refreshingDriver refresh the token and save the storage on init, like the drivers with the token managers
+type refreshingDriver struct {
+	driver.Driver
+	storage model.Storage
+	refreshingAddition
+}
+
+func (d *refreshingDriver) Config() driver.Config {
+	return driver.Config{Name: "Refreshing"}
+}
+
+func (d *refreshingDriver) Init(ctx context.Context, storage model.Storage) error {
+	d.storage = storage
+	if err := utils.Json.UnmarshalFromString(storage.Addition, &d.refreshingAddition); err != nil {
+		return err
+	}
+	d.Token = "refreshed"
+	MustSaveDriverStorage(d)
+	return nil
+}
+
+func (d *refreshingDriver) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *refreshingDriver) GetStorage() model.Storage {
+	return d.storage
+}
+
+func (d *refreshingDriver) GetAddition() driver.Additional {
+	return d.refreshingAddition
+}
+
+// the db is initialized by the tests of operations_test
+func TestProbeCredential(t *testing.T) {
+	RegisterDriver(driver.Config{Name: "Refreshing"}, func() driver.Driver { return &refreshingDriver{} })
+	credential := model.Credential{Name: "refreshing", Data: `{"token":"old"}`}
+	if err := db.CreateCredential(&credential); err != nil {
+		t.Fatalf("failed create credential: %+v", err)
+	}
+	defer func() {
+		_ = db.DeleteCredentialById(credential.ID)
+	}()
+	storage := model.Storage{Driver: "Refreshing", MountPath: "/refreshing", Addition: `{"root":"/in_use"}`, CredentialID: credential.ID}
+	if err := db.CreateStorage(&storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	// the storages in the database are loaded by the other tests
+	defer func() {
+		_ = db.DeleteStorageById(storage.ID)
+	}()
+	probe := storage
+	probe.Remark = "probe"
+	if err := probeCredential(context.Background(), probe, nil); err != nil {
+		t.Fatalf("failed probe: %+v", err)
+	}
+	got, err := db.GetStorageById(storage.ID)
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if got.Remark != "" || got.Addition != storage.Addition {
+		t.Errorf("expected the storage is not saved by the probe, got %+v", got)
+	}
+	// the refreshed token is kept, a rotated one can't be refreshed again
+	gotCredential, err := db.GetCredentialById(credential.ID)
+	if err != nil {
+		t.Fatalf("failed get credential: %+v", err)
+	}
+	if gotCredential.Data != `{"token":"refreshed"}` {
+		t.Errorf("expected the refreshed token is saved, got %s", gotCredential.Data)
+	}
+	// the download quota is exceeded still
+	err = probeCredential(context.Background(), probe, func(ctx context.Context, storageDriver driver.Driver) error {
+		return errors.WithStack(errs.QuotaExceeded)
+	})
+	if !errors.Is(errors.Cause(err), errs.QuotaExceeded) {
+		t.Errorf("expected the call failed is redone, got %+v", err)
+	}
+	if isProbe(&refreshingDriver{}) || len(probes) != 0 {
+		t.Errorf("expected the probe is unregistered after probing")
+	}
+}
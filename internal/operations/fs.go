@@ -2,9 +2,11 @@ package operations
 
 import (
 	"context"
+	"net/http"
 	"os"
 	stdpath "path"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Xhofe/go-cache"
@@ -12,6 +14,8 @@ import (
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/errs"
 	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
 	"github.com/alist-org/alist/v3/pkg/singleflight"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
@@ -22,16 +26,38 @@ import (
 
 var filesCache = cache.NewMemCache(cache.WithShards[[]model.Obj](64))
 var filesG singleflight.Group[[]model.Obj]
+var getG singleflight.Group[model.Obj]
+
+// the generation of the listings of a storage is increased when a dir is listed from the driver
+// or its cache is cleared, so the listings made out of alist are known to be changed
+var listGens generic_sync.MapOf[string, *uint64]
+
+// ListGeneration get the generation of the listings of the storage
+func ListGeneration(storage driver.Driver) uint64 {
+	gen, _ := listGens.LoadOrStore(storage.GetStorage().MountPath, new(uint64))
+	return atomic.LoadUint64(gen)
+}
+
+func increaseListGeneration(storage driver.Driver) {
+	gen, _ := listGens.LoadOrStore(storage.GetStorage().MountPath, new(uint64))
+	atomic.AddUint64(gen, 1)
+}
 
 func ClearCache(storage driver.Driver, path string) {
 	key := stdpath.Join(storage.GetStorage().MountPath, path)
 	filesCache.Del(key)
+	clearNotFound(storage)
+	increaseListGeneration(storage)
 }
 
 // List files in storage, not contains virtual file
 func List(ctx context.Context, storage driver.Driver, path string, refresh ...bool) ([]model.Obj, error) {
 	path = utils.StandardizePath(path)
-	log.Debugf("operations.List %s", path)
+	utils.Log(ctx).Debugf("operations.List %s", path)
+	// the paths created out of alist are found again by refreshing
+	if len(refresh) > 0 && refresh[0] {
+		clearNotFound(storage)
+	}
 	dir, err := Get(ctx, storage, path)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get dir")
@@ -39,25 +65,67 @@ func List(ctx context.Context, storage driver.Driver, path string, refresh ...bo
 	if !dir.IsDir() {
 		return nil, errors.WithStack(errs.NotFolder)
 	}
-	if storage.Config().NoCache {
-		return storage.List(ctx, dir)
-	}
 	key := stdpath.Join(storage.GetStorage().MountPath, path)
-	if len(refresh) == 0 || !refresh[0] {
+	noCache := storage.Config().NoCache || storage.GetStorage().CacheExpiration < 0
+	if !noCache && (len(refresh) == 0 || !refresh[0]) {
 		if files, ok := filesCache.Get(key); ok {
 			return files, nil
 		}
 	}
-	files, err, _ := filesG.Do(key, func() ([]model.Obj, error) {
-		files, err := storage.List(ctx, dir)
+	return coalesce(ctx, &filesG, key, func(ctx context.Context) ([]model.Obj, error) {
+		files, err := retryCall(ctx, storage, func(ctx context.Context) ([]model.Obj, error) {
+			release, err := acquire(ctx, storage)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+			start := time.Now()
+			files, err := storage.List(ctx, dir)
+			reportResult(storage, err, func(ctx context.Context, storageDriver driver.Driver) error {
+				_, err := storageDriver.List(ctx, dir)
+				return err
+			})
+			if err == nil {
+				reportLatency(storage, time.Since(start))
+			}
+			return files, err
+		})
 		if err != nil {
 			return nil, errors.WithMessage(err, "failed to list files")
 		}
-		// TODO: maybe can get duration from storage's config
-		filesCache.Set(key, files, cache.WithEx[[]model.Obj](time.Minute*time.Duration(conf.Conf.CaCheExpiration)))
+		if !noCache {
+			filesCache.Set(key, files, cache.WithEx[[]model.Obj](cacheExpiration(storage)))
+		}
+		increaseListGeneration(storage)
 		return files, nil
 	})
-	return files, err
+}
+
+// cacheExpiration the list cache duration of the storage, fallback to the global one
+func cacheExpiration(storage driver.Driver) time.Duration {
+	if minutes := storage.GetStorage().CacheExpiration; minutes > 0 {
+		return time.Minute * time.Duration(minutes)
+	}
+	return time.Minute * time.Duration(conf.Conf.CaCheExpiration)
+}
+
+// CheckOperation check whether the operation is disabled for the storage by admin
+func CheckOperation(storage driver.Driver, op string) error {
+	if setting.IsReadOnly() {
+		return errors.Wrapf(errs.InstanceReadOnly, "can't %s", op)
+	}
+	// the database of the demo is reset but the files of the storages can't be,
+	// so every operation is disabled whatever the storages are set to
+	if conf.Conf.Demo.Enable {
+		return errors.Wrapf(errs.OperationDisabled, "can't %s in demo mode", op)
+	}
+	if storage.GetStorage().ReadOnly {
+		return errors.Wrapf(errs.StorageReadOnly, "can't %s", op)
+	}
+	if storage.GetStorage().IsOpDisabled(op) {
+		return errors.Wrapf(errs.OperationDisabled, "can't %s", op)
+	}
+	return nil
 }
 
 func isRoot(path, rootFolderPath string) bool {
@@ -76,9 +144,30 @@ func isRoot(path, rootFolderPath string) bool {
 // Get object from list of files
 func Get(ctx context.Context, storage driver.Driver, path string) (model.Obj, error) {
 	path = utils.StandardizePath(path)
-	log.Debugf("operations.Get %s", path)
+	utils.Log(ctx).Debugf("operations.Get %s", path)
+	if isNotFoundCached(storage, path) {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	obj, err := get(ctx, storage, path)
+	if errs.IsObjectNotFound(err) {
+		cacheNotFound(storage, path)
+	}
+	return obj, err
+}
+
+func get(ctx context.Context, storage driver.Driver, path string) (model.Obj, error) {
 	if g, ok := storage.(driver.Getter); ok {
-		return g.Get(ctx, path)
+		key := stdpath.Join(storage.GetStorage().MountPath, path)
+		return coalesce(ctx, &getG, key, func(ctx context.Context) (model.Obj, error) {
+			return retryCall(ctx, storage, func(ctx context.Context) (model.Obj, error) {
+				release, err := acquire(ctx, storage)
+				if err != nil {
+					return nil, err
+				}
+				defer release()
+				return g.Get(ctx, path)
+			})
+		})
 	}
 	// is root folder
 	if r, ok := storage.GetAddition().(driver.IRootFolderId); ok && utils.PathEqual(path, "/") {
@@ -134,20 +223,64 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 	}
 	key := stdpath.Join(storage.GetStorage().MountPath, path)
 	if link, ok := linkCache.Get(key); ok {
-		return link, file, nil
+		return limitLink(ctx, storage, accelerateLink(ctx, storage, networkLink(storage, link))), file, nil
 	}
-	fn := func() (*model.Link, error) {
-		link, err := storage.Link(ctx, file, args)
+	// the Data of a link can't be shared, so only the caller running the driver call
+	// can use a link with Data, the others have to call again
+	var own bool
+	fn := func(ctx context.Context) (*model.Link, error) {
+		own = true
+		var untrack func()
+		link, err := retryCall(ctx, storage, func(ctx context.Context) (*model.Link, error) {
+			releaseSlot, u, err := acquireApart(ctx, storage)
+			if err != nil {
+				return nil, err
+			}
+			untrack = u
+			start := time.Now()
+			link, err := storage.Link(ctx, file, args)
+			// the slot is for the call only, reading the Data doesn't take it
+			releaseSlot()
+			reportResult(storage, err, func(ctx context.Context, storageDriver driver.Driver) error {
+				// only the first byte, the links of some drivers are the streams of the files
+				probeArgs := args
+				probeArgs.Header = http.Header{"Range": []string{"bytes=0-0"}}
+				link, err := storageDriver.Link(ctx, file, probeArgs)
+				if err == nil && link.Data != nil {
+					_ = link.Data.Close()
+				}
+				return err
+			})
+			if err == nil {
+				reportLatency(storage, time.Since(start))
+			} else {
+				untrack()
+			}
+			return link, err
+		})
 		if err != nil {
 			return nil, errors.WithMessage(err, "failed get link")
 		}
-		if link.Expiration != nil {
+		// the storage is still in use until the Data is closed
+		link = holdLink(link, untrack)
+		if link.Expiration != nil && link.Data == nil {
 			linkCache.Set(key, link, cache.WithEx[*model.Link](*link.Expiration))
 		}
 		return link, nil
 	}
-	link, err, _ := linkG.Do(key, fn)
-	return link, file, err
+	// the Data of the link got by the caller having left is closed, nobody else can use it
+	link, err := coalesceOrDiscard(ctx, &linkG, key, fn, func(link *model.Link) {
+		if link.Data != nil {
+			_ = link.Data.Close()
+		}
+	})
+	if err == nil && link.Data != nil && !own {
+		link, err = fn(ctx)
+	}
+	if err != nil {
+		return nil, file, err
+	}
+	return limitLink(ctx, storage, accelerateLink(ctx, storage, networkLink(storage, link))), file, nil
 }
 
 func MakeDir(ctx context.Context, storage driver.Driver, path string) error {
@@ -165,6 +298,18 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string) error {
 			if err != nil {
 				return errors.WithMessagef(err, "failed to get parent dir [%s]", parentPath)
 			}
+			if err := CheckOperation(storage, model.OpMakeDir); err != nil {
+				return err
+			}
+			if err := checkCapability(storage, GetCapabilities(storage).MakeDir, "make dir"); err != nil {
+				return err
+			}
+			defer clearNotFound(storage)
+			release, err := acquire(ctx, storage)
+			if err != nil {
+				return err
+			}
+			defer release()
 			return storage.MakeDir(ctx, parentDir, dirName)
 		} else {
 			return errors.WithMessage(err, "failed to check if dir exists")
@@ -181,6 +326,19 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string) error {
 }
 
 func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string) error {
+	if err := CheckOperation(storage, model.OpMove); err != nil {
+		return err
+	}
+	if err := checkCapability(storage, GetCapabilities(storage).Move, model.OpMove); err != nil {
+		return err
+	}
+	if err := checkHold(ctx, storage, model.OpMove, srcPath, true); err != nil {
+		return err
+	}
+	// the held path may be overwritten by the moved one
+	if err := checkHold(ctx, storage, model.OpPut, stdpath.Join(dstDirPath, stdpath.Base(srcPath)), false); err != nil {
+		return err
+	}
 	srcObj, err := Get(ctx, storage, srcPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed to get src object")
@@ -189,28 +347,92 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	if err != nil {
 		return errors.WithMessage(err, "failed to get dst dir")
 	}
-	return storage.Move(ctx, srcObj, dstDir)
+	defer clearNotFound(storage)
+	release, err := acquire(ctx, storage)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = storage.Move(ctx, srcObj, dstDir)
+	if err == nil {
+		dropHashes(storage, srcPath)
+		// the files overwritten in the destination
+		dropHashes(storage, stdpath.Join(dstDirPath, srcObj.GetName()))
+	}
+	return err
 }
 
 func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string) error {
+	if err := CheckOperation(storage, model.OpRename); err != nil {
+		return err
+	}
+	if err := checkCapability(storage, GetCapabilities(storage).Rename, model.OpRename); err != nil {
+		return err
+	}
+	if err := checkHold(ctx, storage, model.OpRename, srcPath, true); err != nil {
+		return err
+	}
 	srcObj, err := Get(ctx, storage, srcPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed to get src object")
 	}
-	return storage.Rename(ctx, srcObj, dstName)
+	defer clearNotFound(storage)
+	release, err := acquire(ctx, storage)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = storage.Rename(ctx, srcObj, dstName)
+	if err == nil {
+		dropHashes(storage, srcPath)
+		dropHashes(storage, stdpath.Join(stdpath.Dir(srcPath), dstName))
+	}
+	return err
 }
 
 // Copy Just copy file[s] in a storage
 func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string) error {
+	if err := CheckOperation(storage, model.OpCopy); err != nil {
+		return err
+	}
 	srcObj, err := Get(ctx, storage, srcPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed to get src object")
 	}
+	if err := checkCapability(storage, CanCopy(storage, srcObj), model.OpCopy); err != nil {
+		return err
+	}
+	if err := checkHold(ctx, storage, model.OpPut, stdpath.Join(dstDirPath, srcObj.GetName()), false); err != nil {
+		return err
+	}
 	dstDir, err := Get(ctx, storage, dstDirPath)
-	return storage.Copy(ctx, srcObj, dstDir)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get dst dir")
+	}
+	defer clearNotFound(storage)
+	release, err := acquire(ctx, storage)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = storage.Copy(ctx, srcObj, dstDir)
+	if err == nil {
+		// the files overwritten in the destination
+		dropHashes(storage, stdpath.Join(dstDirPath, srcObj.GetName()))
+	}
+	return err
 }
 
 func Remove(ctx context.Context, storage driver.Driver, path string) error {
+	if err := CheckOperation(storage, model.OpRemove); err != nil {
+		return err
+	}
+	if err := checkCapability(storage, GetCapabilities(storage).Remove, model.OpRemove); err != nil {
+		return err
+	}
+	if err := checkHold(ctx, storage, model.OpRemove, path, true); err != nil {
+		return err
+	}
 	obj, err := Get(ctx, storage, path)
 	if err != nil {
 		// if object not found, it's ok
@@ -219,7 +441,17 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 		}
 		return errors.WithMessage(err, "failed to get object")
 	}
-	return storage.Remove(ctx, obj)
+	defer clearNotFound(storage)
+	release, err := acquire(ctx, storage)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = storage.Remove(ctx, obj)
+	if err == nil {
+		dropHashes(storage, path)
+	}
+	return err
 }
 
 func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress) error {
@@ -236,10 +468,20 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 			log.Errorf("failed to close file streamer, %v", err)
 		}
 	}()
+	if err := CheckOperation(storage, model.OpPut); err != nil {
+		return err
+	}
+	if err := checkCapability(storage, GetCapabilities(storage).Put, model.OpPut); err != nil {
+		return err
+	}
 	// if file exist and size = 0, delete it
 	dstPath := stdpath.Join(dstDirPath, file.GetName())
 	fi, err := Get(ctx, storage, dstPath)
 	if err == nil {
+		// overwrite the file
+		if err := checkHold(ctx, storage, model.OpPut, dstPath, false); err != nil {
+			return err
+		}
 		if fi.GetSize() == 0 {
 			err = Remove(ctx, storage, dstPath)
 			if err != nil {
@@ -261,12 +503,115 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	if up == nil {
 		up = func(p int) {}
 	}
-	err = storage.Put(ctx, parentDir, file, up)
-	log.Debugf("put file [%s] done", file.GetName())
+	hs := newHashingStream(file)
+	release, err := acquire(ctx, storage)
+	if err != nil {
+		return err
+	}
+	err = storage.Put(ctx, parentDir, limitStream(ctx, storage, hs), up)
+	release()
+	reportResult(storage, err)
+	clearNotFound(storage)
+	utils.Log(ctx).Debugf("put file [%s] done", file.GetName())
 	if err == nil {
 		// clear cache
 		key := stdpath.Join(storage.GetStorage().MountPath, dstDirPath)
 		filesCache.Del(key)
+		addUploaded(storage, file.GetSize())
+		saveHashes(ctx, storage, dstPath, hs)
+	}
+	return err
+}
+
+// Append the stream to the end of the file, errs.NotSupport if the driver can't append
+func Append(ctx context.Context, storage driver.Driver, path string, stream model.FileStreamer, up driver.UpdateProgress) error {
+	defer func() {
+		if err := stream.Close(); err != nil {
+			log.Errorf("failed to close file streamer, %v", err)
+		}
+	}()
+	if err := CheckOperation(storage, model.OpPut); err != nil {
+		return err
+	}
+	a, ok := storage.(driver.Append)
+	if !ok {
+		return errors.WithStack(errs.NotSupport)
+	}
+	file, err := Get(ctx, storage, path)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get file")
+	}
+	if file.IsDir() {
+		return errors.WithStack(errs.NotFile)
+	}
+	if err := checkHold(ctx, storage, model.OpPut, path, false); err != nil {
+		return err
+	}
+	if up == nil {
+		up = func(p int) {}
+	}
+	release, err := acquire(ctx, storage)
+	if err != nil {
+		return err
+	}
+	err = a.Append(ctx, file, limitStream(ctx, storage, stream), up)
+	release()
+	reportResult(storage, err)
+	if err == nil {
+		key := stdpath.Join(storage.GetStorage().MountPath, stdpath.Dir(path))
+		filesCache.Del(key)
+		linkCache.Del(stdpath.Join(storage.GetStorage().MountPath, path))
+		dropHashes(storage, path)
+		addUploaded(storage, stream.GetSize())
+	}
+	return err
+}
+
+// Patch write the stream into the file at offset, errs.NotSupport if the driver can't write in place
+func Patch(ctx context.Context, storage driver.Driver, path string, offset int64, stream model.FileStreamer, up driver.UpdateProgress) error {
+	defer func() {
+		if err := stream.Close(); err != nil {
+			log.Errorf("failed to close file streamer, %v", err)
+		}
+	}()
+	if err := CheckOperation(storage, model.OpPut); err != nil {
+		return err
+	}
+	p, ok := storage.(driver.Patch)
+	if !ok {
+		return errors.WithStack(errs.NotSupport)
+	}
+	file, err := Get(ctx, storage, path)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get file")
+	}
+	if file.IsDir() {
+		return errors.WithStack(errs.NotFile)
+	}
+	if err := checkHold(ctx, storage, model.OpPut, path, false); err != nil {
+		return err
+	}
+	if offset < 0 || offset > file.GetSize() {
+		return errors.Errorf("offset %d is out of the file", offset)
+	}
+	if up == nil {
+		up = func(p int) {}
+	}
+	release, err := acquire(ctx, storage)
+	if err != nil {
+		return err
+	}
+	err = p.Patch(ctx, file, offset, limitStream(ctx, storage, stream), up)
+	release()
+	reportResult(storage, err)
+	if err == nil {
+		key := stdpath.Join(storage.GetStorage().MountPath, stdpath.Dir(path))
+		filesCache.Del(key)
+		linkCache.Del(stdpath.Join(storage.GetStorage().MountPath, path))
+		dropHashes(storage, path)
+		if grown := offset + stream.GetSize() - file.GetSize(); grown > 0 {
+			addUploaded(storage, grown)
+		}
 	}
 	return err
 }
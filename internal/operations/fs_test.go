@@ -0,0 +1,55 @@
+package operations_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+func TestAppend(t *testing.T) {
+	dir, s := operations.CreateLocal(t, model.Storage{MountPath: "/append"})
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stream := &model.FileStream{
+		Obj:        model.Object{Name: "app.log", Size: 2},
+		ReadCloser: io.NopCloser(strings.NewReader("b\n")),
+	}
+	if err := operations.Append(context.Background(), s, filepath.Join(dir, "app.log"), stream, nil); err != nil {
+		t.Fatalf("failed append: %+v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a\nb\n" {
+		t.Errorf("expected the appended content, got %q", data)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	dir, s := operations.CreateLocal(t, model.Storage{MountPath: "/patch"})
+	if err := os.WriteFile(filepath.Join(dir, "blocks.bin"), []byte("aaaabbbb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stream := &model.FileStream{
+		Obj:        model.Object{Name: "blocks.bin", Size: 6},
+		ReadCloser: io.NopCloser(strings.NewReader("ccccdd")),
+	}
+	if err := operations.Patch(context.Background(), s, filepath.Join(dir, "blocks.bin"), 4, stream, nil); err != nil {
+		t.Fatalf("failed patch: %+v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "blocks.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "aaaaccccdd" {
+		t.Errorf("expected the patched content, got %q", data)
+	}
+}
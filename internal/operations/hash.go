@@ -0,0 +1,161 @@
+package operations
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterStorageHook(func(event StorageEvent) {
+		if event.Type != EventStorageDeleted {
+			return
+		}
+		if err := db.DeleteObjHashesByStorage(event.Storage.ID); err != nil {
+			log.Warnf("failed delete hashes of storage [%s]: %+v", event.Storage.MountPath, err)
+		}
+	})
+}
+
+// hashingStream compute the hashes of the file while the driver reads it
+type hashingStream struct {
+	model.FileStreamer
+	r      io.Reader
+	md5    hash.Hash
+	sha256 hash.Hash
+	read   int64
+}
+
+func newHashingStream(file model.FileStreamer) *hashingStream {
+	s := &hashingStream{FileStreamer: file, md5: md5.New(), sha256: sha256.New()}
+	s.r = io.TeeReader(file, io.MultiWriter(s.md5, s.sha256))
+	return s
+}
+
+func (s *hashingStream) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.read += int64(n)
+	return n, err
+}
+
+// complete is false if the driver didn't read the whole stream,
+// e.g. the file is rapid uploaded or the driver reads the temp file directly
+func (s *hashingStream) complete() bool {
+	return s.read == s.GetSize()
+}
+
+func (s *hashingStream) objHash(storage driver.Driver, path string) *model.ObjHash {
+	return &model.ObjHash{
+		StorageID: storage.GetStorage().ID,
+		Path:      path,
+		Size:      s.read,
+		MD5:       hex.EncodeToString(s.md5.Sum(nil)),
+		SHA256:    hex.EncodeToString(s.sha256.Sum(nil)),
+		Updated:   time.Now(),
+	}
+}
+
+// saveHashes verify the hashes computed during the upload against the ones
+// reported by the storage, and save them if they match. the mismatch is only logged,
+// the file is uploaded already and the storage may report the hash of another algo variant
+func saveHashes(ctx context.Context, storage driver.Driver, path string, s *hashingStream) {
+	if !s.complete() {
+		dropHashes(storage, path)
+		return
+	}
+	h := s.objHash(storage, path)
+	if obj, err := Get(ctx, storage, path); err == nil {
+		if reported, ok := obj.(model.Hash); ok {
+			for _, algo := range []string{model.HashMD5, model.HashSHA256} {
+				expected := strings.ToLower(reported.GetHash(algo))
+				if expected != "" && expected != h.GetHash(algo) {
+					log.Warnf("%s of [%s] mismatch after upload, expected %s, got %s", algo, path, expected, h.GetHash(algo))
+					dropHashes(storage, path)
+					return
+				}
+			}
+		}
+	}
+	if err := db.SaveObjHash(h); err != nil {
+		log.Warnf("failed save hashes of [%s]: %+v", path, err)
+	}
+}
+
+// dropHashes remove the saved hashes of the path that is changed, and everything under it
+func dropHashes(storage driver.Driver, path string) {
+	if err := db.DeleteObjHashes(storage.GetStorage().ID, path); err != nil {
+		log.Warnf("failed delete hashes of [%s]: %+v", path, err)
+	}
+}
+
+// GetHash return the hex of the algo of the file, from the storage if it's reported,
+// or from the hashes saved when the file was uploaded. empty if unknown
+func GetHash(ctx context.Context, storage driver.Driver, path, algo string) (string, error) {
+	obj, err := Get(ctx, storage, path)
+	if err != nil {
+		return "", err
+	}
+	if reported, ok := obj.(model.Hash); ok {
+		if sum := reported.GetHash(algo); sum != "" {
+			return strings.ToLower(sum), nil
+		}
+	}
+	h, err := db.GetObjHash(storage.GetStorage().ID, stdpath.Clean(path))
+	// the file may be changed out of alist
+	if err != nil || h.Size != obj.GetSize() {
+		return "", nil
+	}
+	return h.GetHash(algo), nil
+}
+
+// GetKnownHashes return the hashes of the files in the dir known without downloading them,
+// reported by the storage or saved when they were uploaded, keyed by the algo.
+// only the reported ones if storage is nil, e.g. the objs come from several storages
+func GetKnownHashes(storage driver.Driver, dirPath string, objs []model.Obj) []map[string]string {
+	saved := make(map[string]model.ObjHash)
+	if storage != nil {
+		hashes, err := db.GetObjHashesInDir(storage.GetStorage().ID, stdpath.Clean(dirPath))
+		if err != nil {
+			log.Warnf("failed get hashes in [%s]: %+v", dirPath, err)
+		}
+		for _, h := range hashes {
+			saved[stdpath.Base(h.Path)] = h
+		}
+	}
+	res := make([]map[string]string, len(objs))
+	for i, obj := range objs {
+		if obj.IsDir() {
+			continue
+		}
+		sums := make(map[string]string)
+		h, ok := saved[obj.GetName()]
+		// the file may be changed out of alist
+		ok = ok && h.Size == obj.GetSize()
+		for _, algo := range []string{model.HashMD5, model.HashSHA256} {
+			if reported, is := obj.(model.Hash); is {
+				if sum := reported.GetHash(algo); sum != "" {
+					sums[algo] = strings.ToLower(sum)
+					continue
+				}
+			}
+			if ok && h.GetHash(algo) != "" {
+				sums[algo] = h.GetHash(algo)
+			}
+		}
+		if len(sums) > 0 {
+			res[i] = sums
+		}
+	}
+	return res
+}
@@ -0,0 +1,53 @@
+package operations_test
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+)
+
+func TestUploadHash(t *testing.T) {
+	dir, s := operations.CreateLocal(t, model.Storage{MountPath: "/hash"})
+	stream := &model.FileStream{
+		Obj:        model.Object{Name: "a.txt", Size: 5},
+		ReadCloser: io.NopCloser(strings.NewReader("hello")),
+	}
+	if err := operations.Put(context.Background(), s, dir, stream, nil); err != nil {
+		t.Fatalf("failed put: %+v", err)
+	}
+	filePath := path.Join(dir, "a.txt")
+	sum, err := operations.GetHash(context.Background(), s, filePath, model.HashMD5)
+	if err != nil {
+		t.Fatalf("failed get hash: %+v", err)
+	}
+	if sum != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("expected the md5 computed during upload, got %q", sum)
+	}
+	// the hashes of the file overwritten by copying are dropped
+	dirPath := path.Join(dir, "sub")
+	stream = &model.FileStream{
+		Obj:        model.Object{Name: "a.txt", Size: 5},
+		ReadCloser: io.NopCloser(strings.NewReader("world")),
+	}
+	if err := operations.Put(context.Background(), s, dirPath, stream, nil); err != nil {
+		t.Fatalf("failed put: %+v", err)
+	}
+	if err := operations.Copy(context.Background(), s, filePath, dirPath); err != nil {
+		t.Fatalf("failed copy: %+v", err)
+	}
+	if _, err := db.GetObjHash(s.GetStorage().ID, path.Join(dirPath, "a.txt")); err == nil {
+		t.Errorf("expected the hash of the overwritten file to be dropped")
+	}
+	if err := operations.Remove(context.Background(), s, filePath); err != nil {
+		t.Fatalf("failed remove: %+v", err)
+	}
+	if _, err := db.GetObjHash(s.GetStorage().ID, filePath); err == nil {
+		t.Errorf("expected the hash to be dropped with the file")
+	}
+}
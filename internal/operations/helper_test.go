@@ -0,0 +1,40 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+// createLocal create a local storage of a temp folder with the settings of the storage,
+// it's deleted after the test. the temp folder and the storage are returned
+func createLocal(t *testing.T, storage model.Storage) (string, driver.Driver) {
+	t.Helper()
+	dir := t.TempDir()
+	storage.Driver = "Local"
+	storage.Addition = fmt.Sprintf(`{"root_folder":%q}`, dir)
+	if err := CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	s, err := GetStorageByVirtualPath(storage.MountPath)
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	t.Cleanup(func() {
+		// the test may have deleted it
+		if _, err := db.GetStorageById(s.GetStorage().ID); err != nil {
+			return
+		}
+		if err := DeleteStorageById(context.Background(), s.GetStorage().ID); err != nil {
+			t.Errorf("failed delete storage: %+v", err)
+		}
+	})
+	return dir, s
+}
+
+// CreateLocal is createLocal for the tests of operations_test
+var CreateLocal = createLocal
@@ -0,0 +1,72 @@
+package operations
+
+import (
+	"context"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// PlaceHold hold the virtual path, only admins should place the holds
+func PlaceHold(path, reason string, user *model.User) (*model.Hold, error) {
+	hold := model.Hold{
+		Path:    utils.StandardizePath(path),
+		Reason:  reason,
+		Creator: user.Username,
+		Created: time.Now(),
+	}
+	if err := db.CreateHold(&hold); err != nil {
+		return nil, errors.WithMessagef(err, "failed hold [%s]", hold.Path)
+	}
+	logHold(hold.Path, model.HoldPlaced, hold.Path, user.Username, reason)
+	return &hold, nil
+}
+
+// ReleaseHold release the hold, the reason is recorded for the audit
+func ReleaseHold(id uint, reason string, user *model.User) error {
+	hold, err := db.GetHoldById(id)
+	if err != nil {
+		return err
+	}
+	if err := db.DeleteHoldById(id); err != nil {
+		return err
+	}
+	logHold(hold.Path, model.HoldReleased, hold.Path, user.Username, reason)
+	return nil
+}
+
+func logHold(path, action, target, username, detail string) {
+	l := model.HoldLog{Path: path, Action: action, Target: target, Username: username, Detail: detail, Time: time.Now()}
+	if err := db.CreateHoldLog(&l); err != nil {
+		log.Errorf("failed record hold log of [%s]: %+v", path, err)
+	}
+}
+
+// checkHold reject the operation on the held path. if tree is true, the operation affects
+// everything inside the path, so the holds inside it reject it too
+func checkHold(ctx context.Context, storage driver.Driver, op, path string, tree bool) error {
+	holds, err := db.GetHolds()
+	if err != nil {
+		return err
+	}
+	target := VirtualPath(storage, path)
+	for _, hold := range holds {
+		if !utils.IsSubPath(hold.Path, target) && !(tree && utils.IsSubPath(target, hold.Path)) {
+			continue
+		}
+		var username string
+		if user, ok := ctx.Value("user").(*model.User); ok {
+			username = user.Username
+		}
+		log.Warnf("%s [%s] is blocked by the hold of [%s]", op, target, hold.Path)
+		logHold(hold.Path, model.HoldBlocked, target, username, op)
+		return errors.Wrapf(errs.PathOnHold, "can't %s [%s], [%s] is held", op, target, hold.Path)
+	}
+	return nil
+}
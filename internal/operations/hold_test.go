@@ -0,0 +1,58 @@
+package operations_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/pkg/errors"
+)
+
+func TestHold(t *testing.T) {
+	dir, s := operations.CreateLocal(t, model.Storage{MountPath: "/hold"})
+	for _, path := range []string{filepath.Join(dir, "archive", "2023"), filepath.Join(dir, "draft", "2023")} {
+		if err := os.MkdirAll(path, 0777); err != nil {
+			t.Fatalf("failed make dir: %+v", err)
+		}
+	}
+	admin := &model.User{Username: "admin", Role: model.ADMIN}
+	hold, err := operations.PlaceHold("/hold/archive/2023", "lawsuit", admin)
+	if err != nil {
+		t.Fatalf("failed place hold: %+v", err)
+	}
+	ctx := context.WithValue(context.Background(), "user", &model.User{Username: "bob"})
+	// removing the parent removes the held dir too
+	for _, path := range []string{filepath.Join(dir, "archive", "2023"), filepath.Join(dir, "archive")} {
+		if err := operations.Remove(ctx, s, path); !errors.Is(errors.Cause(err), errs.PathOnHold) {
+			t.Errorf("expected removing %s is blocked, got %+v", path, err)
+		}
+	}
+	if err := operations.Rename(ctx, s, filepath.Join(dir, "archive", "2023"), "2024"); !errors.Is(errors.Cause(err), errs.PathOnHold) {
+		t.Errorf("expected renaming is blocked, got %+v", err)
+	}
+	// the held dir would be overwritten
+	if err := operations.Copy(ctx, s, filepath.Join(dir, "draft", "2023"), filepath.Join(dir, "archive")); !errors.Is(errors.Cause(err), errs.PathOnHold) {
+		t.Errorf("expected copying over the held dir is blocked, got %+v", err)
+	}
+	if err := operations.Move(ctx, s, filepath.Join(dir, "draft", "2023"), filepath.Join(dir, "archive")); !errors.Is(errors.Cause(err), errs.PathOnHold) {
+		t.Errorf("expected moving over the held dir is blocked, got %+v", err)
+	}
+	logs, _, err := db.GetHoldLogs("/hold/archive/2023", 1, 10)
+	if err != nil || len(logs) != 6 || logs[0].Action != model.HoldBlocked || logs[0].Username != "bob" {
+		t.Errorf("unexpected hold logs: %+v %+v", logs, err)
+	}
+	if err := operations.ReleaseHold(hold.ID, "settled", admin); err != nil {
+		t.Fatalf("failed release hold: %+v", err)
+	}
+	if err := operations.Remove(ctx, s, filepath.Join(dir, "archive")); err != nil {
+		t.Errorf("failed remove after released: %+v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive")); !os.IsNotExist(err) {
+		t.Errorf("expected the dir is removed, got %+v", err)
+	}
+}
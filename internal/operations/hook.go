@@ -0,0 +1,67 @@
+package operations
+
+import (
+	"sync"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EventStorageCreated    = "created"
+	EventStorageUpdated    = "updated"
+	EventStorageDeleted    = "deleted"
+	EventStorageInitFailed = "init_failed"
+)
+
+type StorageEvent struct {
+	Type    string
+	Storage model.Storage
+	Err     error // only for init_failed
+}
+
+// StorageHook is called synchronously after the storage changed,
+// so it should return quickly and start a goroutine for slow work
+type StorageHook func(event StorageEvent)
+
+var (
+	storageHooks   = map[int]StorageHook{}
+	storageHookID  int
+	storageHooksMu sync.RWMutex
+)
+
+// RegisterStorageHook subscribe the lifecycle events of storages,
+// the returned func removes the hook
+func RegisterStorageHook(hook StorageHook) func() {
+	storageHooksMu.Lock()
+	defer storageHooksMu.Unlock()
+	storageHookID++
+	id := storageHookID
+	storageHooks[id] = hook
+	return func() {
+		storageHooksMu.Lock()
+		defer storageHooksMu.Unlock()
+		delete(storageHooks, id)
+	}
+}
+
+func emitStorageEvent(event StorageEvent) {
+	storageHooksMu.RLock()
+	hooks := make([]StorageHook, 0, len(storageHooks))
+	for _, hook := range storageHooks {
+		hooks = append(hooks, hook)
+	}
+	storageHooksMu.RUnlock()
+	for _, hook := range hooks {
+		callStorageHook(hook, event)
+	}
+}
+
+func callStorageHook(hook StorageHook, event StorageEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("storage hook panic on %s event of [%s]: %v", event.Type, event.Storage.MountPath, r)
+		}
+	}()
+	hook(event)
+}
@@ -0,0 +1,154 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+type inflight struct {
+	sync.Mutex
+	count int
+	idle  chan struct{} // closed when count drops to 0
+}
+
+// driver.Driver => *inflight, the driver instance is the key
+// because the mount path may be changed by update
+var inflights sync.Map
+
+// acquire wait for the rate limit and a free slot of the storage, then count an in-flight call to it,
+// the returned func must be called when it's done
+func acquire(ctx context.Context, storage driver.Driver) (func(), error) {
+	releaseSlot, untrack, err := acquireApart(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			untrack()
+			releaseSlot()
+		})
+	}, nil
+}
+
+// acquireApart is acquire returning the releases of the slot and the in-flight count apart,
+// for the calls returning a stream, which is in flight until closed but takes no slot
+func acquireApart(ctx context.Context, storage driver.Driver) (func(), func(), error) {
+	if err := waitRequest(ctx, storage); err != nil {
+		return nil, nil, err
+	}
+	releaseSlot, err := acquireSlot(ctx, storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	return releaseSlot, track(storage), nil
+}
+
+// track count an in-flight call to the storage, the returned func must be called when it's done
+func track(storage driver.Driver) func() {
+	v, _ := inflights.LoadOrStore(storage, &inflight{})
+	f := v.(*inflight)
+	f.Lock()
+	if f.count == 0 {
+		f.idle = make(chan struct{})
+	}
+	f.count++
+	f.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.Lock()
+			f.count--
+			if f.count == 0 {
+				close(f.idle)
+			}
+			f.Unlock()
+		})
+	}
+}
+
+// waitIdle wait for the in-flight calls to the storage to finish,
+// return false if timeout
+func waitIdle(ctx context.Context, storage driver.Driver, timeout time.Duration) bool {
+	v, ok := inflights.Load(storage)
+	if !ok {
+		return true
+	}
+	f := v.(*inflight)
+	f.Lock()
+	if f.count == 0 {
+		f.Unlock()
+		return true
+	}
+	idle := f.idle
+	f.Unlock()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+func dropTimeout() time.Duration {
+	if conf.Conf == nil {
+		return time.Duration(conf.DefaultConfig().DropTimeout) * time.Second
+	}
+	return time.Duration(conf.Conf.DropTimeout) * time.Second
+}
+
+// dropGracefully drop the storage after the in-flight calls finished,
+// or the drop timeout reached
+func dropGracefully(ctx context.Context, storage driver.Driver) error {
+	if !waitIdle(ctx, storage, dropTimeout()) {
+		log.Warnf("drop storage [%s] with calls still in flight", storage.GetStorage().MountPath)
+	}
+	inflights.Delete(storage)
+	return storage.Drop(ctx)
+}
+
+// retire drop the replaced driver instance in background, the new calls are
+// already served by the new instance, so only the in-flight ones are waited for
+func retire(storageDriver driver.Driver) {
+	// nothing to drop if it's never initialized
+	if isLazy(storageDriver) {
+		return
+	}
+	go func() {
+		// the status is not recorded, it belongs to the new instance now
+		if err := dropGracefully(context.Background(), storageDriver); err != nil {
+			log.Errorf("failed drop replaced storage [%s]: %+v", storageDriver.GetStorage().MountPath, err)
+		}
+	}()
+}
+
+type releaseCloser struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r releaseCloser) Close() error {
+	defer r.release()
+	return r.ReadCloser.Close()
+}
+
+// holdLink keep the call in flight until the Data of the link is closed
+func holdLink(link *model.Link, release func()) *model.Link {
+	if link.Data == nil {
+		release()
+		return link
+	}
+	held := *link
+	held.Data = releaseCloser{ReadCloser: link.Data, release: release}
+	return &held
+}
@@ -0,0 +1,105 @@
+package operations
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/singleflight"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// lazyDriver is stored in storagesMap when lazy init is enabled,
+// it holds an uninitialized driver and the raw storage,
+// the driver will be initialized on the first access
+type lazyDriver struct {
+	driver.Driver
+	storage model.Storage
+}
+
+func (d *lazyDriver) GetStorage() model.Storage {
+	return d.storage
+}
+
+func (d *lazyDriver) SetStorage(storage model.Storage) {
+	d.storage = storage
+}
+
+// CredentialExpiration forward to the driver, the ones not initialized yet are unknown
+func (d *lazyDriver) CredentialExpiration() time.Time {
+	if e, ok := d.Driver.(driver.CredentialExpirer); ok {
+		return e.CredentialExpiration()
+	}
+	return time.Time{}
+}
+
+// storagesMu serialize the updates of the storages in memory, so they don't overwrite each other
+var storagesMu sync.Mutex
+
+// updateStorageInMemory update the raw storage of the driver without init. the lazy driver
+// in storagesMap is copied and swapped, as it's read without lock when it's initialized
+func updateStorageInMemory(storageDriver driver.Driver, update func(storage *model.Storage)) {
+	storagesMu.Lock()
+	defer storagesMu.Unlock()
+	if d, ok := storageDriver.(*lazyDriver); ok {
+		mountPath := d.storage.MountPath
+		if cur, ok := storagesMap.Load(mountPath); ok && cur == driver.Driver(d) {
+			swapped := &lazyDriver{Driver: d.Driver, storage: d.storage}
+			update(&swapped.storage)
+			storagesMap.Store(mountPath, swapped)
+			return
+		}
+	}
+	storage := storageDriver.GetStorage()
+	update(&storage)
+	storageDriver.SetStorage(storage)
+}
+
+// isLazy the driver is a lazyDriver never initialized
+func isLazy(storageDriver driver.Driver) bool {
+	_, ok := storageDriver.(*lazyDriver)
+	return ok
+}
+
+var lazyG singleflight.Group[driver.Driver]
+
+// initIfLazy init the driver if it's still a lazyDriver,
+// and replace it in storagesMap with the initialized one
+func initIfLazy(storageDriver driver.Driver) (driver.Driver, error) {
+	d, ok := storageDriver.(*lazyDriver)
+	if !ok {
+		return storageDriver, nil
+	}
+	mountPath := d.storage.MountPath
+	res, err, _ := lazyG.Do(mountPath, func() (driver.Driver, error) {
+		// maybe initialized by another goroutine before
+		if cur, ok := storagesMap.Load(mountPath); ok {
+			if _, ok := cur.(*lazyDriver); !ok {
+				return cur, nil
+			}
+		}
+		log.Debugf("lazy init storage: [%s]", mountPath)
+		err := d.Driver.Init(context.Background(), d.storage)
+		if err != nil {
+			onInitFailed(d.Driver, d.storage, d.storage.InitAttempts+1, err)
+			return nil, errors.WithMessagef(err, "failed lazy init storage [%s]", mountPath)
+		}
+		storagesMu.Lock()
+		// the lazy one maybe swapped meanwhile, e.g. by reordering
+		if cur, ok := storagesMap.Load(mountPath); ok {
+			if l, ok := cur.(*lazyDriver); ok && l.storage.Index != d.storage.Index {
+				storage := d.Driver.GetStorage()
+				storage.Index = l.storage.Index
+				d.Driver.SetStorage(storage)
+			}
+		}
+		storagesMap.Store(mountPath, d.Driver)
+		storagesMu.Unlock()
+		onInitSucceeded(d.Driver, d.storage)
+		return d.Driver, nil
+	})
+	return res, err
+}
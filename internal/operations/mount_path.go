@@ -0,0 +1,74 @@
+package operations
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const illegalMountPathChars = "\\*?\"<>|"
+
+// ValidateMountPath check the mount path of the storage with id before saving it,
+// id is 0 for a new storage
+func ValidateMountPath(ctx context.Context, mountPath string, id uint) error {
+	if !strings.HasPrefix(mountPath, "/") {
+		return errors.Wrapf(errs.InvalidMountPath, "%s is not absolute", mountPath)
+	}
+	if strings.ContainsAny(mountPath, illegalMountPathChars) {
+		return errors.Wrapf(errs.InvalidMountPath, "%s contains any of %s", mountPath, illegalMountPathChars)
+	}
+	for _, r := range mountPath {
+		if r < 0x20 || r == 0x7f {
+			return errors.Wrapf(errs.InvalidMountPath, "%s contains control characters", mountPath)
+		}
+	}
+	mountPath = utils.StandardizePath(mountPath)
+	if mountPath != "/" {
+		for _, seg := range strings.Split(mountPath[1:], "/") {
+			if seg == "" || seg == "." || seg == ".." || strings.TrimSpace(seg) != seg {
+				return errors.Wrapf(errs.InvalidMountPath, "%s has an empty, relative or space padded segment", mountPath)
+			}
+		}
+	}
+	// the members of a balance group have different .balance suffixes,
+	// so the same mount path is always a conflict
+	if s, err := db.GetStorageByMountPath(mountPath); err == nil && s.ID != id {
+		return errors.Wrapf(errs.MountPathConflict, "%s is used by storage [%d]", mountPath, s.ID)
+	}
+	return checkMountPathIsFile(ctx, mountPath, id)
+}
+
+// checkMountPathIsFile check that the mount path is not a file in the storage mounted above it
+func checkMountPathIsFile(ctx context.Context, mountPath string, id uint) error {
+	var parent driver.Driver
+	var parentPath string
+	storagesMap.Range(func(key string, d driver.Driver) bool {
+		if d.GetStorage().ID == id {
+			return true
+		}
+		virtualPath := utils.GetActualVirtualPath(key)
+		if virtualPath == mountPath || !utils.IsSubPath(virtualPath, mountPath) || len(virtualPath) <= len(parentPath) {
+			return true
+		}
+		parent, parentPath = d, virtualPath
+		return true
+	})
+	if parent == nil {
+		return nil
+	}
+	// don't init a lazy storage just for checking
+	if _, ok := parent.(*lazyDriver); ok {
+		return nil
+	}
+	actualPath := ActualPath(parent.GetAddition(), strings.TrimPrefix(mountPath, strings.TrimSuffix(parentPath, "/")))
+	obj, err := Get(ctx, parent, actualPath)
+	if err == nil && !obj.IsDir() {
+		return errors.Wrapf(errs.MountPathIsFile, "%s is a file in storage [%s]", mountPath, parentPath)
+	}
+	return nil
+}
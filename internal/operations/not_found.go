@@ -0,0 +1,50 @@
+package operations
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+)
+
+// notFoundCache remember the paths which are not found recently,
+// so probing missing paths won't call the driver every time
+var notFoundCache = cache.NewMemCache(cache.WithShards[struct{}](16))
+
+// the generation of a storage is increased on every write,
+// which invalidates all the not found paths cached before
+var notFoundGens generic_sync.MapOf[string, *uint64]
+
+func notFoundKey(storage driver.Driver, path string) (string, bool) {
+	if conf.Conf.NotFoundCacheExpiration <= 0 || storage.Config().NoCache || storage.GetStorage().CacheExpiration < 0 {
+		return "", false
+	}
+	mountPath := storage.GetStorage().MountPath
+	gen, _ := notFoundGens.LoadOrStore(mountPath, new(uint64))
+	return fmt.Sprintf("%s#%d:%s", mountPath, atomic.LoadUint64(gen), path), true
+}
+
+func isNotFoundCached(storage driver.Driver, path string) bool {
+	key, ok := notFoundKey(storage, path)
+	if !ok {
+		return false
+	}
+	_, ok = notFoundCache.Get(key)
+	return ok
+}
+
+func cacheNotFound(storage driver.Driver, path string) {
+	if key, ok := notFoundKey(storage, path); ok {
+		notFoundCache.Set(key, struct{}{}, cache.WithEx[struct{}](time.Duration(conf.Conf.NotFoundCacheExpiration)*time.Second))
+	}
+}
+
+// clearNotFound should be called after writing to the storage
+func clearNotFound(storage driver.Driver) {
+	gen, _ := notFoundGens.LoadOrStore(storage.GetStorage().MountPath, new(uint64))
+	atomic.AddUint64(gen, 1)
+}
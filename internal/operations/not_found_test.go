@@ -0,0 +1,60 @@
+package operations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// cachedDriver the dirs of a storage changed out of alist
+type cachedDriver struct {
+	driver.Driver
+	storage model.Storage
+	dirs    map[string]bool
+}
+
+func (d *cachedDriver) Config() driver.Config {
+	return driver.Config{Name: "Cached"}
+}
+
+func (d *cachedDriver) GetStorage() model.Storage {
+	return d.storage
+}
+
+func (d *cachedDriver) GetAddition() driver.Additional {
+	return nil
+}
+
+func (d *cachedDriver) Get(ctx context.Context, path string) (model.Obj, error) {
+	if !d.dirs[path] {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
+	return &model.Object{ID: path, IsFolder: true}, nil
+}
+
+func (d *cachedDriver) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	return nil, nil
+}
+
+func TestRefreshNotFound(t *testing.T) {
+	expiration := conf.Conf.NotFoundCacheExpiration
+	conf.Conf.NotFoundCacheExpiration = 60
+	defer func() { conf.Conf.NotFoundCacheExpiration = expiration }()
+	d := &cachedDriver{storage: model.Storage{MountPath: "/not_found"}, dirs: map[string]bool{}}
+	if _, err := List(context.Background(), d, "/new"); !errs.IsObjectNotFound(err) {
+		t.Fatalf("expected the dir is not found, got %+v", err)
+	}
+	// created out of alist
+	d.dirs["/new"] = true
+	if _, err := List(context.Background(), d, "/new"); !errs.IsObjectNotFound(err) {
+		t.Fatalf("expected the not found is cached, got %+v", err)
+	}
+	if _, err := List(context.Background(), d, "/new", true); err != nil {
+		t.Errorf("expected the dir is found by refreshing, got %+v", err)
+	}
+}
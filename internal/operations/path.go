@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"github.com/alist-org/alist/v3/internal/errs"
 	stdpath "path"
 	"strings"
@@ -20,20 +21,25 @@ func ActualPath(storage driver.Additional, rawPath string) string {
 	return utils.StandardizePath(rawPath)
 }
 
+// VirtualPath the inverse of ActualPath, remove the actual root folder and join the virtual path
+func VirtualPath(storage driver.Driver, actualPath string) string {
+	if i, ok := storage.GetAddition().(driver.IRootFolderPath); ok {
+		actualPath = strings.TrimPrefix(utils.StandardizePath(actualPath), strings.TrimSuffix(utils.StandardizePath(i.GetRootFolderPath()), "/"))
+	}
+	return stdpath.Join(utils.GetActualVirtualPath(storage.GetStorage().MountPath), actualPath)
+}
+
 // GetStorageAndActualPath Get the corresponding storage and actual path
 // for path: remove the virtual path prefix and join the actual root folder if exists
-func GetStorageAndActualPath(rawPath string) (driver.Driver, string, error) {
+func GetStorageAndActualPath(ctx context.Context, rawPath string) (driver.Driver, string, error) {
 	rawPath = utils.StandardizePath(rawPath)
 	if strings.Contains(rawPath, "..") {
 		return nil, "", errors.WithStack(errs.RelativePath)
 	}
-	storage := GetBalancedStorage(rawPath)
+	storage := GetBalancedStorage(ctx, rawPath)
 	if storage == nil {
 		return nil, "", errors.Errorf("can't find storage with rawPath: %s", rawPath)
 	}
 	log.Debugln("use storage: ", storage.GetStorage().MountPath)
-	virtualPath := utils.GetActualVirtualPath(storage.GetStorage().MountPath)
-	actualPath := strings.TrimPrefix(rawPath, virtualPath)
-	actualPath = ActualPath(storage.GetAddition(), actualPath)
-	return storage, actualPath, nil
+	return storage, getActualPath(storage, rawPath), nil
 }
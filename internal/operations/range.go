@@ -0,0 +1,86 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// OpenRange open the content of the file from the offset, a negative length means till the end.
+// it's used by the overlay drivers reading the files of other storages, the bytes before the offset
+// are skipped if the storage responds the whole file
+func OpenRange(ctx context.Context, storage driver.Driver, path string, offset, length int64) (io.ReadCloser, error) {
+	rangeHeader := fmt.Sprintf("bytes=%d-", offset)
+	if length >= 0 {
+		rangeHeader += fmt.Sprint(offset + length - 1)
+	}
+	header := http.Header{"Range": []string{rangeHeader}}
+	link, _, err := Link(ctx, storage, path, model.LinkArgs{Header: header})
+	if err != nil {
+		return nil, err
+	}
+	var rc io.ReadCloser
+	// the bytes before the offset are still in the content
+	skip := offset
+	switch {
+	case link.Data != nil:
+		rc = link.Data
+		if link.Status == http.StatusPartialContent {
+			skip = 0
+		}
+	case link.FilePath != nil:
+		f, err := os.Open(*link.FilePath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			_ = f.Close()
+			return nil, errors.WithStack(err)
+		}
+		rc, skip = f, 0
+	default:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.URL, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for h, val := range link.Header {
+			req.Header[h] = val
+		}
+		req.Header.Set("Range", rangeHeader)
+		res, err := net.Client(link.Network).Do(req)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		switch res.StatusCode {
+		case http.StatusPartialContent:
+			skip = 0
+		case http.StatusOK:
+		default:
+			_ = res.Body.Close()
+			return nil, errors.WithMessagef(errs.NewStatusError(res), "failed open %s", path)
+		}
+		rc = res.Body
+		if link.Limiter != nil {
+			rc = utils.ReadCloser{Reader: utils.NewLimitedReader(ctx, rc, link.Limiter), Closer: rc}
+		}
+	}
+	if skip > 0 {
+		if _, err := io.CopyN(io.Discard, rc, skip); err != nil {
+			_ = rc.Close()
+			return nil, errors.Wrapf(err, "failed skip to %d of %s", offset, path)
+		}
+	}
+	if length >= 0 {
+		rc = utils.ReadCloser{Reader: io.LimitReader(rc, length), Closer: rc}
+	}
+	return rc, nil
+}
@@ -0,0 +1,73 @@
+package operations
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type ExpiringStorage struct {
+	Storage  model.Storage `json:"storage"`
+	ExpireAt time.Time     `json:"expire_at"`
+}
+
+// GetExpiringStorages get storages whose credentials will expire within `within`,
+// the expired ones are included too
+func GetExpiringStorages(within time.Duration) []ExpiringStorage {
+	res := make([]ExpiringStorage, 0)
+	deadline := time.Now().Add(within)
+	storagesMap.Range(func(key string, value driver.Driver) bool {
+		e, ok := value.(driver.CredentialExpirer)
+		if !ok {
+			return true
+		}
+		expireAt := e.CredentialExpiration()
+		if expireAt.IsZero() || expireAt.After(deadline) {
+			return true
+		}
+		res = append(res, ExpiringStorage{
+			Storage:  value.GetStorage(),
+			ExpireAt: expireAt,
+		})
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ExpireAt.Before(res[j].ExpireAt)
+	})
+	return res
+}
+
+// RemindExpiringStorages log the storages need to re-auth
+func RemindExpiringStorages(within time.Duration) {
+	for _, s := range GetExpiringStorages(within) {
+		if s.ExpireAt.Before(time.Now()) {
+			log.Warnf("credentials of storage [%s] expired at %s, please re-auth it", s.Storage.MountPath, s.ExpireAt)
+		} else {
+			log.Warnf("credentials of storage [%s] will expire at %s, please re-auth it", s.Storage.MountPath, s.ExpireAt)
+		}
+	}
+}
+
+// ReauthStorage only replace the credential fields of the addition,
+// then reinitialize the storage, other fields are kept
+func ReauthStorage(ctx context.Context, id uint, credentials map[string]interface{}) error {
+	if len(credentials) == 0 {
+		return errors.New("no credentials provided")
+	}
+	storage, err := db.GetStorageById(id)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	storage.Addition, err = utils.MergeJson(storage.Addition, credentials)
+	if err != nil {
+		return errors.Wrap(err, "failed merge credentials into addition")
+	}
+	return UpdateStorage(ctx, *storage)
+}
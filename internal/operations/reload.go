@@ -0,0 +1,154 @@
+package operations
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type ReloadResult struct {
+	Loaded   []string          `json:"loaded"`
+	Reloaded []string          `json:"reloaded"`
+	Dropped  []string          `json:"dropped"`
+	Errors   map[string]string `json:"errors"` // mount path => error
+}
+
+// reloadMu make sure only one reload is running
+var reloadMu sync.Mutex
+
+// ReloadStorages diff the storages in the database with the loaded ones,
+// load the new ones, drop the removed ones and reinitialize the changed ones
+func ReloadStorages(ctx context.Context) (*ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	storages, err := db.GetAllStorages()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storages")
+	}
+	loaded := make(map[uint]driver.Driver)
+	storagesMap.Range(func(key string, value driver.Driver) bool {
+		// not saved in database, e.g. loaded by tests
+		if id := value.GetStorage().ID; id != 0 {
+			loaded[id] = value
+		}
+		return true
+	})
+	res := &ReloadResult{Errors: map[string]string{}}
+	fail := func(mountPath string, err error) {
+		log.Errorf("failed reload storage [%s]: %+v", mountPath, err)
+		res.Errors[mountPath] = err.Error()
+	}
+	rows := make(map[uint]struct{}, len(storages))
+	for _, storage := range storages {
+		rows[storage.ID] = struct{}{}
+	}
+	// drop the removed ones first, so their mount paths can be reused
+	for id, storageDriver := range loaded {
+		if _, ok := rows[id]; ok {
+			continue
+		}
+		old := storageDriver.GetStorage()
+		if err := unloadStorage(ctx, storageDriver); err != nil {
+			fail(old.MountPath, err)
+			continue
+		}
+		cancelInitRetry(id)
+		emitStorageEvent(StorageEvent{Type: EventStorageDeleted, Storage: old})
+		res.Dropped = append(res.Dropped, old.MountPath)
+	}
+	for _, storage := range storages {
+		storage.MountPath = utils.StandardizePath(storage.MountPath)
+		storageDriver, ok := loaded[storage.ID]
+		if !ok {
+			if err := LoadStorage(ctx, storage); err != nil {
+				fail(storage.MountPath, err)
+				continue
+			}
+			res.Loaded = append(res.Loaded, storage.MountPath)
+			continue
+		}
+		applied, err := applyCredential(storage)
+		if err != nil {
+			fail(storage.MountPath, errors.WithMessage(err, "failed apply credential"))
+			continue
+		}
+		if !storageChanged(storageDriver, applied) {
+			continue
+		}
+		if err := unloadStorage(ctx, storageDriver); err != nil {
+			fail(storage.MountPath, err)
+			continue
+		}
+		err = LoadStorage(ctx, storage)
+		emitStorageEvent(StorageEvent{Type: EventStorageUpdated, Storage: storage})
+		if err != nil {
+			fail(storage.MountPath, err)
+			continue
+		}
+		res.Reloaded = append(res.Reloaded, storage.MountPath)
+	}
+	return res, nil
+}
+
+// unloadStorage drop the storage and remove it from memory
+func unloadStorage(ctx context.Context, storageDriver driver.Driver) error {
+	mountPath := storageDriver.GetStorage().MountPath
+	// a lazy one has never been initialized
+	if _, lazy := storageDriver.(*lazyDriver); !lazy {
+		if err := dropGracefully(ctx, storageDriver); err != nil {
+			onDropFailed(storageDriver, err)
+			return errors.WithMessage(err, "failed drop storage")
+		}
+	}
+	storagesMap.Delete(mountPath)
+	ClearCache(storageDriver, "/")
+	return nil
+}
+
+// storageChanged compare the config of the loaded storage with the one in database,
+// the runtime states are ignored
+func storageChanged(storageDriver driver.Driver, storage model.Storage) bool {
+	cur := storageDriver.GetStorage()
+	if !cur.Modified.Equal(storage.Modified) {
+		return true
+	}
+	// the addition in memory is not updated when the driver saves it,
+	// so compare with the current addition of the driver
+	if additionChanged(storageDriver, storage.Addition) {
+		return true
+	}
+	for _, s := range []*model.Storage{&cur, &storage} {
+		s.Modified = storage.Modified
+		s.Addition = ""
+		s.Status, s.InitAttempts, s.LastError = "", 0, ""
+	}
+	return !reflect.DeepEqual(cur, storage)
+}
+
+// additionChanged check whether any field in the addition differs from the driver
+func additionChanged(storageDriver driver.Driver, addition string) bool {
+	var want, cur map[string]interface{}
+	if err := utils.Json.UnmarshalFromString(addition, &want); err != nil {
+		return true
+	}
+	bytes, err := utils.Json.Marshal(storageDriver.GetAddition())
+	if err != nil {
+		return true
+	}
+	if err := utils.Json.Unmarshal(bytes, &cur); err != nil {
+		return true
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(cur[k], v) {
+			return true
+		}
+	}
+	return false
+}
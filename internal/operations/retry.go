@@ -0,0 +1,95 @@
+package operations
+
+import (
+	"context"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	initRetryBase     = 30 * time.Second
+	initRetryMax      = 30 * time.Minute
+	initRetryAttempts = 10
+)
+
+// storage id => timer of the next init retry
+var initRetryTimers generic_sync.MapOf[uint, *time.Timer]
+
+func initRetryDelay(attempts int) time.Duration {
+	delay := initRetryBase
+	for i := 1; i < attempts && delay < initRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > initRetryMax {
+		delay = initRetryMax
+	}
+	return delay
+}
+
+// onInitFailed record the error to the storage and schedule the next retry
+func onInitFailed(storageDriver driver.Driver, storage model.Storage, attempts int, err error) {
+	closeTokenManagers(storageDriver)
+	setStatus(storageDriver, storage, model.StorageInitFailed, attempts, err.Error())
+	emitStorageEvent(StorageEvent{Type: EventStorageInitFailed, Storage: storage, Err: err})
+	if attempts >= initRetryAttempts {
+		log.Errorf("give up init storage [%s] after %d attempts", storage.MountPath, attempts)
+		return
+	}
+	delay := initRetryDelay(attempts)
+	log.Warnf("failed init storage [%s], will retry in %s", storage.MountPath, delay)
+	cancelInitRetry(storage.ID)
+	initRetryTimers.Store(storage.ID, time.AfterFunc(delay, func() {
+		retryInit(storage, attempts)
+	}))
+}
+
+// onInitSucceeded reset the init state recorded before
+func onInitSucceeded(storageDriver driver.Driver, storage model.Storage) {
+	activateTokenManagers(storageDriver)
+	cancelInitRetry(storage.ID)
+	setStatus(storageDriver, storage, model.StorageWork, 0, "")
+}
+
+func cancelInitRetry(id uint) {
+	if timer, ok := initRetryTimers.Load(id); ok {
+		timer.Stop()
+		initRetryTimers.Delete(id)
+	}
+}
+
+func retryInit(storage model.Storage, attempts int) {
+	initRetryTimers.Delete(storage.ID)
+	// the storage maybe deleted or updated after the failure
+	cur, err := db.GetStorageById(storage.ID)
+	if err != nil || !cur.Modified.Equal(storage.Modified) {
+		return
+	}
+	storage.Status, storage.InitAttempts, storage.LastError = cur.Status, cur.InitAttempts, cur.LastError
+	driverNew, err := GetDriverNew(storage.Driver)
+	if err != nil {
+		log.Errorf("failed get driver new: %+v", err)
+		return
+	}
+	storageDriver := driverNew()
+	storage, err = applyCredential(storage)
+	if err == nil {
+		err = storageDriver.Init(context.Background(), storage)
+	}
+	if err != nil {
+		onInitFailed(storageDriver, storage, attempts+1, err)
+		return
+	}
+	if old, ok := storagesMap.Load(storage.MountPath); ok {
+		if _, lazy := old.(*lazyDriver); !lazy {
+			_ = old.Drop(context.Background())
+		}
+	}
+	storagesMap.Store(storage.MountPath, storageDriver)
+	onInitSucceeded(storageDriver, storage)
+	log.Infof("success init storage [%s] after %d failed attempts", storage.MountPath, attempts)
+}
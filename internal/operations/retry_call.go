@@ -0,0 +1,86 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	callRetryBackoff    = 500 * time.Millisecond
+	callRetryBackoffMax = 10 * time.Second
+)
+
+// the status of the responses worth retrying, the drivers wrap them as errs.StatusError
+var retryableStatus = map[int]bool{http.StatusTooManyRequests: true, http.StatusInternalServerError: true,
+	http.StatusBadGateway: true, http.StatusServiceUnavailable: true, http.StatusGatewayTimeout: true}
+
+// isRetryable tell whether the error of the driver call is transient
+func isRetryable(storage driver.Driver, err error) bool {
+	if !isStorageError(err) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	cause := errors.Cause(err)
+	for _, e := range []error{context.DeadlineExceeded, io.ErrUnexpectedEOF, syscall.ECONNRESET, syscall.ECONNREFUSED} {
+		if errors.Is(cause, e) {
+			return true
+		}
+	}
+	if retryableStatus[errs.StatusCode(err)] {
+		return true
+	}
+	msg := err.Error()
+	for _, s := range strings.Split(storage.GetStorage().RetryOn, ",") {
+		if s = strings.TrimSpace(s); s != "" && strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func callRetryDelay(storage driver.Driver, attempts int) time.Duration {
+	delay := callRetryBackoff
+	if ms := storage.GetStorage().RetryBackoff; ms > 0 {
+		delay = time.Duration(ms) * time.Millisecond
+	}
+	for i := 1; i < attempts && delay < callRetryBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > callRetryBackoffMax {
+		delay = callRetryBackoffMax
+	}
+	return delay
+}
+
+// retryCall call the driver again with backoff if it fails transiently. only the read calls are retried,
+// the writes may have been done before failing, and the streams of the uploads can't be read again
+func retryCall[T any](ctx context.Context, storage driver.Driver, call func(ctx context.Context) (T, error)) (T, error) {
+	for attempts := 1; ; attempts++ {
+		res, err := call(ctx)
+		if err == nil || attempts > storage.GetStorage().RetryAttempts || ctx.Err() != nil || !isRetryable(storage, err) {
+			return res, err
+		}
+		delay := callRetryDelay(storage, attempts)
+		log.Warnf("storage [%s] failed transiently, retry %d in %s: %+v", storage.GetStorage().MountPath, attempts, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+func TestIsRetryable(t *testing.T) {
+	status := func(code int) error {
+		return errs.NewStatusError(&http.Response{StatusCode: code, Status: http.StatusText(code)})
+	}
+	storage := &throttledDriver{storage: model.Storage{RetryOn: "rate limited, busy"}}
+	for _, c := range []struct {
+		name string
+		err  error
+		ok   bool
+	}{
+		{name: "unavailable", err: errors.WithMessage(status(http.StatusServiceUnavailable), "failed list"), ok: true},
+		{name: "too many requests", err: errors.WithMessage(status(http.StatusTooManyRequests), "failed get"), ok: true},
+		{name: "not found status", err: errors.WithMessage(status(http.StatusNotFound), "failed get"), ok: false},
+		{name: "forbidden", err: status(http.StatusForbidden), ok: false},
+		// a name of a file isn't taken for the status
+		{name: "status text in the message", err: errors.New("failed get Service Unavailable.txt"), ok: false},
+		{name: "unexpected eof", err: errors.WithStack(io.ErrUnexpectedEOF), ok: true},
+		{name: "deadline", err: errors.WithStack(context.DeadlineExceeded), ok: true},
+		{name: "canceled", err: errors.WithStack(context.Canceled), ok: false},
+		{name: "object not found", err: errors.WithStack(errs.ObjectNotFound), ok: false},
+		{name: "retry on", err: errors.New("the server is busy"), ok: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if ok := isRetryable(storage, c.err); ok != c.ok {
+				t.Errorf("expected retryable %v, got %v", c.ok, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package operations
+
+import (
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// setStatus update the status of the storage in memory and database,
+// storage is the one saved in database before, used to skip the unchanged update
+func setStatus(storageDriver driver.Driver, storage model.Storage, status string, attempts int, lastError string) {
+	updateStorageInMemory(storageDriver, func(cur *model.Storage) {
+		// the driver maybe failed before saving the storage
+		if cur.ID != storage.ID {
+			*cur = storage
+		}
+		cur.Status, cur.InitAttempts, cur.LastError = status, attempts, lastError
+	})
+	if storage.Status == status && storage.InitAttempts == attempts && storage.LastError == lastError {
+		return
+	}
+	if err := db.UpdateStorageStatus(storage.ID, status, attempts, lastError); err != nil {
+		log.Errorf("failed update status of storage [%s]: %+v", storage.MountPath, err)
+	}
+}
+
+// onDropFailed record the error of dropping, the status is not changed
+// because the storage is still working
+func onDropFailed(storageDriver driver.Driver, err error) {
+	storage := storageDriver.GetStorage()
+	setStatus(storageDriver, storage, storage.Status, storage.InitAttempts, errors.WithMessage(err, "failed drop").Error())
+}
@@ -5,11 +5,14 @@ import (
 	log "github.com/sirupsen/logrus"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/db"
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
 	"github.com/alist-org/alist/v3/pkg/generic_sync"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
@@ -25,7 +28,41 @@ func GetStorageByVirtualPath(virtualPath string) (driver.Driver, error) {
 	if !ok {
 		return nil, errors.Errorf("no virtual path for an storage is: %s", virtualPath)
 	}
-	return storageDriver, nil
+	return initIfLazy(storageDriver)
+}
+
+// LoadStorage load exist storage in db to memory
+// if lazy init is enabled, the driver will be initialized on first access.
+// no created event is emitted, the storage is created before
+func LoadStorage(ctx context.Context, storage model.Storage) error {
+	storage.MountPath = utils.StandardizePath(storage.MountPath)
+	if _, ok := storagesMap.Load(storage.MountPath); ok {
+		return errors.Errorf("storage [%s] is already loaded", storage.MountPath)
+	}
+	driverNew, err := GetDriverNew(storage.Driver)
+	if err != nil {
+		return errors.WithMessage(err, "failed get driver new")
+	}
+	storageDriver := driverNew()
+	storage, err = applyCredential(storage)
+	if err != nil {
+		return errors.WithMessage(err, "failed apply credential")
+	}
+	if conf.Conf.LazyInit {
+		lazy := &lazyDriver{Driver: storageDriver, storage: storage}
+		storagesMap.Store(storage.MountPath, lazy)
+		setStatus(lazy, storage, model.StoragePending, storage.InitAttempts, storage.LastError)
+		return nil
+	}
+	err = storageDriver.Init(ctx, storage)
+	// store it even if failed, so that it can be updated or deleted later
+	storagesMap.Store(storage.MountPath, storageDriver)
+	if err != nil {
+		onInitFailed(storageDriver, storage, storage.InitAttempts+1, err)
+		return errors.WithMessage(err, "failed init storage")
+	}
+	onInitSucceeded(storageDriver, storage)
+	return nil
 }
 
 // CreateStorage Save the storage to database so storage can get an id
@@ -33,6 +70,14 @@ func GetStorageByVirtualPath(virtualPath string) (driver.Driver, error) {
 func CreateStorage(ctx context.Context, storage model.Storage) error {
 	storage.Modified = time.Now()
 	storage.MountPath = utils.StandardizePath(storage.MountPath)
+	storage.Tags = strings.Join(storage.GetTags(), ",")
+	storage.DependsOn = strings.Join(storage.GetDependsOn(), ",")
+	if err := ValidateMountPath(ctx, storage.MountPath, 0); err != nil {
+		return err
+	}
+	if err := checkDependencyLoop(storage); err != nil {
+		return err
+	}
 	var err error
 	// check driver first
 	driverName := storage.Driver
@@ -40,6 +85,9 @@ func CreateStorage(ctx context.Context, storage model.Storage) error {
 	if err != nil {
 		return errors.WithMessage(err, "failed get driver new")
 	}
+	if err := validateStorage(storage); err != nil {
+		return err
+	}
 	storageDriver := driverNew()
 	// insert storage to database
 	err = db.CreateStorage(&storage)
@@ -47,49 +95,160 @@ func CreateStorage(ctx context.Context, storage model.Storage) error {
 		return errors.WithMessage(err, "failed create storage in database")
 	}
 	// already has an id
+	storage, err = applyCredential(storage)
+	if err != nil {
+		return errors.WithMessage(err, "failed apply credential but storage is already created")
+	}
 	err = storageDriver.Init(ctx, storage)
+	// store it even if failed, so that it can be updated or deleted later
+	storagesMap.Store(storage.MountPath, storageDriver)
+	emitStorageEvent(StorageEvent{Type: EventStorageCreated, Storage: storage})
 	if err != nil {
+		onInitFailed(storageDriver, storage, 1, err)
 		return errors.WithMessage(err, "failed init storage but storage is already created")
 	}
+	onInitSucceeded(storageDriver, storage)
 	log.Debugf("storage %+v is created", storageDriver)
-	storagesMap.Store(storage.MountPath, storageDriver)
 	return nil
 }
 
+type LoadResult struct {
+	Storage model.Storage
+	Err     error
+}
+
+// LoadStorages load storages concurrently with at most `concurrency` workers,
+// the results are in the same order as the input storages
+func LoadStorages(ctx context.Context, storages []model.Storage, concurrency int) []LoadResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]LoadResult, len(storages))
+	workerC := make(chan struct{}, concurrency)
+	// the storages depended on are loaded first
+	for _, layer := range dependencyLayers(storages) {
+		var wg sync.WaitGroup
+		for _, i := range layer {
+			wg.Add(1)
+			workerC <- struct{}{}
+			go func(i int) {
+				defer func() {
+					<-workerC
+					wg.Done()
+				}()
+				results[i] = LoadResult{
+					Storage: storages[i],
+					Err:     LoadStorage(ctx, storages[i]),
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+	return results
+}
+
 // UpdateStorage update storage
 // get old storage first
-// drop the storage then reinitialize
+// init a new instance of the driver then swap it with the old one
 func UpdateStorage(ctx context.Context, storage model.Storage) error {
+	oldStorage, err := prepareUpdate(ctx, &storage)
+	if err != nil {
+		return err
+	}
+	err = db.UpdateStorage(&storage)
+	if err != nil {
+		return errors.WithMessage(err, "failed update storage in database")
+	}
+	return swapUpdated(ctx, *oldStorage, storage)
+}
+
+// prepareUpdate validate and normalize the updated storage before it's saved, return the old one
+func prepareUpdate(ctx context.Context, storage *model.Storage) (*model.Storage, error) {
 	oldStorage, err := db.GetStorageById(storage.ID)
 	if err != nil {
-		return errors.WithMessage(err, "failed get old storage")
+		return nil, errors.WithMessage(err, "failed get old storage")
 	}
 	if oldStorage.Driver != storage.Driver {
-		return errors.Errorf("driver cannot be changed")
+		return nil, errors.Errorf("driver cannot be changed")
 	}
+	// switched by the failover, not by the admin
+	storage.OnBackup = oldStorage.OnBackup && storage.BackupCredentialID != 0
 	storage.Modified = time.Now()
 	storage.MountPath = utils.StandardizePath(storage.MountPath)
-	err = db.UpdateStorage(&storage)
-	if err != nil {
-		return errors.WithMessage(err, "failed update storage in database")
+	storage.Tags = strings.Join(storage.GetTags(), ",")
+	storage.DependsOn = strings.Join(storage.GetDependsOn(), ",")
+	if err := ValidateMountPath(ctx, storage.MountPath, storage.ID); err != nil {
+		return nil, err
+	}
+	if err := checkDependencyLoop(*storage); err != nil {
+		return nil, err
 	}
-	storageDriver, err := GetStorageByVirtualPath(oldStorage.MountPath)
+	if err := validateStorage(*storage); err != nil {
+		return nil, err
+	}
+	return oldStorage, nil
+}
+
+// swapUpdated apply the updated storage saved in database to the memory
+func swapUpdated(ctx context.Context, oldStorage, storage model.Storage) error {
+	if oldStorage.MountPath != storage.MountPath {
+		if _, err := db.RewritePath(oldStorage.MountPath, storage.MountPath, false); err != nil {
+			log.Errorf("failed rewrite records of mount path %s: %+v", oldStorage.MountPath, err)
+		}
+	}
+	if oldStorage.MountPath != storage.MountPath {
+		unpinMember(oldStorage.MountPath)
+		if err := rewriteDependents(oldStorage.MountPath, storage.MountPath); err != nil {
+			log.Errorf("failed rewrite dependents of mount path %s: %+v", oldStorage.MountPath, err)
+		}
+	}
+	if oldStorage.MountPath != storage.MountPath || !storage.DebugCapture {
+		net.RemoveCaptures(oldStorage.MountPath)
+	}
+	// not initialized if it's lazy, the storage may be updated because it fails to init
+	storageDriver, ok := storagesMap.Load(oldStorage.MountPath)
 	if oldStorage.MountPath != storage.MountPath {
 		// virtual path renamed, need to drop the storage
 		storagesMap.Delete(oldStorage.MountPath)
 	}
+	if !ok {
+		return errors.Errorf("no virtual path for an storage is: %s", oldStorage.MountPath)
+	}
+	return reinitStorage(ctx, storageDriver, storage)
+}
+
+// reinitStorage init a new instance of the driver with the updated storage and swap it in,
+// the old instance keeps serving the in-flight calls until they finish or the drop timeout reached
+func reinitStorage(ctx context.Context, oldDriver driver.Driver, storage model.Storage) error {
+	driverNew, err := GetDriverNew(storage.Driver)
 	if err != nil {
-		return errors.WithMessage(err, "failed get storage driver")
+		return errors.WithMessage(err, "failed get driver new")
 	}
-	err = storageDriver.Drop(ctx)
+	storageDriver := driverNew()
+	storage, err = applyCredential(storage)
 	if err != nil {
-		return errors.WithMessage(err, "failed drop storage")
+		return errors.WithMessage(err, "failed apply credential")
 	}
+	// never initialized, so it's still initialized on the first access
+	if isLazy(oldDriver) {
+		lazy := &lazyDriver{Driver: storageDriver, storage: storage}
+		storagesMap.Store(storage.MountPath, lazy)
+		setStatus(lazy, storage, model.StoragePending, 0, "")
+		emitStorageEvent(StorageEvent{Type: EventStorageUpdated, Storage: storage})
+		return nil
+	}
+	// the old instance would save its rotated refresh token over the updated addition
+	closeTokenManagers(oldDriver)
 	err = storageDriver.Init(ctx, storage)
+	// store it even if failed, so that it can be updated or deleted later
+	storagesMap.Store(storage.MountPath, storageDriver)
+	retire(oldDriver)
+	emitStorageEvent(StorageEvent{Type: EventStorageUpdated, Storage: storage})
 	if err != nil {
+		onInitFailed(storageDriver, storage, 1, err)
 		return errors.WithMessage(err, "failed init storage")
 	}
-	storagesMap.Store(storage.MountPath, storageDriver)
+	onInitSucceeded(storageDriver, storage)
 	return nil
 }
 
@@ -98,13 +257,19 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
 	}
-	storageDriver, err := GetStorageByVirtualPath(storage.MountPath)
-	if err != nil {
-		return errors.WithMessage(err, "failed get storage driver")
+	// not initialized if it's lazy, the storage may be deleted because it fails to init
+	storageDriver, ok := storagesMap.Load(storage.MountPath)
+	if !ok {
+		return errors.Errorf("no virtual path for an storage is: %s", storage.MountPath)
 	}
-	// drop the storage in the driver
-	if err := storageDriver.Drop(ctx); err != nil {
-		return errors.WithMessage(err, "failed drop storage")
+	// the dependents can't work without it
+	dropDependents(ctx, storage.MountPath)
+	// drop the storage in the driver, nothing to drop if it's never initialized
+	if !isLazy(storageDriver) {
+		if err := dropGracefully(ctx, storageDriver); err != nil {
+			onDropFailed(storageDriver, err)
+			return errors.WithMessage(err, "failed drop storage")
+		}
 	}
 	// delete the storage in the database
 	if err := db.DeleteStorageById(id); err != nil {
@@ -112,6 +277,10 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 	}
 	// delete the storage in the memory
 	storagesMap.Delete(storage.MountPath)
+	net.RemoveCaptures(storage.MountPath)
+	unpinMember(storage.MountPath)
+	cancelInitRetry(id)
+	emitStorageEvent(StorageEvent{Type: EventStorageDeleted, Storage: *storage})
 	return nil
 }
 
@@ -131,6 +300,16 @@ func saveDriverStorage(driver driver.Driver) error {
 		return errors.Wrap(err, "error while marshal addition")
 	}
 	storage.Addition = string(bytes)
+	if storage.GetCredentialID() != 0 {
+		storage.Addition, err = splitCredential(storage.GetCredentialID(), storage.Addition)
+		if err != nil {
+			return errors.WithMessage(err, "failed split credential from addition")
+		}
+	}
+	// only the refreshed credential of a probe is kept, a rotated token is invalid once refreshed
+	if isProbe(driver) {
+		return nil
+	}
 	err = db.UpdateStorage(&storage)
 	if err != nil {
 		return errors.WithMessage(err, "failed update storage in database")
@@ -218,29 +397,140 @@ func GetStorageVirtualFilesByPath(prefix string) []model.Obj {
 	return files
 }
 
+const (
+	ShadowMerge      = "merge"
+	ShadowMountWins  = "mount_wins"
+	ShadowHideParent = "hide_parent"
+)
+
+// CombineVirtualFiles combine the objs listed from the storage with the virtual files
+// of the storages mounted inside, according to the shadow policy of the storage
+func CombineVirtualFiles(storage driver.Driver, objs, virtualFiles []model.Obj) []model.Obj {
+	if len(virtualFiles) == 0 {
+		return objs
+	}
+	names := make(map[string]struct{}, len(virtualFiles))
+	for _, f := range virtualFiles {
+		names[f.GetName()] = struct{}{}
+	}
+	switch storage.GetStorage().ShadowPolicy {
+	case ShadowHideParent:
+		return virtualFiles
+	case ShadowMountWins:
+		res := make([]model.Obj, 0, len(objs)+len(virtualFiles))
+		for _, obj := range objs {
+			if _, ok := names[obj.GetName()]; !ok {
+				res = append(res, obj)
+			}
+		}
+		return append(res, virtualFiles...)
+	default:
+		// don't append to objs, it's shared with the cache
+		res := make([]model.Obj, 0, len(objs)+len(virtualFiles))
+		listed := make(map[string]struct{}, len(objs))
+		for _, obj := range objs {
+			listed[obj.GetName()] = struct{}{}
+			res = append(res, obj)
+		}
+		for _, f := range virtualFiles {
+			if _, ok := listed[f.GetName()]; !ok {
+				res = append(res, f)
+			}
+		}
+		return res
+	}
+}
+
 var balanceMap generic_sync.MapOf[string, int]
 
+func pickRoundRobin(storages []driver.Driver) int {
+	virtualPath := utils.GetActualVirtualPath(storages[0].GetStorage().MountPath)
+	i := 0
+	if cur, ok := balanceMap.Load(virtualPath); ok {
+		i = (cur + 1) % len(storages)
+	}
+	balanceMap.Store(virtualPath, i)
+	return i
+}
+
 // GetBalancedStorage get storage by path
-func GetBalancedStorage(path string) driver.Driver {
+func GetBalancedStorage(ctx context.Context, path string) driver.Driver {
 	path = utils.StandardizePath(path)
-	storages := getStoragesByPath(path)
+	members := getStoragesByPath(path)
+	// the objs of a union are in specific members, so the health doesn't matter
+	if len(members) > 1 && isUnion(members[0].GetStorage().BalancePolicy) {
+		return pickUnionMember(ctx, members, path)
+	}
+	storages := filterHealthy(members)
 	storageNum := len(storages)
+	var storage driver.Driver
 	switch storageNum {
 	case 0:
 		return nil
 	case 1:
-		return storages[0]
+		storage = storages[0]
 	default:
-		virtualPath := utils.GetActualVirtualPath(storages[0].GetStorage().MountPath)
-		cur, ok := balanceMap.Load(virtualPath)
-		i := 0
-		if ok {
-			i = cur
-			i = (i + 1) % storageNum
-			balanceMap.Store(virtualPath, i)
-		} else {
-			balanceMap.Store(virtualPath, i)
-		}
-		return storages[i]
+		// the pinned member is skipped while it's unhealthy too
+		if i := pickPinned(storages); i >= 0 {
+			storage = storages[i]
+			break
+		}
+		// the policy of the group is decided by the first member, even if it's unhealthy
+		switch members[0].GetStorage().BalancePolicy {
+		case BalanceFastest:
+			storage = storages[pickFastest(storages)]
+		case BalanceSticky:
+			storage = storages[pickSticky(storages, path)]
+		case BalanceStickyIP:
+			key := utils.ClientIPOf(ctx)
+			if key == "" {
+				key = path
+			}
+			storage = storages[pickSticky(storages, key)]
+		default:
+			storage = storages[pickRoundRobin(storages)]
+		}
+	}
+	storage, err := initIfLazy(storage)
+	if err != nil {
+		log.Errorf("%+v", err)
+		return nil
+	}
+	return storage
+}
+
+// ReorderStorages set the index of storages to their position in ids,
+// the storages not in ids keep their index
+func ReorderStorages(ids []uint) error {
+	if err := db.ReorderStorages(ids); err != nil {
+		return errors.WithMessage(err, "failed reorder storages in database")
+	}
+	indexes := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		indexes[id] = i
+	}
+	storagesMap.Range(func(mountPath string, d driver.Driver) bool {
+		if i, ok := indexes[d.GetStorage().ID]; ok {
+			updateStorageInMemory(d, func(storage *model.Storage) {
+				storage.Index = i
+			})
+		}
+		return true
+	})
+	return nil
+}
+
+// CopyStorage create a new storage with the same config of the storage under the new mount path
+func CopyStorage(ctx context.Context, id uint, newMountPath string) error {
+	storage, err := db.GetStorageById(id)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
 	}
+	newStorage := *storage
+	newStorage.ID = 0
+	newStorage.MountPath = newMountPath
+	newStorage.Status = ""
+	newStorage.InitAttempts = 0
+	newStorage.LastError = ""
+	return CreateStorage(ctx, newStorage)
 }
@@ -0,0 +1,111 @@
+package operations
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the fields can't be changed in batch, the mount path must be unique and the addition is merged separately
+var batchProtectedFields = map[string]struct{}{
+	"id": {}, "mount_path": {}, "driver": {}, "addition": {}, "modified": {},
+	"status": {}, "init_attempts": {}, "last_error": {},
+}
+
+// BatchEdit is a partial update applied to many storages, Fields are the json fields of the storage,
+// Addition are the fields of the addition, such as a shared cookie
+type BatchEdit struct {
+	IDs      []uint                 `json:"ids" binding:"required"`
+	Fields   map[string]interface{} `json:"fields"`
+	Addition map[string]interface{} `json:"addition"`
+}
+
+type BatchEditResult struct {
+	ID        uint   `json:"id"`
+	MountPath string `json:"mount_path"`
+	Error     string `json:"error"`
+}
+
+// BatchEditStorages save the edited storages in one transaction, then swap them in one by one, each
+// is validated and applied the same as UpdateStorage. nothing is saved if any storage can't be edited,
+// the init failures are reported per storage
+func BatchEditStorages(ctx context.Context, edit BatchEdit) ([]BatchEditResult, error) {
+	if len(edit.Fields) == 0 && len(edit.Addition) == 0 {
+		return nil, errors.New("nothing to edit")
+	}
+	for k := range edit.Fields {
+		if _, ok := batchProtectedFields[k]; ok {
+			return nil, errors.Errorf("field [%s] can't be edited in batch", k)
+		}
+	}
+	storages := make([]model.Storage, 0, len(edit.IDs))
+	olds := make([]model.Storage, 0, len(edit.IDs))
+	for _, id := range edit.IDs {
+		storage, err := db.GetStorageById(id)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed get storage %d", id)
+		}
+		edited, err := applyBatchEdit(*storage, edit)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed edit storage [%s]", storage.MountPath)
+		}
+		old, err := prepareUpdate(ctx, &edited)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid storage [%s]", storage.MountPath)
+		}
+		storages = append(storages, edited)
+		olds = append(olds, *old)
+	}
+	if err := db.UpdateStorages(storages); err != nil {
+		return nil, errors.WithMessage(err, "failed update storages in database")
+	}
+	results := make([]BatchEditResult, 0, len(storages))
+	for i, storage := range storages {
+		res := BatchEditResult{ID: storage.ID, MountPath: storage.MountPath}
+		if err := swapUpdated(ctx, olds[i], storage); err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// applyBatchEdit overlay the fields on the storage by their json names
+func applyBatchEdit(storage model.Storage, edit BatchEdit) (model.Storage, error) {
+	if len(edit.Fields) > 0 {
+		raw, err := utils.Json.MarshalToString(storage)
+		if err != nil {
+			return storage, errors.WithStack(err)
+		}
+		fields := make(map[string]interface{})
+		if err := utils.Json.UnmarshalFromString(raw, &fields); err != nil {
+			return storage, errors.WithStack(err)
+		}
+		for k, v := range edit.Fields {
+			if _, ok := fields[k]; !ok {
+				return storage, errors.Errorf("unknown field [%s]", k)
+			}
+			fields[k] = v
+		}
+		raw, err = utils.Json.MarshalToString(fields)
+		if err != nil {
+			return storage, errors.WithStack(err)
+		}
+		var edited model.Storage
+		if err := utils.Json.UnmarshalFromString(raw, &edited); err != nil {
+			return storage, errors.Wrap(err, "invalid value of fields")
+		}
+		storage = edited
+	}
+	if len(edit.Addition) > 0 {
+		addition, err := utils.MergeJson(storage.Addition, edit.Addition)
+		if err != nil {
+			return storage, errors.Wrap(err, "failed merge addition")
+		}
+		storage.Addition = addition
+	}
+	return storage, nil
+}
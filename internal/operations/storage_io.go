@@ -0,0 +1,92 @@
+package operations
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictError     = "error"
+)
+
+const (
+	ImportCreate    = "create"
+	ImportOverwrite = "overwrite"
+	ImportSkip      = "skip"
+)
+
+type ImportOptions struct {
+	DryRun bool `json:"dry_run"`
+	// Conflict what to do if the mount path already exists: skip, overwrite or error
+	Conflict string `json:"conflict"`
+}
+
+type ImportResult struct {
+	MountPath string `json:"mount_path"`
+	Action    string `json:"action"`
+	Error     string `json:"error"`
+}
+
+// ExportStorages export all storages with their additions
+func ExportStorages() ([]model.Storage, error) {
+	return db.GetAllStorages()
+}
+
+// ImportStorages create or overwrite storages by mount path,
+// the failure of one storage doesn't affect the others
+func ImportStorages(ctx context.Context, storages []model.Storage, opts ImportOptions) []ImportResult {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+	results := make([]ImportResult, 0, len(storages))
+	for _, storage := range storages {
+		storage.MountPath = utils.StandardizePath(storage.MountPath)
+		res := ImportResult{MountPath: storage.MountPath}
+		action, err := importStorage(ctx, storage, opts)
+		res.Action = action
+		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func importStorage(ctx context.Context, storage model.Storage, opts ImportOptions) (string, error) {
+	if _, err := GetDriverNew(storage.Driver); err != nil {
+		return "", err
+	}
+	storage.ID = 0
+	old, err := db.GetStorageByMountPath(storage.MountPath)
+	if err != nil && !errors.Is(errors.Cause(err), gorm.ErrRecordNotFound) {
+		return "", errors.WithMessage(err, "failed check existing storage")
+	}
+	if old == nil {
+		if opts.DryRun {
+			return ImportCreate, nil
+		}
+		return ImportCreate, CreateStorage(ctx, storage)
+	}
+	switch opts.Conflict {
+	case ConflictSkip:
+		return ImportSkip, nil
+	case ConflictOverwrite:
+		if old.Driver != storage.Driver {
+			return ImportOverwrite, errors.Errorf("driver cannot be changed from %s to %s", old.Driver, storage.Driver)
+		}
+		storage.ID = old.ID
+		if opts.DryRun {
+			return ImportOverwrite, nil
+		}
+		return ImportOverwrite, UpdateStorage(ctx, storage)
+	default:
+		return "", errors.Errorf("storage [%s] already exists", storage.MountPath)
+	}
+}
@@ -0,0 +1,51 @@
+package operations
+
+import (
+	"context"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// SaveStorageAsTemplate save the driver and addition of the storage as a template
+func SaveStorageAsTemplate(storageId uint, name string) (*model.StorageTemplate, error) {
+	storage, err := db.GetStorageById(storageId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storage")
+	}
+	t := model.StorageTemplate{
+		Name:     name,
+		Driver:   storage.Driver,
+		Addition: storage.Addition,
+		Remark:   storage.Remark,
+		Modified: time.Now(),
+	}
+	if err := db.CreateStorageTemplate(&t); err != nil {
+		return nil, errors.WithMessage(err, "failed create storage template")
+	}
+	return &t, nil
+}
+
+// CreateStorageFromTemplate create a storage with the driver of the template,
+// the addition of the storage is merged over the addition of the template
+func CreateStorageFromTemplate(ctx context.Context, templateId uint, storage model.Storage) error {
+	t, err := db.GetStorageTemplateById(templateId)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage template")
+	}
+	patch := make(map[string]interface{})
+	if storage.Addition != "" {
+		if err := utils.Json.UnmarshalFromString(storage.Addition, &patch); err != nil {
+			return errors.Wrap(err, "invalid addition")
+		}
+	}
+	storage.Addition, err = utils.MergeJson(t.Addition, patch)
+	if err != nil {
+		return errors.Wrap(err, "failed merge addition of template")
+	}
+	storage.Driver = t.Driver
+	return CreateStorage(ctx, storage)
+}
@@ -2,12 +2,21 @@ package operations_test
 
 import (
 	"context"
+	"fmt"
+	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/operations"
 	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -17,6 +26,7 @@ func init() {
 	if err != nil {
 		panic("failed to connect database")
 	}
+	conf.Conf = conf.DefaultConfig()
 	db.Init(dB)
 }
 
@@ -58,16 +68,72 @@ func TestGetStorageVirtualFilesByPath(t *testing.T) {
 
 func TestGetBalancedStorage(t *testing.T) {
 	setupStorages(t)
-	storage := operations.GetBalancedStorage("/a/d/e")
+	storage := operations.GetBalancedStorage(context.Background(), "/a/d/e")
 	if storage.GetStorage().MountPath != "/a/d/e" {
 		t.Errorf("expected: /a/d/e, got: %+v", storage.GetStorage().MountPath)
 	}
-	storage = operations.GetBalancedStorage("/a/d/e")
+	storage = operations.GetBalancedStorage(context.Background(), "/a/d/e")
 	if storage.GetStorage().MountPath != "/a/d/e.balance" {
 		t.Errorf("expected: /a/d/e.balance, got: %+v", storage.GetStorage().MountPath)
 	}
 }
 
+func TestLoadStorages(t *testing.T) {
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/load/a", Addition: `{"root_folder":"."}`},
+		{Driver: "Local", MountPath: "/load/b", Addition: `{"root_folder":"."}`},
+		{Driver: "None", MountPath: "/load/c", Addition: `{"root_folder":"."}`},
+	}
+	results := operations.LoadStorages(context.Background(), storages, 2)
+	for i, res := range results {
+		if res.Storage.MountPath != storages[i].MountPath {
+			t.Errorf("expected: %s, got: %s", storages[i].MountPath, res.Storage.MountPath)
+		}
+		if (res.Err != nil) != (storages[i].Driver == "None") {
+			t.Errorf("unexpected result of %s: %+v", res.Storage.MountPath, res.Err)
+		}
+	}
+	if _, err := operations.GetStorageByVirtualPath("/load/b"); err != nil {
+		t.Errorf("failed get loaded storage: %+v", err)
+	}
+}
+
+func TestCreateBundle(t *testing.T) {
+	bundle := operations.Bundle{
+		Storage: model.Storage{Driver: "Local", MountPath: "/bundle", Addition: `{"root_folder":"/not/exists"}`},
+		Metas:   []model.Meta{{Path: "/bundle/a", Password: "pwd"}},
+		Users:   []model.User{{Username: "bundle_user", BasePath: "/bundle"}},
+		Shares:  []operations.BundleShare{{Path: "/bundle/a", Username: "bundle_user", SharePassword: "share"}},
+	}
+	if _, err := operations.CreateBundle(context.Background(), bundle); err == nil {
+		t.Fatalf("expected init error")
+	}
+	if _, err := db.GetMetaByPath("/bundle/a"); err == nil {
+		t.Errorf("expected meta is rolled back")
+	}
+	if shares, _, err := db.GetShares(0, 1, 10); err != nil || len(shares) != 0 {
+		t.Errorf("expected share is rolled back: %+v %+v", shares, err)
+	}
+	bundle.Storage.Addition = `{"root_folder":"."}`
+	shares, err := operations.CreateBundle(context.Background(), bundle)
+	if err != nil {
+		t.Fatalf("failed create bundle: %+v", err)
+	}
+	if _, err := db.GetMetaByPath("/bundle/a"); err != nil {
+		t.Errorf("failed get meta of bundle: %+v", err)
+	}
+	user, err := db.GetUserByName("bundle_user")
+	if err != nil {
+		t.Fatalf("failed get user of bundle: %+v", err)
+	}
+	if len(shares) != 1 || shares[0].UserID != user.ID || !shares[0].CheckPassword("share") {
+		t.Errorf("unexpected shares of bundle: %+v", shares)
+	}
+	if _, err := db.GetShareByToken(shares[0].Token); err != nil {
+		t.Errorf("failed get share of bundle: %+v", err)
+	}
+}
+
 func setupStorages(t *testing.T) {
 	var storages = []model.Storage{
 		{Driver: "Local", MountPath: "/a/b", Index: 0, Addition: `{"root_folder":"."}`},
@@ -83,3 +149,820 @@ func setupStorages(t *testing.T) {
 		}
 	}
 }
+
+func TestImportStorages(t *testing.T) {
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/import", Addition: `{"root_folder":"."}`},
+		{Driver: "Local", MountPath: "/import", Addition: `{"root_folder":"."}`},
+		{Driver: "NotExists", MountPath: "/import_unknown"},
+	}
+	results := operations.ImportStorages(context.Background(), storages, operations.ImportOptions{DryRun: true})
+	if results[0].Action != operations.ImportCreate || results[2].Error == "" {
+		t.Errorf("unexpected dry run results: %+v", results)
+	}
+	if _, err := db.GetStorageByMountPath("/import"); err == nil {
+		t.Fatalf("dry run should not create storage")
+	}
+	results = operations.ImportStorages(context.Background(), storages[:2], operations.ImportOptions{})
+	if results[0].Action != operations.ImportCreate || results[1].Action != operations.ImportSkip {
+		t.Errorf("unexpected import results: %+v", results)
+	}
+}
+
+func TestCreateStorageFromTemplate(t *testing.T) {
+	tmpl := model.StorageTemplate{Name: "local", Driver: "Local", Addition: `{"root_folder":"/not/exists"}`}
+	if err := db.CreateStorageTemplate(&tmpl); err != nil {
+		t.Fatalf("failed create template: %+v", err)
+	}
+	storage := model.Storage{MountPath: "/from_template"}
+	if err := operations.CreateStorageFromTemplate(context.Background(), tmpl.ID, storage); err == nil {
+		t.Errorf("expected init error with addition of template")
+	}
+	storage = model.Storage{MountPath: "/from_template_override", Addition: `{"root_folder":"."}`}
+	if err := operations.CreateStorageFromTemplate(context.Background(), tmpl.ID, storage); err != nil {
+		t.Fatalf("failed create storage from template: %+v", err)
+	}
+	s, err := db.GetStorageByMountPath("/from_template_override")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if s.Driver != "Local" {
+		t.Errorf("unexpected storage: %+v", s)
+	}
+}
+
+func TestCollectUsages(t *testing.T) {
+	storage := model.Storage{Driver: "Local", MountPath: "/usage", Addition: `{"root_folder":"."}`}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	operations.CollectUsages(context.Background())
+	summary, err := operations.GetUsageSummary()
+	if err != nil {
+		t.Fatalf("failed get usage summary: %+v", err)
+	}
+	if len(summary.Storages) == 0 || summary.Total == 0 {
+		t.Errorf("unexpected usage summary: %+v", summary)
+	}
+}
+
+func TestReorderStorages(t *testing.T) {
+	var ids []uint
+	for _, mountPath := range []string{"/reorder/a", "/reorder/b"} {
+		storage := model.Storage{Driver: "Local", MountPath: mountPath, Addition: `{"root_folder":"."}`}
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+		s, _ := db.GetStorageByMountPath(mountPath)
+		ids = append(ids, s.ID)
+	}
+	if err := operations.ReorderStorages([]uint{ids[1], ids[0]}); err != nil {
+		t.Fatalf("failed reorder storages: %+v", err)
+	}
+	files := operations.GetStorageVirtualFilesByPath("/reorder")
+	if len(files) != 2 || files[0].GetName() != "b" {
+		t.Errorf("unexpected order: %+v", files)
+	}
+	if err := operations.ReorderStorages([]uint{ids[0], 99999}); err == nil {
+		t.Errorf("expected error for not exist storage")
+	}
+	if s, _ := db.GetStorageByMountPath("/reorder/a"); s.Index != 1 {
+		t.Errorf("reorder should be rolled back: %+v", s)
+	}
+}
+
+func TestReorderLazyStorages(t *testing.T) {
+	conf.Conf.LazyInit = true
+	defer func() { conf.Conf.LazyInit = false }()
+	var ids []uint
+	for _, mountPath := range []string{"/reorder_lazy/a", "/reorder_lazy/b"} {
+		storage := model.Storage{Driver: "Local", MountPath: mountPath, Addition: fmt.Sprintf(`{"root_folder":%q}`, t.TempDir())}
+		if err := db.CreateStorage(&storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+		if err := operations.LoadStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed load storage: %+v", err)
+		}
+		ids = append(ids, storage.ID)
+	}
+	if err := operations.ReorderStorages([]uint{ids[1], ids[0]}); err != nil {
+		t.Fatalf("failed reorder storages: %+v", err)
+	}
+	// the index is kept when the lazy storages are initialized
+	for _, mountPath := range []string{"/reorder_lazy/a", "/reorder_lazy/b"} {
+		if _, err := operations.GetStorageByVirtualPath(mountPath); err != nil {
+			t.Fatalf("failed init storage: %+v", err)
+		}
+	}
+	files := operations.GetStorageVirtualFilesByPath("/reorder_lazy")
+	if len(files) != 2 || files[0].GetName() != "b" {
+		t.Errorf("unexpected order: %+v", files)
+	}
+}
+
+func TestCopyStorage(t *testing.T) {
+	storage := model.Storage{Driver: "Local", MountPath: "/copy", Addition: `{"root_folder":"."}`}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	s, _ := db.GetStorageByMountPath("/copy")
+	if err := operations.CopyStorage(context.Background(), s.ID, "/copy.balance1"); err != nil {
+		t.Fatalf("failed copy storage: %+v", err)
+	}
+	copied, err := db.GetStorageByMountPath("/copy.balance1")
+	if err != nil {
+		t.Fatalf("failed get copied storage: %+v", err)
+	}
+	if copied.ID == s.ID || copied.Driver != s.Driver || copied.Addition != s.Addition {
+		t.Errorf("unexpected copied storage: %+v", copied)
+	}
+	if _, err := operations.GetStorageByVirtualPath("/copy.balance1"); err != nil {
+		t.Errorf("copied storage is not loaded: %+v", err)
+	}
+}
+
+func TestStorageStatus(t *testing.T) {
+	storage := model.Storage{Driver: "Local", MountPath: "/status", Addition: `{"root_folder":"/not/exists"}`}
+	if err := operations.CreateStorage(context.Background(), storage); err == nil {
+		t.Fatalf("expect failed to init storage")
+	}
+	saved, err := db.GetStorageByMountPath("/status")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if saved.Status != model.StorageInitFailed || saved.LastError == "" {
+		t.Errorf("expected init failed with error, got: %s, %s", saved.Status, saved.LastError)
+	}
+	saved.Addition = `{"root_folder":"."}`
+	if err := operations.UpdateStorage(context.Background(), *saved); err != nil {
+		t.Fatalf("failed update storage: %+v", err)
+	}
+	saved, _ = db.GetStorageById(saved.ID)
+	if saved.Status != model.StorageWork || saved.LastError != "" || saved.InitAttempts != 0 {
+		t.Errorf("expected work, got: %s, %s, %d", saved.Status, saved.LastError, saved.InitAttempts)
+	}
+}
+
+func TestStorageHook(t *testing.T) {
+	var events []string
+	unregister := operations.RegisterStorageHook(func(event operations.StorageEvent) {
+		if event.Storage.MountPath == "/hook" {
+			events = append(events, event.Type)
+		}
+	})
+	defer unregister()
+	storage := model.Storage{Driver: "Local", MountPath: "/hook", Addition: `{"root_folder":"/not/exists"}`}
+	_ = operations.CreateStorage(context.Background(), storage)
+	saved, err := db.GetStorageByMountPath("/hook")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if err := operations.DeleteStorageById(context.Background(), saved.ID); err != nil {
+		t.Fatalf("failed delete storage: %+v", err)
+	}
+	expected := []string{operations.EventStorageCreated, operations.EventStorageInitFailed, operations.EventStorageDeleted}
+	if !utils.SliceEqual(events, expected) {
+		t.Errorf("expected: %+v, got: %+v", expected, events)
+	}
+}
+
+func TestValidateMountPath(t *testing.T) {
+	storage := model.Storage{Driver: "Local", MountPath: "/validate", Addition: `{"root_folder":"."}`}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	var cases = []struct {
+		mountPath string
+		isErr     bool
+	}{
+		{mountPath: "/validate", isErr: true},
+		{mountPath: "/validate.balance", isErr: false},
+		{mountPath: "/validate/storage.go", isErr: true},
+		{mountPath: "/validate/not_exists", isErr: false},
+		{mountPath: "/a/../b", isErr: true},
+		{mountPath: "/a//b", isErr: true},
+		{mountPath: "/a?b", isErr: true},
+		{mountPath: "./a", isErr: true},
+	}
+	for _, c := range cases {
+		err := operations.ValidateMountPath(context.Background(), c.mountPath, 0)
+		if (err != nil) != c.isErr || (err != nil && !errs.IsMountPathError(err)) {
+			t.Errorf("%s: expect error %v, got: %+v", c.mountPath, c.isErr, err)
+		}
+	}
+}
+
+func TestCombineVirtualFiles(t *testing.T) {
+	objs := []model.Obj{&model.Object{Name: "b"}, &model.Object{Name: "c"}}
+	virtualFiles := []model.Obj{&model.Object{Name: "b", IsFolder: true}, &model.Object{Name: "d", IsFolder: true}}
+	var cases = []struct {
+		policy   string
+		expected []string
+		folders  int
+	}{
+		{policy: operations.ShadowMerge, expected: []string{"b", "c", "d"}, folders: 1},
+		{policy: operations.ShadowMountWins, expected: []string{"c", "b", "d"}, folders: 2},
+		{policy: operations.ShadowHideParent, expected: []string{"b", "d"}, folders: 2},
+	}
+	driverNew, err := operations.GetDriverNew("Local")
+	if err != nil {
+		t.Fatalf("failed get driver new: %+v", err)
+	}
+	for _, c := range cases {
+		storage := driverNew()
+		storage.SetStorage(model.Storage{ShadowPolicy: c.policy})
+		res := operations.CombineVirtualFiles(storage, objs, virtualFiles)
+		var names []string
+		folders := 0
+		for _, obj := range res {
+			names = append(names, obj.GetName())
+			if obj.IsDir() {
+				folders++
+			}
+		}
+		if !utils.SliceEqual(names, c.expected) || folders != c.folders {
+			t.Errorf("%s: expected %+v with %d folders, got: %+v with %d folders", c.policy, c.expected, c.folders, names, folders)
+		}
+	}
+}
+
+func TestStickyBalance(t *testing.T) {
+	for _, mountPath := range []string{"/sticky", "/sticky.balance1", "/sticky.balance2"} {
+		storage := model.Storage{Driver: "Local", MountPath: mountPath, Addition: `{"root_folder":"."}`, BalancePolicy: operations.BalanceSticky}
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	picked := make(map[string]struct{})
+	for _, path := range []string{"/sticky/a.mp4", "/sticky/b.mp4", "/sticky/c.mp4", "/sticky/d.mp4"} {
+		first := operations.GetBalancedStorage(context.Background(), path).GetStorage().MountPath
+		for i := 0; i < 3; i++ {
+			if cur := operations.GetBalancedStorage(context.Background(), path).GetStorage().MountPath; cur != first {
+				t.Errorf("%s: expected %s, got %s", path, first, cur)
+			}
+		}
+		picked[first] = struct{}{}
+	}
+	t.Logf("picked members: %+v", picked)
+}
+
+func TestPinBalance(t *testing.T) {
+	for _, mountPath := range []string{"/pin", "/pin.balance1"} {
+		storage := model.Storage{Driver: "Local", MountPath: mountPath, Addition: `{"root_folder":"."}`}
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	if err := operations.PinBalance("/pin", "/other"); err == nil {
+		t.Errorf("expected error when pinning a non-member")
+	}
+	if err := operations.PinBalance("/pin", "/pin.balance1"); err != nil {
+		t.Fatalf("failed pin: %+v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if cur := operations.GetBalancedStorage(context.Background(), "/pin/a").GetStorage().MountPath; cur != "/pin.balance1" {
+			t.Errorf("expected the pinned member, got %s", cur)
+		}
+	}
+	var group *operations.BalanceGroup
+	groups := operations.GetBalanceGroups()
+	for i := range groups {
+		if groups[i].Path == "/pin" {
+			group = &groups[i]
+		}
+	}
+	if group == nil || group.Pinned != "/pin.balance1" || len(group.Members) != 2 {
+		t.Fatalf("unexpected group: %+v", group)
+	}
+	if err := operations.ResetBalance("/pin"); err != nil {
+		t.Fatalf("failed reset: %+v", err)
+	}
+	picked := make(map[string]struct{})
+	for i := 0; i < 2; i++ {
+		picked[operations.GetBalancedStorage(context.Background(), "/pin/a").GetStorage().MountPath] = struct{}{}
+	}
+	if len(picked) != 2 {
+		t.Errorf("expected round robin after reset, got %+v", picked)
+	}
+}
+
+func TestReloadStorages(t *testing.T) {
+	for _, mountPath := range []string{"/reload_a", "/reload_b"} {
+		storage := model.Storage{Driver: "Local", MountPath: mountPath, Addition: `{"root_folder":"."}`}
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	// edit the database directly
+	a, err := db.GetStorageByMountPath("/reload_a")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	a.Remark = "changed"
+	if err := db.UpdateStorage(a); err != nil {
+		t.Fatalf("failed update storage: %+v", err)
+	}
+	c := model.Storage{Driver: "Local", MountPath: "/reload_c", Addition: `{"root_folder":"."}`}
+	if err := db.CreateStorage(&c); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	b, err := db.GetStorageByMountPath("/reload_b")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if err := db.DeleteStorageById(b.ID); err != nil {
+		t.Fatalf("failed delete storage: %+v", err)
+	}
+	events := map[string][]string{}
+	unregister := operations.RegisterStorageHook(func(event operations.StorageEvent) {
+		events[event.Storage.MountPath] = append(events[event.Storage.MountPath], event.Type)
+	})
+	defer unregister()
+	res, err := operations.ReloadStorages(context.Background())
+	if err != nil {
+		t.Fatalf("failed reload: %+v", err)
+	}
+	// the new row is created by another instance, which emits the created event
+	expectedEvents := map[string][]string{
+		"/reload_a": {operations.EventStorageUpdated},
+		"/reload_b": {operations.EventStorageDeleted},
+		"/reload_c": nil,
+	}
+	for mountPath, expected := range expectedEvents {
+		if !utils.SliceEqual(events[mountPath], expected) {
+			t.Errorf("expected events %v of %s, got %v", expected, mountPath, events[mountPath])
+		}
+	}
+	// the storages of other tests may be reloaded too
+	expected := map[string]string{"loaded": "/reload_c", "reloaded": "/reload_a", "dropped": "/reload_b"}
+	for name, got := range map[string][]string{"loaded": res.Loaded, "reloaded": res.Reloaded, "dropped": res.Dropped} {
+		if !utils.SliceContains(got, expected[name]) {
+			t.Errorf("expected %s %s, got %v", name, expected[name], got)
+		}
+	}
+	for _, mountPath := range []string{"/reload_a", "/reload_b", "/reload_c"} {
+		if err, ok := res.Errors[mountPath]; ok {
+			t.Errorf("failed reload %s: %s", mountPath, err)
+		}
+	}
+	if s, err := operations.GetStorageByVirtualPath("/reload_a"); err != nil || s.GetStorage().Remark != "changed" {
+		t.Errorf("storage is not reloaded: %+v", err)
+	}
+	if _, err := operations.GetStorageByVirtualPath("/reload_b"); err == nil {
+		t.Errorf("storage is not dropped")
+	}
+}
+
+func TestAlias(t *testing.T) {
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/alias_src", Addition: `{"root_folder":"."}`},
+		{Driver: "Alias", MountPath: "/alias/dst", Addition: `{"path":"/alias_src"}`},
+		{Driver: "Alias", MountPath: "/alias/a", Addition: `{"path":"/alias/b"}`},
+	}
+	for _, storage := range storages {
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage %s: %+v", storage.MountPath, err)
+		}
+	}
+	storage, actualPath, err := operations.GetStorageAndActualPath(context.Background(), "/alias/dst")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	objs, err := operations.List(context.Background(), storage, actualPath)
+	if err != nil {
+		t.Fatalf("failed list alias: %+v", err)
+	}
+	if len(objs) == 0 {
+		t.Errorf("expected the objs of the target")
+	}
+	for _, storage := range []model.Storage{
+		{Driver: "Alias", MountPath: "/alias/b", Addition: `{"path":"/alias/a"}`},
+		{Driver: "Alias", MountPath: "/alias/self", Addition: `{"path":"/alias/self/sub"}`},
+	} {
+		err := operations.CreateStorage(context.Background(), storage)
+		if !errors.Is(errors.Cause(err), errs.AliasLoop) {
+			t.Errorf("expected alias loop of %s, got: %+v", storage.MountPath, err)
+		}
+	}
+	// the alias not initialized yet is followed too
+	conf.Conf.LazyInit = true
+	err = operations.LoadStorage(context.Background(), model.Storage{Driver: "Alias", MountPath: "/alias/lazy", Addition: `{"path":"/alias/c"}`})
+	conf.Conf.LazyInit = false
+	if err != nil {
+		t.Fatalf("failed load storage: %+v", err)
+	}
+	err = operations.CreateStorage(context.Background(), model.Storage{Driver: "Alias", MountPath: "/alias/c", Addition: `{"path":"/alias/lazy"}`})
+	if !errors.Is(errors.Cause(err), errs.AliasLoop) {
+		t.Errorf("expected alias loop through the lazy storage, got: %+v", err)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	dirs := []string{t.TempDir(), t.TempDir(), t.TempDir()}
+	files := [][]string{{"a.txt", "same.txt"}, {"b.txt", "same.txt"}, {"sub/c.txt"}}
+	if err := os.Mkdir(filepath.Join(dirs[2], "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, mountPath := range []string{"/union", "/union.balance1", "/union.balance2"} {
+		for _, name := range files[i] {
+			if err := os.WriteFile(filepath.Join(dirs[i], name), []byte(mountPath), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		storage := model.Storage{Driver: "Local", MountPath: mountPath, Addition: fmt.Sprintf(`{"root_folder":%q}`, dirs[i]),
+			BalancePolicy: operations.UnionFirstWritable, ReadOnly: i == 0}
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	members := operations.GetUnionMembers("/union")
+	if len(members) != 3 {
+		t.Fatalf("expected 3 members, got %d", len(members))
+	}
+	objs, err := operations.ListUnion(context.Background(), members, "/union")
+	if err != nil {
+		t.Fatalf("failed list union: %+v", err)
+	}
+	if len(objs) != 4 {
+		t.Errorf("expected 4 merged objs, got %d", len(objs))
+	}
+	if s := operations.GetBalancedStorage(context.Background(), "/union/b.txt"); s.GetStorage().MountPath != "/union.balance1" {
+		t.Errorf("expected the member holding b.txt, got %s", s.GetStorage().MountPath)
+	}
+	// the first member is read-only, so new objs go to the second
+	s, _, err := operations.GetPutStorageAndActualPath(context.Background(), "/union", "new.txt", 0)
+	if err != nil {
+		t.Fatalf("failed get put storage: %+v", err)
+	}
+	if s.GetStorage().MountPath != "/union.balance1" {
+		t.Errorf("expected the writable member, got %s", s.GetStorage().MountPath)
+	}
+	// the new objs of a dir go to the member holding the dir
+	s, _, err = operations.GetPutStorageAndActualPath(context.Background(), "/union/sub", "new.txt", 0)
+	if err != nil {
+		t.Fatalf("failed get put storage: %+v", err)
+	}
+	if s.GetStorage().MountPath != "/union.balance2" {
+		t.Errorf("expected the member holding the dir, got %s", s.GetStorage().MountPath)
+	}
+	if s := operations.GetBalancedStorage(context.Background(), "/union/sub/new"); s.GetStorage().MountPath != "/union.balance2" {
+		t.Errorf("expected the member holding the dir, got %s", s.GetStorage().MountPath)
+	}
+}
+
+func TestMapAddition(t *testing.T) {
+	items := []driver.Item{{Name: "type", Type: conf.TypeSelect, Values: "s3, onedrive"}}
+	res, err := operations.MapAddition(`{"type":"onedrive"}`, items)
+	if err != nil {
+		t.Fatalf("failed map addition: %+v", err)
+	}
+	if !utils.SliceEqual(res.Mapped, []string{"type"}) {
+		t.Errorf("expected the value of the select is kept, got %+v", res)
+	}
+}
+
+func TestConvertStorage(t *testing.T) {
+	// the credential of the local driver doesn't fit the alias one
+	credential := model.Credential{Name: "convert", Data: `{}`}
+	if err := db.CreateCredential(&credential); err != nil {
+		t.Fatalf("failed create credential: %+v", err)
+	}
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/convert_src", Addition: `{"root_folder":"."}`},
+		{Driver: "Local", MountPath: "/convert", Addition: `{"root_folder":"."}`, CredentialID: credential.ID},
+	}
+	for _, storage := range storages {
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	storage, err := operations.GetStorageByVirtualPath("/convert")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	id := storage.GetStorage().ID
+	// the alias driver has no root folder but requires a path
+	res, err := operations.ConvertStorage(context.Background(), operations.ConvertReq{ID: id, Driver: "Alias", DryRun: true})
+	if err != nil {
+		t.Fatalf("failed map addition: %+v", err)
+	}
+	if !utils.SliceEqual(res.Dropped, []string{"root_folder", "credential_id"}) || !utils.SliceEqual(res.Missing, []string{"path"}) {
+		t.Errorf("unexpected mapping: %+v", res)
+	}
+	if _, err := operations.ConvertStorage(context.Background(), operations.ConvertReq{ID: id, Driver: "Alias"}); err == nil {
+		t.Errorf("expected failure for the missing fields")
+	}
+	// the storage is kept as it is if the new driver fails to init
+	_, err = operations.ConvertStorage(context.Background(), operations.ConvertReq{ID: id, Driver: "Alias", Addition: `{"path":"/convert"}`})
+	if err == nil {
+		t.Fatalf("expected failure for the alias loop")
+	}
+	if s, err := db.GetStorageById(id); err != nil || s.Driver != "Local" || s.CredentialID != credential.ID {
+		t.Errorf("expected the storage in database is kept, got %+v %+v", s, err)
+	}
+	if s, err := operations.GetStorageByVirtualPath("/convert"); err != nil || s.Config().Name != "Local" {
+		t.Errorf("expected the local driver keeps serving, got %+v", err)
+	}
+	_, err = operations.ConvertStorage(context.Background(), operations.ConvertReq{ID: id, Driver: "Alias", Addition: `{"path":"/convert_src"}`})
+	if err != nil {
+		t.Fatalf("failed convert storage: %+v", err)
+	}
+	storage, err = operations.GetStorageByVirtualPath("/convert")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if storage.Config().Name != "Alias" || storage.GetStorage().ID != id {
+		t.Errorf("expected alias with id %d, got %s with id %d", id, storage.Config().Name, storage.GetStorage().ID)
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	dir := t.TempDir()
+	storage := model.Storage{Driver: "Local", MountPath: "/read_only", Addition: fmt.Sprintf(`{"root_folder":%q}`, dir)}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	s, err := operations.GetStorageByVirtualPath("/read_only")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	setReadOnly := func(value string) {
+		err := db.SaveSettingItem(model.SettingItem{Key: conf.ReadOnly, Value: value, Type: conf.TypeBool, Group: model.GLOBAL})
+		if err != nil {
+			t.Fatalf("failed save setting: %+v", err)
+		}
+	}
+	setReadOnly("true")
+	defer setReadOnly("false")
+	if err := operations.MakeDir(context.Background(), s, filepath.Join(dir, "sub")); !errors.Is(errors.Cause(err), errs.InstanceReadOnly) {
+		t.Errorf("expected read-only error, got: %+v", err)
+	}
+	setReadOnly("false")
+	conf.Conf.Demo.Enable = true
+	err = operations.MakeDir(context.Background(), s, filepath.Join(dir, "sub"))
+	conf.Conf.Demo.Enable = false
+	if !errors.Is(errors.Cause(err), errs.OperationDisabled) {
+		t.Errorf("expected the operations are disabled in demo mode, got: %+v", err)
+	}
+	if err := operations.MakeDir(context.Background(), s, filepath.Join(dir, "sub")); err != nil {
+		t.Errorf("failed make dir: %+v", err)
+	}
+}
+
+func TestBatchEditStorages(t *testing.T) {
+	var ids []uint
+	for _, mountPath := range []string{"/batch_a", "/batch_b"} {
+		storage := model.Storage{Driver: "Local", MountPath: mountPath, Addition: `{"root_folder":"."}`}
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+		s, err := db.GetStorageByMountPath(mountPath)
+		if err != nil {
+			t.Fatalf("failed get storage: %+v", err)
+		}
+		ids = append(ids, s.ID)
+	}
+	_, err := operations.BatchEditStorages(context.Background(), operations.BatchEdit{
+		IDs: ids, Fields: map[string]interface{}{"remark": "x", "mount_path": "/batch_c"},
+	})
+	if err == nil {
+		t.Errorf("expected mount path can't be edited in batch")
+	}
+	// validated the same as updating one by one
+	_, err = operations.BatchEditStorages(context.Background(), operations.BatchEdit{
+		IDs: ids, Fields: map[string]interface{}{"depends_on": "/batch_a"},
+	})
+	if !errors.Is(errors.Cause(err), errs.DependencyLoop) {
+		t.Errorf("expected the dependency loop is rejected, got %+v", err)
+	}
+	results, err := operations.BatchEditStorages(context.Background(), operations.BatchEdit{
+		IDs:      ids,
+		Fields:   map[string]interface{}{"cache_expiration": 5},
+		Addition: map[string]interface{}{"root_folder": "/not/exists"},
+	})
+	if err != nil {
+		t.Fatalf("failed batch edit: %+v", err)
+	}
+	for _, res := range results {
+		if res.Error == "" {
+			t.Errorf("expected init error of %s", res.MountPath)
+		}
+		s, err := db.GetStorageById(res.ID)
+		if err != nil {
+			t.Fatalf("failed get storage: %+v", err)
+		}
+		if s.CacheExpiration != 5 || s.Addition != `{"root_folder":"/not/exists"}` {
+			t.Errorf("storage is not edited: %+v", s)
+		}
+	}
+}
+
+func TestStorageDependency(t *testing.T) {
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/dep/a", Addition: `{"root_folder":"."}`},
+		{Driver: "Local", MountPath: "/dep/b", Addition: `{"root_folder":"."}`, DependsOn: "/dep/a"},
+	}
+	for _, storage := range storages {
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	a, err := db.GetStorageByMountPath("/dep/a")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	a.DependsOn = "dep/b/"
+	err = operations.UpdateStorage(context.Background(), *a)
+	if !errors.Is(errors.Cause(err), errs.DependencyLoop) {
+		t.Errorf("expected dependency loop, got %+v", err)
+	}
+	if err := operations.DeleteStorageById(context.Background(), a.ID); err != nil {
+		t.Fatalf("failed delete storage: %+v", err)
+	}
+	b, err := operations.GetStorageByVirtualPath("/dep/b")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if b.GetStorage().Status != model.StorageInitFailed {
+		t.Errorf("expected the dependent is dropped, got status %s", b.GetStorage().Status)
+	}
+}
+
+func TestRenameDependency(t *testing.T) {
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/rename_dep/a", Addition: `{"root_folder":"."}`},
+		{Driver: "Local", MountPath: "/rename_dep/b", Addition: `{"root_folder":"."}`, DependsOn: "/rename_dep/a"},
+	}
+	for _, storage := range storages {
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	a, err := db.GetStorageByMountPath("/rename_dep/a")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	a.MountPath = "/rename_dep/c"
+	if err := operations.UpdateStorage(context.Background(), *a); err != nil {
+		t.Fatalf("failed update storage: %+v", err)
+	}
+	b, err := db.GetStorageByMountPath("/rename_dep/b")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if b.DependsOn != "/rename_dep/c" {
+		t.Errorf("expected the dependency is renamed in database, got %q", b.DependsOn)
+	}
+	loaded, err := operations.GetStorageByVirtualPath("/rename_dep/b")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if dependsOn := loaded.GetStorage().DependsOn; dependsOn != "/rename_dep/c" {
+		t.Errorf("expected the dependency is renamed in memory, got %q", dependsOn)
+	}
+}
+
+func TestUpdateStorageSwap(t *testing.T) {
+	storage := model.Storage{Driver: "Local", MountPath: "/swap", Addition: `{"root_folder":"."}`}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	old, err := operations.GetStorageByVirtualPath("/swap")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	s, err := db.GetStorageByMountPath("/swap")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	s.Remark = "swapped"
+	if err := operations.UpdateStorage(context.Background(), *s); err != nil {
+		t.Fatalf("failed update storage: %+v", err)
+	}
+	cur, err := operations.GetStorageByVirtualPath("/swap")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if cur == old {
+		t.Errorf("expected a new instance of the driver")
+	}
+	if cur.GetStorage().Remark != "swapped" {
+		t.Errorf("expected the new instance with the updated storage, got remark %q", cur.GetStorage().Remark)
+	}
+	// the old instance still serves the calls started before the swap
+	if _, err := old.List(context.Background(), &model.Object{ID: ".", IsFolder: true}); err != nil {
+		t.Errorf("expected the old instance still works: %+v", err)
+	}
+}
+
+func TestMostFreeUpload(t *testing.T) {
+	for i, mountPath := range []string{"/most_free", "/most_free.balance1"} {
+		storage := model.Storage{Driver: "Local", MountPath: mountPath, Addition: fmt.Sprintf(`{"root_folder":%q}`, t.TempDir()),
+			BalancePolicy: operations.BalanceMostFree}
+		// the first member can't keep such reserve, so the uploads go to the second
+		if i == 0 {
+			storage.UploadReserve = math.MaxInt64 / 2
+		}
+		if err := operations.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+	}
+	operations.CollectUsages(context.Background())
+	s, _, err := operations.GetPutStorageAndActualPath(context.Background(), "/most_free", "new.txt", 1)
+	if err != nil {
+		t.Fatalf("failed get put storage: %+v", err)
+	}
+	if s.GetStorage().MountPath != "/most_free.balance1" {
+		t.Errorf("expected the member above its reserve, got %s", s.GetStorage().MountPath)
+	}
+	_, _, err = operations.GetPutStorageAndActualPath(context.Background(), "/most_free", "huge.bin", math.MaxInt64/2)
+	if !errors.Is(errors.Cause(err), errs.NoSpaceLeft) {
+		t.Errorf("expected no space left, got: %+v", err)
+	}
+}
+
+func TestUpdateLazyStorage(t *testing.T) {
+	conf.Conf.LazyInit = true
+	defer func() { conf.Conf.LazyInit = false }()
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/lazy_fix", Addition: `{"root_folder":"/not/exists/lazy"}`},
+		{Driver: "Local", MountPath: "/lazy_delete", Addition: `{"root_folder":"/not/exists/lazy"}`},
+	}
+	for i := range storages {
+		if err := db.CreateStorage(&storages[i]); err != nil {
+			t.Fatalf("failed create storage: %+v", err)
+		}
+		if err := operations.LoadStorage(context.Background(), storages[i]); err != nil {
+			t.Fatalf("failed load storage: %+v", err)
+		}
+	}
+	// the broken storage is fixed without being initialized with the old addition
+	storages[0].Addition = fmt.Sprintf(`{"root_folder":%q}`, t.TempDir())
+	if err := operations.UpdateStorage(context.Background(), storages[0]); err != nil {
+		t.Fatalf("failed update lazy storage: %+v", err)
+	}
+	if err := operations.DeleteStorageById(context.Background(), storages[1].ID); err != nil {
+		t.Fatalf("failed delete lazy storage: %+v", err)
+	}
+}
+
+func TestUpdateStorageTokenRenewal(t *testing.T) {
+	storage := model.Storage{Driver: "Local", MountPath: "/swap_token", Addition: fmt.Sprintf(`{"root_folder":%q}`, t.TempDir())}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	old, err := operations.GetStorageByVirtualPath("/swap_token")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	refreshToken := "old"
+	tokens := operations.NewTokenManager(old, &refreshToken, func(ctx context.Context, refreshToken string) (*operations.OAuthToken, error) {
+		return &operations.OAuthToken{AccessToken: "access", Expiry: time.Now().Add(time.Hour), RefreshToken: "rotated"}, nil
+	})
+	defer tokens.Close()
+	s, err := db.GetStorageByMountPath("/swap_token")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	s.Addition = fmt.Sprintf(`{"root_folder":%q}`, t.TempDir())
+	if err := operations.UpdateStorage(context.Background(), *s); err != nil {
+		t.Fatalf("failed update storage: %+v", err)
+	}
+	// the token of the replaced instance is not renewed, which would save its addition
+	if _, err := tokens.Token(context.Background()); err == nil {
+		t.Errorf("expected the token manager of the replaced instance is closed")
+	}
+	saved, err := db.GetStorageByMountPath("/swap_token")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if saved.Addition != s.Addition {
+		t.Errorf("expected the updated addition %s is kept, got %s", s.Addition, saved.Addition)
+	}
+}
+
+func TestTokenCredentialExpiration(t *testing.T) {
+	storage := model.Storage{Driver: "Local", MountPath: "/token_expiry", Addition: fmt.Sprintf(`{"root_folder":%q}`, t.TempDir())}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	s, err := operations.GetStorageByVirtualPath("/token_expiry")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	refreshToken := "refresh"
+	expiry := time.Now().Add(7 * 24 * time.Hour)
+	tokens := operations.NewTokenManager(s, &refreshToken, func(ctx context.Context, refreshToken string) (*operations.OAuthToken, error) {
+		return &operations.OAuthToken{AccessToken: "access", Expiry: time.Now().Add(time.Hour), RefreshExpiry: expiry}, nil
+	})
+	defer tokens.Close()
+	if !tokens.CredentialExpiration().IsZero() {
+		t.Errorf("expected the expiry is unknown before refreshing")
+	}
+	if _, err := tokens.Token(context.Background()); err != nil {
+		t.Fatalf("failed get token: %+v", err)
+	}
+	if !tokens.CredentialExpiration().Equal(expiry) {
+		t.Errorf("expected the refresh token expires at %s, got %s", expiry, tokens.CredentialExpiration())
+	}
+}
@@ -0,0 +1,163 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+type storageLimiter struct {
+	limit   int64
+	limiter *rate.Limiter
+}
+
+// limiters are shared by all transfers of the same storage, keyed by mount path
+var uploadLimiters, downloadLimiters generic_sync.MapOf[string, *storageLimiter]
+
+// swapLimitersMu serialize the replacing of the limiters whose settings changed
+var swapLimitersMu sync.Mutex
+
+// loadLimiter get the limiter of the storage, the concurrent calls get the same one.
+// the one not matching the settings of the storage is replaced by a new one
+func loadLimiter[V any](limiters *generic_sync.MapOf[string, V], mountPath string, match func(V) bool, create func() V) V {
+	l, ok := limiters.Load(mountPath)
+	if !ok {
+		l, _ = limiters.LoadOrStore(mountPath, create())
+	}
+	if match(l) {
+		return l
+	}
+	swapLimitersMu.Lock()
+	defer swapLimitersMu.Unlock()
+	if l, ok := limiters.Load(mountPath); ok && match(l) {
+		return l
+	}
+	l = create()
+	limiters.Store(mountPath, l)
+	return l
+}
+
+func getLimiter(limiters *generic_sync.MapOf[string, *storageLimiter], mountPath string, limit int64) *rate.Limiter {
+	if limit <= 0 {
+		limiters.Delete(mountPath)
+		return nil
+	}
+	l := loadLimiter(limiters, mountPath, func(l *storageLimiter) bool {
+		return l.limit == limit
+	}, func() *storageLimiter {
+		// the burst is also the max size of one read
+		burst := int(limit)
+		if burst < 4096 {
+			burst = 4096
+		}
+		return &storageLimiter{limit: limit, limiter: rate.NewLimiter(rate.Limit(limit), burst)}
+	})
+	return l.limiter
+}
+
+func getUploadLimiter(storage driver.Driver) *rate.Limiter {
+	return getLimiter(&uploadLimiters, storage.GetStorage().MountPath, storage.GetStorage().UploadLimit)
+}
+
+func getDownloadLimiter(storage driver.Driver) *rate.Limiter {
+	return getLimiter(&downloadLimiters, storage.GetStorage().MountPath, storage.GetStorage().DownloadLimit)
+}
+
+type limitedStream struct {
+	model.FileStreamer
+	r io.Reader
+}
+
+func (s *limitedStream) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+// limitStream limit the upload speed of the file to the storage
+func limitStream(ctx context.Context, storage driver.Driver, file model.FileStreamer) model.FileStreamer {
+	l := getUploadLimiter(storage)
+	if l == nil {
+		return file
+	}
+	return &limitedStream{FileStreamer: file, r: utils.NewLimitedReader(ctx, file, l)}
+}
+
+// limitLink limit the download speed of the link, the Data is wrapped directly
+// and the Limiter is set for the proxy to limit the others
+func limitLink(ctx context.Context, storage driver.Driver, link *model.Link) *model.Link {
+	l := getDownloadLimiter(storage)
+	if l == nil {
+		return link
+	}
+	limited := *link
+	limited.Limiter = l
+	if link.Data != nil {
+		limited.Data = utils.ReadCloser{Reader: utils.NewLimitedReader(ctx, link.Data, l), Closer: link.Data}
+	}
+	return &limited
+}
+
+type requestLimiter struct {
+	limit   float64
+	burst   int
+	limiter *rate.Limiter
+}
+
+// requestLimiters limit the calls to the drivers, keyed by mount path
+var requestLimiters generic_sync.MapOf[string, *requestLimiter]
+
+// waitRequest wait for the rate limit of the storage before calling its driver,
+// so the aggressive clients don't get the account banned by the provider
+func waitRequest(ctx context.Context, storage driver.Driver) error {
+	s := storage.GetStorage()
+	if s.RateLimit <= 0 {
+		requestLimiters.Delete(s.MountPath)
+		return nil
+	}
+	burst := s.RateBurst
+	if burst <= 0 {
+		burst = 1
+	}
+	l := loadLimiter(&requestLimiters, s.MountPath, func(l *requestLimiter) bool {
+		return l.limit == s.RateLimit && l.burst == burst
+	}, func() *requestLimiter {
+		return &requestLimiter{limit: s.RateLimit, burst: burst, limiter: rate.NewLimiter(rate.Limit(s.RateLimit), burst)}
+	})
+	return errors.WithStack(l.limiter.Wait(ctx))
+}
+
+type parallelLimiter struct {
+	max   int
+	slots chan struct{}
+}
+
+// parallelLimiters limit the calls to the drivers running at once, keyed by mount path
+var parallelLimiters generic_sync.MapOf[string, *parallelLimiter]
+
+// acquireSlot wait for a free slot of the storage, some backends such as ftp and the consumer nas
+// break under dozens of concurrent requests. the returned func frees the slot
+func acquireSlot(ctx context.Context, storage driver.Driver) (func(), error) {
+	s := storage.GetStorage()
+	if s.MaxParallel <= 0 {
+		parallelLimiters.Delete(s.MountPath)
+		return func() {}, nil
+	}
+	// the calls holding the slots of the old one free them there
+	l := loadLimiter(&parallelLimiters, s.MountPath, func(l *parallelLimiter) bool {
+		return l.max == s.MaxParallel
+	}, func() *parallelLimiter {
+		return &parallelLimiter{max: s.MaxParallel, slots: make(chan struct{}, s.MaxParallel)}
+	})
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, errors.WithStack(ctx.Err())
+	}
+}
@@ -0,0 +1,158 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+type throttledDriver struct {
+	driver.Driver
+	storage model.Storage
+}
+
+func (d *throttledDriver) GetStorage() model.Storage {
+	return d.storage
+}
+
+// parallel run the calls at once and return the max number of them holding the slots together
+func parallel(t *testing.T, storage driver.Driver, n int) int32 {
+	var (
+		wg           sync.WaitGroup
+		running, max int32
+	)
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			release, err := acquireSlot(context.Background(), storage)
+			if err != nil {
+				t.Errorf("failed acquire slot: %+v", err)
+				return
+			}
+			defer release()
+			cur := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&max)
+				if cur <= old || atomic.CompareAndSwapInt32(&max, old, cur) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return max
+}
+
+func TestAcquireSlot(t *testing.T) {
+	storage := &throttledDriver{storage: model.Storage{MountPath: "/throttle_slot", MaxParallel: 1}}
+	parallelLimiters.Delete("/throttle_slot")
+	if max := parallel(t, storage, 50); max != 1 {
+		t.Errorf("expected 1 call at once, got %d", max)
+	}
+	storage.storage.MaxParallel = 2
+	if max := parallel(t, storage, 50); max > 2 {
+		t.Errorf("expected 2 calls at once at most after the change, got %d", max)
+	}
+}
+
+func TestWaitRequest(t *testing.T) {
+	storage := &throttledDriver{storage: model.Storage{MountPath: "/throttle_rate", RateLimit: 0.1, RateBurst: 1}}
+	requestLimiters.Delete("/throttle_rate")
+	var (
+		wg     sync.WaitGroup
+		passed int32
+	)
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			if waitRequest(ctx, storage) == nil {
+				atomic.AddInt32(&passed, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	// the calls share the same bucket of 1 token
+	if passed != 1 {
+		t.Errorf("expected 1 call passed, got %d", passed)
+	}
+}
+
+// streamDriver return the files as streams
+type streamDriver struct {
+	throttledDriver
+}
+
+func (d *streamDriver) Config() driver.Config {
+	return driver.Config{NoCache: true}
+}
+
+func (d *streamDriver) GetAddition() driver.Additional {
+	return nil
+}
+
+func (d *streamDriver) Get(ctx context.Context, path string) (model.Obj, error) {
+	return &model.Object{Name: strings.TrimPrefix(path, "/")}, nil
+}
+
+func (d *streamDriver) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	return &model.Link{Data: io.NopCloser(strings.NewReader(file.GetName()))}, nil
+}
+
+func TestLinkSlot(t *testing.T) {
+	storage := &streamDriver{throttledDriver{storage: model.Storage{MountPath: "/throttle_link", MaxParallel: 1}}}
+	parallelLimiters.Delete("/throttle_link")
+	defer inflights.Delete(storage)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// the stream being read doesn't hold the slot
+	a, _, err := Link(ctx, storage, "/a", model.LinkArgs{})
+	if err != nil {
+		t.Fatalf("failed link: %+v", err)
+	}
+	b, _, err := Link(ctx, storage, "/b", model.LinkArgs{})
+	if err != nil {
+		t.Fatalf("failed link while the other stream is open: %+v", err)
+	}
+	// but the storage is in use until the streams are closed
+	_ = a.Data.Close()
+	if waitIdle(ctx, storage, 10*time.Millisecond) {
+		t.Errorf("expected the storage is in use by the open stream")
+	}
+	_ = b.Data.Close()
+	if !waitIdle(ctx, storage, 10*time.Millisecond) {
+		t.Errorf("expected the storage is idle after the streams are closed")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	_, s := createLocal(t, model.Storage{MountPath: "/rate_limit", RateLimit: 0.01, RateBurst: 2})
+	// getting and listing the dir use up the burst
+	if _, err := List(context.Background(), s, "/", true); err != nil {
+		t.Fatalf("failed list: %+v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := List(ctx, s, "/", true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the list is limited, got %+v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/leader"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// the tokens expiring within it are refreshed in background
+	tokenRefreshAhead = 5 * time.Minute
+	// the tokens used by the requests are refreshed a minute early, the requests may be slow
+	tokenUseAhead      = time.Minute
+	tokenCheckInterval = time.Minute
+)
+
+// OAuthToken the access token returned by the provider
+type OAuthToken struct {
+	AccessToken string
+	Expiry      time.Time
+	// the new refresh token if the provider rotates it, empty if not
+	RefreshToken string
+	// RefreshExpiry of the refresh token if the provider tells, such as the apps of google in testing
+	RefreshExpiry time.Time
+}
+
+// TokenRefresher exchange the refresh token for the access token, it should wrap
+// errs.RefreshTokenInvalid if the provider rejects the refresh token
+type TokenRefresher func(ctx context.Context, refreshToken string) (*OAuthToken, error)
+
+// TokenManager keep the access token of a storage authorized by oauth, the token is refreshed
+// before it's expired in background, so the storage doesn't fail when it's accessed,
+// and the rotated refresh token is saved. the storage is flagged if the refresh token is rejected
+type TokenManager struct {
+	storage driver.Driver
+	// the field of the addition, updated if the refresh token is rotated
+	refreshToken *string
+	refresh      TokenRefresher
+
+	// only one refresh at a time, the rotated refresh tokens can be used once
+	refreshMu   sync.Mutex
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+	// the refresh token can't be used after it, zero if unknown
+	refreshExpiry time.Time
+	// the refresh token is rejected, stop refreshing in background until it's authorized again
+	rejected bool
+	// refreshed in background only after the storage is initialized, the instances failed
+	// to init or only probing would spend the rotated refresh tokens of the live one
+	active bool
+	// the instance is dropped or replaced, it must not refresh or save the token of the storage anymore
+	closed bool
+}
+
+var (
+	tokenManagers    generic_sync.MapOf[*TokenManager, struct{}]
+	tokenRenewalOnce sync.Once
+)
+
+// NewTokenManager register the storage to refresh its token in background once it's initialized,
+// the driver should close it when dropped
+func NewTokenManager(storage driver.Driver, refreshToken *string, refresh TokenRefresher) *TokenManager {
+	m := &TokenManager{storage: storage, refreshToken: refreshToken, refresh: refresh}
+	tokenManagers.Store(m, struct{}{})
+	tokenRenewalOnce.Do(func() {
+		go renewTokens()
+	})
+	return m
+}
+
+// Close stop refreshing the token, the access token got before can still be used
+func (m *TokenManager) Close() {
+	tokenManagers.Delete(m)
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+}
+
+// activateTokenManagers start refreshing the tokens of the initialized instance in background
+func activateTokenManagers(storageDriver driver.Driver) {
+	tokenManagers.Range(func(m *TokenManager, _ struct{}) bool {
+		if m.storage == storageDriver {
+			m.mu.Lock()
+			m.active = true
+			m.mu.Unlock()
+		}
+		return true
+	})
+}
+
+// closeTokenManagers close the token managers of the instance, which failed to init or is replaced
+func closeTokenManagers(storageDriver driver.Driver) {
+	tokenManagers.Range(func(m *TokenManager, _ struct{}) bool {
+		if m.storage == storageDriver {
+			m.Close()
+		}
+		return true
+	})
+}
+
+// Token return the access token, it's refreshed if it's expiring
+func (m *TokenManager) Token(ctx context.Context) (string, error) {
+	if token, ok := m.valid(tokenUseAhead); ok {
+		return token, nil
+	}
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+	// refreshed by another request while waiting
+	if token, ok := m.valid(tokenUseAhead); ok {
+		return token, nil
+	}
+	if err := m.doRefresh(ctx); err != nil {
+		return "", err
+	}
+	token, _ := m.valid(0)
+	return token, nil
+}
+
+// CredentialExpiration the expiry of the refresh token told by the provider, zero if unknown,
+// the storage has to be authorized again before it
+func (m *TokenManager) CredentialExpiration() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.refreshExpiry
+}
+
+// Invalidate drop the access token if it's still in use, so the next request gets a new one,
+// the token may be revoked before it's expired. return false if it's refreshed already
+func (m *TokenManager) Invalidate(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.accessToken != token {
+		return false
+	}
+	m.accessToken = ""
+	return true
+}
+
+func (m *TokenManager) valid(ahead time.Duration) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.accessToken, m.accessToken != "" && time.Now().Add(ahead).Before(m.expiry)
+}
+
+// doRefresh refresh the token with refreshMu held
+func (m *TokenManager) doRefresh(ctx context.Context) error {
+	m.mu.Lock()
+	refreshToken, closed := *m.refreshToken, m.closed
+	m.mu.Unlock()
+	if closed {
+		return errors.Errorf("the token manager of storage [%s] is closed", m.storage.GetStorage().MountPath)
+	}
+	token, err := m.refresh(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(errors.Cause(err), errs.RefreshTokenInvalid) {
+			m.reject(err)
+		}
+		return err
+	}
+	m.mu.Lock()
+	m.accessToken, m.expiry, m.refreshExpiry = token.AccessToken, token.Expiry, token.RefreshExpiry
+	rotated := token.RefreshToken != "" && token.RefreshToken != *m.refreshToken
+	if rotated {
+		*m.refreshToken = token.RefreshToken
+	}
+	rejected := m.rejected
+	m.rejected = false
+	m.mu.Unlock()
+	if rotated {
+		MustSaveDriverStorage(m.storage)
+	}
+	if storage := m.storage.GetStorage(); rejected || storage.Status == model.StorageTokenExpired {
+		setStatus(m.storage, storage, model.StorageWork, 0, "")
+	}
+	return nil
+}
+
+// reject flag the storage, the admin is notified once
+func (m *TokenManager) reject(err error) {
+	m.mu.Lock()
+	m.accessToken = ""
+	rejected := m.rejected
+	m.rejected = true
+	m.mu.Unlock()
+	if rejected {
+		return
+	}
+	storage := m.storage.GetStorage()
+	log.Errorf("the refresh token of storage [%s] is rejected: %+v", storage.MountPath, err)
+	setStatus(m.storage, storage, model.StorageTokenExpired, storage.InitAttempts, err.Error())
+	notifyStorage(storage, "needs to be authorized again",
+		fmt.Sprintf("The refresh token is rejected by the provider: %s\nUpdate the storage with a new refresh token.", err))
+}
+
+// renewTokens refresh the tokens expiring soon, the ones never got or rejected are skipped.
+// only the leader renews, the replicas sharing the refresh tokens would invalidate each other's
+func renewTokens() {
+	ticker := time.NewTicker(tokenCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !leader.IsLeader() {
+			continue
+		}
+		tokenManagers.Range(func(m *TokenManager, _ struct{}) bool {
+			m.mu.Lock()
+			skip := !m.active || m.accessToken == "" || m.rejected || time.Now().Add(tokenRefreshAhead).Before(m.expiry)
+			m.mu.Unlock()
+			if skip {
+				return true
+			}
+			go m.renew()
+			return true
+		})
+	}
+}
+
+func (m *TokenManager) renew() {
+	if !m.refreshMu.TryLock() {
+		// refreshing by a request
+		return
+	}
+	defer m.refreshMu.Unlock()
+	if _, ok := m.valid(tokenRefreshAhead); ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := m.doRefresh(ctx); err != nil {
+		log.Warnf("failed renew the token of storage [%s]: %+v", m.storage.GetStorage().MountPath, err)
+	}
+}
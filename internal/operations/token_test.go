@@ -0,0 +1,41 @@
+package operations_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/pkg/errors"
+)
+
+func TestTokenManager(t *testing.T) {
+	_, s := operations.CreateLocal(t, model.Storage{MountPath: "/token"})
+	refreshToken, rejected := "old", true
+	tokens := operations.NewTokenManager(s, &refreshToken, func(ctx context.Context, refreshToken string) (*operations.OAuthToken, error) {
+		if rejected {
+			return nil, errors.Wrap(errs.RefreshTokenInvalid, "invalid_grant")
+		}
+		return &operations.OAuthToken{AccessToken: "access", Expiry: time.Now().Add(time.Hour), RefreshToken: "new"}, nil
+	})
+	defer tokens.Close()
+	if _, err := tokens.Token(context.Background()); err == nil {
+		t.Fatalf("expected the rejected refresh token fails")
+	}
+	if status := s.GetStorage().Status; status != model.StorageTokenExpired {
+		t.Errorf("expected the storage is flagged, got %q", status)
+	}
+	rejected = false
+	token, err := tokens.Token(context.Background())
+	if err != nil {
+		t.Fatalf("failed get token: %+v", err)
+	}
+	if token != "access" || refreshToken != "new" {
+		t.Errorf("expected the token is refreshed and the refresh token rotated, got %q %q", token, refreshToken)
+	}
+	if status := s.GetStorage().Status; status != model.StorageWork {
+		t.Errorf("expected the storage works again, got %q", status)
+	}
+}
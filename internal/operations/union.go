@@ -0,0 +1,236 @@
+package operations
+
+import (
+	"context"
+	stdpath "path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the union policies merge the members of a balance group into one directory
+// instead of spreading the load, they differ in where the new objs are created
+const (
+	UnionFirstWritable = "union_first_writable"
+	UnionMostFree      = "union_most_free"
+)
+
+func isUnion(policy string) bool {
+	return policy == UnionFirstWritable || policy == UnionMostFree
+}
+
+// GetUnionMembers return the initialized members of the union group the path belongs to,
+// or nil if the path is not in a union
+func GetUnionMembers(rawPath string) []driver.Driver {
+	members := getStoragesByPath(utils.StandardizePath(rawPath))
+	if len(members) < 2 || !isUnion(members[0].GetStorage().BalancePolicy) {
+		return nil
+	}
+	res := make([]driver.Driver, 0, len(members))
+	for _, member := range members {
+		member, err := initIfLazy(member)
+		if err != nil {
+			log.Errorf("%+v", err)
+			continue
+		}
+		res = append(res, member)
+	}
+	return res
+}
+
+// getActualPath get the actual path of the raw path in the storage
+func getActualPath(storage driver.Driver, rawPath string) string {
+	virtualPath := utils.GetActualVirtualPath(storage.GetStorage().MountPath)
+	return ActualPath(storage.GetAddition(), strings.TrimPrefix(rawPath, virtualPath))
+}
+
+// ListUnion list the path in all members of the union, the objs with the same name
+// are merged, the one in the member sorted first wins
+func ListUnion(ctx context.Context, members []driver.Driver, rawPath string, refresh ...bool) ([]model.Obj, error) {
+	var (
+		res     []model.Obj
+		lastErr error
+		listed  bool
+	)
+	seen := make(map[string]struct{})
+	for _, member := range members {
+		objs, err := List(ctx, member, getActualPath(member, rawPath), refresh...)
+		if err != nil {
+			// the folder may only exist in some members
+			if !errs.IsObjectNotFound(err) {
+				log.Warnf("failed list %s in union member [%s]: %+v", rawPath, member.GetStorage().MountPath, err)
+			}
+			lastErr = err
+			continue
+		}
+		listed = true
+		for _, obj := range objs {
+			if _, ok := seen[obj.GetName()]; ok {
+				continue
+			}
+			seen[obj.GetName()] = struct{}{}
+			res = append(res, obj)
+		}
+	}
+	if !listed {
+		return nil, lastErr
+	}
+	return res, nil
+}
+
+// findUnionMember return the first member holding the path, or nil
+func findUnionMember(ctx context.Context, members []driver.Driver, rawPath string) driver.Driver {
+	for _, member := range members {
+		if _, err := Get(ctx, member, getActualPath(member, rawPath)); err == nil {
+			return member
+		}
+	}
+	return nil
+}
+
+// withParent return the members holding the parent dir of the path, or all of them if none does,
+// so the new objs are created beside their siblings instead of splitting the dir across the members
+func withParent(ctx context.Context, members []driver.Driver, rawPath string) []driver.Driver {
+	var res []driver.Driver
+	parent := stdpath.Dir(rawPath)
+	for _, member := range members {
+		if obj, err := Get(ctx, member, getActualPath(member, parent)); err == nil && obj.IsDir() {
+			res = append(res, member)
+		}
+	}
+	if len(res) == 0 {
+		return members
+	}
+	return res
+}
+
+// pickUnionMember return the member holding the path,
+// or the one to create it by the policy if no member has it
+func pickUnionMember(ctx context.Context, members []driver.Driver, rawPath string) driver.Driver {
+	initialized := make([]driver.Driver, 0, len(members))
+	for _, member := range members {
+		member, err := initIfLazy(member)
+		if err != nil {
+			log.Errorf("%+v", err)
+			continue
+		}
+		initialized = append(initialized, member)
+	}
+	if len(initialized) == 0 {
+		return nil
+	}
+	if member := findUnionMember(ctx, initialized, rawPath); member != nil {
+		return member
+	}
+	member, err := pickCreateMember(members[0].GetStorage().BalancePolicy, withParent(ctx, initialized, rawPath), 0)
+	if err != nil {
+		// let the caller fail on the read-only storage
+		return initialized[0]
+	}
+	return member
+}
+
+func canCreate(storage driver.Driver) bool {
+	s := storage.GetStorage()
+	return !s.ReadOnly && !s.IsOpDisabled(model.OpPut) && !storage.Config().NoUpload && isHealthy(s.MountPath)
+}
+
+// pickCreateMember pick the member to create new objs of size in by the policy of the group,
+// the members whose free space would drop below their upload reserve are skipped
+func pickCreateMember(policy string, members []driver.Driver, size int64) (driver.Driver, error) {
+	free := getFreeSpaces()
+	// the free space above the reserve, the members without known usage are not in it
+	headroom := make(map[uint]int64)
+	var writable []driver.Driver
+	full := false
+	for _, member := range members {
+		if !canCreate(member) {
+			continue
+		}
+		s := member.GetStorage()
+		if f, ok := free[s.ID]; ok {
+			if f-size < s.UploadReserve {
+				full = true
+				continue
+			}
+			headroom[s.ID] = f - size - s.UploadReserve
+		}
+		writable = append(writable, member)
+	}
+	if len(writable) == 0 {
+		if full {
+			return nil, errors.WithStack(errs.NoSpaceLeft)
+		}
+		return nil, errors.WithStack(errs.StorageReadOnly)
+	}
+	if policy != UnionMostFree && policy != BalanceMostFree {
+		return writable[0], nil
+	}
+	// the members without known usage are picked last
+	best := writable[0]
+	for _, member := range writable[1:] {
+		h, ok := headroom[member.GetStorage().ID]
+		if !ok {
+			continue
+		}
+		if bestH, bestOk := headroom[best.GetStorage().ID]; !bestOk || h > bestH {
+			best = member
+		}
+	}
+	return best, nil
+}
+
+// GetPutStorageAndActualPath get the storage and actual path of the dir to put the file named name,
+// in a union the file is put into the member holding it already, or the one picked by the policy,
+// in a most free balance group the file is put into the member with the most free space
+func GetPutStorageAndActualPath(ctx context.Context, dstDirPath, name string, size int64) (driver.Driver, string, error) {
+	dstDirPath = utils.StandardizePath(dstDirPath)
+	members := GetUnionMembers(dstDirPath)
+	if len(members) == 0 {
+		if members = getMostFreeMembers(dstDirPath); len(members) == 0 {
+			return GetStorageAndActualPath(ctx, dstDirPath)
+		}
+		member, err := pickCreateMember(BalanceMostFree, members, size)
+		if err != nil {
+			return nil, "", err
+		}
+		return member, getActualPath(member, dstDirPath), nil
+	}
+	path := stdpath.Join(dstDirPath, name)
+	member := findUnionMember(ctx, members, path)
+	if member == nil {
+		var err error
+		member, err = pickCreateMember(members[0].GetStorage().BalancePolicy, withParent(ctx, members, path), size)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return member, getActualPath(member, dstDirPath), nil
+}
+
+// getMostFreeMembers return the initialized members of the balance group the path belongs to
+// if the uploads of the group are routed by the free space, or nil
+func getMostFreeMembers(rawPath string) []driver.Driver {
+	if strings.Contains(rawPath, "..") {
+		return nil
+	}
+	members := getStoragesByPath(rawPath)
+	if len(members) < 2 || members[0].GetStorage().BalancePolicy != BalanceMostFree {
+		return nil
+	}
+	res := make([]driver.Driver, 0, len(members))
+	for _, member := range filterHealthy(members) {
+		member, err := initIfLazy(member)
+		if err != nil {
+			log.Errorf("%+v", err)
+			continue
+		}
+		res = append(res, member)
+	}
+	return res
+}
@@ -0,0 +1,116 @@
+package operations
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterStorageHook(func(event StorageEvent) {
+		if event.Type != EventStorageDeleted {
+			return
+		}
+		if err := db.DeleteStorageUsage(event.Storage.ID); err != nil {
+			log.Warnf("failed delete usage of storage [%s]: %+v", event.Storage.MountPath, err)
+		}
+	})
+}
+
+var (
+	uploadedMu sync.Mutex
+	// storage id => bytes uploaded since the usage is collected, so the routing
+	// doesn't pick the same member until the next collection
+	uploaded = make(map[uint]int64)
+)
+
+// addUploaded count the uploaded bytes into the usage of the storage
+func addUploaded(storage driver.Driver, size int64) {
+	uploadedMu.Lock()
+	defer uploadedMu.Unlock()
+	uploaded[storage.GetStorage().ID] += size
+}
+
+// getFreeSpaces return the known free bytes of the storages by id,
+// the bytes uploaded since the collection are excluded
+func getFreeSpaces() map[uint]int64 {
+	free := make(map[uint]int64)
+	usages, err := db.GetStorageUsages()
+	if err != nil {
+		log.Warnf("failed get storage usages: %+v", err)
+		return free
+	}
+	uploadedMu.Lock()
+	defer uploadedMu.Unlock()
+	for _, usage := range usages {
+		// the driver doesn't know the space
+		if usage.Total == 0 && usage.Free == 0 {
+			continue
+		}
+		free[usage.StorageID] = usage.Free - uploaded[usage.StorageID]
+	}
+	return free
+}
+
+type UsageSummary struct {
+	Storages []model.StorageUsage `json:"storages"`
+	Total    int64                `json:"total"`
+	Used     int64                `json:"used"`
+	Free     int64                `json:"free"`
+}
+
+// CollectUsages ask the initialized storages which implement driver.About
+// for their space and save it into database
+func CollectUsages(ctx context.Context) {
+	storagesMap.Range(func(mountPath string, d driver.Driver) bool {
+		if _, ok := d.(*lazyDriver); ok {
+			return true
+		}
+		a, ok := d.(driver.About)
+		if !ok {
+			return true
+		}
+		usage, err := a.About(ctx)
+		if err != nil {
+			log.Warnf("failed get usage of storage [%s]: %+v", mountPath, err)
+			return true
+		}
+		usage.StorageID = d.GetStorage().ID
+		usage.Updated = time.Now()
+		if err := db.SaveStorageUsage(usage); err != nil {
+			log.Errorf("failed save usage of storage [%s]: %+v", mountPath, err)
+			return true
+		}
+		uploadedMu.Lock()
+		delete(uploaded, usage.StorageID)
+		uploadedMu.Unlock()
+		return true
+	})
+}
+
+// GetUsageSummary get the latest usages of all storages and the sum of them
+func GetUsageSummary() (*UsageSummary, error) {
+	usages, err := db.GetStorageUsages()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get storage usages")
+	}
+	summary := &UsageSummary{Storages: make([]model.StorageUsage, 0, len(usages))}
+	for _, usage := range usages {
+		storage, err := db.GetStorageById(usage.StorageID)
+		if err != nil {
+			continue
+		}
+		usage.MountPath = storage.MountPath
+		summary.Storages = append(summary.Storages, usage)
+		summary.Total += usage.Total
+		summary.Used += usage.Used
+		summary.Free += usage.Free
+	}
+	return summary, nil
+}
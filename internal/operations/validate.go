@@ -0,0 +1,96 @@
+package operations
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// FieldError an invalid field of the addition
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// AdditionError the invalid fields of the addition found before the driver is initialized
+type AdditionError struct {
+	Fields []FieldError
+}
+
+func (e *AdditionError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return "invalid addition: " + strings.Join(msgs, "; ")
+}
+
+// ValidateAddition check the addition against the items of the driver, such as the required fields,
+// the values of the selects and the formats, so the admin gets the invalid fields instead of a failed init
+func ValidateAddition(addition string, items []driver.Item) error {
+	values := make(map[string]interface{})
+	if addition != "" {
+		if err := utils.Json.UnmarshalFromString(addition, &values); err != nil {
+			return errors.Wrap(err, "invalid addition")
+		}
+	}
+	var fields []FieldError
+	for _, item := range items {
+		v, ok := values[item.Name]
+		if s, isStr := v.(string); !ok || v == nil || (isStr && s == "") {
+			if item.Required {
+				fields = append(fields, FieldError{Field: item.Name, Message: "is required"})
+			}
+			continue
+		}
+		if msg := checkValue(item, v); msg != "" {
+			fields = append(fields, FieldError{Field: item.Name, Message: msg})
+		}
+	}
+	if len(fields) > 0 {
+		return &AdditionError{Fields: fields}
+	}
+	return nil
+}
+
+// checkValue return why the value doesn't fit the item, empty if it fits
+func checkValue(item driver.Item, v interface{}) string {
+	if !compatible(item, v) {
+		switch {
+		case item.Type == conf.TypeBool:
+			return "should be true or false"
+		case isNumberType(item.Type):
+			return "should be a number"
+		case item.Type == conf.TypeSelect:
+			return fmt.Sprintf("should be one of %s", item.Values)
+		default:
+			return "should be a string"
+		}
+	}
+	if item.Format == driver.FormatURL {
+		u, err := url.Parse(v.(string))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "should be a http or https url"
+		}
+	}
+	return ""
+}
+
+// validateStorage validate the addition with the credential merged
+func validateStorage(storage model.Storage) error {
+	items, ok := driverItemsMap[storage.Driver]
+	if !ok {
+		return nil
+	}
+	storage, err := applyCredential(storage)
+	if err != nil {
+		return errors.WithMessage(err, "failed apply credential")
+	}
+	return ValidateAddition(storage.Addition, items.Additional)
+}
@@ -0,0 +1,33 @@
+package operations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/pkg/errors"
+)
+
+func TestValidateAddition(t *testing.T) {
+	err := operations.CreateStorage(context.Background(), model.Storage{
+		Driver:    "S3",
+		MountPath: "/invalid_addition",
+		Addition:  `{"root_folder":"/","bucket":"","endpoint":"s3.amazonaws.com","part_size":"16"}`,
+	})
+	var addErr *operations.AdditionError
+	if !errors.As(err, &addErr) {
+		t.Fatalf("expected an addition error, got %+v", err)
+	}
+	fields := map[string]bool{}
+	for _, f := range addErr.Fields {
+		fields[f.Field] = true
+	}
+	if len(fields) != 3 || !fields["bucket"] || !fields["endpoint"] || !fields["part_size"] {
+		t.Errorf("unexpected invalid fields: %+v", addErr.Fields)
+	}
+	if _, err := db.GetStorageByMountPath("/invalid_addition"); err == nil {
+		t.Errorf("expected the invalid storage is not created")
+	}
+}
@@ -0,0 +1,40 @@
+package operations
+
+import (
+	"context"
+	stdpath "path"
+	"path/filepath"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// Walk visit all objs under the path of the storage recursively, the path passed
+// to walkFn is the actual path. If walkFn returns filepath.SkipDir for a dir,
+// the dir will not be listed.
+func Walk(ctx context.Context, storage driver.Driver, path string, walkFn func(path string, obj model.Obj) error) error {
+	objs, err := List(ctx, storage, path)
+	if err != nil {
+		return errors.WithMessagef(err, "failed list [%s]", path)
+	}
+	for _, obj := range objs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		objPath := stdpath.Join(path, obj.GetName())
+		err := walkFn(objPath, obj)
+		if err == filepath.SkipDir {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if obj.IsDir() {
+			if err := Walk(ctx, storage, objPath, walkFn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	stdpath "path"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/pkg/utils/random"
+	"github.com/pkg/errors"
+)
+
+// Addition the fields are described by the plugin
+type Addition struct {
+	items  []driver.Item
+	values map[string]interface{}
+}
+
+func (a Addition) Items() []driver.Item {
+	return a.items
+}
+
+func (a Addition) MarshalJSON() ([]byte, error) {
+	return utils.Json.Marshal(a.values)
+}
+
+// Driver forward the calls to the plugin, the files are identified by paths
+type Driver struct {
+	model.Storage
+	Addition
+	plugin *Plugin
+	// the token of the instance in the plugin, a new one is used each time it's initialized
+	instance string
+}
+
+func (p *Plugin) config() driver.Config {
+	return driver.Config{
+		Name:      p.desc.Name,
+		LocalSort: p.desc.LocalSort,
+		OnlyProxy: p.desc.OnlyProxy,
+		NoCache:   p.desc.NoCache,
+		NoUpload:  p.desc.NoUpload,
+	}
+}
+
+func (d *Driver) Config() driver.Config {
+	return d.plugin.config()
+}
+
+func (d *Driver) Capabilities() driver.Capabilities {
+	if d.plugin.desc.Capabilities == nil {
+		return driver.AllCapabilities()
+	}
+	return *d.plugin.desc.Capabilities
+}
+
+func (d *Driver) Init(ctx context.Context, storage model.Storage) error {
+	d.Storage = storage
+	d.instance = random.String(16)
+	d.values = map[string]interface{}{}
+	if storage.Addition != "" {
+		if err := utils.Json.UnmarshalFromString(storage.Addition, &d.values); err != nil {
+			return errors.Wrap(err, "error while unmarshal addition")
+		}
+	}
+	return d.plugin.init(ctx, InitArgs{
+		StorageArgs: d.args(),
+		MountPath:   storage.MountPath,
+		Addition:    d.values,
+	})
+}
+
+func (d *Driver) Drop(ctx context.Context) error {
+	return d.plugin.drop(ctx, d.args())
+}
+
+func (d *Driver) GetAddition() driver.Additional {
+	return d.Addition
+}
+
+func (d *Driver) args() StorageArgs {
+	return StorageArgs{StorageID: d.ID, Instance: d.instance}
+}
+
+func (d *Driver) Get(ctx context.Context, path string) (model.Obj, error) {
+	var obj Object
+	if err := d.plugin.call(ctx, MethodGet, PathArgs{StorageArgs: d.args(), Path: path}, &obj); err != nil {
+		return nil, err
+	}
+	return toObj(path, obj), nil
+}
+
+func (d *Driver) List(ctx context.Context, dir model.Obj) ([]model.Obj, error) {
+	var objs []Object
+	if err := d.plugin.call(ctx, MethodList, PathArgs{StorageArgs: d.args(), Path: dir.GetID()}, &objs); err != nil {
+		return nil, err
+	}
+	return utils.SliceConvert(objs, func(obj Object) (model.Obj, error) {
+		return toObj(stdpath.Join(dir.GetID(), obj.Name), obj), nil
+	})
+}
+
+func toObj(path string, obj Object) model.Obj {
+	if obj.Name == "" {
+		obj.Name = stdpath.Base(path)
+	}
+	return &model.Object{
+		ID:       path,
+		Name:     obj.Name,
+		Size:     obj.Size,
+		Modified: obj.Modified,
+		IsFolder: obj.IsDir,
+	}
+}
+
+func (d *Driver) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	var link Link
+	if err := d.plugin.call(ctx, MethodLink, LinkArgs{
+		PathArgs: PathArgs{StorageArgs: d.args(), Path: file.GetID()},
+		IP:       args.IP,
+		Header:   args.Header,
+	}, &link); err != nil {
+		return nil, err
+	}
+	res := &model.Link{URL: link.URL, Header: link.Header}
+	if link.Expiration > 0 {
+		expiration := time.Duration(link.Expiration) * time.Second
+		res.Expiration = &expiration
+	}
+	return res, nil
+}
+
+func (d *Driver) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	return d.plugin.call(ctx, MethodMakeDir, MakeDirArgs{StorageArgs: d.args(), ParentPath: parentDir.GetID(), Name: dirName}, &Empty{})
+}
+
+func (d *Driver) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.plugin.call(ctx, MethodMove, MoveArgs{StorageArgs: d.args(), SrcPath: srcObj.GetID(), DstDirPath: dstDir.GetID()}, &Empty{})
+}
+
+func (d *Driver) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.plugin.call(ctx, MethodRename, RenameArgs{StorageArgs: d.args(), SrcPath: srcObj.GetID(), NewName: newName}, &Empty{})
+}
+
+func (d *Driver) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.plugin.call(ctx, MethodCopy, MoveArgs{StorageArgs: d.args(), SrcPath: srcObj.GetID(), DstDirPath: dstDir.GetID()}, &Empty{})
+}
+
+func (d *Driver) Remove(ctx context.Context, obj model.Obj) error {
+	return d.plugin.call(ctx, MethodRemove, PathArgs{StorageArgs: d.args(), Path: obj.GetID()}, &Empty{})
+}
+
+// Put the stream is cached into a local file for the plugin to read
+func (d *Driver) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) error {
+	f, ok := stream.GetReadCloser().(*os.File)
+	if !ok {
+		var err error
+		f, err = utils.CreateTempFile(stream)
+		if err != nil {
+			return errors.WithMessage(err, "failed cache the stream")
+		}
+		defer func() {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}()
+	}
+	err := d.plugin.call(ctx, MethodPut, PutArgs{
+		StorageArgs: d.args(),
+		DstDirPath:  dstDir.GetID(),
+		Name:        stream.GetName(),
+		Size:        stream.GetSize(),
+		Mimetype:    stream.GetMimetype(),
+		FilePath:    f.Name(),
+	}, &Empty{})
+	if err == nil {
+		up(100)
+	}
+	return err
+}
+
+func (d *Driver) Other(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, errs.NotSupport
+}
+
+var _ driver.Driver = (*Driver)(nil)
+var _ driver.Getter = (*Driver)(nil)
+var _ driver.Capable = (*Driver)(nil)
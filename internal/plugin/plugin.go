@@ -0,0 +1,250 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the time for a plugin to describe itself after started
+var describeTimeout = 10 * time.Second
+
+var (
+	// the delay before restarting a plugin crashed again, doubled every crash
+	restartBackoff    = time.Second
+	restartBackoffMax = 5 * time.Minute
+	// the crashes are forgotten once the plugin has run for it
+	crashResetAfter = time.Minute
+)
+
+var errRestartBackoff = errors.New("the plugin keeps crashing, waiting to restart")
+
+// Plugin the process of a plugin, it's restarted if it exits,
+// and the storages initialized before are initialized again
+type Plugin struct {
+	path string
+	desc Description
+
+	mu     sync.Mutex
+	client *rpc.Client
+	// closed once the storages are initialized again after the client is connected
+	ready chan struct{}
+	// the crashes in a row, it's not restarted until restartAt
+	crashes   int
+	restartAt time.Time
+	// the storages initialized, by instance
+	storages map[string]InitArgs
+}
+
+// Load start the plugins in the dir and register their drivers,
+// a plugin failed to start or conflicting with another driver is skipped
+func Load(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("failed read plugin dir %s: %+v", dir, err)
+		}
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		p, err := start(filepath.Join(dir, e.Name()))
+		if err != nil {
+			log.Errorf("failed load plugin %s: %+v", e.Name(), err)
+			continue
+		}
+		log.Infof("loaded plugin %s providing driver [%s]", e.Name(), p.desc.Name)
+	}
+}
+
+func start(path string) (*Plugin, error) {
+	p := &Plugin{path: path, storages: map[string]InitArgs{}}
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+	if err := p.call(ctx, MethodDescribe, Empty{}, &p.desc); err != nil {
+		p.kill()
+		return nil, errors.WithMessage(err, "failed describe")
+	}
+	if p.desc.Name == "" {
+		p.kill()
+		return nil, errors.New("the driver has no name")
+	}
+	if _, err := operations.GetDriverNew(p.desc.Name); err == nil {
+		p.kill()
+		return nil, errors.Errorf("driver [%s] is registered already", p.desc.Name)
+	}
+	operations.RegisterDriver(p.config(), func() driver.Driver {
+		return &Driver{plugin: p, Addition: Addition{items: p.desc.Items}}
+	})
+	return p, nil
+}
+
+// connect start the process if it's not running, with mu held
+func (p *Plugin) connect() (*rpc.Client, error) {
+	if p.client != nil {
+		return p.client, nil
+	}
+	if delay := time.Until(p.restartAt); delay > 0 {
+		return nil, errors.Wrapf(errRestartBackoff, "plugin %s crashed %d times, restart in %s", p.path, p.crashes, delay.Round(time.Second))
+	}
+	cmd := exec.Command(p.path)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	client := jsonrpc.NewClient(struct {
+		io.Reader
+		io.WriteCloser
+	}{stdout, stdin})
+	started := time.Now()
+	go func() {
+		err := cmd.Wait()
+		p.mu.Lock()
+		// it's crashed rather than killed
+		if p.client == client {
+			p.client = nil
+			if time.Since(started) > crashResetAfter {
+				p.crashes = 0
+			}
+			p.crashes++
+			p.restartAt = time.Now().Add(restartDelay(p.crashes))
+		}
+		log.Warnf("plugin %s exited: %v, crashed %d times", p.path, err, p.crashes)
+		p.mu.Unlock()
+		_ = client.Close()
+	}()
+	p.client = client
+	return client, nil
+}
+
+func (p *Plugin) kill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		// the plugin exits when its stdin is closed
+		_ = p.client.Close()
+		p.client = nil
+	}
+}
+
+// restartDelay return the delay before restarting after the crashes in a row,
+// the first crash is restarted at once
+func restartDelay(crashes int) time.Duration {
+	if crashes <= 1 {
+		return 0
+	}
+	delay := restartBackoff
+	for i := 2; i < crashes && delay < restartBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > restartBackoffMax {
+		delay = restartBackoffMax
+	}
+	return delay
+}
+
+// getClient return the client, the storages are initialized again if the plugin is restarted
+func (p *Plugin) getClient(ctx context.Context) (*rpc.Client, error) {
+	p.mu.Lock()
+	if p.client != nil {
+		client, ready := p.client, p.ready
+		p.mu.Unlock()
+		select {
+		case <-ready:
+			return client, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	client, err := p.connect()
+	if err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+	ready := make(chan struct{})
+	p.ready = ready
+	storages := make([]InitArgs, 0, len(p.storages))
+	for _, args := range p.storages {
+		storages = append(storages, args)
+	}
+	p.mu.Unlock()
+	// mu isn't held, so a slow init doesn't block the exit of the plugin, the other calls wait for ready
+	defer close(ready)
+	for _, args := range storages {
+		if err := wait(ctx, client, MethodInit, args, &Empty{}); err != nil {
+			log.Errorf("failed init storage [%s] after plugin %s restarted: %+v", args.MountPath, p.path, err)
+		}
+	}
+	return client, nil
+}
+
+func (p *Plugin) call(ctx context.Context, method string, args, reply interface{}) error {
+	client, err := p.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	return wait(ctx, client, method, args, reply)
+}
+
+func wait(ctx context.Context, client *rpc.Client, method string, args, reply interface{}) error {
+	call := client.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		return toError(call.Error)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// toError map the errors of the plugin to the ones of alist
+func toError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, e := range []error{errs.ObjectNotFound, errs.NotSupport} {
+		if err.Error() == e.Error() {
+			return errors.WithStack(e)
+		}
+	}
+	return errors.WithStack(err)
+}
+
+func (p *Plugin) init(ctx context.Context, args InitArgs) error {
+	if err := p.call(ctx, MethodInit, args, &Empty{}); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.storages[args.Instance] = args
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Plugin) drop(ctx context.Context, args StorageArgs) error {
+	p.mu.Lock()
+	delete(p.storages, args.Instance)
+	p.mu.Unlock()
+	return p.call(ctx, MethodDrop, args, &Empty{})
+}
@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+// the test binary serves as the plugin if the env is set
+const helperEnv = "ALIST_PLUGIN_HELPER"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(helperEnv) {
+	case "crash":
+		os.Exit(1)
+	case "1":
+		server := rpc.NewServer()
+		if err := server.RegisterName("Driver", &fakeDriver{storages: map[string]bool{}}); err != nil {
+			os.Exit(1)
+		}
+		server.ServeCodec(jsonrpc.NewServerCodec(struct {
+			io.Reader
+			io.WriteCloser
+		}{os.Stdin, os.Stdout}))
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type fakeDriver struct {
+	storages map[string]bool
+}
+
+func (f *fakeDriver) Describe(args Empty, reply *Description) error {
+	*reply = Description{Name: "Fake"}
+	return nil
+}
+
+func (f *fakeDriver) Init(args InitArgs, reply *Empty) error {
+	f.storages[args.Instance] = true
+	return nil
+}
+
+func (f *fakeDriver) Drop(args StorageArgs, reply *Empty) error {
+	delete(f.storages, args.Instance)
+	return nil
+}
+
+func (f *fakeDriver) List(args PathArgs, reply *[]Object) error {
+	if !f.storages[args.Instance] {
+		return errors.New("not initialized")
+	}
+	*reply = []Object{{Name: "a.txt", Size: 1}}
+	return nil
+}
+
+func (f *fakeDriver) Get(args PathArgs, reply *Object) error {
+	return errors.New(errs.ObjectNotFound.Error())
+}
+
+func TestPlugin(t *testing.T) {
+	t.Setenv(helperEnv, "1")
+	p := &Plugin{path: os.Args[0], storages: map[string]InitArgs{}}
+	defer p.kill()
+	ctx := context.Background()
+	if err := p.call(ctx, MethodDescribe, Empty{}, &p.desc); err != nil || p.desc.Name != "Fake" {
+		t.Fatalf("failed describe: %+v %+v", p.desc, err)
+	}
+	d := &Driver{plugin: p}
+	if err := d.Init(ctx, model.Storage{ID: 1, Addition: `{"root":"/"}`}); err != nil {
+		t.Fatalf("failed init: %+v", err)
+	}
+	objs, err := d.List(ctx, &model.Object{ID: "/dir", IsFolder: true})
+	if err != nil || len(objs) != 1 || objs[0].GetID() != "/dir/a.txt" {
+		t.Fatalf("unexpected list: %+v %+v", objs, err)
+	}
+	if _, err := d.Get(ctx, "/missing"); !errs.IsObjectNotFound(err) {
+		t.Errorf("expected object not found, got %+v", err)
+	}
+	// the storage is initialized again after the plugin is restarted
+	p.kill()
+	if _, err := d.List(ctx, &model.Object{ID: "/", IsFolder: true}); err != nil {
+		t.Errorf("failed list after restart: %+v", err)
+	}
+	// the old instance of the updated storage is dropped after the new one is initialized
+	updated := &Driver{plugin: p}
+	if err := updated.Init(ctx, model.Storage{ID: 1, Addition: `{"root":"/new"}`}); err != nil {
+		t.Fatalf("failed init: %+v", err)
+	}
+	if err := d.Drop(ctx); err != nil {
+		t.Fatalf("failed drop: %+v", err)
+	}
+	if _, err := updated.List(ctx, &model.Object{ID: "/", IsFolder: true}); err != nil {
+		t.Errorf("failed list after the old instance dropped: %+v", err)
+	}
+	if _, err := d.List(ctx, &model.Object{ID: "/", IsFolder: true}); err == nil {
+		t.Errorf("expected the dropped instance is gone")
+	}
+}
+
+func TestPluginCrashLoop(t *testing.T) {
+	t.Setenv(helperEnv, "crash")
+	p := &Plugin{path: os.Args[0], storages: map[string]InitArgs{}}
+	defer p.kill()
+	crashes := func() int {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.crashes
+	}
+	// the first crash is restarted at once, the second one waits for the backoff
+	for i := 1; i <= 2; i++ {
+		if err := p.call(context.Background(), MethodDescribe, Empty{}, &p.desc); err == nil {
+			t.Fatalf("expected the crashed plugin fails")
+		}
+		for deadline := time.Now().Add(5 * time.Second); crashes() < i; time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d crashes, got %d", i, crashes())
+			}
+		}
+	}
+	if err := p.call(context.Background(), MethodDescribe, Empty{}, &p.desc); !errors.Is(err, errRestartBackoff) {
+		t.Errorf("expected it's not restarted during the backoff, got %+v", err)
+	}
+}
@@ -0,0 +1,126 @@
+// Package plugin run the drivers shipped as separate executables. a plugin is an executable in
+// the plugins directory, it's started at bootstrap and speaks JSON-RPC 1.0 (the codec of net/rpc/jsonrpc)
+// over its stdin and stdout, the methods are served by the service named Driver. the plugin should
+// write its logs to stderr and exit when its stdin is closed.
+//
+// the storages are identified by the instance tokens, a storage gets a new one each time it's initialized,
+// e.g. when it's updated the new instance is initialized before the old one is dropped, so the state of
+// the plugin should be keyed by the instance rather than the storage id. the files are identified by their
+// paths relative to the storage,
+// the errors whose messages are "object not found" or "not support" are mapped to the ones of alist.
+package plugin
+
+import (
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/driver"
+)
+
+// the methods served by the plugin, with the types of their args and replies
+const (
+	// MethodDescribe Empty -> Description, called once the plugin is started
+	MethodDescribe = "Driver.Describe"
+	// MethodInit InitArgs -> Empty, also called again for the storages after the plugin is restarted
+	MethodInit = "Driver.Init"
+	// MethodDrop StorageArgs -> Empty
+	MethodDrop = "Driver.Drop"
+	// MethodGet PathArgs -> Object
+	MethodGet = "Driver.Get"
+	// MethodList PathArgs -> []Object
+	MethodList = "Driver.List"
+	// MethodLink LinkArgs -> Link
+	MethodLink = "Driver.Link"
+	// MethodMakeDir MakeDirArgs -> Empty
+	MethodMakeDir = "Driver.MakeDir"
+	// MethodMove MoveArgs -> Empty
+	MethodMove = "Driver.Move"
+	// MethodRename RenameArgs -> Empty
+	MethodRename = "Driver.Rename"
+	// MethodCopy MoveArgs -> Empty
+	MethodCopy = "Driver.Copy"
+	// MethodRemove PathArgs -> Empty
+	MethodRemove = "Driver.Remove"
+	// MethodPut PutArgs -> Empty
+	MethodPut = "Driver.Put"
+)
+
+type Empty struct{}
+
+// Description the driver provided by the plugin
+type Description struct {
+	Name      string `json:"name"`
+	LocalSort bool   `json:"local_sort"`
+	OnlyProxy bool   `json:"only_proxy"`
+	NoCache   bool   `json:"no_cache"`
+	NoUpload  bool   `json:"no_upload"`
+	// Items the fields of the addition
+	Items []driver.Item `json:"items"`
+	// Capabilities all are supported if nil
+	Capabilities *driver.Capabilities `json:"capabilities"`
+}
+
+type StorageArgs struct {
+	StorageID uint `json:"storage_id"`
+	// Instance the token of the initialized instance of the storage
+	Instance string `json:"instance"`
+}
+
+type InitArgs struct {
+	StorageArgs
+	MountPath string                 `json:"mount_path"`
+	Addition  map[string]interface{} `json:"addition"`
+}
+
+type PathArgs struct {
+	StorageArgs
+	Path string `json:"path"`
+}
+
+type LinkArgs struct {
+	PathArgs
+	IP     string              `json:"ip"`
+	Header map[string][]string `json:"header"`
+}
+
+type MakeDirArgs struct {
+	StorageArgs
+	ParentPath string `json:"parent_path"`
+	Name       string `json:"name"`
+}
+
+type MoveArgs struct {
+	StorageArgs
+	SrcPath    string `json:"src_path"`
+	DstDirPath string `json:"dst_dir_path"`
+}
+
+type RenameArgs struct {
+	StorageArgs
+	SrcPath string `json:"src_path"`
+	NewName string `json:"new_name"`
+}
+
+// PutArgs the content is in a local file, which is removed after the call
+type PutArgs struct {
+	StorageArgs
+	DstDirPath string `json:"dst_dir_path"`
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	Mimetype   string `json:"mimetype"`
+	FilePath   string `json:"file_path"`
+}
+
+type Object struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	IsDir    bool      `json:"is_dir"`
+}
+
+// Link the plugin can't stream the content over rpc, it may serve a local url instead
+type Link struct {
+	URL    string              `json:"url"`
+	Header map[string][]string `json:"header"`
+	// Expiration seconds of the url, 0 if unknown
+	Expiration int `json:"expiration"`
+}
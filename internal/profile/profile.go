@@ -0,0 +1,171 @@
+// Package profile capture the cpu profiles and heap snapshots of the running server for download
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	KindCPU  = "cpu"
+	KindHeap = "heap"
+)
+
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// DefaultSeconds is the duration of the cpu profile if not specified
+const DefaultSeconds = 30
+
+type Capture struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Seconds int       `json:"seconds,omitempty"`
+	Created time.Time `json:"created"`
+	Status  string    `json:"status"`
+	Size    int64     `json:"size"`
+	Error   string    `json:"error,omitempty"`
+}
+
+var (
+	captures = map[string]*Capture{}
+	mu       sync.Mutex
+)
+
+// the captures are kept in the temp dir, so they are cleared on restart
+func dir() string {
+	return filepath.Join(conf.Conf.TempDir, "profiles")
+}
+
+// Get return the finished capture and its file
+func Get(id string) (*Capture, string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := captures[id]
+	if !ok {
+		return nil, "", errors.Errorf("capture %s not found", id)
+	}
+	if c.Status != StatusDone {
+		return nil, "", errors.Errorf("capture %s is %s", id, c.Status)
+	}
+	res := *c
+	return &res, filepath.Join(dir(), id), nil
+}
+
+// Start capture the profile of the kind, the cpu profile is captured in background for seconds
+func Start(kind string, seconds int) (*Capture, error) {
+	if kind != KindCPU && kind != KindHeap {
+		return nil, errors.Errorf("unsupported profile kind: %s", kind)
+	}
+	if err := os.MkdirAll(dir(), 0700); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c := &Capture{
+		ID:      uuid.NewString(),
+		Kind:    kind,
+		Created: time.Now(),
+		Status:  StatusRunning,
+	}
+	f, err := os.Create(filepath.Join(dir(), c.ID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if kind == KindHeap {
+		runtime.GC()
+		err = pprof.WriteHeapProfile(f)
+		finish(c, f, err)
+		mu.Lock()
+		captures[c.ID] = c
+		mu.Unlock()
+		return c, err
+	}
+	if seconds <= 0 {
+		seconds = DefaultSeconds
+	}
+	c.Seconds = seconds
+	// only one cpu profile can run at a time
+	if err := pprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, errors.Wrap(err, "failed start cpu profile")
+	}
+	mu.Lock()
+	captures[c.ID] = c
+	mu.Unlock()
+	go func() {
+		time.Sleep(time.Duration(seconds) * time.Second)
+		pprof.StopCPUProfile()
+		finish(c, f, nil)
+	}()
+	return c, nil
+}
+
+func finish(c *Capture, f *os.File, err error) {
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	var size int64
+	if stat, statErr := os.Stat(f.Name()); statErr == nil {
+		size = stat.Size()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	c.Size = size
+	if err != nil {
+		log.Errorf("failed capture %s profile: %+v", c.Kind, err)
+		c.Status, c.Error = StatusFailed, err.Error()
+		return
+	}
+	c.Status = StatusDone
+}
+
+// List return the captures, the latest first
+func List() []Capture {
+	mu.Lock()
+	defer mu.Unlock()
+	res := make([]Capture, 0, len(captures))
+	for _, c := range captures {
+		res = append(res, *c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Created.After(res[j].Created)
+	})
+	return res
+}
+
+// Delete the finished capture and its file
+func Delete(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := captures[id]
+	if !ok {
+		return errors.Errorf("capture %s not found", id)
+	}
+	if c.Status == StatusRunning {
+		return errors.Errorf("capture %s is running", id)
+	}
+	delete(captures, id)
+	if err := os.Remove(filepath.Join(dir(), id)); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Filename return the name to download the capture as
+func (c Capture) Filename() string {
+	return fmt.Sprintf("alist-%s-%s.pprof", c.Kind, c.Created.Format("20060102-150405"))
+}
@@ -1,8 +1,11 @@
 package setting
 
 import (
-	"github.com/alist-org/alist/v3/internal/db"
 	"strconv"
+
+	"github.com/alist-org/alist/v3/cmd/args"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
 )
 
 func GetByKey(key string, defaultValue ...string) string {
@@ -27,3 +30,8 @@ func GetIntSetting(key string, defaultVal int) int {
 func IsTrue(key string) bool {
 	return GetByKey(key) == "true" || GetByKey(key) == "1"
 }
+
+// IsReadOnly check whether the whole site is read-only by the flag or the setting
+func IsReadOnly() bool {
+	return args.ReadOnly || IsTrue(conf.ReadOnly)
+}
@@ -0,0 +1,208 @@
+// Package supervisor track the resources used by subsystems and reject the work over the budgets
+package supervisor
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/pkg/errors"
+)
+
+// the resources tracked
+const (
+	ProxyStreams = "proxy_streams" // the streams proxied to the clients
+	Tasks        = "tasks"         // the pending and running upload and copy tasks
+	TempDisk     = "temp_disk"     // bytes of the temp files
+	Thumbnails   = "thumbnails"    // the cover images of the folders served
+)
+
+var ErrOverBudget = errors.New("server is busy, over the budget")
+
+// RetryAfter is the time suggested to the rejected clients
+const RetryAfter = 30 * time.Second
+
+type pool struct {
+	sync.Mutex
+	used     int64
+	peak     int64
+	rejected int64
+}
+
+// the set of pools is fixed, so the map is read only
+var pools = map[string]*pool{
+	ProxyStreams: {},
+	Tasks:        {},
+	TempDisk:     {},
+	Thumbnails:   {},
+}
+
+// the subsystems whose goroutines and open files are tracked
+var subsystems = []string{ProxyStreams, Tasks, Thumbnails}
+
+type counter struct {
+	goroutines int64
+	files      int64
+}
+
+// the set of subsystems is fixed, so the map is read only
+var counters = map[string]*counter{
+	ProxyStreams: {},
+	Tasks:        {},
+	Thumbnails:   {},
+}
+
+func limit(name string) int64 {
+	budget := conf.DefaultConfig().Budget
+	if conf.Conf != nil {
+		budget = conf.Conf.Budget
+	}
+	switch name {
+	case ProxyStreams:
+		return int64(budget.ProxyStreams)
+	case Tasks:
+		return int64(budget.Tasks)
+	case TempDisk:
+		return budget.TempDisk * 1024 * 1024
+	case Thumbnails:
+		return int64(budget.Thumbnails)
+	}
+	return 0
+}
+
+// Acquire n of the resource, the returned func must be called to release it,
+// a request larger than the whole budget is still allowed when nothing is used
+func Acquire(name string, n int64) (func(), error) {
+	p, ok := pools[name]
+	if !ok {
+		return nil, errors.Errorf("unknown resource: %s", name)
+	}
+	p.Lock()
+	if max := limit(name); max > 0 && p.used > 0 && p.used+n > max {
+		p.rejected++
+		p.Unlock()
+		return nil, errors.Wrapf(ErrOverBudget, "%s used %d of %d", name, p.used, max)
+	}
+	p.used += n
+	if p.used > p.peak {
+		p.peak = p.used
+	}
+	p.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.Lock()
+			p.used -= n
+			p.Unlock()
+		})
+	}, nil
+}
+
+func track(n *int64) func() {
+	atomic.AddInt64(n, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			atomic.AddInt64(n, -1)
+		})
+	}
+}
+
+// TrackGoroutine count a goroutine of the subsystem, the returned func must be called when it ends
+func TrackGoroutine(subsystem string) func() {
+	return track(&counters[subsystem].goroutines)
+}
+
+// TrackFile count a file opened by the subsystem, the returned func must be called when it's closed
+func TrackFile(subsystem string) func() {
+	return track(&counters[subsystem].files)
+}
+
+type Usage struct {
+	Name     string `json:"name"`
+	Used     int64  `json:"used"`
+	Limit    int64  `json:"limit"` // 0 means no limit
+	Peak     int64  `json:"peak"`
+	Rejected int64  `json:"rejected"`
+}
+
+type SubsystemUsage struct {
+	Name        string `json:"name"`
+	Goroutines  int64  `json:"goroutines"`
+	FileHandles int64  `json:"file_handles"`
+}
+
+type Stats struct {
+	Goroutines int              `json:"goroutines"` // all goroutines of the process
+	Usages     []Usage          `json:"usages"`
+	Subsystems []SubsystemUsage `json:"subsystems"`
+}
+
+// GetStats return the current usage of the resources
+func GetStats() Stats {
+	stats := Stats{Goroutines: runtime.NumGoroutine()}
+	for _, name := range []string{ProxyStreams, Tasks, TempDisk, Thumbnails} {
+		p := pools[name]
+		p.Lock()
+		stats.Usages = append(stats.Usages, Usage{
+			Name:     name,
+			Used:     p.used,
+			Limit:    limit(name),
+			Peak:     p.peak,
+			Rejected: p.rejected,
+		})
+		p.Unlock()
+	}
+	for _, name := range subsystems {
+		stats.Subsystems = append(stats.Subsystems, SubsystemUsage{
+			Name:        name,
+			Goroutines:  atomic.LoadInt64(&counters[name].goroutines),
+			FileHandles: atomic.LoadInt64(&counters[name].files),
+		})
+	}
+	return stats
+}
+
+// Metrics return the usage in the text format of prometheus
+func Metrics() string {
+	stats := GetStats()
+	var b strings.Builder
+	metric := func(name, help string, write func()) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		write()
+	}
+	metric("alist_goroutines", "The goroutines of the process.", func() {
+		fmt.Fprintf(&b, "alist_goroutines %d\n", stats.Goroutines)
+	})
+	gauges := []struct {
+		name, help string
+		value      func(u Usage) int64
+	}{
+		{"alist_budget_used", "The resources used.", func(u Usage) int64 { return u.Used }},
+		{"alist_budget_limit", "The budget of the resources, 0 means no limit.", func(u Usage) int64 { return u.Limit }},
+		{"alist_budget_peak", "The peak of the resources used.", func(u Usage) int64 { return u.Peak }},
+		{"alist_budget_rejected", "The requests rejected for being over the budget.", func(u Usage) int64 { return u.Rejected }},
+	}
+	for _, g := range gauges {
+		metric(g.name, g.help, func() {
+			for _, u := range stats.Usages {
+				fmt.Fprintf(&b, "%s{resource=%q} %d\n", g.name, u.Name, g.value(u))
+			}
+		})
+	}
+	metric("alist_subsystem_goroutines", "The goroutines of the subsystems.", func() {
+		for _, s := range stats.Subsystems {
+			fmt.Fprintf(&b, "alist_subsystem_goroutines{subsystem=%q} %d\n", s.Name, s.Goroutines)
+		}
+	})
+	metric("alist_subsystem_file_handles", "The files opened by the subsystems.", func() {
+		for _, s := range stats.Subsystems {
+			fmt.Fprintf(&b, "alist_subsystem_file_handles{subsystem=%q} %d\n", s.Name, s.FileHandles)
+		}
+	})
+	return b.String()
+}
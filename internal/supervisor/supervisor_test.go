@@ -0,0 +1,39 @@
+package supervisor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/pkg/errors"
+)
+
+func TestBudget(t *testing.T) {
+	conf.Conf = conf.DefaultConfig()
+	conf.Conf.Budget.Thumbnails = 1
+	release, err := Acquire(Thumbnails, 1)
+	if err != nil {
+		t.Fatalf("failed acquire: %+v", err)
+	}
+	if _, err := Acquire(Thumbnails, 1); !errors.Is(err, ErrOverBudget) {
+		t.Fatalf("expected over budget, got %+v", err)
+	}
+	release()
+	release()
+	untrack := TrackFile(Thumbnails)
+	metrics := Metrics()
+	for _, line := range []string{
+		`alist_budget_used{resource="thumbnails"} 0`,
+		`alist_budget_limit{resource="thumbnails"} 1`,
+		`alist_budget_rejected{resource="thumbnails"} 1`,
+		`alist_subsystem_file_handles{subsystem="thumbnails"} 1`,
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("expected %s in metrics:\n%s", line, metrics)
+		}
+	}
+	untrack()
+	if files := GetStats().Subsystems[2].FileHandles; files != 0 {
+		t.Errorf("expected the file is untracked, got %d", files)
+	}
+}
@@ -0,0 +1,347 @@
+// Package userdata export all the data of a user into an archive, and erase it on request,
+// such as for the data subject requests of GDPR
+package userdata
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/generic_sync"
+	"github.com/alist-org/alist/v3/pkg/task"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var TaskManager = task.NewTaskManager(1, func(tid *uint64) {
+	atomic.AddUint64(tid, 1)
+})
+
+// task id => id of the user exported, to check who can download the archive
+var exports generic_sync.MapOf[uint64, uint]
+
+const pageSize = 100
+
+// the archives are kept in the temp dir, so they are cleared on restart
+func dir() string {
+	return filepath.Join(conf.Conf.TempDir, "user_data")
+}
+
+func archivePath(tid uint64) string {
+	return filepath.Join(dir(), fmt.Sprintf("%d.zip", tid))
+}
+
+// File an entry of the manifest of the files under the base path of the user
+type File struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	IsDir    bool      `json:"is_dir"`
+}
+
+// Export add a task to bundle the records of the user, such as the profile, the shares, the activities
+// and the playback progresses, and the manifest of the files under the base path into a zip archive
+func Export(userId uint) (uint64, error) {
+	user, err := db.GetUserById(userId)
+	if err != nil {
+		return 0, err
+	}
+	if user.IsGuest() {
+		return 0, errors.New("the data of guest can't be exported")
+	}
+	tid := TaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
+		Name: fmt.Sprintf("export data of user [%s]", user.Username),
+		Func: func(t *task.Task[uint64]) error {
+			return export(t, user)
+		},
+	}))
+	exports.Store(tid, user.ID)
+	return tid, nil
+}
+
+func export(t *task.Task[uint64], user *model.User) error {
+	if err := os.MkdirAll(dir(), 0700); err != nil {
+		return errors.WithStack(err)
+	}
+	f, err := os.Create(archivePath(t.ID))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = writeArchive(t, f, user)
+	if closeErr := f.Close(); err == nil {
+		err = errors.WithStack(closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+func writeArchive(t *task.Task[uint64], f *os.File, user *model.User) error {
+	w := zip.NewWriter(f)
+	writeJson := func(name string, v interface{}) error {
+		entry, err := w.Create(name)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(utils.Json.NewEncoder(entry).Encode(v))
+	}
+	t.SetStatus("exporting profile")
+	profile := *user
+	profile.Password = ""
+	if err := writeJson("profile.json", profile); err != nil {
+		return err
+	}
+	t.SetStatus("exporting shares")
+	shares, err := getShares(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeJson("shares.json", shares); err != nil {
+		return err
+	}
+	var logs []model.ShareLog
+	for _, share := range shares {
+		l, err := collect(func(pageIndex int) ([]model.ShareLog, error) {
+			logs, _, err := db.GetShareLogs(share.Token, pageIndex, pageSize)
+			return logs, err
+		})
+		if err != nil {
+			return err
+		}
+		logs = append(logs, l...)
+	}
+	if err := writeJson("share_logs.json", logs); err != nil {
+		return err
+	}
+	t.SetProgress(10)
+	t.SetStatus("exporting activities")
+	activities, err := collect(func(pageIndex int) ([]model.Activity, error) {
+		activities, _, _, err := db.GetActivities(user.ID, false, pageIndex, pageSize)
+		return activities, err
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeJson("activities.json", activities); err != nil {
+		return err
+	}
+	appPasswords, err := db.GetAppPasswordsOfUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeJson("app_passwords.json", appPasswords); err != nil {
+		return err
+	}
+	progresses, err := collect(func(pageIndex int) ([]model.Progress, error) {
+		progresses, _, err := db.GetProgresses(user.ID, pageIndex, pageSize)
+		return progresses, err
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeJson("progresses.json", progresses); err != nil {
+		return err
+	}
+	t.SetProgress(20)
+	t.SetStatus("listing files")
+	var files []File
+	ctx := context.WithValue(t.Ctx, "user", user)
+	err = fs.Walk(ctx, user.BasePath, func(path string, obj model.Obj) error {
+		if utils.IsCanceled(t.Ctx) {
+			return t.Ctx.Err()
+		}
+		files = append(files, File{Path: path, Size: obj.GetSize(), Modified: obj.ModTime(), IsDir: obj.IsDir()})
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed list files")
+	}
+	if err := writeJson("files.json", files); err != nil {
+		return err
+	}
+	t.SetProgress(100)
+	t.SetStatus(fmt.Sprintf("%d shares, %d activities and %d files exported", len(shares), len(activities), len(files)))
+	return errors.WithStack(w.Close())
+}
+
+// collect get all pages of the items
+func collect[T any](get func(pageIndex int) ([]T, error)) ([]T, error) {
+	var all []T
+	for pageIndex := 1; ; pageIndex++ {
+		items, err := get(pageIndex)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+func getShares(userId uint) ([]model.Share, error) {
+	return collect(func(pageIndex int) ([]model.Share, error) {
+		shares, _, err := db.GetShares(userId, pageIndex, pageSize)
+		return shares, err
+	})
+}
+
+// GetArchive return the archive of the finished export task, and the user exported
+func GetArchive(tid uint64) (string, uint, error) {
+	t, ok := TaskManager.Get(tid)
+	userId, exported := exports.Load(tid)
+	if !ok || !exported {
+		return "", 0, errors.WithStack(task.ErrTaskNotFound)
+	}
+	if t.GetState() != task.SUCCEEDED {
+		return "", 0, errors.Errorf("the export is %s", t.GetState())
+	}
+	return archivePath(tid), userId, nil
+}
+
+// Remove remove the finished task and its archive
+func Remove(tid uint64) error {
+	if err := TaskManager.Remove(tid); err != nil {
+		return err
+	}
+	removeArchive(tid)
+	return nil
+}
+
+// ClearDone remove the finished tasks and their archives
+func ClearDone() {
+	for _, t := range TaskManager.ListDone() {
+		removeArchive(t.ID)
+	}
+	TaskManager.ClearDone()
+}
+
+func removeArchive(tid uint64) {
+	if _, ok := exports.Load(tid); !ok {
+		return
+	}
+	exports.Delete(tid)
+	if err := os.Remove(archivePath(tid)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("failed remove the archive of task %d: %+v", tid, err)
+	}
+}
+
+// Erase add a task to remove the files under the base path of the user, the records of the user
+// and then the user. the held files are kept, and the files are kept if the base path is shared with others
+func Erase(userId uint) (uint64, error) {
+	user, err := db.GetUserById(userId)
+	if err != nil {
+		return 0, err
+	}
+	if user.IsAdmin() || user.IsGuest() {
+		return 0, errors.WithStack(errs.DeleteAdminOrGuest)
+	}
+	tid := TaskManager.Submit(task.WithCancelCtx(&task.Task[uint64]{
+		Name: fmt.Sprintf("erase data of user [%s]", user.Username),
+		Func: func(t *task.Task[uint64]) error {
+			return erase(t, user)
+		},
+	}))
+	return tid, nil
+}
+
+func erase(t *task.Task[uint64], user *model.User) error {
+	var held []string
+	shared, err := isBasePathShared(user)
+	if err != nil {
+		return err
+	}
+	if !shared {
+		t.SetStatus("removing files")
+		ctx := context.WithValue(t.Ctx, "user", user)
+		if held, err = removeAll(ctx, user.BasePath); err != nil {
+			return err
+		}
+	}
+	t.SetProgress(80)
+	if utils.IsCanceled(t.Ctx) {
+		return t.Ctx.Err()
+	}
+	// the shares, activities, app passwords and progresses go with the user
+	t.SetStatus("removing the user")
+	if err := db.DeleteUserById(user.ID); err != nil {
+		return err
+	}
+	t.SetProgress(100)
+	switch {
+	case shared:
+		t.SetStatus(fmt.Sprintf("user erased, the files are kept, %s is shared with other users", user.BasePath))
+	case len(held) > 0:
+		t.SetStatus(fmt.Sprintf("user erased, %d paths are kept by holds: %v", len(held), held))
+	default:
+		t.SetStatus("user erased")
+	}
+	return nil
+}
+
+// isBasePathShared tell whether the base path of other users is in the base path of the user,
+// the users above it such as the admin and guest at / don't count, or no files would ever be erased
+func isBasePathShared(user *model.User) (bool, error) {
+	users, err := db.GetAllUsers()
+	if err != nil {
+		return false, err
+	}
+	for _, u := range users {
+		if u.ID != user.ID && utils.IsSubPath(user.BasePath, u.BasePath) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// removeAll remove everything under the dir, return the paths kept by holds
+func removeAll(ctx context.Context, dirPath string) ([]string, error) {
+	meta, err := db.GetNearestMeta(dirPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return nil, err
+	}
+	objs, err := fs.List(context.WithValue(ctx, "meta", meta), dirPath, true)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed list [%s]", dirPath)
+	}
+	var held []string
+	for _, obj := range objs {
+		if utils.IsCanceled(ctx) {
+			return held, ctx.Err()
+		}
+		path := stdpath.Join(dirPath, obj.GetName())
+		err := fs.Remove(ctx, path)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(errors.Cause(err), errs.PathOnHold) {
+			return held, errors.WithMessagef(err, "failed remove [%s]", path)
+		}
+		if !obj.IsDir() {
+			held = append(held, path)
+			continue
+		}
+		// the dir is held or has held files inside
+		sub, err := removeAll(ctx, path)
+		held = append(held, sub...)
+		if err != nil {
+			return held, err
+		}
+		if len(sub) == 0 {
+			held = append(held, path)
+		}
+	}
+	return held, nil
+}
@@ -0,0 +1,161 @@
+package userdata
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/task"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig()
+	db.Init(dB)
+}
+
+func wait(t *testing.T, tid uint64) *task.Task[uint64] {
+	for i := 0; i < 100; i++ {
+		if tsk, ok := TaskManager.Get(tid); ok && tsk.Done() {
+			return tsk
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("task %d is not done", tid)
+	return nil
+}
+
+func TestExportAndErase(t *testing.T) {
+	conf.Conf.TempDir = t.TempDir()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "alice", "archive"), 0777); err != nil {
+		t.Fatalf("failed make dir: %+v", err)
+	}
+	for _, name := range []string{"alice/a.txt", "alice/archive/b.txt"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("data"), 0666); err != nil {
+			t.Fatalf("failed write file: %+v", err)
+		}
+	}
+	storage := model.Storage{Driver: "Local", MountPath: "/ud", Addition: fmt.Sprintf(`{"root_folder":%q}`, root)}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	user := model.User{Username: "alice", BasePath: "/ud/alice", Role: model.GENERAL}
+	if err := db.CreateUser(&user); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	if err := db.CreateShare(&model.Share{Token: "alice", Path: "/ud/alice/a.txt", UserID: user.ID, Created: time.Now()}); err != nil {
+		t.Fatalf("failed create share: %+v", err)
+	}
+
+	tid, err := Export(user.ID)
+	if err != nil {
+		t.Fatalf("failed export: %+v", err)
+	}
+	if tsk := wait(t, tid); tsk.GetState() != task.SUCCEEDED {
+		t.Fatalf("export failed: %s", tsk.GetErrMsg())
+	}
+	path, userId, err := GetArchive(tid)
+	if err != nil || userId != user.ID {
+		t.Fatalf("failed get archive: %d %+v", userId, err)
+	}
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed open archive: %+v", err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	_ = r.Close()
+	if len(names) != 7 || names[0] != "profile.json" || names[6] != "files.json" {
+		t.Errorf("unexpected entries: %v", names)
+	}
+
+	admin := &model.User{Username: "admin", Role: model.ADMIN}
+	if _, err := operations.PlaceHold("/ud/alice/archive", "lawsuit", admin); err != nil {
+		t.Fatalf("failed place hold: %+v", err)
+	}
+	tid, err = Erase(user.ID)
+	if err != nil {
+		t.Fatalf("failed erase: %+v", err)
+	}
+	if tsk := wait(t, tid); tsk.GetState() != task.SUCCEEDED {
+		t.Fatalf("erase failed: %s", tsk.GetErrMsg())
+	}
+	if _, err := os.Stat(filepath.Join(root, "alice", "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected a.txt is removed, got %+v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "alice", "archive", "b.txt")); err != nil {
+		t.Errorf("expected the held file is kept, got %+v", err)
+	}
+	if _, err := db.GetUserById(user.ID); err == nil {
+		t.Errorf("expected the user is deleted")
+	}
+	if shares, _, _ := db.GetShares(user.ID, 1, 10); len(shares) != 0 {
+		t.Errorf("expected the shares are deleted, got %+v", shares)
+	}
+}
+
+func TestEraseWithDefaultUsers(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"bob", "carol", "carol/dave"} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0777); err != nil {
+			t.Fatalf("failed make dir: %+v", err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name, "a.txt"), []byte("data"), 0666); err != nil {
+			t.Fatalf("failed write file: %+v", err)
+		}
+	}
+	storage := model.Storage{Driver: "Local", MountPath: "/ud_default", Addition: fmt.Sprintf(`{"root_folder":%q}`, root)}
+	if err := operations.CreateStorage(context.Background(), storage); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	// the same as the ones created at the first start
+	users := []model.User{
+		{Username: "admin", BasePath: "/", Role: model.ADMIN},
+		{Username: "guest", BasePath: "/", Role: model.GUEST},
+		{Username: "bob", BasePath: "/ud_default/bob", Role: model.GENERAL},
+		{Username: "carol", BasePath: "/ud_default/carol", Role: model.GENERAL},
+		{Username: "dave", BasePath: "/ud_default/carol/dave", Role: model.GENERAL},
+	}
+	for i := range users {
+		if err := db.CreateUser(&users[i]); err != nil {
+			t.Fatalf("failed create user: %+v", err)
+		}
+	}
+	for _, c := range []struct {
+		user    model.User
+		file    string
+		removed bool
+	}{
+		{users[2], "bob/a.txt", true},
+		// dave can still access the files of carol
+		{users[3], "carol/a.txt", false},
+	} {
+		tid, err := Erase(c.user.ID)
+		if err != nil {
+			t.Fatalf("failed erase: %+v", err)
+		}
+		if tsk := wait(t, tid); tsk.GetState() != task.SUCCEEDED {
+			t.Fatalf("erase failed: %s", tsk.GetErrMsg())
+		}
+		if _, err := os.Stat(filepath.Join(root, c.file)); os.IsNotExist(err) != c.removed {
+			t.Errorf("expected %s removed: %v, got %+v", c.file, c.removed, err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package task
 
 import (
+	"sync"
+
 	"github.com/alist-org/alist/v3/pkg/generic_sync"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
@@ -8,7 +10,9 @@ import (
 )
 
 type Manager[K comparable] struct {
-	workerC  chan struct{}
+	workerC chan struct{}
+	// idMu guards curID, the tasks are submitted concurrently
+	idMu     sync.Mutex
 	curID    K
 	updateID func(*K)
 	tasks    generic_sync.MapOf[K, *Task[K]]
@@ -16,8 +20,10 @@ type Manager[K comparable] struct {
 
 func (tm *Manager[K]) Submit(task *Task[K]) K {
 	if tm.updateID != nil {
+		tm.idMu.Lock()
+		tm.updateID(&tm.curID)
 		task.ID = tm.curID
-		tm.updateID(&task.ID)
+		tm.idMu.Unlock()
 	}
 	tm.tasks.Store(task.ID, task)
 	tm.do(task)
@@ -3,6 +3,7 @@ package task
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -34,6 +35,8 @@ type Task[K comparable] struct {
 
 	Ctx    context.Context
 	cancel context.CancelFunc
+	// the time the last run ended
+	endTime time.Time
 }
 
 func (t *Task[K]) SetStatus(status string) {
@@ -56,6 +59,10 @@ func (t Task[K]) GetStatus() string {
 	return t.status
 }
 
+func (t Task[K]) GetEndTime() time.Time {
+	return t.endTime
+}
+
 func (t Task[K]) GetErrMsg() string {
 	if t.Error == nil {
 		return ""
@@ -65,6 +72,9 @@ func (t Task[K]) GetErrMsg() string {
 
 func (t *Task[K]) run() {
 	t.state = RUNNING
+	defer func() {
+		t.endTime = time.Now()
+	}()
 	defer func() {
 		if err := recover(); err != nil {
 			log.Errorf("error [%+v] while run task [%s]", err, t.Name)
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -94,3 +95,37 @@ func TestTask_Retry(t *testing.T) {
 		t.Errorf("task error: %+v, but expected nil", task.Error)
 	}
 }
+
+func TestTask_ID(t *testing.T) {
+	tm := NewTaskManager(3, func(id *uint64) {
+		atomic.AddUint64(id, 1)
+	})
+	fn := func(task *Task[uint64]) error { return nil }
+	id1 := tm.Submit(WithCancelCtx(&Task[uint64]{Name: "1", Func: fn}))
+	id2 := tm.Submit(WithCancelCtx(&Task[uint64]{Name: "2", Func: fn}))
+	if id1 == id2 {
+		t.Errorf("tasks have the same id: %d", id1)
+	}
+	if len(tm.GetAll()) != 2 {
+		t.Errorf("expected 2 tasks, got %d", len(tm.GetAll()))
+	}
+}
+
+func TestTask_ConcurrentID(t *testing.T) {
+	tm := NewTaskManager(3, func(id *uint64) {
+		atomic.AddUint64(id, 1)
+	})
+	fn := func(task *Task[uint64]) error { return nil }
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tm.Submit(WithCancelCtx(&Task[uint64]{Name: "concurrent", Func: fn}))
+		}()
+	}
+	wg.Wait()
+	if len(tm.GetAll()) != 100 {
+		t.Errorf("expected 100 tasks with distinct ids, got %d", len(tm.GetAll()))
+	}
+}
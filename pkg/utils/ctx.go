@@ -2,6 +2,9 @@ package utils
 
 import (
 	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 func IsCanceled(ctx context.Context) bool {
@@ -12,3 +15,54 @@ func IsCanceled(ctx context.Context) bool {
 		return false
 	}
 }
+
+type withoutCancel struct {
+	context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (withoutCancel) Done() <-chan struct{} {
+	return nil
+}
+
+func (withoutCancel) Err() error {
+	return nil
+}
+
+// WithoutCancel return a context with the values of ctx but never canceled
+func WithoutCancel(ctx context.Context) context.Context {
+	return withoutCancel{ctx}
+}
+
+const (
+	// RequestIDKey is the key of the request id in the context
+	RequestIDKey = "request_id"
+	// RequestIDHeader is the header carrying the request id, inbound and to the providers
+	RequestIDHeader = "X-Request-ID"
+	// ClientIPKey is the key of the ip of the client in the context
+	ClientIPKey = "client_ip"
+)
+
+// ClientIPOf return the ip of the client of the ctx if any
+func ClientIPOf(ctx context.Context) string {
+	ip, _ := ctx.Value(ClientIPKey).(string)
+	return ip
+}
+
+// RequestID return the request id of the ctx if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// Log return the logger with the request id of the ctx if any,
+// so the entries of the same request can be correlated
+func Log(ctx context.Context) *log.Entry {
+	if id := RequestID(ctx); id != "" {
+		return log.WithField(RequestIDKey, id)
+	}
+	return log.NewEntry(log.StandardLogger())
+}
@@ -3,6 +3,9 @@ package utils
 import (
 	"context"
 	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
 )
 
 // here is some syntaxic sugar inspired by the Tomas Senart's video,
@@ -33,3 +36,96 @@ func CopyWithCtx(ctx context.Context, out io.Writer, in io.Reader) error {
 	}))
 	return err
 }
+
+type limitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *rate.Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if burst := r.l.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.l.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// NewLimitedReader limit the reading speed of r by the limiter, the limiter may be shared by readers
+func NewLimitedReader(ctx context.Context, r io.Reader, l *rate.Limiter) io.Reader {
+	return &limitedReader{ctx: ctx, r: r, l: l}
+}
+
+type ReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+const readAheadChunk = 64 * 1024
+
+type readAheadReader struct {
+	rc    io.ReadCloser
+	ch    chan []byte
+	cur   []byte
+	err   error
+	done  chan struct{}
+	close sync.Once
+}
+
+func (r *readAheadReader) fill() {
+	defer close(r.ch)
+	for {
+		buf := make([]byte, readAheadChunk)
+		n, err := r.rc.Read(buf)
+		if n > 0 {
+			select {
+			case r.ch <- buf[:n]:
+			case <-r.done:
+				return
+			}
+		}
+		if err != nil {
+			r.err = err
+			return
+		}
+	}
+}
+
+func (r *readAheadReader) Read(p []byte) (int, error) {
+	if len(r.cur) == 0 {
+		buf, ok := <-r.ch
+		if !ok {
+			return 0, r.err
+		}
+		r.cur = buf
+	}
+	n := copy(p, r.cur)
+	r.cur = r.cur[n:]
+	return n, nil
+}
+
+func (r *readAheadReader) Close() error {
+	var err error
+	r.close.Do(func() {
+		close(r.done)
+		err = r.rc.Close()
+	})
+	return err
+}
+
+// NewReadAheadReader read rc in background and buffer up to size bytes
+// before they are read, so the slow source won't block the reader
+func NewReadAheadReader(rc io.ReadCloser, size int) io.ReadCloser {
+	n := size / readAheadChunk
+	if n < 1 {
+		n = 1
+	}
+	r := &readAheadReader{rc: rc, ch: make(chan []byte, n), done: make(chan struct{})}
+	go r.fill()
+	return r
+}
@@ -22,3 +22,30 @@ func WriteToJson(src string, conf interface{}) bool {
 	}
 	return true
 }
+
+// MergeJson set the fields of patch into the json object raw, and return the merged json
+func MergeJson(raw string, patch map[string]interface{}) (string, error) {
+	obj := make(map[string]interface{})
+	if raw != "" {
+		if err := Json.UnmarshalFromString(raw, &obj); err != nil {
+			return "", err
+		}
+	}
+	for k, v := range patch {
+		obj[k] = v
+	}
+	return Json.MarshalToString(obj)
+}
+
+// JsonEqual compare two values by their json representation
+func JsonEqual(a, b interface{}) bool {
+	aj, err := Json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bj, err := Json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(aj) == string(bj)
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"net/url"
 	stdpath "path"
 	"path/filepath"
 	"runtime"
@@ -29,6 +30,30 @@ func PathEqual(path1, path2 string) bool {
 	return StandardizePath(path1) == StandardizePath(path2)
 }
 
+// IsSubPath judge sub is equal to path or inside it
+func IsSubPath(path string, sub string) bool {
+	path, sub = StandardizePath(path), StandardizePath(sub)
+	return path == sub || strings.HasPrefix(sub, strings.TrimSuffix(path, "/")+"/")
+}
+
+// ReplacePathPrefix replace the prefix oldPath of path with newPath
+func ReplacePathPrefix(path, oldPath, newPath string) string {
+	path, oldPath, newPath = StandardizePath(path), StandardizePath(oldPath), StandardizePath(newPath)
+	if path == oldPath {
+		return newPath
+	}
+	return stdpath.Join(newPath, strings.TrimPrefix(path, strings.TrimSuffix(oldPath, "/")))
+}
+
+// EncodePath escape each segment of the path, used to build urls
+func EncodePath(path string) string {
+	seg := strings.Split(path, "/")
+	for i := range seg {
+		seg[i] = url.PathEscape(seg[i])
+	}
+	return strings.Join(seg, "/")
+}
+
 func Ext(path string) string {
 	ext := stdpath.Ext(path)
 	if strings.HasPrefix(ext, ".") {
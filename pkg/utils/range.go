@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseRange parse the single range of the Range header into the first and last byte,
+// false if the header is empty, invalid or has multiple ranges
+func ParseRange(header string, size int64) (int64, int64, bool) {
+	spec := strings.TrimPrefix(header, "bytes=")
+	if spec == header || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	startStr, endStr, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+	var start, end int64
+	var err error
+	if startStr == "" {
+		// the suffix range, such as bytes=-500
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		start, end = size-n, size-1
+		if start < 0 {
+			start = 0
+		}
+		return start, end, true
+	}
+	if start, err = strconv.ParseInt(startStr, 10, 64); err != nil || start >= size {
+		return 0, 0, false
+	}
+	end = size - 1
+	if endStr != "" {
+		if end, err = strconv.ParseInt(endStr, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
@@ -1,37 +1,71 @@
 package common
 
 import (
+	"net/http"
+	"strconv"
+
 	"github.com/alist-org/alist/v3/cmd/args"
+	"github.com/alist-org/alist/v3/internal/supervisor"
+	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/gin-gonic/gin"
-	log "github.com/sirupsen/logrus"
+	"github.com/pkg/errors"
 )
 
 // ErrorResp is used to return error response
 // @param l: if true, log error
 func ErrorResp(c *gin.Context, err error, code int, l ...bool) {
+	if errors.Is(err, supervisor.ErrOverBudget) {
+		BusyResp(c, err)
+		return
+	}
 	if len(l) > 0 && l[0] {
 		if args.Debug || args.Dev {
-			log.Errorf("%+v", err)
+			utils.Log(c).Errorf("%+v", err)
 		} else {
-			log.Errorf("%v", err)
+			utils.Log(c).Errorf("%v", err)
 		}
 	}
 	c.JSON(200, Resp{
-		Code:    code,
-		Message: err.Error(),
-		Data:    nil,
+		Code:      code,
+		Message:   err.Error(),
+		Data:      nil,
+		RequestID: c.GetString(utils.RequestIDKey),
+	})
+	c.Abort()
+}
+
+// ErrorDataResp is used to return error response with the details, such as the invalid fields
+func ErrorDataResp(c *gin.Context, err error, code int, data interface{}) {
+	c.JSON(200, Resp{
+		Code:      code,
+		Message:   err.Error(),
+		Data:      data,
+		RequestID: c.GetString(utils.RequestIDKey),
+	})
+	c.Abort()
+}
+
+// BusyResp respond 503 with Retry-After when the resources are over budget
+func BusyResp(c *gin.Context, err error) {
+	c.Header("Retry-After", strconv.Itoa(int(supervisor.RetryAfter.Seconds())))
+	c.JSON(http.StatusServiceUnavailable, Resp{
+		Code:      http.StatusServiceUnavailable,
+		Message:   err.Error(),
+		Data:      nil,
+		RequestID: c.GetString(utils.RequestIDKey),
 	})
 	c.Abort()
 }
 
 func ErrorStrResp(c *gin.Context, str string, code int, l ...bool) {
 	if len(l) != 0 && l[0] {
-		log.Error(str)
+		utils.Log(c).Error(str)
 	}
 	c.JSON(200, Resp{
-		Code:    code,
-		Message: str,
-		Data:    nil,
+		Code:      code,
+		Message:   str,
+		Data:      nil,
+		RequestID: c.GetString(utils.RequestIDKey),
 	})
 	c.Abort()
 }
@@ -2,7 +2,15 @@ package common
 
 import (
 	"fmt"
+	"mime"
+	stdpath "path"
+
+	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/internal/supervisor"
+	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"io"
@@ -14,14 +22,21 @@ import (
 	"strings"
 )
 
-var HttpClient = &http.Client{}
-
 func Proxy(w http.ResponseWriter, r *http.Request, link *model.Link, file model.Obj) error {
+	release, err := supervisor.Acquire(supervisor.ProxyStreams, 1)
+	if err != nil {
+		if link.Data != nil {
+			_ = link.Data.Close()
+		}
+		return err
+	}
+	defer release()
+	defer supervisor.TrackGoroutine(supervisor.ProxyStreams)()
 	// read data with native
-	var err error
 	if link.Data != nil {
+		data := readAhead(link.Data, file)
 		defer func() {
-			_ = link.Data.Close()
+			_ = data.Close()
 		}()
 		w.Header().Set("Content-Type", "application/octet-stream")
 		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, file.GetName(), url.QueryEscape(file.GetName())))
@@ -36,7 +51,7 @@ func Proxy(w http.ResponseWriter, r *http.Request, link *model.Link, file model.
 		} else {
 			w.WriteHeader(link.Status)
 		}
-		_, err = io.Copy(w, link.Data)
+		_, err = io.Copy(w, data)
 		if err != nil {
 			return err
 		}
@@ -48,6 +63,7 @@ func Proxy(w http.ResponseWriter, r *http.Request, link *model.Link, file model.
 		if err != nil {
 			return err
 		}
+		defer supervisor.TrackFile(supervisor.ProxyStreams)()
 		defer func() {
 			_ = f.Close()
 		}()
@@ -56,7 +72,14 @@ func Proxy(w http.ResponseWriter, r *http.Request, link *model.Link, file model.
 			return err
 		}
 		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, file.GetName(), url.QueryEscape(file.GetName())))
-		http.ServeContent(w, r, file.GetName(), fileStat.ModTime(), f)
+		var rs io.ReadSeeker = f
+		if link.Limiter != nil {
+			rs = struct {
+				io.Reader
+				io.Seeker
+			}{utils.NewLimitedReader(r.Context(), f, link.Limiter), f}
+		}
+		http.ServeContent(w, r, file.GetName(), fileStat.ModTime(), rs)
 		return nil
 	} else {
 		req, err := http.NewRequest(r.Method, link.URL, nil)
@@ -72,7 +95,8 @@ func Proxy(w http.ResponseWriter, r *http.Request, link *model.Link, file model.
 		for h, val := range link.Header {
 			req.Header[h] = val
 		}
-		res, err := HttpClient.Do(req)
+		client := net.Client(link.Network)
+		res, err := client.Do(req)
 		if err != nil {
 			return err
 		}
@@ -90,10 +114,49 @@ func Proxy(w http.ResponseWriter, r *http.Request, link *model.Link, file model.
 			log.Debugln(msg)
 			return errors.New(msg)
 		}
-		_, err = io.Copy(w, res.Body)
+		var rc io.ReadCloser = res.Body
+		if r.Method == http.MethodGet {
+			rc = operations.NewMirrorReader(r.Context(), client, link, res)
+		}
+		body := readAhead(rc, file)
+		defer func() {
+			_ = body.Close()
+		}()
+		var limited io.Reader = body
+		if link.Limiter != nil {
+			limited = utils.NewLimitedReader(r.Context(), body, link.Limiter)
+		}
+		_, err = io.Copy(w, limited)
 		if err != nil {
 			return err
 		}
 		return nil
 	}
 }
+
+// readAhead buffer the upcoming data of media files, so the player won't stutter
+// when the storage is slow for a while
+func readAhead(rc io.ReadCloser, file model.Obj) io.ReadCloser {
+	if conf.Conf.ReadAhead <= 0 {
+		return rc
+	}
+	mimetype := mime.TypeByExtension(stdpath.Ext(file.GetName()))
+	if !strings.HasPrefix(mimetype, "video/") && !strings.HasPrefix(mimetype, "audio/") {
+		return rc
+	}
+	return &trackedReadCloser{
+		ReadCloser: utils.NewReadAheadReader(rc, conf.Conf.ReadAhead*1024*1024),
+		release:    supervisor.TrackGoroutine(supervisor.ProxyStreams),
+	}
+}
+
+// trackedReadCloser release the goroutine of the reader counted when it's closed
+type trackedReadCloser struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *trackedReadCloser) Close() error {
+	defer r.release()
+	return r.ReadCloser.Close()
+}
@@ -1,9 +1,10 @@
 package common
 
 type Resp struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"request_id,omitempty"` // only in error responses
 }
 
 type PageResp struct {
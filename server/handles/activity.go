@@ -0,0 +1,102 @@
+package handles
+
+import (
+	"io"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/activity"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// the comment sent to keep the event stream alive through the proxies
+const activityKeepAlive = 30 * time.Second
+
+type ListActivitiesReq struct {
+	common.PageReq
+	Unread bool `json:"unread" form:"unread"`
+}
+
+type ListActivitiesResp struct {
+	common.PageResp
+	Unread int64 `json:"unread"`
+}
+
+// relativeActivity make the path relative to the base path of the user
+func relativeActivity(user *model.User, a model.Activity) model.Activity {
+	if a.Path != "" {
+		a.Path = stdpath.Join("/", strings.TrimPrefix(a.Path, strings.TrimSuffix(user.BasePath, "/")))
+	}
+	return a
+}
+
+func ListActivities(c *gin.Context) {
+	var req ListActivitiesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.MustGet("user").(*model.User)
+	activities, total, unread, err := db.GetActivities(user.ID, req.Unread, req.PageIndex, req.PageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	for i := range activities {
+		activities[i] = relativeActivity(user, activities[i])
+	}
+	common.SuccessResp(c, ListActivitiesResp{
+		PageResp: common.PageResp{Content: activities, Total: total},
+		Unread:   unread,
+	})
+}
+
+type MarkActivitiesReadReq struct {
+	IDs []uint `json:"ids"` // empty means all
+}
+
+func MarkActivitiesRead(c *gin.Context) {
+	var req MarkActivitiesReadReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	if err := db.MarkActivitiesRead(user.ID, req.IDs); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// ActivityEvents push the new activities of the user as server-sent events
+func ActivityEvents(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	if user.IsGuest() {
+		common.ErrorStrResp(c, "guest has no activities", 403)
+		return
+	}
+	ch, unsubscribe := activity.Subscribe(user.ID)
+	defer unsubscribe()
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	ticker := time.NewTicker(activityKeepAlive)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case a := <-ch:
+			c.SSEvent("activity", relativeActivity(user, a))
+		case <-ticker.C:
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+		}
+		return true
+	})
+}
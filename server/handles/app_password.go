@@ -0,0 +1,104 @@
+package handles
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils/random"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAppPasswordReq struct {
+	Name     string `json:"name" binding:"required"`
+	Protocol string `json:"protocol"` // webdav by default
+}
+
+// ListAppPasswords list the devices registered with the app passwords, with the last seen of them
+func ListAppPasswords(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	listAppPasswords(c, user.ID)
+}
+
+// ListUserAppPasswords list the devices of the user for admin
+func ListUserAppPasswords(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("user_id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	listAppPasswords(c, uint(userId))
+}
+
+func listAppPasswords(c *gin.Context, userId uint) {
+	passwords, err := db.GetAppPasswordsOfUser(userId)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, passwords)
+}
+
+// CreateAppPassword the password is returned only once, only its hash is saved
+func CreateAppPassword(c *gin.Context) {
+	var req CreateAppPasswordReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	if user.IsGuest() {
+		common.ErrorStrResp(c, "guest can't create app passwords", 403)
+		return
+	}
+	if req.Protocol == "" {
+		req.Protocol = model.ProtocolWebdav
+	}
+	if req.Protocol != model.ProtocolWebdav {
+		common.ErrorStrResp(c, "app passwords are only supported by webdav", 400)
+		return
+	}
+	password := random.String(24)
+	p := model.AppPassword{
+		UserID:   user.ID,
+		Name:     req.Name,
+		Protocol: req.Protocol,
+		Created:  time.Now(),
+	}
+	if err := db.CreateAppPassword(&p, password); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, gin.H{"app_password": p, "password": password})
+}
+
+// DeleteAppPassword revoke the device, the next request of it is rejected
+func DeleteAppPassword(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	deleteAppPassword(c, user.ID)
+}
+
+// DeleteUserAppPassword revoke the device of the user for admin, such as a lost one
+func DeleteUserAppPassword(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("user_id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	deleteAppPassword(c, uint(userId))
+}
+
+func deleteAppPassword(c *gin.Context, userId uint) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := db.DeleteAppPassword(userId, uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
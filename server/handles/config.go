@@ -0,0 +1,69 @@
+package handles
+
+import (
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportConfig export the settings, metas and users to promote them to another instance
+func ExportConfig(c *gin.Context) {
+	var req operations.ConfigExportOptions
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	config, err := operations.ExportConfig(req)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, config)
+}
+
+type ImportConfigReq struct {
+	Config operations.Config `json:"config"`
+	operations.ConfigImportOptions
+}
+
+func ImportConfig(c *gin.Context) {
+	var req ImportConfigReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	switch req.Conflict {
+	case "", operations.ConflictSkip, operations.ConflictOverwrite, operations.ConflictMerge, operations.ConflictError:
+	default:
+		common.ErrorStrResp(c, "unknown conflict strategy: "+req.Conflict, 400)
+		return
+	}
+	common.SuccessResp(c, operations.ImportConfig(req.Config, req.ConfigImportOptions))
+}
+
+type ApplyStateReq struct {
+	State  operations.State `json:"state"`
+	DryRun bool             `json:"dry_run"`
+}
+
+// ApplyState converge the storages, users, metas and settings to the declared state,
+// only the plan is returned with dry_run
+func ApplyState(c *gin.Context) {
+	var req ApplyStateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	var changes []operations.Change
+	var err error
+	if req.DryRun {
+		changes, err = operations.PlanState(req.State)
+	} else {
+		changes, err = operations.ApplyState(c, req.State)
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, changes)
+}
@@ -0,0 +1,215 @@
+package handles
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/internal/supervisor"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxCoverSize the max size of the uploaded covers, they are kept in the database
+const maxCoverSize = 5 << 20
+
+func coverSignData(metaID uint) string {
+	return fmt.Sprintf("cover:%d", metaID)
+}
+
+// UploadCover upload the cover image of the folder of the meta, as a form file or the raw body
+func UploadCover(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	meta, err := db.GetMetaById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	body := io.Reader(c.Request.Body)
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxCoverSize+1))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if len(data) > maxCoverSize {
+		common.ErrorStrResp(c, fmt.Sprintf("the cover is larger than %d bytes", maxCoverSize), 400)
+		return
+	}
+	mimetype := http.DetectContentType(data)
+	if !strings.HasPrefix(mimetype, "image/") {
+		common.ErrorStrResp(c, "the cover is not an image", 400)
+		return
+	}
+	err = db.SaveCoverImage(&model.CoverImage{
+		MetaID:   meta.ID,
+		Mimetype: mimetype,
+		Data:     data,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func DeleteCover(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := db.DeleteCoverImage(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// Cover serve the uploaded cover of the meta, the url is signed in the list responses
+func Cover(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := sign.Verify(coverSignData(uint(id)), c.Query("sign")); err != nil {
+		common.ErrorResp(c, err, 401)
+		return
+	}
+	release, err := supervisor.Acquire(supervisor.Thumbnails, 1)
+	if err != nil {
+		common.BusyResp(c, err)
+		return
+	}
+	defer release()
+	defer supervisor.TrackGoroutine(supervisor.Thumbnails)()
+	cover, err := db.GetCoverImage(uint(id))
+	if err != nil {
+		if errors.Is(errors.Cause(err), gorm.ErrRecordNotFound) {
+			common.ErrorStrResp(c, "cover not found", 404)
+			return
+		}
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	c.Header("Last-Modified", cover.Modified.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(200, cover.Mimetype, cover.Data)
+}
+
+// coverURL the url of the cover of the folder of the meta, empty if it has none or the user can't access it.
+// the uploaded cover takes precedence over the cover path, the modified time busts the caches of the old one
+func coverURL(c *gin.Context, user *model.User, password string, meta *model.Meta, uploaded *time.Time) string {
+	if uploaded != nil {
+		return fmt.Sprintf("%s/api/public/cover?id=%d&t=%d&sign=%s", common.GetBaseUrl(c.Request),
+			meta.ID, uploaded.Unix(), sign.Sign(coverSignData(meta.ID)))
+	}
+	if meta.Cover == "" {
+		return ""
+	}
+	path := stdpath.Clean(meta.Cover)
+	if !strings.HasPrefix(path, "/") {
+		// a relative cover stays in the folder
+		path = stdpath.Join(meta.Path, path)
+		if !utils.IsSubPath(meta.Path, path) {
+			return ""
+		}
+	}
+	// the signed url skips the password of the meta of the cover
+	coverMeta, err := db.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		log.Errorf("failed get meta of cover [%s]: %+v", path, err)
+		return ""
+	}
+	if !canAccess(user, coverMeta, path, password) {
+		return ""
+	}
+	return signURL(c, "d", path, stdpath.Base(path))
+}
+
+// getFolderInfo the cover url and description of the folder, only the meta of the folder itself applies
+func getFolderInfo(c *gin.Context, user *model.User, password string, meta *model.Meta, path string) (string, string) {
+	if meta == nil || !utils.PathEqual(meta.Path, path) {
+		return "", ""
+	}
+	times, err := db.GetCoverImageTimes([]uint{meta.ID})
+	if err != nil {
+		log.Errorf("failed get cover of [%s]: %+v", path, err)
+	}
+	var uploaded *time.Time
+	if t, ok := times[meta.ID]; ok {
+		uploaded = &t
+	}
+	return coverURL(c, user, password, meta, uploaded), meta.Description
+}
+
+// fillChildFolderInfo set the covers and descriptions of the sub folders listed in the dir,
+// the folders protected by a password the user can't skip are left as they are
+func fillChildFolderInfo(c *gin.Context, user *model.User, dir string, resp []ObjResp) {
+	metas, err := db.GetChildMetas(dir)
+	if err != nil || len(metas) == 0 {
+		if err != nil {
+			log.Errorf("failed get child metas of [%s]: %+v", dir, err)
+		}
+		return
+	}
+	ids := make([]uint, 0, len(metas))
+	byName := make(map[string]*model.Meta, len(metas))
+	for i := range metas {
+		if !canAccess(user, &metas[i], metas[i].Path, "") {
+			continue
+		}
+		ids = append(ids, metas[i].ID)
+		byName[stdpath.Base(metas[i].Path)] = &metas[i]
+	}
+	times, err := db.GetCoverImageTimes(ids)
+	if err != nil {
+		log.Errorf("failed get covers of [%s]: %+v", dir, err)
+	}
+	for i := range resp {
+		if !resp[i].IsDir {
+			continue
+		}
+		meta, ok := byName[resp[i].Name]
+		if !ok {
+			continue
+		}
+		var uploaded *time.Time
+		if t, ok := times[meta.ID]; ok {
+			uploaded = &t
+		}
+		resp[i].Cover = coverURL(c, user, "", meta, uploaded)
+		resp[i].Description = meta.Description
+	}
+}
@@ -0,0 +1,38 @@
+package handles
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCoverURL(t *testing.T) {
+	if err := db.CreateMeta(&model.Meta{Path: "/covers/secret", Password: "meta", PSub: true}); err != nil {
+		t.Fatalf("failed create meta: %+v", err)
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/fs/list", nil)
+	guest := &model.User{Role: model.GUEST}
+	for _, tc := range []struct {
+		name     string
+		cover    string
+		password string
+		ok       bool
+	}{
+		{name: "in the folder", cover: "a.jpg", ok: true},
+		{name: "escaping the folder", cover: "../../a.jpg"},
+		{name: "protected by password", cover: "secret/a.jpg"},
+		{name: "with the password", cover: "secret/a.jpg", password: "meta", ok: true},
+		{name: "absolute and protected", cover: "/covers/secret/a.jpg"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := &model.Meta{Path: "/covers", Cover: tc.cover}
+			if url := coverURL(c, guest, tc.password, meta, nil); (url != "") != tc.ok {
+				t.Errorf("expected signed %v, got %q", tc.ok, url)
+			}
+		})
+	}
+}
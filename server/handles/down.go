@@ -28,7 +28,7 @@ func Down(c *gin.Context) {
 		Proxy(c)
 		return
 	} else {
-		link, _, err := fs.Link(c, rawPath, model.LinkArgs{
+		link, file, err := fs.Link(c, rawPath, model.LinkArgs{
 			IP:     c.ClientIP(),
 			Header: c.Request.Header,
 		})
@@ -36,6 +36,14 @@ func Down(c *gin.Context) {
 			common.ErrorResp(c, err, 500)
 			return
 		}
+		fs.CountAccess(rawPath, fs.AccessDownload)
+		// no url to redirect to, e.g. an alias of a local storage
+		if link.URL == "" {
+			if err := common.Proxy(c.Writer, c.Request, link, file); err != nil {
+				common.ErrorResp(c, err, 500, true)
+			}
+			return
+		}
 		c.Redirect(302, link.URL)
 	}
 }
@@ -65,6 +73,7 @@ func Proxy(c *gin.Context) {
 			common.ErrorResp(c, err, 500)
 			return
 		}
+		fs.CountAccess(rawPath, fs.AccessDownload)
 		err = common.Proxy(c.Writer, c.Request, link, file)
 		if err != nil {
 			common.ErrorResp(c, err, 500, true)
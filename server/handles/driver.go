@@ -2,6 +2,7 @@ package handles
 
 import (
 	"fmt"
+	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/operations"
 	"github.com/alist-org/alist/v3/server/common"
 	"github.com/gin-gonic/gin"
@@ -25,3 +26,24 @@ func GetDriverItems(c *gin.Context) {
 	}
 	common.SuccessResp(c, items)
 }
+
+type EnumerateDriverFieldReq struct {
+	Driver   string `json:"driver" binding:"required"`
+	Addition string `json:"addition"`
+	Field    string `json:"field" binding:"required"`
+}
+
+func EnumerateDriverField(c *gin.Context) {
+	var req EnumerateDriverFieldReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	storage := model.Storage{Driver: req.Driver, Addition: req.Addition}
+	values, err := operations.EnumerateDriverField(c, storage, req.Field)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, values)
+}
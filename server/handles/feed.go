@@ -0,0 +1,320 @@
+package handles
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	stdpath "path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/pkg/singleflight"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	sitemapMaxURLs    = 50000
+	sitemapExpiration = time.Hour
+	feedMaxItems      = 50
+
+	xmlContentType = "application/xml; charset=utf-8"
+)
+
+var (
+	// the sitemaps built, keyed by the base url and the feed paths
+	sitemapCache = cache.NewMemCache(cache.WithShards[[]byte](1))
+	sitemapG     singleflight.Group[[]byte]
+)
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// feedPaths return the public paths of the sitemap and feed,
+// which are visible to the guest
+func feedPaths(guest *model.User) []string {
+	var paths []string
+	for _, p := range strings.Split(setting.GetByKey(conf.FeedPaths), "\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		p = utils.StandardizePath(p)
+		if !utils.IsSubPath(guest.BasePath, p) {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// pageURL return the url of the page of the path in the frontend
+func pageURL(base string, guest *model.User, path string) string {
+	rel := utils.StandardizePath(strings.TrimPrefix(path, strings.TrimSuffix(guest.BasePath, "/")))
+	return base + utils.EncodePath(rel)
+}
+
+// isVisible check whether the user can see the path under the root
+// with the password, the hidden objs are invisible too
+func isVisible(user *model.User, root, path, password string) (bool, error) {
+	for p := path; ; p = stdpath.Dir(p) {
+		dir := stdpath.Dir(p)
+		if p == root || p == "/" {
+			dir = p
+		}
+		meta, err := db.GetNearestMeta(dir)
+		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			return false, err
+		}
+		if !canAccess(user, meta, dir, password) {
+			return false, nil
+		}
+		if dir == p {
+			return true, nil
+		}
+		if fs.IsHidden(user, meta, dir, stdpath.Base(p)) {
+			return false, nil
+		}
+	}
+}
+
+func feedGuest(c *gin.Context) (*model.User, []string, bool) {
+	guest, err := db.GetGuest()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return nil, nil, false
+	}
+	paths := feedPaths(guest)
+	if len(paths) == 0 {
+		c.Status(404)
+		return nil, nil, false
+	}
+	return guest, paths, true
+}
+
+// Sitemap serve the sitemap of the objs under the feed paths, it's built in background
+// and cached, walking all the paths is slow
+func Sitemap(c *gin.Context) {
+	guest, paths, ok := feedGuest(c)
+	if !ok {
+		return
+	}
+	base := common.GetBaseUrl(c.Request)
+	key := base + "\n" + strings.Join(paths, "\n")
+	if data, ok := sitemapCache.Get(key); ok {
+		c.Data(200, xmlContentType, data)
+		return
+	}
+	// the build goes on if the request leaves, the next one gets the result
+	ch := sitemapG.DoChan(key, func() ([]byte, error) {
+		data, err := buildSitemap(context.WithValue(context.Background(), "user", guest), base, guest, paths)
+		if err == nil {
+			sitemapCache.Set(key, data, cache.WithEx[[]byte](sitemapExpiration))
+		}
+		return data, err
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			common.ErrorResp(c, res.Err, 500, true)
+			return
+		}
+		c.Data(200, xmlContentType, res.Val)
+	case <-c.Request.Context().Done():
+	}
+}
+
+// buildSitemap walk the feed paths for the sitemap until it's full,
+// the dirs failed to list are skipped rather than failing the whole sitemap
+func buildSitemap(ctx context.Context, base string, guest *model.User, paths []string) ([]byte, error) {
+	urlSet := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	add := func(path string, modified time.Time) bool {
+		if len(urlSet.URLs) >= sitemapMaxURLs {
+			return false
+		}
+		u := sitemapURL{Loc: pageURL(base, guest, path)}
+		if !modified.IsZero() {
+			u.LastMod = modified.UTC().Format(time.RFC3339)
+		}
+		urlSet.URLs = append(urlSet.URLs, u)
+		return true
+	}
+	// walk return false if the sitemap is full
+	var walk func(dir string) bool
+	walk = func(dir string) bool {
+		meta, err := db.GetNearestMeta(dir)
+		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			log.Warnf("skip [%s] in the sitemap: %+v", dir, err)
+			return true
+		}
+		objs, err := fs.List(context.WithValue(ctx, "meta", meta), dir)
+		if err != nil {
+			log.Warnf("skip [%s] in the sitemap: %+v", dir, err)
+			return true
+		}
+		for _, obj := range objs {
+			path := stdpath.Join(dir, obj.GetName())
+			if obj.IsDir() {
+				meta, err := db.GetNearestMeta(path)
+				if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+					log.Warnf("skip [%s] in the sitemap: %+v", path, err)
+					continue
+				}
+				if !canAccess(guest, meta, path, "") {
+					continue
+				}
+			}
+			if !add(path, obj.ModTime()) {
+				return false
+			}
+			if obj.IsDir() && !walk(path) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, root := range paths {
+		public, err := isVisible(guest, root, root, "")
+		if err != nil {
+			return nil, err
+		}
+		if !public {
+			continue
+		}
+		if !add(root, time.Time{}) || !walk(root) {
+			break
+		}
+	}
+	return marshalXML(urlSet)
+}
+
+// Feed generate a rss feed of the files recently added under the feed paths
+func Feed(c *gin.Context) {
+	guest, paths, ok := feedGuest(c)
+	if !ok {
+		return
+	}
+	ctx := context.WithValue(c, "user", guest)
+	var changes []model.Change
+	for _, root := range paths {
+		rootChanges, err := db.GetChanges(root, model.ChangeCreate, feedMaxItems*2)
+		if err != nil {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+		changes = append(changes, rootChanges...)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].ID > changes[j].ID
+	})
+	channel := rssChannel{
+		Title:       setting.GetByKey(conf.SiteTitle),
+		Link:        common.GetBaseUrl(c.Request),
+		Description: "Recently added files",
+	}
+	seen := make(map[string]struct{})
+	for _, change := range changes {
+		if len(channel.Items) >= feedMaxItems {
+			break
+		}
+		if _, ok := seen[change.Path]; ok || change.IsDir {
+			continue
+		}
+		seen[change.Path] = struct{}{}
+		root := feedRoot(paths, change.Path)
+		public, err := isVisible(guest, root, change.Path, "")
+		if err != nil {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+		if !public {
+			continue
+		}
+		// the file may have been removed or moved since
+		if _, err := fs.Get(ctx, change.Path); err != nil {
+			continue
+		}
+		link := pageURL(common.GetBaseUrl(c.Request), guest, change.Path)
+		channel.Items = append(channel.Items, rssItem{
+			Title:       stdpath.Base(change.Path),
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: "false", Value: fmt.Sprintf("%s#%d", link, change.ID)},
+			PubDate:     change.Time.UTC().Format(time.RFC1123Z),
+			Description: fmt.Sprintf("%s (%d bytes)", change.Path, change.Size),
+		})
+	}
+	renderXML(c, rss{Version: "2.0", Channel: channel})
+}
+
+// feedRoot return the longest feed path containing the path
+func feedRoot(paths []string, path string) string {
+	root := "/"
+	for _, p := range paths {
+		if utils.IsSubPath(p, path) && len(p) > len(root) {
+			root = p
+		}
+	}
+	return root
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func renderXML(c *gin.Context, v interface{}) {
+	data, err := marshalXML(v)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	c.Data(200, xmlContentType, data)
+}
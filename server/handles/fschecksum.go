@@ -0,0 +1,80 @@
+package handles
+
+import (
+	stdpath "path"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type ChecksumReq struct {
+	Path     string `json:"path"`
+	Algo     string `json:"algo"` // sha256, md5 or sfv
+	Password string `json:"password"`
+}
+
+type ChecksumResp struct {
+	TaskID uint64 `json:"task_id"`
+}
+
+// FsGenerateChecksum write a checksum manifest of the files under the dir into the dir
+func FsGenerateChecksum(c *gin.Context) {
+	var req ChecksumReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	req.Path = stdpath.Join(user.BasePath, req.Path)
+	if !user.CanWrite() {
+		meta, err := db.GetNearestMeta(req.Path)
+		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		if !canWrite(meta, req.Path) {
+			common.ErrorResp(c, errs.PermissionDenied, 403)
+			return
+		}
+	}
+	if req.Algo == "" {
+		req.Algo = "sha256"
+	}
+	tid, err := fs.GenerateChecksum(req.Path, req.Algo)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, ChecksumResp{TaskID: tid})
+}
+
+// FsVerifyChecksum verify the files under the dir of the manifest, the path is the manifest
+func FsVerifyChecksum(c *gin.Context) {
+	var req ChecksumReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	req.Path = stdpath.Join(user.BasePath, req.Path)
+	meta, err := db.GetNearestMeta(req.Path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !canAccess(user, meta, req.Path, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect", 403)
+		return
+	}
+	tid, err := fs.VerifyChecksum(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, ChecksumResp{TaskID: tid})
+}
@@ -0,0 +1,137 @@
+package handles
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ListOpAdd    = "add"
+	ListOpModify = "modify"
+	ListOpDelete = "delete"
+
+	// the full listing is returned if there are more changes after the cursor
+	listDeltaMaxChanges = 1000
+)
+
+type ListChangeResp struct {
+	Op   string   `json:"op"`
+	Name string   `json:"name"`
+	Obj  *ObjResp `json:"obj,omitempty"` // nil if deleted
+}
+
+// listCursor the cursor of the listing with its state, so a cursor of the listing before
+// the dir is listed again is known to be stale. the virtual folders have the change id only
+func listCursor(cursor uint, state string, stateful bool) string {
+	if !stateful {
+		return strconv.FormatUint(uint64(cursor), 10)
+	}
+	return fmt.Sprintf("%d-%s", cursor, state)
+}
+
+func listETag(cursor uint, state string) string {
+	return `"` + listCursor(cursor, state, true) + `"`
+}
+
+// parseListCursor get the change id of the cursor, false if it's not of the current state
+func parseListCursor(s, state string, stateful bool) (uint, bool) {
+	cursorStr, cursorState, ok := strings.Cut(s, "-")
+	if ok != stateful || cursorState != state {
+		return 0, false
+	}
+	cursor, err := strconv.ParseUint(cursorStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(cursor), true
+}
+
+// listState the state the listing depends on besides the changes: the generation of the listings
+// of the storage, which changes if the dir is listed again, the user and the page.
+// the virtual folders above the storages have no state, so they are never answered by 304
+func listState(user *model.User, req *ListReq) (string, bool) {
+	storage, err := fs.GetStorage(req.Path)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%d-%d-%d-%d", operations.ListGeneration(storage), user.ID, req.PageIndex, req.PageSize), true
+}
+
+// listSince get the cursor the client listed the dir at, from the cursor of the request
+// or the etag in If-None-Match, the latter is answered by 304 if nothing changed.
+// both are only used if the state of the listing is the same
+func listSince(c *gin.Context, req *ListReq, state string, stateful bool) (uint, bool, bool) {
+	if req.Cursor != "" {
+		cursor, ok := parseListCursor(req.Cursor, state, stateful)
+		return cursor, ok, false
+	}
+	etag := strings.TrimPrefix(c.GetHeader("If-None-Match"), "W/")
+	if etag == "" || !stateful {
+		return 0, false, false
+	}
+	cursor, ok := parseListCursor(strings.Trim(etag, `"`), state, true)
+	return cursor, ok, ok
+}
+
+// listChangesSince get the changes under the dir made in (since, latest], false if they
+// can't be known, e.g. the changes after the cursor have been cleaned or there are too many
+func listChangesSince(dir string, since, latest uint) ([]model.Change, bool, error) {
+	first, err := db.GetFirstChangeID()
+	if err != nil {
+		return nil, false, err
+	}
+	if since > latest || first > since+1 {
+		return nil, false, nil
+	}
+	changes, reached, err := db.GetChangesAfter(dir, since, latest, listDeltaMaxChanges)
+	if err != nil {
+		return nil, false, err
+	}
+	return changes, reached == latest, nil
+}
+
+// deltaOps turn the changes under the dir into the ops of its entries, an entry listed is added
+// if it was created first after the cursor, otherwise modified, e.g. it's overwritten or the objs
+// in a sub folder changed
+func deltaOps(c *gin.Context, user *model.User, meta *model.Meta, dir string, changes []model.Change, objs []model.Obj) []ListChangeResp {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var names []string
+	first := make(map[string]string)
+	for _, change := range changes {
+		name, _, deeper := strings.Cut(strings.TrimPrefix(change.Path, prefix), "/")
+		if _, ok := first[name]; ok || fs.IsHidden(user, meta, dir, name) {
+			continue
+		}
+		names = append(names, name)
+		first[name] = change.Action
+		if deeper {
+			first[name] = ""
+		}
+	}
+	content := toObjResp(objs)
+	fillChildFolderInfo(c, user, dir, content)
+	current := make(map[string]*ObjResp, len(content))
+	for i := range content {
+		current[content[i].Name] = &content[i]
+	}
+	ops := make([]ListChangeResp, 0, len(names))
+	for _, name := range names {
+		obj, ok := current[name]
+		switch {
+		case !ok:
+			ops = append(ops, ListChangeResp{Op: ListOpDelete, Name: name})
+		case first[name] == model.ChangeCreate:
+			ops = append(ops, ListChangeResp{Op: ListOpAdd, Name: name, Obj: obj})
+		default:
+			ops = append(ops, ListChangeResp{Op: ListOpModify, Name: name, Obj: obj})
+		}
+	}
+	return ops
+}
@@ -0,0 +1,94 @@
+package handles
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig()
+	db.Init(dB)
+}
+
+func TestListSince(t *testing.T) {
+	tests := []struct {
+		name        string
+		cursor      string
+		ifNoneMatch string
+		state       string
+		stateful    bool
+		since       uint
+		ok          bool
+		notModified bool
+	}{
+		{name: "cursor", cursor: "3-1-1-1-50", ifNoneMatch: `"5-1-1-1-50"`, state: "1-1-1-50", stateful: true, since: 3, ok: true},
+		{name: "cursor listed again", cursor: "3-1-1-1-50", ifNoneMatch: `"5-2-1-1-50"`, state: "2-1-1-50", stateful: true},
+		{name: "cursor of virtual folder", cursor: "3", state: "", since: 3, ok: true},
+		{name: "old cursor", cursor: "3", state: "1-1-1-50", stateful: true},
+		{name: "etag", ifNoneMatch: `"5-1-1-1-50"`, state: "1-1-1-50", stateful: true, since: 5, ok: true, notModified: true},
+		{name: "weak etag", ifNoneMatch: `W/"5-1-1-1-50"`, state: "1-1-1-50", stateful: true, since: 5, ok: true, notModified: true},
+		{name: "listed again", ifNoneMatch: `"5-1-1-1-50"`, state: "2-1-1-50", stateful: true},
+		{name: "another user", ifNoneMatch: `"5-1-1-1-50"`, state: "1-2-1-50", stateful: true},
+		{name: "another page", ifNoneMatch: `"5-1-1-1-50"`, state: "1-1-2-50", stateful: true},
+		{name: "virtual folder", ifNoneMatch: `"5-1-1-1-50"`, state: "1-1-1-50"},
+		{name: "old etag", ifNoneMatch: `"5"`, state: "1-1-1-50", stateful: true},
+		{name: "none", state: "1-1-1-50", stateful: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/fs/list", nil)
+			if tt.ifNoneMatch != "" {
+				c.Request.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			since, ok, notModified := listSince(c, &ListReq{Cursor: tt.cursor}, tt.state, tt.stateful)
+			if since != tt.since || ok != tt.ok || notModified != tt.notModified {
+				t.Errorf("expected (%d, %v, %v), got (%d, %v, %v)", tt.since, tt.ok, tt.notModified, since, ok, notModified)
+			}
+		})
+	}
+}
+
+func TestDeltaOps(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/fs/list", nil)
+	changes := []model.Change{
+		{Path: "/d/added", Action: model.ChangeCreate},
+		{Path: "/d/overwritten", Action: model.ChangeModify},
+		{Path: "/d/sub/file", Action: model.ChangeCreate},
+		{Path: "/d/removed", Action: model.ChangeRemove},
+		{Path: "/d/gone", Action: model.ChangeCreate},
+		{Path: "/d/gone", Action: model.ChangeRemove},
+		{Path: "/d/added", Action: model.ChangeModify},
+	}
+	objs := []model.Obj{
+		&model.Object{Name: "added"},
+		&model.Object{Name: "overwritten"},
+		&model.Object{Name: "sub", IsFolder: true},
+		&model.Object{Name: "unchanged"},
+	}
+	ops := deltaOps(c, &model.User{Role: model.ADMIN}, nil, "/d", changes, objs)
+	var got []string
+	for _, op := range ops {
+		if (op.Obj == nil) != (op.Op == ListOpDelete) || (op.Obj != nil && op.Obj.Name != op.Name) {
+			t.Errorf("unexpected obj of %s: %+v", op.Name, op.Obj)
+		}
+		got = append(got, op.Op+" "+op.Name)
+	}
+	expected := []string{"add added", "modify overwritten", "modify sub", "delete removed", "delete gone"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,93 @@
+package handles
+
+import (
+	"fmt"
+	stdpath "path"
+	"path/filepath"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type FsExportReq struct {
+	Path     string `json:"path" form:"path"`
+	Password string `json:"password" form:"password"`
+	Format   string `json:"format" form:"format"` // aria2, wget or curl
+}
+
+// FsExport generate an aria2 input file or a shell script to download
+// all files under the path, keeping the relative paths
+func FsExport(c *gin.Context) {
+	var req FsExportReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	var line func(url, name string) string
+	var header, filename string
+	switch req.Format {
+	case "", "aria2":
+		line = func(url, name string) string {
+			return fmt.Sprintf("%s\n  out=%s\n", url, name)
+		}
+		filename = "aria2.txt"
+	case "wget":
+		line = func(url, name string) string {
+			return fmt.Sprintf("mkdir -p %s && wget -c -O %s %s\n", shellQuote(stdpath.Dir(name)), shellQuote(name), shellQuote(url))
+		}
+		header, filename = "#!/bin/sh\n", "wget.sh"
+	case "curl":
+		line = func(url, name string) string {
+			return fmt.Sprintf("curl -L -C - --create-dirs -o %s %s\n", shellQuote(name), shellQuote(url))
+		}
+		header, filename = "#!/bin/sh\n", "curl.sh"
+	default:
+		common.ErrorStrResp(c, "unsupported format: "+req.Format, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	root := stdpath.Join(user.BasePath, req.Path)
+	meta, err := db.GetNearestMeta(root)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !canAccess(user, meta, root, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect", 403)
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(header)
+	err = fs.Walk(c, root, func(path string, obj model.Obj) error {
+		if obj.IsDir() {
+			meta, err := db.GetNearestMeta(path)
+			if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+				return err
+			}
+			// skip the sub folders protected by another password
+			if !canAccess(user, meta, path, req.Password) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := strings.TrimPrefix(path, strings.TrimSuffix(root, "/")+"/")
+		sb.WriteString(line(signURL(c, "d", path, obj.GetName()), name))
+		return nil
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.String(200, sb.String())
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
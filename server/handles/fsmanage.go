@@ -217,7 +217,7 @@ func FsPut(c *gin.Context) {
 		WebPutAsTask: asTask,
 	}
 	if asTask {
-		err = fs.PutAsTask(dir, stream)
+		err = fs.PutAsTask(c, dir, stream)
 	} else {
 		err = fs.PutDirectly(c, dir, stream)
 	}
@@ -228,6 +228,89 @@ func FsPut(c *gin.Context) {
 	common.SuccessResp(c)
 }
 
+// checkWritePath respond 403 if the user can't write the path
+func checkWritePath(c *gin.Context, user *model.User, path string) bool {
+	if user.CanWrite() {
+		return true
+	}
+	meta, err := db.GetNearestMeta(path)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return false
+	}
+	if !canWrite(meta, path) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return false
+	}
+	return true
+}
+
+// FsAppend append the body to the end of the file at File-Path
+func FsAppend(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	path := stdpath.Join(user.BasePath, c.GetHeader("File-Path"))
+	if !checkWritePath(c, user, path) {
+		return
+	}
+	size, err := strconv.ParseInt(c.GetHeader("Content-Length"), 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	stream := &model.FileStream{
+		Obj: model.Object{
+			Name:     stdpath.Base(path),
+			Size:     size,
+			Modified: time.Now(),
+		},
+		ReadCloser: c.Request.Body,
+		Mimetype:   c.GetHeader("Content-Type"),
+	}
+	if err := fs.Append(c, path, stream); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// FsPatch write the body into the file at File-Path, the range is given by
+// the Content-Range header like `bytes 0-1023/*`, the total is ignored
+func FsPatch(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	path := stdpath.Join(user.BasePath, c.GetHeader("File-Path"))
+	if !checkWritePath(c, user, path) {
+		return
+	}
+	var start, end int64
+	if _, err := fmt.Sscanf(c.GetHeader("Content-Range"), "bytes %d-%d/", &start, &end); err != nil || start < 0 || end < start {
+		common.ErrorStrResp(c, "invalid Content-Range", 400)
+		return
+	}
+	size, err := strconv.ParseInt(c.GetHeader("Content-Length"), 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if size != end-start+1 {
+		common.ErrorStrResp(c, "Content-Length doesn't match Content-Range", 400)
+		return
+	}
+	stream := &model.FileStream{
+		Obj: model.Object{
+			Name:     stdpath.Base(path),
+			Size:     size,
+			Modified: time.Now(),
+		},
+		ReadCloser: c.Request.Body,
+		Mimetype:   c.GetHeader("Content-Type"),
+	}
+	if err := fs.Patch(c, path, start, stream); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
+
 // Link return real link, just for proxy program, it may contain cookie
 func Link(c *gin.Context) {
 	var req FsGetOrLinkReq
@@ -3,14 +3,17 @@ package handles
 import (
 	"fmt"
 	"github.com/alist-org/alist/v3/internal/sign"
+	"net/http"
 	stdpath "path"
 	"strings"
 	"time"
 
 	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/errs"
 	"github.com/alist-org/alist/v3/internal/fs"
 	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/alist-org/alist/v3/server/common"
 	"github.com/gin-gonic/gin"
@@ -21,6 +24,10 @@ type ListReq struct {
 	common.PageReq
 	Path     string `json:"path" form:"path"`
 	Password string `json:"password" form:"password"`
+	Refresh  bool   `json:"refresh" form:"refresh"`
+	// Cursor of the last listing, only the entries changed since then are returned if it's known
+	// and the dir isn't listed again meanwhile
+	Cursor string `json:"cursor" form:"cursor"`
 }
 
 type DirReq struct {
@@ -34,6 +41,9 @@ type ObjResp struct {
 	IsDir    bool      `json:"is_dir"`
 	Modified time.Time `json:"modified"`
 	Sign     string    `json:"sign"`
+	// Cover and Description of the folder set by its meta
+	Cover       string `json:"cover,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 type FsListResp struct {
@@ -41,6 +51,16 @@ type FsListResp struct {
 	Total   int64     `json:"total"`
 	Readme  string    `json:"readme"`
 	Write   bool      `json:"write"`
+	// Cover and Description of the listed folder
+	Cover       string `json:"cover"`
+	Description string `json:"description"`
+	// Capabilities of the storage, the ui hides the operations not supported
+	Capabilities *driver.Capabilities `json:"capabilities,omitempty"`
+	// Cursor to get the changes made through alist after the listing
+	Cursor string `json:"cursor"`
+	// Delta is true if only the Changes since the cursor of the request are returned
+	Delta   bool             `json:"delta,omitempty"`
+	Changes []ListChangeResp `json:"changes,omitempty"`
 }
 
 func FsList(c *gin.Context) {
@@ -64,18 +84,78 @@ func FsList(c *gin.Context) {
 		common.ErrorStrResp(c, "password is incorrect", 403)
 		return
 	}
-	objs, err := fs.List(c, req.Path)
+	// refresh bypasses the cache, so only allow users who can write
+	if req.Refresh && !user.CanWrite() && !canWrite(meta, req.Path) {
+		common.ErrorStrResp(c, "refresh without permission", 403)
+		return
+	}
+	// taken before listing, so the changes made meanwhile are not missed
+	cursor, err := db.GetLatestChangeID()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	var (
+		changes []model.Change
+		delta   bool
+	)
+	// the etag differs between the users and the pages
+	c.Header("Vary", "Authorization")
+	state, stateful := listState(user, &req)
+	// the changes out of alist are not recorded, so refresh always lists again
+	if since, ok, notModified := listSince(c, &req, state, stateful); ok && !req.Refresh {
+		changes, delta, err = listChangesSince(req.Path, since, cursor)
+		if err != nil {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+		if delta && notModified && len(changes) == 0 {
+			c.Header("ETag", listETag(since, state))
+			c.Status(http.StatusNotModified)
+			return
+		}
+		delta = delta && req.Cursor != ""
+	}
+	objs, err := fs.List(c, req.Path, req.Refresh)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	total, objs := pagination(objs, &req.PageReq)
-	common.SuccessResp(c, FsListResp{
-		Content: toObjResp(objs),
-		Total:   int64(total),
-		Readme:  getReadme(meta, req.Path),
-		Write:   user.CanWrite() || canWrite(meta, req.Path),
-	})
+	fs.CountAccess(req.Path, fs.AccessList)
+	cover, description := getFolderInfo(c, user, req.Password, meta, req.Path)
+	resp := FsListResp{
+		Total:        int64(len(objs)),
+		Readme:       getReadme(meta, req.Path),
+		Write:        user.CanWrite() || canWrite(meta, req.Path),
+		Cover:        cover,
+		Description:  description,
+		Capabilities: getCapabilities(req.Path),
+	}
+	if delta {
+		resp.Delta = true
+		resp.Changes = deltaOps(c, user, meta, req.Path, changes, objs)
+	} else {
+		_, objs = pagination(objs, &req.PageReq)
+		resp.Content = toObjResp(objs)
+		fillChildFolderInfo(c, user, req.Path, resp.Content)
+	}
+	// taken after listing, as the listing from the driver changes the generation
+	state, stateful = listState(user, &req)
+	resp.Cursor = listCursor(cursor, state, stateful)
+	if stateful {
+		c.Header("ETag", listETag(cursor, state))
+	}
+	common.SuccessResp(c, resp)
+}
+
+// getCapabilities of the storage of the path, nil for the virtual folders above the storages
+func getCapabilities(path string) *driver.Capabilities {
+	storage, err := fs.GetStorage(path)
+	if err != nil {
+		return nil
+	}
+	caps := operations.GetCapabilities(storage)
+	return &caps
 }
 
 func FsDirs(c *gin.Context) {
@@ -132,17 +212,18 @@ func getReadme(meta *model.Meta, path string) string {
 	return ""
 }
 
+// isPasswordProtected tell whether the password of the meta applies to the path
+func isPasswordProtected(meta *model.Meta, path string) bool {
+	// if meta is nil or password is empty, or meta doesn't apply to sub_folder
+	return meta != nil && meta.Password != "" && (utils.PathEqual(meta.Path, path) || meta.PSub)
+}
+
 func canAccess(user *model.User, meta *model.Meta, path string, password string) bool {
 	// if is not guest, can access
 	if user.CanAccessWithoutPassword() {
 		return true
 	}
-	// if meta is nil or password is empty, can access
-	if meta == nil || meta.Password == "" {
-		return true
-	}
-	// if meta doesn't apply to sub_folder, can access
-	if !utils.PathEqual(meta.Path, path) && !meta.PSub {
+	if !isPasswordProtected(meta, path) {
 		return true
 	}
 	// validate password
@@ -212,9 +293,11 @@ func FsGet(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	var rawURL string
-	// file have raw url
-	if !obj.IsDir() {
+	var rawURL, cover, description string
+	if obj.IsDir() {
+		cover, description = getFolderInfo(c, user, req.Password, meta, req.Path)
+	} else {
+		// file have raw url
 		if u, ok := obj.(model.URL); ok {
 			rawURL = u.URL()
 		} else {
@@ -238,12 +321,125 @@ func FsGet(c *gin.Context) {
 	}
 	common.SuccessResp(c, FsGetResp{
 		ObjResp: ObjResp{
-			Name:     obj.GetName(),
-			Size:     obj.GetSize(),
-			IsDir:    obj.IsDir(),
-			Modified: obj.ModTime(),
-			Sign:     common.Sign(obj),
+			Name:        obj.GetName(),
+			Size:        obj.GetSize(),
+			IsDir:       obj.IsDir(),
+			Modified:    obj.ModTime(),
+			Sign:        common.Sign(obj),
+			Cover:       cover,
+			Description: description,
 		},
 		RawURL: rawURL,
 	})
 }
+
+type FsLinksReq struct {
+	Paths    []string `json:"paths"`
+	Dir      string   `json:"dir"`
+	Glob     string   `json:"glob"` // filter files of dir by name, all files if empty
+	Password string   `json:"password"`
+	Direct   bool     `json:"direct"` // return the links of storages instead of signed links
+}
+
+type FsLinkResp struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	URL   string `json:"url"`
+	Error string `json:"error,omitempty"`
+}
+
+// FsLinks get links of multiple files in one call, used to generate download lists
+func FsLinks(c *gin.Context) {
+	var req FsLinksReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	paths := req.Paths
+	if req.Dir != "" {
+		if req.Glob != "" {
+			if _, err := stdpath.Match(req.Glob, ""); err != nil {
+				common.ErrorResp(c, err, 400)
+				return
+			}
+		}
+		dir := stdpath.Join(user.BasePath, req.Dir)
+		meta, err := db.GetNearestMeta(dir)
+		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+		c.Set("meta", meta)
+		if !canAccess(user, meta, dir, req.Password) {
+			common.ErrorStrResp(c, "password is incorrect", 403)
+			return
+		}
+		objs, err := fs.List(c, dir)
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		for _, obj := range objs {
+			if obj.IsDir() {
+				continue
+			}
+			if ok, _ := stdpath.Match(req.Glob, obj.GetName()); req.Glob == "" || ok {
+				paths = append(paths, stdpath.Join(req.Dir, obj.GetName()))
+			}
+		}
+	}
+	resp := make([]FsLinkResp, 0, len(paths))
+	for _, path := range paths {
+		res := FsLinkResp{Path: path}
+		size, url, err := fsLink(c, user, stdpath.Join(user.BasePath, path), req.Password, req.Direct)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Size, res.URL = size, url
+		}
+		resp = append(resp, res)
+	}
+	common.SuccessResp(c, resp)
+}
+
+func fsLink(c *gin.Context, user *model.User, path, password string, direct bool) (int64, string, error) {
+	meta, err := db.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return 0, "", err
+	}
+	if !canAccess(user, meta, path, password) {
+		return 0, "", errors.New("password is incorrect")
+	}
+	obj, err := fs.Get(c, path)
+	if err != nil {
+		return 0, "", err
+	}
+	if obj.IsDir() {
+		return 0, "", errors.WithStack(errs.NotFile)
+	}
+	if !direct {
+		return obj.GetSize(), signURL(c, "d", path, obj.GetName()), nil
+	}
+	if u, ok := obj.(model.URL); ok {
+		return obj.GetSize(), u.URL(), nil
+	}
+	storage, err := fs.GetStorage(path)
+	if err != nil {
+		return 0, "", err
+	}
+	// the storage has no direct link, use the proxy link
+	if storage.Config().MustProxy() || storage.GetStorage().WebProxy {
+		return obj.GetSize(), signURL(c, "p", path, obj.GetName()), nil
+	}
+	link, _, err := fs.Link(c, path, model.LinkArgs{IP: c.ClientIP()})
+	if err != nil {
+		return 0, "", err
+	}
+	return obj.GetSize(), link.URL, nil
+}
+
+// signURL build the signed url of the file by the route prefix, d for download and p for proxy
+func signURL(c *gin.Context, prefix, path, name string) string {
+	return fmt.Sprintf("%s/%s%s?sign=%s", common.GetBaseUrl(c.Request), prefix, utils.EncodePath(path), sign.Sign(name))
+}
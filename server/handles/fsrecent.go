@@ -0,0 +1,189 @@
+package handles
+
+import (
+	"context"
+	stdpath "path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	RecentAdded    = "added"
+	RecentModified = "modified"
+	RecentPopular  = "popular"
+
+	recentDefaultLimit = 20
+	recentMaxLimit     = 100
+	// the max count of objs visited for the recently modified view
+	recentWalkLimit = 10000
+)
+
+var errRecentWalkLimit = errors.New("too many objs")
+
+type FsRecentReq struct {
+	Path     string `json:"path" form:"path"`
+	Password string `json:"password" form:"password"`
+	Type     string `json:"type" form:"type"` // added, modified or popular
+	Limit    int    `json:"limit" form:"limit"`
+}
+
+type RecentObjResp struct {
+	ObjResp
+	Path string `json:"path"` // relative to the base path of the user
+}
+
+type recentObj struct {
+	path string
+	obj  model.Obj
+}
+
+// FsRecent list the newest or most downloaded files under the path, the recently added ones
+// come from the change log, the recently modified ones from walking the path
+// and the popular ones from the access counts
+func FsRecent(c *gin.Context) {
+	var req FsRecentReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = recentDefaultLimit
+	}
+	if req.Limit > recentMaxLimit {
+		req.Limit = recentMaxLimit
+	}
+	user := c.MustGet("user").(*model.User)
+	root := stdpath.Join(user.BasePath, req.Path)
+	meta, err := db.GetNearestMeta(root)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !canAccess(user, meta, root, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect", 403)
+		return
+	}
+	var objs []recentObj
+	switch req.Type {
+	case "", RecentAdded:
+		objs, err = recentAdded(c, user, root, req.Password, req.Limit)
+	case RecentModified:
+		objs, err = recentModified(c, user, root, req.Password, req.Limit)
+	case RecentPopular:
+		objs, err = popular(c, user, root, req.Password, req.Limit)
+	default:
+		common.ErrorStrResp(c, "unsupported type: "+req.Type, 400)
+		return
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	resp := make([]RecentObjResp, 0, len(objs))
+	for _, o := range objs {
+		resp = append(resp, RecentObjResp{
+			ObjResp: toObjResp([]model.Obj{o.obj})[0],
+			Path:    stdpath.Join("/", strings.TrimPrefix(o.path, strings.TrimSuffix(user.BasePath, "/"))),
+		})
+	}
+	common.SuccessResp(c, resp)
+}
+
+func recentAdded(ctx context.Context, user *model.User, root, password string, limit int) ([]recentObj, error) {
+	// fetch more since some of them may be removed or invisible
+	changes, err := db.GetChanges(root, model.ChangeCreate, limit*4)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, change := range changes {
+		if !change.IsDir {
+			paths = append(paths, change.Path)
+		}
+	}
+	return visibleFiles(ctx, user, root, password, paths, limit)
+}
+
+func popular(ctx context.Context, user *model.User, root, password string, limit int) ([]recentObj, error) {
+	counts, err := db.GetTopAccessCounts(root, "downloads", limit*4)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(counts))
+	for _, count := range counts {
+		paths = append(paths, count.Path)
+	}
+	return visibleFiles(ctx, user, root, password, paths, limit)
+}
+
+// visibleFiles get the first limit files of the paths, which exist and are visible to the user
+func visibleFiles(ctx context.Context, user *model.User, root, password string, paths []string, limit int) ([]recentObj, error) {
+	ctx = context.WithValue(ctx, "user", user)
+	var objs []recentObj
+	seen := make(map[string]struct{})
+	for _, path := range paths {
+		if len(objs) >= limit {
+			break
+		}
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		visible, err := isVisible(user, root, path, password)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			continue
+		}
+		obj, err := fs.Get(ctx, path)
+		if err != nil || obj.IsDir() {
+			continue
+		}
+		objs = append(objs, recentObj{path: path, obj: obj})
+	}
+	return objs, nil
+}
+
+func recentModified(ctx context.Context, user *model.User, root, password string, limit int) ([]recentObj, error) {
+	var objs []recentObj
+	visited := 0
+	err := fs.Walk(ctx, root, func(path string, obj model.Obj) error {
+		visited++
+		if visited > recentWalkLimit {
+			return errRecentWalkLimit
+		}
+		if obj.IsDir() {
+			meta, err := db.GetNearestMeta(path)
+			if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+				return err
+			}
+			if !canAccess(user, meta, path, password) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		objs = append(objs, recentObj{path: path, obj: obj})
+		return nil
+	})
+	// the view of a huge folder is built from the objs visited
+	if err != nil && !errors.Is(err, errRecentWalkLimit) {
+		return nil, err
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		return objs[i].obj.ModTime().After(objs[j].obj.ModTime())
+	})
+	if len(objs) > limit {
+		objs = objs[:limit]
+	}
+	return objs, nil
+}
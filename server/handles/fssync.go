@@ -0,0 +1,233 @@
+package handles
+
+import (
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	syncDefaultLimit = 1000
+	syncMaxLimit     = 5000
+	syncMaxStat      = 1000
+)
+
+type SyncObjResp struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"is_dir"`
+	Modified time.Time `json:"modified"`
+	// Hashes known without downloading the file, keyed by md5 or sha256
+	Hashes map[string]string `json:"hashes,omitempty"`
+}
+
+type SyncListReq struct {
+	Path     string `json:"path"`
+	Password string `json:"password"`
+}
+
+type SyncListResp struct {
+	Content []SyncObjResp `json:"content"`
+	// Cursor to get the changes under the path made after the listing
+	Cursor uint `json:"cursor"`
+}
+
+func toSyncObjResp(obj model.Obj, hashes map[string]string) SyncObjResp {
+	return SyncObjResp{
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		IsDir:    obj.IsDir(),
+		Modified: obj.ModTime(),
+		Hashes:   hashes,
+	}
+}
+
+// FsSyncList list the dir with the known hashes of the files for the sync clients,
+// along with the cursor of the change log to follow the dir afterwards
+func FsSyncList(c *gin.Context) {
+	var req SyncListReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	path := stdpath.Join(user.BasePath, req.Path)
+	meta, err := db.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	c.Set("meta", meta)
+	if !canAccess(user, meta, path, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect", 403)
+		return
+	}
+	// taken before listing, so the changes made meanwhile are not missed
+	cursor, err := db.GetLatestChangeID()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	objs, err := fs.List(c, path)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	hashes := fs.KnownHashes(path, objs)
+	content := make([]SyncObjResp, 0, len(objs))
+	for i, obj := range objs {
+		content = append(content, toSyncObjResp(obj, hashes[i]))
+	}
+	common.SuccessResp(c, SyncListResp{Content: content, Cursor: cursor})
+}
+
+type SyncChangesReq struct {
+	Path     string `json:"path"`
+	Password string `json:"password"`
+	Cursor   uint   `json:"cursor"`
+	Limit    int    `json:"limit"`
+}
+
+type SyncChangeResp struct {
+	Path   string    `json:"path"` // relative to the base path of the user
+	Action string    `json:"action"`
+	IsDir  bool      `json:"is_dir"`
+	Size   int64     `json:"size"`
+	Time   time.Time `json:"time"`
+}
+
+type SyncChangesResp struct {
+	Changes []SyncChangeResp `json:"changes"`
+	Cursor  uint             `json:"cursor"`
+	HasMore bool             `json:"has_more"`
+	// Reset is true if the changes after the cursor have been cleaned,
+	// the client has to list the path again
+	Reset bool `json:"reset"`
+}
+
+// FsSyncChanges get the changes under the path made through alist after the cursor
+func FsSyncChanges(c *gin.Context) {
+	var req SyncChangesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = syncDefaultLimit
+	}
+	if req.Limit > syncMaxLimit {
+		req.Limit = syncMaxLimit
+	}
+	user := c.MustGet("user").(*model.User)
+	root := stdpath.Join(user.BasePath, req.Path)
+	meta, err := db.GetNearestMeta(root)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !canAccess(user, meta, root, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect", 403)
+		return
+	}
+	latest, err := db.GetLatestChangeID()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	first, err := db.GetFirstChangeID()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	// the changes after the cursor have been cleaned, or the cursor is from the future,
+	// e.g. the database is restored from a backup
+	if req.Cursor > latest || first > req.Cursor+1 {
+		common.SuccessResp(c, SyncChangesResp{Changes: []SyncChangeResp{}, Cursor: latest, Reset: true})
+		return
+	}
+	changes, reached, err := db.GetChangesAfter(root, req.Cursor, latest, req.Limit)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	resp := SyncChangesResp{Changes: make([]SyncChangeResp, 0, len(changes)), Cursor: reached, HasMore: reached < latest}
+	for _, change := range changes {
+		visible, err := isVisible(user, root, change.Path, req.Password)
+		if err != nil {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+		if !visible {
+			continue
+		}
+		resp.Changes = append(resp.Changes, SyncChangeResp{
+			Path:   stdpath.Join("/", strings.TrimPrefix(change.Path, strings.TrimSuffix(user.BasePath, "/"))),
+			Action: change.Action,
+			IsDir:  change.IsDir,
+			Size:   change.Size,
+			Time:   change.Time,
+		})
+	}
+	common.SuccessResp(c, resp)
+}
+
+type SyncStatReq struct {
+	Paths    []string `json:"paths"`
+	Password string   `json:"password"`
+}
+
+type SyncStatResp struct {
+	Path string `json:"path"`
+	*SyncObjResp
+	Error string `json:"error,omitempty"`
+}
+
+// FsSyncStat get the objs of the paths with their known hashes in one call
+func FsSyncStat(c *gin.Context) {
+	var req SyncStatReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if len(req.Paths) > syncMaxStat {
+		common.ErrorStrResp(c, "too many paths", 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	resp := make([]SyncStatResp, 0, len(req.Paths))
+	for _, path := range req.Paths {
+		res := SyncStatResp{Path: path}
+		obj, err := syncStat(c, user, stdpath.Join(user.BasePath, path), req.Password)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.SyncObjResp = obj
+		}
+		resp = append(resp, res)
+	}
+	common.SuccessResp(c, resp)
+}
+
+func syncStat(c *gin.Context, user *model.User, path, password string) (*SyncObjResp, error) {
+	meta, err := db.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return nil, err
+	}
+	if !canAccess(user, meta, path, password) {
+		return nil, errors.New("password is incorrect")
+	}
+	obj, err := fs.Get(c, path)
+	if err != nil {
+		return nil, err
+	}
+	res := toSyncObjResp(obj, fs.KnownHashes(stdpath.Dir(path), []model.Obj{obj})[0])
+	return &res, nil
+}
@@ -0,0 +1,78 @@
+package handles
+
+import (
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func ListHolds(c *gin.Context) {
+	holds, err := db.GetHolds()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, holds)
+}
+
+type PlaceHoldReq struct {
+	Path   string `json:"path" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+func PlaceHold(c *gin.Context) {
+	var req PlaceHoldReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	hold, err := operations.PlaceHold(req.Path, req.Reason, c.MustGet("user").(*model.User))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, hold)
+}
+
+type ReleaseHoldReq struct {
+	ID     uint   `json:"id" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+func ReleaseHold(c *gin.Context) {
+	var req ReleaseHoldReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := operations.ReleaseHold(req.ID, req.Reason, c.MustGet("user").(*model.User)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type ListHoldLogsReq struct {
+	common.PageReq
+	Path string `json:"path" form:"path"`
+}
+
+func ListHoldLogs(c *gin.Context) {
+	var req ListHoldLogsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	logs, total, err := db.GetHoldLogs(req.Path, req.PageIndex, req.PageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: logs,
+		Total:   total,
+	})
+}
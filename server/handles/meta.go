@@ -110,3 +110,30 @@ func GetMeta(c *gin.Context) {
 	}
 	common.SuccessResp(c, meta)
 }
+
+type PropagateMetaReq struct {
+	model.Meta
+	Attrs  []string `json:"attrs" binding:"required"`
+	DryRun bool     `json:"dry_run"`
+}
+
+// PropagateMeta apply attrs of the meta to all its existing sub metas
+func PropagateMeta(c *gin.Context) {
+	var req PropagateMetaReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	r, err := validHide(req.Hide)
+	if err != nil {
+		common.ErrorStrResp(c, fmt.Sprintf("%s is illegal: %s", r, err.Error()), 400)
+		return
+	}
+	req.Path = utils.StandardizePath(req.Path)
+	metas, err := db.PropagateMeta(req.Meta, req.Attrs, req.DryRun)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, metas)
+}
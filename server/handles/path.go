@@ -0,0 +1,29 @@
+package handles
+
+import (
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type RewritePathReq struct {
+	OldPath string `json:"old_path" binding:"required"`
+	NewPath string `json:"new_path" binding:"required"`
+	DryRun  bool   `json:"dry_run"`
+}
+
+// RewritePath rewrite the records referencing old path, used to repair
+// records after the path is changed outside of alist
+func RewritePath(c *gin.Context) {
+	var req RewritePathReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	res, err := db.RewritePath(req.OldPath, req.NewPath, req.DryRun)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, res)
+}
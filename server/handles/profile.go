@@ -0,0 +1,70 @@
+package handles
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/profile"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Pprof serve the pprof endpoints, such as /api/admin/debug/pprof/goroutine?debug=1
+func Pprof(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	switch name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+type CaptureProfileReq struct {
+	Kind    string `json:"kind"`
+	Seconds int    `json:"seconds"` // duration of the cpu profile, default 30
+}
+
+// CaptureProfile start capturing a cpu profile or heap snapshot, which can be downloaded when done
+func CaptureProfile(c *gin.Context) {
+	var req CaptureProfileReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	capture, err := profile.Start(req.Kind, req.Seconds)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, capture)
+}
+
+func ListProfiles(c *gin.Context) {
+	common.SuccessResp(c, profile.List())
+}
+
+func DownloadProfile(c *gin.Context) {
+	capture, path, err := profile.Get(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	c.FileAttachment(path, capture.Filename())
+}
+
+func DeleteProfile(c *gin.Context) {
+	if err := profile.Delete(c.Query("id")); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
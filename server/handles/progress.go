@@ -0,0 +1,97 @@
+package handles
+
+import (
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type SetProgressReq struct {
+	Path     string    `json:"path" binding:"required"`
+	Position float64   `json:"position"`
+	Duration float64   `json:"duration"`
+	Device   string    `json:"device"`
+	Updated  time.Time `json:"updated"` // when the position is reached on the device, now if empty
+}
+
+// relativeProgress make the path relative to the base path of the user
+func relativeProgress(user *model.User, p model.Progress) model.Progress {
+	p.Path = stdpath.Join("/", strings.TrimPrefix(p.Path, strings.TrimSuffix(user.BasePath, "/")))
+	return p
+}
+
+func ListProgresses(c *gin.Context) {
+	var req common.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.MustGet("user").(*model.User)
+	progresses, total, err := db.GetProgresses(user.ID, req.PageIndex, req.PageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	for i := range progresses {
+		progresses[i] = relativeProgress(user, progresses[i])
+	}
+	common.SuccessResp(c, common.PageResp{Content: progresses, Total: total})
+}
+
+// GetProgress null is returned if the file has not been played
+func GetProgress(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	p, err := db.GetProgress(user.ID, stdpath.Join(user.BasePath, c.Query("path")))
+	if err != nil {
+		common.SuccessResp(c, nil)
+		return
+	}
+	common.SuccessResp(c, relativeProgress(user, *p))
+}
+
+// SetProgress the progress kept is returned, it's the one of another device if that is newer
+func SetProgress(c *gin.Context) {
+	var req SetProgressReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	if user.IsGuest() {
+		common.ErrorStrResp(c, "guest can't save the progress", 403)
+		return
+	}
+	now := time.Now()
+	// the clock of the device may be ahead, it would win forever
+	if req.Updated.IsZero() || req.Updated.After(now) {
+		req.Updated = now
+	}
+	p, err := db.SaveProgress(&model.Progress{
+		UserID:   user.ID,
+		Path:     stdpath.Join(user.BasePath, req.Path),
+		Position: req.Position,
+		Duration: req.Duration,
+		Device:   req.Device,
+		Updated:  req.Updated,
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, relativeProgress(user, *p))
+}
+
+func DeleteProgress(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	if err := db.DeleteProgress(user.ID, stdpath.Join(user.BasePath, c.Query("path"))); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
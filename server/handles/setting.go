@@ -4,6 +4,7 @@ import (
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/db"
 	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/setting"
 	"github.com/alist-org/alist/v3/internal/sign"
 	"github.com/alist-org/alist/v3/pkg/utils/random"
 	"github.com/alist-org/alist/v3/server/common"
@@ -100,3 +101,14 @@ func DeleteSetting(c *gin.Context) {
 func PublicSettings(c *gin.Context) {
 	common.SuccessResp(c, db.GetPublicSettingsMap())
 }
+
+type CapabilitiesResp struct {
+	ReadOnly bool `json:"read_only"` // all writes are rejected, the ui should hide the write actions
+}
+
+// Capabilities tell the ui what the site allows
+func Capabilities(c *gin.Context) {
+	common.SuccessResp(c, CapabilitiesResp{
+		ReadOnly: setting.IsReadOnly(),
+	})
+}
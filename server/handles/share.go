@@ -0,0 +1,308 @@
+package handles
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/internal/activity"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+)
+
+// the expiration of the download urls returned by resolving shares
+const shareLinkExpiration = time.Hour
+
+type CreateShareReq struct {
+	Path     string `json:"path"`
+	Password string `json:"password"` // the password of the path, not the share
+	// the password required to resolve the share, empty means no password
+	SharePassword string `json:"share_password"`
+	ExpireHours   int    `json:"expire_hours"` // 0 means never expire
+}
+
+func CreateShare(c *gin.Context) {
+	var req CreateShareReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	if user.IsGuest() {
+		common.ErrorStrResp(c, "guest can't create shares", 403)
+		return
+	}
+	path := stdpath.Join(user.BasePath, req.Path)
+	meta, err := db.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !canAccess(user, meta, path, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect", 403)
+		return
+	}
+	// the visitors have the permissions of the creator, they can't pass the password of the path
+	if isPasswordProtected(meta, path) && !user.CanAccessWithoutPassword() {
+		common.ErrorStrResp(c, "can't share a path protected by password", 403)
+		return
+	}
+	if _, err := fs.Get(c, path); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	share := model.Share{
+		Token:   strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Path:    path,
+		UserID:  user.ID,
+		Created: time.Now(),
+	}
+	if req.SharePassword != "" {
+		share.PasswordHash = utils.HashPassword(req.SharePassword)
+	}
+	if req.ExpireHours > 0 {
+		expires := share.Created.Add(time.Duration(req.ExpireHours) * time.Hour)
+		share.Expires = &expires
+	}
+	if err := db.CreateShare(&share); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, share)
+}
+
+// ShareResp the share listed, the password is not returned but whether it has one
+type ShareResp struct {
+	model.Share
+	HasPassword bool `json:"has_password"`
+}
+
+// ListShares list the shares of the current user, or all shares for admin
+func ListShares(c *gin.Context) {
+	var req common.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.MustGet("user").(*model.User)
+	userID := user.ID
+	if user.IsAdmin() {
+		userID = 0
+	}
+	shares, total, err := db.GetShares(userID, req.PageIndex, req.PageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	resp := make([]ShareResp, 0, len(shares))
+	for _, share := range shares {
+		resp = append(resp, ShareResp{Share: share, HasPassword: share.HasPassword()})
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: resp,
+		Total:   total,
+	})
+}
+
+func DeleteShare(c *gin.Context) {
+	idStr := c.Query("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	share, err := db.GetShareById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if share.UserID != user.ID && !user.IsAdmin() {
+		common.ErrorStrResp(c, "can't delete the share of others", 403)
+		return
+	}
+	if err := db.DeleteShareById(share.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type ListShareLogsReq struct {
+	common.PageReq
+	Token string `json:"token" form:"token"`
+}
+
+func ListShareLogs(c *gin.Context) {
+	var req ListShareLogsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	logs, total, err := db.GetShareLogs(req.Token, req.PageIndex, req.PageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: logs,
+		Total:   total,
+	})
+}
+
+// ip => limiter, removed after 10 minutes without requests
+var shareLimiters = cache.NewMemCache(cache.WithShards[*rate.Limiter](16))
+
+// allowResolve check the rate limit of resolving shares per ip
+func allowResolve(ip string) bool {
+	perMinute := setting.GetIntSetting(conf.ShareRateLimit, 60)
+	if perMinute <= 0 {
+		return true
+	}
+	limiter, ok := shareLimiters.Get(ip)
+	if !ok || limiter.Burst() != perMinute {
+		limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(perMinute)), perMinute)
+	}
+	shareLimiters.Set(ip, limiter, cache.WithEx[*rate.Limiter](10*time.Minute))
+	return limiter.Allow()
+}
+
+type ResolveShareReq struct {
+	Token    string `json:"token" form:"token" binding:"required"`
+	Path     string `json:"path" form:"path"` // relative to the shared path
+	Password string `json:"password" form:"password"`
+}
+
+type ResolveShareResp struct {
+	ObjResp
+	Path    string     `json:"path"`
+	URL     string     `json:"url,omitempty"` // temporary download url of the file
+	Expires *time.Time `json:"expires,omitempty"`
+	Content []ObjResp  `json:"content,omitempty"` // the children of the folder
+}
+
+// ResolveShare resolve the token and path to the metadata and a temporary download url in one call,
+// every call is logged for abuse tracking except the ones rejected by the rate limit, which would flood the logs
+func ResolveShare(c *gin.Context) {
+	var req ResolveShareReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Path = utils.StandardizePath(req.Path)
+	status := 200
+	defer func() {
+		if status == 429 {
+			return
+		}
+		err := db.CreateShareLog(&model.ShareLog{
+			Token:     req.Token,
+			Path:      req.Path,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Status:    status,
+			Time:      time.Now(),
+		})
+		if err != nil {
+			log.Errorf("failed log share resolution: %+v", err)
+		}
+	}()
+	if !allowResolve(c.ClientIP()) {
+		status = 429
+		common.ErrorStrResp(c, "too many requests", status)
+		return
+	}
+	resp, status, err := resolveShare(c, req)
+	if err != nil {
+		common.ErrorResp(c, err, status)
+		return
+	}
+	common.SuccessResp(c, resp)
+}
+
+func resolveShare(c *gin.Context, req ResolveShareReq) (*ResolveShareResp, int, error) {
+	share, err := db.GetShareByToken(req.Token)
+	if err != nil {
+		if errors.Is(errors.Cause(err), gorm.ErrRecordNotFound) {
+			return nil, 404, errors.New("share not found")
+		}
+		return nil, 500, err
+	}
+	if share.IsExpired() {
+		return nil, 404, errors.New("share expired")
+	}
+	if !share.CheckPassword(req.Password) {
+		return nil, 403, errors.New("password is incorrect")
+	}
+	// the share has the permissions of its creator, the paths protected by password under
+	// the shared path are only accessible if the creator can access them without password
+	owner, err := db.GetUserById(share.UserID)
+	if err != nil {
+		return nil, 404, errors.New("share not found")
+	}
+	path := stdpath.Join(share.Path, req.Path)
+	if !utils.IsSubPath(owner.BasePath, path) {
+		return nil, 403, errors.New("share not accessible")
+	}
+	visible, err := isVisible(owner, share.Path, path, "")
+	if err != nil {
+		return nil, 500, err
+	}
+	if !visible {
+		return nil, 404, errors.WithStack(errs.ObjectNotFound)
+	}
+	meta, err := db.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return nil, 500, err
+	}
+	if !canAccess(owner, meta, path, "") {
+		return nil, 403, errors.New("share not accessible")
+	}
+	ctx := context.WithValue(context.WithValue(c, "user", owner), "meta", meta)
+	obj, err := fs.Get(ctx, path)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			return nil, 404, err
+		}
+		return nil, 500, err
+	}
+	resp := &ResolveShareResp{
+		ObjResp: toObjResp([]model.Obj{obj})[0],
+		Path:    req.Path,
+	}
+	if obj.IsDir() {
+		objs, err := fs.List(ctx, path)
+		if err != nil {
+			return nil, 500, err
+		}
+		resp.Cover, resp.Description = getFolderInfo(c, owner, "", meta, path)
+		resp.Content = toObjResp(objs)
+		fillChildFolderInfo(c, owner, path, resp.Content)
+		return resp, 200, nil
+	}
+	expires := time.Now().Add(shareLinkExpiration)
+	resp.URL = fmt.Sprintf("%s/d%s?sign=%s", common.GetBaseUrl(c.Request), utils.EncodePath(path), sign.WithDuration(obj.GetName(), shareLinkExpiration))
+	resp.Expires = &expires
+	activity.Record(owner.ID, model.ActivityShareDownloaded,
+		fmt.Sprintf("%s in your share was downloaded from %s", obj.GetName(), c.ClientIP()), path)
+	return resp, 200, nil
+}
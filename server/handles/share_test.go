@@ -0,0 +1,78 @@
+package handles
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestShare(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "secret"), 0700); err != nil {
+		t.Fatalf("failed mkdir: %+v", err)
+	}
+	addition, _ := utils.Json.MarshalToString(map[string]interface{}{"root_folder": dir})
+	if err := operations.CreateStorage(context.Background(), model.Storage{Driver: "Local", MountPath: "/share", Addition: addition}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	if err := db.CreateMeta(&model.Meta{Path: "/share/secret", Password: "meta", PSub: true}); err != nil {
+		t.Fatalf("failed create meta: %+v", err)
+	}
+	user := &model.User{Username: "sharer", BasePath: "/"}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	create := func(body string) (*model.Share, int) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/fs/share/create", bytes.NewReader([]byte(body)))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", user)
+		CreateShare(c)
+		var resp struct {
+			Code int         `json:"code"`
+			Data model.Share `json:"data"`
+		}
+		if err := utils.Json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unexpected response: %s %+v", w.Body.String(), err)
+		}
+		return &resp.Data, resp.Code
+	}
+	// the visitors can't pass the password of the path
+	if _, code := create(`{"path":"/share/secret","password":"meta"}`); code != 403 {
+		t.Errorf("expected sharing a path protected by password is refused, got %d", code)
+	}
+	share, code := create(`{"path":"/share","share_password":"potato"}`)
+	if code != 200 {
+		t.Fatalf("failed create share: %d", code)
+	}
+	saved, err := db.GetShareByToken(share.Token)
+	if err != nil || saved.PasswordHash == "" || saved.PasswordHash == "potato" {
+		t.Fatalf("expected the password of the share is hashed, got %+v %+v", saved, err)
+	}
+	for _, c := range []struct {
+		path, password string
+		status         int
+	}{
+		{"/", "", 403},
+		{"/", "tomato", 403},
+		{"/", "potato", 200},
+		{"/secret", "potato", 403},
+	} {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("GET", "/api/public/share", nil)
+		_, status, err := resolveShare(ctx, ResolveShareReq{Token: share.Token, Path: c.path, Password: c.password})
+		if status != c.status {
+			t.Errorf("expected resolving %s with password %q responds %d, got %d %+v", c.path, c.password, c.status, status, err)
+		}
+	}
+}
@@ -2,24 +2,34 @@ package handles
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
 	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/net"
 	"github.com/alist-org/alist/v3/internal/operations"
 	"github.com/alist-org/alist/v3/server/common"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
+type ListStoragesReq struct {
+	common.PageReq
+	db.StorageFilter
+}
+
 func ListStorages(c *gin.Context) {
-	var req common.PageReq
+	var req ListStoragesReq
 	if err := c.ShouldBind(&req); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
 	req.Validate()
 	log.Debugf("%+v", req)
-	storages, total, err := db.GetStorages(req.PageIndex, req.PageSize)
+	storages, total, err := db.GetStorages(req.PageIndex, req.PageSize, req.StorageFilter)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -37,12 +47,25 @@ func CreateStorage(c *gin.Context) {
 		return
 	}
 	if err := operations.CreateStorage(c, req); err != nil {
-		common.ErrorResp(c, err, 500, true)
+		storageErrorResp(c, err)
 	} else {
 		common.SuccessResp(c)
 	}
 }
 
+// storageErrorResp the invalid fields of the addition are responded as the data, so the ui can mark them
+func storageErrorResp(c *gin.Context, err error) {
+	var additionErr *operations.AdditionError
+	switch {
+	case errors.As(err, &additionErr):
+		common.ErrorDataResp(c, err, 400, additionErr.Fields)
+	case errs.IsMountPathError(err):
+		common.ErrorResp(c, err, 400)
+	default:
+		common.ErrorResp(c, err, 500, true)
+	}
+}
+
 func UpdateStorage(c *gin.Context) {
 	var req model.Storage
 	if err := c.ShouldBind(&req); err != nil {
@@ -50,12 +73,27 @@ func UpdateStorage(c *gin.Context) {
 		return
 	}
 	if err := operations.UpdateStorage(c, req); err != nil {
-		common.ErrorResp(c, err, 500, true)
+		storageErrorResp(c, err)
 	} else {
 		common.SuccessResp(c)
 	}
 }
 
+// ConvertStorage change the driver of the storage, with dry_run only the mapped addition is returned
+func ConvertStorage(c *gin.Context) {
+	var req operations.ConvertReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	res, err := operations.ConvertStorage(c, req)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, res)
+}
+
 func DeleteStorage(c *gin.Context) {
 	idStr := c.Query("id")
 	id, err := strconv.Atoi(idStr)
@@ -84,3 +122,288 @@ func GetStorage(c *gin.Context) {
 	}
 	common.SuccessResp(c, storage)
 }
+
+func ListExpiringStorages(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("within", "24"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, operations.GetExpiringStorages(time.Duration(hours)*time.Hour))
+}
+
+type ReauthStorageReq struct {
+	ID       uint                   `json:"id" binding:"required"`
+	Addition map[string]interface{} `json:"addition" binding:"required"`
+}
+
+func ReauthStorage(c *gin.Context) {
+	var req ReauthStorageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := operations.ReauthStorage(c, req.ID, req.Addition); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func CreateBundle(c *gin.Context) {
+	var req operations.Bundle
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	shares, err := operations.CreateBundle(c, req)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, shares)
+}
+
+func ExportStorages(c *gin.Context) {
+	storages, err := operations.ExportStorages()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, storages)
+}
+
+type ImportStoragesReq struct {
+	Storages []model.Storage `json:"storages" binding:"required"`
+	operations.ImportOptions
+}
+
+func ImportStorages(c *gin.Context) {
+	var req ImportStoragesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, operations.ImportStorages(c, req.Storages, req.ImportOptions))
+}
+
+type SpeedTestReq struct {
+	Path   string `json:"path" binding:"required"`
+	Size   int64  `json:"size"`
+	Rounds int    `json:"rounds"`
+}
+
+func SpeedTest(c *gin.Context) {
+	var req SpeedTestReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Size == 0 {
+		req.Size = 8 * 1024 * 1024
+	}
+	if req.Rounds == 0 {
+		req.Rounds = 10
+	}
+	res, err := fs.SpeedTest(c, req.Path, req.Size, req.Rounds)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, res)
+}
+
+func StorageUsage(c *gin.Context) {
+	summary, err := operations.GetUsageSummary()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, summary)
+}
+
+func CollectStorageUsage(c *gin.Context) {
+	operations.CollectUsages(c)
+	StorageUsage(c)
+}
+
+type ReorderStoragesReq struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+func ReorderStorages(c *gin.Context) {
+	var req ReorderStoragesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := operations.ReorderStorages(req.IDs); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type CopyStorageReq struct {
+	ID        uint   `json:"id" binding:"required"`
+	MountPath string `json:"mount_path" binding:"required"`
+}
+
+func CopyStorage(c *gin.Context) {
+	var req CopyStorageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := operations.CopyStorage(c, req.ID, req.MountPath); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type MigrateReq struct {
+	SrcPath string `json:"src_path" binding:"required"`
+	DstPath string `json:"dst_path" binding:"required"`
+}
+
+// Migrate copy everything from src to dst in a task, submit it again to resume
+func Migrate(c *gin.Context) {
+	var req MigrateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	tid, err := fs.Migrate(req.SrcPath, req.DstPath)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"task_id": tid})
+}
+
+func ListBalanceGroups(c *gin.Context) {
+	common.SuccessResp(c, operations.GetBalanceGroups())
+}
+
+type BalanceReq struct {
+	Path      string `json:"path" binding:"required"`
+	MountPath string `json:"mount_path"` // the member to pin, empty to unpin
+}
+
+func ResetBalance(c *gin.Context) {
+	var req BalanceReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := operations.ResetBalance(req.Path); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func PinBalance(c *gin.Context) {
+	var req BalanceReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := operations.PinBalance(req.Path, req.MountPath); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type PopularReq struct {
+	MountPath string `json:"mount_path" form:"mount_path" binding:"required"`
+	By        string `json:"by" form:"by"` // lists or downloads
+	Limit     int    `json:"limit" form:"limit"`
+}
+
+// ListPopular list the most accessed paths under the mount path
+func ListPopular(c *gin.Context) {
+	var req PopularReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	counts, err := db.GetTopAccessCounts(req.MountPath, req.By, req.Limit)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, counts)
+}
+
+// ReloadStorages apply the storages changed in the database without restart
+func ReloadStorages(c *gin.Context) {
+	res, err := operations.ReloadStorages(c)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, res)
+}
+
+// ListStorageGroups count the storages by tag, driver or status
+func ListStorageGroups(c *gin.Context) {
+	by := c.DefaultQuery("by", db.GroupByTag)
+	groups, err := db.GetStorageGroups(by)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, groups)
+}
+
+func BatchEditStorages(c *gin.Context) {
+	var req operations.BatchEdit
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	results, err := operations.BatchEditStorages(c, req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, results)
+}
+
+// ListCaptures list the recent http calls of the storage captured in debug mode, the latest first
+func ListCaptures(c *gin.Context) {
+	storage, ok := getStorageByQuery(c)
+	if !ok {
+		return
+	}
+	common.SuccessResp(c, net.GetCaptures(storage.MountPath))
+}
+
+func ClearCaptures(c *gin.Context) {
+	storage, ok := getStorageByQuery(c)
+	if !ok {
+		return
+	}
+	net.ClearCaptures(storage.MountPath)
+	common.SuccessResp(c)
+}
+
+func getStorageByQuery(c *gin.Context) (*model.Storage, bool) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return nil, false
+	}
+	storage, err := db.GetStorageById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return nil, false
+	}
+	return storage, true
+}
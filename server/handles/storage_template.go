@@ -0,0 +1,132 @@
+package handles
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func ListStorageTemplates(c *gin.Context) {
+	var req common.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	templates, total, err := db.GetStorageTemplates(req.PageIndex, req.PageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: templates,
+		Total:   total,
+	})
+}
+
+func GetStorageTemplate(c *gin.Context) {
+	idStr := c.Query("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	template, err := db.GetStorageTemplateById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, template)
+}
+
+func CreateStorageTemplate(c *gin.Context) {
+	var req model.StorageTemplate
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if _, err := operations.GetDriverNew(req.Driver); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Modified = time.Now()
+	if err := db.CreateStorageTemplate(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func UpdateStorageTemplate(c *gin.Context) {
+	var req model.StorageTemplate
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if _, err := operations.GetDriverNew(req.Driver); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Modified = time.Now()
+	if err := db.UpdateStorageTemplate(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func DeleteStorageTemplate(c *gin.Context) {
+	idStr := c.Query("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := db.DeleteStorageTemplateById(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type SaveStorageTemplateReq struct {
+	StorageID uint   `json:"storage_id" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+}
+
+func SaveStorageAsTemplate(c *gin.Context) {
+	var req SaveStorageTemplateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	template, err := operations.SaveStorageAsTemplate(req.StorageID, req.Name)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, template)
+}
+
+type CreateFromTemplateReq struct {
+	TemplateID uint `json:"template_id" binding:"required"`
+	model.Storage
+}
+
+func CreateStorageFromTemplate(c *gin.Context) {
+	var req CreateFromTemplateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := operations.CreateStorageFromTemplate(c, req.TemplateID, req.Storage); err != nil {
+		common.ErrorResp(c, err, 500, true)
+	} else {
+		common.SuccessResp(c)
+	}
+}
@@ -0,0 +1,91 @@
+package handles
+
+import (
+	"fmt"
+	stdpath "path"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/setting"
+	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// the longest expiration of the streaming sessions, long enough for a movie paused for a while
+const maxStreamSessionExpiration = 12 * time.Hour
+
+// streamSessionExpiration the sessions expire like the signed links, but they are capped
+// even if the links never expire, the url of a session is not meant to be kept
+func streamSessionExpiration() time.Duration {
+	expire := time.Duration(setting.GetIntSetting(conf.LinkExpiration, 0)) * time.Hour
+	if expire <= 0 || expire > maxStreamSessionExpiration {
+		return maxStreamSessionExpiration
+	}
+	return expire
+}
+
+type FsStreamResp struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+// streamSignData the whole path is signed, so the session can't be used for another file of the same name
+func streamSignData(path string) string {
+	return "stream:" + path
+}
+
+// FsStream create a streaming session of the file for the players. the url of the session is stable,
+// every request of it is redirected to the current link of the provider, so the playback goes on
+// after the short-lived link expires, the player requests the session url again
+func FsStream(c *gin.Context) {
+	var req FsGetOrLinkReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	path := stdpath.Join(user.BasePath, req.Path)
+	meta, err := db.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !canAccess(user, meta, path, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect", 403)
+		return
+	}
+	obj, err := fs.Get(c, path)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if obj.IsDir() {
+		common.ErrorStrResp(c, "can't stream a folder", 400)
+		return
+	}
+	expire := streamSessionExpiration()
+	common.SuccessResp(c, FsStreamResp{
+		URL: fmt.Sprintf("%s/stream%s?sign=%s", common.GetBaseUrl(c.Request), utils.EncodePath(path),
+			sign.WithDuration(streamSignData(path), expire)),
+		Expires: time.Now().Add(expire),
+	})
+}
+
+// Stream serve the streaming session, the redirect is not cached so the players always get the current link
+func Stream(c *gin.Context) {
+	path := utils.StandardizePath(c.Param("path"))
+	if err := sign.Verify(streamSignData(path), c.Query("sign")); err != nil {
+		common.ErrorResp(c, err, 401)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Set("path", path)
+	Down(c)
+}
@@ -0,0 +1,79 @@
+package handles
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/alist-org/alist/v3/drivers/local"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/operations"
+	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestFsStream(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "movie.mkv"), []byte("movie"), 0600); err != nil {
+		t.Fatalf("failed write file: %+v", err)
+	}
+	addition, _ := utils.Json.MarshalToString(map[string]interface{}{"root_folder": dir})
+	if err := operations.CreateStorage(context.Background(), model.Storage{Driver: "Local", MountPath: "/stream", Addition: addition}); err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	user := &model.User{Role: model.ADMIN, BasePath: "/"}
+	for _, c := range []struct {
+		linkExpiration string
+		expire         time.Duration
+	}{
+		{"2", 2 * time.Hour},
+		// the links never expire, the sessions still do
+		{"0", maxStreamSessionExpiration},
+		{"48", maxStreamSessionExpiration},
+	} {
+		if err := db.SaveSettingItem(model.SettingItem{Key: conf.LinkExpiration, Value: c.linkExpiration}); err != nil {
+			t.Fatalf("failed save setting: %+v", err)
+		}
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", "/api/fs/stream?path=/stream/movie.mkv", nil)
+		ctx.Set("user", user)
+		FsStream(ctx)
+		var resp struct {
+			Code int          `json:"code"`
+			Data FsStreamResp `json:"data"`
+		}
+		if err := utils.Json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != 200 {
+			t.Fatalf("unexpected response: %s %+v", w.Body.String(), err)
+		}
+		if d := time.Until(resp.Data.Expires); d > c.expire || d < c.expire-time.Minute {
+			t.Errorf("expected the session of link expiration %s expires in %s, got %s", c.linkExpiration, c.expire, d)
+		}
+		_, query, ok := strings.Cut(resp.Data.URL, "/stream/stream/movie.mkv?")
+		values, err := url.ParseQuery(query)
+		if !ok || err != nil {
+			t.Fatalf("unexpected url: %s %+v", resp.Data.URL, err)
+		}
+		s := values.Get("sign")
+		if err := sign.Verify(streamSignData("/stream/movie.mkv"), s); err != nil {
+			t.Errorf("expected the session is signed: %+v", err)
+		}
+		if err := sign.Verify(streamSignData("/stream/other.mkv"), s); err == nil {
+			t.Errorf("expected the session is only valid for the file")
+		}
+		// the sign ends with the unix time it expires at
+		expire, _ := strconv.ParseInt(s[strings.LastIndex(s, ":")+1:], 10, 64)
+		if d := resp.Data.Expires.Unix() - expire; d < 0 || d > 1 {
+			t.Errorf("expected the sign expires at %d, got %d", resp.Data.Expires.Unix(), expire)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package handles
+
+import (
+	"github.com/alist-org/alist/v3/internal/supervisor"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetResourceUsage return the goroutines and the usage of the budgeted resources
+func GetResourceUsage(c *gin.Context) {
+	common.SuccessResp(c, supervisor.GetStats())
+}
+
+// GetResourceMetrics return the usage in the text format of prometheus, to be scraped
+func GetResourceMetrics(c *gin.Context) {
+	c.Data(200, "text/plain; version=0.0.4; charset=utf-8", []byte(supervisor.Metrics()))
+}
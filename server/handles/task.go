@@ -5,6 +5,7 @@ import (
 
 	"github.com/alist-org/alist/v3/internal/aria2"
 	"github.com/alist-org/alist/v3/internal/fs"
+	"github.com/alist-org/alist/v3/internal/userdata"
 	"github.com/alist-org/alist/v3/pkg/task"
 	"github.com/alist-org/alist/v3/server/common"
 	"github.com/gin-gonic/gin"
@@ -210,3 +211,141 @@ func ClearDoneCopyTasks(c *gin.Context) {
 	fs.CopyTaskManager.ClearDone()
 	common.SuccessResp(c)
 }
+
+func UndoneChecksumTask(c *gin.Context) {
+	common.SuccessResp(c, getTaskInfosUint(fs.ChecksumTaskManager.ListUndone()))
+}
+
+func DoneChecksumTask(c *gin.Context) {
+	common.SuccessResp(c, getTaskInfosUint(fs.ChecksumTaskManager.ListDone()))
+}
+
+func CancelChecksumTask(c *gin.Context) {
+	id := c.Query("tid")
+	tid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := fs.ChecksumTaskManager.Cancel(tid); err != nil {
+		common.ErrorResp(c, err, 500)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func DeleteChecksumTask(c *gin.Context) {
+	id := c.Query("tid")
+	tid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := fs.ChecksumTaskManager.Remove(tid); err != nil {
+		common.ErrorResp(c, err, 500)
+	} else {
+		fs.ChecksumReports.Delete(tid)
+		common.SuccessResp(c)
+	}
+}
+
+func ClearDoneChecksumTasks(c *gin.Context) {
+	for _, t := range fs.ChecksumTaskManager.ListDone() {
+		fs.ChecksumReports.Delete(t.ID)
+	}
+	fs.ChecksumTaskManager.ClearDone()
+	common.SuccessResp(c)
+}
+
+func ChecksumTaskReport(c *gin.Context) {
+	id := c.Query("tid")
+	tid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	report, _ := fs.ChecksumReports.Load(tid)
+	common.SuccessResp(c, report)
+}
+
+func UndoneMigrateTask(c *gin.Context) {
+	common.SuccessResp(c, getTaskInfosUint(fs.MigrateTaskManager.ListUndone()))
+}
+
+func DoneMigrateTask(c *gin.Context) {
+	common.SuccessResp(c, getTaskInfosUint(fs.MigrateTaskManager.ListDone()))
+}
+
+func CancelMigrateTask(c *gin.Context) {
+	id := c.Query("tid")
+	tid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := fs.MigrateTaskManager.Cancel(tid); err != nil {
+		common.ErrorResp(c, err, 500)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func DeleteMigrateTask(c *gin.Context) {
+	id := c.Query("tid")
+	tid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := fs.MigrateTaskManager.Remove(tid); err != nil {
+		common.ErrorResp(c, err, 500)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func ClearDoneMigrateTasks(c *gin.Context) {
+	fs.MigrateTaskManager.ClearDone()
+	common.SuccessResp(c)
+}
+
+func UndoneUserDataTask(c *gin.Context) {
+	common.SuccessResp(c, getTaskInfosUint(userdata.TaskManager.ListUndone()))
+}
+
+func DoneUserDataTask(c *gin.Context) {
+	common.SuccessResp(c, getTaskInfosUint(userdata.TaskManager.ListDone()))
+}
+
+func CancelUserDataTask(c *gin.Context) {
+	id := c.Query("tid")
+	tid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := userdata.TaskManager.Cancel(tid); err != nil {
+		common.ErrorResp(c, err, 500)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func DeleteUserDataTask(c *gin.Context) {
+	id := c.Query("tid")
+	tid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := userdata.Remove(tid); err != nil {
+		common.ErrorResp(c, err, 500)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func ClearDoneUserDataTasks(c *gin.Context) {
+	userdata.ClearDone()
+	common.SuccessResp(c)
+}
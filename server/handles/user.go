@@ -2,8 +2,10 @@ package handles
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/alist-org/alist/v3/internal/db"
+	"github.com/alist-org/alist/v3/internal/digest"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/server/common"
 	"github.com/gin-gonic/gin"
@@ -96,3 +98,15 @@ func GetUser(c *gin.Context) {
 	}
 	common.SuccessResp(c, user)
 }
+
+// SendDigest send the digest of the current period to the current user now,
+// it's used to check the notification settings, so the period isn't ended by it
+func SendDigest(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	now := time.Now()
+	if err := digest.Send(user, digest.PeriodStart(user.Digest, now), now); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
@@ -0,0 +1,71 @@
+package handles
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/userdata"
+	"github.com/alist-org/alist/v3/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportMyData add a task to export the data of the current user
+func ExportMyData(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	tid, err := userdata.Export(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{"tid": tid})
+}
+
+func ExportUserData(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	tid, err := userdata.Export(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"tid": tid})
+}
+
+// DownloadUserData download the exported archive, the users can only download their own ones, except the admin
+func DownloadUserData(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	tid, err := strconv.ParseUint(c.Query("tid"), 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	path, userId, err := userdata.GetArchive(tid)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if userId != user.ID && !user.IsAdmin() {
+		common.ErrorStrResp(c, "can't download the data of others", 403)
+		return
+	}
+	c.FileAttachment(path, fmt.Sprintf("user_data_%d.zip", tid))
+}
+
+// EraseUserData add a task to erase the user and the data, the held files are kept
+func EraseUserData(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	tid, err := userdata.Erase(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"tid": tid})
+}
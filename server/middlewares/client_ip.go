@@ -0,0 +1,16 @@
+package middlewares
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIP set the ip of the client into the context, the storages of a balance group can be picked by it
+func ClientIP(c *gin.Context) {
+	ip := c.ClientIP()
+	c.Set(utils.ClientIPKey, ip)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), utils.ClientIPKey, ip))
+	c.Next()
+}
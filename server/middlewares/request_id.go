@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	trustedOnce sync.Once
+	trustedNets []*net.IPNet
+)
+
+func loadTrustedProxies() {
+	for _, proxy := range conf.Conf.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Warnf("invalid trusted proxy %s: %+v", proxy, err)
+			continue
+		}
+		trustedNets = append(trustedNets, ipNet)
+	}
+}
+
+// isTrustedProxy check whether the direct peer of the request is a trusted proxy
+func isTrustedProxy(remoteAddr string) bool {
+	trustedOnce.Do(loadTrustedProxies)
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trustedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestID set the id of the request into the context and the response header,
+// the inbound X-Request-ID is only accepted from the trusted proxies
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(utils.RequestIDHeader)
+	if id == "" || len(id) > 128 || !isTrustedProxy(c.Request.RemoteAddr) {
+		id = uuid.NewString()
+	}
+	c.Set(utils.RequestIDKey, id)
+	// the calls made with the context of the request carry it to the providers
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), utils.RequestIDKey, id))
+	c.Header(utils.RequestIDHeader, id)
+	c.Next()
+}
+
+// LogFormatter is the default format of gin with the request id appended
+func LogFormatter(param gin.LogFormatterParams) string {
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		param.Keys[utils.RequestIDKey],
+		param.ErrorMessage,
+	)
+}
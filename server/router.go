@@ -18,6 +18,9 @@ func Init(r *gin.Engine) {
 
 	r.GET("/d/*path", middlewares.Down, handles.Down)
 	r.GET("/p/*path", middlewares.Down, handles.Proxy)
+	r.GET("/stream/*path", handles.Stream)
+	r.GET("/sitemap.xml", handles.Sitemap)
+	r.GET("/feed.xml", handles.Feed)
 
 	api := r.Group("/api")
 	auth := api.Group("", middlewares.Auth)
@@ -25,10 +28,25 @@ func Init(r *gin.Engine) {
 	api.POST("/auth/login", handles.Login)
 	auth.GET("/me", handles.CurrentUser)
 	auth.POST("/me/update", handles.UpdateCurrent)
+	auth.GET("/me/activities", handles.ListActivities)
+	auth.POST("/me/activities/read", handles.MarkActivitiesRead)
+	auth.GET("/me/activities/events", handles.ActivityEvents)
+	auth.POST("/me/export", handles.ExportMyData)
+	auth.GET("/me/export/download", handles.DownloadUserData)
+	auth.GET("/me/app_password/list", handles.ListAppPasswords)
+	auth.POST("/me/app_password/create", handles.CreateAppPassword)
+	auth.POST("/me/app_password/delete", handles.DeleteAppPassword)
+	auth.GET("/me/progress/list", handles.ListProgresses)
+	auth.GET("/me/progress/get", handles.GetProgress)
+	auth.POST("/me/progress/set", handles.SetProgress)
+	auth.POST("/me/progress/delete", handles.DeleteProgress)
 
 	// no need auth
 	public := api.Group("/public")
 	public.Any("/settings", handles.PublicSettings)
+	public.GET("/capabilities", handles.Capabilities)
+	public.Any("/share/resolve", handles.ResolveShare)
+	public.GET("/cover", handles.Cover)
 
 	fs(auth.Group("/fs"))
 	admin(auth.Group("/admin", middlewares.AuthAdmin))
@@ -44,6 +62,10 @@ func admin(g *gin.RouterGroup) {
 	meta.POST("/create", handles.CreateMeta)
 	meta.POST("/update", handles.UpdateMeta)
 	meta.POST("/delete", handles.DeleteMeta)
+	meta.POST("/propagate", handles.PropagateMeta)
+	meta.POST("/rewrite_path", handles.RewritePath)
+	meta.POST("/cover/upload", handles.UploadCover)
+	meta.POST("/cover/delete", handles.DeleteCover)
 
 	user := g.Group("/user")
 	user.GET("/list", handles.ListUsers)
@@ -51,18 +73,61 @@ func admin(g *gin.RouterGroup) {
 	user.POST("/create", handles.CreateUser)
 	user.POST("/update", handles.UpdateUser)
 	user.POST("/delete", handles.DeleteUser)
+	user.POST("/send_digest", handles.SendDigest)
+	user.POST("/export", handles.ExportUserData)
+	user.POST("/erase", handles.EraseUserData)
+	user.GET("/app_password/list", handles.ListUserAppPasswords)
+	user.POST("/app_password/delete", handles.DeleteUserAppPassword)
 
 	storage := g.Group("/storage")
 	storage.GET("/list", handles.ListStorages)
+	storage.GET("/groups", handles.ListStorageGroups)
 	storage.GET("/get", handles.GetStorage)
 	storage.POST("/create", handles.CreateStorage)
 	storage.POST("/update", handles.UpdateStorage)
+	storage.POST("/batch_edit", handles.BatchEditStorages)
+	storage.POST("/convert", handles.ConvertStorage)
 	storage.POST("/delete", handles.DeleteStorage)
+	storage.GET("/expiring", handles.ListExpiringStorages)
+	storage.POST("/reauth", handles.ReauthStorage)
+	storage.POST("/create_bundle", handles.CreateBundle)
+	storage.GET("/export", handles.ExportStorages)
+	storage.POST("/import", handles.ImportStorages)
+	storage.POST("/speed_test", handles.SpeedTest)
+	storage.GET("/usage", handles.StorageUsage)
+	storage.POST("/usage/refresh", handles.CollectStorageUsage)
+	storage.GET("/popular", handles.ListPopular)
+	storage.POST("/reorder", handles.ReorderStorages)
+	storage.POST("/copy", handles.CopyStorage)
+	storage.POST("/migrate", handles.Migrate)
+	storage.POST("/reload", handles.ReloadStorages)
+	storage.GET("/balance/list", handles.ListBalanceGroups)
+	storage.POST("/balance/reset", handles.ResetBalance)
+	storage.POST("/balance/pin", handles.PinBalance)
+	storage.GET("/captures", handles.ListCaptures)
+	storage.POST("/captures/clear", handles.ClearCaptures)
+
+	template := g.Group("/storage_template")
+	template.GET("/list", handles.ListStorageTemplates)
+	template.GET("/get", handles.GetStorageTemplate)
+	template.POST("/create", handles.CreateStorageTemplate)
+	template.POST("/update", handles.UpdateStorageTemplate)
+	template.POST("/delete", handles.DeleteStorageTemplate)
+	template.POST("/save", handles.SaveStorageAsTemplate)
+	template.POST("/create_storage", handles.CreateStorageFromTemplate)
+
+	credential := g.Group("/credential")
+	credential.GET("/list", handles.ListCredentials)
+	credential.GET("/get", handles.GetCredential)
+	credential.POST("/create", handles.CreateCredential)
+	credential.POST("/update", handles.UpdateCredential)
+	credential.POST("/delete", handles.DeleteCredential)
 
 	driver := g.Group("/driver")
 	driver.GET("/list", handles.ListDriverItems)
 	driver.GET("/names", handles.ListDriverNames)
 	driver.GET("/items", handles.GetDriverItems)
+	driver.POST("/enumerate", handles.EnumerateDriverField)
 
 	setting := g.Group("/setting")
 	setting.GET("/get", handles.GetSetting)
@@ -72,6 +137,11 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/reset_token", handles.ResetToken)
 	setting.POST("/set_aria2", handles.SetAria2)
 
+	config := g.Group("/config")
+	config.GET("/export", handles.ExportConfig)
+	config.POST("/import", handles.ImportConfig)
+	config.POST("/apply", handles.ApplyState)
+
 	task := g.Group("/task")
 	task.GET("/down/undone", handles.UndoneDownTask)
 	task.GET("/down/done", handles.DoneDownTask)
@@ -93,6 +163,41 @@ func admin(g *gin.RouterGroup) {
 	task.POST("/copy/cancel", handles.CancelCopyTask)
 	task.POST("/copy/delete", handles.DeleteCopyTask)
 	task.POST("/copy/clear_done", handles.ClearDoneCopyTasks)
+	task.GET("/checksum/undone", handles.UndoneChecksumTask)
+	task.GET("/checksum/done", handles.DoneChecksumTask)
+	task.POST("/checksum/cancel", handles.CancelChecksumTask)
+	task.POST("/checksum/delete", handles.DeleteChecksumTask)
+	task.POST("/checksum/clear_done", handles.ClearDoneChecksumTasks)
+	task.GET("/checksum/report", handles.ChecksumTaskReport)
+	task.GET("/migrate/undone", handles.UndoneMigrateTask)
+	task.GET("/migrate/done", handles.DoneMigrateTask)
+	task.POST("/migrate/cancel", handles.CancelMigrateTask)
+	task.POST("/migrate/delete", handles.DeleteMigrateTask)
+	task.POST("/migrate/clear_done", handles.ClearDoneMigrateTasks)
+	task.GET("/user_data/undone", handles.UndoneUserDataTask)
+	task.GET("/user_data/done", handles.DoneUserDataTask)
+	task.POST("/user_data/cancel", handles.CancelUserDataTask)
+	task.POST("/user_data/delete", handles.DeleteUserDataTask)
+	task.POST("/user_data/clear_done", handles.ClearDoneUserDataTasks)
+
+	share := g.Group("/share")
+	share.GET("/logs", handles.ListShareLogs)
+
+	hold := g.Group("/hold")
+	hold.GET("/list", handles.ListHolds)
+	hold.POST("/place", handles.PlaceHold)
+	hold.POST("/release", handles.ReleaseHold)
+	hold.GET("/logs", handles.ListHoldLogs)
+
+	g.GET("/supervisor/usage", handles.GetResourceUsage)
+	g.GET("/supervisor/metrics", handles.GetResourceMetrics)
+
+	debug := g.Group("/debug")
+	debug.GET("/pprof/*name", handles.Pprof)
+	debug.POST("/profile/capture", handles.CaptureProfile)
+	debug.GET("/profile/list", handles.ListProfiles)
+	debug.GET("/profile/download", handles.DownloadProfile)
+	debug.POST("/profile/delete", handles.DeleteProfile)
 
 	ms := g.Group("/message")
 	ms.GET("/get", message.PostInstance.GetHandle)
@@ -103,19 +208,33 @@ func fs(g *gin.RouterGroup) {
 	g.Any("/list", handles.FsList)
 	g.Any("/get", handles.FsGet)
 	g.Any("/dirs", handles.FsDirs)
+	g.POST("/links", handles.FsLinks)
+	g.POST("/stream", handles.FsStream)
+	g.GET("/export", handles.FsExport)
+	g.Any("/recent", handles.FsRecent)
 	g.POST("/mkdir", handles.FsMkdir)
 	g.POST("/rename", handles.FsRename)
 	g.POST("/move", handles.FsMove)
 	g.POST("/copy", handles.FsCopy)
 	g.POST("/remove", handles.FsRemove)
 	g.POST("/put", handles.FsPut)
+	g.POST("/append", handles.FsAppend)
+	g.PATCH("/put", handles.FsPatch)
+	g.POST("/checksum/generate", handles.FsGenerateChecksum)
+	g.POST("/checksum/verify", handles.FsVerifyChecksum)
+	g.POST("/sync/list", handles.FsSyncList)
+	g.POST("/sync/changes", handles.FsSyncChanges)
+	g.POST("/sync/stat", handles.FsSyncStat)
 	g.POST("/link", middlewares.AuthAdmin, handles.Link)
 	g.POST("/add_aria2", handles.AddAria2)
+	g.POST("/share/create", handles.CreateShare)
+	g.GET("/share/list", handles.ListShares)
+	g.POST("/share/delete", handles.DeleteShare)
 }
 
 func Cors(r *gin.Engine) {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
-	config.AllowHeaders = append(config.AllowHeaders, "Authorization", "range", "File-Path", "As-Task")
+	config.AllowHeaders = append(config.AllowHeaders, "Authorization", "range", "File-Path", "As-Task", "Content-Range")
 	r.Use(cors.New(config))
 }
@@ -9,6 +9,9 @@ import (
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
+	stdpath "path"
+	"strings"
 )
 
 var handler *webdav.Handler
@@ -59,7 +62,9 @@ func WebDAVAuth(c *gin.Context) {
 		return
 	}
 	user, err := db.GetUserByName(username)
-	if err != nil || user.ValidatePassword(password) != nil {
+	// the guest is refused even with the right password, it's the user of the visitors
+	// not signed in, which is never meant to be an account of webdav
+	if err != nil || user.IsGuest() || !validateWebdavPassword(c, user, password) {
 		if c.Request.Method == "OPTIONS" {
 			c.Set("user", guest)
 			c.Next()
@@ -69,6 +74,8 @@ func WebDAVAuth(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	// the permissions denied to webdav are removed before checking
+	user = user.ForProtocol(model.ProtocolWebdav)
 	if !user.CanWebdavRead() {
 		if c.Request.Method == "OPTIONS" {
 			c.Set("user", guest)
@@ -79,7 +86,8 @@ func WebDAVAuth(c *gin.Context) {
 		c.Abort()
 		return
 	}
-	if !user.CanWebdavManage() && utils.SliceContains([]string{"PUT", "DELETE", "PROPPATCH", "MKCOL", "COPY", "MOVE"}, c.Request.Method) {
+	if (!user.CanWebdavManage() && utils.SliceContains([]string{"PUT", "DELETE", "PROPPATCH", "MKCOL", "COPY", "MOVE"}, c.Request.Method)) ||
+		user.IsDenied(model.ProtocolWebdav, webdavMethodBit(c.Request)) {
 		if c.Request.Method == "OPTIONS" {
 			c.Set("user", guest)
 			c.Next()
@@ -92,3 +100,36 @@ func WebDAVAuth(c *gin.Context) {
 	c.Set("user", user)
 	c.Next()
 }
+
+// validateWebdavPassword accept the main password or an app password for webdav,
+// the device using the app password is recorded
+func validateWebdavPassword(c *gin.Context, user *model.User, password string) bool {
+	if user.ValidatePassword(password) == nil {
+		return true
+	}
+	p, err := db.MatchAppPassword(user.ID, model.ProtocolWebdav, password, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		log.Errorf("failed match the app password of %s: %+v", user.Username, err)
+	}
+	return p != nil
+}
+
+// webdavMethodBit the permission bit needed by the method, -1 if no permission is needed
+func webdavMethodBit(r *http.Request) int {
+	switch r.Method {
+	case "PUT", "MKCOL", "PROPPATCH":
+		return 3
+	case "DELETE":
+		return 7
+	case "COPY":
+		return 6
+	case "MOVE":
+		// moved within the same dir is renaming
+		if u, err := url.Parse(r.Header.Get("Destination")); err == nil &&
+			stdpath.Dir(strings.TrimSuffix(u.Path, "/")) == stdpath.Dir(strings.TrimSuffix(r.URL.Path, "/")) {
+			return 4
+		}
+		return 5
+	}
+	return -1
+}
@@ -12,12 +12,14 @@ import (
 	"github.com/alist-org/alist/v3/internal/fs"
 	"github.com/alist-org/alist/v3/internal/model"
 	"github.com/alist-org/alist/v3/internal/sign"
+	"github.com/alist-org/alist/v3/internal/supervisor"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/alist-org/alist/v3/server/common"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -223,6 +225,10 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request) (sta
 			return http.StatusInternalServerError, err
 		}
 		err = common.Proxy(w, r, link, fi)
+		if errors.Is(err, supervisor.ErrOverBudget) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(supervisor.RetryAfter.Seconds())))
+			return http.StatusServiceUnavailable, err
+		}
 		if err != nil {
 			return http.StatusInternalServerError, err
 		}